@@ -0,0 +1,110 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/z-wentao/voiceflow/pkg/config"
+	"github.com/z-wentao/voiceflow/pkg/queue"
+	"github.com/z-wentao/voiceflow/pkg/sources"
+)
+
+// prefetchQueue 支持调整预取数量的内存队列（代替 RabbitMQ）
+type prefetchQueue struct {
+	*queue.MemoryQueue
+	prefetch int
+}
+
+func (q *prefetchQueue) SetPrefetch(prefetchCount int) error {
+	q.prefetch = prefetchCount
+	return nil
+}
+
+func TestAdminRoutesWithAdminKeyOnly(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	cfg := &config.Config{}
+	cfg.Server.AdminAPIKey = "admin-key"
+	cfg.Auth.Enabled = true
+	cfg.Auth.APIKeys = []string{"api-key"}
+	cfg.Users.Keys = []config.UserKeyConfig{{Key: "user-key", UserID: "alice"}}
+	cfg.FileStore.Type = "s3"
+
+	q := &prefetchQueue{MemoryQueue: queue.NewMemoryQueue(1)}
+	app := &App{config: cfg, queue: q}
+	router := app.setupRouter()
+
+	tests := []struct {
+		name   string
+		header string
+		key    string
+		want   int
+	}{
+		{"只携带管理 Key", "Authorization", "Bearer admin-key", http.StatusOK},
+		{"管理 Key 放在 X-API-Key", "X-API-Key", "admin-key", http.StatusOK},
+		{"用户 Key", "X-API-Key", "user-key", http.StatusUnauthorized},
+		{"不携带 Key", "", "", http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPut, "/api/admin/queue/prefetch", strings.NewReader(`{"prefetch": 4}`))
+			req.Header.Set("Content-Type", "application/json")
+			if tt.header != "" {
+				req.Header.Set(tt.header, tt.key)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			if w.Code != tt.want {
+				t.Fatalf("状态码 = %d，期望 %d（响应: %s）", w.Code, tt.want, w.Body.String())
+			}
+		})
+	}
+	if q.prefetch != 4 {
+		t.Fatalf("预取数量 = %d，期望 4", q.prefetch)
+	}
+}
+
+func TestSourceToggleRequiresAdmin(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	cfg := &config.Config{}
+	cfg.Server.AdminAPIKey = "admin-key"
+	cfg.Users.Keys = []config.UserKeyConfig{{Key: "user-key", UserID: "alice"}}
+	cfg.FileStore.Type = "s3"
+
+	scheduler := sources.NewScheduler(nil, nil, []sources.Config{{Name: "pod", URL: "https://example.com/feed.xml", Enabled: true}})
+	app := &App{config: cfg, queue: queue.NewMemoryQueue(1), sources: scheduler}
+	router := app.setupRouter()
+
+	tests := []struct {
+		name string
+		path string
+		key  string
+		want int
+	}{
+		{"用户 Key 访问旧地址", "/api/sources/pod/disable", "user-key", http.StatusNotFound},
+		{"用户 Key", "/api/admin/sources/pod/disable", "user-key", http.StatusUnauthorized},
+		{"不携带 Key", "/api/admin/sources/pod/disable", "", http.StatusUnauthorized},
+		{"订阅源不存在", "/api/admin/sources/other/disable", "admin-key", http.StatusNotFound},
+		{"管理 Key", "/api/admin/sources/pod/disable", "admin-key", http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, tt.path, nil)
+			if tt.key != "" {
+				req.Header.Set("X-API-Key", tt.key)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			if w.Code != tt.want {
+				t.Fatalf("状态码 = %d，期望 %d（响应: %s）", w.Code, tt.want, w.Body.String())
+			}
+		})
+	}
+	if app.sources.List()[0].Enabled {
+		t.Fatal("订阅源应已停用")
+	}
+}
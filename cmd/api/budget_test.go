@@ -0,0 +1,80 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/z-wentao/voiceflow/pkg/budget"
+	"github.com/z-wentao/voiceflow/pkg/config"
+	"github.com/z-wentao/voiceflow/pkg/filestore"
+	"github.com/z-wentao/voiceflow/pkg/queue"
+	"github.com/z-wentao/voiceflow/pkg/storage"
+)
+
+// TestUploadBudgetExhausted 本月预算用尽时上传返回 402，不保存文件也不创建任务
+func TestUploadBudgetExhausted(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	tests := []struct {
+		name  string
+		limit float64
+		spent float64
+		want  bool // 期望返回 402
+	}{
+		{"未启用预算", 0, 100, false},
+		{"预算有剩余", 10, 9.99, false},
+		{"刚好用完", 10, 10, true},
+		{"已超出", 10, 12.5, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			store := storage.NewJobStore(10)
+			if _, err := store.IncrUsage(ctx, time.Now().Format("2006-01"), tt.spent); err != nil {
+				t.Fatalf("IncrUsage: %v", err)
+			}
+
+			cfg := &config.Config{}
+			cfg.Server.MaxUploadSize = 1 << 20
+			cfg.Server.MaxBatchFiles = 1
+			cfg.Server.UploadTempDir = t.TempDir()
+			cfg.FileStore.Type = "local"
+			cfg.FileStore.Local.Dir = t.TempDir()
+			q := queue.NewMemoryQueue(1)
+			app := &App{config: cfg, queue: q, store: store, files: filestore.NewLocalStore(cfg.FileStore.Local.Dir), budget: budget.NewTracker(store, tt.limit)}
+			router := app.setupRouter()
+
+			body, contentType := uploadBody(t, "talk.mp3", 1024)
+			req := httptest.NewRequest(http.MethodPost, "/api/upload", bytes.NewReader(body))
+			req.Header.Set("Content-Type", contentType)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if got := w.Code == http.StatusPaymentRequired; got != tt.want {
+				t.Fatalf("状态码 = %d，期望返回 402: %v（响应: %s）", w.Code, tt.want, w.Body.String())
+			}
+			if tt.want && !strings.Contains(w.Body.String(), budget.ErrBudgetExhausted.Error()) {
+				t.Fatalf("响应没有说明预算已用尽: %s", w.Body.String())
+			}
+			// 测试文件不是有效的音频：预算放行时在读取时长时被拒绝，同样不会留下文件和任务
+			if n := countFiles(t, cfg.FileStore.Local.Dir); n != 0 {
+				t.Fatalf("文件存储中有 %d 个文件，期望 0", n)
+			}
+			jobs, err := store.List(ctx)
+			if err != nil {
+				t.Fatalf("List: %v", err)
+			}
+			if len(jobs) != 0 {
+				t.Fatalf("创建了 %d 个任务，期望 0", len(jobs))
+			}
+			if stats, _ := q.Stats(); stats.Depth != 0 {
+				t.Fatalf("队列中有 %d 个任务，期望 0", stats.Depth)
+			}
+		})
+	}
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/z-wentao/voiceflow/pkg/config"
+	"github.com/z-wentao/voiceflow/pkg/filestore"
+	"github.com/z-wentao/voiceflow/pkg/models"
+	"github.com/z-wentao/voiceflow/pkg/queue"
+	"github.com/z-wentao/voiceflow/pkg/storage"
+)
+
+// editRaceStore 模拟修正期间任务被重新转录（第二次读取时任务已回到处理中）或保存失败
+type editRaceStore struct {
+	storage.Store
+	restart  bool
+	failSave bool
+
+	mu   sync.Mutex
+	gets int
+}
+
+func (s *editRaceStore) Get(ctx context.Context, jobID string) (*models.TranscriptionJob, error) {
+	job, err := s.Store.Get(ctx, jobID)
+	if err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	s.gets++
+	gets := s.gets
+	s.mu.Unlock()
+	if s.restart && gets > 1 {
+		job.Status = models.StatusProcessing
+	}
+	return job, nil
+}
+
+func (s *editRaceStore) Save(ctx context.Context, job *models.TranscriptionJob) error {
+	if s.failSave {
+		return errors.New("数据库不可用")
+	}
+	return s.Store.Save(ctx, job)
+}
+
+// listUploads 列出 uploads 目录下的文件名（排序后）
+func listUploads(t *testing.T, root string) []string {
+	t.Helper()
+	entries, err := os.ReadDir(filepath.Join(root, "uploads"))
+	if err != nil {
+		t.Fatalf("ReadDir: %v", err)
+	}
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	sort.Strings(names)
+	return names
+}
+
+// TestEditResultSubtitles 修正字幕写到新的 key，保存成功后才替换任务的字幕路径；修正未保存时原来的字幕不受影响
+func TestEditResultSubtitles(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	const cuesBody = `{"cues": [{"index": 1, "start": 0, "end": 2, "text": "Hello everyone."}, {"index": 2, "start": 2, "end": 4, "text": "Welcome back."}]}`
+	original := []string{"job-1.bilingual.srt", "job-1.mp3", "job-1.srt", "job-1.vtt"}
+
+	tests := []struct {
+		name        string
+		body        string
+		restart     bool
+		failSave    bool
+		wantCode    int
+		wantEdited  bool // 任务引用新的字幕文件，旧字幕已删除
+		wantUploads []string
+	}{
+		{"修正字幕", cuesBody, false, false, http.StatusOK, true, nil},
+		{"只修正文本", `{"text": "Hello everyone."}`, false, false, http.StatusOK, false, original},
+		{"保存前任务被重新转录", cuesBody, true, false, http.StatusConflict, false, original},
+		{"保存任务失败", cuesBody, false, true, http.StatusInternalServerError, false, original},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			dir := t.TempDir()
+			files := filestore.NewLocalStore(dir)
+			for key, content := range map[string]string{
+				"uploads/job-1.mp3":           "ID3",
+				"uploads/job-1.srt":           "1\n00:00:00,000 --> 00:00:02,000\nHelo everyone.\n",
+				"uploads/job-1.vtt":           "WEBVTT\n\n00:00:00.000 --> 00:00:02.000\nHelo everyone.\n",
+				"uploads/job-1.bilingual.srt": "1\n00:00:00,000 --> 00:00:02,000\nHelo everyone.\n大家好。\n",
+			} {
+				if err := files.Put(ctx, key, strings.NewReader(content), int64(len(content)), ""); err != nil {
+					t.Fatalf("Put %s: %v", key, err)
+				}
+			}
+			base := storage.NewJobStore(10)
+			if err := base.Save(ctx, &models.TranscriptionJob{
+				JobID:            "job-1",
+				Filename:         "talk.mp3",
+				FilePath:         "uploads/job-1.mp3",
+				SubtitlePath:     "uploads/job-1.srt",
+				VTTPath:          "uploads/job-1.vtt",
+				BilingualSRTPath: "uploads/job-1.bilingual.srt",
+				Status:           models.StatusCompleted,
+				Result:           "Helo everyone.",
+			}); err != nil {
+				t.Fatalf("Save: %v", err)
+			}
+			store := &editRaceStore{Store: base, restart: tt.restart, failSave: tt.failSave}
+
+			cfg := &config.Config{}
+			cfg.Server.UploadTempDir = t.TempDir()
+			cfg.FileStore.Type = "local"
+			cfg.FileStore.Local.Dir = dir
+			app := &App{config: cfg, queue: queue.NewMemoryQueue(1), store: store, files: files}
+			router := app.setupRouter()
+
+			req := httptest.NewRequest(http.MethodPut, "/api/jobs/job-1/result", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			if w.Code != tt.wantCode {
+				t.Fatalf("状态码 = %d，期望 %d（响应: %s）", w.Code, tt.wantCode, w.Body.String())
+			}
+
+			job, err := base.Get(ctx, "job-1")
+			if err != nil {
+				t.Fatalf("Get: %v", err)
+			}
+			uploads := listUploads(t, dir)
+			if !tt.wantEdited {
+				if job.SubtitlePath != "uploads/job-1.srt" || job.VTTPath != "uploads/job-1.vtt" || job.BilingualSRTPath != "uploads/job-1.bilingual.srt" {
+					t.Fatalf("字幕路径被修改: %s, %s, %s", job.SubtitlePath, job.VTTPath, job.BilingualSRTPath)
+				}
+				if strings.Join(uploads, ",") != strings.Join(tt.wantUploads, ",") {
+					t.Fatalf("uploads = %v，期望 %v", uploads, tt.wantUploads)
+				}
+				srt, err := filestore.ReadAll(ctx, files, "uploads/job-1.srt")
+				if err != nil || !strings.Contains(string(srt), "Helo everyone.") {
+					t.Fatalf("原来的字幕被覆盖: %q (%v)", srt, err)
+				}
+				return
+			}
+
+			if job.SubtitlePath == "uploads/job-1.srt" || job.VTTPath == "uploads/job-1.vtt" || job.BilingualSRTPath != "" {
+				t.Fatalf("字幕路径没有替换: %s, %s, %s", job.SubtitlePath, job.VTTPath, job.BilingualSRTPath)
+			}
+			want := []string{"job-1.mp3", filepath.Base(job.SubtitlePath), filepath.Base(job.VTTPath)}
+			sort.Strings(want)
+			if strings.Join(uploads, ",") != strings.Join(want, ",") {
+				t.Fatalf("uploads = %v，期望 %v（旧字幕应已删除）", uploads, want)
+			}
+			for _, key := range []string{job.SubtitlePath, job.VTTPath} {
+				if !strings.HasPrefix(key, "uploads/job-1.") {
+					t.Fatalf("字幕 key %q 应以任务 ID 开头", key)
+				}
+				data, err := filestore.ReadAll(ctx, files, key)
+				if err != nil || !strings.Contains(string(data), "Welcome back.") {
+					t.Fatalf("%s = %q (%v)，期望修正后的字幕", key, data, err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,28 @@
+package main
+
+import "testing"
+
+func TestIsValidAudioFormat(t *testing.T) {
+	tests := []struct {
+		ext  string
+		want bool
+	}{
+		{".mp3", true},
+		{".MP4", true},
+		{".m4a", true},
+		{".wav", true},
+		{".ogg", true},
+		{".opus", true},
+		{".OPUS", true},
+		{".txt", false},
+		{".exe", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.ext, func(t *testing.T) {
+			if got := isValidAudioFormat(tt.ext); got != tt.want {
+				t.Fatalf("isValidAudioFormat(%q) = %v，期望 %v", tt.ext, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/z-wentao/voiceflow/pkg/config"
+	"github.com/z-wentao/voiceflow/pkg/filestore"
+	"github.com/z-wentao/voiceflow/pkg/models"
+	"github.com/z-wentao/voiceflow/pkg/queue"
+	"github.com/z-wentao/voiceflow/pkg/sources"
+	"github.com/z-wentao/voiceflow/pkg/storage"
+)
+
+// makeTestAudio 用 ffmpeg 生成 1 秒的 MP3（下载和上传流程需要 ffprobe 探测时长），未安装 ffmpeg 时跳过测试
+func makeTestAudio(t *testing.T) []byte {
+	t.Helper()
+	if _, err := exec.LookPath("ffmpeg"); err != nil {
+		t.Skip("未安装 ffmpeg，跳过测试")
+	}
+	if _, err := exec.LookPath("ffprobe"); err != nil {
+		t.Skip("未安装 ffprobe，跳过测试")
+	}
+	path := filepath.Join(t.TempDir(), "tone.mp3")
+	if out, err := exec.Command("ffmpeg", "-f", "lavfi", "-i", "sine=frequency=440:duration=1", "-c:a", "libmp3lame", "-y", path).CombinedOutput(); err != nil {
+		t.Skipf("生成测试音频失败（编码器不可用？）: %v\n%s", err, out)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("读取测试音频失败: %v", err)
+	}
+	return data
+}
+
+// TestIngestFromURLEnqueueFailure 订阅源任务加入队列失败时标记为失败，不会永远停在 pending
+func TestIngestFromURLEnqueueFailure(t *testing.T) {
+	audio := makeTestAudio(t)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "audio/mpeg")
+		w.Write(audio)
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		name       string
+		closed     bool
+		wantErr    bool
+		wantStatus models.JobStatus
+		wantQueued int
+	}{
+		{"加入队列", false, false, models.StatusPending, 1},
+		{"队列已关闭", true, true, models.StatusFailed, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			dir := t.TempDir()
+			cfg := &config.Config{}
+			cfg.Server.MaxUploadSize = 10 << 20
+			cfg.Server.UploadTempDir = t.TempDir()
+			q := queue.NewMemoryQueue(1)
+			if tt.closed {
+				q.Close()
+			}
+			store := storage.NewJobStore(10)
+			app := &App{config: cfg, queue: q, store: store, files: filestore.NewLocalStore(dir)}
+
+			err := app.ingestFromURL(ctx, "job-1", "feed", sources.Item{Title: "第一期", MediaURL: srv.URL + "/ep1.mp3"})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ingestFromURL() err = %v，期望出错: %v", err, tt.wantErr)
+			}
+			job, err := store.Get(ctx, "job-1")
+			if err != nil {
+				t.Fatalf("Get: %v", err)
+			}
+			if job.Status != tt.wantStatus {
+				t.Fatalf("任务状态 = %s，期望 %s", job.Status, tt.wantStatus)
+			}
+			stats, err := q.Stats()
+			if err != nil {
+				t.Fatalf("Stats: %v", err)
+			}
+			if stats.Depth != tt.wantQueued {
+				t.Fatalf("队列中任务数 = %d，期望 %d", stats.Depth, tt.wantQueued)
+			}
+		})
+	}
+}
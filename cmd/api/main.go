@@ -1,29 +1,58 @@
 package main
 
 import (
+    "bytes"
     "context"
+    "crypto/sha256"
+    "encoding/hex"
     "encoding/json"
+    "errors"
     "fmt"
+    "html"
+    "io"
     "log"
+    "log/slog"
+    "mime"
     "net/http"
+    "net/url"
     "os"
+    "os/exec"
     "os/signal"
+    "path"
     "path/filepath"
+    "slices"
     "sort"
+    "strconv"
     "strings"
+    "sync"
     "syscall"
     "time"
+    "unicode/utf8"
 
     "github.com/gin-gonic/gin"
     "github.com/google/uuid"
+    "github.com/gorilla/websocket"
+    "github.com/z-wentao/voiceflow/pkg/api"
+    "github.com/z-wentao/voiceflow/pkg/artifacts"
+    "github.com/z-wentao/voiceflow/pkg/budget"
     "github.com/z-wentao/voiceflow/pkg/config"
+    "github.com/z-wentao/voiceflow/pkg/credentials"
+    "github.com/z-wentao/voiceflow/pkg/events"
+    "github.com/z-wentao/voiceflow/pkg/filestore"
+    "github.com/z-wentao/voiceflow/pkg/janitor"
+    "github.com/z-wentao/voiceflow/pkg/llmtask"
+    "github.com/z-wentao/voiceflow/pkg/logging"
     "github.com/z-wentao/voiceflow/pkg/maimemo_service"
+    "github.com/z-wentao/voiceflow/pkg/middleware"
     "github.com/z-wentao/voiceflow/pkg/models"
     "github.com/z-wentao/voiceflow/pkg/queue"
+    "github.com/z-wentao/voiceflow/pkg/sinks"
+    "github.com/z-wentao/voiceflow/pkg/sources"
     "github.com/z-wentao/voiceflow/pkg/storage"
     "github.com/z-wentao/voiceflow/pkg/templates"
     "github.com/z-wentao/voiceflow/pkg/transcriber"
     "github.com/z-wentao/voiceflow/pkg/vocabulary"
+    "github.com/z-wentao/voiceflow/pkg/webhook"
     "github.com/z-wentao/voiceflow/pkg/worker"
 )
 
@@ -32,10 +61,22 @@ type App struct {
     config         *config.Config
     queue          queue.Queue
     store          storage.Store
+    files          filestore.FileStore     // 媒体和字幕文件存储（本地磁盘或 S3）
     workers        []*worker.Worker
+    workerStates   *worker.Registry        // Worker 当前状态（管理接口查询）
+    janitor        *janitor.Janitor
+    sources        *sources.Scheduler
+    events         *events.Hub
+    broadcaster    *events.Broadcaster     // 任务状态变化广播（WebSocket）
+    rateLimiters   []*middleware.RateLimiter // 按客户端 IP 的限流器（关闭服务时停止清理 goroutine）
     engine         *transcriber.TranscriptionEngine
     extractor      *vocabulary.Extractor
+    translator     *transcriber.Translator
     maimemoService *maimemo_service.Client // Maimemo 微服务客户端
+    credentials    *credentials.Store      // 用户凭证（墨墨 Token），未配置密钥时为 nil
+    sinks          *sinks.Registry         // 单词同步目标
+    budget         *budget.Tracker         // OpenAI 月度预算
+    tasks          *llmtask.Scheduler      // 大模型子任务调度器（支持取消）
 }
 
 func main() {
@@ -43,11 +84,21 @@ func main() {
     if err != nil {
 	log.Fatalf("❌ 加载配置失败: %v", err)
     }
-    log.Println("✓ 配置加载成功")
 
-    if err := os.MkdirAll("uploads", 0755); err != nil {
+    // 结构化日志：标准库 log 的输出也经过同一个 handler
+    logger, err := logging.New(os.Stderr, cfg.Log.Level, cfg.Log.Format)
+    if err != nil {
+	log.Fatalf("❌ 初始化日志失败: %v", err)
+    }
+    slog.SetDefault(logger)
+    slog.Info("✓ 配置加载成功", "log_level", cfg.Log.Level, "log_format", cfg.Log.Format)
+
+    if err := os.MkdirAll(filepath.Join(cfg.FileStore.Local.Dir, "uploads"), 0755); err != nil {
 	log.Fatalf("❌ 创建 uploads 目录失败: %v", err)
     }
+    if err := os.MkdirAll(cfg.Server.UploadTempDir, 0755); err != nil {
+	log.Fatalf("❌ 创建上传临时目录失败: %v", err)
+    }
 
     app := &App{
 	config: cfg,
@@ -55,19 +106,29 @@ func main() {
 
     switch cfg.Storage.Type {
     case "memory":
-	app.store = storage.NewJobStore()
-	log.Println("✓ 使用内存存储")
+	memoryStore := storage.NewJobStore(cfg.Storage.Memory.MaxJobs)
+	memoryStore.SetEvictHandler(app.deleteEvictedJob)
+	app.store = memoryStore
+	if cfg.Storage.Memory.MaxJobs > 0 {
+	    log.Printf("✓ 使用内存存储 (最多保留 %d 个任务)", cfg.Storage.Memory.MaxJobs)
+	} else {
+	    log.Println("✓ 使用内存存储")
+	}
     case "redis":
 	ttl := time.Duration(cfg.Storage.Redis.TTL) * time.Hour
-	app.store, err = storage.NewRedisJobStore(
+	redisStore, err := storage.NewRedisJobStore(
 	    cfg.Storage.Redis.Addr,
 	    cfg.Storage.Redis.Password,
 	    cfg.Storage.Redis.DB,
 	    ttl,
+	    time.Duration(cfg.Storage.Redis.CleanIntervalMinutes)*time.Minute,
 	    )
 	if err != nil {
 	    log.Fatalf("❌ 初始化 Redis 存储失败: %v", err)
 	}
+	redisStore.SetListLimit(cfg.Storage.Redis.ListLimit)
+	redisStore.SetRefreshOnAccess(cfg.Storage.Redis.RefreshOnAccess)
+	app.store = redisStore
 	log.Printf("✓ 使用 Redis 存储 (地址: %s, TTL: %d 小时)", cfg.Storage.Redis.Addr, cfg.Storage.Redis.TTL)
     case "postgres":
 	// 构建 PostgreSQL 连接字符串
@@ -79,16 +140,34 @@ func main() {
 	    cfg.Storage.Postgres.Database,
 	    cfg.Storage.Postgres.SSLMode,
 	    )
-	app.store, err = storage.NewPostgresJobStore(connStr)
+	pgStore, err := storage.NewPostgresJobStore(connStr, !cfg.Storage.Postgres.SkipMigrations)
 	if err != nil {
 	    log.Fatalf("❌ 初始化 PostgreSQL 存储失败: %v", err)
 	}
+	pgStore.SetListLimit(cfg.Storage.Postgres.ListLimit)
+	app.store = pgStore
 	log.Printf("✓ 使用 PostgreSQL 存储 (数据库: %s@%s:%d/%s)",
 	    cfg.Storage.Postgres.User,
 	    cfg.Storage.Postgres.Host,
 	    cfg.Storage.Postgres.Port,
 	    cfg.Storage.Postgres.Database,
 	    )
+    case "sqlite":
+	app.store, err = storage.NewSQLiteJobStore(cfg.Storage.SQLite.Path)
+	if err != nil {
+	    log.Fatalf("❌ 初始化 SQLite 存储失败: %v", err)
+	}
+	log.Printf("✓ 使用 SQLite 存储 (文件: %s)", cfg.Storage.SQLite.Path)
+    case "mongo":
+	app.store, err = storage.NewMongoJobStore(storage.MongoOptions{
+	    URI:       cfg.Storage.Mongo.URI,
+	    Database:  cfg.Storage.Mongo.Database,
+	    ListLimit: cfg.Storage.Mongo.ListLimit,
+	})
+	if err != nil {
+	    log.Fatalf("❌ 初始化 MongoDB 存储失败: %v", err)
+	}
+	log.Printf("✓ 使用 MongoDB 存储 (数据库: %s)", cfg.Storage.Mongo.Database)
     case "hybrid":
 	// 初始化 Redis 存储（热数据）
 	ttl := time.Duration(cfg.Storage.Redis.TTL) * time.Hour
@@ -97,10 +176,13 @@ func main() {
 	    cfg.Storage.Redis.Password,
 	    cfg.Storage.Redis.DB,
 	    ttl,
+	    time.Duration(cfg.Storage.Redis.CleanIntervalMinutes)*time.Minute,
 	    )
 	if err != nil {
 	    log.Fatalf("❌ 初始化 Redis 存储失败: %v", err)
 	}
+	redisStore.SetListLimit(cfg.Storage.Redis.ListLimit)
+	redisStore.SetRefreshOnAccess(cfg.Storage.Redis.RefreshOnAccess)
 
 	// 初始化 PostgreSQL 存储（冷数据）
 	connStr := fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=%s",
@@ -111,13 +193,19 @@ func main() {
 	    cfg.Storage.Postgres.Database,
 	    cfg.Storage.Postgres.SSLMode,
 	    )
-	dbStore, err := storage.NewPostgresJobStore(connStr)
+	dbStore, err := storage.NewPostgresJobStore(connStr, !cfg.Storage.Postgres.SkipMigrations)
 	if err != nil {
 	    log.Fatalf("❌ 初始化 PostgreSQL 存储失败: %v", err)
 	}
+	dbStore.SetListLimit(cfg.Storage.Postgres.ListLimit)
 
-	// 创建混合存储
-	app.store = storage.NewHybridJobStore(redisStore, dbStore)
+	// 创建混合存储（任务列表合并 Redis 与数据库，条数取两者配置中较大的一个）
+	hybridStore := storage.NewHybridJobStore(redisStore, dbStore, storage.HybridOptions{
+	    SyncMaxAttempts: cfg.Storage.Hybrid.SyncMaxAttempts,
+	    DeadLetterPath:  cfg.Storage.Hybrid.DeadLetterPath,
+	})
+	hybridStore.SetListLimit(max(cfg.Storage.Redis.ListLimit, cfg.Storage.Postgres.ListLimit))
+	app.store = hybridStore
 	log.Printf("✓ 使用混合存储 (Redis: %s + PostgreSQL: %s/%s)",
 	    cfg.Storage.Redis.Addr,
 	    cfg.Storage.Postgres.Host,
@@ -127,51 +215,242 @@ func main() {
 	log.Fatalf("❌ 不支持的存储类型: %s", cfg.Storage.Type)
     }
 
+    // 配置了加密密钥时，转录文本、单词和片段结果加密后写入存储
+    if cfg.Storage.EncryptionKey != "" {
+	fieldCipher, err := storage.NewFieldCipher(cfg.Storage.EncryptionKey, cfg.Storage.OldEncryptionKeys...)
+	if err != nil {
+	    log.Fatalf("❌ 初始化存储加密失败: %v", err)
+	}
+	app.store = storage.NewEncryptedStore(app.store, fieldCipher)
+	log.Printf("✓ 已启用存储加密 (密钥 ID: %s, 旧密钥 %d 个)", fieldCipher.KeyID(), len(cfg.Storage.OldEncryptionKeys))
+    }
+
+    // 初始化文件存储（上传的媒体和生成的字幕）
+    switch cfg.FileStore.Type {
+    case "s3":
+	s3 := cfg.FileStore.S3
+	app.files, err = filestore.NewS3Store(context.Background(), filestore.S3Options{
+	    Endpoint:      s3.Endpoint,
+	    Region:        s3.Region,
+	    Bucket:        s3.Bucket,
+	    AccessKey:     s3.AccessKey,
+	    SecretKey:     s3.SecretKey,
+	    UseSSL:        s3.UseSSL,
+	    Prefix:        s3.Prefix,
+	    PresignExpiry: time.Duration(s3.PresignExpiry) * time.Second,
+	})
+	if err != nil {
+	    log.Fatalf("❌ 初始化 S3 文件存储失败: %v", err)
+	}
+	log.Printf("✓ 使用 S3 文件存储 (%s/%s)", s3.Endpoint, s3.Bucket)
+    default:
+	app.files = filestore.NewLocalStore(cfg.FileStore.Local.Dir)
+	log.Printf("✓ 使用本地文件存储 (目录: %s)", cfg.FileStore.Local.Dir)
+    }
+
     // 6. 初始化队列（根据配置选择类型）
     switch cfg.Queue.Type {
     case "memory":
-	app.queue = queue.NewMemoryQueue(cfg.Queue.BufferSize)
-	log.Println("✓ 使用内存队列")
+	memoryQueue := queue.NewMemoryQueue(cfg.Queue.BufferSize)
+	memoryQueue.SetRedeliveryPolicy(cfg.Queue.MaxRedeliveries, app.deadLetterJob)
+	if err := memoryQueue.SetEnqueuePolicy(cfg.Queue.EnqueuePolicy, time.Duration(cfg.Queue.EnqueueTimeout)*time.Second); err != nil {
+	    log.Fatalf("❌ 初始化内存队列失败: %v", err)
+	}
+	app.queue = memoryQueue
+	log.Printf("✓ 使用内存队列 (已满时: %s)", cfg.Queue.EnqueuePolicy)
     case "rabbitmq":
 	app.queue, err = queue.NewRabbitMQQueue(
 	    cfg.Queue.RabbitMQ.URL,
 	    cfg.Queue.RabbitMQ.QueueName,
+	    cfg.Queue.RabbitMQ.Prefetch,
+	    cfg.Queue.RabbitMQ.PublisherConfirms,
 	    )
 	if err != nil {
 	    log.Fatalf("❌ 初始化 RabbitMQ 队列失败: %v", err)
 	}
-	log.Printf("✓ 使用 RabbitMQ 队列 (队列名: %s)", cfg.Queue.RabbitMQ.QueueName)
+	log.Printf("✓ 使用 RabbitMQ 队列 (队列名: %s, 预取数量: %d)", cfg.Queue.RabbitMQ.QueueName, cfg.Queue.RabbitMQ.Prefetch)
+	if cfg.Queue.RabbitMQ.Prefetch < cfg.Transcriber.WorkerPoolSize {
+	    log.Printf("⚠️ RabbitMQ 预取数量 (%d) 小于 Worker 数量 (%d)，部分 Worker 会一直空闲", cfg.Queue.RabbitMQ.Prefetch, cfg.Transcriber.WorkerPoolSize)
+	}
+    case "nats":
+	app.queue, err = queue.NewNatsQueue(queue.NatsOptions{
+	    URL:      cfg.Queue.NATS.URL,
+	    Stream:   cfg.Queue.NATS.Stream,
+	    Subject:  cfg.Queue.NATS.Subject,
+	    Consumer: cfg.Queue.NATS.Consumer,
+	    AckWait:  time.Duration(cfg.Queue.NATS.AckWait) * time.Second,
+	})
+	if err != nil {
+	    log.Fatalf("❌ 初始化 NATS 队列失败: %v", err)
+	}
+	log.Printf("✓ 使用 NATS JetStream 队列 (Stream: %s)", cfg.Queue.NATS.Stream)
+    case "kafka":
+	app.queue, err = queue.NewKafkaQueue(queue.KafkaOptions{
+	    Brokers:      cfg.Queue.Kafka.Brokers,
+	    Topic:        cfg.Queue.Kafka.Topic,
+	    GroupID:      cfg.Queue.Kafka.GroupID,
+	    WriteTimeout: time.Duration(cfg.Queue.Kafka.WriteTimeout) * time.Second,
+	})
+	if err != nil {
+	    log.Fatalf("❌ 初始化 Kafka 队列失败: %v", err)
+	}
+	log.Printf("✓ 使用 Kafka 队列 (Topic: %s, 消费者组: %s)", cfg.Queue.Kafka.Topic, cfg.Queue.Kafka.GroupID)
     default:
 	log.Fatalf("❌ 不支持的队列类型: %s", cfg.Queue.Type)
     }
 
-    // 8. 初始化转换引擎
+    // 7. 初始化 OpenAI 月度预算
+    app.budget = budget.NewTracker(app.store, cfg.OpenAI.MonthlyBudgetUSD)
+    app.budget.SetRates(pricingRates(cfg.OpenAI.Pricing))
+    app.budget.SetFailOpen(cfg.OpenAI.BudgetFailOpen)
+    if app.budget.Enabled() {
+	log.Printf("✓ OpenAI 月度预算: $%.2f", cfg.OpenAI.MonthlyBudgetUSD)
+    }
+
+    // 8. 初始化单词提取器
+    app.extractor = vocabulary.NewExtractor(cfg.OpenAI.APIKey, cfg.OpenAI.BaseURL)
+    app.tasks = llmtask.NewScheduler(60 * time.Second)
+    log.Println("✓ 单词提取器初始化成功")
+
+    // 字幕翻译与单词提取共用 OpenAI 客户端
+    app.translator = transcriber.NewTranslator(app.extractor.Client(), cfg.Transcriber.TargetLanguage)
+    var engineTranslator *transcriber.Translator
+    if cfg.Transcriber.Bilingual {
+	engineTranslator = app.translator
+	log.Printf("✓ 自动生成双语字幕已开启 (目标语言: %s)", cfg.Transcriber.TargetLanguage)
+    }
+
+    // 9. 初始化转换引擎
+    // 存储支持时保存已完成片段的结果，服务崩溃或重启后继续转录未完成的片段
+    var segmentStore transcriber.SegmentStore
+    if store, ok := app.store.(transcriber.SegmentStore); ok {
+	segmentStore = store
+    }
     app.engine = transcriber.NewTranscriptionEngine(
 	cfg.OpenAI.APIKey,
 	cfg.Transcriber.SegmentConcurrency,
 	cfg.Transcriber.SegmentDuration,
+	transcriber.EngineOptions{
+	    BaseURL:        cfg.OpenAI.BaseURL,
+	    WordTimestamps: cfg.Transcriber.WordTimestamps,
+	    MaxCues:        cfg.Transcriber.MaxCues,
+	    MaxLineChars:   cfg.Transcriber.MaxLineChars,
+	    OverlapSeconds: cfg.Transcriber.SegmentOverlap,
+	    Prompt:         cfg.Transcriber.Prompt,
+	    Temperature:    cfg.Transcriber.Temperature,
+	    Translator:     engineTranslator,
+
+	    WhisperTimeout:   time.Duration(cfg.Transcriber.WhisperTimeoutSeconds) * time.Second,
+	    TimeoutPerMinute: time.Duration(cfg.Transcriber.WhisperSecondsPerMinute) * time.Second,
+	    SegmentStore:     segmentStore,
+	    SegmentEncoding: transcriber.SegmentEncoding{
+		Bitrate:    cfg.Transcriber.SegmentBitrate,
+		SampleRate: cfg.Transcriber.SegmentSampleRate,
+		Channels:   cfg.Transcriber.SegmentChannels,
+	    },
+	},
 	)
     log.Println("✓ 转换引擎初始化成功")
 
-    // 9. 初始化单词提取器
-    app.extractor = vocabulary.NewExtractor(cfg.OpenAI.APIKey)
-    log.Println("✓ 单词提取器初始化成功")
-
     // 10. 初始化 Maimemo 微服务客户端
     app.maimemoService = maimemo_service.NewClient(cfg.MaimemoService.URL)
+    app.maimemoService.SetLimits(maimemo_service.Limits{
+	MaxWordsPerRequest: cfg.MaimemoService.MaxWordsPerRequest,
+	MaxNotepadBytes:    cfg.MaimemoService.MaxNotepadBytes,
+	RequestInterval:    time.Duration(cfg.MaimemoService.RequestIntervalMS) * time.Millisecond,
+    })
     log.Printf("✓ Maimemo 微服务客户端初始化成功 (地址: %s)", cfg.MaimemoService.URL)
 
+    if cfg.MaimemoService.TokenKey != "" {
+	app.credentials, err = credentials.New(app.store, cfg.MaimemoService.TokenKey)
+	if err != nil {
+	    log.Fatalf("初始化凭证存储失败: %v", err)
+	}
+	log.Println("✓ 墨墨 Token 将加密保存在服务端")
+    }
+
+    app.sinks, err = newVocabularySinks(cfg.VocabularySinks, app.maimemoService)
+    if err != nil {
+	log.Fatalf("❌ 初始化单词同步目标失败: %v", err)
+    }
+    log.Printf("✓ 单词同步目标: %d 个", len(app.sinks.List()))
+
     // 11. 启动 Worker 池
     workerPoolSize := cfg.Transcriber.WorkerPoolSize
     app.workers = make([]*worker.Worker, workerPoolSize)
+    app.workerStates = worker.NewRegistry()
+    app.events = events.NewHub()
+    app.broadcaster = events.NewBroadcaster(app.events)
+    app.tasks.OnFinish(app.publishSubTaskEvent)
+    notifier := webhook.NewNotifier(cfg.Webhook.URL, cfg.Server.PublicBaseURL, time.Duration(cfg.Webhook.Timeout)*time.Second, app.files)
+    if notifier.Enabled() {
+	log.Printf("✓ 任务结束通知: %s", cfg.Webhook.URL)
+    }
+
+    // 临时错误的自动重试策略（max_retries 为负数时不重试）
+    retryPolicy := worker.RetryPolicy{
+	MaxRetries: max(cfg.Queue.MaxRetries, 0),
+	BaseDelay:  time.Duration(cfg.Queue.RetryDelaySeconds) * time.Second,
+	MaxDelay:   10 * time.Minute,
+    }
+    if retryPolicy.MaxRetries > 0 {
+	log.Printf("✓ 临时错误自动重试: 最多 %d 次, 首次等待 %s", retryPolicy.MaxRetries, retryPolicy.BaseDelay)
+    }
 
     log.Printf("🚀 正在启动 %d 个 Worker 实例...", workerPoolSize)
     for i := 0; i < workerPoolSize; i++ {
-	app.workers[i] = worker.NewWorker(i+1, app.queue, app.store, app.engine)
+	app.workers[i] = worker.NewWorker(i+1, app.queue, app.store, app.files, cfg.Server.UploadTempDir, app.engine, app.budget, notifier, app.events, app.workerStates)
+	app.workers[i].SetRetryPolicy(retryPolicy)
 	app.workers[i].Start()
     }
 
-    // 12. 启动 HTTP 服务器
+    // 启动恢复：上次退出时未完成的任务重新入队（内存队列的消息随进程丢失）
+    if cfg.Queue.RecoverOnStartup {
+	recovered, err := worker.Recover(context.Background(), app.store, app.queue, cfg.Queue.MaxRecoveryAttempts)
+	if err != nil {
+	    log.Printf("⚠️ 启动恢复失败: %v", err)
+	} else {
+	    log.Printf("✓ 启动恢复: 重新入队 %d 个任务, 等待重试 %d 个, 标记失败 %d 个, 入队失败 %d 个", recovered.Requeued, recovered.Delayed, recovered.Failed, recovered.Skipped)
+	}
+    }
+
+    // 12. 启动后台清理器（按保留策略删除过期文件和任务）
+    if cfg.Retention.MediaAfterDays > 0 || cfg.Retention.JobsAfterDays > 0 || cfg.Retention.DeletedAfterDays > 0 || cfg.Retention.ArchiveAfterDays > 0 {
+	app.janitor = janitor.NewJanitor(app.store, app.files, janitor.Options{
+	    MediaAfter:       time.Duration(cfg.Retention.MediaAfterDays) * 24 * time.Hour,
+	    JobsAfter:        time.Duration(cfg.Retention.JobsAfterDays) * 24 * time.Hour,
+	    DeletedAfter:     time.Duration(cfg.Retention.DeletedAfterDays) * 24 * time.Hour,
+	    KeepDeletedFiles: cfg.Retention.KeepFilesOnDelete,
+	    ArchiveAfter:     time.Duration(cfg.Retention.ArchiveAfterDays) * 24 * time.Hour,
+	    Interval:         time.Duration(cfg.Retention.IntervalMinutes) * time.Minute,
+	    DryRun:           cfg.Retention.DryRun,
+	})
+	app.janitor.Start()
+	log.Printf("✓ 后台清理器已启动 (原始媒体保留 %d 天, 任务保留 %d 天, 删除的任务保留 %d 天, 完成 %d 天后归档, dry-run: %v)",
+	    cfg.Retention.MediaAfterDays, cfg.Retention.JobsAfterDays, cfg.Retention.DeletedAfterDays, cfg.Retention.ArchiveAfterDays, cfg.Retention.DryRun)
+    }
+
+    // 一次性回填单词索引（多实例部署时只有一个实例执行）
+    go app.backfillWordIndex()
+
+    // 13. 启动播客订阅源调度器
+    sourceConfigs := make([]sources.Config, 0, len(cfg.Sources))
+    for _, src := range cfg.Sources {
+	sourceConfigs = append(sourceConfigs, sources.Config{
+	    Name:     src.Name,
+	    URL:      src.URL,
+	    Interval: time.Duration(src.IntervalMinutes) * time.Minute,
+	    MaxItems: src.MaxItems,
+	    Enabled:  !src.Disabled,
+	})
+    }
+    app.sources = sources.NewScheduler(app.store, app.ingestFromURL, sourceConfigs)
+    if len(sourceConfigs) > 0 {
+	app.sources.Start()
+	log.Printf("✓ 播客订阅源调度器已启动 (%d 个订阅源)", len(sourceConfigs))
+    }
+
+    // 14. 启动 HTTP 服务器
     router := app.setupRouter()
     port := fmt.Sprintf(":%d", cfg.Server.Port)
 
@@ -190,7 +469,7 @@ func main() {
     log.Printf("   - 存储类型: %s", cfg.Storage.Type)
     log.Printf("   - Maimemo 微服务: %s", cfg.MaimemoService.URL)
 
-    // 13. 优雅关闭（面试亮点）
+    // 15. 优雅关闭（面试亮点）
     // 在 goroutine 中启动服务器
     go func() {
 	if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
@@ -211,22 +490,59 @@ func main() {
     ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
     defer cancel()
 
+    // WebSocket 连接已被劫持，不受 Shutdown 管理，先断开
+    app.broadcaster.Stop()
+
     log.Println("📍 停止接受新的 HTTP 请求...")
     if err := srv.Shutdown(ctx); err != nil {
 	log.Printf("⚠️  HTTP 服务器强制关闭: %v", err)
     } else {
 	log.Println("✓ HTTP 服务器已优雅关闭（所有请求已处理完成）")
     }
+    for _, limiter := range app.rateLimiters {
+	limiter.Stop()
+    }
+
+    // 2. 停止后台清理器和所有 Worker（不再处理新的队列任务），等待进行中的任务完成
+    if app.janitor != nil {
+	app.janitor.Stop()
+    }
+    app.sources.Stop()
+    log.Println("📍 停止 Worker 池，等待进行中的任务完成...")
+    var wg sync.WaitGroup
+    for _, w := range app.workers {
+	wg.Add(1)
+	go func(w *worker.Worker) {
+	    defer wg.Done()
+	    w.Stop()
+	}(w)
+    }
+    drained := make(chan struct{})
+    go func() {
+	wg.Wait()
+	close(drained)
+    }()
 
-    // 2. 停止所有 Worker（不再处理新的队列任务）
-    log.Println("📍 停止 Worker 池...")
-    for i, w := range app.workers {
-	log.Printf("   正在停止 Worker #%d...", i+1)
-	w.Stop()
+    drainTimeout := time.Duration(cfg.Transcriber.DrainTimeout) * time.Second
+    select {
+    case <-drained:
+	log.Println("✓ Worker 已停止，进行中的任务已全部完成")
+    case <-time.After(drainTimeout):
+	// 超时：取消剩余任务（重置为 pending 并退回队列），再给它们一点时间写入状态
+	log.Printf("⚠️  等待任务完成超时 (%v)，取消剩余任务并重新入队", drainTimeout)
+	for _, w := range app.workers {
+	    w.Abort()
+	}
+	select {
+	case <-drained:
+	    log.Println("✓ Worker 已停止，进行中的任务已全部完成")
+	case <-time.After(10 * time.Second):
+	    log.Println("⚠️  仍有任务未结束，强制关闭")
+	}
     }
-    log.Println("✓ 所有 Worker 已停止")
 
-    // 3. 关闭队列和存储
+    // 3. 取消进行中的大模型子任务，关闭队列和存储
+    app.tasks.CancelAll()
     log.Println("📍 关闭队列和存储...")
     app.queue.Close()
     app.store.Close()
@@ -235,6 +551,190 @@ func main() {
 }
 
 
+// budgetRejection 预算检查失败时的状态码和提示：预算用尽为 402，无法读取本月费用为 503
+func budgetRejection(err error) (int, string) {
+    if errors.Is(err, budget.ErrBudgetExhausted) {
+	return http.StatusPaymentRequired, fmt.Sprintf("%s，请下月再试或联系管理员提高预算", err)
+    }
+    return http.StatusServiceUnavailable, fmt.Sprintf("%s，请稍后重试", err)
+}
+
+// ingestFromURL 下载订阅源中的一期节目并创建转录任务
+func (app *App) ingestFromURL(ctx context.Context, jobID, source string, item sources.Item) error {
+    if err := app.budget.Check(ctx); err != nil {
+	return err
+    }
+
+    req, err := http.NewRequestWithContext(ctx, http.MethodGet, item.MediaURL, nil)
+    if err != nil {
+	return fmt.Errorf("创建下载请求失败: %w", err)
+    }
+    resp, err := http.DefaultClient.Do(req)
+    if err != nil {
+	return fmt.Errorf("下载媒体失败: %w", err)
+    }
+    defer resp.Body.Close()
+
+    if resp.StatusCode != http.StatusOK {
+	return fmt.Errorf("下载媒体失败: 状态码 %d", resp.StatusCode)
+    }
+    maxSize := app.config.Server.MaxUploadSize
+    if resp.ContentLength > maxSize {
+	return fmt.Errorf("文件太大: %d 字节 (上限 %d)", resp.ContentLength, maxSize)
+    }
+
+    ext := mediaExtension(item.MediaURL, resp.Header.Get("Content-Type"))
+    if !isValidAudioFormat(ext) {
+	return fmt.Errorf("不支持的文件格式: %q", ext)
+    }
+
+    savePath := "uploads/" + jobID + ext
+    written, _, duration, err := app.saveStream(ctx, resp.Body, savePath)
+    if err != nil {
+	return fmt.Errorf("保存媒体失败: %w", err)
+    }
+    log.Printf("✓ 文件已下载: %s (%.2f MB)", savePath, float64(written)/1024/1024)
+
+    title := item.Title
+    if title == "" {
+	title = strings.TrimSuffix(filepath.Base(savePath), ext)
+    }
+    job := &models.TranscriptionJob{
+	JobID:     jobID,
+	Filename:  strings.NewReplacer("/", "_", "\\", "_").Replace(title) + ext,
+	FilePath:  savePath,
+	Status:    models.StatusPending,
+	Progress:  0,
+	Duration:  duration,
+	FileSize:  written,
+	Source:    source,
+	CreatedAt: time.Now(),
+    }
+
+    if err := app.store.Save(ctx, job); err != nil {
+	app.files.Delete(ctx, savePath)
+	return fmt.Errorf("保存任务失败: %w", err)
+    }
+    app.events.Publish(jobEvent(job))
+    if err := queue.EnqueueContext(ctx, app.queue, job); err != nil {
+	job.Status = models.StatusFailed
+	job.Error = "任务加入队列失败"
+	job.LastUpdated = time.Now()
+	if err := app.store.Save(context.WithoutCancel(ctx), job); err != nil {
+	    log.Printf("❌ 保存任务失败: %v", err)
+	}
+	app.events.Publish(jobEvent(job))
+	return fmt.Errorf("任务加入队列失败: %w", err)
+    }
+
+    log.Printf("✓ 任务已加入队列: %s (来源: %s)", jobID, source)
+    return nil
+}
+
+// mediaExtension 根据 URL 路径或 Content-Type 推断媒体文件扩展名
+func mediaExtension(rawURL, contentType string) string {
+    if u, err := url.Parse(rawURL); err == nil {
+	if ext := strings.ToLower(filepath.Ext(u.Path)); isValidAudioFormat(ext) {
+	    return ext
+	}
+    }
+    if mediaType, _, err := mime.ParseMediaType(contentType); err == nil {
+	if exts, err := mime.ExtensionsByType(mediaType); err == nil {
+	    for _, ext := range exts {
+		if isValidAudioFormat(ext) {
+		    return ext
+		}
+	    }
+	}
+    }
+    return ""
+}
+
+// errFileTooLarge 上传或下载的文件超过 MaxUploadSize
+var errFileTooLarge = errors.New("文件太大")
+
+// errUnreadableMedia 上传或下载的文件无法被 ffprobe 解析（损坏或不是音频/视频文件）
+var errUnreadableMedia = errors.New("无法识别的音频/视频文件")
+
+// multipartOverhead multipart 边界、表单头等额外字节的余量
+const multipartOverhead = 1 << 20
+
+// saveStream 将数据流保存到文件存储的 key 下，同时计算 SHA-256 并读取媒体时长（ffprobe 无法解析时返回 errUnreadableMedia）
+// 先写入临时目录，完整写入后再移动到本地存储（或上传到对象存储）；超过 MaxUploadSize、
+// 读取失败（如客户端中途断开）时删除临时文件，存储中不会残留半个文件
+func (app *App) saveStream(ctx context.Context, r io.Reader, key string) (int64, string, float64, error) {
+    maxSize := app.config.Server.MaxUploadSize
+
+    tmp, err := os.CreateTemp(app.config.Server.UploadTempDir, "upload-*.part")
+    if err != nil {
+	return 0, "", 0, fmt.Errorf("创建临时文件失败: %w", err)
+    }
+    tmpPath := tmp.Name()
+    success := false
+    defer func() {
+	if !success {
+	    os.Remove(tmpPath)
+	}
+    }()
+
+    h := sha256.New()
+    written, err := io.Copy(io.MultiWriter(tmp, h), io.LimitReader(r, maxSize+1))
+    if closeErr := tmp.Close(); err == nil {
+	err = closeErr
+    }
+    if err != nil {
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+	    return written, "", 0, errFileTooLarge
+	}
+	return written, "", 0, fmt.Errorf("写入文件失败: %w", err)
+    }
+    if written > maxSize {
+	return written, "", 0, errFileTooLarge
+    }
+
+    // 保存前用 ffprobe 读取时长：无法解析的文件直接拒绝，不必等到 Worker 处理时才失败
+    duration, err := transcriber.ProbeDuration(tmpPath)
+    if err != nil {
+	log.Printf("⚠️ 读取媒体时长失败: %v", err)
+	return written, "", 0, errUnreadableMedia
+    }
+
+    contentType := mime.TypeByExtension(strings.ToLower(filepath.Ext(key)))
+    if err := filestore.PutFile(ctx, app.files, key, tmpPath, contentType); err != nil {
+	return written, "", 0, fmt.Errorf("保存文件失败: %w", err)
+    }
+    success = true
+
+    return written, hex.EncodeToString(h.Sum(nil)), duration, nil
+}
+
+// claimContentHash 为新任务登记文件哈希
+// 返回 nil 表示登记成功（应创建新任务）；否则返回已存在的同内容任务。
+// 已有任务被删除或失败时，清除旧登记后重新抢占，允许重新上传
+func (app *App) claimContentHash(ctx context.Context, hash, jobID string) (*models.TranscriptionJob, error) {
+    for attempt := 0; attempt < 3; attempt++ {
+	existingID, created, err := app.store.SetHashIfAbsent(ctx, hash, jobID)
+	if err != nil {
+	    return nil, err
+	}
+	if created {
+	    return nil, nil
+	}
+
+	existing, err := app.store.Get(ctx, existingID)
+	if err == nil && existing.Status != models.StatusFailed && existing.DeletedAt.IsZero() {
+	    return existing, nil
+	}
+
+	if err := app.store.DeleteHash(ctx, hash, existingID); err != nil {
+	    return nil, err
+	}
+    }
+
+    return nil, fmt.Errorf("登记文件哈希失败: 并发冲突")
+}
+
 // Whisper API 支持的格式：mp3, mp4, mpeg, mpga, m4a, wav, webm, flac, aac
 func isValidAudioFormat(ext string) bool {
     validFormats := map[string]bool{
@@ -247,6 +747,8 @@ func isValidAudioFormat(ext string) bool {
 	".webm": true,
 	".flac": true,
 	".aac":  true,
+	".ogg":  true, // Whisper 不支持，转录前转码为 MP3
+	".opus": true,
     }
 
     // 转为小写比较
@@ -254,185 +756,2441 @@ func isValidAudioFormat(ext string) bool {
     return validFormats[ext]
 }
 
-// setupRouter 设置路由
-func (app *App) setupRouter() *gin.Engine {
-    r := gin.Default()
-
-    // 静态文件
-    r.StaticFile("/", "./web/index.html")
-    r.Static("/uploads", "./uploads")
+// userOptions 由配置生成多用户中间件的参数
+func userOptions(cfg config.UsersConfig, public []string) middleware.UserOptions {
+    opts := middleware.UserOptions{
+	Header: cfg.Header,
+	Admins: make(map[string]bool, len(cfg.Admins)),
+	Public: public,
+    }
+    for _, k := range cfg.Keys {
+	opts.Keys = append(opts.Keys, middleware.UserKey{
+	    Key:  k.Key,
+	    User: middleware.User{ID: k.UserID, Admin: k.Admin},
+	})
+    }
+    for _, id := range cfg.Admins {
+	opts.Admins[id] = true
+    }
+    return opts
+}
 
-    // API 路由
-    api := r.Group("/api")
-    {
-	api.GET("/ping", app.handlePing)
+// adminPathPrefix 管理接口的路径前缀
+// 配置了 admin_api_key 时整个前缀由该 Key 单独保护（middleware.APIKey），不再要求 API 鉴权和用户身份：
+// 请求只能携带一个 Key，否则用户 Key 与管理 Key 不同时无法访问
+const adminPathPrefix = "/api/admin/"
 
-	// HTMX 路由（返回 HTML 片段）
-	api.POST("/upload", app.handleUpload)
-	api.GET("/jobs", app.handleListJobs)
-	api.GET("/jobs/history", app.handleListJobsHistory)
-	api.GET("/jobs/count", app.handleJobsCount)
-	api.GET("/jobs/:job_id", app.handleGetJob)
-	api.GET("/jobs/:job_id/details", app.handleJobDetails)
-	api.GET("/jobs/:job_id/download", app.handleDownloadResult)
-	api.GET("/jobs/:job_id/download-subtitle", app.handleDownloadSubtitle)
-	api.GET("/jobs/:job_id/subtitle.vtt", app.handleSubtitleVTT)
-	api.DELETE("/jobs/:job_id", app.handleDeleteJob)
-	api.POST("/jobs/:job_id/extract-vocabulary", app.handleExtractVocabulary)
-	api.POST("/jobs/:job_id/sync-to-maimemo", app.handleSyncToMaimemo)
-	api.POST("/maimemo/list-notepads", app.handleListNotepads)
+// authOptions 由配置生成 API 鉴权中间件的参数
+// 多用户的 Key 同样允许通过（再由多用户中间件识别用户）；配置了 admin_api_key 的管理接口由该 Key 单独保护
+func authOptions(cfg *config.Config) middleware.AuthOptions {
+    opts := middleware.AuthOptions{
+	Keys:   cfg.Auth.Keys(),
+	Public: []string{"/api/ping", "/api/health"},
+    }
+    for _, k := range cfg.Users.Keys {
+	opts.Keys = append(opts.Keys, k.Key)
     }
+    if cfg.Server.AdminAPIKey != "" {
+	opts.Public = append(opts.Public, adminPathPrefix)
+    }
+    return opts
+}
 
-    return r
+// userPublicPaths 不需要识别用户的路径
+func userPublicPaths(cfg *config.Config) []string {
+    public := []string{"/api/ping", "/api/health", "/api/openapi.json"}
+    if cfg.Server.AdminAPIKey != "" {
+	public = append(public, adminPathPrefix)
+    }
+    return public
 }
 
-func (app *App) handlePing(c *gin.Context) {
-    c.JSON(http.StatusOK, gin.H{
-	"message": "pong",
-	"version": "0.3.0-htmx",
+// rateLimited 开启限流时在 handler 之前加上按客户端 IP 的令牌桶限流（每个接口独立计数）
+func (app *App) rateLimited(rule config.RateLimitRule, handler gin.HandlerFunc) []gin.HandlerFunc {
+    if !app.config.RateLimit.Enabled {
+	return []gin.HandlerFunc{handler}
+    }
+    limiter := middleware.NewRateLimiter(middleware.RateLimitOptions{
+	PerMinute: rule.PerMinute,
+	Burst:     rule.Burst,
+	IdleTTL:   time.Duration(app.config.RateLimit.IdleMinutes) * time.Minute,
     })
+    app.rateLimiters = append(app.rateLimiters, limiter)
+    return []gin.HandlerFunc{limiter.Handler(), handler}
 }
 
-// handleUpload 处理文件上传（返回 HTML）
-func (app *App) handleUpload(c *gin.Context) {
-    file, err := c.FormFile("audio")
+// getJob 读取当前用户可以访问的任务
+// 其他用户的任务与不存在的任务返回相同的错误，不暴露任务是否存在
+func (app *App) getJob(c *gin.Context, jobID string) (*models.TranscriptionJob, error) {
+    job, err := app.store.Get(c.Request.Context(), jobID)
     if err != nil {
-	c.Data(http.StatusBadRequest, "text/html", []byte(`
-	    <div class="bg-red-50 text-red-800 p-3 rounded-lg text-sm">
-	    ❌ 请上传文件
-	    </div>
-	    `))
-	return
+	return nil, err
+    }
+    if !job.DeletedAt.IsZero() {
+	return nil, fmt.Errorf("任务已删除: %s", jobID)
     }
+    if user := middleware.CurrentUser(c); user.Restricted() && job.UserID != user.ID {
+	return nil, fmt.Errorf("任务不存在: %s", jobID)
+    }
+    return job, nil
+}
 
-    ext := filepath.Ext(file.Filename)
-    if !isValidAudioFormat(ext) {
-	c.Data(http.StatusBadRequest, "text/html", []byte(fmt.Sprintf(`
-	    <div class="bg-red-50 text-red-800 p-3 rounded-lg text-sm">
-	    ❌ 不支持的文件格式 %s
-	    </div>
-	    `, ext)))
-	return
+// listJobs 列出当前用户可以访问的任务：普通用户只列出自己的任务（最多 limit 个，0 表示不限制），
+// 未启用多用户或管理员使用 list 列出所有任务
+func (app *App) listJobs(c *gin.Context, list func(context.Context) ([]*models.TranscriptionJob, error), limit int) ([]*models.TranscriptionJob, error) {
+    if user := middleware.CurrentUser(c); user.Restricted() {
+	return app.store.ListForUser(c.Request.Context(), user.ID, limit)
     }
+    return list(c.Request.Context())
+}
 
-    if file.Size > app.config.Server.MaxUploadSize {
-	c.Data(http.StatusBadRequest, "text/html", []byte(fmt.Sprintf(`
-	    <div class="bg-red-50 text-red-800 p-3 rounded-lg text-sm">
-	    ❌ 文件太大，最大 %.0f MB
-	    </div>
-	    `, float64(app.config.Server.MaxUploadSize)/1024/1024)))
-	return
+// ownJobs 过滤出当前用户可以访问的任务（用于搜索等没有按用户查询的接口）
+func ownJobs(c *gin.Context, jobs []*models.TranscriptionJob) []*models.TranscriptionJob {
+    user := middleware.CurrentUser(c)
+    if !user.Restricted() {
+	return jobs
     }
+    owned := make([]*models.TranscriptionJob, 0, len(jobs))
+    for _, job := range jobs {
+	if job.UserID == user.ID {
+	    owned = append(owned, job)
+	}
+    }
+    return owned
+}
 
-    jobID := uuid.New().String()
-    filename := jobID + ext
-    savePath := filepath.Join("uploads", filename)
+// setupRouter 设置路由
+func (app *App) setupRouter() *gin.Engine {
+    r, spec := app.buildRouter()
 
-    if err := c.SaveUploadedFile(file, savePath); err != nil {
-	c.Data(http.StatusInternalServerError, "text/html", []byte(`
-	    <div class="bg-red-50 text-red-800 p-3 rounded-lg text-sm">
-	    ❌ 保存文件失败
-	    </div>
-	    `))
-	return
+    // 绕过 routes 直接注册的接口不会出现在文档中
+    for _, route := range spec.Missing(r.Routes(), "/api") {
+	log.Printf("⚠️ 路由未记录到 OpenAPI 文档: %s", route)
     }
 
-    log.Printf("✓ 文件已保存: %s (%.2f MB)", filename, float64(file.Size)/1024/1024)
+    return r
+}
+
+// buildRouter 注册所有路由，同时返回记录 /api 接口的 OpenAPI 文档
+func (app *App) buildRouter() (*gin.Engine, *api.Spec) {
+    r := gin.Default()
+    r.MaxMultipartMemory = app.config.Server.MaxMultipartMemory
 
-    job := &models.TranscriptionJob{
-	JobID:     jobID,
-	Filename:  file.Filename,
-	FilePath:  savePath,
-	Status:    models.StatusPending,
-	Progress:  0,
-	CreatedAt: time.Now(),
+    // 只信任配置的反向代理设置的 X-Forwarded-For，否则客户端可以伪造 IP 绕过限流
+    if err := r.SetTrustedProxies(app.config.Server.TrustedProxies); err != nil {
+	log.Fatalf("❌ server.trusted_proxies 无效: %v", err)
     }
 
-    if err := app.store.Save(job); err != nil {
-	c.Data(http.StatusInternalServerError, "text/html", []byte(`
-	    <div class="bg-red-50 text-red-800 p-3 rounded-lg text-sm">
-	    ❌ 保存任务失败
-	    </div>
-	    `))
-	return
-    }
+    // 跨域（全局注册，预检请求没有对应路由）
+    cors := app.config.Server.CORS
+    r.Use(middleware.CORS("/api", middleware.CORSOptions{
+	AllowedOrigins:   cors.AllowedOrigins,
+	AllowedMethods:   cors.AllowedMethods,
+	AllowedHeaders:   cors.AllowedHeaders,
+	AllowCredentials: cors.AllowCredentials,
+	MaxAge:           cors.MaxAge,
+    }))
 
-    if err := app.queue.Enqueue(job); err != nil {
-	c.Data(http.StatusInternalServerError, "text/html", []byte(`
-	    <div class="bg-red-50 text-red-800 p-3 rounded-lg text-sm">
-	    ❌ 任务加入队列失败
-	    </div>
-	    `))
-	return
+    // gzip 压缩（在 CORS 之后注册，预检请求不经过压缩）；音视频按 Content-Type 跳过
+    if !app.config.Server.DisableCompression {
+	r.Use(middleware.Gzip())
     }
 
-    log.Printf("✓ 任务已加入队列: %s", jobID)
+    // API 鉴权：开启后 /api 下的接口（存活检查和健康检查除外）都需要携带有效的 Key（默认关闭）
+    if app.config.Auth.Enabled {
+	r.Use(middleware.Auth("/api", authOptions(app.config)))
+    }
 
-    // 返回任务卡片 HTML
-    html := templates.RenderTaskCard(job)
-    c.Data(http.StatusOK, "text/html", []byte(html))
-}
+    // 多用户：识别用户后，每个用户只能访问自己的任务（未配置时不启用）
+    // 管理接口配置了单独的 admin_api_key 时由该 Key 保护，不要求用户身份
+    r.Use(middleware.Users("/api", userOptions(app.config.Users, userPublicPaths(app.config))))
 
-// handleListJobs 列出所有任务（返回 HTML）
-func (app *App) handleListJobs(c *gin.Context) {
-    jobs, err := app.store.List()
-    if err != nil {
-	c.Data(http.StatusInternalServerError, "text/html", []byte(`
-	    <div class="text-center py-16 text-red-400">
-	    <p class="text-5xl mb-3">❌</p>
-	    <p class="text-lg">获取任务列表失败</p>
-	    </div>
-	    `))
-	return
-    }
+    // 静态文件（上传的媒体不作为静态目录提供，经过鉴权和用户隔离后由 /api/jobs/:job_id/media 返回）
+    r.StaticFile("/", "./web/index.html")
 
-    // 按创建时间倒序排序
-    sort.Slice(jobs, func(i, j int) bool {
-	return jobs[i].CreatedAt.After(jobs[j].CreatedAt)
-    })
+    // Prometheus 指标（不在 /api 下，不经过 API 鉴权和多用户；配置 server.admin_api_key 时需要携带该 Key）
+    r.GET("/metrics", middleware.APIKey(app.config.Server.AdminAPIKey), app.handleMetrics)
 
-    html := templates.RenderTasksList(jobs)
-    c.Data(http.StatusOK, "text/html", []byte(html))
-}
+    // API 路由（通过 routes 注册，同时记录到 OpenAPI 文档 /api/openapi.json）
+    spec := api.NewSpec("VoiceFlow API", apiVersion)
+    spec.Enum(models.JobStatus(""), models.StatusPending, models.StatusProcessing, models.StatusCompleted, models.StatusFailed)
 
-func (app *App) handleListJobsHistory(c *gin.Context) {
-    jobs, err := app.store.ListAll()
-    if err != nil {
-	c.Data(http.StatusInternalServerError, "text/html", []byte(`
-	    <div class="text-center py-16 text-red-400">
-	    <p class="text-5xl mb-3">❌</p>
-	    <p class="text-lg">获取任务历史失败</p>
-	    </div>
-	    `))
-	return
+    notFound := api.HTML(http.StatusNotFound, "任务不存在")
+    jobNotFound := api.Error(http.StatusNotFound, "任务不存在")
+    levelParams := []api.Param{
+	{Name: "min_level", In: api.InQuery, Enum: vocabulary.Levels, Description: "最低 CEFR 等级（也可作为表单字段提交）"},
+	{Name: "max_level", In: api.InQuery, Enum: vocabulary.Levels, Description: "最高 CEFR 等级（也可作为表单字段提交）"},
+	{Name: "exclude_known", In: api.InQuery, Description: "非空时排除其他任务中已提取过的单词（也可作为表单字段提交）"},
+    }
+    maimemoParams := []api.Param{
+	api.FormField("token", false, "墨墨 API Token（同步到 maimemo 类型的目标时使用，为空时使用服务端保存的 Token）"),
+	api.FormField("notepad_id", false, "墨墨云词本 ID（同步到 maimemo 类型的目标时必填）"),
     }
-    // 按创建时间倒序排序
-    sort.Slice(jobs, func(i, j int) bool {
-	return jobs[i].CreatedAt.After(jobs[j].CreatedAt)
-    })
 
-    html := templates.RenderTasksList(jobs)
-    c.Data(http.StatusOK, "text/html", []byte(html))
+    routes := spec.Wrap(r.Group("/api"))
+    {
+	routes.GET("/openapi.json", api.Operation{
+	    Summary:   "OpenAPI 文档",
+	    Tags:      []string{"system"},
+	    Responses: []api.Response{api.JSON(http.StatusOK, "OpenAPI 3 文档", nil)},
+	}, spec.Handler())
+	routes.GET("/ping", api.Operation{
+	    Summary:   "存活检查",
+	    Tags:      []string{"system"},
+	    Responses: []api.Response{api.JSON(http.StatusOK, "服务版本", pingResponse{})},
+	}, app.handlePing)
+	routes.GET("/health", api.Operation{
+	    Summary: "深度健康检查",
+	    Tags:    []string{"system"},
+	    Responses: []api.Response{
+		api.JSON(http.StatusOK, "所有组件正常", healthResponse{}),
+		api.JSON(http.StatusServiceUnavailable, "存在异常组件", healthResponse{}),
+	    },
+	}, app.handleHealth)
+	routes.GET("/stats", api.Operation{
+	    Summary:   "统计信息",
+	    Tags:      []string{"system"},
+	    Responses: []api.Response{api.JSON(http.StatusOK, "本月预算使用情况", statsResponse{})},
+	}, app.handleStats)
+	routes.GET("/stats/cost", api.Operation{
+	    Summary:     "任务费用统计",
+	    Description: "汇总日期范围内创建的任务的 OpenAI 费用估算（转录、字幕翻译、单词提取）；多用户模式下普通用户只统计本人的任务",
+	    Tags:        []string{"system"},
+	    Params: []api.Param{
+		api.Query("from", "开始日期（YYYY-MM-DD，服务器时区，包含当天），默认本月 1 日"),
+		api.Query("to", "结束日期（YYYY-MM-DD，包含当天），默认今天"),
+	    },
+	    Responses: []api.Response{
+		api.JSON(http.StatusOK, "费用汇总", costStatsResponse{}),
+		api.Error(http.StatusBadRequest, "日期格式错误或结束日期早于开始日期"),
+		api.Error(http.StatusInternalServerError, "统计失败"),
+	    },
+	}, app.handleCostStats)
 
-}
+	// 管理接口（配置 server.admin_api_key 时需要携带 API Key）
+	admin := routes.Group("/admin").Use(middleware.APIKey(app.config.Server.AdminAPIKey))
+	admin.GET("/status", api.Operation{
+	    Summary:     "队列与 Worker 状态",
+	    Description: "队列深度、本实例正在处理的任务数、每个 Worker 当前处理的任务，以及各状态的任务数。配置 server.admin_api_key 时需要 Authorization: Bearer <key>",
+	    Tags:        []string{"admin"},
+	    Responses: []api.Response{
+		api.JSON(http.StatusOK, "运行状态", adminStatusResponse{}),
+		api.Error(http.StatusUnauthorized, "API Key 缺失或错误"),
+		api.Error(http.StatusForbidden, "多用户模式下的普通用户"),
+	    },
+	}, app.handleAdminStatus)
+	admin.PUT("/queue/prefetch", api.Operation{
+	    Summary:     "调整队列预取数量",
+	    Description: "运行时调整本实例 RabbitMQ 消费者的预取数量（QoS prefetch count），立即生效，重连后沿用；重启后恢复为配置的 queue.rabbitmq.prefetch",
+	    Tags:        []string{"admin"},
+	    Body:        prefetchRequest{},
+	    Responses: []api.Response{
+		api.JSON(http.StatusOK, "调整后的预取数量", prefetchRequest{}),
+		api.Error(http.StatusBadRequest, "预取数量无效，或队列不支持调整（非 RabbitMQ）"),
+		api.Error(http.StatusUnauthorized, "API Key 缺失或错误"),
+		api.Error(http.StatusForbidden, "多用户模式下的普通用户"),
+		api.Error(http.StatusInternalServerError, "设置 QoS 失败"),
+	    },
+	}, app.handleSetPrefetch)
+	admin.POST("/sources/:name/enable", api.Operation{
+	    Summary:   "启用订阅源（立即轮询一次）",
+	    Tags:      []string{"admin", "sources"},
+	    Responses: []api.Response{
+		api.JSON(http.StatusOK, "订阅源状态", sources.Status{}),
+		api.Error(http.StatusUnauthorized, "API Key 缺失或错误"),
+		api.Error(http.StatusForbidden, "多用户模式下的普通用户"),
+		api.Error(http.StatusNotFound, "订阅源不存在"),
+	    },
+	}, app.handleEnableSource)
+	admin.POST("/sources/:name/disable", api.Operation{
+	    Summary:   "停用订阅源",
+	    Tags:      []string{"admin", "sources"},
+	    Responses: []api.Response{
+		api.JSON(http.StatusOK, "订阅源状态", sources.Status{}),
+		api.Error(http.StatusUnauthorized, "API Key 缺失或错误"),
+		api.Error(http.StatusForbidden, "多用户模式下的普通用户"),
+		api.Error(http.StatusNotFound, "订阅源不存在"),
+	    },
+	}, app.handleDisableSource)
 
-// handleJobsCount 返回任务计数（返回 HTML）
-func (app *App) handleJobsCount(c *gin.Context) {
-    jobs, err := app.store.List()
+	// HTMX 路由（返回 HTML 片段）
+	routes.POST("/upload", api.Operation{
+	    Summary:     "上传音视频文件",
+	    Description: "每个文件创建一个转录任务，返回任务卡片；无效的文件单独显示错误信息",
+	    Tags:        []string{"jobs"},
+	    Params: []api.Param{
+		{Name: "force", In: api.InForm, Type: "boolean", Description: "为 true 时跳过重复检测，重新转录（需位于文件字段之前，也可作为查询参数）"},
+		api.FormField("prompt", false, "Whisper 提示词，列出专有名词、术语以引导拼写（最多 1000 个字符，需位于文件字段之前，也可作为查询参数），为空时使用配置的默认值"),
+		{Name: "temperature", In: api.InForm, Type: "number", Description: "Whisper 采样温度（0 到 1，需位于文件字段之前，也可作为查询参数），为空时使用配置的默认值"},
+		api.FormFiles("audio", true, "音视频文件，可包含多个"),
+	    },
+	    Responses: []api.Response{
+		api.HTML(http.StatusOK, "任务卡片"),
+		api.HTML(http.StatusBadRequest, "没有文件、上传内容太大或参数无效"),
+		api.HTML(http.StatusPaymentRequired, "本月预算已用完"),
+		api.HTML(http.StatusServiceUnavailable, "无法读取本月费用（openai.budget_fail_open 为 false 时）"),
+		api.HTML(http.StatusTooManyRequests, "请求太频繁（开启限流时）"),
+	    },
+	}, app.rateLimited(app.config.RateLimit.Upload, app.handleUpload)...)
+	routes.GET("/jobs", api.Operation{
+	    Summary:   "任务列表",
+	    Tags:      []string{"jobs"},
+	    Responses: []api.Response{api.HTML(http.StatusOK, "任务列表")},
+	}, app.handleListJobs)
+	routes.GET("/jobs/history", api.Operation{
+	    Summary:   "历史任务列表（包含数据库中的已完成任务）",
+	    Tags:      []string{"jobs"},
+	    Responses: []api.Response{api.HTML(http.StatusOK, "任务列表")},
+	}, app.handleListJobsHistory)
+	routes.GET("/jobs/count", api.Operation{
+	    Summary:   "任务数量",
+	    Tags:      []string{"jobs"},
+	    Responses: []api.Response{api.HTML(http.StatusOK, "如“3 个任务”")},
+	}, app.handleJobsCount)
+	routes.GET("/jobs/count/by-status", api.Operation{
+	    Summary:     "按状态统计任务数量",
+	    Description: "多用户模式下普通用户只统计本人的任务；Accept: application/json 时返回 JSON",
+	    Tags:        []string{"jobs"},
+	    Responses: []api.Response{
+		api.HTML(http.StatusOK, "如“⏳ 等待处理 1 · 🔄 处理中 2 · ✅ 已完成 10 · ❌ 失败 1”"),
+		api.JSON(http.StatusOK, "各状态的任务数", statusCountsResponse{}),
+		api.HTML(http.StatusInternalServerError, "统计失败"),
+	    },
+	}, app.handleJobsCountByStatus)
+	routes.GET("/jobs/search", api.Operation{
+	    Summary:   "搜索任务",
+	    Tags:      []string{"jobs"},
+	    Params:    []api.Param{api.Query("q", "关键词（匹配转录文本）")},
+	    Responses: []api.Response{api.HTML(http.StatusOK, "搜索结果")},
+	}, app.handleSearchJobs)
+	routes.GET("/jobs/active-summary", api.Operation{
+	    Summary:     "进行中任务汇总",
+	    Description: "Accept: application/json 时返回 JSON",
+	    Tags:        []string{"jobs"},
+	    Responses: []api.Response{
+		api.HTML(http.StatusOK, "汇总"),
+		api.JSON(http.StatusOK, "汇总", models.ActiveSummary{}),
+	    },
+	}, app.handleActiveSummary)
+	routes.GET("/vocabulary/:word/jobs", api.Operation{
+	    Summary: "查询单词出现过的任务",
+	    Tags:    []string{"vocabulary"},
+	    Responses: []api.Response{
+		api.JSON(http.StatusOK, "任务列表", wordJobsResponse{}),
+		api.Error(http.StatusBadRequest, "单词为空"),
+	    },
+	}, app.handleWordJobs)
+	routes.GET("/jobs/:job_id", api.Operation{
+	    Summary:     "任务状态",
+	    Description: "返回任务卡片；Accept: application/json 时返回任务 JSON",
+	    Tags:        []string{"jobs"},
+	    Responses: []api.Response{
+		api.HTML(http.StatusOK, "任务卡片"),
+		api.JSON(http.StatusOK, "任务", models.TranscriptionJob{}),
+		notFound,
+	    },
+	}, app.handleGetJob)
+	routes.GET("/jobs/:job_id/events", api.Operation{
+	    Summary:     "任务进度推送（SSE）",
+	    Description: "每帧 data 为一个进度事件，任务结束后关闭连接",
+	    Tags:        []string{"jobs"},
+	    Responses: []api.Response{
+		{Status: http.StatusOK, Description: "进度事件流", ContentType: "text/event-stream", Schema: events.Event{}},
+		jobNotFound,
+	    },
+	}, app.handleJobEvents)
+	routes.GET("/ws", api.Operation{
+	    Summary:     "任务状态推送（WebSocket）",
+	    Description: "升级为 WebSocket 连接，任意任务状态变化（新建、开始处理、完成、失败、重新排队）时推送一条 JSON 文本消息；多用户模式下普通用户只收到自己的任务",
+	    Tags:        []string{"jobs"},
+	    Responses: []api.Response{
+		{Status: http.StatusSwitchingProtocols, Description: "每条消息为一个状态变化事件", Schema: events.Event{}},
+		{Status: http.StatusBadRequest, Description: "不是 WebSocket 握手请求", ContentType: "text/plain"},
+		{Status: http.StatusForbidden, Description: "跨域来源不在允许列表中", ContentType: "text/plain"},
+	    },
+	}, app.handleJobsWebSocket)
+	routes.GET("/jobs/:job_id/progress", api.Operation{
+	    Summary:     "任务进度片段（轮询）",
+	    Description: "只包含进度条、百分比和状态；带 If-None-Match 且没有变化时返回 304，任务结束后返回 286（htmx 停止轮询）",
+	    Tags:        []string{"jobs"},
+	    Responses: []api.Response{
+		api.HTML(http.StatusOK, "进度片段（每 2 秒轮询自身）"),
+		{Status: http.StatusNotModified, Description: "进度没有变化"},
+		api.HTML(statusProgressDone, "任务已结束的进度片段"),
+		notFound,
+	    },
+	}, app.handleJobProgress)
+	routes.GET("/jobs/:job_id/details", api.Operation{
+	    Summary:   "任务详情",
+	    Tags:      []string{"jobs"},
+	    Responses: []api.Response{api.HTML(http.StatusOK, "任务详情"), notFound},
+	}, app.handleJobDetails)
+	routes.GET("/jobs/:job_id/download", api.Operation{
+	    Summary: "下载转录文本",
+	    Tags:    []string{"jobs"},
+	    Responses: []api.Response{
+		api.File("text/plain; charset=utf-8", "转录文本"),
+		api.Error(http.StatusBadRequest, "任务尚未完成"),
+		jobNotFound,
+	    },
+	}, app.handleDownloadResult)
+	routes.PUT("/jobs/:job_id/result", api.Operation{
+	    Summary:     "修正转录结果",
+	    Description: "text 替换纯文本结果；提供 cues 时校验时间轴（按 index 排序后时间单调递增、互不重叠）并重新生成 SRT / VTT 字幕，未提供 text 时由字幕文本拼接。已生成的双语字幕与修正后的字幕不再对应，一并删除",
+	    Tags:        []string{"jobs"},
+	    Body:        editResultRequest{},
+	    Responses: []api.Response{
+		api.JSON(http.StatusOK, "修正后的任务", models.TranscriptionJob{}),
+		api.Error(http.StatusBadRequest, "任务尚未完成、请求体无效或字幕时间轴不合法"),
+		jobNotFound,
+		api.Error(http.StatusConflict, "任务已重新开始转录"),
+	    },
+	}, app.handleEditResult)
+	routes.GET("/jobs/:job_id/media", api.Operation{
+	    Summary: "原始媒体文件",
+	    Tags:    []string{"jobs"},
+	    Responses: []api.Response{
+		{Status: http.StatusOK, Description: "媒体文件（本地存储，支持 Range 请求）"},
+		{Status: http.StatusFound, Description: "跳转到 S3 预签名地址"},
+		api.Error(http.StatusNotFound, "任务不存在或媒体已清理"),
+	    },
+	}, app.handleMedia)
+	routes.GET("/jobs/:job_id/download-subtitle", api.Operation{
+	    Summary: "下载 SRT 字幕",
+	    Tags:    []string{"subtitles"},
+	    Responses: []api.Response{
+		api.File("text/plain; charset=utf-8", "SRT 字幕"),
+		api.Error(http.StatusBadRequest, "任务尚未完成或无字幕"),
+		jobNotFound,
+	    },
+	}, app.handleDownloadSubtitle)
+	routes.GET("/jobs/:job_id/subtitle.vtt", api.Operation{
+	    Summary: "WebVTT 字幕",
+	    Tags:    []string{"subtitles"},
+	    Responses: []api.Response{
+		api.File("text/vtt; charset=utf-8", "WebVTT 字幕"),
+		api.Error(http.StatusBadRequest, "任务尚未完成或无字幕"),
+		jobNotFound,
+	    },
+	}, app.handleSubtitleVTT)
+	routes.GET("/jobs/:job_id/transcript-timestamped.txt", api.Operation{
+	    Summary:     "下载带时间戳的转录文本",
+	    Description: "由字幕文件重新生成，每条字幕一行，格式为 [00:01:23] 文本",
+	    Tags:        []string{"subtitles"},
+	    Responses: []api.Response{
+		api.File("text/plain; charset=utf-8", "带时间戳的转录文本"),
+		api.Error(http.StatusBadRequest, "任务尚未完成或没有字幕（只有纯文本结果时无法生成时间戳）"),
+		jobNotFound,
+	    },
+	}, app.handleTimestampedTranscript)
+	routes.GET("/jobs/:job_id/transcript.json", api.Operation{
+	    Summary:     "下载 JSON 转录稿",
+	    Description: "包含全文、语言、时长，有字幕时包含每条字幕的起止时间（秒）",
+	    Tags:        []string{"subtitles"},
+	    Responses: []api.Response{
+		api.File("application/json; charset=utf-8", "JSON 转录稿"),
+		api.Error(http.StatusBadRequest, "任务尚未完成或没有转录文本"),
+		jobNotFound,
+	    },
+	}, app.handleGeneratedArtifact("transcript_json"))
+	routes.GET("/jobs/:job_id/transcript.md", api.Operation{
+	    Summary:     "下载 Markdown 转录稿",
+	    Description: "文件名作为标题，有字幕时每条字幕一段并以时间戳开头，否则为全文",
+	    Tags:        []string{"subtitles"},
+	    Responses: []api.Response{
+		api.File("text/markdown; charset=utf-8", "Markdown 转录稿"),
+		api.Error(http.StatusBadRequest, "任务尚未完成或没有转录文本"),
+		jobNotFound,
+	    },
+	}, app.handleGeneratedArtifact("transcript_md"))
+	routes.GET("/jobs/:job_id/artifacts.zip", api.Operation{
+	    Summary:     "打包下载所有产物",
+	    Description: "包含产物列表（/api/v1/jobs/:job_id/artifacts）中除原始媒体外的所有文件",
+	    Tags:        []string{"jobs"},
+	    Responses: []api.Response{
+		api.File("application/zip", "ZIP 压缩包"),
+		api.Error(http.StatusBadRequest, "任务尚未完成，没有可下载的文件"),
+		jobNotFound,
+	    },
+	}, app.handleDownloadZip)
+	routes.GET("/jobs/:job_id/share", api.Operation{
+	    Summary:     "分享页面",
+	    Description: "完整的 HTML 页面，列出任务的所有可下载产物和转录文本，与其他任务接口使用相同的鉴权",
+	    Tags:        []string{"jobs"},
+	    Responses:   []api.Response{api.HTML(http.StatusOK, "分享页面"), jobNotFound},
+	}, app.handleSharePage)
+	routes.POST("/jobs/:job_id/generate-bilingual", api.Operation{
+	    Summary:   "生成双语字幕（后台子任务）",
+	    Tags:      []string{"subtitles"},
+	    Responses: []api.Response{api.HTML(http.StatusOK, "子任务状态"), api.HTML(http.StatusBadRequest, "任务尚未完成"), notFound},
+	}, app.handleGenerateBilingual)
+	routes.GET("/jobs/:job_id/download-bilingual-subtitle", api.Operation{
+	    Summary: "下载双语 SRT 字幕",
+	    Tags:    []string{"subtitles"},
+	    Responses: []api.Response{
+		api.File("text/plain; charset=utf-8", "双语 SRT 字幕"),
+		api.Error(http.StatusBadRequest, "尚未生成双语字幕"),
+		jobNotFound,
+	    },
+	}, app.handleDownloadBilingualSubtitle)
+	routes.GET("/jobs/:job_id/bilingual.vtt", api.Operation{
+	    Summary: "双语 WebVTT 字幕",
+	    Tags:    []string{"subtitles"},
+	    Responses: []api.Response{
+		api.File("text/vtt; charset=utf-8", "双语 WebVTT 字幕"),
+		api.Error(http.StatusBadRequest, "尚未生成双语字幕"),
+		jobNotFound,
+	    },
+	}, app.handleBilingualVTT)
+	routes.DELETE("/jobs/:job_id", api.Operation{
+	    Summary:     "删除任务",
+	    Description: "软删除：任务从列表中隐藏，retention.deleted_after_days 天内可以恢复，之后由后台清理器连同文件永久删除",
+	    Tags:        []string{"jobs"},
+	    Responses:   []api.Response{api.HTML(http.StatusOK, "删除提示（带撤销按钮）"), notFound},
+	}, app.handleDeleteJob)
+	routes.POST("/jobs/:job_id/restore", api.Operation{
+	    Summary: "恢复已删除的任务",
+	    Tags:    []string{"jobs"},
+	    Responses: []api.Response{
+		api.HTML(http.StatusOK, "任务卡片（Accept: application/json 时返回任务 JSON）"),
+		api.HTML(http.StatusNotFound, "任务不存在或未被删除"),
+		api.HTML(http.StatusGone, "已超过恢复期限"),
+	    },
+	}, app.handleRestoreJob)
+	routes.POST("/jobs/:job_id/re-transcribe", api.Operation{
+	    Summary:     "重新转录",
+	    Description: "使用原始媒体重新转录已结束的任务：清除转录结果和字幕、重置进度后重新加入队列。与普通重试不同，可以指定新的语言和模型",
+	    Tags:        []string{"jobs"},
+	    Params: []api.Param{
+		api.FormField("language", false, "音频语言（ISO 639-1，如 en），未提供时沿用原设置，为空或 auto 时自动检测（也可作为查询参数）"),
+		api.FormField("model", false, "Whisper 模型，未提供时沿用原设置，为空时使用默认模型 whisper-1（也可作为查询参数）"),
+		{Name: "keep_vocabulary", In: api.InForm, Type: "boolean", Description: "为 true 时保留已提取的单词，默认一并清除（也可作为查询参数）"},
+	    },
+	    Responses: []api.Response{
+		api.HTML(http.StatusOK, "任务卡片（Accept: application/json 时返回任务 JSON）"),
+		api.HTML(http.StatusBadRequest, "参数无效"),
+		api.HTML(http.StatusPaymentRequired, "本月预算已用完"),
+		api.HTML(http.StatusServiceUnavailable, "无法读取本月费用（openai.budget_fail_open 为 false 时）"),
+		notFound,
+		api.HTML(http.StatusConflict, "任务正在转录中"),
+		api.HTML(http.StatusGone, "原始媒体已删除"),
+	    },
+	}, app.handleReTranscribe)
+	routes.POST("/jobs/:job_id/extract-vocabulary", api.Operation{
+	    Summary: "提取单词（后台子任务，sync=true 时等待提取结束）",
+	    Tags:    []string{"vocabulary"},
+	    Params: append(slices.Clone(levelParams),
+		api.Param{Name: "sync", In: api.InQuery, Description: "为 true 时等待提取结束，直接返回包含单词列表的任务详情"},
+	    ),
+	    Responses: []api.Response{
+		api.HTML(http.StatusOK, "子任务状态（同步模式下为任务详情）"),
+		api.HTML(http.StatusBadRequest, "任务尚未完成或参数无效"),
+		api.HTML(http.StatusInternalServerError, "同步模式下提取失败或被取消"),
+		notFound,
+		api.HTML(http.StatusTooManyRequests, "请求太频繁（开启限流时）"),
+	    },
+	}, app.rateLimited(app.config.RateLimit.Extract, app.handleExtractVocabulary)...)
+	routes.GET("/jobs/:job_id/vocabulary.csv", api.Operation{
+	    Summary:   "导出单词（CSV）",
+	    Tags:      []string{"vocabulary"},
+	    Responses: []api.Response{api.File("text/csv; charset=utf-8", "列为 word, definition, example"), api.Error(http.StatusBadRequest, "尚未提取单词"), jobNotFound},
+	}, app.handleExportVocabulary(','))
+	routes.GET("/jobs/:job_id/vocabulary.tsv", api.Operation{
+	    Summary:   "导出单词（Anki TSV）",
+	    Tags:      []string{"vocabulary"},
+	    Responses: []api.Response{api.File("text/tab-separated-values; charset=utf-8", "带 Anki 文件头"), api.Error(http.StatusBadRequest, "尚未提取单词"), jobNotFound},
+	}, app.handleExportVocabulary('\t'))
+	routes.GET("/jobs/:job_id/tasks/:task_id", api.Operation{
+	    Summary:   "子任务状态",
+	    Tags:      []string{"jobs"},
+	    Responses: []api.Response{api.HTML(http.StatusOK, "子任务状态，完成后为任务详情"), notFound},
+	}, app.handleGetSubTask)
+	routes.POST("/jobs/:job_id/tasks/:task_id/cancel", api.Operation{
+	    Summary:   "取消子任务",
+	    Tags:      []string{"jobs"},
+	    Responses: []api.Response{api.HTML(http.StatusOK, "子任务状态"), api.HTML(http.StatusNotFound, "子任务不存在")},
+	}, app.handleCancelSubTask)
+	routes.POST("/jobs/:job_id/sync/:sink", api.Operation{
+	    Summary:     "同步单词到外部系统",
+	    Description: "sink 为配置 vocabulary_sinks 中的名称",
+	    Tags:        []string{"vocabulary"},
+	    Params:      maimemoParams,
+	    Responses:   []api.Response{api.HTML(http.StatusOK, "同步结果"), api.HTML(http.StatusNotFound, "任务或同步目标不存在")},
+	}, app.handleSyncVocabulary)
+	routes.POST("/jobs/:job_id/sync-to-maimemo", api.Operation{
+	    Summary:   "同步单词到墨墨（兼容旧版前端）",
+	    Tags:      []string{"maimemo"},
+	    Params:    maimemoParams,
+	    Responses: []api.Response{api.HTML(http.StatusOK, "同步结果"), notFound},
+	}, app.handleSyncToMaimemo)
+	routes.POST("/maimemo/list-notepads", api.Operation{
+	    Summary: "查询墨墨云词本列表",
+	    Tags:    []string{"maimemo"},
+	    Params: []api.Param{
+		api.FormField("token", false, "墨墨 API Token，为空时使用服务端保存的 Token"),
+		api.FormField("job_id", true, "任务 ID（也可作为查询参数），用于渲染同步表单"),
+	    },
+	    Responses: []api.Response{api.HTML(http.StatusOK, "云词本列表"), api.HTML(http.StatusBadRequest, "缺少任务 ID 或 token"), api.HTML(http.StatusNotFound, "任务不存在")},
+	}, app.handleListNotepads)
+	routes.POST("/maimemo/create-notepad", api.Operation{
+	    Summary:     "新建墨墨云词本",
+	    Description: "创建成功后新云词本的 ID 自动填入同步表单",
+	    Tags:        []string{"maimemo"},
+	    Params: []api.Param{
+		api.FormField("token", false, "墨墨 API Token，为空时使用服务端保存的 Token"),
+		api.FormField("job_id", true, "任务 ID（也可作为查询参数），用于填写同步表单"),
+		api.FormField("title", true, "云词本标题"),
+		api.FormField("brief", false, "云词本简介"),
+	    },
+	    Responses: []api.Response{
+		api.HTML(http.StatusOK, "创建结果"),
+		api.HTML(http.StatusBadRequest, "缺少任务 ID、token 或标题"),
+		api.HTML(http.StatusNotFound, "任务不存在"),
+		api.HTML(http.StatusInternalServerError, "创建失败"),
+	    },
+	}, app.handleCreateNotepad)
+	routes.POST("/maimemo/remove-words", api.Operation{
+	    Summary:     "从墨墨云词本删除单词",
+	    Description: "只删除与单词完全相同的行，保留日期标题行和其他单词（如把已学会的单词移出云词本）",
+	    Tags:        []string{"maimemo"},
+	    Params: []api.Param{
+		api.FormField("token", false, "墨墨 API Token，为空时使用服务端保存的 Token"),
+		api.FormField("notepad_id", true, "墨墨云词本 ID"),
+		api.FormField("words", true, "要删除的单词，可重复提交，也可以用逗号或换行分隔"),
+	    },
+	    Responses: []api.Response{
+		api.HTML(http.StatusOK, "删除结果"),
+		api.HTML(http.StatusBadRequest, "缺少 token、云词本 ID 或单词"),
+		api.HTML(http.StatusInternalServerError, "删除失败"),
+	    },
+	}, app.handleRemoveNotepadWords)
+	routes.GET("/maimemo/token", api.Operation{
+	    Summary:     "查询是否保存了墨墨 Token",
+	    Description: "按当前用户（多用户模式下由 API Key 确定）查询，只返回保存状态，不返回 Token",
+	    Tags:        []string{"maimemo"},
+	    Responses:   []api.Response{api.HTML(http.StatusOK, "Token 区域：已保存状态或输入框")},
+	}, app.handleGetMaimemoToken)
+	routes.POST("/maimemo/token", api.Operation{
+	    Summary:     "保存墨墨 Token",
+	    Description: "使用 maimemo_service.token_key 加密后保存，之后同步和查询云词本时可省略 token",
+	    Tags:        []string{"maimemo"},
+	    Params:      []api.Param{api.FormField("token", true, "墨墨 API Token")},
+	    Responses: []api.Response{
+		api.HTML(http.StatusOK, "已保存状态"),
+		api.HTML(http.StatusBadRequest, "缺少 token"),
+		api.HTML(http.StatusServiceUnavailable, "未配置加密密钥"),
+	    },
+	}, app.handleSaveMaimemoToken)
+	routes.DELETE("/maimemo/token", api.Operation{
+	    Summary:   "删除保存的墨墨 Token",
+	    Tags:      []string{"maimemo"},
+	    Responses: []api.Response{api.HTML(http.StatusOK, "Token 输入框")},
+	}, app.handleDeleteMaimemoToken)
+
+	// JSON 路由
+	v1 := routes.Group("/v1")
+	v1.GET("/jobs/:job_id/artifacts", api.Operation{
+	    Summary:   "任务产物列表",
+	    Tags:      []string{"jobs"},
+	    Responses: []api.Response{api.JSON(http.StatusOK, "可下载的产物", artifactsResponse{}), jobNotFound},
+	}, app.handleListArtifacts)
+	v1.GET("/jobs/:job_id/result.json", api.Operation{
+	    Summary:   "任务结果描述（与 webhook payload 中的 job 一致）",
+	    Tags:      []string{"jobs"},
+	    Responses: []api.Response{api.JSON(http.StatusOK, "任务结果", artifacts.Result{}), jobNotFound},
+	}, app.handleGetResult)
+
+	// 播客订阅源
+	routes.GET("/sources", api.Operation{
+	    Summary:   "订阅源列表",
+	    Tags:      []string{"sources"},
+	    Responses: []api.Response{api.JSON(http.StatusOK, "订阅源状态", sourcesResponse{})},
+	}, app.handleListSources)
+    }
+
+    return r, spec
+}
+
+// apiVersion 接口版本（/api/ping 和 OpenAPI 文档）
+const apiVersion = "0.3.0-htmx"
+
+// 以下响应结构仅用于生成 OpenAPI 文档，与 handler 中的 gin.H 保持一致
+type pingResponse struct {
+    Message string `json:"message"`
+    Version string `json:"version"`
+}
+
+type healthResponse struct {
+    Status     string                     `json:"status"` // ok 或 degraded
+    Components map[string]componentStatus `json:"components"`
+}
+
+type statsResponse struct {
+    Budget budget.Usage `json:"budget"`
+}
+
+type costStatsResponse struct {
+    From string           `json:"from"` // 开始日期（包含）
+    To   string           `json:"to"`   // 结束日期（包含）
+    Cost models.CostStats `json:"cost"`
+}
+
+type statusCountsResponse struct {
+    Counts map[models.JobStatus]int `json:"counts"` // 各状态的任务数（没有任务的状态不出现）
+    Total  int                      `json:"total"`
+}
+
+type adminStatusResponse struct {
+    Queue      queue.QueueStats         `json:"queue"`
+    QueueError string                   `json:"queue_error,omitempty"` // 查询队列失败的原因
+    Workers    []worker.State           `json:"workers"`
+    Jobs       map[models.JobStatus]int `json:"jobs"`                  // 各状态的任务数
+    JobsError  string                   `json:"jobs_error,omitempty"`  // 查询任务失败的原因
+    Stats      *models.JobStats         `json:"stats,omitempty"`       // 所有任务的汇总统计
+    Today      *models.JobStats         `json:"today,omitempty"`       // 今天（服务器时区）创建的任务的汇总统计
+
+    IndexMaintenance *storage.IndexMaintenanceStats `json:"index_maintenance,omitempty"` // 存储索引的后台维护状态（Redis / 混合存储）
+}
+
+type prefetchRequest struct {
+    Prefetch int `json:"prefetch"` // 预取数量，应等于或略大于 Worker 数量
+}
+
+type wordJobsResponse struct {
+    Word string    `json:"word"`
+    Jobs []wordJob `json:"jobs"`
+}
+
+type artifactsResponse struct {
+    JobID     string               `json:"job_id"`
+    Status    models.JobStatus     `json:"status"`
+    Artifacts []artifacts.Artifact `json:"artifacts"`
+}
+
+type sourcesResponse struct {
+    Sources []sources.Status `json:"sources"`
+}
+
+// editResultRequest 修正转录结果的请求体（text 和 cues 至少提供一个）
+type editResultRequest struct {
+    Text *string     `json:"text,omitempty"` // 修正后的纯文本结果
+    Cues []editedCue `json:"cues,omitempty"` // 修正后的完整字幕列表，提供时重新生成 SRT / VTT
+}
+
+// editedCue 一条修正后的字幕（时间为秒）
+type editedCue struct {
+    Index int     `json:"index"` // 序号，字幕按序号排序
+    Start float64 `json:"start"`
+    End   float64 `json:"end"`
+    Text  string  `json:"text"`
+}
+
+func (app *App) handlePing(c *gin.Context) {
+    c.JSON(http.StatusOK, gin.H{
+	"message": "pong",
+	"version": apiVersion,
+    })
+}
+
+// componentStatus 单个依赖组件的健康状态
+type componentStatus struct {
+    Backend   string  `json:"backend,omitempty"` // 具体实现，如 redis、rabbitmq
+    Status    string  `json:"status"`            // ok 或 down
+    LatencyMs float64 `json:"latency_ms"`        // 检查耗时（毫秒）
+    Error     string  `json:"error,omitempty"`   // 失败原因
+}
+
+// checkComponent 执行检查并记录耗时
+func checkComponent(backend string, check func() error) componentStatus {
+    start := time.Now()
+    err := check()
+    status := componentStatus{
+	Backend:   backend,
+	Status:    "ok",
+	LatencyMs: float64(time.Since(start).Microseconds()) / 1000,
+    }
+    if err != nil {
+	status.Status = "down"
+	status.Error = err.Error()
+    }
+    return status
+}
+
+// lookPath 检查命令行工具是否在 PATH 中
+func lookPath(name string) func() error {
+    return func() error {
+	_, err := exec.LookPath(name)
+	return err
+    }
+}
+
+// handleHealth 深度健康检查：逐一检查存储、队列和 FFmpeg 工具
+// 全部正常返回 200，任意组件异常返回 503（供负载均衡摘除实例）
+func (app *App) handleHealth(c *gin.Context) {
+    components := map[string]componentStatus{
+	"store":   checkComponent(app.config.Storage.Type, func() error { return app.store.Ping(c.Request.Context()) }),
+	"queue":   checkComponent(app.config.Queue.Type, app.queue.Ping),
+	"ffmpeg":  checkComponent("", lookPath("ffmpeg")),
+	"ffprobe": checkComponent("", lookPath("ffprobe")),
+    }
+
+    healthy := true
+    for name, status := range components {
+	if status.Status != "ok" {
+	    healthy = false
+	    log.Printf("⚠️ 健康检查失败: %s: %s", name, status.Error)
+	}
+    }
+
+    code := http.StatusOK
+    overall := "ok"
+    if !healthy {
+	code = http.StatusServiceUnavailable
+	overall = "degraded"
+    }
+
+    c.JSON(code, gin.H{
+	"status":     overall,
+	"components": components,
+    })
+}
+
+// handleStats 返回统计信息（JSON）
+func (app *App) handleStats(c *gin.Context) {
+    usage, err := app.budget.CurrentUsage(c.Request.Context())
+    if err != nil {
+	c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	return
+    }
+
+    c.JSON(http.StatusOK, gin.H{
+	"budget": usage,
+    })
+}
+
+// handleCostStats 按日期范围汇总任务的费用估算（JSON）
+func (app *App) handleCostStats(c *gin.Context) {
+    now := time.Now()
+    from := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.Local)
+    to := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+    for _, param := range []struct {
+	name  string
+	value *time.Time
+    }{{"from", &from}, {"to", &to}} {
+	raw := c.Query(param.name)
+	if raw == "" {
+	    continue
+	}
+	day, err := time.ParseInLocation(time.DateOnly, raw, time.Local)
+	if err != nil {
+	    c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("%s 必须是 YYYY-MM-DD 格式的日期", param.name)})
+	    return
+	}
+	*param.value = day
+    }
+    if to.Before(from) {
+	c.JSON(http.StatusBadRequest, gin.H{"error": "结束日期不能早于开始日期"})
+	return
+    }
+
+    userID := ""
+    if user := middleware.CurrentUser(c); user.Restricted() {
+	userID = user.ID
+    }
+    // 结束日期包含当天：统计到次日零点之前创建的任务
+    stats, err := storage.CostStats(c.Request.Context(), app.store, userID, from, to.AddDate(0, 0, 1))
+    if err != nil {
+	log.Printf("❌ %v", err)
+	c.JSON(http.StatusInternalServerError, gin.H{"error": "统计任务费用失败"})
+	return
+    }
+
+    c.JSON(http.StatusOK, costStatsResponse{
+	From: from.Format(time.DateOnly),
+	To:   to.Format(time.DateOnly),
+	Cost: stats,
+    })
+}
+
+// handleSetPrefetch 运行时调整 RabbitMQ 消费者的预取数量（JSON）
+func (app *App) handleSetPrefetch(c *gin.Context) {
+    if middleware.CurrentUser(c).Restricted() {
+	c.JSON(http.StatusForbidden, gin.H{"error": "需要管理员权限"})
+	return
+    }
+
+    setter, ok := app.queue.(queue.PrefetchSetter)
+    if !ok {
+	c.JSON(http.StatusBadRequest, gin.H{"error": "当前队列不支持调整预取数量"})
+	return
+    }
+
+    var req prefetchRequest
+    if err := c.ShouldBindJSON(&req); err != nil {
+	c.JSON(http.StatusBadRequest, gin.H{"error": "请求体不是有效的 JSON"})
+	return
+    }
+    if req.Prefetch <= 0 {
+	c.JSON(http.StatusBadRequest, gin.H{"error": "prefetch 必须大于 0"})
+	return
+    }
+
+    if err := setter.SetPrefetch(req.Prefetch); err != nil {
+	log.Printf("❌ 调整预取数量失败: %v", err)
+	c.JSON(http.StatusInternalServerError, gin.H{"error": "设置 QoS 失败"})
+	return
+    }
+    if req.Prefetch < app.config.Transcriber.WorkerPoolSize {
+	log.Printf("⚠️ RabbitMQ 预取数量 (%d) 小于 Worker 数量 (%d)，部分 Worker 会一直空闲", req.Prefetch, app.config.Transcriber.WorkerPoolSize)
+    }
+
+    c.JSON(http.StatusOK, req)
+}
+
+// handleAdminStatus 返回队列深度、Worker 状态和各状态的任务数（JSON）
+// 队列或存储查询失败时对应字段为空并附带错误，其余信息照常返回
+func (app *App) handleAdminStatus(c *gin.Context) {
+    // 多用户模式下普通用户不能查看（Worker 状态包含其他用户的任务 ID）
+    if middleware.CurrentUser(c).Restricted() {
+	c.JSON(http.StatusForbidden, gin.H{"error": "需要管理员权限"})
+	return
+    }
+
+    status := adminStatusResponse{
+	Workers: app.workerStates.Snapshot(),
+    }
+
+    stats, err := app.queue.Stats()
+    if err != nil {
+	log.Printf("⚠️ 查询队列状态失败: %v", err)
+	status.QueueError = err.Error()
+    }
+    status.Queue = stats
+
+    counts, err := app.store.CountByStatus(c.Request.Context(), "")
+    if err != nil {
+	log.Printf("⚠️ 统计任务状态失败: %v", err)
+	status.JobsError = err.Error()
+	counts = make(map[models.JobStatus]int)
+    }
+    status.Jobs = counts
+
+    if stats, err := app.store.Stats(c.Request.Context(), "", time.Time{}); err != nil {
+	log.Printf("⚠️ 汇总任务统计失败: %v", err)
+	status.JobsError = err.Error()
+    } else {
+	status.Stats = &stats
+    }
+
+    now := time.Now()
+    midnight := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+    if stats, err := app.store.Stats(c.Request.Context(), "", midnight); err != nil {
+	log.Printf("⚠️ 汇总今天的任务统计失败: %v", err)
+	status.JobsError = err.Error()
+    } else {
+	status.Today = &stats
+    }
+    status.IndexMaintenance = storage.IndexMaintenanceOf(app.store)
+
+    c.JSON(http.StatusOK, status)
+}
+
+// handleMetrics 以 Prometheus 文本格式返回队列深度、正在处理的任务数、消费者数量和各状态的 Worker 数
+// 查询队列失败时 voiceflow_queue_up 为 0，不输出队列的其他指标
+func (app *App) handleMetrics(c *gin.Context) {
+    var b strings.Builder
+    gauge := func(name, help string, value int) {
+	fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s gauge\n%s %d\n", name, help, name, name, value)
+    }
+
+    stats, err := app.queue.Stats()
+    if err != nil {
+	log.Printf("⚠️ 查询队列状态失败: %v", err)
+	gauge("voiceflow_queue_up", "查询队列状态是否成功", 0)
+    } else {
+	gauge("voiceflow_queue_up", "查询队列状态是否成功", 1)
+	gauge("voiceflow_queue_depth", "等待处理的任务数", stats.Depth)
+	gauge("voiceflow_queue_in_flight", "本实例 Worker 正在处理的任务数", stats.InFlight)
+	gauge("voiceflow_queue_consumers", "队列的消费者数量", stats.Consumers)
+    }
+
+    workers := map[string]int{worker.StateIdle: 0, worker.StateProcessing: 0, worker.StateStopped: 0}
+    for _, state := range app.workerStates.Snapshot() {
+	workers[state.State]++
+    }
+    b.WriteString("# HELP voiceflow_workers 本实例各状态的 Worker 数\n# TYPE voiceflow_workers gauge\n")
+    for _, state := range []string{worker.StateIdle, worker.StateProcessing, worker.StateStopped} {
+	fmt.Fprintf(&b, "voiceflow_workers{state=%q} %d\n", state, workers[state])
+    }
+
+    c.Data(http.StatusOK, "text/plain; version=0.0.4; charset=utf-8", []byte(b.String()))
+}
+
+// handleUpload 处理文件上传（返回 HTML）
+// audio 字段可以包含多个文件，每个文件创建一个任务，返回所有任务卡片；
+// 无效的文件单独显示错误信息，不影响同批次的其他文件。
+// 文件以流的方式直接写入磁盘，不在内存中缓存整个文件
+func (app *App) handleUpload(c *gin.Context) {
+    maxSize := app.config.Server.MaxUploadSize
+    maxFiles := app.config.Server.MaxBatchFiles
+    maxBody := maxSize*int64(maxFiles) + multipartOverhead
+
+    // 请求体声明的长度已超限时直接拒绝，不读取文件内容
+    if c.Request.ContentLength > maxBody {
+	c.Data(http.StatusBadRequest, "text/html", []byte(fmt.Sprintf(`
+	    <div class="bg-red-50 text-red-800 p-3 rounded-lg text-sm">
+	    ❌ 上传内容太大，单个文件最大 %.0f MB，每次最多 %d 个文件
+	    </div>
+	    `, float64(maxSize)/1024/1024, maxFiles)))
+	return
+    }
+    c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxBody)
+
+    // 本月预算用尽时拒绝新任务（已在处理中的任务不受影响）
+    if err := app.budget.Check(c.Request.Context()); err != nil {
+	code, message := budgetRejection(err)
+	c.Data(code, "text/html", []byte(fmt.Sprintf(`
+	    <div class="bg-red-50 text-red-800 p-3 rounded-lg text-sm">
+	    ❌ %s
+	    </div>
+	    `, message)))
+	return
+    }
+
+    noFile := []byte(`
+	<div class="bg-red-50 text-red-800 p-3 rounded-lg text-sm">
+	❌ 请上传文件
+	</div>
+	`)
+
+    reader, err := c.Request.MultipartReader()
+    if err != nil {
+	c.Data(http.StatusBadRequest, "text/html", noFile)
+	return
+    }
+
+    // 任务参数（表单字段需位于文件之前，也可以作为查询参数）
+    // force=true 时跳过重复检测，重新转录；prompt / temperature 为空时使用配置的默认值
+    opts := uploadOptions{
+	Force:  isTruthy(c.Query("force")),
+	UserID: middleware.CurrentUser(c).ID,
+    }
+    if err := opts.setWhisperParam("prompt", c.Query("prompt")); err != nil {
+	c.Data(http.StatusBadRequest, "text/html", []byte(uploadError("", err.Error())))
+	return
+    }
+    if err := opts.setWhisperParam("temperature", c.Query("temperature")); err != nil {
+	c.Data(http.StatusBadRequest, "text/html", []byte(uploadError("", err.Error())))
+	return
+    }
+
+    var cards strings.Builder
+    files, created := 0, 0
+    for {
+	part, err := reader.NextPart()
+	if err == io.EOF {
+	    break
+	}
+	if err != nil {
+	    if c.Request.Context().Err() != nil {
+		log.Printf("⚠️ 客户端在上传过程中断开")
+		return
+	    }
+	    cards.WriteString(uploadError("", "读取上传内容失败，其余文件已跳过"))
+	    break
+	}
+	if part.FormName() == "force" && part.FileName() == "" {
+	    value, _ := io.ReadAll(io.LimitReader(part, 16))
+	    part.Close()
+	    opts.Force = isTruthy(string(value))
+	    continue
+	}
+	if (part.FormName() == "prompt" || part.FormName() == "temperature") && part.FileName() == "" {
+	    value, _ := io.ReadAll(io.LimitReader(part, maxPromptLength*4+1))
+	    part.Close()
+	    if err := opts.setWhisperParam(part.FormName(), string(value)); err != nil {
+		c.Data(http.StatusBadRequest, "text/html", []byte(uploadError("", err.Error())))
+		return
+	    }
+	    continue
+	}
+	if part.FormName() != "audio" || part.FileName() == "" {
+	    part.Close()
+	    continue
+	}
+
+	files++
+	filename := filepath.Base(part.FileName())
+	if files > maxFiles {
+	    part.Close()
+	    cards.WriteString(uploadError("", fmt.Sprintf("每次最多上传 %d 个文件，其余文件已跳过", maxFiles)))
+	    break
+	}
+
+	job, reused, err := app.uploadFile(c.Request.Context(), part, filename, opts)
+	part.Close()
+	if err != nil {
+	    if c.Request.Context().Err() != nil {
+		log.Printf("⚠️ 客户端在上传过程中断开，已清理临时文件: %s", filename)
+		return
+	    }
+	    cards.WriteString(uploadError(filename, err.Error()))
+	    continue
+	}
+
+	if reused {
+	    cards.WriteString(uploadNotice(filename, job))
+	}
+	cards.WriteString(string(templates.RenderTaskCard(job)))
+	created++
+    }
+
+    if files == 0 {
+	c.Data(http.StatusBadRequest, "text/html", noFile)
+	return
+    }
+    if files > 1 {
+	log.Printf("📦 批量上传: %d 个文件，成功 %d 个", files, created)
+    }
+
+    status := http.StatusOK
+    if created == 0 {
+	status = http.StatusBadRequest
+    }
+    c.Data(status, "text/html", []byte(cards.String()))
+}
+
+// uploadFile 保存一个上传的文件并创建转录任务
+// 相同内容的文件已上传过时返回已有任务；返回的错误信息直接展示给用户
+// 相同内容的文件已转录过（或正在转录）时返回已有任务，reused 为 true；opts.Force 为 true 时总是创建新任务
+// opts.UserID 为上传的用户（未启用多用户时为空），只复用同一用户的任务
+func (app *App) uploadFile(ctx context.Context, part io.Reader, filename string, opts uploadOptions) (job *models.TranscriptionJob, reused bool, err error) {
+    ext := filepath.Ext(filename)
+    if !isValidAudioFormat(ext) {
+	return nil, false, fmt.Errorf("不支持的文件格式 %s", ext)
+    }
+
+    jobID := uuid.New().String()
+    savePath := "uploads/" + jobID + ext
+
+    size, hash, duration, err := app.saveStream(ctx, part, savePath)
+    if errors.Is(err, errFileTooLarge) {
+	return nil, false, fmt.Errorf("文件太大，最大 %.0f MB", float64(app.config.Server.MaxUploadSize)/1024/1024)
+    }
+    if errors.Is(err, errUnreadableMedia) {
+	return nil, false, fmt.Errorf("无法识别的音频/视频文件，请确认文件未损坏且格式与扩展名一致")
+    }
+    if err != nil {
+	log.Printf("❌ 保存上传文件失败: %v", err)
+	return nil, false, fmt.Errorf("保存文件失败")
+    }
+
+    log.Printf("✓ 文件已保存: %s (%.2f MB, %.0f 秒)", filepath.Base(savePath), float64(size)/1024/1024, duration)
+
+    if !opts.Force {
+	if existing := app.findDuplicate(ctx, hash, jobID); existing != nil && existing.UserID == opts.UserID {
+	    app.files.Delete(ctx, savePath)
+	    log.Printf("♻️ 文件已上传过，复用任务: %s", existing.JobID)
+	    return existing, true, nil
+	}
+    }
+
+    job = &models.TranscriptionJob{
+	JobID:       jobID,
+	Filename:    filename,
+	FilePath:    savePath,
+	Status:      models.StatusPending,
+	Progress:    0,
+	Duration:    duration,
+	FileSize:    size,
+	CreatedAt:   time.Now(),
+	ContentHash: hash,
+	Prompt:      opts.Prompt,
+	Temperature: opts.Temperature,
+	UserID:      opts.UserID,
+    }
+
+    if err := app.store.Save(ctx, job); err != nil {
+	return nil, false, fmt.Errorf("保存任务失败")
+    }
+    app.events.Publish(jobEvent(job))
+
+    // 传入请求的 context：内存队列 block 策略等待空位时，客户端断开即停止等待
+    if err := queue.EnqueueContext(ctx, app.queue, job); err != nil {
+	// 任务标记为失败，不会一直停在等待处理（消息实际已入队时 Worker 会跳过已失败的任务）
+	log.Printf("❌ 任务加入队列失败: %s: %v", jobID, err)
+	job.Status = models.StatusFailed
+	job.Error = "任务加入队列失败"
+	job.LastUpdated = time.Now()
+	if err := app.store.Save(context.WithoutCancel(ctx), job); err != nil {
+	    log.Printf("❌ 保存任务失败: %v", err)
+	}
+	app.events.Publish(jobEvent(job))
+	return nil, false, fmt.Errorf("任务加入队列失败，请重试")
+    }
+
+    log.Printf("✓ 任务已加入队列: %s", jobID)
+    return job, false, nil
+}
+
+// findDuplicate 查找相同内容的任务：优先返回已完成的任务，
+// 否则通过存储层原子登记哈希（多个 API 实例同时上传同一文件时只有一个创建任务），返回正在转录的任务
+func (app *App) findDuplicate(ctx context.Context, hash, jobID string) *models.TranscriptionJob {
+    existing, err := app.store.FindByHash(ctx, hash)
+    if err != nil {
+	log.Printf("⚠️ 按文件哈希查询任务失败: %v", err)
+    } else if existing != nil {
+	return existing
+    }
+
+    existing, err = app.claimContentHash(ctx, hash, jobID)
+    if err != nil {
+	log.Printf("⚠️ 登记文件哈希失败，跳过去重: %v", err)
+	return nil
+    }
+    return existing
+}
+
+// maxPromptLength Whisper 提示词的最大长度（字符数，Whisper 只使用提示词的最后 224 个 token）
+const maxPromptLength = 1000
+
+// uploadOptions 上传表单中的任务参数
+type uploadOptions struct {
+    Force       bool    // 跳过重复检测，重新转录
+    UserID      string  // 上传的用户（未启用多用户时为空），只复用同一用户的任务
+    Prompt      string  // Whisper 提示词（专有名词、术语）
+    Temperature float64 // Whisper 采样温度（0 到 1）
+}
+
+// setWhisperParam 解析并设置 prompt / temperature 参数（空值表示使用配置的默认值）
+// 返回的错误信息直接展示给用户
+func (opts *uploadOptions) setWhisperParam(name, value string) error {
+    value = strings.TrimSpace(value)
+    switch name {
+    case "prompt":
+	if utf8.RuneCountInString(value) > maxPromptLength {
+	    return fmt.Errorf("提示词太长，最多 %d 个字符", maxPromptLength)
+	}
+	opts.Prompt = value
+    case "temperature":
+	if value == "" {
+	    opts.Temperature = 0
+	    return nil
+	}
+	temperature, err := strconv.ParseFloat(value, 64)
+	if err != nil || temperature < 0 || temperature > 1 {
+	    return fmt.Errorf("temperature 必须是 0 到 1 之间的数字")
+	}
+	opts.Temperature = temperature
+    }
+    return nil
+}
+
+// isTruthy 解析布尔型表单值（true / 1 / on）
+func isTruthy(value string) bool {
+    switch strings.ToLower(strings.TrimSpace(value)) {
+    case "1", "true", "on", "yes":
+	return true
+    }
+    return false
+}
+
+// uploadError 单个文件上传失败的提示（filename 为空表示整批的提示）
+func uploadError(filename, message string) string {
+    if filename != "" {
+	message = filename + ": " + message
+    }
+    return fmt.Sprintf(`
+	<div class="upload-message bg-red-50 text-red-800 p-3 rounded-lg text-sm">
+	❌ %s
+	</div>
+	`, html.EscapeString(message))
+}
+
+// uploadNotice 重复上传的提示（复用已有任务，不重新转录）
+func uploadNotice(filename string, job *models.TranscriptionJob) string {
+    message := "已转录过相同内容的文件，直接显示已有结果"
+    if job.Status != models.StatusCompleted {
+	message = "相同内容的文件正在转录，直接显示该任务"
+    }
+    return fmt.Sprintf(`
+	<div class="upload-message bg-blue-50 text-blue-800 p-3 rounded-lg text-sm">
+	♻️ %s: %s（如需重新转录，请勾选"强制重新转录"后上传）
+	</div>
+	`, html.EscapeString(filename), message)
+}
+
+// recentJobsLimit 任务列表（非历史记录）最多显示的任务数，与存储层 List 的上限一致
+const recentJobsLimit = 100
+
+// handleListJobs 列出所有任务（返回 HTML）
+func (app *App) handleListJobs(c *gin.Context) {
+    jobs, err := app.listJobs(c, app.store.List, recentJobsLimit)
+    if err != nil {
+	c.Data(http.StatusInternalServerError, "text/html", []byte(`
+	    <div class="text-center py-16 text-red-400">
+	    <p class="text-5xl mb-3">❌</p>
+	    <p class="text-lg">获取任务列表失败</p>
+	    </div>
+	    `))
+	return
+    }
+
+    // 按创建时间倒序排序
+    sort.Slice(jobs, func(i, j int) bool {
+	return jobs[i].CreatedAt.After(jobs[j].CreatedAt)
+    })
+
+    html := templates.RenderTasksList(jobs)
+    c.Data(http.StatusOK, "text/html", []byte(html))
+}
+
+func (app *App) handleListJobsHistory(c *gin.Context) {
+    jobs, err := app.listJobs(c, app.store.ListAll, 0)
+    if err != nil {
+	c.Data(http.StatusInternalServerError, "text/html", []byte(`
+	    <div class="text-center py-16 text-red-400">
+	    <p class="text-5xl mb-3">❌</p>
+	    <p class="text-lg">获取任务历史失败</p>
+	    </div>
+	    `))
+	return
+    }
+    // 按创建时间倒序排序
+    sort.Slice(jobs, func(i, j int) bool {
+	return jobs[i].CreatedAt.After(jobs[j].CreatedAt)
+    })
+
+    html := templates.RenderTasksList(jobs)
+    c.Data(http.StatusOK, "text/html", []byte(html))
+
+}
+
+// handleSearchJobs 按关键词搜索任务（返回 HTML）
+func (app *App) handleSearchJobs(c *gin.Context) {
+    query := strings.TrimSpace(c.Query("q"))
+    if query == "" {
+	c.Data(http.StatusBadRequest, "text/html", []byte(`
+	    <div class="bg-red-50 text-red-800 p-3 rounded-lg text-sm">
+	    ❌ 请输入搜索关键词
+	    </div>
+	    `))
+	return
+    }
+
+    jobs, err := app.store.Search(c.Request.Context(), query)
+    if err != nil {
+	log.Printf("❌ 搜索任务失败: %v", err)
+	c.Data(http.StatusInternalServerError, "text/html", []byte(`
+	    <div class="text-center py-16 text-red-400">
+	    <p class="text-5xl mb-3">❌</p>
+	    <p class="text-lg">搜索失败</p>
+	    </div>
+	    `))
+	return
+    }
+    jobs = ownJobs(c, jobs)
+
+    html := templates.RenderSearchResults(jobs, query)
+    c.Data(http.StatusOK, "text/html", []byte(html))
+}
+
+// wordJob 单词出现过的任务及上下文
+type wordJob struct {
+    JobID     string    `json:"job_id"`
+    Filename  string    `json:"filename"`
+    CreatedAt time.Time `json:"created_at"`
+    Context   string    `json:"context"` // 转录文本中包含该单词的句子（找不到时为提取单词时的例句）
+}
+
+// handleWordJobs 查询单词出现过的所有任务（返回 JSON）
+func (app *App) handleWordJobs(c *gin.Context) {
+    word := vocabulary.Normalize(c.Param("word"))
+    if word == "" {
+	c.JSON(http.StatusBadRequest, gin.H{"error": "单词不能为空"})
+	return
+    }
+
+    jobs, err := app.store.JobsByWord(c.Request.Context(), word)
+    if err != nil {
+	log.Printf("❌ 查询单词索引失败: %v", err)
+	c.JSON(http.StatusInternalServerError, gin.H{"error": "查询失败"})
+	return
+    }
+    jobs = ownJobs(c, jobs)
+
+    results := make([]wordJob, 0, len(jobs))
+    for _, job := range jobs {
+	results = append(results, wordJob{
+	    JobID:     job.JobID,
+	    Filename:  job.Filename,
+	    CreatedAt: job.CreatedAt,
+	    Context:   wordContext(job, word),
+	})
+    }
+
+    c.JSON(http.StatusOK, gin.H{
+	"word": word,
+	"jobs": results,
+    })
+}
+
+// wordContext 查找单词在任务中的上下文：优先取转录文本中的原句，其次取单词详情中的例句
+func wordContext(job *models.TranscriptionJob, word string) string {
+    if sentence := vocabulary.SentenceContext(job.Result, word); sentence != "" {
+	return sentence
+    }
+    for _, detail := range job.VocabDetail {
+	if vocabulary.Normalize(detail.Word) == word {
+	    return detail.Example
+	}
+    }
+    return ""
+}
+
+// wordIndexMarker 单词索引回填状态（保存在永不过期的登记中，值为 wordIndexRunning 或 wordIndexDone）
+// 内存存储重启后登记和任务一起清空，重新回填时没有任务需要处理
+// 修改 vocabulary.Normalize 的规则后需要更换版本号以重建索引
+const wordIndexMarker = "word-index:v1"
+
+const (
+    wordIndexRunning = "backfill"      // 回填已开始（实例中途退出时保持此值，下次启动继续回填）
+    wordIndexDone    = "backfill-done" // 回填已完成
+)
+
+// backfillWordIndex 为已有任务回填单词索引
+// IndexWords 整体替换任务的单词索引，回填可以重复执行：只有全部任务处理完才标记完成，
+// 中途退出或失败时下次启动重新回填；多个实例同时回填只是重复写入相同的索引
+func (app *App) backfillWordIndex() {
+    ctx := context.Background()
+    state, _, err := storage.SetMarkerIfAbsent(ctx, app.store, wordIndexMarker, wordIndexRunning)
+    if err != nil {
+	log.Printf("⚠️  检查单词索引回填状态失败: %v", err)
+	return
+    }
+    if state == wordIndexDone {
+	return
+    }
+
+    // 分页遍历（数据库存储不会一次加载所有任务）
+    indexed := 0
+    err = storage.ForEachJob(ctx, app.store, func(job *models.TranscriptionJob) error {
+	if len(job.Vocabulary) == 0 {
+	    return nil
+	}
+	if err := app.store.IndexWords(ctx, job.JobID, vocabulary.FilterDuplicates(job.Vocabulary)); err != nil {
+	    return fmt.Errorf("任务 %s: %w", job.JobID, err)
+	}
+	indexed++
+	return nil
+    })
+    if err != nil {
+	log.Printf("⚠️  回填单词索引失败（下次启动时重试）: %v", err)
+	return
+    }
+
+    // 标记完成：替换"进行中"标记（其他实例已经标记完成或正在回填时，由它们完成标记）
+    if err := storage.DeleteMarker(ctx, app.store, wordIndexMarker, wordIndexRunning); err != nil {
+	log.Printf("⚠️  更新单词索引回填状态失败: %v", err)
+	return
+    }
+    if _, _, err := storage.SetMarkerIfAbsent(ctx, app.store, wordIndexMarker, wordIndexDone); err != nil {
+	log.Printf("⚠️  更新单词索引回填状态失败: %v", err)
+	return
+    }
+
+    if indexed > 0 {
+	log.Printf("✓ 单词索引回填完成 (%d 个任务)", indexed)
+    }
+}
+
+// handleActiveSummary 返回所有未结束任务的汇总（页头轮询使用）
+// 默认返回 HTML 片段，Accept: application/json 时返回 JSON
+func (app *App) handleActiveSummary(c *gin.Context) {
+    jobs, err := app.store.ListSummaries(c.Request.Context(), models.ActiveStatuses...)
+    if err != nil {
+	log.Printf("❌ 查询进行中任务失败: %v", err)
+	c.Data(http.StatusInternalServerError, "text/html", []byte("获取任务状态失败"))
+	return
+    }
+    if user := middleware.CurrentUser(c); user.Restricted() {
+	owned := jobs[:0]
+	for _, job := range jobs {
+	    if job.UserID == user.ID {
+		owned = append(owned, job)
+	    }
+	}
+	jobs = owned
+    }
+
+    summary := models.SummarizeActive(jobs)
+    if c.NegotiateFormat(gin.MIMEHTML, gin.MIMEJSON) == gin.MIMEJSON {
+	c.JSON(http.StatusOK, summary)
+	return
+    }
+
+    c.Data(http.StatusOK, "text/html", []byte(templates.RenderActiveSummary(summary)))
+}
+
+// handleJobsCount 返回任务计数（返回 HTML）
+// 通过 Store.CountByStatus 统计，不读取任务数据；多用户模式下普通用户只统计本人的任务
+func (app *App) handleJobsCount(c *gin.Context) {
+    userID := ""
+    if user := middleware.CurrentUser(c); user.Restricted() {
+	userID = user.ID
+    }
+
+    counts, err := app.store.CountByStatus(c.Request.Context(), userID)
+    if err != nil {
+	log.Printf("⚠️ 统计任务数量失败: %v", err)
+	c.Data(http.StatusOK, "text/html", []byte("0 个任务"))
+	return
+    }
+
+    total := 0
+    for _, count := range counts {
+	total += count
+    }
+    html := fmt.Sprintf("%d 个任务", total)
+    c.Data(http.StatusOK, "text/html", []byte(html))
+}
+
+// handleJobsCountByStatus 按状态统计任务数量（返回 HTML，Accept: application/json 时返回 JSON）
+// 多用户模式下普通用户只统计本人的任务
+func (app *App) handleJobsCountByStatus(c *gin.Context) {
+    userID := ""
+    if user := middleware.CurrentUser(c); user.Restricted() {
+	userID = user.ID
+    }
+
+    counts, err := app.store.CountByStatus(c.Request.Context(), userID)
+    if err != nil {
+	log.Printf("❌ 统计任务状态失败: %v", err)
+	if c.NegotiateFormat(gin.MIMEHTML, gin.MIMEJSON) == gin.MIMEJSON {
+	    c.JSON(http.StatusInternalServerError, gin.H{"error": "统计任务状态失败"})
+	    return
+	}
+	c.Data(http.StatusInternalServerError, "text/html", []byte("统计任务状态失败"))
+	return
+    }
+
+    if c.NegotiateFormat(gin.MIMEHTML, gin.MIMEJSON) == gin.MIMEJSON {
+	total := 0
+	for _, count := range counts {
+	    total += count
+	}
+	c.JSON(http.StatusOK, statusCountsResponse{Counts: counts, Total: total})
+	return
+    }
+
+    c.Data(http.StatusOK, "text/html", []byte(templates.RenderStatusCounts(counts)))
+}
+
+// handleGetJob 获取任务状态（返回 HTML）
+func (app *App) handleGetJob(c *gin.Context) {
+    jobID := c.Param("job_id")
+
+    job, err := app.getJob(c, jobID)
+    if err != nil {
+	c.Data(http.StatusNotFound, "text/html", []byte(`
+	    <div class="bg-red-50 text-red-800 p-3 rounded-lg text-sm">
+	    ❌ 任务不存在
+	    </div>
+	    `))
+	return
+    }
+
+    if c.NegotiateFormat(gin.MIMEHTML, gin.MIMEJSON) == gin.MIMEJSON {
+	c.JSON(http.StatusOK, job)
+	return
+    }
+
+    html := templates.RenderTaskCard(job)
+    c.Data(http.StatusOK, "text/html", []byte(html))
+}
+
+// handleJobDetails 获取任务详情（返回 HTML）
+func (app *App) handleJobDetails(c *gin.Context) {
+    jobID := c.Param("job_id")
+
+    job, err := app.getJob(c, jobID)
+    if err != nil {
+	c.Data(http.StatusNotFound, "text/html", []byte(`
+	    <div class="bg-red-50 text-red-800 p-3 rounded-lg text-sm">
+	    ❌ 任务不存在
+	    </div>
+	    `))
+	return
+    }
+
+    html := templates.RenderTaskDetails(job, app.syncTargets())
+    c.Data(http.StatusOK, "text/html", []byte(html))
+}
+
+// handleDownloadResult 下载转录结果
+func (app *App) handleDownloadResult(c *gin.Context) {
+    jobID := c.Param("job_id")
+
+    job, err := app.getJob(c, jobID)
+    if err != nil {
+	c.JSON(http.StatusNotFound, gin.H{"error": "任务不存在"})
+	return
+    }
+
+    if job.Status != models.StatusCompleted || job.Result == "" {
+	c.JSON(http.StatusBadRequest, gin.H{"error": "任务尚未完成或无结果"})
+	return
+    }
+
+    // 设置下载响应头
+    c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s_转录.txt", job.Filename))
+    c.Data(http.StatusOK, "text/plain; charset=utf-8", []byte(job.Result))
+}
+
+// handleMedia 返回原始媒体文件：本地存储直接返回文件，S3 跳转到预签名地址
+func (app *App) handleMedia(c *gin.Context) {
+    job, err := app.getJob(c, c.Param("job_id"))
+    if err != nil {
+	c.JSON(http.StatusNotFound, gin.H{"error": "任务不存在"})
+	return
+    }
+    if job.FilePath == "" {
+	c.JSON(http.StatusNotFound, gin.H{"error": "原始媒体已清理"})
+	return
+    }
+
+    // 本地存储直接返回文件（http.ServeFile 支持 Range，播放器可以拖动进度）
+    if local, ok := app.files.(*filestore.LocalStore); ok {
+	c.File(local.Path(job.FilePath))
+	return
+    }
+
+    mediaURL, err := app.files.URL(c.Request.Context(), job.FilePath)
+    if err != nil {
+	log.Printf("❌ 生成媒体地址失败: %v", err)
+	c.JSON(http.StatusInternalServerError, gin.H{"error": "生成媒体地址失败"})
+	return
+    }
+    c.Redirect(http.StatusFound, mediaURL)
+}
+
+// handleDownloadSubtitle 下载 SRT 字幕文件
+func (app *App) handleDownloadSubtitle(c *gin.Context) {
+    jobID := c.Param("job_id")
+
+    job, err := app.getJob(c, jobID)
+    if err != nil {
+	c.JSON(http.StatusNotFound, gin.H{"error": "任务不存在"})
+	return
+    }
+
+    if job.Status != models.StatusCompleted || job.SubtitlePath == "" {
+	c.JSON(http.StatusBadRequest, gin.H{"error": "任务尚未完成或无字幕文件"})
+	return
+    }
+
+    // 读取 SRT 文件内容
+    srtContent, err := filestore.ReadAll(c.Request.Context(), app.files, job.SubtitlePath)
+    if err != nil {
+	c.JSON(http.StatusInternalServerError, gin.H{"error": "读取字幕文件失败"})
+	return
+    }
+
+    // 安全的文件名（移除特殊字符）
+    safeFilename := strings.TrimSuffix(job.Filename, filepath.Ext(job.Filename))
+    safeFilename = strings.ReplaceAll(safeFilename, `"`, "")
+
+    // 设置下载响应头（修复 Safari 兼容性）
+    c.Header("Content-Type", "text/plain; charset=utf-8")
+    c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.srt"`, safeFilename))
+    c.Header("Content-Length", fmt.Sprintf("%d", len(srtContent)))
+    c.Data(http.StatusOK, "text/plain; charset=utf-8", srtContent)
+}
+
+// handleSubtitleVTT 返回 WebVTT 字幕文件（用于视频播放器）
+func (app *App) handleSubtitleVTT(c *gin.Context) {
+    jobID := c.Param("job_id")
+
+    job, err := app.getJob(c, jobID)
+    if err != nil {
+	c.JSON(http.StatusNotFound, gin.H{"error": "任务不存在"})
+	return
+    }
+
+    if job.Status != models.StatusCompleted || job.VTTPath == "" {
+	c.JSON(http.StatusBadRequest, gin.H{"error": "任务尚未完成或无字幕文件"})
+	return
+    }
+
+    // 读取 VTT 文件内容
+    vttContent, err := filestore.ReadAll(c.Request.Context(), app.files, job.VTTPath)
+    if err != nil {
+	c.JSON(http.StatusInternalServerError, gin.H{"error": "读取字幕文件失败"})
+	return
+    }
+
+    // 设置 CORS 和响应头（允许视频播放器访问）
+    c.Header("Content-Type", "text/vtt; charset=utf-8")
+    c.Header("Cache-Control", "public, max-age=3600")
+    c.Data(http.StatusOK, "text/vtt; charset=utf-8", vttContent)
+}
+
+// handleTimestampedTranscript 下载带时间戳的转录文本（[00:01:23] 文本）
+// 优先解析 WebVTT 字幕，没有时解析 SRT 字幕；只保存了纯文本结果的任务没有时间信息，返回 400
+func (app *App) handleTimestampedTranscript(c *gin.Context) {
+    job, err := app.getJob(c, c.Param("job_id"))
+    if err != nil {
+	c.JSON(http.StatusNotFound, gin.H{"error": "任务不存在"})
+	return
+    }
+
+    if job.Status != models.StatusCompleted {
+	c.JSON(http.StatusBadRequest, gin.H{"error": "任务尚未完成"})
+	return
+    }
+    if job.VTTPath == "" && job.SubtitlePath == "" {
+	c.JSON(http.StatusBadRequest, gin.H{"error": "该任务只有纯文本结果，没有字幕文件，无法生成带时间戳的文本"})
+	return
+    }
+
+    content, err := artifacts.TimestampedTranscript(c.Request.Context(), app.files, job)
+    if err != nil {
+	log.Printf("❌ 读取字幕失败: %v", err)
+	c.JSON(http.StatusInternalServerError, gin.H{"error": "读取字幕文件失败"})
+	return
+    }
+
+    c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.timestamped.txt"`, artifacts.BaseName(job)))
+    c.Data(http.StatusOK, "text/plain; charset=utf-8", content)
+}
+
+// handleGeneratedArtifact 下载由任务数据即时生成的产物（见 artifacts.Generate）
+func (app *App) handleGeneratedArtifact(kind string) gin.HandlerFunc {
+    return func(c *gin.Context) {
+	job, err := app.getJob(c, c.Param("job_id"))
+	if err != nil {
+	    c.JSON(http.StatusNotFound, gin.H{"error": "任务不存在"})
+	    return
+	}
+
+	artifact, data, err := artifacts.Generate(c.Request.Context(), app.files, job, kind)
+	if errors.Is(err, artifacts.ErrUnavailable) {
+	    c.JSON(http.StatusBadRequest, gin.H{"error": "任务尚未完成或没有转录文本"})
+	    return
+	}
+	if err != nil {
+	    log.Printf("❌ 生成 %s 失败: %v", kind, err)
+	    c.JSON(http.StatusInternalServerError, gin.H{"error": "生成文件失败"})
+	    return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, artifact.Filename))
+	c.Data(http.StatusOK, artifact.ContentType, data)
+    }
+}
+
+// handleDownloadZip 打包下载任务的所有产物（原始媒体除外，内容见 artifacts.WriteZip）
+func (app *App) handleDownloadZip(c *gin.Context) {
+    job, err := app.getJob(c, c.Param("job_id"))
+    if err != nil {
+	c.JSON(http.StatusNotFound, gin.H{"error": "任务不存在"})
+	return
+    }
+
+    var buf bytes.Buffer
+    err = artifacts.WriteZip(c.Request.Context(), app.files, job, &buf)
+    if errors.Is(err, artifacts.ErrUnavailable) {
+	c.JSON(http.StatusBadRequest, gin.H{"error": "任务尚未完成，没有可下载的文件"})
+	return
+    }
+    if err != nil {
+	log.Printf("❌ 打包任务 %s 失败: %v", job.JobID, err)
+	c.JSON(http.StatusInternalServerError, gin.H{"error": "打包失败"})
+	return
+    }
+
+    c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.zip"`, artifacts.BaseName(job)))
+    c.Data(http.StatusOK, "application/zip", buf.Bytes())
+}
+
+// handleSharePage 任务分享页面（完整 HTML 页面，产物列表与 /api/v1/jobs/:job_id/artifacts 一致）
+func (app *App) handleSharePage(c *gin.Context) {
+    job, err := app.getJob(c, c.Param("job_id"))
+    if err != nil {
+	c.Data(http.StatusNotFound, "text/html; charset=utf-8", []byte("<p>任务不存在</p>"))
+	return
+    }
+
+    page := templates.RenderSharePage(job, artifacts.List(c.Request.Context(), app.files, job))
+    c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(page))
+}
+
+// errJobRestarted 修正结果时任务已重新开始转录
+var errJobRestarted = errors.New("任务已重新开始转录")
+
+// handleEditResult 手动修正转录结果（JSON 接口）
+// 提供 cues 时先把 SRT / VTT 写到新的 key（媒体文件旁边），保存任务时再替换字幕路径：
+// 保存前任务被重新转录或保存失败时删除新文件，原来的字幕不受影响；保存成功后删除旧字幕
+func (app *App) handleEditResult(c *gin.Context) {
+    ctx := c.Request.Context()
+    jobID := c.Param("job_id")
+
+    job, err := app.getJob(c, jobID)
+    if err != nil {
+	c.JSON(http.StatusNotFound, gin.H{"error": "任务不存在"})
+	return
+    }
+    if job.Status != models.StatusCompleted {
+	c.JSON(http.StatusBadRequest, gin.H{"error": "任务尚未完成"})
+	return
+    }
+
+    var req editResultRequest
+    if err := c.ShouldBindJSON(&req); err != nil {
+	c.JSON(http.StatusBadRequest, gin.H{"error": "请求体不是有效的 JSON"})
+	return
+    }
+    if req.Text == nil && req.Cues == nil {
+	c.JSON(http.StatusBadRequest, gin.H{"error": "text 和 cues 至少提供一个"})
+	return
+    }
+
+    var cues []transcriber.Cue
+    if req.Cues != nil {
+	if len(req.Cues) == 0 {
+	    c.JSON(http.StatusBadRequest, gin.H{"error": "cues 不能为空"})
+	    return
+	}
+	edited := slices.Clone(req.Cues)
+	slices.SortStableFunc(edited, func(a, b editedCue) int { return a.Index - b.Index })
+	for _, cue := range edited {
+	    cues = append(cues, transcriber.Cue{Start: cue.Start, End: cue.End, Text: strings.TrimSpace(cue.Text)})
+	}
+	if err := transcriber.ValidateCues(cues); err != nil {
+	    c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	    return
+	}
+    }
+
+    text := transcriber.CuesText(cues)
+    if req.Text != nil {
+	text = strings.TrimSpace(*req.Text)
+    }
+    if text == "" {
+	c.JSON(http.StatusBadRequest, gin.H{"error": "转录文本不能为空"})
+	return
+    }
+
+    // 每次修正使用新的 key（以任务 ID 开头，清理器按任务识别），不覆盖任务当前引用的字幕
+    var srtKey, vttKey string
+    if cues != nil {
+	base := strings.TrimSuffix(job.FilePath, path.Ext(job.FilePath)) + ".edit-" + uuid.New().String()[:8]
+	srtKey, vttKey = base+".srt", base+".vtt"
+    }
+    // discardNew 删除本次写入的字幕（修正未保存时调用）
+    discardNew := func() {
+	for _, key := range []string{srtKey, vttKey} {
+	    if key == "" {
+		continue
+	    }
+	    if err := app.files.Delete(context.WithoutCancel(ctx), key); err != nil {
+		log.Printf("⚠️ 删除未保存的字幕失败: %s: %v", key, err)
+	    }
+	}
+    }
+    if cues != nil {
+	if err := app.writeSubtitle(ctx, srtKey, func(local string) error {
+	    return transcriber.WriteSRT(cues, local)
+	}); err != nil {
+	    log.Printf("❌ 重新生成 SRT 字幕失败: %v", err)
+	    discardNew()
+	    c.JSON(http.StatusInternalServerError, gin.H{"error": "生成字幕文件失败"})
+	    return
+	}
+	if err := app.writeSubtitle(ctx, vttKey, func(local string) error {
+	    return transcriber.WriteVTT(cues, local)
+	}); err != nil {
+	    log.Printf("❌ 重新生成 VTT 字幕失败: %v", err)
+	    discardNew()
+	    c.JSON(http.StatusInternalServerError, gin.H{"error": "生成字幕文件失败"})
+	    return
+	}
+    }
+
+    var staleFiles []string
+    job, err = storage.Modify(ctx, app.store, jobID, func(latest *models.TranscriptionJob) error {
+	// 期间被重新转录时不覆盖（新的结果由 Worker 生成）
+	if latest.Status != models.StatusCompleted {
+	    return errJobRestarted
+	}
+	latest.Result = text
+	if cues != nil {
+	    staleFiles = []string{latest.SubtitlePath, latest.VTTPath, latest.BilingualSRTPath, latest.BilingualVTTPath}
+	    latest.SubtitlePath = srtKey
+	    latest.VTTPath = vttKey
+	    latest.BilingualSRTPath = ""
+	    latest.BilingualVTTPath = ""
+	}
+	latest.LastUpdated = time.Now()
+	return nil
+    })
+    if err != nil {
+	discardNew()
+	if errors.Is(err, errJobRestarted) {
+	    c.JSON(http.StatusConflict, gin.H{"error": "任务已重新开始转录，修正未保存"})
+	    return
+	}
+	log.Printf("❌ 保存修正结果失败: %v", err)
+	c.JSON(http.StatusInternalServerError, gin.H{"error": "保存任务失败"})
+	return
+    }
+
+    for _, key := range staleFiles {
+	if key == "" || key == srtKey || key == vttKey {
+	    continue
+	}
+	if err := app.files.Delete(ctx, key); err != nil {
+	    log.Printf("⚠️ 删除旧字幕失败: %s: %v", key, err)
+	}
+    }
+    app.events.Publish(jobEvent(job))
+
+    log.Printf("✏️ 转录结果已修正: %s (%d 个字符, %d 条字幕)", jobID, len(text), len(cues))
+    c.JSON(http.StatusOK, job)
+}
+
+// handleListSources 列出播客订阅源及最近一次轮询结果（返回 JSON）
+func (app *App) handleListSources(c *gin.Context) {
+    c.JSON(http.StatusOK, gin.H{"sources": app.sources.List()})
+}
+
+// handleEnableSource 启用订阅源（立即轮询一次）
+func (app *App) handleEnableSource(c *gin.Context) {
+    app.setSourceEnabled(c, true)
+}
+
+// handleDisableSource 停用订阅源
+func (app *App) handleDisableSource(c *gin.Context) {
+    app.setSourceEnabled(c, false)
+}
+
+// setSourceEnabled 启用或停用订阅源（管理接口，订阅源创建的任务不属于任何用户）
+func (app *App) setSourceEnabled(c *gin.Context, enabled bool) {
+    if middleware.CurrentUser(c).Restricted() {
+	c.JSON(http.StatusForbidden, gin.H{"error": "需要管理员权限"})
+	return
+    }
+
+    status, err := app.sources.SetEnabled(c.Param("name"), enabled)
+    if err != nil {
+	c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	return
+    }
+    c.JSON(http.StatusOK, status)
+}
+
+// statusProgressDone htmx 约定的"停止轮询"状态码
+const statusProgressDone = 286
+
+// handleJobProgress 返回任务进度片段（供无法使用 SSE 的客户端轮询）
+// 响应带 ETag（由状态、进度和 LastUpdated 计算），进度没有变化时返回 304 不带正文；
+// 任务结束后返回 286 让 htmx 停止轮询，并触发 taskUpdated 刷新任务列表
+func (app *App) handleJobProgress(c *gin.Context) {
+    job, err := app.getJob(c, c.Param("job_id"))
+    if err != nil {
+	c.Data(http.StatusNotFound, "text/html", []byte(`<span>任务不存在</span>`))
+	return
+    }
+
+    etag := fmt.Sprintf(`"%s-%d-%d"`, job.Status, job.Progress, job.LastUpdated.UnixNano())
+    c.Header("ETag", etag)
+    c.Header("Cache-Control", "no-cache") // 浏览器每次都带 If-None-Match 重新验证
+    if c.GetHeader("If-None-Match") == etag {
+	c.Status(http.StatusNotModified)
+	return
+    }
+
+    terminal := job.Status == models.StatusCompleted || job.Status == models.StatusFailed
+    status := http.StatusOK
+    if terminal {
+	status = statusProgressDone
+	c.Header("HX-Trigger", "taskUpdated")
+    }
+    c.Data(status, "text/html", []byte(templates.RenderJobProgress(job, !terminal)))
+}
+
+// handleJobEvents 通过 Server-Sent Events 实时推送任务进度
+// 任务进入 completed / failed 后结束推送；客户端断开时自动取消订阅。
+// 处理任务的 Worker 可能在其他实例上（收不到本进程事件），因此定期从存储补充检查状态
+func (app *App) handleJobEvents(c *gin.Context) {
+    jobID := c.Param("job_id")
+
+    // 先订阅再读取当前状态，避免错过两者之间发布的事件
+    ch, unsubscribe := app.events.Subscribe(jobID)
+    defer unsubscribe()
+
+    job, err := app.getJob(c, jobID)
+    if err != nil {
+	c.JSON(http.StatusNotFound, gin.H{"error": "任务不存在"})
+	return
+    }
+
+    c.Header("Content-Type", "text/event-stream")
+    c.Header("Cache-Control", "no-cache")
+    c.Header("Connection", "keep-alive")
+    c.Header("X-Accel-Buffering", "no") // 禁用 Nginx 缓冲
+
+    last := jobEvent(job)
+    if !writeSSE(c, last) || last.Terminal() {
+	return
+    }
+
+    ticker := time.NewTicker(5 * time.Second)
+    defer ticker.Stop()
+
+    for {
+	var event events.Event
+	select {
+	case <-c.Request.Context().Done():
+	    return
+	case event = <-ch:
+	case <-ticker.C:
+	    job, err := app.store.Get(c.Request.Context(), jobID)
+	    if err != nil {
+		return
+	    }
+	    event = jobEvent(job)
+	    if event == last {
+		// 状态未变化，发送注释行保持连接
+		if _, err := fmt.Fprint(c.Writer, ": ping\n\n"); err != nil {
+		    return
+		}
+		c.Writer.Flush()
+		continue
+	    }
+	}
+
+	if !writeSSE(c, event) || event.Terminal() {
+	    return
+	}
+	last = event
+    }
+}
+
+// jobEvent 由任务当前状态构造进度事件
+func jobEvent(job *models.TranscriptionJob) events.Event {
+    return events.Event{
+	JobID:    job.JobID,
+	Status:   job.Status,
+	Progress: job.Progress,
+	Error:    job.Error,
+	UserID:   job.UserID,
+    }
+}
+
+// WebSocket 连接参数
+const (
+    wsWriteWait    = 10 * time.Second    // 单条消息的写超时
+    wsPongWait     = 60 * time.Second    // 超过该时间未收到 pong 视为连接已断开
+    wsPingInterval = wsPongWait * 9 / 10 // 发送 ping 的间隔（需小于 wsPongWait）
+)
+
+// handleJobsWebSocket 通过 WebSocket 推送所有任务的状态变化（任务列表实时刷新）
+// 每个连接注册为广播器的客户端：发送缓冲区写满（客户端过慢）时广播器断开客户端，这里随即关闭连接
+func (app *App) handleJobsWebSocket(c *gin.Context) {
+    upgrader := websocket.Upgrader{CheckOrigin: app.checkWebSocketOrigin}
+    conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
+    if err != nil {
+	// Upgrade 已写入错误响应
+	log.Printf("⚠️ WebSocket 握手失败: %v", err)
+	return
+    }
+    defer conn.Close()
+
+    user := middleware.CurrentUser(c)
+    client := app.broadcaster.Register(func(e events.Event) bool {
+	return !user.Restricted() || e.UserID == user.ID
+    })
+    defer app.broadcaster.Unregister(client)
+
+    // 读循环：处理 pong 和关闭帧，客户端发送的其他消息忽略
+    done := make(chan struct{})
+    go func() {
+	defer close(done)
+	conn.SetReadLimit(512)
+	conn.SetReadDeadline(time.Now().Add(wsPongWait))
+	conn.SetPongHandler(func(string) error {
+	    return conn.SetReadDeadline(time.Now().Add(wsPongWait))
+	})
+	for {
+	    if _, _, err := conn.ReadMessage(); err != nil {
+		return
+	    }
+	}
+    }()
+
+    ticker := time.NewTicker(wsPingInterval)
+    defer ticker.Stop()
+
+    for {
+	select {
+	case event, ok := <-client.Events():
+	    conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+	    if !ok {
+		// 客户端过慢或服务关闭
+		conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseGoingAway, ""))
+		return
+	    }
+	    if err := conn.WriteJSON(event); err != nil {
+		return
+	    }
+	case <-ticker.C:
+	    conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+	    if err := conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+		return
+	    }
+	case <-done:
+	    return
+	}
+    }
+}
+
+// checkWebSocketOrigin 允许同源连接和跨域配置中允许的来源（浏览器的 WebSocket 不受 CORS 限制，需要在握手时检查）
+func (app *App) checkWebSocketOrigin(r *http.Request) bool {
+    origin := r.Header.Get("Origin")
+    if origin == "" {
+	return true // 非浏览器客户端
+    }
+    if u, err := url.Parse(origin); err == nil && u.Host == r.Host {
+	return true
+    }
+    for _, allowed := range app.config.Server.CORS.AllowedOrigins {
+	if allowed == "*" || strings.TrimSuffix(allowed, "/") == origin {
+	    return true
+	}
+    }
+    return false
+}
+
+// writeSSE 写入一帧 SSE 数据并立即刷新，连接已断开时返回 false
+func writeSSE(c *gin.Context, event events.Event) bool {
+    data, err := json.Marshal(event)
+    if err != nil {
+	return false
+    }
+    if _, err := fmt.Fprintf(c.Writer, "data: %s\n\n", data); err != nil {
+	return false
+    }
+    c.Writer.Flush()
+    return true
+}
+
+// handleGetResult 返回任务结果描述（与 webhook payload 中的 job 一致）
+func (app *App) handleGetResult(c *gin.Context) {
+    jobID := c.Param("job_id")
+
+    job, err := app.getJob(c, jobID)
+    if err != nil {
+	c.JSON(http.StatusNotFound, gin.H{"error": "任务不存在"})
+	return
+    }
+
+    c.JSON(http.StatusOK, artifacts.BuildResult(c.Request.Context(), app.files, job, app.config.Server.PublicBaseURL))
+}
+
+// handleListArtifacts 列出任务所有可下载产物（返回 JSON）
+func (app *App) handleListArtifacts(c *gin.Context) {
+    jobID := c.Param("job_id")
+
+    job, err := app.getJob(c, jobID)
+    if err != nil {
+	c.JSON(http.StatusNotFound, gin.H{"error": "任务不存在"})
+	return
+    }
+
+    c.JSON(http.StatusOK, gin.H{
+	"job_id":    job.JobID,
+	"status":    job.Status,
+	"artifacts": artifacts.List(c.Request.Context(), app.files, job),
+    })
+}
+
+// handleDeleteJob 软删除任务（返回带撤销按钮的提示，替换任务卡片）
+// 媒体和字幕文件保留到恢复期限之后，由后台清理器永久删除任务时一并清理
+func (app *App) handleDeleteJob(c *gin.Context) {
+    jobID := c.Param("job_id")
+
+    job, err := app.getJob(c, jobID)
+    if err == nil {
+	err = app.store.Delete(c.Request.Context(), jobID)
+    }
+    if err != nil {
+	log.Printf("❌ 删除任务失败: %v", err)
+	c.Data(http.StatusNotFound, "text/html", []byte(`
+	    <div class="bg-red-50 text-red-800 p-3 rounded-lg text-sm">
+	    ❌ 删除失败
+	    </div>
+	    `))
+	return
+    }
+
+    if days := app.config.Retention.DeletedAfterDays; days > 0 {
+	log.Printf("✓ 任务已删除: %s (%d 天内可以恢复)", jobID, days)
+    } else {
+	log.Printf("✓ 任务已删除: %s", jobID)
+    }
+
+    c.Data(http.StatusOK, "text/html", []byte(templates.RenderDeletedJob(job)))
+}
+
+// handleRestoreJob 恢复软删除的任务（超过恢复期限的任务即将被永久删除，不再恢复）
+func (app *App) handleRestoreJob(c *gin.Context) {
+    ctx := c.Request.Context()
+    wantJSON := c.NegotiateFormat(gin.MIMEHTML, gin.MIMEJSON) == gin.MIMEJSON
+    reject := func(code int, message string) {
+	if wantJSON {
+	    c.JSON(code, gin.H{"error": message})
+	    return
+	}
+	c.Data(code, "text/html", []byte(fmt.Sprintf(`
+	    <div class="bg-red-50 text-red-800 p-3 rounded-lg text-sm">
+	    ❌ %s
+	    </div>
+	    `, html.EscapeString(message))))
+    }
+
+    // 已删除的任务 getJob 视为不存在，这里直接读取后检查归属
+    jobID := c.Param("job_id")
+    job, err := app.store.Get(ctx, jobID)
+    if err == nil {
+	if user := middleware.CurrentUser(c); user.Restricted() && job.UserID != user.ID {
+	    err = fmt.Errorf("任务不存在: %s", jobID)
+	}
+    }
+    if err != nil || job.DeletedAt.IsZero() {
+	reject(http.StatusNotFound, "任务不存在或未被删除")
+	return
+    }
+
+    if days := app.config.Retention.DeletedAfterDays; days > 0 && time.Since(job.DeletedAt) > time.Duration(days)*24*time.Hour {
+	reject(http.StatusGone, fmt.Sprintf("任务已删除超过 %d 天，无法恢复", days))
+	return
+    }
+
+    if err := app.store.Restore(ctx, jobID); err != nil {
+	log.Printf("❌ 恢复任务失败: %v", err)
+	reject(http.StatusNotFound, "任务不存在或未被删除")
+	return
+    }
+    job, err = app.store.Get(ctx, jobID)
     if err != nil {
-	c.Data(http.StatusOK, "text/html", []byte("0 个任务"))
+	log.Printf("❌ 恢复任务后读取失败: %v", err)
+	reject(http.StatusInternalServerError, "读取任务失败")
 	return
     }
+    log.Printf("↩️ 任务已恢复: %s", jobID)
+    app.events.Publish(jobEvent(job))
 
-    html := fmt.Sprintf("%d 个任务", len(jobs))
-    c.Data(http.StatusOK, "text/html", []byte(html))
+    if wantJSON {
+	c.JSON(http.StatusOK, job)
+	return
+    }
+    c.Header("HX-Trigger", "taskUpdated")
+    c.Data(http.StatusOK, "text/html", []byte(templates.RenderTaskCard(job)))
 }
 
-// handleGetJob 获取任务状态（返回 HTML）
-func (app *App) handleGetJob(c *gin.Context) {
+// handleReTranscribe 使用原始媒体重新转录已结束的任务（可指定新的语言和模型）
+// 清除转录结果和字幕、重置进度后重新加入队列；单词默认一并清除，keep_vocabulary=true 时保留
+func (app *App) handleReTranscribe(c *gin.Context) {
+    ctx := c.Request.Context()
+    wantJSON := c.NegotiateFormat(gin.MIMEHTML, gin.MIMEJSON) == gin.MIMEJSON
+    reject := func(code int, message string) {
+	if wantJSON {
+	    c.JSON(code, gin.H{"error": message})
+	    return
+	}
+	c.Data(code, "text/html", []byte(fmt.Sprintf(`
+	    <div class="bg-red-50 text-red-800 p-3 rounded-lg text-sm">
+	    ❌ %s
+	    </div>
+	    `, html.EscapeString(message))))
+    }
+
+    jobID := c.Param("job_id")
+    job, err := app.getJob(c, jobID)
+    if err != nil {
+	reject(http.StatusNotFound, "任务不存在")
+	return
+    }
+
+    if job.Status == models.StatusPending || job.Status == models.StatusProcessing {
+	reject(http.StatusConflict, "任务正在转录中，请等待结束后再重新转录")
+	return
+    }
+
+    // 原始媒体已按保留策略清理或被删除时无法重新转录
+    if job.MediaPurged || job.FilePath == "" {
+	reject(http.StatusGone, "原始媒体已删除，无法重新转录")
+	return
+    }
+    if _, err := app.files.Stat(ctx, job.FilePath); err != nil {
+	log.Printf("⚠️ 重新转录时读取原始媒体失败: %s: %v", job.FilePath, err)
+	reject(http.StatusGone, "原始媒体已删除，无法重新转录")
+	return
+    }
+
+    // 未提供的参数沿用任务原来的设置，提供空值（或 auto）表示自动检测语言 / 使用默认模型
+    language, model := job.Language, job.Model
+    if value, ok := reTranscribeParam(c, "language"); ok {
+	value = strings.ToLower(value)
+	if value == "auto" {
+	    value = ""
+	}
+	if value != "" && !validLanguageCode(value) {
+	    reject(http.StatusBadRequest, "language 必须是 ISO 639-1 语言代码（如 en、zh），留空自动检测")
+	    return
+	}
+	language = value
+    }
+    if value, ok := reTranscribeParam(c, "model"); ok {
+	if value != "" && !validModelName(value) {
+	    reject(http.StatusBadRequest, fmt.Sprintf("model 无效（最多 %d 个字符，只能包含字母、数字和 . _ - : /）", maxModelNameLength))
+	    return
+	}
+	model = value
+    }
+    keepVocabulary := isTruthy(formOrQuery(c, "keep_vocabulary"))
+
+    // 本月预算用尽时拒绝（重新转录与新任务一样调用 Whisper）
+    if err := app.budget.Check(ctx); err != nil {
+	reject(budgetRejection(err))
+	return
+    }
+
+    // 旧字幕在任务重置后清理（新字幕由 Worker 重新生成）
+    oldFiles := []string{job.SubtitlePath, job.VTTPath, job.BilingualSRTPath, job.BilingualVTTPath}
+
+    job.Language = language
+    job.Model = model
+    job.Status = models.StatusPending
+    job.Progress = 0
+    job.Result = ""
+    job.SubtitlePath = ""
+    job.VTTPath = ""
+    job.BilingualSRTPath = ""
+    job.BilingualVTTPath = ""
+    job.Error = ""
+    job.Attempts = 0
+    job.CompletedAt = time.Time{}
+    job.LastUpdated = time.Now()
+    if !keepVocabulary {
+	job.Vocabulary = nil
+	job.VocabDetail = nil
+    }
+
+    // 使用 Save 而不是 Update：混合存储的 Update 只在进入终态时同步数据库
+    // 任务在上面的检查之后被其他请求修改过（如同时点击了两次重新转录）时版本号不一致，拒绝而不是覆盖
+    if err := app.store.Save(ctx, job); err != nil {
+	if errors.Is(err, storage.ErrVersionConflict) {
+	    reject(http.StatusConflict, "任务已被其他操作修改，请刷新后重试")
+	    return
+	}
+	log.Printf("❌ 重新转录时保存任务失败: %v", err)
+	reject(http.StatusInternalServerError, "保存任务失败")
+	return
+    }
+    if !keepVocabulary {
+	if err := app.store.IndexWords(ctx, jobID, nil); err != nil {
+	    log.Printf("⚠️ 清空单词索引失败: %v", err)
+	}
+    }
+    for _, key := range oldFiles {
+	if key == "" {
+	    continue
+	}
+	if err := app.files.Delete(ctx, key); err != nil {
+	    log.Printf("⚠️ 删除旧字幕失败: %s: %v", key, err)
+	}
+    }
+    app.events.Publish(jobEvent(job))
+
+    if err := queue.EnqueueContext(ctx, app.queue, job); err != nil {
+	log.Printf("❌ 重新转录任务加入队列失败: %v", err)
+	job.Status = models.StatusFailed
+	job.Error = "任务加入队列失败"
+	job.LastUpdated = time.Now()
+	if err := app.store.Save(context.WithoutCancel(ctx), job); err != nil {
+	    log.Printf("❌ 保存任务失败: %v", err)
+	}
+	app.events.Publish(jobEvent(job))
+	reject(http.StatusServiceUnavailable, "任务加入队列失败")
+	return
+    }
+
+    log.Printf("🔁 任务已重新加入队列: %s (语言: %s, 模型: %s, 保留单词: %v)",
+	jobID, displayOrDefault(language, "自动检测"), displayOrDefault(model, transcriber.DefaultModel), keepVocabulary)
+
+    if wantJSON {
+	c.JSON(http.StatusOK, job)
+	return
+    }
+    c.Header("HX-Trigger", "taskUpdated")
+    c.Data(http.StatusOK, "text/html", []byte(templates.RenderTaskCard(job)))
+}
+
+// maxModelNameLength 模型名称的最大长度
+const maxModelNameLength = 64
+
+// reTranscribeParam 读取重新转录的参数（查询参数或表单），ok 为 false 表示未提供
+func reTranscribeParam(c *gin.Context, name string) (value string, ok bool) {
+    if value, ok = c.GetQuery(name); !ok {
+	value, ok = c.GetPostForm(name)
+    }
+    return strings.TrimSpace(value), ok
+}
+
+// validLanguageCode 判断是否为 2 到 3 个小写字母的语言代码
+func validLanguageCode(code string) bool {
+    if len(code) < 2 || len(code) > 3 {
+	return false
+    }
+    for _, r := range code {
+	if r < 'a' || r > 'z' {
+	    return false
+	}
+    }
+    return true
+}
+
+// validModelName 判断模型名称是否只包含安全字符（字母、数字和 . _ - : /）
+func validModelName(name string) bool {
+    if len(name) > maxModelNameLength {
+	return false
+    }
+    for _, r := range name {
+	switch {
+	case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+	case strings.ContainsRune("._-:/", r):
+	default:
+	    return false
+	}
+    }
+    return true
+}
+
+// displayOrDefault 值为空时返回默认说明（用于日志）
+func displayOrDefault(value, fallback string) string {
+    if value == "" {
+	return fallback
+    }
+    return value
+}
+
+// handleExtractVocabulary 提取单词（返回 HTML）
+func (app *App) handleExtractVocabulary(c *gin.Context) {
     jobID := c.Param("job_id")
 
-    job, err := app.store.Get(jobID)
+    job, err := app.getJob(c, jobID)
     if err != nil {
 	c.Data(http.StatusNotFound, "text/html", []byte(`
 	    <div class="bg-red-50 text-red-800 p-3 rounded-lg text-sm">
@@ -442,15 +3200,255 @@ func (app *App) handleGetJob(c *gin.Context) {
 	return
     }
 
-    html := templates.RenderTaskCard(job)
-    c.Data(http.StatusOK, "text/html", []byte(html))
+    if job.Status != models.StatusCompleted {
+	c.Data(http.StatusBadRequest, "text/html", []byte(`
+	    <div class="bg-yellow-50 text-yellow-800 p-3 rounded-lg text-sm">
+	    ⚠️ 任务尚未完成，无法提取单词
+	    </div>
+	    `))
+	return
+    }
+
+    if job.Result == "" {
+	c.Data(http.StatusBadRequest, "text/html", []byte(`
+	    <div class="bg-yellow-50 text-yellow-800 p-3 rounded-lg text-sm">
+	    ⚠️ 转换结果为空
+	    </div>
+	    `))
+	return
+    }
+
+    // 可选的 CEFR 等级范围（如 min_level=B2 只保留中高级词汇）
+    minLevel, maxLevel, err := vocabulary.ParseLevelRange(formOrQuery(c, "min_level"), formOrQuery(c, "max_level"))
+    if err != nil {
+	c.Data(http.StatusBadRequest, "text/html", []byte(fmt.Sprintf(`
+	    <div class="bg-yellow-50 text-yellow-800 p-3 rounded-lg text-sm">
+	    ⚠️ %s
+	    </div>
+	    `, html.EscapeString(err.Error()))))
+	return
+    }
+
+    // 可选：排除其他任务中已经提取过的单词（系列视频中反复出现的词只学一次）
+    excludeKnown := formOrQuery(c, "exclude_known") != ""
+
+    log.Printf("开始提取单词，任务 ID: %s (等级: %s-%s, 排除已学: %v)", jobID, minLevel, maxLevel, excludeKnown)
+
+    // 异步提取单词（注册为可取消的子任务）
+    task := app.tasks.Submit(jobID, "vocabulary", func(ctx context.Context) (func() error, error) {
+	result, err := app.extractor.Extract(ctx, job.Result)
+	if err != nil {
+	    return nil, fmt.Errorf("提取单词失败: %w", err)
+	}
+	// 已经产生的调用费用即使任务被取消也要记录
+	app.recordChatCost(context.WithoutCancel(ctx), jobID, result.PromptTokens, result.CompletionTokens)
+
+	details := vocabulary.FilterByLevel(result.Details, minLevel, maxLevel)
+	if excludeKnown {
+	    known, err := app.knownWords(ctx, job)
+	    if err != nil {
+		return nil, err
+	    }
+	    var filtered int
+	    details, filtered = vocabulary.FilterKnown(details, known)
+	    llmtask.SetDetail(ctx, fmt.Sprintf("已排除 %d 个在其他任务中提取过的单词", filtered))
+	}
+
+	return func() error {
+	    words := make([]string, len(details))
+	    wordDetails := make([]models.WordDetail, len(details))
+	    for i, detail := range details {
+		words[i] = detail.Word
+		wordDetails[i] = models.WordDetail{
+		    Word:       detail.Word,
+		    Definition: detail.Definition,
+		    Example:    detail.Example,
+		    Phonetic:   detail.Phonetic,
+		    Level:      detail.Level,
+		}
+	    }
+
+	    // 提取期间任务可能被修改过（如同步记录、双语字幕），重新读取最新的任务再写入单词
+	    _, err := storage.Modify(ctx, app.store, jobID, func(latest *models.TranscriptionJob) error {
+		latest.Vocabulary = words
+		latest.VocabDetail = wordDetails
+		return nil
+	    })
+	    if err != nil {
+		return fmt.Errorf("保存单词列表失败: %w", err)
+	    }
+	    if err := app.store.IndexWords(ctx, jobID, vocabulary.FilterDuplicates(words)); err != nil {
+		log.Printf("⚠️  更新单词索引失败: %v", err)
+	    }
+
+	    log.Printf("✓ 成功提取 %d 个单词（等级过滤后 %d 个）", len(result.Words), len(details))
+	    return nil
+	}, nil
+    })
+
+    if !isTruthy(c.Query("sync")) {
+	// 显示加载状态（带取消按钮，轮询子任务状态）；结束时通过事件中心发布子任务事件
+	html := templates.RenderSubTaskStatus(jobID, task.ID, subTaskLabel(task.Kind), string(task.State), "")
+	c.Data(http.StatusOK, "text/html", []byte(html))
+	return
+    }
+
+    // 同步模式：等待提取结束后直接返回单词列表（客户端断开时提取仍在后台继续）
+    task, err = app.tasks.Wait(c.Request.Context(), jobID, task.ID)
+    if err != nil {
+	log.Printf("⚠️  等待单词提取结束时请求已取消，任务 ID: %s", jobID)
+	return
+    }
+    if task.State != llmtask.StateCompleted {
+	html := templates.RenderSubTaskStatus(jobID, task.ID, subTaskLabel(task.Kind), string(task.State), task.Error)
+	c.Data(http.StatusInternalServerError, "text/html", []byte(html))
+	return
+    }
+
+    job, err = app.store.Get(c.Request.Context(), jobID)
+    if err != nil {
+	c.Data(http.StatusNotFound, "text/html", []byte(`
+	    <div class="bg-red-50 text-red-800 p-3 rounded-lg text-sm">
+	    ❌ 任务不存在
+	    </div>
+	    `))
+	return
+    }
+    details := templates.RenderTaskDetails(job, app.syncTargets())
+    if task.Detail != "" {
+	details = templates.RenderSubTaskDetail(task.Detail) + details
+    }
+    c.Data(http.StatusOK, "text/html", []byte(details))
 }
 
-// handleJobDetails 获取任务详情（返回 HTML）
-func (app *App) handleJobDetails(c *gin.Context) {
+// deleteEvictedJob 内存存储超过任务数上限淘汰的任务：在后台删除其媒体和字幕文件
+func (app *App) deleteEvictedJob(job *models.TranscriptionJob) {
+    log.Printf("🧹 内存存储已满，淘汰任务: %s (%s)", job.JobID, job.Filename)
+    go func() {
+	ctx := context.Background()
+	for _, key := range []string{job.FilePath, job.SubtitlePath, job.VTTPath, job.BilingualSRTPath, job.BilingualVTTPath} {
+	    if key == "" {
+		continue
+	    }
+	    if err := app.files.Delete(ctx, key); err != nil {
+		log.Printf("⚠️ 删除淘汰任务的文件失败: %s: %v", key, err)
+	    }
+	}
+    }()
+}
+
+// deadLetterJob 内存队列放弃的任务（重新投递次数达到上限或无法退回队列）：标记为失败
+func (app *App) deadLetterJob(job *models.TranscriptionJob, reason string) {
+    log.Printf("☠️ 任务被队列放弃: %s (%s)", job.JobID, reason)
+    ctx := context.Background()
+    err := app.store.Update(ctx, job.JobID, func(j *models.TranscriptionJob) {
+	if j.Status == models.StatusCompleted || j.Status == models.StatusFailed {
+	    return
+	}
+	j.Status = models.StatusFailed
+	j.Error = reason
+	j.CompletedAt = time.Now()
+	j.LastUpdated = j.CompletedAt
+    })
+    if err != nil {
+	log.Printf("⚠️  标记任务失败时出错: %v", err)
+	return
+    }
+    if latest, err := app.store.Get(ctx, job.JobID); err == nil {
+	app.events.Publish(jobEvent(latest))
+    }
+}
+
+// publishSubTaskEvent 子任务（单词提取、双语字幕等）结束时发布事件，前端据此刷新任务详情
+func (app *App) publishSubTaskEvent(task llmtask.Task) {
+    job, err := app.store.Get(context.Background(), task.JobID)
+    if err != nil {
+	log.Printf("⚠️  读取任务失败，未发布子任务事件: %v", err)
+	return
+    }
+    event := jobEvent(job)
+    event.Task = task.Kind
+    event.TaskID = task.ID
+    event.TaskState = string(task.State)
+    event.TaskError = task.Error
+    app.events.Publish(event)
+}
+
+// knownWords 其他任务已提取的单词集合（归一化形式，不含当前任务）
+// 多用户模式下只统计同一用户的任务
+func (app *App) knownWords(ctx context.Context, job *models.TranscriptionJob) (map[string]bool, error) {
+    vocab, err := app.store.ListVocabulary(ctx)
+    if err != nil {
+	return nil, fmt.Errorf("读取已提取单词失败: %w", err)
+    }
+
+    var owned map[string]bool
+    if job.UserID != "" {
+	jobs, err := app.store.ListForUser(ctx, job.UserID, 0)
+	if err != nil {
+	    return nil, fmt.Errorf("读取用户任务失败: %w", err)
+	}
+	owned = make(map[string]bool, len(jobs))
+	for _, j := range jobs {
+	    owned[j.JobID] = true
+	}
+    }
+
+    known := make(map[string]bool)
+    for otherID, words := range vocab {
+	if otherID == job.JobID || (owned != nil && !owned[otherID]) {
+	    continue
+	}
+	for _, word := range words {
+	    known[word] = true
+	}
+    }
+    return known, nil
+}
+
+// formOrQuery 读取查询参数，没有时读取表单（htmx 的 hx-include 以表单提交）
+func formOrQuery(c *gin.Context, name string) string {
+    if value, ok := c.GetQuery(name); ok {
+	return value
+    }
+    return c.PostForm(name)
+}
+
+// handleExportVocabulary 导出单词列表（word, definition, example），格式见 artifacts.Vocabulary
+func (app *App) handleExportVocabulary(comma rune) gin.HandlerFunc {
+    return func(c *gin.Context) {
+	job, err := app.getJob(c, c.Param("job_id"))
+	if err != nil {
+	    c.JSON(http.StatusNotFound, gin.H{"error": "任务不存在"})
+	    return
+	}
+
+	data, err := artifacts.Vocabulary(job, comma)
+	if errors.Is(err, artifacts.ErrUnavailable) {
+	    c.JSON(http.StatusBadRequest, gin.H{"error": "尚未提取单词"})
+	    return
+	}
+	if err != nil {
+	    c.JSON(http.StatusInternalServerError, gin.H{"error": "生成文件失败"})
+	    return
+	}
+
+	ext := "csv"
+	contentType := "text/csv; charset=utf-8"
+	if comma == '\t' {
+	    ext = "tsv"
+	    contentType = "text/tab-separated-values; charset=utf-8"
+	}
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.vocabulary.%s"`, artifacts.BaseName(job), ext))
+	c.Data(http.StatusOK, contentType, data)
+    }
+}
+
+// handleGenerateBilingual 翻译字幕并生成双语 SRT / VTT（返回 HTML）
+func (app *App) handleGenerateBilingual(c *gin.Context) {
     jobID := c.Param("job_id")
 
-    job, err := app.store.Get(jobID)
+    job, err := app.getJob(c, jobID)
     if err != nil {
 	c.Data(http.StatusNotFound, "text/html", []byte(`
 	    <div class="bg-red-50 text-red-800 p-3 rounded-lg text-sm">
@@ -460,117 +3458,326 @@ func (app *App) handleJobDetails(c *gin.Context) {
 	return
     }
 
-    html := templates.RenderTaskDetails(job)
-    c.Data(http.StatusOK, "text/html", []byte(html))
+    if job.Status != models.StatusCompleted || job.SubtitlePath == "" {
+	c.Data(http.StatusBadRequest, "text/html", []byte(`
+	    <div class="bg-yellow-50 text-yellow-800 p-3 rounded-lg text-sm">
+	    ⚠️ 任务尚未完成或无字幕文件，无法生成双语字幕
+	    </div>
+	    `))
+	return
+    }
+
+    if err := app.budget.Check(c.Request.Context()); err != nil {
+	code, message := budgetRejection(err)
+	c.Data(code, "text/html", []byte(fmt.Sprintf(`
+	    <div class="bg-red-50 text-red-800 p-3 rounded-lg text-sm">
+	    ❌ %s
+	    </div>
+	    `, message)))
+	return
+    }
+
+    var cues []transcriber.Cue
+    srtContent, err := filestore.ReadAll(c.Request.Context(), app.files, job.SubtitlePath)
+    if err == nil {
+	cues, err = transcriber.ParseSRTContent(srtContent)
+    }
+    if err != nil {
+	log.Printf("❌ 读取字幕失败: %v", err)
+	c.Data(http.StatusInternalServerError, "text/html", []byte(`
+	    <div class="bg-red-50 text-red-800 p-3 rounded-lg text-sm">
+	    ❌ 读取字幕文件失败
+	    </div>
+	    `))
+	return
+    }
+
+    log.Printf("开始生成双语字幕，任务 ID: %s (%d 条字幕 → %s)", jobID, len(cues), app.translator.TargetLanguage())
+
+    // 每批字幕约需数秒，超时时间随字幕条数增长
+    timeout := 60*time.Second + time.Duration(len(cues))*time.Second
+    task := app.tasks.SubmitWithTimeout(jobID, "bilingual", timeout, func(ctx context.Context) (func() error, error) {
+	result, err := app.translator.TranslateCues(ctx, cues)
+	// 已经产生的调用费用即使任务失败或被取消也要记录
+	app.recordChatCost(context.WithoutCancel(ctx), jobID, result.PromptTokens, result.CompletionTokens)
+	if err != nil {
+	    return nil, err
+	}
+
+	return func() error {
+	    srtPath := transcriber.BilingualPath(job.SubtitlePath, ".srt")
+	    vttPath := transcriber.BilingualPath(job.SubtitlePath, ".vtt")
+	    if err := app.writeSubtitle(ctx, srtPath, func(local string) error {
+		return transcriber.GenerateBilingualSRT(cues, result.Lines, local)
+	    }); err != nil {
+		return err
+	    }
+	    if err := app.writeSubtitle(ctx, vttPath, func(local string) error {
+		return transcriber.GenerateBilingualVTT(cues, result.Lines, local)
+	    }); err != nil {
+		return err
+	    }
+
+	    _, err := storage.Modify(ctx, app.store, jobID, func(latest *models.TranscriptionJob) error {
+		latest.BilingualSRTPath = srtPath
+		latest.BilingualVTTPath = vttPath
+		return nil
+	    })
+	    if err != nil {
+		return fmt.Errorf("保存双语字幕路径失败: %w", err)
+	    }
+
+	    log.Printf("✓ 双语字幕已生成: %s, %s", srtPath, vttPath)
+	    return nil
+	}, nil
+    })
+
+    html := templates.RenderSubTaskStatus(jobID, task.ID, subTaskLabel(task.Kind), string(task.State), "")
+    c.Data(http.StatusOK, "text/html", []byte(html))
+}
+
+// recordChatCost 记录子任务的 Chat 调用费用：计入月度预算，并累加到任务的费用估算和 token 用量
+func (app *App) recordChatCost(ctx context.Context, jobID string, promptTokens, completionTokens int) {
+    if promptTokens == 0 && completionTokens == 0 {
+	return
+    }
+    cost := app.budget.RecordChat(ctx, promptTokens, completionTokens)
+    _, err := storage.Modify(ctx, app.store, jobID, func(job *models.TranscriptionJob) error {
+	job.EstimatedCost += cost
+	job.PromptTokens += promptTokens
+	job.CompletionTokens += completionTokens
+	return nil
+    })
+    if err != nil {
+	log.Printf("⚠️ 记录任务费用失败: %s: %v", jobID, err)
+    }
+}
+
+// pricingRates 由配置生成估算费用的单价，未设置的单价使用官方定价
+func pricingRates(cfg config.PricingConfig) budget.Rates {
+    rates := budget.DefaultRates
+    if cfg.WhisperPerMinute > 0 {
+	rates.WhisperPerMinute = cfg.WhisperPerMinute
+    }
+    if cfg.ChatInputPerMillion > 0 {
+	rates.ChatPerInputToken = cfg.ChatInputPerMillion / 1000000
+    }
+    if cfg.ChatOutputPerMillion > 0 {
+	rates.ChatPerOutputToken = cfg.ChatOutputPerMillion / 1000000
+    }
+    return rates
+}
+
+// writeSubtitle 在临时目录生成字幕文件，再保存到文件存储的 key 下
+func (app *App) writeSubtitle(ctx context.Context, key string, generate func(localPath string) error) error {
+    tmpDir, err := os.MkdirTemp(app.config.Server.UploadTempDir, "subtitle-*")
+    if err != nil {
+	return fmt.Errorf("创建临时目录失败: %w", err)
+    }
+    defer os.RemoveAll(tmpDir)
+
+    localPath := filepath.Join(tmpDir, path.Base(key))
+    if err := generate(localPath); err != nil {
+	return err
+    }
+
+    contentType := "text/plain; charset=utf-8"
+    if strings.HasSuffix(key, ".vtt") {
+	contentType = "text/vtt; charset=utf-8"
+    }
+    return filestore.PutFile(ctx, app.files, key, localPath, contentType)
 }
 
-// handleDownloadResult 下载转录结果
-func (app *App) handleDownloadResult(c *gin.Context) {
-    jobID := c.Param("job_id")
-
-    job, err := app.store.Get(jobID)
+// handleDownloadBilingualSubtitle 下载双语 SRT 字幕
+func (app *App) handleDownloadBilingualSubtitle(c *gin.Context) {
+    job, err := app.getJob(c, c.Param("job_id"))
     if err != nil {
 	c.JSON(http.StatusNotFound, gin.H{"error": "任务不存在"})
 	return
     }
 
-    if job.Status != models.StatusCompleted || job.Result == "" {
-	c.JSON(http.StatusBadRequest, gin.H{"error": "任务尚未完成或无结果"})
+    if job.BilingualSRTPath == "" {
+	c.JSON(http.StatusBadRequest, gin.H{"error": "尚未生成双语字幕"})
 	return
     }
 
-    // 设置下载响应头
-    c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s_转录.txt", job.Filename))
-    c.Data(http.StatusOK, "text/plain; charset=utf-8", []byte(job.Result))
-}
+    content, err := filestore.ReadAll(c.Request.Context(), app.files, job.BilingualSRTPath)
+    if err != nil {
+	c.JSON(http.StatusInternalServerError, gin.H{"error": "读取字幕文件失败"})
+	return
+    }
 
-// handleDownloadSubtitle 下载 SRT 字幕文件
-func (app *App) handleDownloadSubtitle(c *gin.Context) {
-    jobID := c.Param("job_id")
+    c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.bilingual.srt"`, artifacts.BaseName(job)))
+    c.Data(http.StatusOK, "text/plain; charset=utf-8", content)
+}
 
-    job, err := app.store.Get(jobID)
+// handleBilingualVTT 返回双语 WebVTT 字幕（用于视频播放器）
+func (app *App) handleBilingualVTT(c *gin.Context) {
+    job, err := app.getJob(c, c.Param("job_id"))
     if err != nil {
 	c.JSON(http.StatusNotFound, gin.H{"error": "任务不存在"})
 	return
     }
 
-    if job.Status != models.StatusCompleted || job.SubtitlePath == "" {
-	c.JSON(http.StatusBadRequest, gin.H{"error": "任务尚未完成或无字幕文件"})
+    if job.BilingualVTTPath == "" {
+	c.JSON(http.StatusBadRequest, gin.H{"error": "尚未生成双语字幕"})
 	return
     }
 
-    // 读取 SRT 文件内容
-    srtContent, err := os.ReadFile(job.SubtitlePath)
+    content, err := filestore.ReadAll(c.Request.Context(), app.files, job.BilingualVTTPath)
     if err != nil {
 	c.JSON(http.StatusInternalServerError, gin.H{"error": "读取字幕文件失败"})
 	return
     }
 
-    // 安全的文件名（移除特殊字符）
-    safeFilename := strings.TrimSuffix(job.Filename, filepath.Ext(job.Filename))
-    safeFilename = strings.ReplaceAll(safeFilename, `"`, "")
+    c.Header("Cache-Control", "public, max-age=3600")
+    c.Data(http.StatusOK, "text/vtt; charset=utf-8", content)
+}
 
-    // 设置下载响应头（修复 Safari 兼容性）
-    c.Header("Content-Type", "text/plain; charset=utf-8")
-    c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.srt"`, safeFilename))
-    c.Header("Content-Length", fmt.Sprintf("%d", len(srtContent)))
-    c.Data(http.StatusOK, "text/plain; charset=utf-8", srtContent)
+// subTaskLabel 子任务类型的显示名称
+func subTaskLabel(kind string) string {
+    switch kind {
+    case "vocabulary":
+	return "提取单词"
+    case "bilingual":
+	return "生成双语字幕"
+    default:
+	return kind
+    }
 }
 
-// handleSubtitleVTT 返回 WebVTT 字幕文件（用于视频播放器）
-func (app *App) handleSubtitleVTT(c *gin.Context) {
+// handleGetSubTask 查询子任务状态（返回 HTML，完成后返回任务详情）
+func (app *App) handleGetSubTask(c *gin.Context) {
     jobID := c.Param("job_id")
+    taskID := c.Param("task_id")
+
+    task, ok := app.tasks.Get(jobID, taskID)
+    if ok && task.State != llmtask.StateCompleted {
+	html := templates.RenderSubTaskStatus(jobID, taskID, subTaskLabel(task.Kind), string(task.State), task.Error)
+	c.Data(http.StatusOK, "text/html", []byte(html))
+	return
+    }
 
-    job, err := app.store.Get(jobID)
+    // 任务已完成（或已过保留期），直接渲染最新的任务详情
+    job, err := app.getJob(c, jobID)
     if err != nil {
-	c.JSON(http.StatusNotFound, gin.H{"error": "任务不存在"})
+	c.Data(http.StatusNotFound, "text/html", []byte(`
+	    <div class="bg-red-50 text-red-800 p-3 rounded-lg text-sm">
+	    ❌ 任务不存在
+	    </div>
+	    `))
 	return
     }
 
-    if job.Status != models.StatusCompleted || job.VTTPath == "" {
-	c.JSON(http.StatusBadRequest, gin.H{"error": "任务尚未完成或无字幕文件"})
+    details := templates.RenderTaskDetails(job, app.syncTargets())
+    if ok && task.Detail != "" {
+	details = templates.RenderSubTaskDetail(task.Detail) + details
+    }
+    c.Data(http.StatusOK, "text/html", []byte(details))
+}
+
+// handleCancelSubTask 取消子任务（返回 HTML）
+func (app *App) handleCancelSubTask(c *gin.Context) {
+    jobID := c.Param("job_id")
+    taskID := c.Param("task_id")
+
+    if _, err := app.getJob(c, jobID); err != nil {
+	c.Data(http.StatusNotFound, "text/html", []byte(`
+	    <div class="bg-red-50 text-red-800 p-3 rounded-lg text-sm">
+	    ❌ 任务不存在
+	    </div>
+	    `))
 	return
     }
 
-    // 读取 VTT 文件内容
-    vttContent, err := os.ReadFile(job.VTTPath)
+    task, err := app.tasks.Cancel(jobID, taskID)
+    if err == llmtask.ErrTaskFinished {
+	// 已经结束，返回当前状态（htmx 只替换 2xx 响应）
+	html := templates.RenderSubTaskStatus(jobID, taskID, subTaskLabel(task.Kind), string(task.State), task.Error)
+	c.Data(http.StatusOK, "text/html", []byte(html))
+	return
+    }
     if err != nil {
-	c.JSON(http.StatusInternalServerError, gin.H{"error": "读取字幕文件失败"})
+	c.Data(http.StatusNotFound, "text/html", []byte(`
+	    <div class="bg-red-50 text-red-800 p-3 rounded-lg text-sm">
+	    ❌ 子任务不存在
+	    </div>
+	    `))
 	return
     }
 
-    // 设置 CORS 和响应头（允许视频播放器访问）
-    c.Header("Access-Control-Allow-Origin", "*")
-    c.Header("Content-Type", "text/vtt; charset=utf-8")
-    c.Header("Cache-Control", "public, max-age=3600")
-    c.Data(http.StatusOK, "text/vtt; charset=utf-8", vttContent)
+    html := templates.RenderSubTaskStatus(jobID, taskID, subTaskLabel(task.Kind), string(task.State), "")
+    c.Data(http.StatusOK, "text/html", []byte(html))
 }
 
-// handleDeleteJob 删除任务（返回空内容，让 htmx 删除元素）
-func (app *App) handleDeleteJob(c *gin.Context) {
-    jobID := c.Param("job_id")
+// newVocabularySinks 按配置创建单词同步目标
+func newVocabularySinks(cfgs []config.VocabularySinkConfig, maimemoClient *maimemo_service.Client) (*sinks.Registry, error) {
+    list := make([]sinks.VocabularySink, 0, len(cfgs))
+    for _, sc := range cfgs {
+	timeout := time.Duration(sc.Timeout) * time.Second
+	switch sc.Type {
+	case sinks.TypeMaimemo:
+	    list = append(list, sinks.NewMaimemoSink(sc.Name, sc.Label, maimemoClient))
+	case sinks.TypeAnkiConnect:
+	    list = append(list, sinks.NewAnkiConnectSink(sc.Name, sc.Label, sinks.AnkiConnectOptions{
+		URL:     sc.URL,
+		Deck:    sc.Deck,
+		Model:   sc.Model,
+		Timeout: timeout,
+	    }))
+	case sinks.TypeWebhook:
+	    list = append(list, sinks.NewWebhookSink(sc.Name, sc.Label, sc.URL, timeout))
+	default:
+	    return nil, fmt.Errorf("不支持的同步目标类型: %s", sc.Type)
+	}
+    }
+    return sinks.NewRegistry(list...)
+}
 
-    if err := app.store.Delete(jobID); err != nil {
-	log.Printf("❌ 删除任务失败: %v", err)
+// syncTargets 单词面板上显示的同步目标
+func (app *App) syncTargets() []templates.SyncTarget {
+    list := app.sinks.List()
+    targets := make([]templates.SyncTarget, len(list))
+    for i, sink := range list {
+	targets[i] = templates.SyncTarget{Name: sink.Name(), Label: sink.Label(), Type: sink.Type()}
+    }
+    return targets
+}
+
+// handleSyncVocabulary 同步单词到指定的同步目标（返回 HTML）
+func (app *App) handleSyncVocabulary(c *gin.Context) {
+    sink, ok := app.sinks.Get(c.Param("sink"))
+    if !ok {
 	c.Data(http.StatusNotFound, "text/html", []byte(`
 	    <div class="bg-red-50 text-red-800 p-3 rounded-lg text-sm">
-	    ❌ 删除失败
+	    ❌ 同步目标不存在
 	    </div>
 	    `))
 	return
     }
+    app.syncVocabulary(c, sink)
+}
 
-    log.Printf("✓ 任务已删除: %s", jobID)
-
-    // 返回空内容，htmx 会删除目标元素
-    c.Data(http.StatusOK, "text/html", []byte(""))
+// handleSyncToMaimemo 同步到墨墨（旧接口，等价于 /sync/{墨墨同步目标}）
+func (app *App) handleSyncToMaimemo(c *gin.Context) {
+    for _, sink := range app.sinks.List() {
+	if sink.Type() == sinks.TypeMaimemo {
+	    app.syncVocabulary(c, sink)
+	    return
+	}
+    }
+    c.Data(http.StatusNotFound, "text/html", []byte(`
+	<div class="bg-red-50 text-red-800 p-3 rounded-lg text-sm">
+	❌ 未配置墨墨同步目标
+	</div>
+	`))
 }
 
-// handleExtractVocabulary 提取单词（返回 HTML）
-func (app *App) handleExtractVocabulary(c *gin.Context) {
+// syncVocabulary 同步任务的单词并记录同步结果
+func (app *App) syncVocabulary(c *gin.Context, sink sinks.VocabularySink) {
     jobID := c.Param("job_id")
 
-    job, err := app.store.Get(jobID)
+    job, err := app.getJob(c, jobID)
     if err != nil {
 	c.Data(http.StatusNotFound, "text/html", []byte(`
 	    <div class="bg-red-50 text-red-800 p-3 rounded-lg text-sm">
@@ -580,129 +3787,205 @@ func (app *App) handleExtractVocabulary(c *gin.Context) {
 	return
     }
 
-    if job.Status != models.StatusCompleted {
+    words := syncWords(job)
+    if len(words) == 0 {
 	c.Data(http.StatusBadRequest, "text/html", []byte(`
 	    <div class="bg-yellow-50 text-yellow-800 p-3 rounded-lg text-sm">
-	    ⚠️ 任务尚未完成，无法提取单词
+	    ⚠️ 尚未提取单词，请先提取单词
 	    </div>
 	    `))
 	return
     }
 
-    if job.Result == "" {
+    log.Printf("开始同步到 %s，任务 ID: %s, 单词数: %d", sink.Name(), jobID, len(words))
+
+    // 保存的墨墨 Token 只提供给墨墨同步目标
+    token := c.PostForm("token")
+    if sink.Type() == sinks.TypeMaimemo {
+	token = app.maimemoToken(c)
+    }
+
+    count, err := sink.Sync(c.Request.Context(), sinks.Request{
+	JobID:    job.JobID,
+	Filename: job.Filename,
+	Words:    words,
+	Params: map[string]string{
+	    "token":      token,
+	    "notepad_id": c.PostForm("notepad_id"),
+	},
+    })
+    if errors.Is(err, sinks.ErrMissingParams) {
 	c.Data(http.StatusBadRequest, "text/html", []byte(`
 	    <div class="bg-yellow-50 text-yellow-800 p-3 rounded-lg text-sm">
-	    ⚠️ 转换结果为空
+	    ⚠️ 请输入（或保存）Token 和云词本 ID
 	    </div>
 	    `))
 	return
     }
 
-    log.Printf("开始提取单词，任务 ID: %s", jobID)
+    record := models.SyncRecord{Sink: sink.Name(), Count: count, SyncedAt: time.Now()}
+    if err != nil {
+	record.Error = err.Error()
+    }
+    if recordErr := app.recordSync(c.Request.Context(), jobID, record); recordErr != nil {
+	log.Printf("⚠️  保存同步记录失败: %v", recordErr)
+    }
 
-    // 显示加载状态
-    c.Data(http.StatusOK, "text/html", []byte(`
-	<div class="text-center p-8">
-	<span class="spinner"></span>
-	<p class="text-gray-600 mt-2">正在提取单词，请稍候...</p>
-	</div>
-	`))
+    if err != nil {
+	log.Printf("❌ 同步到 %s 失败: %v", sink.Name(), err)
+	c.Data(http.StatusInternalServerError, "text/html", []byte(fmt.Sprintf(`
+	    <div class="bg-red-50 text-red-800 p-3 rounded-lg text-sm">
+	    ❌ 同步失败: %s
+	    </div>
+	    `, html.EscapeString(err.Error()))))
+	return
+    }
 
-    // 异步提取单词
-    go func() {
-	// 使用独立的 context，避免 HTTP 请求结束后 context 被取消
-	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
-	defer cancel()
+    log.Printf("✓ 成功同步 %d 个单词到 %s", count, sink.Name())
 
-	result, err := app.extractor.Extract(ctx, job.Result)
-	if err != nil {
-	    log.Printf("❌ 提取单词失败: %v", err)
-	    return
-	}
+    c.Data(http.StatusOK, "text/html", []byte(fmt.Sprintf(`
+	<div class="bg-green-50 text-green-800 p-3 rounded-lg text-sm">
+	✅ 成功同步 %d 个单词到%s！
+	</div>
+	`, count, html.EscapeString(sink.Label()))))
+}
 
-	job.Vocabulary = result.Words
-	job.VocabDetail = make([]models.WordDetail, len(result.Details))
-	for i, detail := range result.Details {
-	    job.VocabDetail[i] = models.WordDetail{
-		Word:       detail.Word,
-		Definition: detail.Definition,
-		Example:    detail.Example,
-	    }
-	}
+// syncWords 待同步的单词（旧任务可能只有单词列表，没有释义）
+func syncWords(job *models.TranscriptionJob) []models.WordDetail {
+    if len(job.VocabDetail) > 0 {
+	return job.VocabDetail
+    }
+    words := make([]models.WordDetail, len(job.Vocabulary))
+    for i, w := range job.Vocabulary {
+	words[i] = models.WordDetail{Word: w}
+    }
+    return words
+}
 
-	if err := app.store.Save(job); err != nil {
-	    log.Printf("❌ 保存单词列表失败: %v", err)
-	    return
-	}
+// maxSyncHistory 每个任务保留的同步记录数
+const maxSyncHistory = 20
 
-	log.Printf("✓ 成功提取 %d 个单词", len(result.Words))
-    }()
+// recordSync 追加同步记录（重新读取任务，避免覆盖同步期间的其他修改；版本冲突时重试）
+func (app *App) recordSync(ctx context.Context, jobID string, record models.SyncRecord) error {
+    _, err := storage.Modify(ctx, app.store, jobID, func(job *models.TranscriptionJob) error {
+	job.SyncHistory = append(job.SyncHistory, record)
+	if len(job.SyncHistory) > maxSyncHistory {
+	    job.SyncHistory = job.SyncHistory[len(job.SyncHistory)-maxSyncHistory:]
+	}
+	return nil
+    })
+    return err
 }
 
-// handleSyncToMaimemo 同步到墨墨（返回 HTML）
-func (app *App) handleSyncToMaimemo(c *gin.Context) {
-    jobID := c.Param("job_id")
-    token := c.PostForm("token")
-    notepadID := c.PostForm("notepad_id")
+// maimemoToken 请求使用的墨墨 Token：优先使用表单中填写的 Token，为空时使用当前用户保存的 Token
+func (app *App) maimemoToken(c *gin.Context) string {
+    if token := c.PostForm("token"); token != "" {
+	return token
+    }
+    if app.credentials == nil {
+	return ""
+    }
+    token, err := app.credentials.Load(c.Request.Context(), middleware.CurrentUser(c).ID, credentials.Maimemo)
+    if err != nil {
+	log.Printf("⚠️  读取保存的墨墨 Token 失败: %v", err)
+    }
+    return token
+}
 
-    if token == "" || notepadID == "" {
-	c.Data(http.StatusBadRequest, "text/html", []byte(`
-	    <div class="bg-yellow-50 text-yellow-800 p-3 rounded-lg text-sm">
-	    ⚠️ 请输入 Token 和云词本 ID
-	    </div>
-	    `))
-	return
+// handleGetMaimemoToken 查询当前用户是否保存了墨墨 Token（返回 Token 区域 HTML，不返回 Token 本身）
+func (app *App) handleGetMaimemoToken(c *gin.Context) {
+    saved := false
+    if app.credentials != nil {
+	var err error
+	saved, err = app.credentials.Exists(c.Request.Context(), middleware.CurrentUser(c).ID, credentials.Maimemo)
+	if err != nil {
+	    log.Printf("⚠️  查询墨墨 Token 失败: %v", err)
+	}
     }
+    c.Data(http.StatusOK, "text/html", []byte(templates.RenderMaimemoToken(saved, app.credentials != nil)))
+}
 
-    job, err := app.store.Get(jobID)
-    if err != nil {
-	c.Data(http.StatusNotFound, "text/html", []byte(`
-	    <div class="bg-red-50 text-red-800 p-3 rounded-lg text-sm">
-	    ❌ 任务不存在
+// handleSaveMaimemoToken 加密保存当前用户的墨墨 Token
+func (app *App) handleSaveMaimemoToken(c *gin.Context) {
+    if app.credentials == nil {
+	c.Data(http.StatusServiceUnavailable, "text/html", []byte(`
+	    <div class="bg-yellow-50 text-yellow-800 p-3 rounded-lg text-sm">
+	    ⚠️ 未配置 maimemo_service.token_key，无法在服务端保存 Token
 	    </div>
 	    `))
 	return
     }
 
-    if len(job.Vocabulary) == 0 {
+    token := strings.TrimSpace(c.PostForm("token"))
+    if token == "" {
 	c.Data(http.StatusBadRequest, "text/html", []byte(`
 	    <div class="bg-yellow-50 text-yellow-800 p-3 rounded-lg text-sm">
-	    ⚠️ 尚未提取单词，请先提取单词
+	    ⚠️ 请先输入墨墨 API Token
 	    </div>
 	    `))
 	return
     }
 
-    log.Printf("开始同步到墨墨，任务 ID: %s, 单词数: %d", jobID, len(job.Vocabulary))
-
-    if err := app.maimemoService.AddWordsToNotepad(c.Request.Context(), token, notepadID, job.Vocabulary); err != nil {
-	log.Printf("❌ 同步到墨墨失败: %v", err)
-	c.Data(http.StatusInternalServerError, "text/html", []byte(fmt.Sprintf(`
+    if err := app.credentials.Save(c.Request.Context(), middleware.CurrentUser(c).ID, credentials.Maimemo, token); err != nil {
+	log.Printf("❌ 保存墨墨 Token 失败: %v", err)
+	c.Data(http.StatusInternalServerError, "text/html", []byte(`
 	    <div class="bg-red-50 text-red-800 p-3 rounded-lg text-sm">
-	    ❌ 同步失败: %v
+	    ❌ 保存 Token 失败
 	    </div>
-	    `, err)))
+	    `))
 	return
     }
 
-    log.Printf("✓ 成功同步 %d 个单词到墨墨", len(job.Vocabulary))
+    c.Data(http.StatusOK, "text/html", []byte(templates.RenderMaimemoToken(true, true)))
+}
 
-    c.Data(http.StatusOK, "text/html", []byte(fmt.Sprintf(`
-	<div class="bg-green-50 text-green-800 p-3 rounded-lg text-sm">
-	✅ 成功同步 %d 个单词到墨墨背单词！
-	</div>
-	`, len(job.Vocabulary))))
+// handleDeleteMaimemoToken 删除当前用户保存的墨墨 Token
+func (app *App) handleDeleteMaimemoToken(c *gin.Context) {
+    if app.credentials != nil {
+	if err := app.credentials.Delete(c.Request.Context(), middleware.CurrentUser(c).ID, credentials.Maimemo); err != nil {
+	    log.Printf("❌ 删除墨墨 Token 失败: %v", err)
+	    c.Data(http.StatusInternalServerError, "text/html", []byte(`
+		<div class="bg-red-50 text-red-800 p-3 rounded-lg text-sm">
+		❌ 删除 Token 失败
+		</div>
+		`))
+	    return
+	}
+    }
+    c.Data(http.StatusOK, "text/html", []byte(templates.RenderMaimemoToken(false, app.credentials != nil)))
 }
 
 // handleListNotepads 查询云词本列表（返回 HTML）
 func (app *App) handleListNotepads(c *gin.Context) {
-    // 从表单中获取 token（htmx 会自动将 input 值转为 POST 数据）
-    token := c.PostForm("token")
+    // 任务 ID 由同步表单通过 hx-vals 提交（也可作为查询参数），渲染在云词本列表的选择回调中
+    jobID := c.Query("job_id")
+    if jobID == "" {
+	jobID = c.PostForm("job_id")
+    }
+    if jobID == "" {
+	c.Data(http.StatusBadRequest, "text/html", []byte(`
+	    <div class="p-4 text-center text-yellow-800">
+	    ⚠️ 缺少任务 ID，请刷新页面后重试
+	    </div>
+	    `))
+	return
+    }
+    if _, err := app.getJob(c, jobID); err != nil {
+	c.Data(http.StatusNotFound, "text/html", []byte(`
+	    <div class="p-4 text-center text-red-800">
+	    ❌ 任务不存在
+	    </div>
+	    `))
+	return
+    }
+
+    // 表单中的 token（htmx 会自动将 input 值转为 POST 数据），为空时使用保存的 Token
+    token := app.maimemoToken(c)
 
     if token == "" {
 	c.Data(http.StatusBadRequest, "text/html", []byte(`
 	    <div class="p-4 text-center text-yellow-800">
-	    ⚠️ 请先输入墨墨 API Token
+	    ⚠️ 请先输入或保存墨墨 API Token
 	    </div>
 	    `))
 	return
@@ -715,9 +3998,9 @@ func (app *App) handleListNotepads(c *gin.Context) {
 	log.Printf("❌ 查询云词本列表失败: %v", err)
 	c.Data(http.StatusInternalServerError, "text/html", []byte(fmt.Sprintf(`
 	    <div class="p-4 text-center text-red-800">
-	    ❌ 查询失败: %v
+	    ❌ 查询失败: %s
 	    </div>
-	    `, err)))
+	    `, html.EscapeString(err.Error()))))
 	return
     }
 
@@ -733,19 +4016,115 @@ func (app *App) handleListNotepads(c *gin.Context) {
 	notepadMaps[i] = m
     }
 
-    // 从 URL 查询参数或表单中获取 jobID（htmx 可以通过 hx-vals 传递）
+
+    page := templates.RenderNotepads(notepadMaps, jobID)
+    c.Data(http.StatusOK, "text/html", []byte(page))
+}
+
+// handleRemoveNotepadWords 从云词本中删除单词（返回 HTML）
+func (app *App) handleRemoveNotepadWords(c *gin.Context) {
+    token := app.maimemoToken(c)
+    notepadID := strings.TrimSpace(c.PostForm("notepad_id"))
+    if token == "" || notepadID == "" {
+	c.Data(http.StatusBadRequest, "text/html", []byte(`
+	    <div class="bg-yellow-50 text-yellow-800 p-3 rounded-lg text-sm">
+	    ⚠️ 请输入（或保存）Token 和云词本 ID
+	    </div>
+	    `))
+	return
+    }
+
+    var words []string
+    for _, value := range c.PostFormArray("words") {
+	for _, word := range strings.FieldsFunc(value, func(r rune) bool { return r == ',' || r == '\n' }) {
+	    if word = strings.TrimSpace(word); word != "" {
+		words = append(words, word)
+	    }
+	}
+    }
+    if len(words) == 0 {
+	c.Data(http.StatusBadRequest, "text/html", []byte(`
+	    <div class="bg-yellow-50 text-yellow-800 p-3 rounded-lg text-sm">
+	    ⚠️ 请输入要删除的单词
+	    </div>
+	    `))
+	return
+    }
+
+    removed, err := app.maimemoService.RemoveWordsFromNotepad(c.Request.Context(), token, notepadID, words)
+    if err != nil {
+	log.Printf("❌ 从云词本删除单词失败: %v", err)
+	c.Data(http.StatusInternalServerError, "text/html", []byte(fmt.Sprintf(`
+	    <div class="bg-red-50 text-red-800 p-3 rounded-lg text-sm">
+	    ❌ 删除失败: %s
+	    </div>
+	    `, html.EscapeString(err.Error()))))
+	return
+    }
+
+    log.Printf("✓ 从云词本 %s 删除了 %d 个单词（请求 %d 个）", notepadID, removed, len(words))
+    c.Data(http.StatusOK, "text/html", []byte(fmt.Sprintf(`
+	<div class="bg-green-50 text-green-800 p-3 rounded-lg text-sm">
+	✅ 已从云词本删除 %d 个单词（%d 个不在云词本中）
+	</div>
+	`, removed, len(words)-removed)))
+}
+
+// handleCreateNotepad 新建云词本（返回 HTML），新云词本的 ID 填入同步表单
+func (app *App) handleCreateNotepad(c *gin.Context) {
     jobID := c.Query("job_id")
     if jobID == "" {
-	// 尝试从表单中获取
 	jobID = c.PostForm("job_id")
     }
-    // 如果还是为空，尝试从 Referer 中提取
     if jobID == "" {
-	// 假设页面 URL 包含 job_id 信息，或者我们从某个隐藏字段获取
-	// 这里我们需要从前端传递，暂时使用一个占位符
-	jobID = "unknown"
+	c.Data(http.StatusBadRequest, "text/html", []byte(`
+	    <div class="p-4 text-center text-yellow-800">
+	    ⚠️ 缺少任务 ID，请刷新页面后重试
+	    </div>
+	    `))
+	return
+    }
+    if _, err := app.getJob(c, jobID); err != nil {
+	c.Data(http.StatusNotFound, "text/html", []byte(`
+	    <div class="p-4 text-center text-red-800">
+	    ❌ 任务不存在
+	    </div>
+	    `))
+	return
     }
 
-    html := templates.RenderNotepads(notepadMaps, jobID)
-    c.Data(http.StatusOK, "text/html", []byte(html))
+    token := app.maimemoToken(c)
+    if token == "" {
+	c.Data(http.StatusBadRequest, "text/html", []byte(`
+	    <div class="p-4 text-center text-yellow-800">
+	    ⚠️ 请先输入或保存墨墨 API Token
+	    </div>
+	    `))
+	return
+    }
+
+    title := strings.TrimSpace(c.PostForm("title"))
+    if title == "" {
+	c.Data(http.StatusBadRequest, "text/html", []byte(`
+	    <div class="p-4 text-center text-yellow-800">
+	    ⚠️ 请输入云词本标题
+	    </div>
+	    `))
+	return
+    }
+    brief := strings.TrimSpace(c.PostForm("brief"))
+
+    notepadID, err := app.maimemoService.CreateNotepad(c.Request.Context(), token, title, brief)
+    if err != nil {
+	log.Printf("❌ 创建云词本失败: %v", err)
+	c.Data(http.StatusInternalServerError, "text/html", []byte(fmt.Sprintf(`
+	    <div class="p-4 text-center text-red-800">
+	    ❌ 创建失败: %s
+	    </div>
+	    `, html.EscapeString(err.Error()))))
+	return
+    }
+
+    log.Printf("✓ 已创建云词本: %s (%s)", title, notepadID)
+    c.Data(http.StatusOK, "text/html", []byte(templates.RenderNotepadCreated(jobID, notepadID, title)))
 }
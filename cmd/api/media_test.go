@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/z-wentao/voiceflow/pkg/config"
+	"github.com/z-wentao/voiceflow/pkg/filestore"
+	"github.com/z-wentao/voiceflow/pkg/models"
+	"github.com/z-wentao/voiceflow/pkg/queue"
+	"github.com/z-wentao/voiceflow/pkg/storage"
+)
+
+func TestMediaRequiresOwner(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	ctx := context.Background()
+
+	dir := t.TempDir()
+	files := filestore.NewLocalStore(dir)
+	const media = "ID3 fake audio"
+	if err := files.Put(ctx, "uploads/job-1.mp3", strings.NewReader(media), int64(len(media)), "audio/mpeg"); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	store := storage.NewJobStore(10)
+	if err := store.Save(ctx, &models.TranscriptionJob{JobID: "job-1", Filename: "talk.mp3", FilePath: "uploads/job-1.mp3", UserID: "alice", Status: models.StatusCompleted}); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	cfg := &config.Config{}
+	cfg.FileStore.Type = "local"
+	cfg.FileStore.Local.Dir = dir
+	cfg.Users.Keys = []config.UserKeyConfig{{Key: "alice-key", UserID: "alice"}, {Key: "bob-key", UserID: "bob"}}
+	app := &App{config: cfg, queue: queue.NewMemoryQueue(1), store: store, files: files}
+	router := app.setupRouter()
+
+	tests := []struct {
+		name     string
+		path     string
+		key      string
+		rangeHdr string
+		want     int
+		wantBody string
+	}{
+		{"静态目录已移除", "/uploads/job-1.mp3", "alice-key", "", http.StatusNotFound, ""},
+		{"任务所属用户", "/api/jobs/job-1/media", "alice-key", "", http.StatusOK, media},
+		{"断点续传", "/api/jobs/job-1/media", "alice-key", "bytes=0-2", http.StatusPartialContent, "ID3"},
+		{"其他用户", "/api/jobs/job-1/media", "bob-key", "", http.StatusNotFound, ""},
+		{"不携带 Key", "/api/jobs/job-1/media", "", "", http.StatusUnauthorized, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.key != "" {
+				req.Header.Set("X-API-Key", tt.key)
+			}
+			if tt.rangeHdr != "" {
+				req.Header.Set("Range", tt.rangeHdr)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			if w.Code != tt.want {
+				t.Fatalf("状态码 = %d，期望 %d（响应: %s）", w.Code, tt.want, w.Body.String())
+			}
+			if tt.wantBody != "" && w.Body.String() != tt.wantBody {
+				t.Fatalf("响应 = %q，期望 %q", w.Body.String(), tt.wantBody)
+			}
+		})
+	}
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/z-wentao/voiceflow/pkg/config"
+	"github.com/z-wentao/voiceflow/pkg/maimemo_service"
+	"github.com/z-wentao/voiceflow/pkg/models"
+	"github.com/z-wentao/voiceflow/pkg/queue"
+	"github.com/z-wentao/voiceflow/pkg/storage"
+)
+
+func TestListNotepadsEscapesError(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	service := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `<img src=x onerror=alert(1)>`, http.StatusBadGateway)
+	}))
+	defer service.Close()
+
+	store := storage.NewJobStore(10)
+	store.Save(context.Background(), &models.TranscriptionJob{JobID: "job-1", Status: models.StatusCompleted})
+
+	cfg := &config.Config{}
+	cfg.FileStore.Type = "s3"
+	app := &App{config: cfg, queue: queue.NewMemoryQueue(1), store: store, maimemoService: maimemo_service.NewClient(service.URL)}
+	router := app.setupRouter()
+
+	form := url.Values{"token": {"token"}, "job_id": {"job-1"}}
+	req := httptest.NewRequest(http.MethodPost, "/api/maimemo/list-notepads", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusInternalServerError {
+		t.Fatalf("状态码 = %d，期望 500", w.Code)
+	}
+	if strings.Contains(w.Body.String(), "<img") || !strings.Contains(w.Body.String(), "&lt;img") {
+		t.Fatalf("错误信息没有转义: %s", w.Body.String())
+	}
+}
+
+func TestListNotepads(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	service := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/notepads" || r.Header.Get("X-Maimemo-Token") != "token" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"notepads":[{"id":"np-1","title":"我的单词"}],"count":1}`))
+	}))
+	defer service.Close()
+
+	tests := []struct {
+		name     string
+		query    string
+		form     url.Values
+		wantCode int
+		wantBody string
+	}{
+		{"缺少任务 ID", "", url.Values{"token": {"token"}}, http.StatusBadRequest, "缺少任务 ID"},
+		{"任务 ID 为 unknown", "", url.Values{"token": {"token"}, "job_id": {"unknown"}}, http.StatusNotFound, "任务不存在"},
+		{"缺少 Token", "", url.Values{"job_id": {"job-1"}}, http.StatusBadRequest, "墨墨 API Token"},
+		{"表单中的任务 ID", "", url.Values{"token": {"token"}, "job_id": {"job-1"}}, http.StatusOK, "job-1"},
+		{"查询参数中的任务 ID", "?job_id=job-1", url.Values{"token": {"token"}}, http.StatusOK, "job-1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := storage.NewJobStore(10)
+			store.Save(context.Background(), &models.TranscriptionJob{JobID: "job-1", Status: models.StatusCompleted})
+
+			cfg := &config.Config{}
+			cfg.FileStore.Type = "s3"
+			app := &App{config: cfg, queue: queue.NewMemoryQueue(1), store: store, maimemoService: maimemo_service.NewClient(service.URL)}
+			router := app.setupRouter()
+
+			req := httptest.NewRequest(http.MethodPost, "/api/maimemo/list-notepads"+tt.query, strings.NewReader(tt.form.Encode()))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.wantCode {
+				t.Fatalf("状态码 = %d，期望 %d: %s", w.Code, tt.wantCode, w.Body.String())
+			}
+			if !strings.Contains(w.Body.String(), tt.wantBody) {
+				t.Fatalf("响应中没有 %q: %s", tt.wantBody, w.Body.String())
+			}
+			if tt.wantCode == http.StatusOK && (strings.Contains(w.Body.String(), "unknown") || !strings.Contains(w.Body.String(), "np-1")) {
+				t.Fatalf("云词本列表没有带上任务 ID: %s", w.Body.String())
+			}
+		})
+	}
+}
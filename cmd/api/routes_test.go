@@ -0,0 +1,44 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/z-wentao/voiceflow/pkg/config"
+	"github.com/z-wentao/voiceflow/pkg/filestore"
+	"github.com/z-wentao/voiceflow/pkg/queue"
+	"github.com/z-wentao/voiceflow/pkg/storage"
+)
+
+// TestRoutesDocumented /api 下的所有路由都通过 routes 注册，出现在 OpenAPI 文档中
+func TestRoutesDocumented(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	tests := []struct {
+		name      string
+		configure func(cfg *config.Config)
+	}{
+		{"默认配置", func(cfg *config.Config) {}},
+		{"开启鉴权和多用户", func(cfg *config.Config) {
+			cfg.Auth.Enabled = true
+			cfg.Users.Keys = []config.UserKeyConfig{{Key: "alice-key", UserID: "alice"}}
+			cfg.Server.AdminAPIKey = "admin-key"
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{}
+			cfg.FileStore.Type = "local"
+			cfg.FileStore.Local.Dir = t.TempDir()
+			tt.configure(cfg)
+			app := &App{config: cfg, queue: queue.NewMemoryQueue(1), store: storage.NewJobStore(10), files: filestore.NewLocalStore(cfg.FileStore.Local.Dir)}
+
+			r, spec := app.buildRouter()
+			if len(r.Routes()) == 0 {
+				t.Fatal("没有注册任何路由")
+			}
+			if missing := spec.Missing(r.Routes(), "/api"); len(missing) != 0 {
+				t.Fatalf("路由未记录到 OpenAPI 文档: %v", missing)
+			}
+		})
+	}
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/z-wentao/voiceflow/pkg/config"
+	"github.com/z-wentao/voiceflow/pkg/filestore"
+	"github.com/z-wentao/voiceflow/pkg/queue"
+	"github.com/z-wentao/voiceflow/pkg/storage"
+)
+
+// abortReader 读完 data 后返回 err（模拟上传中途连接中断），返回错误前调用 onAbort
+type abortReader struct {
+	data    []byte
+	err     error
+	onAbort func()
+}
+
+func (r *abortReader) Read(p []byte) (int, error) {
+	if len(r.data) == 0 {
+		if r.onAbort != nil {
+			r.onAbort()
+		}
+		return 0, r.err
+	}
+	n := copy(p, r.data)
+	r.data = r.data[n:]
+	return n, nil
+}
+
+// uploadBody 生成包含一个 audio 文件的 multipart 请求体
+func uploadBody(t *testing.T, filename string, size int) ([]byte, string) {
+	t.Helper()
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+	fw, err := mw.CreateFormFile("audio", filename)
+	if err != nil {
+		t.Fatalf("CreateFormFile: %v", err)
+	}
+	fw.Write(bytes.Repeat([]byte("a"), size))
+	mw.Close()
+	return buf.Bytes(), mw.FormDataContentType()
+}
+
+// countFiles 统计目录下的文件数（目录不存在时为 0）
+func countFiles(t *testing.T, dir string) int {
+	t.Helper()
+	count := 0
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() {
+			count++
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("WalkDir: %v", err)
+	}
+	return count
+}
+
+// TestUploadAbortCleansUp 上传中途失败或文件超过上限时，不留下临时文件、上传文件和任务
+func TestUploadAbortCleansUp(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	const maxSize = 4096
+
+	tests := []struct {
+		name       string
+		size       int  // 文件大小
+		cut        int  // 大于 0 时只发送请求体的前 cut 字节，随后连接出错
+		disconnect bool // 连接出错时客户端已经断开（请求 context 被取消）
+		unknownLen bool // 不声明 Content-Length（分块传输）
+		wantCode   int
+		wantBody   string
+	}{
+		{"文件超过上限", maxSize * 2, 0, false, false, http.StatusBadRequest, "文件太大"},
+		{"分块传输超过上限", 2 << 20, 0, false, true, http.StatusBadRequest, "文件太大"},
+		{"声明长度超过上限", 2 << 20, 0, false, false, http.StatusBadRequest, "上传内容太大"},
+		{"上传中途连接出错", maxSize, 1024, false, false, http.StatusBadRequest, "保存文件失败"},
+		{"客户端中途断开", maxSize, 1024, true, false, http.StatusOK, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{}
+			cfg.Server.MaxUploadSize = maxSize
+			cfg.Server.MaxBatchFiles = 1
+			cfg.Server.UploadTempDir = t.TempDir()
+			cfg.FileStore.Type = "local"
+			cfg.FileStore.Local.Dir = t.TempDir()
+			q := queue.NewMemoryQueue(1)
+			store := storage.NewJobStore(10)
+			app := &App{config: cfg, queue: q, store: store, files: filestore.NewLocalStore(cfg.FileStore.Local.Dir)}
+			router := app.setupRouter()
+
+			body, contentType := uploadBody(t, "talk.mp3", tt.size)
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			var reader io.Reader = bytes.NewReader(body)
+			if tt.cut > 0 {
+				onAbort := func() {}
+				if tt.disconnect {
+					onAbort = cancel
+				}
+				reader = &abortReader{data: body[:tt.cut], err: io.ErrUnexpectedEOF, onAbort: onAbort}
+			}
+			req := httptest.NewRequest(http.MethodPost, "/api/upload", reader).WithContext(ctx)
+			req.Header.Set("Content-Type", contentType)
+			req.ContentLength = int64(len(body))
+			if tt.unknownLen {
+				req.ContentLength = -1
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.wantCode || !strings.Contains(w.Body.String(), tt.wantBody) {
+				t.Fatalf("状态码 = %d，期望 %d；响应: %s", w.Code, tt.wantCode, w.Body.String())
+			}
+			if n := countFiles(t, cfg.Server.UploadTempDir); n != 0 {
+				t.Fatalf("临时目录中残留 %d 个文件", n)
+			}
+			if n := countFiles(t, filepath.Join(cfg.FileStore.Local.Dir, "uploads")); n != 0 {
+				t.Fatalf("uploads 中残留 %d 个文件", n)
+			}
+			jobs, err := store.List(context.Background())
+			if err != nil {
+				t.Fatalf("List: %v", err)
+			}
+			if len(jobs) != 0 {
+				t.Fatalf("创建了 %d 个任务，期望 0", len(jobs))
+			}
+			if stats, _ := q.Stats(); stats.Depth != 0 {
+				t.Fatalf("队列中有 %d 个任务，期望 0", stats.Depth)
+			}
+		})
+	}
+}
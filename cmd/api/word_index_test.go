@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/z-wentao/voiceflow/pkg/models"
+	"github.com/z-wentao/voiceflow/pkg/storage"
+)
+
+func TestBackfillWordIndex(t *testing.T) {
+	tests := []struct {
+		name        string
+		redis       bool   // 使用 Redis 存储（任务和哈希登记 1 小时后过期）
+		marker      string // 启动前的回填状态，空表示从未回填
+		legacy      bool   // 回填状态保存在旧版的哈希登记中
+		wantIndexed bool
+	}{
+		{"从未回填", false, "", false, true},
+		{"上次回填中途退出", false, wordIndexRunning, false, true},
+		{"已完成", false, wordIndexDone, false, false},
+		{"Redis 从未回填", true, "", false, true},
+		{"Redis 已完成", true, wordIndexDone, false, false},
+		{"Redis 旧版登记已完成", true, wordIndexDone, true, false},
+		{"Redis 旧版登记中途退出", true, wordIndexRunning, true, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			var store storage.Store = storage.NewJobStore(100)
+			var mr *miniredis.Miniredis
+			if tt.redis {
+				mr = miniredis.RunT(t)
+				redisStore, err := storage.NewRedisJobStore(mr.Addr(), "", 0, time.Hour, 0)
+				if err != nil {
+					t.Fatalf("NewRedisJobStore: %v", err)
+				}
+				t.Cleanup(func() { redisStore.Close() })
+				store = redisStore
+			}
+			if err := store.Save(ctx, &models.TranscriptionJob{JobID: "job-1", Status: models.StatusCompleted, Vocabulary: []string{"serendipity"}}); err != nil {
+				t.Fatalf("Save: %v", err)
+			}
+			if tt.marker != "" {
+				if tt.legacy {
+					store.SetHashIfAbsent(ctx, wordIndexMarker, tt.marker)
+				} else {
+					storage.SetMarkerIfAbsent(ctx, store, wordIndexMarker, tt.marker)
+				}
+			}
+
+			app := &App{store: store}
+			app.backfillWordIndex()
+
+			jobs, err := store.JobsByWord(ctx, "serendipity")
+			if err != nil {
+				t.Fatalf("JobsByWord: %v", err)
+			}
+			if indexed := len(jobs) == 1; indexed != tt.wantIndexed {
+				t.Fatalf("已回填 = %v，期望 %v", indexed, tt.wantIndexed)
+			}
+			// 回填状态不随任务过期，下次启动不会重新回填
+			if mr != nil {
+				mr.FastForward(2 * time.Hour)
+			}
+			if state, _, _ := storage.SetMarkerIfAbsent(ctx, store, wordIndexMarker, wordIndexRunning); state != wordIndexDone {
+				t.Fatalf("回填状态 = %q，期望 %q", state, wordIndexDone)
+			}
+		})
+	}
+}
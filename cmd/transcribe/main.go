@@ -0,0 +1,174 @@
+// transcribe 命令行转录工具：不启动 HTTP 服务和队列，直接转录单个文件
+//
+//	go run ./cmd/transcribe --file lecture.mp3 --out lecture.srt
+//
+// 转录文本和 WebVTT 字幕默认写到与 --out 同名的 .txt / .vtt 文件
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"log/slog"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/z-wentao/voiceflow/pkg/config"
+	"github.com/z-wentao/voiceflow/pkg/logging"
+	"github.com/z-wentao/voiceflow/pkg/transcriber"
+)
+
+func main() {
+	configPath := flag.String("config", "config/config.yaml", "配置文件路径")
+	file := flag.String("file", "", "要转录的音视频文件（必填）")
+	out := flag.String("out", "", "SRT 字幕输出路径，默认与输入文件同名")
+	vttOut := flag.String("vtt", "", "WebVTT 字幕输出路径，默认与 --out 同名（.vtt）")
+	textOut := flag.String("text", "", "转录文本输出路径，默认与 --out 同名（.txt）")
+	language := flag.String("language", "", "音频语言（如 en），为空时自动识别")
+	prompt := flag.String("prompt", "", "Whisper 提示词（专有名词、术语），为空时使用配置的默认值")
+	temperature := flag.Float64("temperature", 0, "Whisper 采样温度（0 到 1），为 0 时使用配置的默认值")
+	model := flag.String("model", "", "Whisper 模型，为空时使用 "+transcriber.DefaultModel)
+	verbose := flag.Bool("v", false, "输出转换引擎的详细日志")
+	flag.Parse()
+
+	if *file == "" {
+		fmt.Fprintln(os.Stderr, "用法: transcribe --file <音视频文件> [--out <字幕.srt>]")
+		flag.PrintDefaults()
+		os.Exit(2)
+	}
+	if _, err := os.Stat(*file); err != nil {
+		fatalf("读取输入文件失败: %v", err)
+	}
+	if *temperature < 0 || *temperature > 1 {
+		fatalf("--temperature 必须在 0 到 1 之间: %g", *temperature)
+	}
+
+	srtPath := *out
+	if srtPath == "" {
+		srtPath = replaceExt(*file, ".srt")
+	}
+	vttPath := *vttOut
+	if vttPath == "" {
+		vttPath = replaceExt(srtPath, ".vtt")
+	}
+	textPath := *textOut
+	if textPath == "" {
+		textPath = replaceExt(srtPath, ".txt")
+	}
+
+	cfg, err := config.LoadConfig(*configPath)
+	if err != nil {
+		fatalf("加载配置失败: %v", err)
+	}
+
+	// 引擎日志与进度条都输出到 stderr，默认只显示进度条
+	if *verbose {
+		logger, err := logging.New(os.Stderr, cfg.Log.Level, cfg.Log.Format)
+		if err != nil {
+			fatalf("初始化日志失败: %v", err)
+		}
+		slog.SetDefault(logger)
+	} else {
+		log.SetOutput(io.Discard)
+	}
+
+	engine := transcriber.NewTranscriptionEngine(
+		cfg.OpenAI.APIKey,
+		cfg.Transcriber.SegmentConcurrency,
+		cfg.Transcriber.SegmentDuration,
+		transcriber.EngineOptions{
+			BaseURL:        cfg.OpenAI.BaseURL,
+			WordTimestamps: cfg.Transcriber.WordTimestamps,
+			MaxCues:        cfg.Transcriber.MaxCues,
+			MaxLineChars:   cfg.Transcriber.MaxLineChars,
+			OverlapSeconds: cfg.Transcriber.SegmentOverlap,
+			Prompt:         cfg.Transcriber.Prompt,
+			Temperature:    cfg.Transcriber.Temperature,
+
+			WhisperTimeout:   time.Duration(cfg.Transcriber.WhisperTimeoutSeconds) * time.Second,
+			TimeoutPerMinute: time.Duration(cfg.Transcriber.WhisperSecondsPerMinute) * time.Second,
+		},
+	)
+
+	// Ctrl+C 取消转录（进行中的分片请求会被中断）
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	// 命令行转录没有任务 ID，不保存片段结果（中断后从头转录）
+	printProgress(0)
+	result, err := engine.Transcribe(ctx, "", *file, transcriber.WhisperOptions{
+		Language:    *language,
+		Prompt:      *prompt,
+		Temperature: *temperature,
+		Model:       *model,
+	}, printProgress)
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		fatalf("转录失败: %v", err)
+	}
+
+	// 引擎把字幕写在输入文件旁边，移动到指定的输出路径
+	if result.SubtitlePath == "" {
+		fatalf("生成字幕失败（使用 -v 查看详细日志）")
+	}
+	if err := moveFile(result.SubtitlePath, srtPath); err != nil {
+		fatalf("写入 SRT 字幕失败: %v", err)
+	}
+	if err := moveFile(result.VTTPath, vttPath); err != nil {
+		fatalf("写入 WebVTT 字幕失败: %v", err)
+	}
+	if err := os.WriteFile(textPath, []byte(result.Text), 0644); err != nil {
+		fatalf("写入转录文本失败: %v", err)
+	}
+
+	fmt.Fprintf(os.Stderr, "✅ 转录完成（音频时长 %.0f 秒）\n", result.Duration)
+	fmt.Fprintf(os.Stderr, "   - 文本: %s\n", textPath)
+	fmt.Fprintf(os.Stderr, "   - SRT:  %s\n", srtPath)
+	fmt.Fprintf(os.Stderr, "   - VTT:  %s\n", vttPath)
+}
+
+// progressWidth 进度条宽度（字符数）
+const progressWidth = 40
+
+// printProgress 在 stderr 上原地刷新进度条
+func printProgress(progress int) {
+	filled := progress * progressWidth / 100
+	bar := strings.Repeat("█", filled) + strings.Repeat("░", progressWidth-filled)
+	fmt.Fprintf(os.Stderr, "\r转录中 %s %3d%%", bar, progress)
+}
+
+// replaceExt 替换文件扩展名
+func replaceExt(path, ext string) string {
+	return strings.TrimSuffix(path, filepath.Ext(path)) + ext
+}
+
+// moveFile 移动文件，跨文件系统时复制后删除源文件
+func moveFile(src, dst string) error {
+	if filepath.Clean(src) == filepath.Clean(dst) {
+		return nil
+	}
+	if err := os.Rename(src, dst); err == nil {
+		return nil
+	}
+
+	data, err := os.ReadFile(src)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(dst, data, 0644); err != nil {
+		return err
+	}
+	return os.Remove(src)
+}
+
+// fatalf 输出错误并以非零状态退出
+func fatalf(format string, args ...any) {
+	fmt.Fprintf(os.Stderr, "❌ "+format+"\n", args...)
+	os.Exit(1)
+}
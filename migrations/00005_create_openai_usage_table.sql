@@ -0,0 +1,18 @@
+-- +goose Up
+-- +goose StatementBegin
+-- 创建 OpenAI 月度费用表
+CREATE TABLE IF NOT EXISTS openai_usage (
+    month VARCHAR(7) PRIMARY KEY,
+    cost_usd DOUBLE PRECISION NOT NULL DEFAULT 0,
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+COMMENT ON TABLE openai_usage IS 'OpenAI 月度预估费用';
+COMMENT ON COLUMN openai_usage.month IS '月份，格式 YYYY-MM';
+COMMENT ON COLUMN openai_usage.cost_usd IS '累计预估费用（美元）';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS openai_usage;
+-- +goose StatementEnd
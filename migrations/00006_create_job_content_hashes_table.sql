@@ -0,0 +1,18 @@
+-- +goose Up
+-- +goose StatementBegin
+-- 创建文件内容哈希表（上传去重，主键约束保证多实例并发时只有一个任务登记成功）
+CREATE TABLE IF NOT EXISTS job_content_hashes (
+    content_hash VARCHAR(64) PRIMARY KEY,
+    job_id VARCHAR(36) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+COMMENT ON TABLE job_content_hashes IS '上传文件内容哈希与任务的对应关系';
+COMMENT ON COLUMN job_content_hashes.content_hash IS '文件内容 SHA-256（十六进制）';
+COMMENT ON COLUMN job_content_hashes.job_id IS '首个上传该文件的任务 ID';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS job_content_hashes;
+-- +goose StatementEnd
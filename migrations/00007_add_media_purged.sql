@@ -0,0 +1,7 @@
+-- +goose Up
+ALTER TABLE transcription_jobs ADD COLUMN media_purged BOOLEAN NOT NULL DEFAULT FALSE;
+
+COMMENT ON COLUMN transcription_jobs.media_purged IS '原始媒体文件是否已按保留策略清理';
+
+-- +goose Down
+ALTER TABLE transcription_jobs DROP COLUMN media_purged;
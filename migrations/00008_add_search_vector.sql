@@ -0,0 +1,14 @@
+-- +goose Up
+-- 全文搜索：文件名 + 转录文本（由应用在 Save 时写入）
+ALTER TABLE transcription_jobs ADD COLUMN search_vector tsvector;
+
+UPDATE transcription_jobs
+SET search_vector = to_tsvector('english', coalesce(filename, '') || ' ' || coalesce(result, ''));
+
+CREATE INDEX idx_jobs_search_vector ON transcription_jobs USING GIN (search_vector);
+
+COMMENT ON COLUMN transcription_jobs.search_vector IS '全文搜索向量（文件名 + 转录文本）';
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_jobs_search_vector;
+ALTER TABLE transcription_jobs DROP COLUMN search_vector;
@@ -0,0 +1,7 @@
+-- +goose Up
+ALTER TABLE transcription_jobs ADD COLUMN source VARCHAR(100);
+
+COMMENT ON COLUMN transcription_jobs.source IS '任务来源（订阅源名称），手动上传为空';
+
+-- +goose Down
+ALTER TABLE transcription_jobs DROP COLUMN source;
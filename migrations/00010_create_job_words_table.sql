@@ -0,0 +1,20 @@
+-- +goose Up
+-- +goose StatementBegin
+-- 创建单词倒排索引表（单词 → 任务），用于查询"哪些转录中出现过这个单词"
+-- 单词为应用层归一化后的形式；已有任务的索引由服务启动时一次性回填
+CREATE TABLE IF NOT EXISTS job_words (
+    word VARCHAR(255) NOT NULL,
+    job_id VARCHAR(36) NOT NULL,
+    PRIMARY KEY (word, job_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_job_words_job_id ON job_words(job_id);
+
+COMMENT ON TABLE job_words IS '单词与任务的倒排索引';
+COMMENT ON COLUMN job_words.word IS '归一化后的单词（小写、词形还原）';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS job_words;
+-- +goose StatementEnd
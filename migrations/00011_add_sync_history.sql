@@ -0,0 +1,7 @@
+-- +goose Up
+ALTER TABLE transcription_jobs ADD COLUMN sync_history JSONB;
+
+COMMENT ON COLUMN transcription_jobs.sync_history IS '单词同步记录（同步目标、单词数、结果）';
+
+-- +goose Down
+ALTER TABLE transcription_jobs DROP COLUMN sync_history;
@@ -0,0 +1,7 @@
+-- +goose Up
+ALTER TABLE transcription_jobs ADD COLUMN attempts INTEGER NOT NULL DEFAULT 0;
+
+COMMENT ON COLUMN transcription_jobs.attempts IS '处理中因服务重启被中断的次数（达到上限后标记为失败）';
+
+-- +goose Down
+ALTER TABLE transcription_jobs DROP COLUMN attempts;
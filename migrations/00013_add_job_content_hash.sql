@@ -0,0 +1,10 @@
+-- +goose Up
+ALTER TABLE transcription_jobs ADD COLUMN content_hash VARCHAR(64);
+
+CREATE INDEX idx_jobs_content_hash ON transcription_jobs(content_hash);
+
+COMMENT ON COLUMN transcription_jobs.content_hash IS '上传文件内容的 SHA-256，用于识别重复上传';
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_jobs_content_hash;
+ALTER TABLE transcription_jobs DROP COLUMN content_hash;
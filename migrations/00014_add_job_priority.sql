@@ -0,0 +1,7 @@
+-- +goose Up
+ALTER TABLE transcription_jobs ADD COLUMN priority SMALLINT NOT NULL DEFAULT 0;
+
+COMMENT ON COLUMN transcription_jobs.priority IS '队列优先级（0 为普通，越大越先处理），启动恢复重新入队时沿用';
+
+-- +goose Down
+ALTER TABLE transcription_jobs DROP COLUMN priority;
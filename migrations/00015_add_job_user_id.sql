@@ -0,0 +1,10 @@
+-- +goose Up
+ALTER TABLE transcription_jobs ADD COLUMN user_id VARCHAR(255);
+
+CREATE INDEX idx_jobs_user_id ON transcription_jobs(user_id, created_at DESC);
+
+COMMENT ON COLUMN transcription_jobs.user_id IS '上传任务的用户（多用户模式），为空表示未启用多用户或系统创建';
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_jobs_user_id;
+ALTER TABLE transcription_jobs DROP COLUMN user_id;
@@ -0,0 +1,21 @@
+-- +goose Up
+-- +goose StatementBegin
+-- 创建用户凭证表（保存加密后的第三方 Token，如墨墨 API Token）
+CREATE TABLE IF NOT EXISTS user_credentials (
+    user_id VARCHAR(255) NOT NULL,
+    name VARCHAR(64) NOT NULL,
+    value TEXT NOT NULL,
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (user_id, name)
+);
+
+COMMENT ON TABLE user_credentials IS '用户的第三方凭证（加密保存）';
+COMMENT ON COLUMN user_credentials.user_id IS '用户 ID（未启用多用户时为空字符串）';
+COMMENT ON COLUMN user_credentials.name IS '凭证名称，如 maimemo';
+COMMENT ON COLUMN user_credentials.value IS 'AES-GCM 加密后的凭证（base64）';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS user_credentials;
+-- +goose StatementEnd
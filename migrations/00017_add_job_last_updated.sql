@@ -0,0 +1,7 @@
+-- +goose Up
+ALTER TABLE transcription_jobs ADD COLUMN last_updated TIMESTAMP;
+
+COMMENT ON COLUMN transcription_jobs.last_updated IS '状态或进度最近一次变化的时间（进度轮询的 ETag 依据）';
+
+-- +goose Down
+ALTER TABLE transcription_jobs DROP COLUMN last_updated;
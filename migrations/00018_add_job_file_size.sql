@@ -0,0 +1,7 @@
+-- +goose Up
+ALTER TABLE transcription_jobs ADD COLUMN file_size BIGINT;
+
+COMMENT ON COLUMN transcription_jobs.file_size IS '原始媒体文件大小（字节）';
+
+-- +goose Down
+ALTER TABLE transcription_jobs DROP COLUMN file_size;
@@ -0,0 +1,10 @@
+-- +goose Up
+ALTER TABLE transcription_jobs ADD COLUMN prompt TEXT;
+ALTER TABLE transcription_jobs ADD COLUMN temperature DOUBLE PRECISION;
+
+COMMENT ON COLUMN transcription_jobs.prompt IS 'Whisper 提示词（专有名词、术语），为空时使用配置的默认值';
+COMMENT ON COLUMN transcription_jobs.temperature IS 'Whisper 采样温度，为 0 或 NULL 时使用配置的默认值';
+
+-- +goose Down
+ALTER TABLE transcription_jobs DROP COLUMN temperature;
+ALTER TABLE transcription_jobs DROP COLUMN prompt;
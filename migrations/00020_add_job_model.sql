@@ -0,0 +1,7 @@
+-- +goose Up
+ALTER TABLE transcription_jobs ADD COLUMN model TEXT;
+
+COMMENT ON COLUMN transcription_jobs.model IS 'Whisper 模型，为空时使用默认模型';
+
+-- +goose Down
+ALTER TABLE transcription_jobs DROP COLUMN model;
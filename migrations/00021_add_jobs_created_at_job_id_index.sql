@@ -0,0 +1,6 @@
+-- +goose Up
+-- ListAll 按 (created_at, job_id) 做 keyset 分页，复合索引让每一页都是索引范围扫描
+CREATE INDEX IF NOT EXISTS idx_jobs_created_at_job_id ON transcription_jobs(created_at DESC, job_id DESC);
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_jobs_created_at_job_id;
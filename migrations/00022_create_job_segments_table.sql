@@ -0,0 +1,20 @@
+-- +goose Up
+-- +goose StatementBegin
+-- 创建片段结果表：保存转录中已完成片段的 Whisper 响应，任务中断后重新处理时跳过这些片段
+-- 所有片段完成后删除；未结束的任务在混合存储中只保存在 Redis，因此不引用 transcription_jobs
+CREATE TABLE IF NOT EXISTS job_segments (
+    job_id VARCHAR(36) NOT NULL,
+    segment_index INTEGER NOT NULL,
+    data TEXT NOT NULL,
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (job_id, segment_index)
+);
+
+COMMENT ON TABLE job_segments IS '转录中已完成片段的结果（断点续传）';
+COMMENT ON COLUMN job_segments.data IS '片段结果（JSON，包含片段边界、请求参数指纹和 Whisper 响应）';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS job_segments;
+-- +goose StatementEnd
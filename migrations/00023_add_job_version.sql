@@ -0,0 +1,8 @@
+-- +goose Up
+-- 乐观锁版本号：每次写入加 1，保存时要求与读取时一致，避免并发修改互相覆盖
+ALTER TABLE transcription_jobs ADD COLUMN version BIGINT NOT NULL DEFAULT 1;
+
+COMMENT ON COLUMN transcription_jobs.version IS '版本号（乐观锁），每次写入加 1';
+
+-- +goose Down
+ALTER TABLE transcription_jobs DROP COLUMN version;
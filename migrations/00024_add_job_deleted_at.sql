@@ -0,0 +1,11 @@
+-- +goose Up
+-- 软删除：删除任务时记录删除时间，保留期内可以恢复，之后由清理器永久删除
+ALTER TABLE transcription_jobs ADD COLUMN deleted_at TIMESTAMP;
+
+CREATE INDEX IF NOT EXISTS idx_jobs_deleted_at ON transcription_jobs(deleted_at) WHERE deleted_at IS NOT NULL;
+
+COMMENT ON COLUMN transcription_jobs.deleted_at IS '软删除时间，NULL 表示未删除';
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_jobs_deleted_at;
+ALTER TABLE transcription_jobs DROP COLUMN deleted_at;
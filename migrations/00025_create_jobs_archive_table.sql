@@ -0,0 +1,20 @@
+-- +goose Up
+-- +goose StatementBegin
+-- 归档表：完成较久的任务从 transcription_jobs 移到这里，任务表保持较小，列表查询不再扫描大量转录文本
+-- 结构与 transcription_jobs 相同（之后给 transcription_jobs 加列时需要同时给归档表加列）；Get 在任务表中查不到时读取归档表
+CREATE TABLE IF NOT EXISTS transcription_jobs_archive (LIKE transcription_jobs INCLUDING DEFAULTS INCLUDING CONSTRAINTS);
+ALTER TABLE transcription_jobs_archive ADD PRIMARY KEY (job_id);
+
+CREATE INDEX IF NOT EXISTS idx_jobs_archive_created_at ON transcription_jobs_archive(created_at);
+
+-- 清理器按完成时间查找要归档的任务
+CREATE INDEX IF NOT EXISTS idx_jobs_completed_at ON transcription_jobs(completed_at) WHERE status = 'completed';
+
+COMMENT ON TABLE transcription_jobs_archive IS '已归档的任务（结构与 transcription_jobs 相同）';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_jobs_completed_at;
+DROP TABLE IF EXISTS transcription_jobs_archive;
+-- +goose StatementEnd
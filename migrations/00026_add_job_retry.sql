@@ -0,0 +1,17 @@
+-- +goose Up
+-- 临时错误自动重试：记录已重试次数和下一次重试时间（归档表与任务表结构保持一致）
+ALTER TABLE transcription_jobs ADD COLUMN retry_count INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE transcription_jobs ADD COLUMN next_attempt_at TIMESTAMP;
+
+ALTER TABLE transcription_jobs_archive ADD COLUMN retry_count INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE transcription_jobs_archive ADD COLUMN next_attempt_at TIMESTAMP;
+
+COMMENT ON COLUMN transcription_jobs.retry_count IS '因临时错误（Whisper 429/5xx、网络超时）自动重试的次数';
+COMMENT ON COLUMN transcription_jobs.next_attempt_at IS '下一次自动重试的时间，NULL 表示没有等待中的重试';
+
+-- +goose Down
+ALTER TABLE transcription_jobs_archive DROP COLUMN next_attempt_at;
+ALTER TABLE transcription_jobs_archive DROP COLUMN retry_count;
+
+ALTER TABLE transcription_jobs DROP COLUMN next_attempt_at;
+ALTER TABLE transcription_jobs DROP COLUMN retry_count;
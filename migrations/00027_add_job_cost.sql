@@ -0,0 +1,22 @@
+-- +goose Up
+-- 每个任务的 OpenAI 费用估算（归档表与任务表结构保持一致）
+ALTER TABLE transcription_jobs ADD COLUMN estimated_cost DOUBLE PRECISION NOT NULL DEFAULT 0;
+ALTER TABLE transcription_jobs ADD COLUMN prompt_tokens INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE transcription_jobs ADD COLUMN completion_tokens INTEGER NOT NULL DEFAULT 0;
+
+ALTER TABLE transcription_jobs_archive ADD COLUMN estimated_cost DOUBLE PRECISION NOT NULL DEFAULT 0;
+ALTER TABLE transcription_jobs_archive ADD COLUMN prompt_tokens INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE transcription_jobs_archive ADD COLUMN completion_tokens INTEGER NOT NULL DEFAULT 0;
+
+COMMENT ON COLUMN transcription_jobs.estimated_cost IS '估算的 OpenAI 费用（美元）：转录、字幕翻译和单词提取累计';
+COMMENT ON COLUMN transcription_jobs.prompt_tokens IS '字幕翻译和单词提取累计的输入 token 数';
+COMMENT ON COLUMN transcription_jobs.completion_tokens IS '字幕翻译和单词提取累计的输出 token 数';
+
+-- +goose Down
+ALTER TABLE transcription_jobs_archive DROP COLUMN completion_tokens;
+ALTER TABLE transcription_jobs_archive DROP COLUMN prompt_tokens;
+ALTER TABLE transcription_jobs_archive DROP COLUMN estimated_cost;
+
+ALTER TABLE transcription_jobs DROP COLUMN completion_tokens;
+ALTER TABLE transcription_jobs DROP COLUMN prompt_tokens;
+ALTER TABLE transcription_jobs DROP COLUMN estimated_cost;
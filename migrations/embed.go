@@ -0,0 +1,10 @@
+// Package migrations 嵌入 PostgreSQL 迁移文件（Goose 格式），
+// 由 PostgreSQL 存储在启动时自动执行，也可以继续使用 goose 命令行手动管理
+package migrations
+
+import "embed"
+
+// FS 所有迁移 SQL 文件
+//
+//go:embed *.sql
+var FS embed.FS
@@ -0,0 +1,496 @@
+package api
+
+import (
+	"net/http"
+	"path"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 参数位置
+const (
+	InPath  = "path"
+	InQuery = "query"
+	InForm  = "form" // 表单字段，生成到 requestBody 中
+)
+
+// Param 接口参数
+type Param struct {
+	Name        string
+	In          string // InPath / InQuery / InForm
+	Type        string // string（默认）、integer、boolean、file
+	Array       bool   // 可重复（如同一字段上传多个文件）
+	Required    bool
+	Enum        []string
+	Description string
+}
+
+// Query 查询参数
+func Query(name, description string) Param {
+	return Param{Name: name, In: InQuery, Description: description}
+}
+
+// FormField 表单字段
+func FormField(name string, required bool, description string) Param {
+	return Param{Name: name, In: InForm, Required: required, Description: description}
+}
+
+// FormFiles 文件字段（可包含多个文件）
+func FormFiles(name string, required bool, description string) Param {
+	return Param{Name: name, In: InForm, Type: "file", Array: true, Required: required, Description: description}
+}
+
+// Response 接口响应
+type Response struct {
+	Status      int
+	Description string
+	ContentType string // 默认 application/json
+	Schema      any    // Go 值（如 models.TranscriptionJob{}），通过反射生成 schema；nil 表示不描述结构
+}
+
+// ErrorBody JSON 接口的错误响应
+type ErrorBody struct {
+	Error string `json:"error"`
+}
+
+// JSON JSON 响应
+func JSON(status int, description string, schema any) Response {
+	return Response{Status: status, Description: description, ContentType: gin.MIMEJSON, Schema: schema}
+}
+
+// Error JSON 错误响应（{"error": "..."}）
+func Error(status int, description string) Response {
+	return JSON(status, description, ErrorBody{})
+}
+
+// HTML HTML 片段响应（htmx 接口）
+func HTML(status int, description string) Response {
+	return Response{Status: status, Description: description, ContentType: gin.MIMEHTML}
+}
+
+// File 文件下载响应
+func File(contentType, description string) Response {
+	return Response{Status: http.StatusOK, Description: description, ContentType: contentType}
+}
+
+// Operation 单个路由的文档
+type Operation struct {
+	Summary     string
+	Description string
+	Tags        []string
+	Params      []Param // 路径参数可省略，注册时根据路由自动补充
+	Body        any     // JSON 请求体（Go 值，通过反射生成 schema）；nil 表示没有 JSON 请求体
+	Responses   []Response
+}
+
+// route 已注册的路由
+type route struct {
+	method string
+	path   string // gin 路由格式，如 /api/jobs/:job_id
+	op     Operation
+}
+
+// Spec 记录通过 Group 注册的路由，生成 OpenAPI 3 文档
+type Spec struct {
+	title   string
+	version string
+
+	mu     sync.Mutex
+	routes []route
+	enums  map[reflect.Type][]any
+}
+
+// NewSpec 创建文档
+func NewSpec(title, version string) *Spec {
+	return &Spec{
+		title:   title,
+		version: version,
+		enums:   make(map[reflect.Type][]any),
+	}
+}
+
+// Enum 声明枚举类型的取值（如 models.JobStatus），生成 schema 时附带 enum
+func (s *Spec) Enum(sample any, values ...any) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.enums[reflect.TypeOf(sample)] = values
+}
+
+// Wrap 包装 gin 路由组，通过返回的 Group 注册的路由会同时记录到文档
+func (s *Spec) Wrap(group *gin.RouterGroup) *Group {
+	return &Group{group: group, spec: s}
+}
+
+// Group 带文档的路由组
+type Group struct {
+	group *gin.RouterGroup
+	spec  *Spec
+}
+
+// Group 创建子路由组
+func (g *Group) Group(relativePath string) *Group {
+	return &Group{group: g.group.Group(relativePath), spec: g.spec}
+}
+
+// Use 为路由组添加中间件（只影响之后注册的路由），返回路由组本身以便链式调用
+func (g *Group) Use(middleware ...gin.HandlerFunc) *Group {
+	g.group.Use(middleware...)
+	return g
+}
+
+// GET 注册 GET 路由
+func (g *Group) GET(relativePath string, op Operation, handlers ...gin.HandlerFunc) {
+	g.Handle(http.MethodGet, relativePath, op, handlers...)
+}
+
+// POST 注册 POST 路由
+func (g *Group) POST(relativePath string, op Operation, handlers ...gin.HandlerFunc) {
+	g.Handle(http.MethodPost, relativePath, op, handlers...)
+}
+
+// PUT 注册 PUT 路由
+func (g *Group) PUT(relativePath string, op Operation, handlers ...gin.HandlerFunc) {
+	g.Handle(http.MethodPut, relativePath, op, handlers...)
+}
+
+// DELETE 注册 DELETE 路由
+func (g *Group) DELETE(relativePath string, op Operation, handlers ...gin.HandlerFunc) {
+	g.Handle(http.MethodDelete, relativePath, op, handlers...)
+}
+
+// Handle 注册路由并记录文档
+func (g *Group) Handle(method, relativePath string, op Operation, handlers ...gin.HandlerFunc) {
+	g.group.Handle(method, relativePath, handlers...)
+
+	g.spec.mu.Lock()
+	defer g.spec.mu.Unlock()
+	g.spec.routes = append(g.spec.routes, route{
+		method: method,
+		path:   path.Join(g.group.BasePath(), relativePath),
+		op:     op,
+	})
+}
+
+// Missing 返回在 gin 中注册、但没有记录到文档的路由（如 "GET /api/ping"）
+// 只检查以 prefix 开头的路由，用于启动时发现绕过 Group 直接注册的接口
+func (s *Spec) Missing(routes gin.RoutesInfo, prefix string) []string {
+	s.mu.Lock()
+	documented := make(map[string]bool, len(s.routes))
+	for _, r := range s.routes {
+		documented[r.method+" "+r.path] = true
+	}
+	s.mu.Unlock()
+
+	var missing []string
+	for _, r := range routes {
+		key := r.Method + " " + r.Path
+		if strings.HasPrefix(r.Path, prefix) && !documented[key] {
+			missing = append(missing, key)
+		}
+	}
+	return missing
+}
+
+// Handler 返回 OpenAPI 文档（JSON）
+func (s *Spec) Handler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.JSON(http.StatusOK, s.Document())
+	}
+}
+
+// Document 生成 OpenAPI 3 文档
+func (s *Spec) Document() map[string]any {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	b := &schemaBuilder{
+		enums:   s.enums,
+		schemas: make(map[string]any),
+		names:   make(map[reflect.Type]string),
+	}
+
+	paths := make(map[string]map[string]any)
+	for _, r := range s.routes {
+		p := openAPIPath(r.path)
+		if paths[p] == nil {
+			paths[p] = make(map[string]any)
+		}
+		paths[p][strings.ToLower(r.method)] = b.operation(r)
+	}
+
+	doc := map[string]any{
+		"openapi": "3.0.3",
+		"info": map[string]any{
+			"title":   s.title,
+			"version": s.version,
+		},
+		"paths": paths,
+	}
+	if len(b.schemas) > 0 {
+		doc["components"] = map[string]any{"schemas": b.schemas}
+	}
+	return doc
+}
+
+// openAPIPath 将 gin 路由参数（:name、*name）转换为 OpenAPI 格式（{name}）
+func openAPIPath(ginPath string) string {
+	parts := strings.Split(ginPath, "/")
+	for i, part := range parts {
+		if strings.HasPrefix(part, ":") || strings.HasPrefix(part, "*") {
+			parts[i] = "{" + part[1:] + "}"
+		}
+	}
+	return strings.Join(parts, "/")
+}
+
+// pathParams 路由中的路径参数名
+func pathParams(ginPath string) []string {
+	var names []string
+	for _, part := range strings.Split(ginPath, "/") {
+		if strings.HasPrefix(part, ":") || strings.HasPrefix(part, "*") {
+			names = append(names, part[1:])
+		}
+	}
+	return names
+}
+
+// schemaBuilder 生成 schema，具名结构体放入 components/schemas 并以 $ref 引用
+type schemaBuilder struct {
+	enums   map[reflect.Type][]any
+	schemas map[string]any
+	names   map[reflect.Type]string
+}
+
+// operation 生成单个路由的文档
+func (b *schemaBuilder) operation(r route) map[string]any {
+	op := map[string]any{}
+	if r.op.Summary != "" {
+		op["summary"] = r.op.Summary
+	}
+	if r.op.Description != "" {
+		op["description"] = r.op.Description
+	}
+	if len(r.op.Tags) > 0 {
+		op["tags"] = r.op.Tags
+	}
+
+	// 未声明的路径参数自动补充
+	params := append([]Param(nil), r.op.Params...)
+	declared := make(map[string]bool)
+	for _, p := range params {
+		if p.In == InPath {
+			declared[p.Name] = true
+		}
+	}
+	for _, name := range pathParams(r.path) {
+		if !declared[name] {
+			params = append(params, Param{Name: name, In: InPath})
+		}
+	}
+
+	var parameters []map[string]any
+	formProps := map[string]any{}
+	var formRequired []string
+	multipart := false
+	for _, p := range params {
+		schema := paramSchema(p)
+		if p.In == InForm {
+			formProps[p.Name] = schema
+			if p.Required {
+				formRequired = append(formRequired, p.Name)
+			}
+			if p.Type == "file" {
+				multipart = true
+			}
+			continue
+		}
+
+		param := map[string]any{
+			"name":     p.Name,
+			"in":       p.In,
+			"required": p.Required || p.In == InPath,
+			"schema":   schema,
+		}
+		if p.Description != "" {
+			param["description"] = p.Description
+		}
+		parameters = append(parameters, param)
+	}
+	if len(parameters) > 0 {
+		op["parameters"] = parameters
+	}
+
+	if len(formProps) > 0 {
+		contentType := "application/x-www-form-urlencoded"
+		if multipart {
+			contentType = "multipart/form-data"
+		}
+		schema := map[string]any{"type": "object", "properties": formProps}
+		if len(formRequired) > 0 {
+			schema["required"] = formRequired
+		}
+		op["requestBody"] = map[string]any{
+			"required": len(formRequired) > 0,
+			"content":  map[string]any{contentType: map[string]any{"schema": schema}},
+		}
+	}
+	if r.op.Body != nil {
+		op["requestBody"] = map[string]any{
+			"required": true,
+			"content":  map[string]any{gin.MIMEJSON: map[string]any{"schema": b.schema(reflect.TypeOf(r.op.Body))}},
+		}
+	}
+
+	// 同一状态码可以有多种内容类型（如按 Accept 返回 HTML 或 JSON），合并到一个响应中
+	responses := map[string]any{}
+	for _, resp := range r.op.Responses {
+		code := strconv.Itoa(resp.Status)
+		entry, ok := responses[code].(map[string]any)
+		if !ok {
+			entry = map[string]any{"description": resp.Description, "content": map[string]any{}}
+			responses[code] = entry
+		}
+		contentType := resp.ContentType
+		if contentType == "" {
+			contentType = gin.MIMEJSON
+		}
+		media := map[string]any{}
+		if resp.Schema != nil {
+			media["schema"] = b.schema(reflect.TypeOf(resp.Schema))
+		}
+		entry["content"].(map[string]any)[contentType] = media
+	}
+	if len(responses) == 0 {
+		responses["200"] = map[string]any{"description": "成功"}
+	}
+	op["responses"] = responses
+
+	return op
+}
+
+// paramSchema 参数的 schema
+func paramSchema(p Param) map[string]any {
+	var schema map[string]any
+	switch p.Type {
+	case "file":
+		schema = map[string]any{"type": "string", "format": "binary"}
+	case "":
+		schema = map[string]any{"type": "string"}
+	default:
+		schema = map[string]any{"type": p.Type}
+	}
+	if len(p.Enum) > 0 {
+		schema["enum"] = p.Enum
+	}
+	if p.Array {
+		return map[string]any{"type": "array", "items": schema}
+	}
+	return schema
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// schema 由 Go 类型生成 schema（字段名取 json tag）
+func (b *schemaBuilder) schema(t reflect.Type) map[string]any {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	if values, ok := b.enums[t]; ok {
+		schema := b.basicSchema(t)
+		schema["enum"] = values
+		return schema
+	}
+
+	if t == timeType {
+		return map[string]any{"type": "string", "format": "date-time"}
+	}
+	if t.Kind() != reflect.Struct {
+		return b.basicSchema(t)
+	}
+	if t.Name() == "" {
+		return b.structSchema(t)
+	}
+
+	// 具名结构体：注册到 components，首次遇到时先占位，支持递归引用
+	name, ok := b.names[t]
+	if !ok {
+		name = b.componentName(t)
+		b.names[t] = name
+		b.schemas[name] = nil
+		b.schemas[name] = b.structSchema(t)
+	}
+	return map[string]any{"$ref": "#/components/schemas/" + name}
+}
+
+// componentName 组件名，不同包中的同名类型加上包名前缀
+func (b *schemaBuilder) componentName(t reflect.Type) string {
+	name := t.Name()
+	if _, taken := b.schemas[name]; !taken {
+		return name
+	}
+	pkg := path.Base(t.PkgPath())
+	return strings.ToUpper(pkg[:1]) + pkg[1:] + name
+}
+
+// basicSchema 非结构体类型的 schema
+func (b *schemaBuilder) basicSchema(t reflect.Type) map[string]any {
+	switch t.Kind() {
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return map[string]any{"type": "string", "format": "byte"}
+		}
+		return map[string]any{"type": "array", "items": b.schema(t.Elem())}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": b.schema(t.Elem())}
+	default:
+		return map[string]any{}
+	}
+}
+
+// structSchema 结构体的 schema：json:"-" 和未导出字段跳过，没有 omitempty 的字段视为必有
+func (b *schemaBuilder) structSchema(t reflect.Type) map[string]any {
+	props := map[string]any{}
+	var required []string
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		if name == "" {
+			name = field.Name
+		}
+		props[name] = b.schema(field.Type)
+		if !strings.Contains(opts, "omitempty") {
+			required = append(required, name)
+		}
+	}
+
+	schema := map[string]any{"type": "object", "properties": props}
+	if len(required) > 0 {
+		sort.Strings(required)
+		schema["required"] = required
+	}
+	return schema
+}
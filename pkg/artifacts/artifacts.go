@@ -0,0 +1,172 @@
+package artifacts
+
+import (
+	"context"
+	"fmt"
+	"mime"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/z-wentao/voiceflow/pkg/filestore"
+	"github.com/z-wentao/voiceflow/pkg/models"
+)
+
+// Artifact 任务产物（可下载的文件）
+type Artifact struct {
+	Kind         string    `json:"kind"`          // 产物类型，如 transcript_txt / srt / vtt / media
+	Filename     string    `json:"filename"`      // 建议的下载文件名
+	URL          string    `json:"url"`           // 下载地址（相对路径）
+	Size         int64     `json:"size"`          // 文件大小（字节），打包下载为 0（打包时才知道大小）
+	ContentType  string    `json:"content_type"`  // MIME 类型
+	LastModified time.Time `json:"last_modified"` // 最后修改时间
+}
+
+// fileArtifact 基于文件存储的产物定义
+// 新增产物类型只需在 fileArtifacts 中追加一项，索引接口会自动包含（即时生成的产物见 generatedArtifacts）
+type fileArtifact struct {
+	kind        string
+	ext         string
+	contentType string
+	path        func(job *models.TranscriptionJob) string
+	url         func(job *models.TranscriptionJob) string
+}
+
+var fileArtifacts = []fileArtifact{
+	{
+		kind:        "srt",
+		ext:         ".srt",
+		contentType: "text/plain; charset=utf-8",
+		path:        func(job *models.TranscriptionJob) string { return job.SubtitlePath },
+		url:         func(job *models.TranscriptionJob) string { return jobURL(job, "download-subtitle") },
+	},
+	{
+		kind:        "vtt",
+		ext:         ".vtt",
+		contentType: "text/vtt; charset=utf-8",
+		path:        func(job *models.TranscriptionJob) string { return job.VTTPath },
+		url:         func(job *models.TranscriptionJob) string { return jobURL(job, "subtitle.vtt") },
+	},
+	{
+		kind:        "bilingual_srt",
+		ext:         ".bilingual.srt",
+		contentType: "text/plain; charset=utf-8",
+		path:        func(job *models.TranscriptionJob) string { return job.BilingualSRTPath },
+		url:         func(job *models.TranscriptionJob) string { return jobURL(job, "download-bilingual-subtitle") },
+	},
+	{
+		kind:        "bilingual_vtt",
+		ext:         ".bilingual.vtt",
+		contentType: "text/vtt; charset=utf-8",
+		path:        func(job *models.TranscriptionJob) string { return job.BilingualVTTPath },
+		url:         func(job *models.TranscriptionJob) string { return jobURL(job, "bilingual.vtt") },
+	},
+}
+
+// List 列出任务当前可用的所有产物（不存在的产物会被忽略）
+func List(ctx context.Context, files filestore.FileStore, job *models.TranscriptionJob) []Artifact {
+	members := bundledMembers(ctx, newSource(files, job))
+	result := make([]Artifact, 0, len(members)+2)
+	for _, member := range members {
+		result = append(result, member.Artifact)
+	}
+
+	// 打包下载（包含以上所有产物）
+	if artifact, ok := zipArtifact(job, result); ok {
+		result = append(result, artifact)
+	}
+
+	// 原始媒体文件
+	if job.FilePath != "" {
+		if info, err := files.Stat(ctx, job.FilePath); err == nil {
+			contentType := mime.TypeByExtension(strings.ToLower(filepath.Ext(job.FilePath)))
+			if contentType == "" {
+				contentType = "application/octet-stream"
+			}
+			result = append(result, Artifact{
+				Kind:         "media",
+				Filename:     job.Filename,
+				URL:          MediaURL(job),
+				Size:         info.Size,
+				ContentType:  contentType,
+				LastModified: info.LastModified,
+			})
+		}
+	}
+
+	return result
+}
+
+// member 可打包的产物：data 为已经生成的内容；文件存储中的产物 data 为 nil，打包时再按 key 读取
+type member struct {
+	Artifact
+	data []byte
+	key  string
+}
+
+// bundledMembers 列出可打包的产物（原始媒体除外）：转录文本、字幕等文件和即时生成的产物
+// List 和 WriteZip 共用，即时生成的产物只生成一次
+func bundledMembers(ctx context.Context, src *source) []member {
+	job := src.job
+	result := make([]member, 0, len(fileArtifacts)+len(generatedArtifacts)+1)
+
+	// 1. 转录文本（保存在任务记录中，而非磁盘文件）
+	if job.Status == models.StatusCompleted && job.Result != "" {
+		result = append(result, member{Artifact: Artifact{
+			Kind:         "transcript_txt",
+			Filename:     BaseName(job) + ".txt",
+			URL:          jobURL(job, "download"),
+			Size:         int64(len(job.Result)),
+			ContentType:  "text/plain; charset=utf-8",
+			LastModified: job.CompletedAt,
+		}, data: []byte(job.Result)})
+	}
+
+	// 2. 文件存储中的字幕等文件
+	if job.Status == models.StatusCompleted {
+		for _, fa := range fileArtifacts {
+			key := fa.path(job)
+			if key == "" {
+				continue
+			}
+			info, err := src.files.Stat(ctx, key)
+			if err != nil {
+				continue
+			}
+			result = append(result, member{Artifact: Artifact{
+				Kind:         fa.kind,
+				Filename:     BaseName(job) + fa.ext,
+				URL:          fa.url(job),
+				Size:         info.Size,
+				ContentType:  fa.contentType,
+				LastModified: info.LastModified,
+			}, key: key})
+		}
+	}
+
+	// 3. 由任务数据即时生成的产物（JSON/Markdown 转录稿、带时间戳的文本、单词导出），大小为生成后的字节数
+	// 字幕只读取和解析一次（见 source）
+	for _, ga := range generatedArtifacts {
+		if artifact, data, err := ga.generate(ctx, src); err == nil {
+			result = append(result, member{Artifact: artifact, data: data})
+		}
+	}
+
+	return result
+}
+
+// BaseName 去掉扩展名和引号的原始文件名，用于生成下载文件名
+func BaseName(job *models.TranscriptionJob) string {
+	name := strings.TrimSuffix(job.Filename, filepath.Ext(job.Filename))
+	return strings.ReplaceAll(name, `"`, "")
+}
+
+// MediaURL 原始媒体地址（经过鉴权，本地存储由服务端直接返回文件，S3 跳转到预签名地址）
+func MediaURL(job *models.TranscriptionJob) string {
+	return jobURL(job, "media")
+}
+
+// jobURL 生成任务相关的接口地址
+func jobURL(job *models.TranscriptionJob, suffix string) string {
+	return fmt.Sprintf("/api/jobs/%s/%s", job.JobID, suffix)
+}
@@ -0,0 +1,175 @@
+package artifacts
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/z-wentao/voiceflow/pkg/filestore"
+	"github.com/z-wentao/voiceflow/pkg/models"
+)
+
+const testSRT = "1\n00:00:01,000 --> 00:00:03,000\nHello everyone.\n\n2\n00:01:23,000 --> 00:01:25,000\nWelcome to the show.\n"
+
+// newTestFiles 在临时目录中创建文件存储并写入字幕和媒体文件
+func newTestFiles(t *testing.T) filestore.FileStore {
+	t.Helper()
+	files := filestore.NewLocalStore(t.TempDir())
+	for key, content := range map[string]string{
+		"uploads/job-1.srt": testSRT,
+		"uploads/job-1.mp3": "ID3",
+	} {
+		if err := files.Put(context.Background(), key, strings.NewReader(content), int64(len(content)), ""); err != nil {
+			t.Fatalf("Put %s: %v", key, err)
+		}
+	}
+	return files
+}
+
+func TestListIncludesGeneratedArtifacts(t *testing.T) {
+	ctx := context.Background()
+	files := newTestFiles(t)
+	job := &models.TranscriptionJob{
+		JobID:        "job-1",
+		Filename:     "podcast.mp3",
+		FilePath:     "uploads/job-1.mp3",
+		SubtitlePath: "uploads/job-1.srt",
+		Status:       models.StatusCompleted,
+		Result:       "Hello everyone. Welcome to the show.",
+		CompletedAt:  time.Now(),
+		VocabDetail:  []models.WordDetail{{Word: "show", Definition: "节目", Example: "Welcome to the show."}},
+	}
+
+	byKind := make(map[string]Artifact)
+	for _, artifact := range List(ctx, files, job) {
+		byKind[artifact.Kind] = artifact
+	}
+
+	tests := []struct {
+		kind     string
+		filename string
+		url      string
+		render   func() ([]byte, error)
+	}{
+		{"transcript_json", "podcast.transcript.json", "/api/jobs/job-1/transcript.json", func() ([]byte, error) { return TranscriptJSON(ctx, files, job) }},
+		{"transcript_md", "podcast.transcript.md", "/api/jobs/job-1/transcript.md", func() ([]byte, error) { return TranscriptMarkdown(ctx, files, job) }},
+		{"transcript_timestamped", "podcast.timestamped.txt", "/api/jobs/job-1/transcript-timestamped.txt", func() ([]byte, error) { return TimestampedTranscript(ctx, files, job) }},
+		{"vocabulary_csv", "podcast.vocabulary.csv", "/api/jobs/job-1/vocabulary.csv", func() ([]byte, error) { return Vocabulary(job, ',') }},
+		{"vocabulary_tsv", "podcast.vocabulary.tsv", "/api/jobs/job-1/vocabulary.tsv", func() ([]byte, error) { return Vocabulary(job, '\t') }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.kind, func(t *testing.T) {
+			artifact, ok := byKind[tt.kind]
+			if !ok {
+				t.Fatalf("索引中缺少 %s", tt.kind)
+			}
+			data, err := tt.render()
+			if err != nil {
+				t.Fatalf("生成 %s: %v", tt.kind, err)
+			}
+			if artifact.Filename != tt.filename || artifact.URL != tt.url || artifact.Size != int64(len(data)) {
+				t.Fatalf("%s = %+v，期望文件名 %s、地址 %s、大小 %d", tt.kind, artifact, tt.filename, tt.url, len(data))
+			}
+		})
+	}
+
+	for _, kind := range []string{"transcript_txt", "srt", "media"} {
+		if _, ok := byKind[kind]; !ok {
+			t.Errorf("索引中缺少 %s", kind)
+		}
+	}
+}
+
+func TestListSkipsUnavailableGeneratedArtifacts(t *testing.T) {
+	// 只有纯文本结果、没有提取单词的任务：不生成带时间戳的文本和单词导出
+	job := &models.TranscriptionJob{JobID: "job-1", Filename: "podcast.mp3", Status: models.StatusCompleted, Result: "Hello."}
+	for _, artifact := range List(context.Background(), newTestFiles(t), job) {
+		switch artifact.Kind {
+		case "transcript_timestamped", "vocabulary_csv", "vocabulary_tsv":
+			t.Errorf("不应包含 %s", artifact.Kind)
+		}
+	}
+}
+
+func TestTimestampedTranscript(t *testing.T) {
+	job := &models.TranscriptionJob{JobID: "job-1", SubtitlePath: "uploads/job-1.srt", Status: models.StatusCompleted}
+	data, err := TimestampedTranscript(context.Background(), newTestFiles(t), job)
+	if err != nil {
+		t.Fatalf("TimestampedTranscript: %v", err)
+	}
+	if !strings.Contains(string(data), "[00:00:01] Hello everyone.") || !strings.Contains(string(data), "[00:01:23] Welcome to the show.") {
+		t.Fatalf("带时间戳的文本:\n%s", data)
+	}
+}
+
+func TestTranscriptJSON(t *testing.T) {
+	files := newTestFiles(t)
+	tests := []struct {
+		name     string
+		job      *models.TranscriptionJob
+		wantCues int
+		wantErr  error
+	}{
+		{"有字幕", &models.TranscriptionJob{JobID: "job-1", Filename: "podcast.mp3", SubtitlePath: "uploads/job-1.srt", Status: models.StatusCompleted, Result: "Hello everyone. Welcome to the show."}, 2, nil},
+		{"只有纯文本", &models.TranscriptionJob{JobID: "job-1", Filename: "podcast.mp3", Status: models.StatusCompleted, Result: "Hello."}, 0, nil},
+		{"未完成", &models.TranscriptionJob{JobID: "job-1", Status: models.StatusProcessing, Result: "Hello."}, 0, ErrUnavailable},
+		{"没有结果", &models.TranscriptionJob{JobID: "job-1", Status: models.StatusCompleted}, 0, ErrUnavailable},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := TranscriptJSON(context.Background(), files, tt.job)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v，期望 %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+			var transcript Transcript
+			if err := json.Unmarshal(data, &transcript); err != nil {
+				t.Fatalf("解析 JSON: %v", err)
+			}
+			if transcript.JobID != tt.job.JobID || transcript.Text != tt.job.Result || len(transcript.Cues) != tt.wantCues {
+				t.Fatalf("转录稿 = %+v", transcript)
+			}
+			if tt.wantCues > 0 && (transcript.Cues[1].Index != 2 || transcript.Cues[1].Start != 83 || transcript.Cues[1].Text != "Welcome to the show.") {
+				t.Fatalf("第二条字幕 = %+v", transcript.Cues[1])
+			}
+		})
+	}
+}
+
+func TestTranscriptMarkdown(t *testing.T) {
+	files := newTestFiles(t)
+	tests := []struct {
+		name string
+		job  *models.TranscriptionJob
+		want []string
+	}{
+		{
+			"有字幕",
+			&models.TranscriptionJob{JobID: "job-1", Filename: "my_podcast.mp3", SubtitlePath: "uploads/job-1.srt", Status: models.StatusCompleted, Result: "Hello everyone. Welcome to the show.", Language: "en", Duration: 3725},
+			[]string{"# my\\_podcast.mp3\n", "- 语言: en\n", "- 时长: 01:02:05\n", "**[00:00:01]** Hello everyone.\n", "**[00:01:23]** Welcome to the show.\n"},
+		},
+		{
+			"只有纯文本",
+			&models.TranscriptionJob{JobID: "job-1", Filename: "podcast.mp3", Status: models.StatusCompleted, Result: "Hello.\n"},
+			[]string{"# podcast.mp3\n\nHello.\n"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := TranscriptMarkdown(context.Background(), files, tt.job)
+			if err != nil {
+				t.Fatalf("TranscriptMarkdown: %v", err)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(string(data), want) {
+					t.Errorf("Markdown 中缺少 %q:\n%s", want, data)
+				}
+			}
+		})
+	}
+}
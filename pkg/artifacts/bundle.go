@@ -0,0 +1,74 @@
+package artifacts
+
+import (
+	"archive/zip"
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/z-wentao/voiceflow/pkg/filestore"
+	"github.com/z-wentao/voiceflow/pkg/models"
+)
+
+// WriteZip 把任务的所有产物（原始媒体除外）打包成 ZIP 写入 w
+// 打包内容与 List 一致，新增的产物类型会自动包含；没有可打包的产物时返回 ErrUnavailable
+func WriteZip(ctx context.Context, files filestore.FileStore, job *models.TranscriptionJob, w io.Writer) error {
+	members := bundledMembers(ctx, newSource(files, job))
+	if len(members) == 0 {
+		return ErrUnavailable
+	}
+	return writeZip(ctx, files, members, w)
+}
+
+// writeZip 按顺序把产物写入 ZIP（修改时间取产物的修改时间，相同内容生成的 ZIP 大小一致）
+// 即时生成的产物使用已经生成的内容，文件存储中的产物此时才读取
+func writeZip(ctx context.Context, files filestore.FileStore, members []member, w io.Writer) error {
+	zw := zip.NewWriter(w)
+	for _, m := range members {
+		data := m.data
+		if data == nil {
+			var err error
+			data, err = filestore.ReadAll(ctx, files, m.key)
+			if err != nil {
+				return fmt.Errorf("读取 %s 失败: %w", m.Kind, err)
+			}
+		}
+		fw, err := zw.CreateHeader(&zip.FileHeader{
+			Name:     m.Filename,
+			Method:   zip.Deflate,
+			Modified: m.LastModified,
+		})
+		if err != nil {
+			return fmt.Errorf("写入 ZIP 失败: %w", err)
+		}
+		if _, err := fw.Write(data); err != nil {
+			return fmt.Errorf("写入 ZIP 失败: %w", err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return fmt.Errorf("写入 ZIP 失败: %w", err)
+	}
+	return nil
+}
+
+// zipArtifact 打包下载的索引信息（list 中没有可打包的产物时返回 false）
+// 大小只有打包后才知道，列出产物时不生成 ZIP，Size 为 0
+func zipArtifact(job *models.TranscriptionJob, list []Artifact) (Artifact, bool) {
+	var lastModified time.Time
+	for _, artifact := range list {
+		if artifact.LastModified.After(lastModified) {
+			lastModified = artifact.LastModified
+		}
+	}
+	if len(list) == 0 {
+		return Artifact{}, false
+	}
+	return Artifact{
+		Kind:         "zip",
+		Filename:     BaseName(job) + ".zip",
+		URL:          jobURL(job, "artifacts.zip"),
+		ContentType:  "application/zip",
+		LastModified: lastModified,
+	}, true
+}
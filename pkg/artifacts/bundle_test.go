@@ -0,0 +1,179 @@
+package artifacts
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/z-wentao/voiceflow/pkg/filestore"
+	"github.com/z-wentao/voiceflow/pkg/models"
+)
+
+const testVTT = "WEBVTT\n\n00:00:01.000 --> 00:00:03.000\nHello everyone.\n"
+
+// newFullJob 所有产物都存在的任务
+func newFullJob(t *testing.T) (filestore.FileStore, *models.TranscriptionJob) {
+	t.Helper()
+	files := newTestFiles(t)
+	for key, content := range map[string]string{
+		"uploads/job-1.vtt":           testVTT,
+		"uploads/job-1.bilingual.srt": testSRT,
+		"uploads/job-1.bilingual.vtt": testVTT,
+	} {
+		if err := files.Put(context.Background(), key, strings.NewReader(content), int64(len(content)), ""); err != nil {
+			t.Fatalf("Put %s: %v", key, err)
+		}
+	}
+	return files, &models.TranscriptionJob{
+		JobID:            "job-1",
+		Filename:         "podcast.mp3",
+		FilePath:         "uploads/job-1.mp3",
+		SubtitlePath:     "uploads/job-1.srt",
+		VTTPath:          "uploads/job-1.vtt",
+		BilingualSRTPath: "uploads/job-1.bilingual.srt",
+		BilingualVTTPath: "uploads/job-1.bilingual.vtt",
+		Status:           models.StatusCompleted,
+		Result:           "Hello everyone. Welcome to the show.",
+		CompletedAt:      time.Now(),
+		VocabDetail:      []models.WordDetail{{Word: "show", Definition: "节目", Example: "Welcome to the show."}},
+	}
+}
+
+func TestListComplete(t *testing.T) {
+	files, job := newFullJob(t)
+
+	var kinds []string
+	for _, artifact := range List(context.Background(), files, job) {
+		kinds = append(kinds, artifact.Kind)
+	}
+	sort.Strings(kinds)
+
+	want := []string{"bilingual_srt", "bilingual_vtt", "media", "srt", "transcript_json", "transcript_md", "transcript_timestamped", "transcript_txt", "vocabulary_csv", "vocabulary_tsv", "vtt", "zip"}
+	if strings.Join(kinds, ",") != strings.Join(want, ",") {
+		t.Fatalf("产物 = %v，期望 %v", kinds, want)
+	}
+}
+
+func TestWriteZip(t *testing.T) {
+	ctx := context.Background()
+	files, job := newFullJob(t)
+	textOnly := &models.TranscriptionJob{JobID: "job-2", Filename: "note.m4a", Status: models.StatusCompleted, Result: "Hello."}
+
+	tests := []struct {
+		name    string
+		job     *models.TranscriptionJob
+		wantErr error
+	}{
+		{"所有产物", job, nil},
+		{"只有纯文本", textOnly, nil},
+		{"未完成", &models.TranscriptionJob{JobID: "job-3", FilePath: "uploads/job-1.mp3", Status: models.StatusProcessing}, ErrUnavailable},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			err := WriteZip(ctx, files, tt.job, &buf)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v，期望 %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+
+			zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+			if err != nil {
+				t.Fatalf("读取 ZIP: %v", err)
+			}
+			entries := make(map[string][]byte)
+			for _, f := range zr.File {
+				rc, err := f.Open()
+				if err != nil {
+					t.Fatalf("打开 %s: %v", f.Name, err)
+				}
+				entries[f.Name], _ = io.ReadAll(rc)
+				rc.Close()
+			}
+
+			// ZIP 的内容与产物列表一致（原始媒体和 ZIP 本身除外），列表中的大小为实际大小
+			for _, artifact := range List(ctx, files, tt.job) {
+				switch artifact.Kind {
+				case "media":
+					if _, ok := entries[artifact.Filename]; ok {
+						t.Errorf("ZIP 不应包含原始媒体 %s", artifact.Filename)
+					}
+				case "zip":
+					// 列出产物时不生成 ZIP，大小未知
+					if artifact.Size != 0 {
+						t.Errorf("列表中 ZIP 大小 = %d，期望 0", artifact.Size)
+					}
+				default:
+					data, ok := entries[artifact.Filename]
+					if !ok {
+						t.Errorf("ZIP 中缺少 %s", artifact.Filename)
+					} else if int64(len(data)) != artifact.Size {
+						t.Errorf("%s 大小 = %d，列表中为 %d", artifact.Filename, len(data), artifact.Size)
+					}
+					delete(entries, artifact.Filename)
+				}
+			}
+			for name := range entries {
+				t.Errorf("ZIP 中多出 %s", name)
+			}
+		})
+	}
+}
+
+// countingFiles 统计每个 key 的读取次数
+type countingFiles struct {
+	filestore.FileStore
+	gets map[string]int
+}
+
+func (f *countingFiles) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	f.gets[key]++
+	return f.FileStore.Get(ctx, key)
+}
+
+// TestArtifactsReadOnce 列出和打包产物时每个文件只读取一次：字幕解析一次供所有即时生成的产物使用，List 不生成 ZIP
+func TestArtifactsReadOnce(t *testing.T) {
+	tests := []struct {
+		name string
+		run  func(ctx context.Context, files filestore.FileStore, job *models.TranscriptionJob) error
+		want map[string]int
+	}{
+		{
+			name: "List",
+			run: func(ctx context.Context, files filestore.FileStore, job *models.TranscriptionJob) error {
+				List(ctx, files, job)
+				return nil
+			},
+			want: map[string]int{"uploads/job-1.vtt": 1},
+		},
+		{
+			name: "WriteZip",
+			run: func(ctx context.Context, files filestore.FileStore, job *models.TranscriptionJob) error {
+				return WriteZip(ctx, files, job, io.Discard)
+			},
+			// 字幕解析一次，打包时再读取一次各个文件的内容
+			want: map[string]int{"uploads/job-1.vtt": 2, "uploads/job-1.srt": 1, "uploads/job-1.bilingual.srt": 1, "uploads/job-1.bilingual.vtt": 1},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			base, job := newFullJob(t)
+			files := &countingFiles{FileStore: base, gets: make(map[string]int)}
+			if err := tt.run(context.Background(), files, job); err != nil {
+				t.Fatal(err)
+			}
+			if fmt.Sprint(files.gets) != fmt.Sprint(tt.want) {
+				t.Fatalf("读取次数 = %v，期望 %v", files.gets, tt.want)
+			}
+		})
+	}
+}
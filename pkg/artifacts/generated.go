@@ -0,0 +1,294 @@
+package artifacts
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/z-wentao/voiceflow/pkg/filestore"
+	"github.com/z-wentao/voiceflow/pkg/models"
+	"github.com/z-wentao/voiceflow/pkg/transcriber"
+)
+
+// ErrUnavailable 任务没有生成该产物所需的数据（如没有字幕、尚未提取单词）
+var ErrUnavailable = errors.New("产物不可用")
+
+// generatedArtifact 由任务数据即时生成的产物（不保存在文件存储中），索引中的大小为生成后的字节数
+// 新增产物类型只需在 generatedArtifacts 中追加一项，下载接口调用同一个 render 函数
+type generatedArtifact struct {
+	kind        string
+	ext         string
+	contentType string
+	url         func(job *models.TranscriptionJob) string
+	render      func(ctx context.Context, src *source) ([]byte, error)
+}
+
+// source 生成产物所需的任务数据：同一次 List / WriteZip 中字幕只读取和解析一次
+type source struct {
+	files filestore.FileStore
+	job   *models.TranscriptionJob
+
+	cuesLoaded bool
+	cues       []transcriber.Cue
+	cuesErr    error
+}
+
+func newSource(files filestore.FileStore, job *models.TranscriptionJob) *source {
+	return &source{files: files, job: job}
+}
+
+// jobCues 任务的字幕（第一次调用时读取和解析，之后返回相同的结果）
+func (s *source) jobCues(ctx context.Context) ([]transcriber.Cue, error) {
+	if !s.cuesLoaded {
+		s.cues, s.cuesErr = jobCues(ctx, s.files, s.job)
+		s.cuesLoaded = true
+	}
+	return s.cues, s.cuesErr
+}
+
+var generatedArtifacts = []generatedArtifact{
+	{
+		kind:        "transcript_json",
+		ext:         ".transcript.json",
+		contentType: "application/json; charset=utf-8",
+		url:         func(job *models.TranscriptionJob) string { return jobURL(job, "transcript.json") },
+		render:      transcriptJSON,
+	},
+	{
+		kind:        "transcript_md",
+		ext:         ".transcript.md",
+		contentType: "text/markdown; charset=utf-8",
+		url:         func(job *models.TranscriptionJob) string { return jobURL(job, "transcript.md") },
+		render:      transcriptMarkdown,
+	},
+	{
+		kind:        "transcript_timestamped",
+		ext:         ".timestamped.txt",
+		contentType: "text/plain; charset=utf-8",
+		url:         func(job *models.TranscriptionJob) string { return jobURL(job, "transcript-timestamped.txt") },
+		render:      timestampedTranscript,
+	},
+	{
+		kind:        "vocabulary_csv",
+		ext:         ".vocabulary.csv",
+		contentType: "text/csv; charset=utf-8",
+		url:         func(job *models.TranscriptionJob) string { return jobURL(job, "vocabulary.csv") },
+		render: func(ctx context.Context, src *source) ([]byte, error) {
+			return Vocabulary(src.job, ',')
+		},
+	},
+	{
+		kind:        "vocabulary_tsv",
+		ext:         ".vocabulary.tsv",
+		contentType: "text/tab-separated-values; charset=utf-8",
+		url:         func(job *models.TranscriptionJob) string { return jobURL(job, "vocabulary.tsv") },
+		render: func(ctx context.Context, src *source) ([]byte, error) {
+			return Vocabulary(src.job, '\t')
+		},
+	},
+}
+
+// Generate 生成指定类型的即时产物，返回产物信息（大小为生成后的字节数）和内容
+// 类型不存在或任务没有所需的数据时返回 ErrUnavailable
+func Generate(ctx context.Context, files filestore.FileStore, job *models.TranscriptionJob, kind string) (Artifact, []byte, error) {
+	for _, ga := range generatedArtifacts {
+		if ga.kind == kind {
+			return ga.generate(ctx, newSource(files, job))
+		}
+	}
+	return Artifact{}, nil, ErrUnavailable
+}
+
+// generate 生成产物内容和索引信息
+func (ga generatedArtifact) generate(ctx context.Context, src *source) (Artifact, []byte, error) {
+	data, err := ga.render(ctx, src)
+	if err != nil {
+		return Artifact{}, nil, err
+	}
+	job := src.job
+	lastModified := job.CompletedAt
+	if job.LastUpdated.After(lastModified) {
+		lastModified = job.LastUpdated
+	}
+	return Artifact{
+		Kind:         ga.kind,
+		Filename:     BaseName(job) + ga.ext,
+		URL:          ga.url(job),
+		Size:         int64(len(data)),
+		ContentType:  ga.contentType,
+		LastModified: lastModified,
+	}, data, nil
+}
+
+// jobCues 读取任务的字幕（优先解析 WebVTT，没有时解析 SRT）
+// 未完成或只保存了纯文本结果的任务没有时间信息，返回 ErrUnavailable
+func jobCues(ctx context.Context, files filestore.FileStore, job *models.TranscriptionJob) ([]transcriber.Cue, error) {
+	if job.Status != models.StatusCompleted || (job.VTTPath == "" && job.SubtitlePath == "") {
+		return nil, ErrUnavailable
+	}
+
+	key, parse := job.VTTPath, transcriber.ParseVTTContent
+	if key == "" {
+		key, parse = job.SubtitlePath, transcriber.ParseSRTContent
+	}
+	content, err := filestore.ReadAll(ctx, files, key)
+	if err != nil {
+		return nil, fmt.Errorf("读取字幕失败: %w", err)
+	}
+	cues, err := parse(content)
+	if err != nil {
+		return nil, fmt.Errorf("解析字幕失败: %w", err)
+	}
+	return cues, nil
+}
+
+// TimestampedTranscript 生成带时间戳的转录文本（每条字幕一行，[00:01:23] 文本）
+// 没有字幕的任务返回 ErrUnavailable
+func TimestampedTranscript(ctx context.Context, files filestore.FileStore, job *models.TranscriptionJob) ([]byte, error) {
+	return timestampedTranscript(ctx, newSource(files, job))
+}
+
+func timestampedTranscript(ctx context.Context, src *source) ([]byte, error) {
+	cues, err := src.jobCues(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return []byte(transcriber.FormatTimestampedTranscript(cues)), nil
+}
+
+// TranscriptCue JSON 转录稿中的一条字幕（时间为秒）
+type TranscriptCue struct {
+	Index int     `json:"index"`
+	Start float64 `json:"start"`
+	End   float64 `json:"end"`
+	Text  string  `json:"text"`
+}
+
+// Transcript JSON 转录稿
+type Transcript struct {
+	JobID    string          `json:"job_id"`
+	Filename string          `json:"filename"`
+	Language string          `json:"language,omitempty"`
+	Duration float64         `json:"duration,omitempty"` // 音频时长（秒）
+	Text     string          `json:"text"`
+	Cues     []TranscriptCue `json:"cues,omitempty"` // 字幕（只有纯文本结果的任务没有）
+}
+
+// transcriptOf 已完成任务的转录稿（有字幕时包含每条字幕），没有转录文本时返回 ErrUnavailable
+func transcriptOf(ctx context.Context, src *source) (*Transcript, error) {
+	job := src.job
+	if job.Status != models.StatusCompleted || job.Result == "" {
+		return nil, ErrUnavailable
+	}
+
+	transcript := &Transcript{
+		JobID:    job.JobID,
+		Filename: job.Filename,
+		Language: job.Language,
+		Duration: job.Duration,
+		Text:     job.Result,
+	}
+	cues, err := src.jobCues(ctx)
+	if err != nil && !errors.Is(err, ErrUnavailable) {
+		return nil, err
+	}
+	for i, cue := range cues {
+		transcript.Cues = append(transcript.Cues, TranscriptCue{Index: i + 1, Start: cue.Start, End: cue.End, Text: cue.Text})
+	}
+	return transcript, nil
+}
+
+// TranscriptJSON 生成 JSON 转录稿（全文和每条字幕的时间），没有转录文本时返回 ErrUnavailable
+func TranscriptJSON(ctx context.Context, files filestore.FileStore, job *models.TranscriptionJob) ([]byte, error) {
+	return transcriptJSON(ctx, newSource(files, job))
+}
+
+func transcriptJSON(ctx context.Context, src *source) ([]byte, error) {
+	transcript, err := transcriptOf(ctx, src)
+	if err != nil {
+		return nil, err
+	}
+	data, err := json.MarshalIndent(transcript, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("序列化转录稿失败: %w", err)
+	}
+	return data, nil
+}
+
+// TranscriptMarkdown 生成 Markdown 转录稿：文件名作为标题，有字幕时每条字幕一段并以时间戳开头，否则为全文
+// 没有转录文本时返回 ErrUnavailable
+func TranscriptMarkdown(ctx context.Context, files filestore.FileStore, job *models.TranscriptionJob) ([]byte, error) {
+	return transcriptMarkdown(ctx, newSource(files, job))
+}
+
+func transcriptMarkdown(ctx context.Context, src *source) ([]byte, error) {
+	transcript, err := transcriptOf(ctx, src)
+	if err != nil {
+		return nil, err
+	}
+	job := src.job
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s\n\n", markdownEscaper.Replace(job.Filename))
+	if job.Language != "" {
+		fmt.Fprintf(&b, "- 语言: %s\n", job.Language)
+	}
+	if job.Duration > 0 {
+		seconds := int(job.Duration)
+		fmt.Fprintf(&b, "- 时长: %02d:%02d:%02d\n", seconds/3600, seconds%3600/60, seconds%60)
+	}
+	if job.Language != "" || job.Duration > 0 {
+		b.WriteString("\n")
+	}
+
+	if len(transcript.Cues) == 0 {
+		b.WriteString(strings.TrimSpace(job.Result))
+		b.WriteString("\n")
+		return []byte(b.String()), nil
+	}
+	for _, cue := range transcript.Cues {
+		text := strings.Join(strings.Fields(cue.Text), " ")
+		if text == "" {
+			continue
+		}
+		seconds := int(cue.Start)
+		fmt.Fprintf(&b, "**[%02d:%02d:%02d]** %s\n\n", seconds/3600, seconds%3600/60, seconds%60, text)
+	}
+	return []byte(b.String()), nil
+}
+
+// markdownEscaper 转义标题中的 Markdown 特殊字符
+var markdownEscaper = strings.NewReplacer(`\`, `\\`, "*", `\*`, "_", `\_`, "#", `\#`, "[", `\[`, "]", `\]`, "`", "\\`")
+
+// Vocabulary 导出单词列表（word, definition, example），comma 为 ',' 时生成 CSV，为 '\t' 时生成 Anki TSV
+// CSV 带表头，方便 Excel 打开；TSV 使用 Anki 的文件头指令，可直接导入 Anki。
+// 两种格式都以 UTF-8 BOM 开头，避免中文释义乱码；尚未提取单词时返回 ErrUnavailable
+func Vocabulary(job *models.TranscriptionJob, comma rune) ([]byte, error) {
+	if len(job.VocabDetail) == 0 {
+		return nil, ErrUnavailable
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("\ufeff")
+	if comma == '\t' {
+		buf.WriteString("#separator:tab\n#html:false\n#columns:word\tdefinition\texample\n")
+	}
+
+	w := csv.NewWriter(&buf)
+	w.Comma = comma
+	if comma == ',' {
+		w.Write([]string{"word", "definition", "example"})
+	}
+	for _, word := range job.VocabDetail {
+		w.Write([]string{word.Word, word.Definition, word.Example})
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return nil, fmt.Errorf("生成单词列表失败: %w", err)
+	}
+	return buf.Bytes(), nil
+}
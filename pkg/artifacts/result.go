@@ -0,0 +1,87 @@
+package artifacts
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/z-wentao/voiceflow/pkg/filestore"
+	"github.com/z-wentao/voiceflow/pkg/models"
+)
+
+// Links 任务常用资源的下载地址
+type Links struct {
+	Result string `json:"result"`          // 本结果（result.json）
+	Text   string `json:"text,omitempty"`  // 转录文本
+	SRT    string `json:"srt,omitempty"`   // SRT 字幕
+	VTT    string `json:"vtt,omitempty"`   // WebVTT 字幕
+	Media  string `json:"media,omitempty"` // 原始媒体
+}
+
+// Result 任务结果描述（result.json 和 webhook 共用）
+// 包含所有资源的下载地址，调用方无需了解路由规则
+type Result struct {
+	JobID       string     `json:"job_id"`
+	Filename    string     `json:"filename"`
+	Status      string     `json:"status"`
+	Error       string     `json:"error,omitempty"`
+	Language    string     `json:"language,omitempty"`
+	Duration    float64    `json:"duration,omitempty"` // 音频时长（秒）
+	CreatedAt   time.Time  `json:"created_at"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+	Links       Links      `json:"links"`
+	Artifacts   []Artifact `json:"artifacts"`
+}
+
+// BuildResult 生成任务结果描述
+// baseURL 为对外访问地址（如 https://voiceflow.example.com），为空时使用相对路径
+func BuildResult(ctx context.Context, files filestore.FileStore, job *models.TranscriptionJob, baseURL string) Result {
+	result := Result{
+		JobID:     job.JobID,
+		Filename:  job.Filename,
+		Status:    string(job.Status),
+		Error:     job.Error,
+		Language:  job.Language,
+		Duration:  job.Duration,
+		CreatedAt: job.CreatedAt,
+		Links: Links{
+			Result: AbsoluteURL(baseURL, ResultURL(job)),
+		},
+		Artifacts: List(ctx, files, job),
+	}
+	if !job.CompletedAt.IsZero() {
+		completedAt := job.CompletedAt
+		result.CompletedAt = &completedAt
+	}
+
+	for i := range result.Artifacts {
+		artifact := &result.Artifacts[i]
+		artifact.URL = AbsoluteURL(baseURL, artifact.URL)
+
+		switch artifact.Kind {
+		case "transcript_txt":
+			result.Links.Text = artifact.URL
+		case "srt":
+			result.Links.SRT = artifact.URL
+		case "vtt":
+			result.Links.VTT = artifact.URL
+		case "media":
+			result.Links.Media = artifact.URL
+		}
+	}
+
+	return result
+}
+
+// ResultURL result.json 的相对地址
+func ResultURL(job *models.TranscriptionJob) string {
+	return "/api/v1/jobs/" + job.JobID + "/result.json"
+}
+
+// AbsoluteURL 拼接对外访问地址和相对路径；baseURL 为空时原样返回相对路径
+func AbsoluteURL(baseURL, path string) string {
+	if baseURL == "" {
+		return path
+	}
+	return strings.TrimRight(baseURL, "/") + path
+}
@@ -0,0 +1,94 @@
+package artifacts
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/z-wentao/voiceflow/pkg/models"
+)
+
+func TestBuildResult(t *testing.T) {
+	files := newTestFiles(t)
+	completedAt := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name    string
+		baseURL string
+		job     *models.TranscriptionJob
+		prefix  string // 期望的地址前缀
+		want    Links
+	}{
+		{
+			name:    "相对路径",
+			baseURL: "",
+			job:     &models.TranscriptionJob{JobID: "job-1", Filename: "podcast.mp3", FilePath: "uploads/job-1.mp3", SubtitlePath: "uploads/job-1.srt", Status: models.StatusCompleted, Result: "Hello everyone.", CompletedAt: completedAt},
+			prefix:  "",
+			want: Links{
+				Result: "/api/v1/jobs/job-1/result.json",
+				Text:   "/api/jobs/job-1/download",
+				SRT:    "/api/jobs/job-1/download-subtitle",
+				Media:  "/api/jobs/job-1/media",
+			},
+		},
+		{
+			name:    "对外访问地址",
+			baseURL: "https://vf.example.com",
+			job:     &models.TranscriptionJob{JobID: "job-1", Filename: "podcast.mp3", FilePath: "uploads/job-1.mp3", SubtitlePath: "uploads/job-1.srt", Status: models.StatusCompleted, Result: "Hello everyone.", CompletedAt: completedAt},
+			prefix:  "https://vf.example.com",
+			want: Links{
+				Result: "https://vf.example.com/api/v1/jobs/job-1/result.json",
+				Text:   "https://vf.example.com/api/jobs/job-1/download",
+				SRT:    "https://vf.example.com/api/jobs/job-1/download-subtitle",
+				Media:  "https://vf.example.com/api/jobs/job-1/media",
+			},
+		},
+		{
+			name:    "地址末尾带斜杠",
+			baseURL: "https://vf.example.com/",
+			job:     &models.TranscriptionJob{JobID: "job-1", Filename: "podcast.mp3", FilePath: "uploads/job-1.mp3", SubtitlePath: "uploads/job-1.srt", Status: models.StatusCompleted, Result: "Hello everyone.", CompletedAt: completedAt},
+			prefix:  "https://vf.example.com",
+			want: Links{
+				Result: "https://vf.example.com/api/v1/jobs/job-1/result.json",
+				Text:   "https://vf.example.com/api/jobs/job-1/download",
+				SRT:    "https://vf.example.com/api/jobs/job-1/download-subtitle",
+				Media:  "https://vf.example.com/api/jobs/job-1/media",
+			},
+		},
+		{
+			name:    "失败的任务",
+			baseURL: "https://vf.example.com/",
+			job:     &models.TranscriptionJob{JobID: "job-1", Filename: "podcast.mp3", FilePath: "uploads/job-1.mp3", Status: models.StatusFailed, Error: "转录失败"},
+			prefix:  "https://vf.example.com",
+			want: Links{
+				Result: "https://vf.example.com/api/v1/jobs/job-1/result.json",
+				Media:  "https://vf.example.com/api/jobs/job-1/media",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := BuildResult(context.Background(), files, tt.job, tt.baseURL)
+
+			if result.JobID != tt.job.JobID || result.Status != string(tt.job.Status) || result.Error != tt.job.Error {
+				t.Fatalf("BuildResult() = {%s %s %q}，期望 {%s %s %q}", result.JobID, result.Status, result.Error, tt.job.JobID, tt.job.Status, tt.job.Error)
+			}
+			if tt.job.CompletedAt.IsZero() != (result.CompletedAt == nil) {
+				t.Fatalf("CompletedAt = %v，任务的完成时间 %v", result.CompletedAt, tt.job.CompletedAt)
+			}
+
+			if result.Links != tt.want {
+				t.Fatalf("Links = %+v，期望 %+v", result.Links, tt.want)
+			}
+
+			if len(result.Artifacts) == 0 {
+				t.Fatal("没有列出任何产物")
+			}
+			for _, artifact := range result.Artifacts {
+				if !strings.HasPrefix(artifact.URL, tt.prefix+"/api/") || strings.Contains(artifact.URL, "//api/") {
+					t.Errorf("产物 %s 的地址 = %q，期望以 %q 开头", artifact.Kind, artifact.URL, tt.prefix+"/api/")
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,190 @@
+package budget
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/z-wentao/voiceflow/pkg/storage"
+)
+
+// OpenAI 官方定价（美元），用于估算费用（未配置 openai.pricing 时使用）
+const (
+	WhisperCostPerMinute   = 0.006          // Whisper: $0.006 / 分钟
+	ChatCostPerInputToken  = 0.15 / 1000000 // GPT-4o-mini 输入: $0.15 / 1M tokens
+	ChatCostPerOutputToken = 0.60 / 1000000 // GPT-4o-mini 输出: $0.60 / 1M tokens
+)
+
+// Rates 估算费用使用的单价（美元）
+type Rates struct {
+	WhisperPerMinute   float64 // 每分钟音频
+	ChatPerInputToken  float64 // 每个输入 token
+	ChatPerOutputToken float64 // 每个输出 token
+}
+
+// DefaultRates OpenAI 官方定价
+var DefaultRates = Rates{
+	WhisperPerMinute:   WhisperCostPerMinute,
+	ChatPerInputToken:  ChatCostPerInputToken,
+	ChatPerOutputToken: ChatCostPerOutputToken,
+}
+
+// Whisper 估算 Whisper 转录费用
+func (r Rates) Whisper(durationSeconds float64) float64 {
+	return durationSeconds / 60 * r.WhisperPerMinute
+}
+
+// Chat 估算 Chat 调用费用
+func (r Rates) Chat(promptTokens, completionTokens int) float64 {
+	return float64(promptTokens)*r.ChatPerInputToken + float64(completionTokens)*r.ChatPerOutputToken
+}
+
+// ErrBudgetExhausted 本月预算已用尽
+var ErrBudgetExhausted = errors.New("本月预算已用尽")
+
+// ErrBudgetUnavailable 读取本月费用失败，无法确认预算是否还有剩余
+var ErrBudgetUnavailable = errors.New("暂时无法确认本月预算")
+
+// Tracker OpenAI 月度预算跟踪器
+// 费用计数器持久化在 Store 中（按月份分 key），月份切换时自然归零
+type Tracker struct {
+	store    storage.Store
+	limitUSD float64 // 月度预算，<= 0 表示不限制
+	rates    Rates
+	failOpen bool // 读取费用失败时是否放行（默认拒绝）
+}
+
+// NewTracker 创建预算跟踪器
+func NewTracker(store storage.Store, monthlyBudgetUSD float64) *Tracker {
+	return &Tracker{
+		store:    store,
+		limitUSD: monthlyBudgetUSD,
+		rates:    DefaultRates,
+	}
+}
+
+// SetRates 设置估算费用使用的单价（启动时调用）
+func (t *Tracker) SetRates(rates Rates) {
+	t.rates = rates
+}
+
+// SetFailOpen 设置读取本月费用失败时是否放行新任务（默认拒绝，启动时调用）
+// 放行可以避免存储抖动导致无法上传，但存储持续不可用期间预算不再生效
+func (t *Tracker) SetFailOpen(failOpen bool) {
+	t.failOpen = failOpen
+}
+
+// Rates 估算费用使用的单价（未创建跟踪器时为官方定价）
+func (t *Tracker) Rates() Rates {
+	if t == nil {
+		return DefaultRates
+	}
+	return t.rates
+}
+
+// currentMonth 当前月份 key，例如 2025-01
+func currentMonth() string {
+	return time.Now().Format("2006-01")
+}
+
+// Enabled 是否启用了预算限制
+func (t *Tracker) Enabled() bool {
+	return t != nil && t.limitUSD > 0
+}
+
+// Check 检查本月预算是否还有剩余（新任务入队前调用）
+// 读取本月费用失败时返回 ErrBudgetUnavailable（SetFailOpen(true) 时记录日志后放行）
+func (t *Tracker) Check(ctx context.Context) error {
+	if !t.Enabled() {
+		return nil
+	}
+
+	spent, err := t.store.GetUsage(ctx, currentMonth())
+	if err != nil {
+		if t.failOpen {
+			log.Printf("⚠️ 读取本月费用失败，按配置放行新任务: %v", err)
+			return nil
+		}
+		log.Printf("⚠️ 读取本月费用失败，拒绝新任务: %v", err)
+		return ErrBudgetUnavailable
+	}
+
+	if spent >= t.limitUSD {
+		return ErrBudgetExhausted
+	}
+	return nil
+}
+
+// Record 记录一笔费用（进行中的任务完成后照常记录，不做拦截）
+func (t *Tracker) Record(ctx context.Context, costUSD float64) {
+	if t == nil || costUSD <= 0 {
+		return
+	}
+
+	total, err := t.store.IncrUsage(ctx, currentMonth(), costUSD)
+	if err != nil {
+		log.Printf("⚠️ 记录费用失败: %v", err)
+		return
+	}
+
+	if t.Enabled() && total >= t.limitUSD {
+		log.Printf("⚠️ 本月 OpenAI 费用 $%.4f 已达到预算 $%.2f，新任务将被拒绝", total, t.limitUSD)
+	}
+}
+
+// RecordWhisper 按音频时长记录 Whisper 费用，返回记录的金额（同时累加到任务的 EstimatedCost）
+func (t *Tracker) RecordWhisper(ctx context.Context, durationSeconds float64) float64 {
+	cost := t.Rates().Whisper(durationSeconds)
+	t.Record(ctx, cost)
+	return cost
+}
+
+// RecordChat 按 token 用量记录 Chat 费用，返回记录的金额
+func (t *Tracker) RecordChat(ctx context.Context, promptTokens, completionTokens int) float64 {
+	cost := t.Rates().Chat(promptTokens, completionTokens)
+	t.Record(ctx, cost)
+	return cost
+}
+
+// Usage 本月预算使用情况
+type Usage struct {
+	Month        string   `json:"month"`
+	SpentUSD     float64  `json:"spent_usd"`
+	BudgetUSD    float64  `json:"budget_usd"`              // 0 表示不限制
+	RemainingUSD *float64 `json:"remaining_usd,omitempty"` // 未启用预算时不返回
+}
+
+// CurrentUsage 获取本月预算使用情况
+func (t *Tracker) CurrentUsage(ctx context.Context) (*Usage, error) {
+	month := currentMonth()
+	spent, err := t.store.GetUsage(ctx, month)
+	if err != nil {
+		return nil, fmt.Errorf("获取本月费用失败: %w", err)
+	}
+
+	usage := &Usage{
+		Month:     month,
+		SpentUSD:  spent,
+		BudgetUSD: t.limitUSD,
+	}
+	if t.Enabled() {
+		remaining := t.limitUSD - spent
+		if remaining < 0 {
+			remaining = 0
+		}
+		usage.RemainingUSD = &remaining
+	}
+	return usage, nil
+}
+
+// WhisperCost 按官方定价估算 Whisper 转录费用
+func WhisperCost(durationSeconds float64) float64 {
+	return DefaultRates.Whisper(durationSeconds)
+}
+
+// ChatCost 按官方定价估算 Chat 调用费用
+func ChatCost(promptTokens, completionTokens int) float64 {
+	return DefaultRates.Chat(promptTokens, completionTokens)
+}
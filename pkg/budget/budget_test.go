@@ -0,0 +1,72 @@
+package budget
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/z-wentao/voiceflow/pkg/storage"
+)
+
+// failingUsageStore 读取费用总是失败的存储
+type failingUsageStore struct {
+	*storage.JobStore
+}
+
+func (s failingUsageStore) GetUsage(ctx context.Context, month string) (float64, error) {
+	return 0, errors.New("connection refused")
+}
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		name     string
+		limit    float64
+		spent    float64
+		failing  bool
+		failOpen bool
+		want     error
+	}{
+		{"未启用预算", 0, 100, false, false, nil},
+		{"预算有剩余", 10, 9.99, false, false, nil},
+		{"刚好用完", 10, 10, false, false, ErrBudgetExhausted},
+		{"已超出", 10, 12.5, false, false, ErrBudgetExhausted},
+		{"读取失败默认拒绝", 10, 0, true, false, ErrBudgetUnavailable},
+		{"读取失败按配置放行", 10, 0, true, true, nil},
+		{"未启用预算时不读取", 0, 0, true, false, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			var store storage.Store = storage.NewJobStore(10)
+			if tt.failing {
+				store = failingUsageStore{storage.NewJobStore(10)}
+			}
+			tracker := NewTracker(store, tt.limit)
+			tracker.SetFailOpen(tt.failOpen)
+			if tt.spent > 0 {
+				store.IncrUsage(ctx, currentMonth(), tt.spent)
+			}
+
+			if err := tracker.Check(ctx); !errors.Is(err, tt.want) {
+				t.Fatalf("Check = %v，期望 %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestRecordBlocksAfterBudget(t *testing.T) {
+	ctx := context.Background()
+	tracker := NewTracker(storage.NewJobStore(10), 0.05)
+
+	// 5 分钟音频 $0.03，预算还有剩余
+	tracker.RecordWhisper(ctx, 300)
+	if err := tracker.Check(ctx); err != nil {
+		t.Fatalf("Check = %v，期望放行", err)
+	}
+
+	// 再转录 5 分钟累计 $0.06，超出预算后拒绝新任务
+	tracker.RecordWhisper(ctx, 300)
+	if err := tracker.Check(ctx); !errors.Is(err, ErrBudgetExhausted) {
+		t.Fatalf("Check = %v，期望 ErrBudgetExhausted", err)
+	}
+}
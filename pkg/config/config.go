@@ -3,51 +3,141 @@ package config
 import (
     "fmt"
     "os"
+    "strconv"
+    "strings"
 
     "github.com/goccy/go-yaml"
 )
 
 // Config 应用配置
 type Config struct {
-    OpenAI         OpenAIConfig         `yaml:"openai"`
-    Transcriber    TranscriberConfig    `yaml:"transcriber"`
-    Queue          QueueConfig          `yaml:"queue"`
-    Storage        StorageConfig        `yaml:"storage"`
-    Server         ServerConfig         `yaml:"server"`
-    MaimemoService MaimemoServiceConfig `yaml:"maimemo_service"` // Maimemo 微服务配置
+    OpenAI          OpenAIConfig           `yaml:"openai"`
+    Transcriber     TranscriberConfig      `yaml:"transcriber"`
+    Queue           QueueConfig            `yaml:"queue"`
+    Storage         StorageConfig          `yaml:"storage"`
+    FileStore       FileStoreConfig        `yaml:"file_store"`       // 文件存储（上传的媒体和生成的字幕）
+    Server          ServerConfig           `yaml:"server"`
+    MaimemoService  MaimemoServiceConfig   `yaml:"maimemo_service"`  // Maimemo 微服务配置
+    Retention       RetentionConfig        `yaml:"retention"`        // 文件保留策略
+    Webhook         WebhookConfig          `yaml:"webhook"`          // 任务结束通知
+    Sources         []SourceConfig         `yaml:"sources"`          // 播客订阅源（自动转录新节目）
+    VocabularySinks []VocabularySinkConfig `yaml:"vocabulary_sinks"` // 单词同步目标（未配置时只有墨墨）
+    Users           UsersConfig            `yaml:"users"`            // 多用户（未配置时所有人共享任务）
+    Auth            AuthConfig             `yaml:"auth"`             // API 鉴权（默认关闭）
+    RateLimit       RateLimitConfig        `yaml:"rate_limit"`       // 上传和单词提取接口按客户端 IP 限流（默认关闭）
+    Log             LogConfig              `yaml:"log"`              // 日志级别和输出格式
+}
+
+// LogConfig 日志配置
+type LogConfig struct {
+    Level  string `yaml:"level"`  // 日志级别: debug/info/warn/error，默认 info
+    Format string `yaml:"format"` // 输出格式: console（本地开发，可读的单行文本）/ json（生产环境，便于日志系统解析），默认 console
 }
 
 // OpenAIConfig OpenAI 配置
 type OpenAIConfig struct {
-    APIKey string `yaml:"api_key"`
+    APIKey           string  `yaml:"api_key"`
+    BaseURL          string  `yaml:"base_url"`           // OpenAI 兼容的 API 地址（如自建 whisper.cpp 服务），为空时使用 OpenAI 官方地址
+    MonthlyBudgetUSD float64       `yaml:"monthly_budget_usd"` // 月度预算（美元），0 表示不限制
+    Pricing          PricingConfig `yaml:"pricing"`            // 估算费用使用的单价（月度预算和每个任务的费用）
+    BudgetFailOpen   bool          `yaml:"budget_fail_open"`   // 读取本月费用失败时放行新任务（默认拒绝，返回 503）
+}
+
+// PricingConfig OpenAI 单价（美元），未设置（0）时使用官方定价
+type PricingConfig struct {
+    WhisperPerMinute     float64 `yaml:"whisper_per_minute"`      // Whisper 每分钟音频，官方定价 0.006
+    ChatInputPerMillion  float64 `yaml:"chat_input_per_million"`  // 单词提取、字幕翻译每百万输入 token，官方定价 0.15（gpt-4o-mini）
+    ChatOutputPerMillion float64 `yaml:"chat_output_per_million"` // 每百万输出 token，官方定价 0.60（gpt-4o-mini）
 }
 
 // TranscriberConfig 转换器配置
 type TranscriberConfig struct {
-    WorkerPoolSize     int `yaml:"worker_pool_size"`     // Worker 实例数量（同时处理多少个音频文件）
-    SegmentConcurrency int `yaml:"segment_concurrency"`  // 每个音频文件的分片并发处理数
-    SegmentDuration    int `yaml:"segment_duration"`
-    MaxRetries         int `yaml:"max_retries"`
+    WorkerPoolSize     int     `yaml:"worker_pool_size"`    // Worker 实例数量（同时处理多少个音频文件）
+    SegmentConcurrency int     `yaml:"segment_concurrency"` // 每个音频文件的分片并发处理数
+    SegmentDuration    int     `yaml:"segment_duration"`
+    SegmentOverlap     int     `yaml:"segment_overlap"`     // 相邻分片的重叠时长（秒），避免切点处的单词丢失或重复（默认 0，不重叠）
+    SegmentBitrate     string  `yaml:"segment_bitrate"`     // 分片的 MP3 比特率，如 "64k"，默认 128k
+    SegmentSampleRate  int     `yaml:"segment_sample_rate"` // 分片的采样率（Hz），如 16000，默认保持源文件
+    SegmentChannels    int     `yaml:"segment_channels"`    // 分片的声道数，1 为单声道，默认保持源文件
+    MaxRetries         int     `yaml:"max_retries"`
+    WordTimestamps     bool    `yaml:"word_timestamps"`     // 请求单词级时间戳，VTT 字幕逐词高亮（默认关闭）
+    MaxCues            int     `yaml:"max_cues"`            // 字幕条数上限，超过时合并相邻字幕（0 表示不限制）
+    MaxLineChars       int     `yaml:"max_line_chars"`      // 字幕每行最大宽度（中日韩文字计 2），默认 42，负数表示不折行
+    TargetLanguage     string  `yaml:"target_language"`     // 双语字幕的翻译目标语言，默认 "简体中文"
+    Bilingual          bool    `yaml:"bilingual"`           // 转录完成后自动生成双语字幕（默认关闭，也可在任务完成后手动生成）
+    DrainTimeout       int     `yaml:"drain_timeout"`       // 关闭服务时等待进行中任务完成的最长时间（秒），超时后取消剩余任务并重新入队，默认 300
+    Prompt             string  `yaml:"prompt"`              // 默认的 Whisper 提示词（专有名词、术语），上传时可按任务指定
+    Temperature        float64 `yaml:"temperature"`         // 默认的 Whisper 采样温度（0 到 1），0 表示使用 API 默认值
+
+    WhisperTimeoutSeconds   int `yaml:"whisper_timeout_seconds"`    // Whisper HTTP 请求的超时上限（秒），默认 300
+    WhisperSecondsPerMinute int `yaml:"whisper_seconds_per_minute"` // 每分钟音频的请求时间预算（秒），片段请求的截止时间按时长等比例计算，默认 30，负数表示不按时长设置
 }
 
 // QueueConfig 队列配置
 type QueueConfig struct {
-    Type       string          `yaml:"type"`
-    BufferSize int             `yaml:"buffer_size"`
-    RabbitMQ   RabbitMQConfig  `yaml:"rabbitmq"`
+    Type                string         `yaml:"type"`
+    BufferSize          int            `yaml:"buffer_size"`
+    RabbitMQ            RabbitMQConfig `yaml:"rabbitmq"`
+    NATS                NATSConfig     `yaml:"nats"`
+    Kafka               KafkaConfig    `yaml:"kafka"`
+    RecoverOnStartup    bool           `yaml:"recover_on_startup"`    // 启动时重新入队 pending / processing 任务（内存队列重启后消息丢失时开启，RabbitMQ 自带持久化无需开启）
+    MaxRecoveryAttempts int            `yaml:"max_recovery_attempts"` // 任务处理中被中断的次数上限，达到后标记为失败，默认 3
+    MaxRetries          int            `yaml:"max_retries"`           // 临时错误（Whisper 429/5xx、网络超时）自动重试的次数，默认 3，负数表示不重试
+    RetryDelaySeconds   int            `yaml:"retry_delay_seconds"`   // 第一次重试前的等待时间（秒），之后每次翻倍（最长 10 分钟），默认 30
+    MaxRedeliveries     int            `yaml:"max_redeliveries"`      // 内存队列中同一任务 Nack 重新入队的次数上限，达到后标记为失败，默认 5
+    EnqueuePolicy       string         `yaml:"enqueue_policy"`        // 内存队列已满时的处理方式：reject（立即失败，默认）/ block（等待空位）/ grow（不限长度）
+    EnqueueTimeout      int            `yaml:"enqueue_timeout"`       // block 策略等待空位的最长时间（秒），默认 10
 }
 
 // RabbitMQConfig RabbitMQ 配置
 type RabbitMQConfig struct {
     URL       string `yaml:"url"`
     QueueName string `yaml:"queue_name"`
+    Prefetch  int    `yaml:"prefetch"` // 消费者预取数量（QoS prefetch count），应等于或略大于 Worker 数量，默认等于 transcriber.worker_pool_size
+
+    PublisherConfirms bool `yaml:"publisher_confirms"` // 发布确认：入队时等待 Broker 确认，未确认时上传返回错误（每次入队增加一次往返，默认关闭）
+}
+
+// NATSConfig NATS JetStream 配置
+type NATSConfig struct {
+    URL      string `yaml:"url"`      // 服务地址，默认 "nats://localhost:4222"
+    Stream   string `yaml:"stream"`   // Stream 名称（不存在时自动创建），默认 "VOICEFLOW"
+    Subject  string `yaml:"subject"`  // 任务消息的 subject，默认 "voiceflow.jobs"
+    Consumer string `yaml:"consumer"` // durable pull consumer 名称，所有实例共享，默认 "voiceflow-workers"
+    AckWait  int    `yaml:"ack_wait"` // 任务未确认的超时时间（秒），超时后重新投递，默认 3600（应大于单个任务的最长处理时间）
+}
+
+// KafkaConfig Kafka 配置（需使用 go build -tags kafka 编译）
+type KafkaConfig struct {
+    Brokers      []string `yaml:"brokers"`       // broker 地址列表，默认 ["localhost:9092"]
+    Topic        string   `yaml:"topic"`         // 任务消息的 topic（需预先创建），默认 "voiceflow.jobs"
+    GroupID      string   `yaml:"group_id"`      // 消费者组，所有实例共享消费进度，默认 "voiceflow-workers"
+    WriteTimeout int      `yaml:"write_timeout"` // 发布消息的超时时间（秒），默认 10
 }
 
 // StorageConfig 存储配置
 type StorageConfig struct {
-    Type     string         `yaml:"type"`     // 存储类型: memory/redis/postgres/hybrid
+    Type     string         `yaml:"type"`     // 存储类型: memory/redis/postgres/hybrid/sqlite/mongo
     Redis    RedisConfig    `yaml:"redis"`    // Redis 配置
     Postgres PostgresConfig `yaml:"postgres"` // PostgreSQL 配置
+    SQLite   SQLiteConfig   `yaml:"sqlite"`   // SQLite 配置
+    Mongo    MongoConfig    `yaml:"mongo"`    // MongoDB 配置
+    Hybrid   HybridConfig   `yaml:"hybrid"`   // 混合存储配置
+    Memory   MemoryConfig   `yaml:"memory"`   // 内存存储配置
+
+    EncryptionKey     string   `yaml:"encryption_key"`      // 转录文本、单词和片段结果的加密密钥（AES-256-GCM），为空表示不加密
+    OldEncryptionKeys []string `yaml:"old_encryption_keys"` // 轮换前使用的密钥，只用于解密之前写入的数据
+}
+
+// MemoryConfig 内存存储配置
+type MemoryConfig struct {
+    MaxJobs int `yaml:"max_jobs"` // 最多保留的任务数，超过时淘汰最久未访问的已完成 / 失败任务（同时删除其文件），0 表示不限制
+}
+
+// HybridConfig 混合存储配置（Redis 与 PostgreSQL 的配置见 RedisConfig / PostgresConfig）
+type HybridConfig struct {
+    SyncMaxAttempts int    `yaml:"sync_max_attempts"` // 任务同步到 PostgreSQL 失败时最多写入的次数（按指数退避重试），默认 5
+    DeadLetterPath  string `yaml:"dead_letter_path"`  // 多次同步失败的任务写入的死信文件（JSON Lines），默认 data/sync_dead_letter.jsonl
 }
 
 // RedisConfig Redis 配置
@@ -56,6 +146,12 @@ type RedisConfig struct {
     Password string `yaml:"password"` // 密码，无密码留空
     DB       int    `yaml:"db"`       // 数据库编号，默认 0
     TTL      int    `yaml:"ttl"`      // 数据过期时间（小时），默认 168（7天）
+
+    RefreshOnAccess bool `yaml:"refresh_on_access"` // 读取任务时剩余过期时间不足一半则重新设为 ttl，默认 false（保存后到期即过期）
+
+    ListLimit int `yaml:"list_limit"` // 任务列表（首页）最多显示的任务数，默认 100；历史记录不受限制
+
+    CleanIntervalMinutes int `yaml:"clean_interval_minutes"` // 后台清理索引中过期任务 ID 的间隔（分钟），默认 10
 }
 
 // PostgresConfig PostgreSQL 配置
@@ -66,18 +162,163 @@ type PostgresConfig struct {
     Password string `yaml:"password"` // 密码
     Database string `yaml:"database"` // 数据库名
     SSLMode  string `yaml:"sslmode"`  // SSL模式: disable/require/verify-ca/verify-full
+
+    ListLimit      int  `yaml:"list_limit"`      // 任务列表（首页）最多显示的任务数，默认 100；历史记录不受限制
+    SkipMigrations bool `yaml:"skip_migrations"` // 启动时不自动执行数据库迁移（由 goose 等外部工具管理表结构时开启）
+}
+
+// SQLiteConfig SQLite 配置
+type SQLiteConfig struct {
+    Path string `yaml:"path"` // 数据库文件路径，默认 "data/voiceflow.db"
+}
+
+// MongoConfig MongoDB 配置（需使用 go build -tags mongo 编译）
+type MongoConfig struct {
+    URI      string `yaml:"uri"`      // 连接串，默认 "mongodb://localhost:27017"（用户名密码可写在连接串中）
+    Database string `yaml:"database"` // 数据库名，默认 "voiceflow"
+
+    ListLimit int `yaml:"list_limit"` // 任务列表（首页）最多显示的任务数，默认 100；历史记录不受限制
+}
+
+// FileStoreConfig 文件存储配置
+// 多实例部署或使用 RabbitMQ 远程 Worker 时需要使用 s3，所有实例共享同一份文件
+type FileStoreConfig struct {
+    Type  string          `yaml:"type"`  // 存储类型: local/s3，默认 local
+    Local LocalFileConfig `yaml:"local"` // 本地磁盘配置
+    S3    S3Config        `yaml:"s3"`    // S3 / MinIO 配置
+}
+
+// LocalFileConfig 本地磁盘存储配置
+type LocalFileConfig struct {
+    Dir string `yaml:"dir"` // 根目录，默认 "."（文件保存在 <dir>/uploads 下）
+}
+
+// S3Config S3 兼容对象存储配置
+type S3Config struct {
+    Endpoint      string `yaml:"endpoint"`       // 如 "s3.amazonaws.com" 或 "localhost:9000"
+    Region        string `yaml:"region"`         // 区域，MinIO 可留空
+    Bucket        string `yaml:"bucket"`         // 存储桶（需提前创建）
+    AccessKey     string `yaml:"access_key"`     // Access Key
+    SecretKey     string `yaml:"secret_key"`     // Secret Key
+    UseSSL        bool   `yaml:"use_ssl"`        // 是否使用 HTTPS
+    Prefix        string `yaml:"prefix"`         // 对象 key 前缀（多个环境共用存储桶时区分）
+    PresignExpiry int    `yaml:"presign_expiry"` // 预签名下载地址有效期（秒），默认 3600
 }
 
 // ServerConfig 服务器配置
 type ServerConfig struct {
-    Port          int   `yaml:"port"`
-    MaxUploadSize int64 `yaml:"max_upload_size"`
+    Port               int        `yaml:"port"`
+    MaxUploadSize      int64      `yaml:"max_upload_size"`
+    MaxBatchFiles      int        `yaml:"max_batch_files"`      // 一次请求最多上传的文件数，默认 20
+    MaxMultipartMemory int64      `yaml:"max_multipart_memory"` // 解析 multipart 表单时内存中最多缓存的字节数（超出部分写入临时文件），默认 8MB
+    UploadTempDir      string     `yaml:"upload_temp_dir"`      // 上传过程中的临时文件目录，默认 "tmp/uploads"（应与 uploads 位于同一文件系统，完成后直接重命名）
+    PublicBaseURL      string     `yaml:"public_base_url"`      // 对外访问地址，如 "https://voiceflow.example.com"，用于生成 webhook / result.json 中的绝对 URL（为空时使用相对路径）
+    AdminAPIKey        string     `yaml:"admin_api_key"`        // 管理接口（/api/admin/*）的 API Key，为空时不校验
+    CORS               CORSConfig `yaml:"cors"`                 // 跨域配置（前端部署在其他域名时使用）
+    TrustedProxies     []string   `yaml:"trusted_proxies"`      // 可信的反向代理地址（IP 或 CIDR），只采用这些代理设置的 X-Forwarded-For 识别客户端 IP，默认不信任任何代理
+    DisableCompression bool       `yaml:"disable_compression"`  // 关闭响应的 gzip 压缩（默认开启；由反向代理负责压缩时关闭）
+}
+
+// CORSConfig 跨域配置（未设置 allowed_origins 时不启用，只有 WebVTT 字幕允许任意来源）
+type CORSConfig struct {
+    AllowedOrigins   []string `yaml:"allowed_origins"`   // 允许的来源，如 ["https://app.example.com"]，"*" 表示任意来源
+    AllowedMethods   []string `yaml:"allowed_methods"`   // 默认 GET, POST, PUT, DELETE, OPTIONS
+    AllowedHeaders   []string `yaml:"allowed_headers"`   // 默认 Content-Type, Authorization 及 htmx 请求头
+    AllowCredentials bool     `yaml:"allow_credentials"` // 是否允许携带 Cookie 等凭证
+    MaxAge           int      `yaml:"max_age"`           // 预检结果缓存时间（秒），默认 600
+}
+
+// UsersConfig 多用户配置：配置 keys 或 header 后启用，每个用户只能访问自己上传的任务
+type UsersConfig struct {
+    Keys   []UserKeyConfig `yaml:"keys"`   // API Key 与用户的对应关系（Authorization: Bearer <key> 或 X-API-Key）
+    Header string          `yaml:"header"` // 由反向代理设置的用户名请求头（如 X-Forwarded-User），只能在代理后部署时开启，否则任何人都可以伪造
+    Admins []string        `yaml:"admins"` // 通过 header 识别的管理员用户（可以访问所有任务）
+}
+
+// AuthConfig API 鉴权：开启后 /api 下除 /api/ping、/api/health 外的接口都需要携带有效的 Key
+// 与多用户不同，只校验请求是否可信，不区分用户；单人部署在公网时使用
+type AuthConfig struct {
+    Enabled bool     `yaml:"enabled"`  // 是否开启（默认关闭，不影响本地使用）
+    APIKeys []string `yaml:"api_keys"` // 允许的 API Key（Authorization: Bearer <key>、X-API-Key 或 Basic 认证的密码）
+    Token   string   `yaml:"token"`    // 单个 Bearer Token，与 api_keys 等效，只需要一个 Key 时使用
+}
+
+// Keys 所有允许的 Key（api_keys 和 token）
+func (a AuthConfig) Keys() []string {
+    keys := append([]string(nil), a.APIKeys...)
+    if a.Token != "" {
+	keys = append(keys, a.Token)
+    }
+    return keys
+}
+
+// RateLimitConfig 按客户端 IP 的令牌桶限流：防止单个客户端频繁上传或提取单词，耗尽 Worker 和 OpenAI 额度
+type RateLimitConfig struct {
+    Enabled     bool          `yaml:"enabled"`      // 是否开启（默认关闭）
+    Upload      RateLimitRule `yaml:"upload"`       // 上传接口，默认每分钟 10 次，突发 5 次
+    Extract     RateLimitRule `yaml:"extract"`      // 单词提取接口，默认每分钟 20 次，突发 10 次
+    IdleMinutes int           `yaml:"idle_minutes"` // 客户端空闲超过该时间后清除其限流状态，默认 10 分钟
+}
+
+// RateLimitRule 单个接口的限流参数
+type RateLimitRule struct {
+    PerMinute float64 `yaml:"per_minute"` // 每分钟允许的请求数（令牌补充速度）
+    Burst     int     `yaml:"burst"`      // 允许的突发请求数（令牌桶容量）
+}
+
+// UserKeyConfig 单个 API Key
+type UserKeyConfig struct {
+    Key    string `yaml:"key"`
+    UserID string `yaml:"user_id"`
+    Admin  bool   `yaml:"admin"` // 管理员可以访问所有用户的任务
 }
 
 // MaimemoServiceConfig Maimemo 微服务配置
 type MaimemoServiceConfig struct {
-    URL     string `yaml:"url"`     // Maimemo 微服务地址
-    Timeout int    `yaml:"timeout"` // 超时时间（秒）
+    URL      string `yaml:"url"`       // Maimemo 微服务地址
+    Timeout  int    `yaml:"timeout"`   // 超时时间（秒）
+    TokenKey string `yaml:"token_key"` // 加密保存用户墨墨 Token 的密钥，为空时不在服务端保存 Token
+
+    MaxWordsPerRequest int `yaml:"max_words_per_request"` // 每次提交的单词数上限，超过时分批提交（默认 100）
+    MaxNotepadBytes    int `yaml:"max_notepad_bytes"`     // 云词本内容大小上限（字节），超过时不再添加单词并提示云词本已满（0 表示不检查）
+    RequestIntervalMS  int `yaml:"request_interval_ms"`   // 相邻两次请求的最小间隔（毫秒），避免触发墨墨开放 API 限流（默认 500，-1 表示不限制）
+}
+
+// VocabularySinkConfig 单词同步目标配置
+type VocabularySinkConfig struct {
+    Name    string `yaml:"name"`    // 名称（唯一，用于接口路径和同步记录）
+    Type    string `yaml:"type"`    // 类型: maimemo/ankiconnect/webhook
+    Label   string `yaml:"label"`   // 界面上显示的名称
+    URL     string `yaml:"url"`     // ankiconnect: AnkiConnect 地址（默认 http://localhost:8765）；webhook: 接收地址（必填）
+    Deck    string `yaml:"deck"`    // ankiconnect: 牌组名称，默认 VoiceFlow
+    Model   string `yaml:"model"`   // ankiconnect: 笔记类型，默认 Basic（使用 Front / Back 字段）
+    Timeout int    `yaml:"timeout"` // 请求超时时间（秒），默认 10
+}
+
+// RetentionConfig 文件保留策略（由后台清理器执行）
+type RetentionConfig struct {
+    MediaAfterDays    int  `yaml:"media_after_days"`     // 任务完成多少天后删除原始媒体（0 表示永久保留）
+    JobsAfterDays     int  `yaml:"jobs_after_days"`      // 任务创建多少天后删除整个任务（记录、媒体和字幕，0 表示永久保留）
+    IntervalMinutes   int  `yaml:"interval_minutes"`     // 清理器检查间隔（分钟），默认 60
+    DryRun            bool `yaml:"dry_run"`              // 只打印将要删除的文件，不实际删除
+    KeepFilesOnDelete bool `yaml:"keep_files_on_delete"` // 永久删除任务时保留媒体和字幕文件（调试用）
+    DeletedAfterDays  int  `yaml:"deleted_after_days"`   // 删除的任务保留多少天后永久删除（期间可以恢复），默认 7，负数表示永久保留
+    ArchiveAfterDays  int  `yaml:"archive_after_days"`   // 任务完成多少天后移到归档表（只支持 PostgreSQL / 混合存储，0 表示不归档）
+}
+
+// WebhookConfig 任务结束通知配置
+type WebhookConfig struct {
+    URL     string `yaml:"url"`     // 接收通知的地址，为空表示不推送
+    Timeout int    `yaml:"timeout"` // 请求超时时间（秒），默认 10
+}
+
+// SourceConfig 播客订阅源配置（RSS / Atom）
+type SourceConfig struct {
+    Name            string `yaml:"name"`             // 名称（唯一，会标记在任务上）
+    URL             string `yaml:"url"`              // 订阅源地址
+    IntervalMinutes int    `yaml:"interval_minutes"` // 轮询间隔（分钟），默认 60
+    MaxItems        int    `yaml:"max_items"`        // 每次只检查最新的几期，默认 3
+    Disabled        bool   `yaml:"disabled"`         // 是否停用
 }
 
 // LoadConfig 加载配置文件
@@ -109,10 +350,19 @@ func (c *Config) Validate() error {
 	return fmt.Errorf("请在配置文件中设置有效的 OpenAI API Key")
     }
 
+    pricing := c.OpenAI.Pricing
+    if pricing.WhisperPerMinute < 0 || pricing.ChatInputPerMillion < 0 || pricing.ChatOutputPerMillion < 0 {
+	return fmt.Errorf("openai.pricing 中的单价不能为负数")
+    }
+
     if c.Transcriber.WorkerPoolSize <= 0 {
 	c.Transcriber.WorkerPoolSize = 2 // 默认 2 个 Worker 实例
     }
 
+    if c.Transcriber.DrainTimeout <= 0 {
+	c.Transcriber.DrainTimeout = 300
+    }
+
     if c.Transcriber.SegmentConcurrency <= 0 {
 	c.Transcriber.SegmentConcurrency = 3 // 默认 3 个并发分片处理
     }
@@ -121,14 +371,142 @@ func (c *Config) Validate() error {
 	c.Transcriber.SegmentDuration = 600
     }
 
+    if c.Transcriber.SegmentOverlap < 0 || c.Transcriber.SegmentOverlap >= c.Transcriber.SegmentDuration {
+	return fmt.Errorf("transcriber.segment_overlap 必须在 0 到 segment_duration 之间: %d", c.Transcriber.SegmentOverlap)
+    }
+    if c.Transcriber.SegmentBitrate != "" && !validBitrate(c.Transcriber.SegmentBitrate) {
+	return fmt.Errorf("transcriber.segment_bitrate 格式错误（如 64k）: %s", c.Transcriber.SegmentBitrate)
+    }
+    if c.Transcriber.SegmentSampleRate < 0 || c.Transcriber.SegmentChannels < 0 || c.Transcriber.SegmentChannels > 2 {
+	return fmt.Errorf("transcriber.segment_sample_rate 不能为负数，segment_channels 只能是 1 或 2")
+    }
+    if c.Transcriber.Temperature < 0 || c.Transcriber.Temperature > 1 {
+	return fmt.Errorf("transcriber.temperature 必须在 0 到 1 之间: %g", c.Transcriber.Temperature)
+    }
+
+    if c.Transcriber.WhisperTimeoutSeconds <= 0 {
+	c.Transcriber.WhisperTimeoutSeconds = 300
+    }
+    if c.Transcriber.WhisperSecondsPerMinute == 0 {
+	c.Transcriber.WhisperSecondsPerMinute = 30 // 10 分钟的片段 5 分钟
+    }
+
+    if c.Transcriber.MaxLineChars == 0 {
+	c.Transcriber.MaxLineChars = 42 // Netflix 字幕规范
+    }
+
+    if c.Transcriber.TargetLanguage == "" {
+	c.Transcriber.TargetLanguage = "简体中文"
+    }
+
     if c.Server.Port <= 0 {
 	c.Server.Port = 8080
     }
+    if c.Server.MaxUploadSize <= 0 {
+	c.Server.MaxUploadSize = 100 << 20 // 默认 100MB
+    }
+    if c.Server.MaxBatchFiles <= 0 {
+	c.Server.MaxBatchFiles = 20
+    }
+    if c.Server.MaxMultipartMemory <= 0 {
+	c.Server.MaxMultipartMemory = 8 << 20
+    }
+    if c.Server.UploadTempDir == "" {
+	c.Server.UploadTempDir = "tmp/uploads"
+    }
+
+    // 跨域配置默认值
+    if len(c.Server.CORS.AllowedMethods) == 0 {
+	c.Server.CORS.AllowedMethods = []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"}
+    }
+    if len(c.Server.CORS.AllowedHeaders) == 0 {
+	c.Server.CORS.AllowedHeaders = []string{"Content-Type", "Authorization", "HX-Request", "HX-Target", "HX-Trigger", "HX-Current-URL"}
+    }
+    if c.Server.CORS.MaxAge <= 0 {
+	c.Server.CORS.MaxAge = 600
+    }
+
+    // 多用户：每个 API Key 必须对应一个用户，且不能重复
+    seenKeys := make(map[string]bool, len(c.Users.Keys))
+    for i, k := range c.Users.Keys {
+	if k.Key == "" || k.UserID == "" {
+	    return fmt.Errorf("users.keys[%d] 缺少 key 或 user_id", i)
+	}
+	if seenKeys[k.Key] {
+	    return fmt.Errorf("users.keys[%d] 的 key 重复", i)
+	}
+	seenKeys[k.Key] = true
+    }
+
+    // API 鉴权：开启时至少需要一个 Key
+    for i, key := range c.Auth.APIKeys {
+	if key == "" {
+	    return fmt.Errorf("auth.api_keys[%d] 为空", i)
+	}
+    }
+    if c.Auth.Enabled && len(c.Auth.Keys()) == 0 {
+	return fmt.Errorf("auth.enabled 为 true 时需要配置 auth.api_keys 或 auth.token")
+    }
+
+    // 限流默认值
+    if c.RateLimit.Upload.PerMinute <= 0 {
+	c.RateLimit.Upload.PerMinute = 10
+    }
+    if c.RateLimit.Upload.Burst <= 0 {
+	c.RateLimit.Upload.Burst = 5
+    }
+    if c.RateLimit.Extract.PerMinute <= 0 {
+	c.RateLimit.Extract.PerMinute = 20
+    }
+    if c.RateLimit.Extract.Burst <= 0 {
+	c.RateLimit.Extract.Burst = 10
+    }
+    if c.RateLimit.IdleMinutes <= 0 {
+	c.RateLimit.IdleMinutes = 10
+    }
+
+    // 日志配置默认值
+    switch strings.ToLower(c.Log.Level) {
+    case "":
+	c.Log.Level = "info"
+    case "debug", "info", "warn", "warning", "error":
+    default:
+	return fmt.Errorf("不支持的日志级别: %q（可选 debug、info、warn、error）", c.Log.Level)
+    }
+    switch c.Log.Format {
+    case "":
+	c.Log.Format = "console"
+    case "console", "json":
+    default:
+	return fmt.Errorf("不支持的日志格式: %q（可选 console、json）", c.Log.Format)
+    }
 
     // 存储配置默认值
     if c.Storage.Type == "" {
 	c.Storage.Type = "memory"
     }
+    if c.Storage.Memory.MaxJobs < 0 {
+	return fmt.Errorf("storage.memory.max_jobs 不能为负数: %d", c.Storage.Memory.MaxJobs)
+    }
+
+    // 文件存储配置默认值
+    switch c.FileStore.Type {
+    case "":
+	c.FileStore.Type = "local"
+    case "local":
+    case "s3":
+	if c.FileStore.S3.Endpoint == "" || c.FileStore.S3.Bucket == "" {
+	    return fmt.Errorf("s3 文件存储缺少 endpoint 或 bucket")
+	}
+    default:
+	return fmt.Errorf("不支持的文件存储类型: %q", c.FileStore.Type)
+    }
+    if c.FileStore.Local.Dir == "" {
+	c.FileStore.Local.Dir = "."
+    }
+    if c.FileStore.S3.PresignExpiry <= 0 {
+	c.FileStore.S3.PresignExpiry = 3600
+    }
 
     // Redis 配置默认值
     if c.Storage.Type == "redis" || c.Storage.Type == "hybrid" {
@@ -138,6 +516,9 @@ func (c *Config) Validate() error {
 	if c.Storage.Redis.TTL <= 0 {
 	    c.Storage.Redis.TTL = 168 // 默认 7 天
 	}
+	if c.Storage.Redis.CleanIntervalMinutes <= 0 {
+	    c.Storage.Redis.CleanIntervalMinutes = 10
+	}
     }
 
     // PostgreSQL 配置默认值
@@ -153,13 +534,58 @@ func (c *Config) Validate() error {
 	}
     }
 
+    // 混合存储配置默认值
+    if c.Storage.Type == "hybrid" {
+	if c.Storage.Hybrid.SyncMaxAttempts <= 0 {
+	    c.Storage.Hybrid.SyncMaxAttempts = 5
+	}
+	if c.Storage.Hybrid.DeadLetterPath == "" {
+	    c.Storage.Hybrid.DeadLetterPath = "data/sync_dead_letter.jsonl"
+	}
+    }
+
+    // SQLite 配置默认值
+    if c.Storage.Type == "sqlite" && c.Storage.SQLite.Path == "" {
+	c.Storage.SQLite.Path = "data/voiceflow.db"
+    }
+
+    // MongoDB 配置默认值
+    if c.Storage.Type == "mongo" {
+	if c.Storage.Mongo.URI == "" {
+	    c.Storage.Mongo.URI = "mongodb://localhost:27017"
+	}
+	if c.Storage.Mongo.Database == "" {
+	    c.Storage.Mongo.Database = "voiceflow"
+	}
+    }
+
     // 队列配置默认值
     if c.Queue.Type == "" {
 	c.Queue.Type = "memory"
     }
+    if c.Queue.MaxRecoveryAttempts <= 0 {
+	c.Queue.MaxRecoveryAttempts = 3
+    }
+    if c.Queue.MaxRetries == 0 {
+	c.Queue.MaxRetries = 3
+    }
+    if c.Queue.RetryDelaySeconds <= 0 {
+	c.Queue.RetryDelaySeconds = 30
+    }
+
     if c.Queue.BufferSize <= 0 {
 	c.Queue.BufferSize = 100
     }
+    switch c.Queue.EnqueuePolicy {
+    case "":
+	c.Queue.EnqueuePolicy = "reject"
+    case "reject", "block", "grow":
+    default:
+	return fmt.Errorf("queue.enqueue_policy 只能是 reject、block 或 grow: %s", c.Queue.EnqueuePolicy)
+    }
+    if c.Queue.EnqueueTimeout <= 0 {
+	c.Queue.EnqueueTimeout = 10
+    }
 
     // RabbitMQ 配置验证
     if c.Queue.Type == "rabbitmq" {
@@ -169,6 +595,44 @@ func (c *Config) Validate() error {
 	if c.Queue.RabbitMQ.QueueName == "" {
 	    c.Queue.RabbitMQ.QueueName = "voiceflow_transcription"
 	}
+	if c.Queue.RabbitMQ.Prefetch <= 0 {
+	    c.Queue.RabbitMQ.Prefetch = c.Transcriber.WorkerPoolSize
+	}
+    }
+
+    // NATS 配置默认值
+    if c.Queue.Type == "nats" {
+	if c.Queue.NATS.URL == "" {
+	    c.Queue.NATS.URL = "nats://localhost:4222"
+	}
+	if c.Queue.NATS.Stream == "" {
+	    c.Queue.NATS.Stream = "VOICEFLOW"
+	}
+	if c.Queue.NATS.Subject == "" {
+	    c.Queue.NATS.Subject = "voiceflow.jobs"
+	}
+	if c.Queue.NATS.Consumer == "" {
+	    c.Queue.NATS.Consumer = "voiceflow-workers"
+	}
+	if c.Queue.NATS.AckWait <= 0 {
+	    c.Queue.NATS.AckWait = 3600
+	}
+    }
+
+    // Kafka 配置默认值
+    if c.Queue.Type == "kafka" {
+	if len(c.Queue.Kafka.Brokers) == 0 {
+	    c.Queue.Kafka.Brokers = []string{"localhost:9092"}
+	}
+	if c.Queue.Kafka.Topic == "" {
+	    c.Queue.Kafka.Topic = "voiceflow.jobs"
+	}
+	if c.Queue.Kafka.GroupID == "" {
+	    c.Queue.Kafka.GroupID = "voiceflow-workers"
+	}
+	if c.Queue.Kafka.WriteTimeout <= 0 {
+	    c.Queue.Kafka.WriteTimeout = 10
+	}
     }
 
     // Maimemo 微服务配置默认值
@@ -178,6 +642,84 @@ func (c *Config) Validate() error {
     if c.MaimemoService.Timeout <= 0 {
 	c.MaimemoService.Timeout = 30
     }
+    if c.MaimemoService.MaxWordsPerRequest < 0 || c.MaimemoService.MaxNotepadBytes < 0 {
+	return fmt.Errorf("maimemo_service.max_words_per_request 和 max_notepad_bytes 不能为负数")
+    }
+
+    // 单词同步目标配置验证（未配置时默认提供墨墨）
+    if len(c.VocabularySinks) == 0 {
+	c.VocabularySinks = []VocabularySinkConfig{{Name: "maimemo", Type: "maimemo"}}
+    }
+    sinkNames := make(map[string]bool)
+    maimemoSinks := 0
+    for i := range c.VocabularySinks {
+	sink := &c.VocabularySinks[i]
+	if sink.Name == "" {
+	    return fmt.Errorf("单词同步目标 #%d 缺少 name", i+1)
+	}
+	if sinkNames[sink.Name] {
+	    return fmt.Errorf("单词同步目标名称重复: %s", sink.Name)
+	}
+	sinkNames[sink.Name] = true
+
+	switch sink.Type {
+	case "maimemo":
+	    // 墨墨同步表单按任务渲染，同一页面只能有一个
+	    maimemoSinks++
+	    if maimemoSinks > 1 {
+		return fmt.Errorf("最多配置一个 maimemo 类型的单词同步目标")
+	    }
+	case "ankiconnect":
+	case "webhook":
+	    if sink.URL == "" {
+		return fmt.Errorf("单词同步目标 %s 缺少 url", sink.Name)
+	    }
+	default:
+	    return fmt.Errorf("单词同步目标 %s 的类型不支持: %q", sink.Name, sink.Type)
+	}
+	if sink.Timeout <= 0 {
+	    sink.Timeout = 10
+	}
+    }
+
+    // Webhook 配置默认值
+    if c.Webhook.Timeout <= 0 {
+	c.Webhook.Timeout = 10
+    }
+
+    // 订阅源配置验证
+    sourceNames := make(map[string]bool)
+    for i := range c.Sources {
+	src := &c.Sources[i]
+	if src.Name == "" || src.URL == "" {
+	    return fmt.Errorf("订阅源 #%d 缺少 name 或 url", i+1)
+	}
+	if sourceNames[src.Name] {
+	    return fmt.Errorf("订阅源名称重复: %s", src.Name)
+	}
+	sourceNames[src.Name] = true
+
+	if src.IntervalMinutes <= 0 {
+	    src.IntervalMinutes = 60
+	}
+	if src.MaxItems <= 0 {
+	    src.MaxItems = 3
+	}
+    }
+
+    // 保留策略默认值
+    if c.Retention.IntervalMinutes <= 0 {
+	c.Retention.IntervalMinutes = 60
+    }
+    if c.Retention.DeletedAfterDays == 0 {
+	c.Retention.DeletedAfterDays = 7
+    }
 
     return nil
 }
+
+// validBitrate 检查 FFmpeg 比特率格式（如 64k 或 64000）
+func validBitrate(value string) bool {
+    n, err := strconv.Atoi(strings.TrimSuffix(strings.ToLower(value), "k"))
+    return err == nil && n > 0
+}
@@ -0,0 +1,41 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// loadTestConfig 把 YAML 写入临时文件后加载
+func loadTestConfig(t *testing.T, yaml string) *Config {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(yaml), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+	return cfg
+}
+
+func TestRabbitMQPublisherConfirms(t *testing.T) {
+	tests := []struct {
+		name     string
+		rabbitmq string
+		want     bool
+	}{
+		{"默认关闭", "", false},
+		{"开启", "    publisher_confirms: true\n", true},
+		{"显式关闭", "    publisher_confirms: false\n", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := loadTestConfig(t, "openai:\n  api_key: sk-test\nqueue:\n  type: rabbitmq\n  rabbitmq:\n    queue_name: jobs\n"+tt.rabbitmq)
+			if got := cfg.Queue.RabbitMQ.PublisherConfirms; got != tt.want {
+				t.Errorf("PublisherConfirms = %v，期望 %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,95 @@
+package credentials
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+
+	"github.com/z-wentao/voiceflow/pkg/storage"
+)
+
+// 凭证名称
+const (
+	Maimemo = "maimemo" // 墨墨 API Token
+)
+
+// ErrNoKey 未配置加密密钥
+var ErrNoKey = errors.New("未配置凭证加密密钥")
+
+// Store 用户凭证存储：凭证使用 AES-256-GCM 加密后保存在任务存储中，
+// 密钥由配置的字符串经 SHA-256 派生，更换密钥后已保存的凭证无法解密，需要重新保存
+type Store struct {
+	store storage.Store
+	aead  cipher.AEAD
+}
+
+// New 创建凭证存储
+func New(store storage.Store, key string) (*Store, error) {
+	if key == "" {
+		return nil, ErrNoKey
+	}
+
+	sum := sha256.Sum256([]byte(key))
+	block, err := aes.NewCipher(sum[:])
+	if err != nil {
+		return nil, fmt.Errorf("初始化加密算法失败: %w", err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("初始化加密算法失败: %w", err)
+	}
+
+	return &Store{store: store, aead: aead}, nil
+}
+
+// Save 加密保存用户凭证（已存在时覆盖）
+func (s *Store) Save(ctx context.Context, userID, name, value string) error {
+	nonce := make([]byte, s.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return fmt.Errorf("生成随机数失败: %w", err)
+	}
+
+	// 用户 ID 和凭证名称作为附加数据，密文不能被挪用到其他用户
+	sealed := s.aead.Seal(nonce, nonce, []byte(value), additionalData(userID, name))
+	return s.store.SetCredential(ctx, userID, name, base64.StdEncoding.EncodeToString(sealed))
+}
+
+// Load 读取并解密用户凭证，没有保存时返回空字符串
+func (s *Store) Load(ctx context.Context, userID, name string) (string, error) {
+	encoded, err := s.store.GetCredential(ctx, userID, name)
+	if err != nil || encoded == "" {
+		return "", err
+	}
+
+	sealed, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil || len(sealed) < s.aead.NonceSize() {
+		return "", fmt.Errorf("凭证格式错误")
+	}
+	nonce, ciphertext := sealed[:s.aead.NonceSize()], sealed[s.aead.NonceSize():]
+	plain, err := s.aead.Open(nil, nonce, ciphertext, additionalData(userID, name))
+	if err != nil {
+		return "", fmt.Errorf("解密凭证失败（加密密钥可能已更换）: %w", err)
+	}
+	return string(plain), nil
+}
+
+// Exists 用户是否保存了凭证
+func (s *Store) Exists(ctx context.Context, userID, name string) (bool, error) {
+	encoded, err := s.store.GetCredential(ctx, userID, name)
+	return encoded != "", err
+}
+
+// Delete 删除用户凭证
+func (s *Store) Delete(ctx context.Context, userID, name string) error {
+	return s.store.DeleteCredential(ctx, userID, name)
+}
+
+// additionalData GCM 附加认证数据
+func additionalData(userID, name string) []byte {
+	return []byte(userID + "\x00" + name)
+}
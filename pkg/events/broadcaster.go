@@ -0,0 +1,139 @@
+package events
+
+import (
+	"log"
+	"sync"
+
+	"github.com/z-wentao/voiceflow/pkg/models"
+)
+
+// clientBuffer 每个广播客户端的发送缓冲区大小，写满说明客户端跟不上，直接断开
+const clientBuffer = 64
+
+// Client 广播客户端（如一个 WebSocket 连接）
+type Client struct {
+	send   chan Event
+	filter func(Event) bool
+}
+
+// Events 待发送给客户端的事件；通道关闭表示客户端已被断开（取消注册、发送过慢或广播器停止）
+func (c *Client) Events() <-chan Event {
+	return c.send
+}
+
+// Broadcaster 将所有任务的状态变化广播给大量长连接客户端
+// 一个广播 goroutine 从 Hub 订阅所有任务的事件，只转发状态变化（同一状态下的进度更新不转发）；
+// 每个客户端有独立的发送缓冲区，缓冲区已满的慢客户端直接断开，不会拖慢其他客户端
+type Broadcaster struct {
+	hub     *Hub
+	mu      sync.Mutex
+	clients map[*Client]struct{}
+	stopCh  chan struct{}
+	once    sync.Once
+}
+
+// NewBroadcaster 创建广播器并启动广播 goroutine
+func NewBroadcaster(hub *Hub) *Broadcaster {
+	b := &Broadcaster{
+		hub:     hub,
+		clients: make(map[*Client]struct{}),
+		stopCh:  make(chan struct{}),
+	}
+	go b.run()
+	return b
+}
+
+// Register 注册客户端，filter 为 nil 时接收所有任务的事件
+// 调用方结束时必须调用 Unregister
+func (b *Broadcaster) Register(filter func(Event) bool) *Client {
+	client := &Client{
+		send:   make(chan Event, clientBuffer),
+		filter: filter,
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	select {
+	case <-b.stopCh:
+		close(client.send) // 已停止：立即断开
+	default:
+		b.clients[client] = struct{}{}
+	}
+	return client
+}
+
+// Unregister 取消注册客户端（已被断开的客户端不报错）
+func (b *Broadcaster) Unregister(client *Client) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.remove(client)
+}
+
+// Stop 停止广播并断开所有客户端
+func (b *Broadcaster) Stop() {
+	b.once.Do(func() {
+		close(b.stopCh)
+	})
+}
+
+// remove 移除客户端并关闭其发送通道（调用方需持有锁）
+func (b *Broadcaster) remove(client *Client) {
+	if _, ok := b.clients[client]; ok {
+		delete(b.clients, client)
+		close(client.send)
+	}
+}
+
+// run 广播主循环
+func (b *Broadcaster) run() {
+	ch, unsubscribe := b.hub.SubscribeAll()
+	defer unsubscribe()
+
+	// 每个未结束任务最近一次转发的状态，用于过滤进度更新
+	last := make(map[string]models.JobStatus)
+
+	for {
+		select {
+		case <-b.stopCh:
+			b.mu.Lock()
+			for client := range b.clients {
+				b.remove(client)
+			}
+			b.mu.Unlock()
+			return
+
+		case event := <-ch:
+			// 子任务结束事件总是转发，不影响状态过滤
+			if event.Task == "" {
+				if last[event.JobID] == event.Status {
+					continue
+				}
+				if event.Terminal() {
+					delete(last, event.JobID)
+				} else {
+					last[event.JobID] = event.Status
+				}
+			}
+			b.broadcast(event)
+		}
+	}
+}
+
+// broadcast 把事件发送给所有匹配的客户端（不阻塞）
+func (b *Broadcaster) broadcast(event Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for client := range b.clients {
+		if client.filter != nil && !client.filter(event) {
+			continue
+		}
+		select {
+		case client.send <- event:
+		default:
+			log.Printf("⚠️ 广播客户端发送缓冲区已满，断开连接")
+			b.remove(client)
+		}
+	}
+}
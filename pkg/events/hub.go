@@ -0,0 +1,127 @@
+package events
+
+import (
+	"sync"
+
+	"github.com/z-wentao/voiceflow/pkg/models"
+)
+
+// subscriberBuffer 每个订阅者的缓冲区大小
+const subscriberBuffer = 16
+
+// allSubscriberBuffer 订阅所有任务事件的缓冲区大小（多个任务同时处理时事件更密集）
+const allSubscriberBuffer = 256
+
+// Event 任务进度事件
+// Task 不为空时表示该任务的子任务（如单词提取）已结束，Status / Progress 为任务当前的状态
+type Event struct {
+	JobID     string           `json:"job_id"`
+	Status    models.JobStatus `json:"status"`
+	Progress  int              `json:"progress"`
+	Error     string           `json:"error,omitempty"`
+	Task      string           `json:"task,omitempty"`       // 结束的子任务类型，如 vocabulary
+	TaskID    string           `json:"task_id,omitempty"`    // 结束的子任务 ID
+	TaskState string           `json:"task_state,omitempty"` // 子任务最终状态：completed / failed / cancelled
+	TaskError string           `json:"task_error,omitempty"` // 子任务失败原因
+	UserID    string           `json:"-"`                    // 任务所属用户，用于广播时按用户过滤
+}
+
+// Terminal 是否为终态事件（收到后订阅者可以结束）
+func (e Event) Terminal() bool {
+	return e.Status == models.StatusCompleted || e.Status == models.StatusFailed
+}
+
+// Hub 进程内的任务事件发布/订阅中心（按 JobID 分组，也可以订阅所有任务）
+// 发布方（Worker）永远不会被慢订阅者阻塞：缓冲区满时丢弃最旧的事件
+type Hub struct {
+	mu   sync.Mutex
+	subs map[string]map[chan Event]struct{}
+	all  map[chan Event]struct{}
+}
+
+// NewHub 创建事件中心
+func NewHub() *Hub {
+	return &Hub{
+		subs: make(map[string]map[chan Event]struct{}),
+		all:  make(map[chan Event]struct{}),
+	}
+}
+
+// Subscribe 订阅任务事件，返回事件通道和取消订阅函数
+// 调用方结束时必须调用取消函数，否则通道不会被释放
+func (h *Hub) Subscribe(jobID string) (<-chan Event, func()) {
+	ch := make(chan Event, subscriberBuffer)
+
+	h.mu.Lock()
+	if h.subs[jobID] == nil {
+		h.subs[jobID] = make(map[chan Event]struct{})
+	}
+	h.subs[jobID][ch] = struct{}{}
+	h.mu.Unlock()
+
+	var once sync.Once
+	unsubscribe := func() {
+		once.Do(func() {
+			h.mu.Lock()
+			defer h.mu.Unlock()
+
+			delete(h.subs[jobID], ch)
+			if len(h.subs[jobID]) == 0 {
+				delete(h.subs, jobID)
+			}
+		})
+	}
+	return ch, unsubscribe
+}
+
+// SubscribeAll 订阅所有任务的事件（如任务列表的实时推送），用法与 Subscribe 相同
+func (h *Hub) SubscribeAll() (<-chan Event, func()) {
+	ch := make(chan Event, allSubscriberBuffer)
+
+	h.mu.Lock()
+	h.all[ch] = struct{}{}
+	h.mu.Unlock()
+
+	var once sync.Once
+	unsubscribe := func() {
+		once.Do(func() {
+			h.mu.Lock()
+			defer h.mu.Unlock()
+			delete(h.all, ch)
+		})
+	}
+	return ch, unsubscribe
+}
+
+// Publish 发布事件给该任务的所有订阅者以及所有任务的订阅者（不阻塞）
+func (h *Hub) Publish(event Event) {
+	if h == nil {
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for ch := range h.subs[event.JobID] {
+		send(ch, event)
+	}
+	for ch := range h.all {
+		send(ch, event)
+	}
+}
+
+// send 非阻塞发送：缓冲区已满时丢弃最旧的事件，保证最新进度（尤其是终态）能送达
+func send(ch chan Event, event Event) {
+	select {
+	case ch <- event:
+	default:
+		select {
+		case <-ch:
+		default:
+		}
+		select {
+		case ch <- event:
+		default:
+		}
+	}
+}
@@ -0,0 +1,168 @@
+package filestore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"time"
+)
+
+// ErrNotFound 对象不存在
+var ErrNotFound = errors.New("文件不存在")
+
+// Info 对象元信息
+type Info struct {
+	Key          string // 对象 key（仅 List 返回）
+	Size         int64
+	LastModified time.Time
+}
+
+// FileStore 文件存储（上传的媒体、字幕等）
+// 任务记录中保存对象 key（如 uploads/<job_id>_<文件名>），而不是本地路径，
+// 多个实例或远程 Worker 共享同一个存储时都能访问
+type FileStore interface {
+	// Put 写入对象（size 未知时传 -1）
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+	// Get 读取对象，调用方负责关闭
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	// Stat 获取对象元信息，不存在时返回 ErrNotFound
+	Stat(ctx context.Context, key string) (Info, error)
+	// Delete 删除对象（不存在时不报错）
+	Delete(ctx context.Context, key string) error
+	// List 列出 prefix "目录"下的所有对象（递归，如 prefix 为 uploads 时列出 uploads/ 下的对象）
+	List(ctx context.Context, prefix string) ([]Info, error)
+	// URL 浏览器可直接访问的地址（S3 为预签名地址；本地存储没有静态目录，只返回 key 对应的路径）
+	URL(ctx context.Context, key string) (string, error)
+}
+
+// localPather 可以直接访问本地路径的存储（避免不必要的复制）
+type localPather interface {
+	Path(key string) string
+}
+
+// PutFile 上传本地文件
+// 本地存储直接移动文件（同一文件系统时只需重命名），其他存储上传后删除本地文件
+func PutFile(ctx context.Context, fs FileStore, key, localPath, contentType string) error {
+	if lp, ok := fs.(localPather); ok {
+		dst := lp.Path(key)
+		if filepath.Clean(dst) == filepath.Clean(localPath) {
+			return nil
+		}
+		if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+			return fmt.Errorf("创建目录失败: %w", err)
+		}
+		return moveFile(localPath, dst)
+	}
+
+	file, err := os.Open(localPath)
+	if err != nil {
+		return fmt.Errorf("打开文件失败: %w", err)
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return fmt.Errorf("读取文件信息失败: %w", err)
+	}
+	if err := fs.Put(ctx, key, file, info.Size(), contentType); err != nil {
+		return err
+	}
+	file.Close()
+	return os.Remove(localPath)
+}
+
+// Fetch 获取对象的本地文件（转码、切分等需要本地文件的场景）
+// 本地存储直接返回原路径；其他存储下载到 dir 下的临时目录，文件名与 key 的文件名一致。
+// 处理完成后调用 cleanup 删除临时文件
+func Fetch(ctx context.Context, fs FileStore, key, dir string) (localPath string, cleanup func(), err error) {
+	if lp, ok := fs.(localPather); ok {
+		p := lp.Path(key)
+		if _, err := os.Stat(p); err != nil {
+			if os.IsNotExist(err) {
+				return "", nil, ErrNotFound
+			}
+			return "", nil, err
+		}
+		return p, func() {}, nil
+	}
+
+	tmpDir, err := os.MkdirTemp(dir, "fetch-*")
+	if err != nil {
+		return "", nil, fmt.Errorf("创建临时目录失败: %w", err)
+	}
+	cleanup = func() { os.RemoveAll(tmpDir) }
+
+	reader, err := fs.Get(ctx, key)
+	if err != nil {
+		cleanup()
+		return "", nil, err
+	}
+	defer reader.Close()
+
+	localPath = filepath.Join(tmpDir, path.Base(key))
+	file, err := os.Create(localPath)
+	if err != nil {
+		cleanup()
+		return "", nil, fmt.Errorf("创建临时文件失败: %w", err)
+	}
+	if _, err := io.Copy(file, reader); err != nil {
+		file.Close()
+		cleanup()
+		return "", nil, fmt.Errorf("下载文件失败: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		cleanup()
+		return "", nil, fmt.Errorf("写入临时文件失败: %w", err)
+	}
+
+	return localPath, cleanup, nil
+}
+
+// ReadAll 读取整个对象（字幕等小文件）
+func ReadAll(ctx context.Context, fs FileStore, key string) ([]byte, error) {
+	reader, err := fs.Get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	return io.ReadAll(reader)
+}
+
+// SiblingKey 与 key 位于同一"目录"、文件名为 localPath 文件名的 key
+// 例如 key=uploads/abc_talk.mp3，localPath=/tmp/fetch-1/abc_talk.srt → uploads/abc_talk.srt
+func SiblingKey(key, localPath string) string {
+	return path.Join(path.Dir(key), filepath.Base(localPath))
+}
+
+// moveFile 移动文件；不在同一文件系统时退化为复制
+func moveFile(src, dst string) error {
+	if err := os.Rename(src, dst); err == nil {
+		return nil
+	}
+
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		os.Remove(dst)
+		return err
+	}
+	if err := out.Close(); err != nil {
+		os.Remove(dst)
+		return err
+	}
+	in.Close()
+	return os.Remove(src)
+}
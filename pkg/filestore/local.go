@@ -0,0 +1,123 @@
+package filestore
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// LocalStore 本地磁盘存储（单实例部署）
+// key 是相对于根目录的路径，根目录默认为工作目录，因此 key 与旧版本保存的路径一致
+type LocalStore struct {
+	root string
+}
+
+// NewLocalStore 创建本地磁盘存储
+func NewLocalStore(root string) *LocalStore {
+	if root == "" {
+		root = "."
+	}
+	return &LocalStore{root: root}
+}
+
+// Path key 对应的本地路径
+func (s *LocalStore) Path(key string) string {
+	return filepath.Join(s.root, filepath.FromSlash(path.Clean("/"+key)))
+}
+
+// Put 写入文件（先写临时文件再重命名，避免读到写了一半的文件）
+func (s *LocalStore) Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error {
+	dst := s.Path(key)
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return fmt.Errorf("创建目录失败: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(dst), ".put-*")
+	if err != nil {
+		return fmt.Errorf("创建临时文件失败: %w", err)
+	}
+	if _, err := io.Copy(tmp, r); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return fmt.Errorf("写入文件失败: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("写入文件失败: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), dst); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("保存文件失败: %w", err)
+	}
+	return nil
+}
+
+// Get 打开文件
+func (s *LocalStore) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	file, err := os.Open(s.Path(key))
+	if os.IsNotExist(err) {
+		return nil, ErrNotFound
+	}
+	return file, err
+}
+
+// Stat 获取文件信息
+func (s *LocalStore) Stat(ctx context.Context, key string) (Info, error) {
+	info, err := os.Stat(s.Path(key))
+	if os.IsNotExist(err) || (err == nil && info.IsDir()) {
+		return Info{}, ErrNotFound
+	}
+	if err != nil {
+		return Info{}, err
+	}
+	return Info{Size: info.Size(), LastModified: info.ModTime()}, nil
+}
+
+// Delete 删除文件
+func (s *LocalStore) Delete(ctx context.Context, key string) error {
+	if err := os.Remove(s.Path(key)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// List 递归列出目录下的文件（跳过写入中的临时文件）
+func (s *LocalStore) List(ctx context.Context, prefix string) ([]Info, error) {
+	var infos []Info
+	dir := s.Path(prefix)
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) && p == dir {
+				return filepath.SkipDir
+			}
+			return err
+		}
+		if d.IsDir() || strings.HasPrefix(d.Name(), ".put-") {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil // 遍历期间被删除
+		}
+		rel, err := filepath.Rel(s.root, p)
+		if err != nil {
+			return err
+		}
+		infos = append(infos, Info{Key: filepath.ToSlash(rel), Size: info.Size(), LastModified: info.ModTime()})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("列出文件失败: %w", err)
+	}
+	return infos, nil
+}
+
+// URL key 对应的路径（本地文件不作为静态目录提供，媒体由 /api/jobs/:job_id/media 直接返回文件）
+func (s *LocalStore) URL(ctx context.Context, key string) (string, error) {
+	return "/" + strings.TrimPrefix(path.Clean("/"+key), "/"), nil
+}
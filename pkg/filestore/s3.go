@@ -0,0 +1,134 @@
+package filestore
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+)
+
+// S3Options S3 / MinIO 连接参数
+type S3Options struct {
+	Endpoint      string        // 如 s3.amazonaws.com 或 localhost:9000
+	Region        string        // 区域，MinIO 可留空
+	Bucket        string        // 存储桶（需提前创建）
+	AccessKey     string        // Access Key
+	SecretKey     string        // Secret Key
+	UseSSL        bool          // 是否使用 HTTPS
+	Prefix        string        // 对象 key 前缀，多个环境共用存储桶时区分
+	PresignExpiry time.Duration // 预签名地址有效期，默认 1 小时
+}
+
+// S3Store S3 兼容对象存储（AWS S3、MinIO 等）
+// 多实例部署或远程 Worker 时使用，所有实例通过对象 key 访问同一份文件
+type S3Store struct {
+	client *minio.Client
+	opts   S3Options
+}
+
+// NewS3Store 创建 S3 存储，并检查存储桶是否存在
+func NewS3Store(ctx context.Context, opts S3Options) (*S3Store, error) {
+	if opts.PresignExpiry <= 0 {
+		opts.PresignExpiry = time.Hour
+	}
+
+	client, err := minio.New(opts.Endpoint, &minio.Options{
+		Creds:  credentials.NewStaticV4(opts.AccessKey, opts.SecretKey, ""),
+		Secure: opts.UseSSL,
+		Region: opts.Region,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("创建 S3 客户端失败: %w", err)
+	}
+
+	exists, err := client.BucketExists(ctx, opts.Bucket)
+	if err != nil {
+		return nil, fmt.Errorf("连接 S3 失败: %w", err)
+	}
+	if !exists {
+		return nil, fmt.Errorf("存储桶不存在: %s", opts.Bucket)
+	}
+
+	return &S3Store{client: client, opts: opts}, nil
+}
+
+// objectName key 对应的对象名（加上前缀）
+func (s *S3Store) objectName(key string) string {
+	return strings.TrimPrefix(path.Join(s.opts.Prefix, path.Clean("/"+key)), "/")
+}
+
+// Put 上传对象
+func (s *S3Store) Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error {
+	_, err := s.client.PutObject(ctx, s.opts.Bucket, s.objectName(key), r, size, minio.PutObjectOptions{
+		ContentType: contentType,
+	})
+	if err != nil {
+		return fmt.Errorf("上传对象失败: %w", err)
+	}
+	return nil
+}
+
+// Get 下载对象
+func (s *S3Store) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	// GetObject 不会立即发起请求，先 Stat 以便返回 ErrNotFound
+	if _, err := s.Stat(ctx, key); err != nil {
+		return nil, err
+	}
+	obj, err := s.client.GetObject(ctx, s.opts.Bucket, s.objectName(key), minio.GetObjectOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("下载对象失败: %w", err)
+	}
+	return obj, nil
+}
+
+// Stat 获取对象信息
+func (s *S3Store) Stat(ctx context.Context, key string) (Info, error) {
+	info, err := s.client.StatObject(ctx, s.opts.Bucket, s.objectName(key), minio.StatObjectOptions{})
+	if err != nil {
+		if minio.ToErrorResponse(err).Code == "NoSuchKey" {
+			return Info{}, ErrNotFound
+		}
+		return Info{}, fmt.Errorf("查询对象失败: %w", err)
+	}
+	return Info{Size: info.Size, LastModified: info.LastModified}, nil
+}
+
+// Delete 删除对象
+func (s *S3Store) Delete(ctx context.Context, key string) error {
+	if err := s.client.RemoveObject(ctx, s.opts.Bucket, s.objectName(key), minio.RemoveObjectOptions{}); err != nil {
+		return fmt.Errorf("删除对象失败: %w", err)
+	}
+	return nil
+}
+
+// List 递归列出前缀下的对象
+func (s *S3Store) List(ctx context.Context, prefix string) ([]Info, error) {
+	root := s.objectName("")
+	var infos []Info
+	for obj := range s.client.ListObjects(ctx, s.opts.Bucket, minio.ListObjectsOptions{
+		Prefix:    s.objectName(prefix) + "/",
+		Recursive: true,
+	}) {
+		if obj.Err != nil {
+			return nil, fmt.Errorf("列出对象失败: %w", obj.Err)
+		}
+		key := strings.TrimPrefix(strings.TrimPrefix(obj.Key, root), "/")
+		infos = append(infos, Info{Key: key, Size: obj.Size, LastModified: obj.LastModified})
+	}
+	return infos, nil
+}
+
+// URL 预签名下载地址（浏览器直接从对象存储读取，支持 Range 请求）
+func (s *S3Store) URL(ctx context.Context, key string) (string, error) {
+	u, err := s.client.PresignedGetObject(ctx, s.opts.Bucket, s.objectName(key), s.opts.PresignExpiry, url.Values{})
+	if err != nil {
+		return "", fmt.Errorf("生成预签名地址失败: %w", err)
+	}
+	return u.String(), nil
+}
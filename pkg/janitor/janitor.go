@@ -0,0 +1,342 @@
+package janitor
+
+import (
+	"context"
+	"log"
+	"path"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/z-wentao/voiceflow/pkg/filestore"
+	"github.com/z-wentao/voiceflow/pkg/models"
+	"github.com/z-wentao/voiceflow/pkg/storage"
+)
+
+// Options 清理策略
+type Options struct {
+	MediaAfter time.Duration // 任务完成多久后删除原始媒体（0 表示不删除）
+	JobsAfter  time.Duration // 任务创建多久后删除整个任务（记录、媒体和字幕，0 表示永久保留）
+	Interval   time.Duration // 检查间隔
+	DryRun     bool          // 只打印将要清理的文件，不实际删除
+
+	DeletedAfter     time.Duration // 软删除的任务保留多久后永久删除（0 表示永久保留，期间可以恢复）
+	KeepDeletedFiles bool          // 永久删除任务时保留媒体和字幕文件（调试用）
+	ArchiveAfter     time.Duration // 任务完成多久后移到归档表（0 表示不归档，存储需实现 storage.Archiver）
+}
+
+// uploadsPrefix 上传的媒体和生成的字幕所在的"目录"
+const uploadsPrefix = "uploads"
+
+// Janitor 后台清理器
+// 定期扫描任务，按保留策略删除文件存储中的过期文件；
+// 配置了任务保留期时，删除过期的已结束任务及其文件，并清理没有对应任务的残留文件；
+// 软删除超过保留期的任务连同文件永久删除；完成较久的任务移到归档表
+type Janitor struct {
+	store  storage.Store
+	files  filestore.FileStore
+	opts   Options
+	stopCh chan struct{}
+}
+
+// NewJanitor 创建清理器
+func NewJanitor(store storage.Store, files filestore.FileStore, opts Options) *Janitor {
+	if opts.Interval <= 0 {
+		opts.Interval = time.Hour
+	}
+	if _, ok := store.(storage.Archiver); opts.ArchiveAfter > 0 && !ok {
+		log.Printf("⚠️ 当前存储不支持归档，已忽略归档策略")
+		opts.ArchiveAfter = 0
+	}
+	return &Janitor{
+		store:  store,
+		files:  files,
+		opts:   opts,
+		stopCh: make(chan struct{}),
+	}
+}
+
+// Start 启动清理循环（启动时立即执行一次）
+func (j *Janitor) Start() {
+	go j.run()
+}
+
+// Stop 停止清理循环
+func (j *Janitor) Stop() {
+	close(j.stopCh)
+}
+
+// run 清理主循环（Stop 时取消进行中的存储和文件操作）
+func (j *Janitor) run() {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		<-j.stopCh
+		cancel()
+	}()
+
+	ticker := time.NewTicker(j.opts.Interval)
+	defer ticker.Stop()
+
+	for {
+		j.RunOnce(ctx)
+
+		select {
+		case <-j.stopCh:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce 执行一轮清理
+func (j *Janitor) RunOnce(ctx context.Context) {
+	now := time.Now()
+	if j.opts.DeletedAfter > 0 {
+		j.purgeDeletedJobs(ctx, now.Add(-j.opts.DeletedAfter))
+	}
+	if j.opts.ArchiveAfter > 0 {
+		j.archiveJobs(ctx, now.Add(-j.opts.ArchiveAfter))
+	}
+	if j.opts.MediaAfter <= 0 && j.opts.JobsAfter <= 0 {
+		return
+	}
+
+	jobs, err := j.store.ListAll(ctx)
+	if err != nil {
+		log.Printf("⚠️ 清理器获取任务列表失败: %v", err)
+		return
+	}
+
+	if j.opts.MediaAfter > 0 {
+		j.purgeExpiredMedia(ctx, jobs, now)
+	}
+	if j.opts.JobsAfter > 0 {
+		cutoff := now.Add(-j.opts.JobsAfter)
+		j.deleteExpiredJobs(ctx, jobs, cutoff)
+		j.deleteOrphanFiles(ctx, cutoff)
+	}
+}
+
+// purgeExpiredMedia 删除超过保留期的原始媒体，返回清理的任务数
+func (j *Janitor) purgeExpiredMedia(ctx context.Context, jobs []*models.TranscriptionJob, now time.Time) int {
+	purged := 0
+	for _, job := range jobs {
+		if !mediaExpired(job, now, j.opts.MediaAfter) {
+			continue
+		}
+		if j.opts.DryRun {
+			log.Printf("🧹 [dry-run] 将清理原始媒体: %s (任务 %s)", job.FilePath, job.JobID)
+			purged++
+			continue
+		}
+		if err := j.purgeMedia(ctx, job.JobID); err != nil {
+			log.Printf("⚠️ 清理原始媒体失败 (任务 %s): %v", job.JobID, err)
+			continue
+		}
+		purged++
+	}
+
+	if purged > 0 {
+		log.Printf("🧹 本轮清理原始媒体 %d 个 (dry-run: %v)", purged, j.opts.DryRun)
+	}
+	return purged
+}
+
+// deleteExpiredJobs 删除创建时间早于 cutoff 的已结束任务：先删除文件，再由存储层批量删除记录
+// 列表中没有的过期任务（如 Redis 只返回最近的任务）由 deleteOrphanFiles 在之后清理文件
+func (j *Janitor) deleteExpiredJobs(ctx context.Context, jobs []*models.TranscriptionJob, cutoff time.Time) int {
+	expired := 0
+	for _, job := range jobs {
+		if !jobExpired(job, cutoff) {
+			continue
+		}
+		expired++
+		if j.opts.DryRun {
+			log.Printf("🧹 [dry-run] 将删除过期任务: %s (%s, 创建于 %s)", job.JobID, job.Filename, job.CreatedAt.Format(time.DateOnly))
+			continue
+		}
+		j.deleteJobFiles(ctx, job)
+	}
+
+	if j.opts.DryRun {
+		if expired > 0 {
+			log.Printf("🧹 [dry-run] 本轮将删除过期任务 %d 个", expired)
+		}
+		return expired
+	}
+
+	deleted, err := j.store.DeleteOlderThan(ctx, cutoff)
+	if err != nil {
+		log.Printf("⚠️ 删除过期任务失败: %v", err)
+		return 0
+	}
+	if deleted > 0 {
+		log.Printf("🧹 本轮删除过期任务 %d 个 (创建早于 %s)", deleted, cutoff.Format(time.DateOnly))
+	}
+	return deleted
+}
+
+// purgeDeletedJobs 永久删除删除时间早于 cutoff 的软删除任务：先删除文件，再删除记录，返回删除的任务数
+func (j *Janitor) purgeDeletedJobs(ctx context.Context, cutoff time.Time) int {
+	jobs, err := j.store.ListDeleted(ctx, cutoff)
+	if err != nil {
+		log.Printf("⚠️ 清理器获取已删除任务失败: %v", err)
+		return 0
+	}
+
+	purged := 0
+	for _, job := range jobs {
+		if j.opts.DryRun {
+			log.Printf("🧹 [dry-run] 将永久删除任务: %s (%s, 删除于 %s)", job.JobID, job.Filename, job.DeletedAt.Format(time.DateOnly))
+			purged++
+			continue
+		}
+		if !j.opts.KeepDeletedFiles {
+			j.deleteJobFiles(ctx, job)
+		}
+		if err := j.store.Purge(ctx, job.JobID); err != nil {
+			log.Printf("⚠️ 永久删除任务失败 (任务 %s): %v", job.JobID, err)
+			continue
+		}
+		purged++
+	}
+
+	if purged > 0 {
+		log.Printf("🧹 本轮永久删除已删除的任务 %d 个 (删除早于 %s, dry-run: %v)", purged, cutoff.Format(time.DateOnly), j.opts.DryRun)
+	}
+	return purged
+}
+
+// archiveJobs 把完成时间早于 cutoff 的任务移到归档表（记录移动，文件不变），返回归档的任务数
+func (j *Janitor) archiveJobs(ctx context.Context, cutoff time.Time) int {
+	if j.opts.DryRun {
+		log.Printf("🧹 [dry-run] 将归档完成早于 %s 的任务", cutoff.Format(time.DateOnly))
+		return 0
+	}
+
+	archived, err := j.store.(storage.Archiver).MoveToArchive(ctx, cutoff)
+	if err != nil {
+		log.Printf("⚠️ 归档任务失败 (已归档 %d 个): %v", archived, err)
+		return archived
+	}
+	if archived > 0 {
+		log.Printf("🗄️ 本轮归档任务 %d 个 (完成早于 %s)", archived, cutoff.Format(time.DateOnly))
+	}
+	return archived
+}
+
+// deleteJobFiles 删除任务的媒体和字幕文件（已不存在的文件视为成功）
+func (j *Janitor) deleteJobFiles(ctx context.Context, job *models.TranscriptionJob) {
+	keys := []string{job.FilePath, job.SubtitlePath, job.VTTPath, job.BilingualSRTPath, job.BilingualVTTPath}
+	for _, key := range keys {
+		if key == "" {
+			continue
+		}
+		if err := j.files.Delete(ctx, key); err != nil {
+			log.Printf("⚠️ 删除文件失败: %s: %v", key, err)
+		}
+	}
+}
+
+// deleteOrphanFiles 删除早于 cutoff、对应任务已不存在的文件
+// （任务已因 Redis TTL 过期、被其他实例删除，或清理文件失败后残留）。
+// 文件名以任务 ID 开头（uploads/<job_id>.mp3、uploads/<job_id>.srt 等），其他文件不处理；
+// 存储不可用时跳过，避免把查询失败误判为任务不存在
+func (j *Janitor) deleteOrphanFiles(ctx context.Context, cutoff time.Time) int {
+	if err := j.store.Ping(ctx); err != nil {
+		log.Printf("⚠️ 存储不可用，跳过残留文件清理: %v", err)
+		return 0
+	}
+
+	files, err := j.files.List(ctx, uploadsPrefix)
+	if err != nil {
+		log.Printf("⚠️ 清理器列出文件失败: %v", err)
+		return 0
+	}
+
+	exists := make(map[string]bool)
+	removed := 0
+	for _, file := range files {
+		if !file.LastModified.Before(cutoff) {
+			continue
+		}
+		jobID, ok := jobIDFromKey(file.Key)
+		if !ok {
+			continue
+		}
+		found, checked := exists[jobID]
+		if !checked {
+			_, err := j.store.Get(ctx, jobID)
+			found = err == nil
+			exists[jobID] = found
+		}
+		if found {
+			continue
+		}
+
+		if j.opts.DryRun {
+			log.Printf("🧹 [dry-run] 将删除残留文件: %s", file.Key)
+			removed++
+			continue
+		}
+		if err := j.files.Delete(ctx, file.Key); err != nil {
+			log.Printf("⚠️ 删除残留文件失败: %s: %v", file.Key, err)
+			continue
+		}
+		removed++
+	}
+
+	if removed > 0 {
+		log.Printf("🧹 本轮清理残留文件 %d 个 (dry-run: %v)", removed, j.opts.DryRun)
+	}
+	return removed
+}
+
+// jobIDFromKey 从文件 key 中解析任务 ID（文件名的前 36 个字符为 UUID）
+func jobIDFromKey(key string) (string, bool) {
+	name := path.Base(key)
+	if len(name) < 36 {
+		return "", false
+	}
+	if _, err := uuid.Parse(name[:36]); err != nil {
+		return "", false
+	}
+	return name[:36], true
+}
+
+// jobExpired 判断任务是否已结束且创建时间早于 cutoff
+func jobExpired(job *models.TranscriptionJob, cutoff time.Time) bool {
+	return (job.Status == models.StatusCompleted || job.Status == models.StatusFailed) &&
+		job.CreatedAt.Before(cutoff)
+}
+
+// mediaExpired 判断已完成任务的原始媒体是否超过保留期
+func mediaExpired(job *models.TranscriptionJob, now time.Time, after time.Duration) bool {
+	return job.Status == models.StatusCompleted &&
+		!job.MediaPurged &&
+		job.FilePath != "" &&
+		!job.CompletedAt.IsZero() &&
+		now.Sub(job.CompletedAt) >= after
+}
+
+// purgeMedia 删除原始媒体文件并标记任务
+// 已完成任务的修改使用 Save（混合存储的 Update 只在进入终态时同步数据库），
+// 删除文件期间任务被修改过时重新读取后再标记，不覆盖其他修改
+func (j *Janitor) purgeMedia(ctx context.Context, jobID string) error {
+	job, err := j.store.Get(ctx, jobID)
+	if err != nil {
+		return err
+	}
+
+	if err := j.files.Delete(ctx, job.FilePath); err != nil {
+		return err
+	}
+	log.Printf("🧹 已清理原始媒体: %s (任务 %s)", job.FilePath, job.JobID)
+
+	_, err = storage.Modify(ctx, j.store, jobID, func(latest *models.TranscriptionJob) error {
+		latest.FilePath = ""
+		latest.MediaPurged = true
+		return nil
+	})
+	return err
+}
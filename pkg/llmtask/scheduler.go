@@ -0,0 +1,259 @@
+package llmtask
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// State 子任务状态
+type State string
+
+const (
+	StateRunning   State = "running"
+	StateCompleted State = "completed"
+	StateFailed    State = "failed"
+	StateCancelled State = "cancelled"
+)
+
+// ErrTaskFinished 任务已结束（或正在提交结果），无法取消
+var ErrTaskFinished = errors.New("任务已结束，无法取消")
+
+// Task 调用大模型的子任务（单词提取、摘要、翻译等）
+type Task struct {
+	ID         string    `json:"id"`
+	JobID      string    `json:"job_id"`
+	Kind       string    `json:"kind"` // 任务类型，如 vocabulary
+	State      State     `json:"state"`
+	Error      string    `json:"error,omitempty"`
+	Detail     string    `json:"detail,omitempty"` // 结果说明（如过滤掉的单词数），由 SetDetail 设置
+	StartedAt  time.Time `json:"started_at"`
+	FinishedAt time.Time `json:"finished_at,omitempty"`
+
+	cancel     context.CancelFunc
+	committing bool          // 正在提交结果，此后不可取消
+	done       chan struct{} // 任务结束（完成、失败或取消）时关闭
+}
+
+// RunFunc 子任务执行函数
+// 在 ctx 下调用大模型，返回的 commit 函数负责保存结果；
+// 任务被取消时 commit 不会执行，部分结果被丢弃
+type RunFunc func(ctx context.Context) (commit func() error, err error)
+
+// Scheduler 子任务调度器
+// 每个子任务拥有独立的 ID 和 Context，可以随时取消；
+// 结束的任务保留一段时间供前端查询状态，之后自动清理
+type Scheduler struct {
+	mu       sync.Mutex
+	tasks    map[string]*Task
+	timeout  time.Duration // 单个任务超时时间
+	retain   time.Duration // 结束后保留时间
+	onFinish func(Task)    // 任务结束时的回调，见 OnFinish
+}
+
+// NewScheduler 创建子任务调度器
+func NewScheduler(timeout time.Duration) *Scheduler {
+	if timeout <= 0 {
+		timeout = 60 * time.Second
+	}
+	return &Scheduler{
+		tasks:   make(map[string]*Task),
+		timeout: timeout,
+		retain:  10 * time.Minute,
+	}
+}
+
+// Submit 提交子任务（异步执行，使用默认超时时间），返回任务快照
+func (s *Scheduler) Submit(jobID, kind string, run RunFunc) Task {
+	return s.SubmitWithTimeout(jobID, kind, s.timeout, run)
+}
+
+// SubmitWithTimeout 提交子任务并指定超时时间（适用于耗时与内容长度相关的任务，如整片字幕翻译）
+func (s *Scheduler) SubmitWithTimeout(jobID, kind string, timeout time.Duration, run RunFunc) Task {
+	// 使用独立的 context，避免 HTTP 请求结束后 context 被取消
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+
+	task := &Task{
+		ID:        uuid.New().String(),
+		JobID:     jobID,
+		Kind:      kind,
+		State:     StateRunning,
+		StartedAt: time.Now(),
+		cancel:    cancel,
+		done:      make(chan struct{}),
+	}
+
+	s.mu.Lock()
+	s.tasks[task.ID] = task
+	snapshot := *task
+	s.mu.Unlock()
+
+	ctx = context.WithValue(ctx, taskRefKey{}, taskRef{s: s, task: task})
+	go s.run(ctx, task, run)
+
+	return snapshot
+}
+
+// taskRefKey 子任务 context 中保存 taskRef 的 key
+type taskRefKey struct{}
+
+// taskRef 子任务 context 中的任务引用（供 SetDetail 使用）
+type taskRef struct {
+	s    *Scheduler
+	task *Task
+}
+
+// OnFinish 设置任务结束（完成、失败或取消）时的回调，参数为任务的最终快照
+// 回调在执行任务的 goroutine 中调用，需在提交任务之前设置
+func (s *Scheduler) OnFinish(fn func(Task)) {
+	s.mu.Lock()
+	s.onFinish = fn
+	s.mu.Unlock()
+}
+
+// SetDetail 记录子任务的结果说明，在 RunFunc 或其返回的 commit 中以任务的 ctx 调用
+// ctx 不属于任何子任务时不做任何事
+func SetDetail(ctx context.Context, detail string) {
+	ref, ok := ctx.Value(taskRefKey{}).(taskRef)
+	if !ok {
+		return
+	}
+	ref.s.mu.Lock()
+	ref.task.Detail = detail
+	ref.s.mu.Unlock()
+}
+
+// run 执行子任务并记录最终状态
+func (s *Scheduler) run(ctx context.Context, task *Task, run RunFunc) {
+	defer task.cancel()
+
+	commit, err := run(ctx)
+
+	s.mu.Lock()
+	if task.State == StateCancelled {
+		// 已被取消，丢弃结果
+		s.mu.Unlock()
+		log.Printf("🚫 子任务 %s (%s) 已取消，丢弃结果", task.ID, task.Kind)
+		return
+	}
+	if err == nil && commit != nil {
+		task.committing = true
+	}
+	s.mu.Unlock()
+
+	if err == nil && commit != nil {
+		err = commit()
+	}
+
+	s.finish(task, err)
+}
+
+// finish 标记任务结束，并在保留期后清理
+func (s *Scheduler) finish(task *Task, err error) {
+	s.mu.Lock()
+	task.FinishedAt = time.Now()
+	if err != nil {
+		task.State = StateFailed
+		task.Error = err.Error()
+		log.Printf("❌ 子任务 %s (%s) 失败: %v", task.ID, task.Kind, err)
+	} else {
+		task.State = StateCompleted
+	}
+	s.scheduleCleanup(task)
+	snapshot, onFinish := *task, s.onFinish
+	s.mu.Unlock()
+
+	if onFinish != nil {
+		onFinish(snapshot)
+	}
+}
+
+// scheduleCleanup 唤醒等待任务结束的调用方，保留期后从注册表移除（调用方需持有锁）
+func (s *Scheduler) scheduleCleanup(task *Task) {
+	close(task.done)
+	time.AfterFunc(s.retain, func() {
+		s.mu.Lock()
+		delete(s.tasks, task.ID)
+		s.mu.Unlock()
+	})
+}
+
+// Cancel 取消子任务
+func (s *Scheduler) Cancel(jobID, taskID string) (Task, error) {
+	s.mu.Lock()
+	task, ok := s.tasks[taskID]
+	if !ok || task.JobID != jobID {
+		s.mu.Unlock()
+		return Task{}, fmt.Errorf("子任务不存在: %s", taskID)
+	}
+	if task.State != StateRunning || task.committing {
+		s.mu.Unlock()
+		return *task, ErrTaskFinished
+	}
+
+	task.State = StateCancelled
+	task.FinishedAt = time.Now()
+	task.cancel()
+	s.scheduleCleanup(task)
+	snapshot, onFinish := *task, s.onFinish
+	s.mu.Unlock()
+
+	log.Printf("🚫 子任务已取消: %s (%s)", task.ID, task.Kind)
+	if onFinish != nil {
+		onFinish(snapshot)
+	}
+	return snapshot, nil
+}
+
+// Get 获取子任务快照
+func (s *Scheduler) Get(jobID, taskID string) (Task, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	task, ok := s.tasks[taskID]
+	if !ok || task.JobID != jobID {
+		return Task{}, false
+	}
+	return *task, true
+}
+
+// Wait 等待子任务结束并返回最终快照
+// ctx 结束时返回 ctx 的错误（子任务不受影响，继续在后台执行）
+func (s *Scheduler) Wait(ctx context.Context, jobID, taskID string) (Task, error) {
+	s.mu.Lock()
+	task, ok := s.tasks[taskID]
+	s.mu.Unlock()
+	if !ok || task.JobID != jobID {
+		return Task{}, fmt.Errorf("子任务不存在: %s", taskID)
+	}
+
+	select {
+	case <-task.done:
+	case <-ctx.Done():
+		return Task{}, ctx.Err()
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return *task, nil
+}
+
+// CancelAll 取消所有进行中的子任务（关闭时调用）
+func (s *Scheduler) CancelAll() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, task := range s.tasks {
+		if task.State == StateRunning && !task.committing {
+			task.State = StateCancelled
+			task.FinishedAt = time.Now()
+			task.cancel()
+			close(task.done)
+		}
+	}
+}
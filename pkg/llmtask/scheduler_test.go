@@ -0,0 +1,346 @@
+package llmtask
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// slowClient 模拟调用中的大模型客户端：started 在调用开始时关闭，release 关闭后返回结果
+// honorCtx 为 true 时 ctx 取消后立即返回 ctx 的错误，否则忽略取消、照常返回结果
+type slowClient struct {
+	started  chan struct{}
+	release  chan struct{}
+	honorCtx bool
+}
+
+func newSlowClient(honorCtx bool) *slowClient {
+	return &slowClient{started: make(chan struct{}), release: make(chan struct{}), honorCtx: honorCtx}
+}
+
+func (c *slowClient) call(ctx context.Context) error {
+	close(c.started)
+	if c.honorCtx {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-c.release:
+			return nil
+		}
+	}
+	<-c.release
+	return nil
+}
+
+// startTask 按 SubmitWithTimeout 的方式登记任务，由测试同步调用 s.run，run 返回时任务的处理已经全部结束
+func startTask(s *Scheduler, jobID string) (context.Context, *Task) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	task := &Task{ID: "task-1", JobID: jobID, Kind: "vocabulary", State: StateRunning, StartedAt: time.Now(), cancel: cancel, done: make(chan struct{})}
+	s.mu.Lock()
+	s.tasks[task.ID] = task
+	s.mu.Unlock()
+	return context.WithValue(ctx, taskRefKey{}, taskRef{s: s, task: task}), task
+}
+
+// TestCancelDiscardsCommit 调用大模型期间取消的任务不会提交结果，即使客户端忽略取消照常返回
+func TestCancelDiscardsCommit(t *testing.T) {
+	tests := []struct {
+		name     string
+		honorCtx bool
+	}{
+		{"客户端响应取消", true},
+		{"客户端忽略取消返回结果", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewScheduler(time.Minute)
+			finished := make(chan Task, 2)
+			s.OnFinish(func(task Task) { finished <- task })
+			client := newSlowClient(tt.honorCtx)
+			var committed atomic.Bool
+
+			ctx, task := startTask(s, "job-1")
+			cancelled := make(chan struct{})
+			go func() {
+				defer close(cancelled)
+				<-client.started
+				if _, err := s.Cancel("job-1", task.ID); err != nil {
+					t.Errorf("Cancel: %v", err)
+				}
+				close(client.release)
+			}()
+			s.run(ctx, task, func(ctx context.Context) (func() error, error) {
+				if err := client.call(ctx); err != nil {
+					return nil, err
+				}
+				return func() error {
+					committed.Store(true)
+					return nil
+				}, nil
+			})
+			<-cancelled
+
+			if committed.Load() {
+				t.Fatal("已取消的任务提交了结果")
+			}
+			got, ok := s.Get("job-1", task.ID)
+			if !ok {
+				t.Fatal("取消后任务应保留到保留期结束")
+			}
+			if got.State != StateCancelled || got.Error != "" {
+				t.Fatalf("任务状态 = %s (%q)，期望 %s", got.State, got.Error, StateCancelled)
+			}
+			if len(finished) != 1 {
+				t.Fatalf("OnFinish 回调了 %d 次，期望一次", len(finished))
+			}
+			if cb := <-finished; cb.State != StateCancelled {
+				t.Fatalf("OnFinish 回调的状态 = %s，期望 %s", cb.State, StateCancelled)
+			}
+		})
+	}
+}
+
+// TestCancelFinished 正在提交结果或已经结束的任务不能取消
+func TestCancelFinished(t *testing.T) {
+	tests := []struct {
+		name      string
+		runErr    error
+		inCommit  bool // 在 commit 执行期间取消
+		wantState State
+	}{
+		{"提交结果中", nil, true, StateCompleted},
+		{"已完成", nil, false, StateCompleted},
+		{"已失败", errors.New("调用失败"), false, StateFailed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewScheduler(time.Minute)
+			committing := make(chan struct{})
+			release := make(chan struct{})
+			task := s.Submit("job-1", "vocabulary", func(ctx context.Context) (func() error, error) {
+				if tt.runErr != nil {
+					return nil, tt.runErr
+				}
+				return func() error {
+					if tt.inCommit {
+						close(committing)
+						<-release
+					}
+					return nil
+				}, nil
+			})
+
+			if tt.inCommit {
+				<-committing
+				got, err := s.Cancel("job-1", task.ID)
+				if !errors.Is(err, ErrTaskFinished) {
+					t.Fatalf("Cancel() err = %v，期望 ErrTaskFinished", err)
+				}
+				if got.State != StateRunning {
+					t.Fatalf("提交中的任务状态 = %s，期望 %s", got.State, StateRunning)
+				}
+				close(release)
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			if _, err := s.Wait(ctx, "job-1", task.ID); err != nil {
+				t.Fatalf("Wait: %v", err)
+			}
+
+			got, err := s.Cancel("job-1", task.ID)
+			if !errors.Is(err, ErrTaskFinished) {
+				t.Fatalf("Cancel() err = %v，期望 ErrTaskFinished", err)
+			}
+			if got.State != tt.wantState {
+				t.Fatalf("任务状态 = %s，期望 %s", got.State, tt.wantState)
+			}
+		})
+	}
+}
+
+// TestWaitAndOnFinish Wait 返回任务的最终快照，OnFinish 对每个任务恰好回调一次
+func TestWaitAndOnFinish(t *testing.T) {
+	tests := []struct {
+		name       string
+		run        RunFunc
+		cancel     bool // 提交后立即取消（run 阻塞到 ctx 取消）
+		wantState  State
+		wantError  string
+		wantDetail string
+	}{
+		{
+			name: "完成",
+			run: func(ctx context.Context) (func() error, error) {
+				SetDetail(ctx, "提取 3 个单词")
+				return func() error { return nil }, nil
+			},
+			wantState:  StateCompleted,
+			wantDetail: "提取 3 个单词",
+		},
+		{
+			name: "没有需要提交的结果",
+			run: func(ctx context.Context) (func() error, error) {
+				return nil, nil
+			},
+			wantState: StateCompleted,
+		},
+		{
+			name: "调用失败",
+			run: func(ctx context.Context) (func() error, error) {
+				return nil, errors.New("请求超时")
+			},
+			wantState: StateFailed,
+			wantError: "请求超时",
+		},
+		{
+			name: "提交失败",
+			run: func(ctx context.Context) (func() error, error) {
+				return func() error { return errors.New("保存失败") }, nil
+			},
+			wantState: StateFailed,
+			wantError: "保存失败",
+		},
+		{
+			name: "取消",
+			run: func(ctx context.Context) (func() error, error) {
+				<-ctx.Done()
+				return nil, ctx.Err()
+			},
+			cancel:    true,
+			wantState: StateCancelled,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewScheduler(time.Minute)
+			finished := make(chan Task, 2)
+			s.OnFinish(func(task Task) { finished <- task })
+
+			task := s.Submit("job-1", "vocabulary", tt.run)
+			if task.State != StateRunning {
+				t.Fatalf("提交时的状态 = %s，期望 %s", task.State, StateRunning)
+			}
+			if tt.cancel {
+				if _, err := s.Cancel("job-1", task.ID); err != nil {
+					t.Fatalf("Cancel: %v", err)
+				}
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			got, err := s.Wait(ctx, "job-1", task.ID)
+			if err != nil {
+				t.Fatalf("Wait: %v", err)
+			}
+			if got.State != tt.wantState || got.Error != tt.wantError || got.Detail != tt.wantDetail {
+				t.Fatalf("Wait() = {%s %q %q}，期望 {%s %q %q}", got.State, got.Error, got.Detail, tt.wantState, tt.wantError, tt.wantDetail)
+			}
+			if got.FinishedAt.IsZero() {
+				t.Fatal("结束的任务没有记录结束时间")
+			}
+
+			select {
+			case cb := <-finished:
+				if cb.ID != task.ID || cb.State != tt.wantState {
+					t.Fatalf("OnFinish 回调 = {%s %s}，期望 {%s %s}", cb.ID, cb.State, task.ID, tt.wantState)
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatal("没有收到 OnFinish 回调")
+			}
+			// 取消的任务 run 返回后不会再次回调
+			time.Sleep(20 * time.Millisecond)
+			if len(finished) != 0 {
+				t.Fatalf("OnFinish 回调了 %d 次以上", 1+len(finished))
+			}
+		})
+	}
+}
+
+// TestWaitErrors Wait 的 ctx 结束时返回 ctx 的错误，任务不存在或不属于该任务时返回错误
+func TestWaitErrors(t *testing.T) {
+	s := NewScheduler(time.Minute)
+	release := make(chan struct{})
+	defer close(release)
+	task := s.Submit("job-1", "vocabulary", func(ctx context.Context) (func() error, error) {
+		<-release
+		return nil, nil
+	})
+
+	expired, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	tests := []struct {
+		name    string
+		ctx     context.Context
+		jobID   string
+		taskID  string
+		wantErr error
+	}{
+		{"等待超时", expired, "job-1", task.ID, context.DeadlineExceeded},
+		{"任务不存在", context.Background(), "job-1", "missing", nil},
+		{"其他任务的子任务", context.Background(), "job-2", task.ID, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := s.Wait(tt.ctx, tt.jobID, tt.taskID)
+			if err == nil {
+				t.Fatal("Wait() 期望返回错误")
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Wait() err = %v，期望 %v", err, tt.wantErr)
+			}
+		})
+	}
+	if got, ok := s.Get("job-1", task.ID); !ok || got.State != StateRunning {
+		t.Fatalf("等待超时不应影响任务: %+v", got)
+	}
+}
+
+// TestCleanupAfterRetain 结束的任务在保留期内可以查询，之后从注册表移除
+func TestCleanupAfterRetain(t *testing.T) {
+	tests := []struct {
+		name   string
+		cancel bool
+	}{
+		{"完成", false},
+		{"取消", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewScheduler(time.Minute)
+			s.retain = 50 * time.Millisecond
+			task := s.Submit("job-1", "vocabulary", func(ctx context.Context) (func() error, error) {
+				if tt.cancel {
+					<-ctx.Done()
+					return nil, ctx.Err()
+				}
+				return nil, nil
+			})
+			if tt.cancel {
+				if _, err := s.Cancel("job-1", task.ID); err != nil {
+					t.Fatalf("Cancel: %v", err)
+				}
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			if _, err := s.Wait(ctx, "job-1", task.ID); err != nil {
+				t.Fatalf("Wait: %v", err)
+			}
+			if _, ok := s.Get("job-1", task.ID); !ok {
+				t.Fatal("保留期内应能查询到结束的任务")
+			}
+
+			deadline := time.Now().Add(5 * time.Second)
+			for {
+				if _, ok := s.Get("job-1", task.ID); !ok {
+					break
+				}
+				if time.Now().After(deadline) {
+					t.Fatal("保留期后任务没有被清理")
+				}
+				time.Sleep(10 * time.Millisecond)
+			}
+		})
+	}
+}
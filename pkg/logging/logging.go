@@ -0,0 +1,66 @@
+// Package logging 基于 log/slog 的结构化日志
+//
+// 两种输出格式：
+//   - console：本地开发使用，保留 "✓ 消息" 风格的可读输出，结构化字段以 key=value 追加在行尾
+//   - json：生产环境使用，每行一个 JSON 对象，便于日志系统按 job_id、worker_id 等字段检索和过滤
+//
+// main 中调用 slog.SetDefault 后，仍在使用 log.Printf 的代码也会经由同一个 Handler 以 INFO 级别输出
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+)
+
+// New 创建日志记录器
+// level: debug / info / warn / error；format: console / json
+func New(w io.Writer, level, format string) (*slog.Logger, error) {
+	lvl, err := ParseLevel(level)
+	if err != nil {
+		return nil, err
+	}
+
+	switch format {
+	case "", "console":
+		return slog.New(NewConsoleHandler(w, lvl)), nil
+	case "json":
+		return slog.New(slog.NewJSONHandler(w, &slog.HandlerOptions{Level: lvl})), nil
+	default:
+		return nil, fmt.Errorf("不支持的日志格式: %q", format)
+	}
+}
+
+// ParseLevel 解析日志级别（不区分大小写，空字符串为 info）
+func ParseLevel(level string) (slog.Level, error) {
+	switch strings.ToLower(level) {
+	case "debug":
+		return slog.LevelDebug, nil
+	case "", "info":
+		return slog.LevelInfo, nil
+	case "warn", "warning":
+		return slog.LevelWarn, nil
+	case "error":
+		return slog.LevelError, nil
+	default:
+		return 0, fmt.Errorf("不支持的日志级别: %q", level)
+	}
+}
+
+// contextKey 日志记录器在 context 中的 key
+type contextKey struct{}
+
+// WithLogger 将日志记录器放入 context（如带有 job_id、worker_id 字段的记录器），供下游代码取用
+func WithLogger(ctx context.Context, logger *slog.Logger) context.Context {
+	return context.WithValue(ctx, contextKey{}, logger)
+}
+
+// FromContext 取出 context 中的日志记录器，没有时返回默认记录器
+func FromContext(ctx context.Context) *slog.Logger {
+	if logger, ok := ctx.Value(contextKey{}).(*slog.Logger); ok {
+		return logger
+	}
+	return slog.Default()
+}
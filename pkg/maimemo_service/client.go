@@ -4,26 +4,88 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"slices"
+	"strings"
+	"sync"
 	"time"
 )
 
+// 默认的请求限制
+const (
+	DefaultMaxWordsPerRequest = 100
+	DefaultRequestInterval    = 500 * time.Millisecond
+)
+
+// ErrNotepadFull 云词本内容已达到 Limits.MaxNotepadBytes
+var ErrNotepadFull = errors.New("云词本已满")
+
+// Limits 请求限制（墨墨开放 API 会限流，也不接受过大的云词本内容）
+type Limits struct {
+	MaxWordsPerRequest int           // 每次提交的单词数上限，超过时分批提交（<= 0 时为 DefaultMaxWordsPerRequest）
+	MaxNotepadBytes    int           // 云词本内容大小上限（字节），0 表示不检查
+	RequestInterval    time.Duration // 相邻两次请求的最小间隔（< 0 时不限制，0 时为 DefaultRequestInterval）
+}
+
 // Client Maimemo 微服务客户端
 type Client struct {
 	baseURL    string
 	httpClient *http.Client
+	limits     Limits
+
+	mu       sync.Mutex // 保护 lastCall，所有请求按 RequestInterval 排队
+	lastCall time.Time
 }
 
-// NewClient 创建 Maimemo 微服务客户端
+// NewClient 创建 Maimemo 微服务客户端（使用默认的请求限制）
 func NewClient(baseURL string) *Client {
-	return &Client{
+	c := &Client{
 		baseURL: baseURL,
 		httpClient: &http.Client{
 			Timeout: 30 * time.Second,
 		},
 	}
+	c.SetLimits(Limits{})
+	return c
+}
+
+// SetLimits 设置请求限制（零值字段使用默认值），需在发出请求之前调用
+func (c *Client) SetLimits(limits Limits) {
+	if limits.MaxWordsPerRequest <= 0 {
+		limits.MaxWordsPerRequest = DefaultMaxWordsPerRequest
+	}
+	if limits.RequestInterval == 0 {
+		limits.RequestInterval = DefaultRequestInterval
+	}
+	c.limits = limits
+}
+
+// do 发送请求，与上一次请求的间隔不足 RequestInterval 时先等待（等待期间 context 取消时返回错误）
+func (c *Client) do(req *http.Request) (*http.Response, error) {
+	if interval := c.limits.RequestInterval; interval > 0 {
+		c.mu.Lock()
+		next := c.lastCall.Add(interval)
+		now := time.Now()
+		if next.Before(now) {
+			next = now
+		}
+		c.lastCall = next
+		c.mu.Unlock()
+
+		if wait := time.Until(next); wait > 0 {
+			timer := time.NewTimer(wait)
+			select {
+			case <-req.Context().Done():
+				timer.Stop()
+				return nil, req.Context().Err()
+			case <-timer.C:
+			}
+		}
+	}
+	return c.httpClient.Do(req)
 }
 
 // Notepad 云词本
@@ -45,6 +107,22 @@ type ListNotepadsResponse struct {
 	Count    int       `json:"count"`
 }
 
+// GetNotepadResponse 云词本详情响应（包含内容）
+type GetNotepadResponse struct {
+	Notepad Notepad `json:"notepad"`
+}
+
+// CreateNotepadRequest 创建云词本请求
+type CreateNotepadRequest struct {
+	Title string `json:"title"`
+	Brief string `json:"brief"`
+}
+
+// CreateNotepadResponse 创建云词本响应
+type CreateNotepadResponse struct {
+	Notepad Notepad `json:"notepad"`
+}
+
 // AddWordsRequest 添加单词请求
 type AddWordsRequest struct {
 	Token     string   `json:"token"`
@@ -58,6 +136,11 @@ type AddWordsResponse struct {
 	Count   int    `json:"count"`
 }
 
+// UpdateNotepadRequest 更新云词本请求
+type UpdateNotepadRequest struct {
+	Notepad Notepad `json:"notepad"`
+}
+
 // ErrorResponse 错误响应
 type ErrorResponse struct {
 	Error string `json:"error"`
@@ -75,7 +158,7 @@ func (c *Client) ListNotepads(ctx context.Context, token string) ([]Notepad, err
 	req.Header.Set("X-Maimemo-Token", token)
 	req.Header.Set("Content-Type", "application/json")
 
-	resp, err := c.httpClient.Do(req)
+	resp, err := c.do(req)
 	if err != nil {
 		return nil, fmt.Errorf("请求失败: %w", err)
 	}
@@ -102,8 +185,167 @@ func (c *Client) ListNotepads(ctx context.Context, token string) ([]Notepad, err
 	return result.Notepads, nil
 }
 
-// AddWordsToNotepad 添加单词到云词本
-func (c *Client) AddWordsToNotepad(ctx context.Context, token, notepadID string, words []string) error {
+// CreateNotepad 创建云词本，返回新云词本的 ID
+func (c *Client) CreateNotepad(ctx context.Context, token, title, brief string) (string, error) {
+	url := fmt.Sprintf("%s/api/v1/notepads", c.baseURL)
+
+	reqBody := CreateNotepadRequest{
+		Title: title,
+		Brief: brief,
+	}
+
+	jsonData, err := json.Marshal(reqBody)
+	if err != nil {
+		return "", fmt.Errorf("序列化请求失败: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return "", fmt.Errorf("创建请求失败: %w", err)
+	}
+
+	req.Header.Set("X-Maimemo-Token", token)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.do(req)
+	if err != nil {
+		return "", fmt.Errorf("请求失败: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("读取响应失败: %w", err)
+	}
+
+	// 创建成功可能返回 200 或 201
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+		var errResp ErrorResponse
+		if err := json.Unmarshal(body, &errResp); err == nil {
+			return "", fmt.Errorf("API 错误: %s", errResp.Error)
+		}
+		return "", fmt.Errorf("API 返回错误: %d - %s", resp.StatusCode, string(body))
+	}
+
+	var result CreateNotepadResponse
+	if err := json.Unmarshal(body, &result); err != nil {
+		return "", fmt.Errorf("解析响应失败: %w", err)
+	}
+	if result.Notepad.ID == "" {
+		return "", fmt.Errorf("响应中缺少云词本 ID: %s", string(body))
+	}
+
+	return result.Notepad.ID, nil
+}
+
+// GetNotepad 获取云词本详情（包含内容）
+func (c *Client) GetNotepad(ctx context.Context, token, notepadID string) (*Notepad, error) {
+	url := fmt.Sprintf("%s/api/v1/notepads/%s", c.baseURL, notepadID)
+
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("创建请求失败: %w", err)
+	}
+
+	req.Header.Set("X-Maimemo-Token", token)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.do(req)
+	if err != nil {
+		return nil, fmt.Errorf("请求失败: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("读取响应失败: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		var errResp ErrorResponse
+		if err := json.Unmarshal(body, &errResp); err == nil {
+			return nil, fmt.Errorf("API 错误: %s", errResp.Error)
+		}
+		return nil, fmt.Errorf("API 返回错误: %d - %s", resp.StatusCode, string(body))
+	}
+
+	var result GetNotepadResponse
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("解析响应失败: %w", err)
+	}
+
+	return &result.Notepad, nil
+}
+
+// NotepadWords 解析云词本内容中的单词（小写）
+// 云词本内容每行一个单词，以 # 开头的行是日期标题（如 #2025-01-01），不计入
+func NotepadWords(content string) map[string]bool {
+	words := make(map[string]bool)
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		words[strings.ToLower(line)] = true
+	}
+	return words
+}
+
+// AddWordsToNotepad 添加单词到云词本，返回实际添加和跳过的单词数
+// 先读取云词本内容，已存在的单词（不区分大小写）和重复提交的单词跳过，只把新单词追加到今天的日期下，
+// 同一任务多次同步不会重复添加。新单词按 Limits.MaxWordsPerRequest 分批提交；
+// 设置了 Limits.MaxNotepadBytes 时只添加放得下的单词，其余返回 ErrNotepadFull（added 为已添加的数量）
+func (c *Client) AddWordsToNotepad(ctx context.Context, token, notepadID string, words []string) (added, skipped int, err error) {
+	notepad, err := c.GetNotepad(ctx, token, notepadID)
+	if err != nil {
+		return 0, 0, fmt.Errorf("读取云词本失败: %w", err)
+	}
+
+	existing := NotepadWords(notepad.Content)
+	newWords := make([]string, 0, len(words))
+	for _, word := range words {
+		key := strings.ToLower(strings.TrimSpace(word))
+		if key == "" || existing[key] {
+			continue
+		}
+		existing[key] = true
+		newWords = append(newWords, word)
+	}
+	skipped = len(words) - len(newWords)
+	if len(newWords) == 0 {
+		return 0, skipped, nil
+	}
+
+	// 云词本容量：估算追加后的内容大小（每个单词一行，加上今天的日期标题），只保留放得下的单词
+	var full error
+	if limit := c.limits.MaxNotepadBytes; limit > 0 {
+		size := len(notepad.Content) + len("\n#2006-01-02\n")
+		fit := 0
+		for _, word := range newWords {
+			size += len(word) + 1
+			if size > limit {
+				break
+			}
+			fit++
+		}
+		if fit < len(newWords) {
+			full = fmt.Errorf("%w（%d / %d 字节），%d 个单词未添加", ErrNotepadFull, len(notepad.Content), limit, len(newWords)-fit)
+			newWords = newWords[:fit]
+		}
+	}
+
+	for chunk := range slices.Chunk(newWords, c.limits.MaxWordsPerRequest) {
+		if err := c.addWords(ctx, token, notepadID, chunk); err != nil {
+			return added, skipped, err
+		}
+		added += len(chunk)
+	}
+
+	return added, skipped, full
+}
+
+// addWords 提交一批单词（POST /api/v1/notepads/:id/words）
+func (c *Client) addWords(ctx context.Context, token, notepadID string, words []string) error {
 	url := fmt.Sprintf("%s/api/v1/notepads/%s/words", c.baseURL, notepadID)
 
 	reqBody := AddWordsRequest{
@@ -124,7 +366,7 @@ func (c *Client) AddWordsToNotepad(ctx context.Context, token, notepadID string,
 
 	req.Header.Set("Content-Type", "application/json")
 
-	resp, err := c.httpClient.Do(req)
+	resp, err := c.do(req)
 	if err != nil {
 		return fmt.Errorf("请求失败: %w", err)
 	}
@@ -150,3 +392,87 @@ func (c *Client) AddWordsToNotepad(ctx context.Context, token, notepadID string,
 
 	return nil
 }
+
+// RemoveWordsFromNotepad 从云词本中删除单词，返回实际删除的单词数（不在云词本中的单词不计入）
+// 读取云词本内容，只删除与单词完全相同的行（不区分大小写，忽略首尾空白），#日期 标题行和包含该单词的其他行（如词组）保留，
+// 再写回云词本；没有需要删除的单词时不写回
+func (c *Client) RemoveWordsFromNotepad(ctx context.Context, token, notepadID string, words []string) (int, error) {
+	notepad, err := c.GetNotepad(ctx, token, notepadID)
+	if err != nil {
+		return 0, fmt.Errorf("读取云词本失败: %w", err)
+	}
+
+	content, removed := RemoveNotepadWords(notepad.Content, words)
+	if removed == 0 {
+		return 0, nil
+	}
+
+	notepad.Content = content
+	if err := c.UpdateNotepad(ctx, token, notepad); err != nil {
+		return 0, fmt.Errorf("写回云词本失败: %w", err)
+	}
+	return removed, nil
+}
+
+// RemoveNotepadWords 从云词本内容中删除单词所在的行，返回新的内容和删除的单词数（同一单词出现在多行时全部删除，只计一次）
+// 只删除整行与单词相同的行（不区分大小写），以 # 开头的日期标题行原样保留
+func RemoveNotepadWords(content string, words []string) (string, int) {
+	targets := make(map[string]bool, len(words))
+	for _, word := range words {
+		if key := strings.ToLower(strings.TrimSpace(word)); key != "" {
+			targets[key] = true
+		}
+	}
+
+	lines := strings.Split(content, "\n")
+	kept := make([]string, 0, len(lines))
+	found := make(map[string]bool)
+	for _, line := range lines {
+		key := strings.ToLower(strings.TrimSpace(line))
+		if !strings.HasPrefix(key, "#") && targets[key] {
+			found[key] = true
+			continue
+		}
+		kept = append(kept, line)
+	}
+	return strings.Join(kept, "\n"), len(found)
+}
+
+// UpdateNotepad 更新云词本（PUT /api/v1/notepads/:id，请求体与 GetNotepad 的响应相同，整体替换标题、简介和内容）
+func (c *Client) UpdateNotepad(ctx context.Context, token string, notepad *Notepad) error {
+	url := fmt.Sprintf("%s/api/v1/notepads/%s", c.baseURL, notepad.ID)
+
+	jsonData, err := json.Marshal(UpdateNotepadRequest{Notepad: *notepad})
+	if err != nil {
+		return fmt.Errorf("序列化请求失败: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "PUT", url, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return fmt.Errorf("创建请求失败: %w", err)
+	}
+
+	req.Header.Set("X-Maimemo-Token", token)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.do(req)
+	if err != nil {
+		return fmt.Errorf("请求失败: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("读取响应失败: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		var errResp ErrorResponse
+		if err := json.Unmarshal(body, &errResp); err == nil {
+			return fmt.Errorf("API 错误: %s", errResp.Error)
+		}
+		return fmt.Errorf("API 返回错误: %d - %s", resp.StatusCode, string(body))
+	}
+
+	return nil
+}
@@ -0,0 +1,249 @@
+package maimemo_service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestRemoveNotepadWords(t *testing.T) {
+	tests := []struct {
+		name        string
+		content     string
+		words       []string
+		wantContent string
+		wantRemoved int
+	}{
+		{
+			name:        "保留日期标题和其他单词",
+			content:     "#2025-01-01\napple\nbanana\n#2025-01-02\ncherry",
+			words:       []string{"banana"},
+			wantContent: "#2025-01-01\napple\n#2025-01-02\ncherry",
+			wantRemoved: 1,
+		},
+		{
+			name:        "只删除整行相同的单词，词组保留",
+			content:     "#2025-01-01\nrun\nrun out of\nrunner",
+			words:       []string{"run"},
+			wantContent: "#2025-01-01\nrun out of\nrunner",
+			wantRemoved: 1,
+		},
+		{
+			name:        "不区分大小写，忽略首尾空白",
+			content:     "#2025-01-01\n  Apple \nbanana",
+			words:       []string{"APPLE"},
+			wantContent: "#2025-01-01\nbanana",
+			wantRemoved: 1,
+		},
+		{
+			name:        "同一单词出现在多个日期下全部删除，只计一次",
+			content:     "#2025-01-01\napple\n#2025-01-02\napple\nbanana",
+			words:       []string{"apple", "apple"},
+			wantContent: "#2025-01-01\n#2025-01-02\nbanana",
+			wantRemoved: 1,
+		},
+		{
+			name:        "日期标题不会被当作单词删除",
+			content:     "#2025-01-01\napple",
+			words:       []string{"#2025-01-01"},
+			wantContent: "#2025-01-01\napple",
+			wantRemoved: 0,
+		},
+		{
+			name:        "不在云词本中的单词不计入",
+			content:     "#2025-01-01\napple\n",
+			words:       []string{"banana", ""},
+			wantContent: "#2025-01-01\napple\n",
+			wantRemoved: 0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			content, removed := RemoveNotepadWords(tt.content, tt.words)
+			if content != tt.wantContent || removed != tt.wantRemoved {
+				t.Fatalf("RemoveNotepadWords = (%q, %d)，期望 (%q, %d)", content, removed, tt.wantContent, tt.wantRemoved)
+			}
+		})
+	}
+}
+
+// fakeNotepadService 只实现 GET / PUT /api/v1/notepads/:id 和 POST /api/v1/notepads/:id/words 的微服务
+type fakeNotepadService struct {
+	notepad Notepad
+	puts    int
+	posts   [][]string // 每次 POST 提交的单词
+}
+
+func (s *fakeNotepadService) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodPost && r.URL.Path == "/api/v1/notepads/"+s.notepad.ID+"/words" {
+		var req AddWordsRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Token != "token" {
+			http.Error(w, `{"error":"bad request"}`, http.StatusBadRequest)
+			return
+		}
+		s.posts = append(s.posts, req.Words)
+		s.notepad.Content += "\n#2025-06-01\n" + strings.Join(req.Words, "\n")
+		json.NewEncoder(w).Encode(AddWordsResponse{Message: "ok", Count: len(req.Words)})
+		return
+	}
+	if r.URL.Path != "/api/v1/notepads/"+s.notepad.ID || r.Header.Get("X-Maimemo-Token") != "token" {
+		http.Error(w, `{"error":"not found"}`, http.StatusNotFound)
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+		json.NewEncoder(w).Encode(GetNotepadResponse{Notepad: s.notepad})
+	case http.MethodPut:
+		var req UpdateNotepadRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, `{"error":"bad request"}`, http.StatusBadRequest)
+			return
+		}
+		s.notepad = req.Notepad
+		s.puts++
+		w.Write([]byte(`{}`))
+	default:
+		http.Error(w, `{"error":"method not allowed"}`, http.StatusMethodNotAllowed)
+	}
+}
+
+func TestRemoveWordsFromNotepad(t *testing.T) {
+	service := &fakeNotepadService{notepad: Notepad{ID: "np-1", Title: "听力生词", Content: "#2025-01-01\napple\nbanana"}}
+	server := httptest.NewServer(service)
+	defer server.Close()
+
+	client := NewClient(server.URL)
+	client.SetLimits(Limits{RequestInterval: -1})
+
+	removed, err := client.RemoveWordsFromNotepad(context.Background(), "token", "np-1", []string{"apple", "durian"})
+	if err != nil {
+		t.Fatalf("RemoveWordsFromNotepad: %v", err)
+	}
+	if removed != 1 || service.notepad.Content != "#2025-01-01\nbanana" || service.notepad.Title != "听力生词" {
+		t.Fatalf("删除 %d 个，写回的云词本: %+v", removed, service.notepad)
+	}
+
+	// 没有需要删除的单词时不写回
+	removed, err = client.RemoveWordsFromNotepad(context.Background(), "token", "np-1", []string{"durian"})
+	if err != nil || removed != 0 || service.puts != 1 {
+		t.Fatalf("removed=%d err=%v puts=%d，期望不写回", removed, err, service.puts)
+	}
+}
+
+func TestNotepadWords(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    []string
+	}{
+		{"空内容", "", nil},
+		{"跳过日期标题和空行", "#2025-01-01\napple\n\n#2025-01-02\nbanana\n", []string{"apple", "banana"}},
+		{"转为小写并忽略首尾空白", "  Apple \nBANANA", []string{"apple", "banana"}},
+		{"词组整行作为一项", "run out of\nrun", []string{"run", "run out of"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for word := range NotepadWords(tt.content) {
+				got = append(got, word)
+			}
+			slices.Sort(got)
+			if !slices.Equal(got, tt.want) {
+				t.Fatalf("NotepadWords = %v，期望 %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAddWordsToNotepad(t *testing.T) {
+	tests := []struct {
+		name        string
+		content     string
+		words       []string
+		limits      Limits
+		wantAdded   int
+		wantSkipped int
+		wantPosts   [][]string
+		wantErr     error
+	}{
+		{
+			name:        "已存在的单词跳过",
+			content:     "#2025-01-01\napple\nBanana",
+			words:       []string{"apple", "banana", "cherry", "durian"},
+			wantAdded:   2,
+			wantSkipped: 2,
+			wantPosts:   [][]string{{"cherry", "durian"}},
+		},
+		{
+			name:        "重复提交和大小写不同的单词只添加一次",
+			content:     "",
+			words:       []string{"Cherry", "cherry", " CHERRY ", ""},
+			wantAdded:   1,
+			wantSkipped: 3,
+			wantPosts:   [][]string{{"Cherry"}},
+		},
+		{
+			name:        "全部已存在时不提交",
+			content:     "#2025-01-01\napple",
+			words:       []string{"Apple"},
+			wantSkipped: 1,
+		},
+		{
+			name:      "按每次请求的单词数分批",
+			words:     []string{"a1", "a2", "a3", "a4", "a5"},
+			limits:    Limits{MaxWordsPerRequest: 2},
+			wantAdded: 5,
+			wantPosts: [][]string{{"a1", "a2"}, {"a3", "a4"}, {"a5"}},
+		},
+		{
+			name:      "云词本容量不足时只添加放得下的单词",
+			content:   "#2025-01-01\napple",
+			words:     []string{"cherry", "durian", "elderberry"},
+			limits:    Limits{MaxNotepadBytes: len("#2025-01-01\napple") + len("\n#2006-01-02\n") + len("cherry\ndurian\n")},
+			wantAdded: 2,
+			wantPosts: [][]string{{"cherry", "durian"}},
+			wantErr:   ErrNotepadFull,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := &fakeNotepadService{notepad: Notepad{ID: "np-1", Content: tt.content}}
+			server := httptest.NewServer(service)
+			defer server.Close()
+
+			client := NewClient(server.URL)
+			limits := tt.limits
+			limits.RequestInterval = -1
+			client.SetLimits(limits)
+
+			added, skipped, err := client.AddWordsToNotepad(context.Background(), "token", "np-1", tt.words)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v，期望 %v", err, tt.wantErr)
+			}
+			if added != tt.wantAdded || skipped != tt.wantSkipped {
+				t.Fatalf("added=%d skipped=%d，期望 added=%d skipped=%d", added, skipped, tt.wantAdded, tt.wantSkipped)
+			}
+			if len(service.posts) != len(tt.wantPosts) {
+				t.Fatalf("提交了 %v，期望 %v", service.posts, tt.wantPosts)
+			}
+			for i := range tt.wantPosts {
+				if !slices.Equal(service.posts[i], tt.wantPosts[i]) {
+					t.Fatalf("第 %d 次提交 %v，期望 %v", i+1, service.posts[i], tt.wantPosts[i])
+				}
+			}
+
+			// 再次同步相同的单词不会重复添加
+			if tt.wantErr == nil {
+				again, _, err := client.AddWordsToNotepad(context.Background(), "token", "np-1", tt.words)
+				if err != nil || again != 0 {
+					t.Fatalf("再次同步 added=%d err=%v，期望不添加", again, err)
+				}
+			}
+		})
+	}
+}
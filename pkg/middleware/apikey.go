@@ -0,0 +1,28 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// APIKey 校验请求携带的 API Key（Authorization: Bearer <key> 或 X-API-Key 请求头）
+// key 为空表示未配置，直接放行；校验失败返回 401 JSON
+func APIKey(key string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if key == "" {
+			c.Next()
+			return
+		}
+
+		provided := requestKey(c)
+
+		// 固定时间比较，避免通过响应时间猜测 Key
+		if subtle.ConstantTimeCompare([]byte(provided), []byte(key)) != 1 {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "API Key 缺失或错误"})
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,79 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AuthOptions API 鉴权配置
+type AuthOptions struct {
+	Keys   []string // 允许的 API Key，为空表示不鉴权
+	Public []string // 不需要鉴权的路径（如存活检查），以 / 结尾的表示该前缀下的所有路径
+}
+
+// Auth 要求 pathPrefix 下的请求携带有效的 Key，否则返回 401 HTML
+// Key 可以通过 Authorization: Bearer <key>、X-API-Key 或 Basic 认证（用户名任意，密码为 Key）携带；
+// 响应的 WWW-Authenticate 同时声明 Basic，浏览器访问网页时会弹出登录框，之后的请求自动携带凭证
+func Auth(pathPrefix string, opts AuthOptions) gin.HandlerFunc {
+	public := publicPaths(opts.Public)
+
+	return func(c *gin.Context) {
+		path := c.Request.URL.Path
+		if len(opts.Keys) == 0 || !strings.HasPrefix(path, pathPrefix) || public(path) {
+			c.Next()
+			return
+		}
+
+		if key := requestKey(c); key != "" && validKey(key, opts.Keys) {
+			c.Next()
+			return
+		}
+
+		c.Header("WWW-Authenticate", `Basic realm="voiceflow", charset="UTF-8"`)
+		c.Data(http.StatusUnauthorized, "text/html", []byte(`
+	    <div class="bg-red-50 text-red-800 p-3 rounded-lg text-sm">
+	    ❌ 未授权：API Key 缺失或错误
+	    </div>
+	    `))
+		c.Abort()
+	}
+}
+
+// publicPaths 返回判断路径是否公开的函数：以 / 结尾的条目按前缀匹配（如 /api/admin/），其余精确匹配
+func publicPaths(paths []string) func(path string) bool {
+	exact := make(map[string]bool, len(paths))
+	var prefixes []string
+	for _, path := range paths {
+		if strings.HasSuffix(path, "/") {
+			prefixes = append(prefixes, path)
+		} else {
+			exact[path] = true
+		}
+	}
+
+	return func(path string) bool {
+		if exact[path] {
+			return true
+		}
+		for _, prefix := range prefixes {
+			if strings.HasPrefix(path, prefix) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// validKey 判断 key 是否在允许的列表中（逐个固定时间比较，避免通过响应时间猜测 Key）
+func validKey(key string, keys []string) bool {
+	valid := false
+	for _, k := range keys {
+		if subtle.ConstantTimeCompare([]byte(key), []byte(k)) == 1 {
+			valid = true
+		}
+	}
+	return valid
+}
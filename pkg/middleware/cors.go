@@ -0,0 +1,79 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CORSOptions 跨域配置
+type CORSOptions struct {
+	AllowedOrigins   []string // 允许的来源，"*" 表示任意来源；为空表示未配置跨域
+	AllowedMethods   []string
+	AllowedHeaders   []string
+	AllowCredentials bool
+	MaxAge           int // 预检结果缓存时间（秒）
+}
+
+// CORS 为 pathPrefix 下的接口添加跨域响应头，并直接响应预检（OPTIONS）请求
+// 需要通过 engine.Use 注册：预检请求没有对应的路由，只有全局中间件会执行。
+// 未配置允许的来源时保持原有行为：只有 WebVTT 字幕允许任意来源访问（供其他站点的播放器加载）
+func CORS(pathPrefix string, opts CORSOptions) gin.HandlerFunc {
+	allowAll := false
+	origins := make(map[string]bool, len(opts.AllowedOrigins))
+	for _, origin := range opts.AllowedOrigins {
+		if origin == "*" {
+			allowAll = true
+		}
+		origins[strings.TrimSuffix(origin, "/")] = true
+	}
+	methods := strings.Join(opts.AllowedMethods, ", ")
+	headers := strings.Join(opts.AllowedHeaders, ", ")
+
+	return func(c *gin.Context) {
+		if !strings.HasPrefix(c.Request.URL.Path, pathPrefix) {
+			c.Next()
+			return
+		}
+
+		if len(origins) == 0 {
+			if strings.HasSuffix(c.Request.URL.Path, ".vtt") {
+				c.Header("Access-Control-Allow-Origin", "*")
+			}
+			c.Next()
+			return
+		}
+
+		origin := c.GetHeader("Origin")
+		if origin == "" || (!allowAll && !origins[origin]) {
+			c.Next()
+			return
+		}
+
+		// 携带凭证时不能使用 "*"，回显请求来源
+		if allowAll && !opts.AllowCredentials {
+			c.Header("Access-Control-Allow-Origin", "*")
+		} else {
+			c.Header("Access-Control-Allow-Origin", origin)
+			c.Header("Vary", "Origin")
+		}
+		if opts.AllowCredentials {
+			c.Header("Access-Control-Allow-Credentials", "true")
+		}
+
+		// 预检请求
+		if c.Request.Method == http.MethodOptions && c.GetHeader("Access-Control-Request-Method") != "" {
+			c.Header("Access-Control-Allow-Methods", methods)
+			c.Header("Access-Control-Allow-Headers", headers)
+			if opts.MaxAge > 0 {
+				c.Header("Access-Control-Max-Age", strconv.Itoa(opts.MaxAge))
+			}
+			c.AbortWithStatus(http.StatusNoContent)
+			return
+		}
+
+		c.Next()
+	}
+}
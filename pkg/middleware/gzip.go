@@ -0,0 +1,142 @@
+package middleware
+
+import (
+	"compress/gzip"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+// gzipWriters 复用 gzip.Writer（每个 Writer 内部有数百 KB 的压缩缓冲区）
+var gzipWriters = sync.Pool{
+	New: func() any {
+		gz, _ := gzip.NewWriterLevel(nil, gzip.DefaultCompression)
+		return gz
+	},
+}
+
+// Gzip 客户端支持（Accept-Encoding 包含 gzip）时压缩响应
+// 是否压缩在写入第一个字节时根据响应头决定：音视频、图片、SSE 事件流、已经编码或部分内容（206）的响应不压缩，
+// 压缩时删除 handler 设置的 Content-Length（长度已改变）。excludePrefixes 下的路径（如 /uploads 下的媒体文件）
+// 和 WebSocket 握手请求直接跳过
+func Gzip(excludePrefixes ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !acceptsGzip(c.GetHeader("Accept-Encoding")) ||
+			c.Request.Method == http.MethodHead ||
+			c.GetHeader("Upgrade") != "" {
+			c.Next()
+			return
+		}
+		for _, prefix := range excludePrefixes {
+			if strings.HasPrefix(c.Request.URL.Path, prefix) {
+				c.Next()
+				return
+			}
+		}
+
+		w := &gzipWriter{ResponseWriter: c.Writer}
+		c.Writer = w
+		defer w.close()
+
+		c.Next()
+	}
+}
+
+// acceptsGzip 解析 Accept-Encoding，gzip（或 *）的 q 值为 0 时视为不接受
+func acceptsGzip(header string) bool {
+	for _, part := range strings.Split(header, ",") {
+		coding, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		coding = strings.ToLower(strings.TrimSpace(coding))
+		if coding != "gzip" && coding != "*" {
+			continue
+		}
+		if q, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if value, err := strconv.ParseFloat(q, 64); err == nil && value == 0 {
+				continue
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// gzipWriter 延迟到第一次写入时决定是否压缩
+type gzipWriter struct {
+	gin.ResponseWriter
+	gz      *gzip.Writer
+	decided bool
+}
+
+// decide 根据已设置的响应头决定是否压缩，压缩时改写响应头
+func (w *gzipWriter) decide() {
+	if w.decided {
+		return
+	}
+	w.decided = true
+
+	if w.ResponseWriter.Written() || !compressible(w.Status(), w.Header()) {
+		return
+	}
+
+	header := w.Header()
+	header.Del("Content-Length")
+	header.Set("Content-Encoding", "gzip")
+	header.Add("Vary", "Accept-Encoding")
+
+	w.gz = gzipWriters.Get().(*gzip.Writer)
+	w.gz.Reset(w.ResponseWriter)
+}
+
+// compressible 响应是否适合压缩
+func compressible(status int, header http.Header) bool {
+	if status < http.StatusOK || status == http.StatusNoContent ||
+		status == http.StatusPartialContent || status == http.StatusNotModified {
+		return false
+	}
+	if header.Get("Content-Encoding") != "" || header.Get("Content-Range") != "" {
+		return false
+	}
+
+	contentType := strings.ToLower(header.Get("Content-Type"))
+	for _, prefix := range []string{"audio/", "video/", "image/", "text/event-stream", "application/zip", "application/gzip", "application/octet-stream"} {
+		if strings.HasPrefix(contentType, prefix) {
+			return false
+		}
+	}
+	return true
+}
+
+func (w *gzipWriter) Write(data []byte) (int, error) {
+	w.decide()
+	if w.gz == nil {
+		return w.ResponseWriter.Write(data)
+	}
+	w.ResponseWriter.WriteHeaderNow()
+	return w.gz.Write(data)
+}
+
+func (w *gzipWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+// Flush 先刷新压缩缓冲区，再刷新底层连接
+func (w *gzipWriter) Flush() {
+	if w.gz != nil {
+		w.gz.Flush()
+	}
+	w.ResponseWriter.Flush()
+}
+
+// close 写入 gzip 结尾并归还 Writer；没有写入任何内容时不压缩
+func (w *gzipWriter) close() {
+	if w.gz == nil {
+		return
+	}
+	w.gz.Close()
+	w.gz.Reset(nil)
+	gzipWriters.Put(w.gz)
+	w.gz = nil
+}
@@ -0,0 +1,125 @@
+package middleware
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/time/rate"
+)
+
+// RateLimitOptions 限流参数
+type RateLimitOptions struct {
+	PerMinute float64       // 每分钟补充的令牌数（允许的平均请求数）
+	Burst     int           // 令牌桶容量（允许的突发请求数）
+	IdleTTL   time.Duration // 客户端空闲超过该时间后移除其令牌桶
+}
+
+// RateLimiter 按客户端 IP 的令牌桶限流器
+// 每个 IP 一个令牌桶；后台 goroutine 定期移除空闲的 IP，表的大小只与最近活跃的客户端数有关
+type RateLimiter struct {
+	opts    RateLimitOptions
+	mu      sync.Mutex
+	clients map[string]*rateClient
+	stopCh  chan struct{}
+	once    sync.Once
+}
+
+// rateClient 单个客户端的令牌桶
+type rateClient struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+// NewRateLimiter 创建限流器并启动空闲清理 goroutine（调用 Stop 停止）
+func NewRateLimiter(opts RateLimitOptions) *RateLimiter {
+	l := &RateLimiter{
+		opts:    opts,
+		clients: make(map[string]*rateClient),
+		stopCh:  make(chan struct{}),
+	}
+	go l.cleanupLoop()
+	return l
+}
+
+// Handler 限流中间件：超出限制时返回 429 HTML，并通过 Retry-After 告知需要等待的秒数
+func (l *RateLimiter) Handler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		wait := l.reserve(c.ClientIP())
+		if wait <= 0 {
+			c.Next()
+			return
+		}
+
+		seconds := int(math.Ceil(wait.Seconds()))
+		c.Header("Retry-After", fmt.Sprint(seconds))
+		c.Data(http.StatusTooManyRequests, "text/html", []byte(fmt.Sprintf(`
+	    <div class="bg-red-50 text-red-800 p-3 rounded-lg text-sm">
+	    ❌ 请求太频繁，请 %d 秒后再试
+	    </div>
+	    `, seconds)))
+		c.Abort()
+	}
+}
+
+// reserve 为客户端取一个令牌，返回需要等待的时间（0 表示放行）
+// 需要等待时不消耗令牌，被拒绝的请求不会延长后续的等待时间
+func (l *RateLimiter) reserve(key string) time.Duration {
+	l.mu.Lock()
+	client, ok := l.clients[key]
+	if !ok {
+		client = &rateClient{limiter: rate.NewLimiter(rate.Limit(l.opts.PerMinute/60), l.opts.Burst)}
+		l.clients[key] = client
+	}
+	client.lastSeen = time.Now()
+	l.mu.Unlock()
+
+	now := time.Now()
+	r := client.limiter.ReserveN(now, 1)
+	if !r.OK() {
+		return time.Minute // Burst 为 0 等无法满足的配置
+	}
+	wait := r.DelayFrom(now)
+	if wait > 0 {
+		r.CancelAt(now)
+	}
+	return wait
+}
+
+// Stop 停止空闲清理 goroutine
+func (l *RateLimiter) Stop() {
+	l.once.Do(func() {
+		close(l.stopCh)
+	})
+}
+
+// cleanupLoop 定期移除空闲的客户端
+func (l *RateLimiter) cleanupLoop() {
+	ticker := time.NewTicker(l.opts.IdleTTL)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			l.cleanup(time.Now().Add(-l.opts.IdleTTL))
+		case <-l.stopCh:
+			return
+		}
+	}
+}
+
+// cleanup 移除最近一次请求早于 before 的客户端
+// 空闲时间足够长的令牌桶已经补满，移除后重新创建的效果相同
+func (l *RateLimiter) cleanup(before time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for key, client := range l.clients {
+		if client.lastSeen.Before(before) {
+			delete(l.clients, key)
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// userContextKey 当前用户在 gin.Context 中的键
+const userContextKey = "voiceflow.user"
+
+// User 发起请求的用户
+type User struct {
+	ID    string // 用户 ID，未启用多用户时为空
+	Admin bool   // 管理员可以访问所有用户的任务
+}
+
+// Restricted 是否只能访问自己的任务（已识别的普通用户）
+func (u User) Restricted() bool {
+	return u.ID != "" && !u.Admin
+}
+
+// UserKey 一个 API Key 对应的用户
+type UserKey struct {
+	Key  string
+	User User
+}
+
+// UserOptions 多用户配置
+type UserOptions struct {
+	Keys   []UserKey       // API Key（Authorization: Bearer <key> 或 X-API-Key）
+	Header string          // 由反向代理设置的用户名请求头，为空表示不使用
+	Admins map[string]bool // 通过 Header 识别的管理员用户
+	Public []string        // 不需要识别用户的路径（如存活检查），以 / 结尾的表示该前缀下的所有路径
+}
+
+// Enabled 是否启用多用户
+func (o UserOptions) Enabled() bool {
+	return len(o.Keys) > 0 || o.Header != ""
+}
+
+// Users 识别发起请求的用户并保存到 gin.Context（通过 CurrentUser 读取）
+// 先匹配 API Key，再读取代理设置的用户名请求头；都没有时返回 401。
+// 未启用多用户时直接放行，CurrentUser 返回空用户（可以访问所有任务）
+func Users(pathPrefix string, opts UserOptions) gin.HandlerFunc {
+	public := publicPaths(opts.Public)
+
+	return func(c *gin.Context) {
+		path := c.Request.URL.Path
+		if !opts.Enabled() || !strings.HasPrefix(path, pathPrefix) || public(path) {
+			c.Next()
+			return
+		}
+
+		if user, ok := resolveUser(c, opts); ok {
+			c.Set(userContextKey, user)
+			c.Next()
+			return
+		}
+
+		c.Header("WWW-Authenticate", `Bearer realm="voiceflow"`)
+		c.Data(http.StatusUnauthorized, "text/html", []byte(`
+	    <div class="bg-red-50 text-red-800 p-3 rounded-lg text-sm">
+	    ❌ 未登录或 API Key 无效
+	    </div>
+	    `))
+		c.Abort()
+	}
+}
+
+// resolveUser 根据 API Key 或用户名请求头识别用户
+func resolveUser(c *gin.Context, opts UserOptions) (User, bool) {
+	if key := requestKey(c); key != "" {
+		// 固定时间比较，避免通过响应时间猜测 Key
+		for _, k := range opts.Keys {
+			if subtle.ConstantTimeCompare([]byte(key), []byte(k.Key)) == 1 {
+				return k.User, true
+			}
+		}
+		return User{}, false
+	}
+
+	if opts.Header != "" {
+		if id := strings.TrimSpace(c.GetHeader(opts.Header)); id != "" {
+			return User{ID: id, Admin: opts.Admins[id]}, true
+		}
+	}
+	return User{}, false
+}
+
+// requestKey 请求携带的 API Key（Authorization: Bearer <key>、X-API-Key 请求头，或 Basic 认证的密码）
+func requestKey(c *gin.Context) string {
+	if key := c.GetHeader("X-API-Key"); key != "" {
+		return key
+	}
+	if auth := c.GetHeader("Authorization"); strings.HasPrefix(auth, "Bearer ") {
+		return strings.TrimPrefix(auth, "Bearer ")
+	}
+	if _, password, ok := c.Request.BasicAuth(); ok {
+		return password
+	}
+	return ""
+}
+
+// CurrentUser 当前请求的用户（未启用多用户或未经过 Users 中间件时为空用户）
+func CurrentUser(c *gin.Context) User {
+	if value, ok := c.Get(userContextKey); ok {
+		if user, ok := value.(User); ok {
+			return user
+		}
+	}
+	return User{}
+}
@@ -0,0 +1,85 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// CurrentSchemaVersion 任务 JSON 的当前版本
+// 修改 TranscriptionJob 的 JSON 结构（重命名、改变字段含义）时递增，
+// 并在 jobMigrations 中追加一个把上一版本升级到新版本的函数。
+// 只新增可选字段时无需递增：旧数据缺少的字段会得到零值。
+const CurrentSchemaVersion = 1
+
+// jobMigration 将某一版本的原始 JSON 字段升级到下一版本
+type jobMigration func(fields map[string]json.RawMessage) error
+
+// jobMigrations 第 i 项把版本 i 升级到版本 i+1
+var jobMigrations = []jobMigration{
+	0: migrateJobV0,
+}
+
+// transportFields 仅用于进程内传递、不应出现在 JSON 中的字段
+// 早期版本曾误将 RabbitMQ delivery 写入任务 JSON
+var transportFields = []string{"DeliveryTag", "RabbitMQDelivery", "delivery_tag", "rabbitmq_delivery"}
+
+// migrateJobV0 v0（未带 schema_version 的旧数据）→ v1：移除误写入的传输层字段
+func migrateJobV0(fields map[string]json.RawMessage) error {
+	for _, name := range transportFields {
+		delete(fields, name)
+	}
+	return nil
+}
+
+// MarshalJob 序列化任务（写入 Redis、消息队列等跨进程场景使用）
+// 总是写入当前的 schema_version，不修改传入的任务
+func MarshalJob(job *TranscriptionJob) ([]byte, error) {
+	versioned := *job
+	versioned.SchemaVersion = CurrentSchemaVersion
+	return json.Marshal(&versioned)
+}
+
+// UnmarshalJob 反序列化任务，并将旧版本的数据逐级升级到当前版本
+// 比当前版本更新的数据（滚动升级期间由新版本写入）按当前结构尽量解析，未知字段被忽略
+func UnmarshalJob(data []byte) (*TranscriptionJob, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, fmt.Errorf("解析任务 JSON 失败: %w", err)
+	}
+	if fields == nil {
+		return nil, fmt.Errorf("解析任务 JSON 失败: 数据为空")
+	}
+
+	version := 0
+	if raw, ok := fields["schema_version"]; ok {
+		if err := json.Unmarshal(raw, &version); err != nil {
+			return nil, fmt.Errorf("解析 schema_version 失败: %w", err)
+		}
+		if version < 0 {
+			return nil, fmt.Errorf("无效的 schema_version: %d", version)
+		}
+	}
+
+	if version < CurrentSchemaVersion {
+		for v := version; v < CurrentSchemaVersion; v++ {
+			if err := jobMigrations[v](fields); err != nil {
+				return nil, fmt.Errorf("升级任务数据 v%d → v%d 失败: %w", v, v+1, err)
+			}
+		}
+		upgraded, err := json.Marshal(fields)
+		if err != nil {
+			return nil, fmt.Errorf("升级任务数据失败: %w", err)
+		}
+		data = upgraded
+	}
+
+	var job TranscriptionJob
+	if err := json.Unmarshal(data, &job); err != nil {
+		return nil, fmt.Errorf("反序列化任务失败: %w", err)
+	}
+	if job.SchemaVersion < CurrentSchemaVersion {
+		job.SchemaVersion = CurrentSchemaVersion
+	}
+
+	return &job, nil
+}
@@ -0,0 +1,137 @@
+package models
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestUnmarshalJobVersions(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		want    TranscriptionJob
+		wantErr bool
+	}{
+		{
+			name: "v0 旧数据移除传输层字段",
+			data: `{"job_id":"job-1","status":"completed","result":"hello","DeliveryTag":7,"RabbitMQDelivery":{"Body":"x"},"rabbitmq_delivery":null}`,
+			want: TranscriptionJob{SchemaVersion: CurrentSchemaVersion, JobID: "job-1", Status: StatusCompleted, Result: "hello"},
+		},
+		{
+			name: "当前版本",
+			data: `{"schema_version":1,"job_id":"job-1","status":"pending","priority":5}`,
+			want: TranscriptionJob{SchemaVersion: 1, JobID: "job-1", Status: StatusPending, Priority: 5},
+		},
+		{
+			name: "新版本写入的数据忽略未知字段",
+			data: `{"schema_version":2,"job_id":"job-1","status":"processing","future_field":{"a":1}}`,
+			want: TranscriptionJob{SchemaVersion: 2, JobID: "job-1", Status: StatusProcessing},
+		},
+		{name: "不是 JSON", data: `job-1`, wantErr: true},
+		{name: "null", data: `null`, wantErr: true},
+		{name: "schema_version 为负数", data: `{"schema_version":-1,"job_id":"job-1"}`, wantErr: true},
+		{name: "schema_version 类型错误", data: `{"schema_version":"1","job_id":"job-1"}`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := UnmarshalJob([]byte(tt.data))
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("UnmarshalJob 应返回错误，实际得到 %+v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("UnmarshalJob: %v", err)
+			}
+			if !reflect.DeepEqual(*got, tt.want) {
+				t.Fatalf("UnmarshalJob = %+v，期望 %+v", *got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMarshalJobExcludesTransportFields(t *testing.T) {
+	job := &TranscriptionJob{
+		JobID:            "job-1",
+		DeliveryTag:      7,
+		Redeliveries:     2,
+		RabbitMQDelivery: struct{ Body []byte }{[]byte("x")},
+		NatsMsg:          "msg",
+		KafkaMsg:         "msg",
+	}
+	data, err := MarshalJob(job)
+	if err != nil {
+		t.Fatalf("MarshalJob: %v", err)
+	}
+	if job.SchemaVersion != 0 {
+		t.Fatal("MarshalJob 不应修改传入的任务")
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	if string(fields["schema_version"]) != "1" {
+		t.Fatalf("schema_version = %s，期望 1", fields["schema_version"])
+	}
+	for name := range fields {
+		lower := strings.ToLower(name)
+		if strings.Contains(lower, "delivery") || strings.Contains(lower, "msg") {
+			t.Fatalf("JSON 中不应出现传输层字段 %s: %s", name, data)
+		}
+	}
+}
+
+// FuzzJobRoundTrip 任意版本的数据经过 UnmarshalJob → MarshalJob → UnmarshalJob 后内容不变
+func FuzzJobRoundTrip(f *testing.F) {
+	f.Add(0, "job-1", "hello", 50, int64(3), "RabbitMQDelivery")
+	f.Add(1, "job-2", "你好，世界", 100, int64(0), "")
+	f.Add(2, "", "", -1, int64(-5), "future_field")
+	f.Fuzz(func(t *testing.T, version int, jobID, result string, progress int, size int64, extra string) {
+		fields := map[string]any{
+			"job_id":     jobID,
+			"status":     StatusCompleted,
+			"result":     result,
+			"progress":   progress,
+			"file_size":  size,
+			"vocabulary": []string{result},
+			"created_at": time.Unix(1700000000, 0).UTC(),
+		}
+		if version != 0 {
+			fields["schema_version"] = version
+		}
+		if extra != "" && extra != "schema_version" && !strings.EqualFold(extra, "job_id") {
+			fields[extra] = map[string]string{"x": result}
+		}
+		data, err := json.Marshal(fields)
+		if err != nil {
+			t.Skip()
+		}
+
+		first, err := UnmarshalJob(data)
+		if err != nil {
+			// 负数版本，或与结构字段同名（不区分大小写）但类型不符的额外字段
+			return
+		}
+		if first.SchemaVersion < CurrentSchemaVersion {
+			t.Fatalf("升级后 schema_version = %d", first.SchemaVersion)
+		}
+		encoded, err := MarshalJob(first)
+		if err != nil {
+			t.Fatalf("MarshalJob: %v", err)
+		}
+		second, err := UnmarshalJob(encoded)
+		if err != nil {
+			t.Fatalf("UnmarshalJob(MarshalJob(job)): %v", err)
+		}
+		// 新版本写入的数据按当前版本重新写出
+		second.SchemaVersion = first.SchemaVersion
+		if !reflect.DeepEqual(first, second) {
+			t.Fatalf("往返后任务不一致:\n%+v\n%+v", first, second)
+		}
+	})
+}
@@ -11,34 +11,76 @@ const (
     StatusFailed     JobStatus = "failed"      
 )
 
+// 任务优先级：数值越大越先处理，默认 0（普通）
+const (
+    PriorityNormal = 0
+    PriorityHigh   = 5 // 付费用户上传
+    MaxPriority    = 9 // 允许的最大优先级（RabbitMQ 队列的 x-max-priority）
+)
+
 type WordDetail struct {
     Word       string `json:"word"`       
     Definition string `json:"definition"` 
     Example    string `json:"example"`   
+    Phonetic   string `json:"phonetic,omitempty"` // 国际音标（IPA），旧数据为空
+    Level      string `json:"level,omitempty"`    // CEFR 等级（A1–C2），旧数据为空表示未标注
+}
+
+// SyncRecord 单词同步记录（同步到墨墨、Anki 等外部系统）
+type SyncRecord struct {
+    Sink     string    `json:"sink"`            // 同步目标名称（见配置 vocabulary_sinks）
+    Count    int       `json:"count"`           // 成功同步的单词数
+    Error    string    `json:"error,omitempty"` // 失败原因
+    SyncedAt time.Time `json:"synced_at"`
 }
 
+// TranscriptionJob 转录任务
+// 跨进程传递（Redis、消息队列）时使用 MarshalJob / UnmarshalJob，以兼容不同版本的数据
 type TranscriptionJob struct {
+    SchemaVersion    int          `json:"schema_version,omitempty"` // JSON 结构版本（见 CurrentSchemaVersion）
     JobID            string       `json:"job_id"`
     Filename         string       `json:"filename"`
-    FilePath         string       `json:"file_path"`
+    FilePath         string       `json:"file_path"`              // 原始媒体在文件存储中的 key，如 uploads/<job_id>.mp3（字段名沿用旧版的本地路径）
     Status           JobStatus    `json:"status"`
     Progress         int          `json:"progress"`
     Result           string       `json:"result"`
-    SubtitlePath     string       `json:"subtitle_path"`          // SRT 字幕文件路径（单语）
-    VTTPath          string       `json:"vtt_path"`               // WebVTT 字幕文件路径（单语）
-    BilingualSRTPath string       `json:"bilingual_srt_path"`     // 双语 SRT 字幕文件路径
-    BilingualVTTPath string       `json:"bilingual_vtt_path"`     // 双语 WebVTT 字幕文件路径
+    SubtitlePath     string       `json:"subtitle_path"`          // SRT 字幕的 key（单语）
+    VTTPath          string       `json:"vtt_path"`               // WebVTT 字幕的 key（单语）
+    BilingualSRTPath string       `json:"bilingual_srt_path"`     // 双语 SRT 字幕的 key
+    BilingualVTTPath string       `json:"bilingual_vtt_path"`     // 双语 WebVTT 字幕的 key
     Language         string       `json:"language"`
-    Duration         float64      `json:"duration"`
+    Duration         float64      `json:"duration"`               // 音频时长（秒），上传时由 ffprobe 读取
+    FileSize         int64        `json:"file_size,omitempty"`    // 原始媒体文件大小（字节）
     Error            string       `json:"error"`
     Vocabulary       []string     `json:"vocabulary"`
     VocabDetail      []WordDetail `json:"vocab_detail"`
     CreatedAt        time.Time    `json:"created_at"`
     CompletedAt      time.Time    `json:"completed_at"`
+    LastUpdated      time.Time    `json:"last_updated"`           // 状态或进度最近一次变化的时间（Worker 更新），用于进度轮询的 ETag
+    MediaPurged      bool         `json:"media_purged"`           // 原始媒体文件已按保留策略清理（转录结果和字幕仍保留）
+    Source           string       `json:"source,omitempty"`       // 任务来源（订阅源名称），手动上传为空
+    SyncHistory      []SyncRecord `json:"sync_history,omitempty"` // 单词同步记录
+    Attempts         int          `json:"attempts,omitempty"`     // 处理中因服务重启被中断的次数（启动恢复时累加）
+    RetryCount       int          `json:"retry_count,omitempty"`  // 因临时错误（Whisper 429/5xx、网络超时）自动重试的次数
+    NextAttemptAt    time.Time    `json:"next_attempt_at,omitzero"` // 下一次自动重试的时间（等待重试期间为 pending），零值表示没有等待中的重试
+    EstimatedCost    float64      `json:"estimated_cost,omitempty"`    // 估算的 OpenAI 费用（美元）：转录、字幕翻译、单词提取累计（重新转录时继续累加）
+    PromptTokens     int          `json:"prompt_tokens,omitempty"`     // 字幕翻译和单词提取累计的输入 token 数
+    CompletionTokens int          `json:"completion_tokens,omitempty"` // 字幕翻译和单词提取累计的输出 token 数
+    ContentHash      string       `json:"content_hash,omitempty"` // 上传文件内容的 SHA-256（十六进制），用于识别重复上传
+    Priority         int          `json:"priority,omitempty"`     // 队列优先级（0 为普通，越大越先处理，最大 MaxPriority）
+    Prompt           string       `json:"prompt,omitempty"`       // Whisper 提示词（专有名词、术语），为空时使用配置的默认值
+    Temperature      float64      `json:"temperature,omitempty"`  // Whisper 采样温度（0 到 1），为 0 时使用配置的默认值
+    Model            string       `json:"model,omitempty"`        // Whisper 模型（如 whisper-1），为空时使用默认模型
+    UserID           string       `json:"user_id,omitempty"`      // 上传任务的用户（多用户模式下只有本人和管理员可以访问），为空表示未启用多用户或系统创建（如订阅源）
+    Version          int64        `json:"version,omitempty"`      // 存储中的版本号，每次写入加 1（乐观锁，见 storage.ErrVersionConflict），0 表示未从存储读取
+    DeletedAt        time.Time    `json:"deleted_at,omitzero"`    // 软删除时间：任务从列表中隐藏，保留期内可以恢复，之后由清理器永久删除；零值表示未删除
 
-    // RabbitMQ 相关（不序列化到 JSON）
+    // 消息队列相关（仅在进程内传递，不序列化到 JSON）
     DeliveryTag      uint64      `json:"-"` // RabbitMQ delivery tag
+    Redeliveries     int         `json:"-"` // 内存队列中 Nack 重新入队的次数（达到上限后放弃）
     RabbitMQDelivery any `json:"-"` // RabbitMQ delivery 对象（用于 Ack/Nack）
+    NatsMsg          any `json:"-"` // NATS JetStream 消息（用于 Ack/Nak/Term）
+    KafkaMsg         any `json:"-"` // Kafka 消息（用于提交 offset）
 }
 
 // Segment 音频片段
@@ -47,6 +89,7 @@ type Segment struct {
     FilePath string  `json:"file_path"` // 片段文件路径
     Start    float64 `json:"start"`     // 开始时间（秒）
     End      float64 `json:"end"`       // 结束时间（秒）
+    Overlap  float64 `json:"overlap"`   // 开头与上一片段重叠的时长（秒），重叠部分的转录结果以上一片段为准
 }
 
 // TranscriptionResult 转换结果
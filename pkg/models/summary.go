@@ -0,0 +1,71 @@
+package models
+
+import "time"
+
+// JobSummary 任务的轻量投影（不含转录文本、单词等大字段），用于列表聚合
+type JobSummary struct {
+	JobID     string    `json:"job_id"`
+	Filename  string    `json:"filename"`
+	Status    JobStatus `json:"status"`
+	Progress  int       `json:"progress"`
+	CreatedAt time.Time `json:"created_at"`
+	UserID    string    `json:"user_id,omitempty"`
+}
+
+// ActiveStatuses 未结束的任务状态
+var ActiveStatuses = []JobStatus{StatusPending, StatusProcessing}
+
+// AllStatuses 所有任务状态
+var AllStatuses = []JobStatus{StatusPending, StatusProcessing, StatusCompleted, StatusFailed}
+
+// JobStats 任务的汇总统计
+type JobStats struct {
+	Total       int     `json:"total"`        // 任务总数（包括未结束的任务）
+	Completed   int     `json:"completed"`    // 已完成的任务数
+	Failed      int     `json:"failed"`       // 失败的任务数
+	AvgDuration float64 `json:"avg_duration"` // 已完成任务的平均音频时长（秒），没有已完成的任务时为 0
+}
+
+// CostStats 一段时间内创建的任务的费用汇总
+type CostStats struct {
+	Jobs             int     `json:"jobs"`              // 有费用记录的任务数
+	EstimatedCost    float64 `json:"estimated_cost"`    // 费用估算合计（美元）
+	AudioMinutes     float64 `json:"audio_minutes"`     // 这些任务的音频时长合计（分钟）
+	PromptTokens     int     `json:"prompt_tokens"`     // LLM 输入 token 合计
+	CompletionTokens int     `json:"completion_tokens"` // LLM 输出 token 合计
+}
+
+// ActiveSummary 进行中任务的汇总
+type ActiveSummary struct {
+	Total           int               `json:"total"`
+	Counts          map[JobStatus]int `json:"counts"`            // 各状态的任务数
+	AverageProgress int               `json:"average_progress"`  // 所有未结束任务的平均进度（pending 计为 0）
+	Closest         *JobSummary       `json:"closest,omitempty"` // 最接近完成的任务
+}
+
+// SummarizeActive 汇总未结束任务的状态和进度
+func SummarizeActive(jobs []JobSummary) ActiveSummary {
+	summary := ActiveSummary{Counts: make(map[JobStatus]int)}
+	for _, status := range ActiveStatuses {
+		summary.Counts[status] = 0
+	}
+
+	totalProgress := 0
+	for i := range jobs {
+		job := &jobs[i]
+		summary.Counts[job.Status]++
+		summary.Total++
+		totalProgress += job.Progress
+
+		// 进度相同时先创建的任务更早完成
+		if summary.Closest == nil || job.Progress > summary.Closest.Progress ||
+			(job.Progress == summary.Closest.Progress && job.CreatedAt.Before(summary.Closest.CreatedAt)) {
+			summary.Closest = job
+		}
+	}
+	if summary.Total > 0 {
+		summary.AverageProgress = totalProgress / summary.Total
+	}
+
+	return summary
+}
@@ -0,0 +1,12 @@
+package queue
+
+import "time"
+
+// KafkaOptions Kafka 队列参数
+// Kafka 客户端只在使用 kafka 构建标签时编译（go build -tags kafka），见 kafka_client.go
+type KafkaOptions struct {
+	Brokers      []string      // broker 地址，如 ["localhost:9092"]
+	Topic        string        // 任务消息的 topic（需预先创建，分区数决定最多有多少个实例同时消费）
+	GroupID      string        // 消费者组，多个实例共享消费进度，每个分区只分配给组内的一个实例
+	WriteTimeout time.Duration // 发布消息的超时时间
+}
@@ -0,0 +1,306 @@
+//go:build kafka
+
+package queue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/z-wentao/voiceflow/pkg/models"
+)
+
+// kafkaRequestTimeout Ping / Stats 查询集群元数据的超时时间
+const kafkaRequestTimeout = 5 * time.Second
+
+// KafkaQueue Kafka 队列实现
+// 1. 消费者组：多个实例共享消费进度，topic 的每个分区只分配给组内的一个实例
+// 2. Ack 提交 offset：offset 是分区内的水位线，多个 Worker 并发处理同一分区时只提交已连续处理完成的最大 offset
+// 3. Nack(requeue=true) 不提交 offset，消息在重启或分区重新分配后重新投递；Nack(requeue=false) 与 Ack 相同
+//
+// Kafka 不支持消息优先级，任务按分区内的顺序处理
+type KafkaQueue struct {
+	opts   KafkaOptions
+	client *kafka.Client
+	writer *kafka.Writer
+	reader *kafka.Reader
+	closed chan struct{}
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	// 已取出、尚未提交的消息（按分区），保护 offset 的提交顺序
+	fetchMu sync.Mutex
+	mu      sync.Mutex
+	pending map[int]*partitionOffsets
+
+	inFlightGauge
+}
+
+// partitionOffsets 一个分区中已取出、尚未提交的消息
+type partitionOffsets struct {
+	offsets []int64        // 按取出顺序（即 offset 递增）排列
+	done    map[int64]bool // 已处理完成的 offset
+}
+
+// NewKafkaQueue 创建 Kafka 队列（topic 需已存在）
+func NewKafkaQueue(opts KafkaOptions) (*KafkaQueue, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	kq := &KafkaQueue{
+		opts:   opts,
+		client: &kafka.Client{Addr: kafka.TCP(opts.Brokers...), Timeout: kafkaRequestTimeout},
+		writer: &kafka.Writer{
+			Addr:         kafka.TCP(opts.Brokers...),
+			Topic:        opts.Topic,
+			Balancer:     &kafka.Hash{}, // 按任务 ID 分区
+			RequiredAcks: kafka.RequireAll,
+			WriteTimeout: opts.WriteTimeout,
+		},
+		reader: kafka.NewReader(kafka.ReaderConfig{
+			Brokers:        opts.Brokers,
+			GroupID:        opts.GroupID,
+			Topic:          opts.Topic,
+			StartOffset:    kafka.FirstOffset, // 新的消费者组从最早的消息开始
+			CommitInterval: 0,                 // 同步提交：Ack 返回时 offset 已提交
+		}),
+		closed:  make(chan struct{}),
+		ctx:     ctx,
+		cancel:  cancel,
+		pending: make(map[int]*partitionOffsets),
+	}
+
+	if err := kq.Ping(); err != nil {
+		kq.Close()
+		return nil, err
+	}
+
+	slog.Info("✓ Kafka 队列初始化成功", "topic", opts.Topic, "group_id", opts.GroupID)
+	return kq, nil
+}
+
+// Enqueue 发布任务消息（key 为任务 ID，等待所有同步副本确认）
+func (kq *KafkaQueue) Enqueue(job *models.TranscriptionJob) error {
+	body, err := models.MarshalJob(job)
+	if err != nil {
+		return fmt.Errorf("序列化任务失败: %w", err)
+	}
+
+	if err := kq.writer.WriteMessages(kq.ctx, kafka.Message{
+		Key:   []byte(job.JobID),
+		Value: body,
+	}); err != nil {
+		return fmt.Errorf("发布消息失败: %w", err)
+	}
+	return nil
+}
+
+// Dequeue 从消费者组取出一条消息（阻塞，关闭队列后返回错误）
+func (kq *KafkaQueue) Dequeue() (*models.TranscriptionJob, error) {
+	// 取出和登记在同一把锁内，保证同一分区的 offset 按递增顺序登记
+	kq.fetchMu.Lock()
+	defer kq.fetchMu.Unlock()
+
+	msg, err := kq.reader.FetchMessage(kq.ctx)
+	if err != nil {
+		if kq.ctx.Err() != nil || errors.Is(err, io.EOF) {
+			return nil, fmt.Errorf("队列已关闭")
+		}
+		return nil, fmt.Errorf("拉取消息失败: %w", err)
+	}
+	kq.track(msg)
+
+	// 反序列化任务（旧版本生产者发布的消息自动升级）
+	job, err := models.UnmarshalJob(msg.Value)
+	if err != nil {
+		// 反序列化失败，直接提交 offset（不再投递）
+		kq.commit(msg)
+		return nil, err
+	}
+
+	// 保存消息用于后续提交 offset
+	job.KafkaMsg = msg
+	return job, nil
+}
+
+// Ack 确认消息（任务处理成功）：提交已连续处理完成的 offset
+func (kq *KafkaQueue) Ack(job *models.TranscriptionJob) error {
+	msg, ok := job.KafkaMsg.(kafka.Message)
+	if !ok {
+		return nil // 不是 Kafka 消息，忽略
+	}
+	return kq.commit(msg)
+}
+
+// Nack 拒绝消息（任务处理失败）
+// requeue=true 时不提交 offset，消息在重启或分区重新分配后重新投递（Kafka 不支持延迟投递，忽略 requeueAfter）；
+// requeue=false 时提交 offset，不再投递
+func (kq *KafkaQueue) Nack(job *models.TranscriptionJob, requeue bool, requeueAfter time.Duration) error {
+	msg, ok := job.KafkaMsg.(kafka.Message)
+	if !ok {
+		return nil // 不是 Kafka 消息，忽略
+	}
+	if requeue {
+		return nil
+	}
+	return kq.commit(msg)
+}
+
+// track 登记已取出的消息
+func (kq *KafkaQueue) track(msg kafka.Message) {
+	kq.mu.Lock()
+	defer kq.mu.Unlock()
+
+	p, ok := kq.pending[msg.Partition]
+	if !ok {
+		p = &partitionOffsets{done: make(map[int64]bool)}
+		kq.pending[msg.Partition] = p
+	}
+	p.offsets = append(p.offsets, msg.Offset)
+}
+
+// commit 标记消息处理完成，并提交分区中已连续处理完成的最大 offset
+// 前面还有未完成的消息时只做标记，等前面的消息完成后一起提交
+func (kq *KafkaQueue) commit(msg kafka.Message) error {
+	kq.mu.Lock()
+	p, ok := kq.pending[msg.Partition]
+	if !ok {
+		kq.mu.Unlock()
+		return nil // 分区已重新分配（重启后该消息会重新投递）
+	}
+	p.done[msg.Offset] = true
+
+	committable := int64(-1)
+	for len(p.offsets) > 0 && p.done[p.offsets[0]] {
+		committable = p.offsets[0]
+		delete(p.done, committable)
+		p.offsets = p.offsets[1:]
+	}
+	kq.mu.Unlock()
+
+	if committable < 0 {
+		return nil
+	}
+
+	msg.Offset = committable
+	if err := kq.reader.CommitMessages(kq.ctx, msg); err != nil {
+		return fmt.Errorf("提交 offset 失败: %w", err)
+	}
+	return nil
+}
+
+// Ping 检查 Kafka 集群是否可用以及 topic 是否存在
+func (kq *KafkaQueue) Ping() error {
+	select {
+	case <-kq.closed:
+		return fmt.Errorf("队列已关闭")
+	default:
+	}
+
+	_, err := kq.partitions()
+	return err
+}
+
+// partitions 查询 topic 的分区列表
+func (kq *KafkaQueue) partitions() ([]int, error) {
+	ctx, cancel := context.WithTimeout(kq.ctx, kafkaRequestTimeout)
+	defer cancel()
+
+	meta, err := kq.client.Metadata(ctx, &kafka.MetadataRequest{Topics: []string{kq.opts.Topic}})
+	if err != nil {
+		return nil, fmt.Errorf("查询集群元数据失败: %w", err)
+	}
+	if len(meta.Topics) == 0 {
+		return nil, fmt.Errorf("topic 不存在: %s", kq.opts.Topic)
+	}
+	if err := meta.Topics[0].Error; err != nil {
+		return nil, fmt.Errorf("查询 topic %s 失败: %w", kq.opts.Topic, err)
+	}
+
+	ids := make([]int, 0, len(meta.Topics[0].Partitions))
+	for _, p := range meta.Topics[0].Partitions {
+		ids = append(ids, p.ID)
+	}
+	return ids, nil
+}
+
+// Stats 查询消费者组的积压消息数（各分区最新 offset 与已提交 offset 之差，包含已取出但尚未确认的消息）
+// 和消费者组的成员数（即正在消费的实例数），并附带本实例正在处理的任务数
+func (kq *KafkaQueue) Stats() (QueueStats, error) {
+	ids, err := kq.partitions()
+	if err != nil {
+		return QueueStats{}, err
+	}
+
+	ctx, cancel := context.WithTimeout(kq.ctx, kafkaRequestTimeout)
+	defer cancel()
+
+	requests := make([]kafka.OffsetRequest, 0, len(ids)*2)
+	for _, id := range ids {
+		requests = append(requests, kafka.FirstOffsetOf(id), kafka.LastOffsetOf(id))
+	}
+	offsets, err := kq.client.ListOffsets(ctx, &kafka.ListOffsetsRequest{
+		Topics: map[string][]kafka.OffsetRequest{kq.opts.Topic: requests},
+	})
+	if err != nil {
+		return QueueStats{}, fmt.Errorf("查询分区 offset 失败: %w", err)
+	}
+
+	committed, err := kq.client.OffsetFetch(ctx, &kafka.OffsetFetchRequest{
+		GroupID: kq.opts.GroupID,
+		Topics:  map[string][]int{kq.opts.Topic: ids},
+	})
+	if err != nil {
+		return QueueStats{}, fmt.Errorf("查询消费者组 offset 失败: %w", err)
+	}
+	committedByPartition := make(map[int]int64)
+	for _, p := range committed.Topics[kq.opts.Topic] {
+		committedByPartition[p.Partition] = p.CommittedOffset
+	}
+
+	depth := int64(0)
+	for _, p := range offsets.Topics[kq.opts.Topic] {
+		start, ok := committedByPartition[p.Partition]
+		if !ok || start < p.FirstOffset {
+			start = p.FirstOffset // 尚未提交过（或已提交的消息已过期删除）
+		}
+		if p.LastOffset > start {
+			depth += p.LastOffset - start
+		}
+	}
+
+	groups, err := kq.client.DescribeGroups(ctx, &kafka.DescribeGroupsRequest{GroupIDs: []string{kq.opts.GroupID}})
+	if err != nil {
+		return QueueStats{}, fmt.Errorf("查询消费者组失败: %w", err)
+	}
+	consumers := 0
+	if len(groups.Groups) > 0 {
+		consumers = len(groups.Groups[0].Members)
+	}
+
+	return QueueStats{Depth: int(depth), InFlight: kq.inFlight(), Consumers: consumers}, nil
+}
+
+// Close 关闭队列：中断进行中的拉取，离开消费者组并关闭连接
+func (kq *KafkaQueue) Close() error {
+	select {
+	case <-kq.closed:
+		return nil // 已经关闭
+	default:
+		close(kq.closed)
+		kq.cancel()
+
+		if err := kq.reader.Close(); err != nil {
+			slog.Warn("⚠️ 关闭 Kafka 消费者失败", "error", err)
+		}
+		if err := kq.writer.Close(); err != nil {
+			slog.Warn("⚠️ 关闭 Kafka 生产者失败", "error", err)
+		}
+
+		slog.Info("✓ Kafka 队列已关闭")
+		return nil
+	}
+}
@@ -0,0 +1,15 @@
+//go:build !kafka
+
+package queue
+
+import "fmt"
+
+// KafkaQueue 未使用 kafka 构建标签编译时的占位类型
+type KafkaQueue struct {
+	Queue
+}
+
+// NewKafkaQueue 未使用 kafka 构建标签编译，不包含 Kafka 客户端
+func NewKafkaQueue(opts KafkaOptions) (*KafkaQueue, error) {
+	return nil, fmt.Errorf("当前程序未包含 Kafka 支持，请使用 go build -tags kafka 重新编译")
+}
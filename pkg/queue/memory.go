@@ -1,51 +1,316 @@
 package queue
 
 import (
+    "context"
+    "errors"
     "fmt"
+    "sync"
+    "time"
+
     "github.com/z-wentao/voiceflow/pkg/models"
 )
 
+// DefaultMaxRedeliveries 内存队列中同一任务默认最多重新投递的次数
+const DefaultMaxRedeliveries = 5
+
+// ErrQueueClosed 队列已关闭
+var ErrQueueClosed = errors.New("队列已关闭")
+
+// 内存队列 Channel 已满时 Enqueue 的处理方式
+const (
+    EnqueueReject = "reject" // 立即返回“队列已满”
+    EnqueueBlock  = "block"  // 等待空位，最长等待 blockTimeout（调用方的 context 取消时提前返回）
+    EnqueueGrow   = "grow"   // 放入无上限的溢出列表，Worker 取走任务后依次补回 Channel
+)
+
+// DefaultEnqueueTimeout block 策略默认的最长等待时间
+const DefaultEnqueueTimeout = 10 * time.Second
+
+// ErrQueueFull 队列已满（reject 策略，或 block 策略等待超时）
+var ErrQueueFull = errors.New("队列已满")
+
+// DeadLetterFunc 任务被放弃时的回调（重新投递次数达到上限或无法退回队列），reason 为放弃的原因
+type DeadLetterFunc func(job *models.TranscriptionJob, reason string)
+
 // MemoryQueue 基于 Channel 的内存队列实现
+// 高优先级（Priority > 0）和普通任务分别放在两个 Channel 中，Dequeue 优先取高优先级任务；
+// Nack 重新入队的任务放回队尾，重新投递次数记录在任务上（job.Redeliveries），达到上限后交给死信回调
+// Channel 已满时按 Enqueue 策略（EnqueueReject / EnqueueBlock / EnqueueGrow）拒绝、等待或放入溢出列表
 type MemoryQueue struct {
-    queue chan *models.TranscriptionJob
+    high chan *models.TranscriptionJob
+    low  chan *models.TranscriptionJob
+
+    mu              sync.RWMutex // 保护 closed：写入 Channel 时持有读锁，Close 持有写锁，避免向已关闭的 Channel 发送
+    closed          bool
+    closing         chan struct{} // Close 开始时关闭，让 block 策略中等待空位的 Enqueue 释放读锁
+    closeOnce       sync.Once
+    maxRedeliveries int
+    onDeadLetter    DeadLetterFunc
+
+    policy       string
+    blockTimeout time.Duration
+
+    // grow 策略的溢出列表（按优先级各一个），非空时新任务也放入溢出列表，保持先进先出
+    overflowMu   sync.Mutex
+    highOverflow []*models.TranscriptionJob
+    lowOverflow  []*models.TranscriptionJob
+
+    inFlightGauge
 }
 
-// NewMemoryQueue 创建内存队列
+// NewMemoryQueue 创建内存队列（两个 Channel 各自缓冲 bufferSize 个任务，已满时立即拒绝）
 func NewMemoryQueue(bufferSize int) *MemoryQueue {
     return &MemoryQueue{
-	queue: make(chan *models.TranscriptionJob, bufferSize),
+	high:            make(chan *models.TranscriptionJob, bufferSize),
+	low:             make(chan *models.TranscriptionJob, bufferSize),
+	closing:         make(chan struct{}),
+	maxRedeliveries: DefaultMaxRedeliveries,
+	policy:          EnqueueReject,
+	blockTimeout:    DefaultEnqueueTimeout,
+    }
+}
+
+// SetEnqueuePolicy 设置 Channel 已满时的处理方式（为空时为 EnqueueReject），
+// blockTimeout 为 block 策略的最长等待时间（<= 0 时使用 DefaultEnqueueTimeout）。需在开始入队之前调用
+func (mq *MemoryQueue) SetEnqueuePolicy(policy string, blockTimeout time.Duration) error {
+    switch policy {
+    case "":
+	policy = EnqueueReject
+    case EnqueueReject, EnqueueBlock, EnqueueGrow:
+    default:
+	return fmt.Errorf("未知的入队策略: %s", policy)
+    }
+    if blockTimeout <= 0 {
+	blockTimeout = DefaultEnqueueTimeout
     }
+    mq.policy = policy
+    mq.blockTimeout = blockTimeout
+    return nil
 }
 
-// Enqueue 将任务加入队列
+// SetRedeliveryPolicy 设置重新投递次数上限（<= 0 时使用 DefaultMaxRedeliveries）和死信回调（可以为 nil，只丢弃任务）
+// 需在开始消费之前调用
+func (mq *MemoryQueue) SetRedeliveryPolicy(maxRedeliveries int, onDeadLetter DeadLetterFunc) {
+    if maxRedeliveries <= 0 {
+	maxRedeliveries = DefaultMaxRedeliveries
+    }
+    mq.maxRedeliveries = maxRedeliveries
+    mq.onDeadLetter = onDeadLetter
+}
+
+// Enqueue 将任务加入队列（按任务的 Priority 选择 Channel），Channel 已满时按入队策略处理
 func (mq *MemoryQueue) Enqueue(job *models.TranscriptionJob) error {
-    select {
-    case mq.queue <- job:
+    return mq.EnqueueContext(context.Background(), job)
+}
+
+// EnqueueContext 与 Enqueue 相同，block 策略等待空位时 ctx 取消（如上传的客户端断开）则停止等待
+func (mq *MemoryQueue) EnqueueContext(ctx context.Context, job *models.TranscriptionJob) error {
+    return mq.enqueue(ctx, job, mq.policy)
+}
+
+// enqueue 按 policy 写入任务
+func (mq *MemoryQueue) enqueue(ctx context.Context, job *models.TranscriptionJob, policy string) error {
+    mq.mu.RLock()
+    defer mq.mu.RUnlock()
+    if mq.closed {
+	return ErrQueueClosed
+    }
+
+    queue, overflow := mq.low, &mq.lowOverflow
+    if job.Priority > models.PriorityNormal {
+	queue, overflow = mq.high, &mq.highOverflow
+    }
+
+    switch policy {
+    case EnqueueGrow:
+	mq.overflowMu.Lock()
+	defer mq.overflowMu.Unlock()
+	if len(*overflow) == 0 {
+	    select {
+	    case queue <- job:
+		return nil
+	    default:
+	    }
+	}
+	*overflow = append(*overflow, job)
 	return nil
+
+    case EnqueueBlock:
+	select {
+	case queue <- job:
+	    return nil
+	default:
+	}
+
+	timer := time.NewTimer(mq.blockTimeout)
+	defer timer.Stop()
+	select {
+	case queue <- job:
+	    return nil
+	case <-ctx.Done():
+	    return fmt.Errorf("等待队列空位时取消: %w", ctx.Err())
+	case <-timer.C:
+	    return fmt.Errorf("%w（等待 %s 后仍没有空位）", ErrQueueFull, mq.blockTimeout)
+	case <-mq.closing:
+	    return ErrQueueClosed
+	}
+
     default:
-	return fmt.Errorf("队列已满")
+	select {
+	case queue <- job:
+	    return nil
+	default:
+	    return ErrQueueFull
+	}
+    }
+}
+
+// refill 把溢出列表中最早的任务依次补回 Channel 的空位（grow 策略，每次取出任务后调用）
+func (mq *MemoryQueue) refill() {
+    mq.mu.RLock()
+    defer mq.mu.RUnlock()
+    if mq.closed {
+	return
     }
+
+    mq.overflowMu.Lock()
+    defer mq.overflowMu.Unlock()
+    mq.highOverflow = fill(mq.high, mq.highOverflow)
+    mq.lowOverflow = fill(mq.low, mq.lowOverflow)
+}
+
+// fill 按顺序把 pending 写入 queue 直到写满，返回剩下的任务
+func fill(queue chan *models.TranscriptionJob, pending []*models.TranscriptionJob) []*models.TranscriptionJob {
+    for len(pending) > 0 {
+	select {
+	case queue <- pending[0]:
+	    pending[0] = nil
+	    pending = pending[1:]
+	default:
+	    return pending
+	}
+    }
+    return nil
 }
 
 // Dequeue 从队列取出任务（阻塞等待）
+// 先非阻塞地检查高优先级 Channel，没有任务时再同时等待两个 Channel；
+// 队列关闭后继续取出两个 Channel 中剩余的任务，都取空后返回 ErrQueueClosed
 func (mq *MemoryQueue) Dequeue() (*models.TranscriptionJob, error) {
-    job, ok := <-mq.queue
-    if !ok {
-	return nil, fmt.Errorf("队列已关闭")
+    high, low := mq.high, mq.low
+
+    select {
+    case job, ok := <-high:
+	if ok {
+	    return mq.dequeued(job), nil
+	}
+	high = nil
+    default:
+    }
+
+    // 已关闭并取空的 Channel 置为 nil，不再参与 select
+    for high != nil || low != nil {
+	select {
+	case job, ok := <-high:
+	    if !ok {
+		high = nil
+		continue
+	    }
+	    return mq.dequeued(job), nil
+	case job, ok := <-low:
+	    if !ok {
+		low = nil
+		continue
+	    }
+	    return mq.dequeued(job), nil
+	}
+    }
+    return nil, ErrQueueClosed
+}
+
+// dequeued grow 策略下取走任务后把溢出列表中的任务补回空位
+func (mq *MemoryQueue) dequeued(job *models.TranscriptionJob) *models.TranscriptionJob {
+    if mq.policy == EnqueueGrow {
+	mq.refill()
     }
-    return job, nil
+    return job
 }
 
+// Ack 确认消息（内存队列取出任务时已经移除，无需确认）
 func (mq *MemoryQueue) Ack(job *models.TranscriptionJob) error {
     return nil
 }
 
-func (mq *MemoryQueue) Nack(job *models.TranscriptionJob, requeue bool) error {
+// Nack 拒绝消息
+// requeue 为 false 时直接丢弃（任务已由调用方标记为失败）；
+// requeue 为 true 时放回队尾并累加 job.Redeliveries，次数超过上限或队列已满时交给死信回调并返回错误。
+// requeueAfter > 0 时通过 time.AfterFunc 延迟放回，立即返回 nil，之后的失败只交给死信回调。
+// 队列已关闭时只返回错误，不调用死信回调（服务正在关闭，任务保持 pending，由下次启动的恢复流程重新入队）
+func (mq *MemoryQueue) Nack(job *models.TranscriptionJob, requeue bool, requeueAfter time.Duration) error {
+    if !requeue {
+	return nil
+    }
+    if requeueAfter > 0 {
+	time.AfterFunc(requeueAfter, func() {
+	    mq.Nack(job, true, 0)
+	})
+	return nil
+    }
+
+    if job.Redeliveries >= mq.maxRedeliveries {
+	reason := fmt.Sprintf("重新投递 %d 次后仍未成功，不再重试", job.Redeliveries)
+	mq.deadLetter(job, reason)
+	return fmt.Errorf("任务 %s %s", job.JobID, reason)
+    }
+
+    // 重新入队由 Worker 调用，不能等待空位（Worker 自己就是消费者），block 策略下已满时与 reject 相同
+    policy := mq.policy
+    if policy == EnqueueBlock {
+	policy = EnqueueReject
+    }
+    job.Redeliveries++
+    if err := mq.enqueue(context.Background(), job, policy); err != nil {
+	job.Redeliveries--
+	if !errors.Is(err, ErrQueueClosed) {
+	    mq.deadLetter(job, fmt.Sprintf("重新入队失败: %v", err))
+	}
+	return fmt.Errorf("任务 %s 重新入队失败: %w", job.JobID, err)
+    }
+    return nil
+}
+
+// deadLetter 调用死信回调
+func (mq *MemoryQueue) deadLetter(job *models.TranscriptionJob, reason string) {
+    if mq.onDeadLetter != nil {
+	mq.onDeadLetter(job, reason)
+    }
+}
+
+// Ping 检查队列是否可用（内存队列始终可用）
+func (mq *MemoryQueue) Ping() error {
     return nil
 }
 
-// Close 关闭队列
+// Stats 队列深度（两个 Channel 中缓冲的任务数与 grow 策略溢出列表中的任务数之和）和正在处理的任务数
+func (mq *MemoryQueue) Stats() (QueueStats, error) {
+    mq.overflowMu.Lock()
+    overflow := len(mq.highOverflow) + len(mq.lowOverflow)
+    mq.overflowMu.Unlock()
+    return QueueStats{Depth: len(mq.high) + len(mq.low) + overflow, InFlight: mq.inFlight()}, nil
+}
+
+// Close 关闭队列（重复调用无副作用）
+// 两个 Channel 中已有的任务仍可被 Dequeue 取出（都取空后返回 ErrQueueClosed），Enqueue 和 Nack 重新入队返回错误；
+// 溢出列表中的任务不再投递，保持 pending，由下次启动的恢复流程重新入队
 func (mq *MemoryQueue) Close() error {
-    close(mq.queue)
+    mq.closeOnce.Do(func() { close(mq.closing) })
+    mq.mu.Lock()
+    defer mq.mu.Unlock()
+    if mq.closed {
+	return nil
+    }
+    mq.closed = true
+    close(mq.high)
+    close(mq.low)
     return nil
 }
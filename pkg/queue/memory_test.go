@@ -0,0 +1,355 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/z-wentao/voiceflow/pkg/models"
+)
+
+func newTestJob(id string, priority int) *models.TranscriptionJob {
+	return &models.TranscriptionJob{JobID: id, Priority: priority}
+}
+
+func TestMemoryQueueFullPolicies(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  string
+		timeout time.Duration
+		ctx     func() (context.Context, context.CancelFunc)
+		wantErr error
+	}{
+		{"reject 立即拒绝", EnqueueReject, 0, nil, ErrQueueFull},
+		{"block 等待超时", EnqueueBlock, 20 * time.Millisecond, nil, ErrQueueFull},
+		{"block 调用方取消", EnqueueBlock, time.Minute, func() (context.Context, context.CancelFunc) {
+			return context.WithTimeout(context.Background(), 20*time.Millisecond)
+		}, context.DeadlineExceeded},
+		{"grow 放入溢出列表", EnqueueGrow, 0, nil, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mq := NewMemoryQueue(1)
+			if err := mq.SetEnqueuePolicy(tt.policy, tt.timeout); err != nil {
+				t.Fatalf("SetEnqueuePolicy: %v", err)
+			}
+			if err := mq.Enqueue(newTestJob("job-1", 0)); err != nil {
+				t.Fatalf("Enqueue: %v", err)
+			}
+
+			ctx := context.Background()
+			if tt.ctx != nil {
+				var cancel context.CancelFunc
+				ctx, cancel = tt.ctx()
+				defer cancel()
+			}
+			err := mq.EnqueueContext(ctx, newTestJob("job-2", 0))
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v，期望 %v", err, tt.wantErr)
+			}
+
+			wantDepth := 1
+			if tt.wantErr == nil {
+				wantDepth = 2
+			}
+			if stats, _ := mq.Stats(); stats.Depth != wantDepth {
+				t.Fatalf("队列深度 = %d，期望 %d", stats.Depth, wantDepth)
+			}
+		})
+	}
+}
+
+func TestMemoryQueueBlockWaitsForSpace(t *testing.T) {
+	mq := NewMemoryQueue(1)
+	mq.SetEnqueuePolicy(EnqueueBlock, time.Minute)
+	mq.Enqueue(newTestJob("job-1", 0))
+
+	done := make(chan error, 1)
+	go func() { done <- mq.Enqueue(newTestJob("job-2", 0)) }()
+
+	select {
+	case err := <-done:
+		t.Fatalf("队列已满时不应立即返回: %v", err)
+	case <-time.After(20 * time.Millisecond):
+	}
+	if _, err := mq.Dequeue(); err != nil {
+		t.Fatalf("Dequeue: %v", err)
+	}
+	if err := <-done; err != nil {
+		t.Fatalf("有空位后 Enqueue = %v", err)
+	}
+}
+
+func TestMemoryQueueBlockReleasedByClose(t *testing.T) {
+	mq := NewMemoryQueue(1)
+	mq.SetEnqueuePolicy(EnqueueBlock, time.Minute)
+	mq.Enqueue(newTestJob("job-1", 0))
+
+	done := make(chan error, 1)
+	go func() { done <- mq.Enqueue(newTestJob("job-2", 0)) }()
+	time.Sleep(10 * time.Millisecond)
+	mq.Close()
+
+	select {
+	case err := <-done:
+		if !errors.Is(err, ErrQueueClosed) {
+			t.Fatalf("err = %v，期望 ErrQueueClosed", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Close 后等待空位的 Enqueue 没有返回")
+	}
+}
+
+// TestMemoryQueueConcurrentProducers 多个生产者并发入队，消费者间歇性变慢：
+// 入队成功的任务恰好被取出一次，grow 和 block 策略不丢任务，reject 策略只拒绝而不丢失已接受的任务
+func TestMemoryQueueConcurrentProducers(t *testing.T) {
+	const producers, perProducer = 8, 50
+
+	tests := []struct {
+		policy      string
+		wantAllSent bool
+	}{
+		{EnqueueReject, false},
+		{EnqueueBlock, true},
+		{EnqueueGrow, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.policy, func(t *testing.T) {
+			mq := NewMemoryQueue(4)
+			mq.SetEnqueuePolicy(tt.policy, 10*time.Second)
+
+			var accepted, rejected atomic.Int64
+			var wg sync.WaitGroup
+			for p := 0; p < producers; p++ {
+				wg.Add(1)
+				go func(p int) {
+					defer wg.Done()
+					for i := 0; i < perProducer; i++ {
+						err := mq.Enqueue(newTestJob(fmt.Sprintf("job-%d-%d", p, i), i%2))
+						switch {
+						case err == nil:
+							accepted.Add(1)
+						case errors.Is(err, ErrQueueFull):
+							rejected.Add(1)
+						default:
+							t.Errorf("Enqueue: %v", err)
+						}
+					}
+				}(p)
+			}
+
+			seen := make(map[string]bool)
+			consumed := make(chan struct{})
+			go func() {
+				defer close(consumed)
+				for n := 0; ; n++ {
+					job, err := mq.Dequeue()
+					if err != nil {
+						return
+					}
+					if seen[job.JobID] {
+						t.Errorf("任务 %s 被取出两次", job.JobID)
+					}
+					seen[job.JobID] = true
+					if n%10 == 0 {
+						time.Sleep(time.Millisecond) // 间歇性变慢
+					}
+				}
+			}()
+
+			// 等消费者取完再关闭（关闭后溢出列表中的任务不再投递）
+			wg.Wait()
+			for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(time.Millisecond) {
+				if stats, _ := mq.Stats(); stats.Depth == 0 {
+					break
+				}
+			}
+			mq.Close()
+			<-consumed
+
+			if int64(len(seen)) != accepted.Load() {
+				t.Fatalf("取出 %d 个任务，入队成功 %d 个", len(seen), accepted.Load())
+			}
+			if accepted.Load()+rejected.Load() != producers*perProducer {
+				t.Fatalf("成功 %d + 拒绝 %d != %d", accepted.Load(), rejected.Load(), producers*perProducer)
+			}
+			if tt.wantAllSent && rejected.Load() > 0 {
+				t.Fatalf("%s 策略拒绝了 %d 个任务", tt.policy, rejected.Load())
+			}
+		})
+	}
+}
+
+func TestMemoryQueueGrowKeepsOrder(t *testing.T) {
+	mq := NewMemoryQueue(2)
+	mq.SetEnqueuePolicy(EnqueueGrow, 0)
+	for i := 0; i < 10; i++ {
+		if err := mq.Enqueue(newTestJob(fmt.Sprintf("job-%d", i), 0)); err != nil {
+			t.Fatalf("Enqueue: %v", err)
+		}
+	}
+	for i := 0; i < 10; i++ {
+		job, err := mq.Dequeue()
+		if err != nil {
+			t.Fatalf("Dequeue: %v", err)
+		}
+		if want := fmt.Sprintf("job-%d", i); job.JobID != want {
+			t.Fatalf("第 %d 个任务 = %s，期望 %s", i, job.JobID, want)
+		}
+	}
+}
+
+func TestMemoryQueueNack(t *testing.T) {
+	tests := []struct {
+		name            string
+		redeliveries    int
+		requeueAfter    time.Duration
+		wantRequeued    bool
+		wantDeadLetters int
+	}{
+		{"立即重新入队", 0, 0, true, 0},
+		{"延迟重新入队", 0, 30 * time.Millisecond, true, 0},
+		{"达到重新投递上限", 2, 0, false, 1},
+		{"延迟后达到上限", 2, 10 * time.Millisecond, false, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mq := NewMemoryQueue(4)
+			var deadLetters atomic.Int32
+			mq.SetRedeliveryPolicy(2, func(job *models.TranscriptionJob, reason string) { deadLetters.Add(1) })
+
+			job := newTestJob("job-1", 0)
+			job.Redeliveries = tt.redeliveries
+			start := time.Now()
+			mq.Nack(job, true, tt.requeueAfter)
+
+			if tt.requeueAfter > 0 {
+				if stats, _ := mq.Stats(); stats.Depth != 0 {
+					t.Fatal("延迟重新入队的任务不应立即回到队列")
+				}
+			}
+			if tt.wantRequeued {
+				got, err := mq.Dequeue()
+				if err != nil {
+					t.Fatalf("Dequeue: %v", err)
+				}
+				if elapsed := time.Since(start); elapsed < tt.requeueAfter {
+					t.Fatalf("%s 后就重新入队，期望至少 %s", elapsed, tt.requeueAfter)
+				}
+				if got.JobID != job.JobID || got.Redeliveries != tt.redeliveries+1 {
+					t.Fatalf("重新入队的任务 = %+v", got)
+				}
+			} else {
+				time.Sleep(tt.requeueAfter + 20*time.Millisecond)
+				if stats, _ := mq.Stats(); stats.Depth != 0 {
+					t.Fatal("超过上限的任务不应重新入队")
+				}
+			}
+			if int(deadLetters.Load()) != tt.wantDeadLetters {
+				t.Fatalf("死信 %d 次，期望 %d", deadLetters.Load(), tt.wantDeadLetters)
+			}
+		})
+	}
+}
+
+func TestMemoryQueueCloseDrainsBothChannels(t *testing.T) {
+	mq := NewMemoryQueue(4)
+	mq.Enqueue(newTestJob("high", models.PriorityNormal+1))
+	mq.Enqueue(newTestJob("low-1", 0))
+	mq.Enqueue(newTestJob("low-2", 0))
+	mq.Close()
+
+	if err := mq.Enqueue(newTestJob("late", 0)); !errors.Is(err, ErrQueueClosed) {
+		t.Fatalf("关闭后 Enqueue = %v，期望 ErrQueueClosed", err)
+	}
+
+	var got []string
+	for {
+		job, err := mq.Dequeue()
+		if err != nil {
+			if !errors.Is(err, ErrQueueClosed) {
+				t.Fatalf("Dequeue: %v", err)
+			}
+			break
+		}
+		got = append(got, job.JobID)
+	}
+	if fmt.Sprint(got) != "[high low-1 low-2]" {
+		t.Fatalf("取出的任务 = %v，期望高优先级在前并取完所有任务", got)
+	}
+}
+
+func TestMemoryQueueNackRequeueOrder(t *testing.T) {
+	mq := NewMemoryQueue(4)
+	for _, id := range []string{"job-1", "job-2", "job-3"} {
+		mq.Enqueue(newTestJob(id, 0))
+	}
+
+	first, err := mq.Dequeue()
+	if err != nil {
+		t.Fatalf("Dequeue: %v", err)
+	}
+	if err := mq.Ack(first); err != nil {
+		t.Fatalf("Ack: %v", err)
+	}
+	second, _ := mq.Dequeue()
+	if err := mq.Nack(second, true, 0); err != nil {
+		t.Fatalf("Nack: %v", err)
+	}
+
+	var got []string
+	for i := 0; i < 2; i++ {
+		job, err := mq.Dequeue()
+		if err != nil {
+			t.Fatalf("Dequeue: %v", err)
+		}
+		got = append(got, job.JobID)
+	}
+	if fmt.Sprint(got) != "[job-3 job-2]" {
+		t.Fatalf("取出的任务 = %v，期望重新入队的任务排在队尾", got)
+	}
+}
+
+func TestMemoryQueueNackFailure(t *testing.T) {
+	tests := []struct {
+		name            string
+		closed          bool
+		requeue         bool
+		wantErr         error
+		wantDeadLetters int
+	}{
+		{"不重新入队直接丢弃", false, false, nil, 0},
+		{"队列已满", false, true, ErrQueueFull, 1},
+		{"队列已关闭", true, true, ErrQueueClosed, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mq := NewMemoryQueue(1)
+			var deadLetters atomic.Int32
+			mq.SetRedeliveryPolicy(3, func(job *models.TranscriptionJob, reason string) { deadLetters.Add(1) })
+			mq.Enqueue(newTestJob("job-0", 0))
+			if tt.closed {
+				mq.Close()
+			}
+
+			job := newTestJob("job-1", 0)
+			err := mq.Nack(job, tt.requeue, 0)
+			if tt.wantErr == nil && err != nil || tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Nack = %v，期望 %v", err, tt.wantErr)
+			}
+			if job.Redeliveries != 0 {
+				t.Fatalf("重新入队失败后 Redeliveries = %d，期望 0", job.Redeliveries)
+			}
+			if int(deadLetters.Load()) != tt.wantDeadLetters {
+				t.Fatalf("死信 %d 次，期望 %d", deadLetters.Load(), tt.wantDeadLetters)
+			}
+			if stats, _ := mq.Stats(); stats.Depth != 1 {
+				t.Fatalf("队列深度 = %d，期望 1", stats.Depth)
+			}
+		})
+	}
+}
@@ -0,0 +1,231 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"github.com/z-wentao/voiceflow/pkg/models"
+)
+
+// NatsOptions NATS JetStream 队列参数
+type NatsOptions struct {
+	URL      string        // 服务地址，如 nats://localhost:4222
+	Stream   string        // Stream 名称（不存在时自动创建）
+	Subject  string        // 任务消息的 subject
+	Consumer string        // durable pull consumer 名称，多个实例共享同一个消费者
+	AckWait  time.Duration // 投递后未确认的超时时间，超时后重新投递（应大于单个任务的最长处理时间）
+}
+
+// natsFetchWait 每次拉取请求的最长等待时间（超时后重新发起，期间可响应 Close）
+const natsFetchWait = 30 * time.Second
+
+// NatsQueue NATS JetStream 队列实现
+// 1. WorkQueue 保留策略的 Stream：消息被确认后删除，每条消息只投递给一个消费者
+// 2. durable pull consumer：所有实例共享消费进度，Worker 空闲时才拉取一条消息，不会积压在本地
+// 3. 手动 Ack / Nak / Term 保证消息可靠性（与 RabbitMQ 实现一致）
+//
+// JetStream 不支持消息优先级，任务按入队顺序处理
+type NatsQueue struct {
+	opts     NatsOptions
+	conn     *nats.Conn
+	js       jetstream.JetStream
+	consumer jetstream.Consumer
+	closed   chan struct{}
+	ctx      context.Context
+	cancel   context.CancelFunc
+
+	inFlightGauge
+}
+
+// NewNatsQueue 创建 NATS JetStream 队列
+func NewNatsQueue(opts NatsOptions) (*NatsQueue, error) {
+	conn, err := nats.Connect(opts.URL, nats.Name("voiceflow"), nats.MaxReconnects(-1))
+	if err != nil {
+		return nil, fmt.Errorf("连接失败: %w", err)
+	}
+
+	js, err := jetstream.New(conn)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("初始化 JetStream 失败: %w", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	nq := &NatsQueue{
+		opts:   opts,
+		conn:   conn,
+		js:     js,
+		closed: make(chan struct{}),
+		ctx:    ctx,
+		cancel: cancel,
+	}
+
+	if err := nq.setup(); err != nil {
+		cancel()
+		conn.Close()
+		return nil, err
+	}
+
+	slog.Info("✓ NATS JetStream 队列初始化成功", "stream", opts.Stream, "subject", opts.Subject, "consumer", opts.Consumer)
+	return nq, nil
+}
+
+// setup 声明 Stream 和 durable consumer（幂等操作，已存在时更新配置）
+func (nq *NatsQueue) setup() error {
+	ctx, cancel := context.WithTimeout(nq.ctx, 10*time.Second)
+	defer cancel()
+
+	stream, err := nq.js.CreateOrUpdateStream(ctx, jetstream.StreamConfig{
+		Name:      nq.opts.Stream,
+		Subjects:  []string{nq.opts.Subject},
+		Retention: jetstream.WorkQueuePolicy, // 确认后删除消息
+		Storage:   jetstream.FileStorage,     // 持久化到磁盘
+	})
+	if err != nil {
+		return fmt.Errorf("声明 Stream 失败: %w", err)
+	}
+
+	nq.consumer, err = stream.CreateOrUpdateConsumer(ctx, jetstream.ConsumerConfig{
+		Durable:   nq.opts.Consumer,
+		AckPolicy: jetstream.AckExplicitPolicy, // 手动确认
+		AckWait:   nq.opts.AckWait,
+	})
+	if err != nil {
+		return fmt.Errorf("声明消费者失败: %w", err)
+	}
+	return nil
+}
+
+// Enqueue 将任务发布到 Stream（等待 JetStream 确认已持久化）
+func (nq *NatsQueue) Enqueue(job *models.TranscriptionJob) error {
+	body, err := models.MarshalJob(job)
+	if err != nil {
+		return fmt.Errorf("序列化任务失败: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(nq.ctx, 5*time.Second)
+	defer cancel()
+
+	if _, err := nq.js.Publish(ctx, nq.opts.Subject, body); err != nil {
+		return fmt.Errorf("发布消息失败: %w", err)
+	}
+	return nil
+}
+
+// Dequeue 从 durable consumer 拉取一条消息（阻塞，关闭队列后返回错误）
+// 每个 Worker 各自发起拉取请求，JetStream 保证每条消息只投递给一个请求
+func (nq *NatsQueue) Dequeue() (*models.TranscriptionJob, error) {
+	for {
+		select {
+		case <-nq.closed:
+			return nil, fmt.Errorf("队列已关闭")
+		default:
+		}
+
+		ctx, cancel := context.WithTimeout(nq.ctx, natsFetchWait)
+		msg, err := nq.consumer.Next(jetstream.FetchContext(ctx))
+		cancel()
+		if err != nil {
+			if nq.ctx.Err() != nil {
+				return nil, fmt.Errorf("队列已关闭")
+			}
+			if errors.Is(err, nats.ErrTimeout) || errors.Is(err, context.DeadlineExceeded) {
+				continue // 暂无消息，重新拉取
+			}
+			return nil, fmt.Errorf("拉取消息失败: %w", err)
+		}
+
+		// 反序列化任务（旧版本生产者发布的消息自动升级）
+		job, err := models.UnmarshalJob(msg.Data())
+		if err != nil {
+			// 反序列化失败，终止消息（不再重新投递）
+			msg.Term()
+			return nil, err
+		}
+
+		// 保存消息用于后续确认
+		job.NatsMsg = msg
+		return job, nil
+	}
+}
+
+// Ack 确认消息（任务处理成功），WorkQueue Stream 随即删除该消息
+func (nq *NatsQueue) Ack(job *models.TranscriptionJob) error {
+	msg, ok := job.NatsMsg.(jetstream.Msg)
+	if !ok {
+		return nil // 不是 NATS 消息，忽略
+	}
+	return msg.Ack()
+}
+
+// Nack 拒绝消息（任务处理失败）
+// requeue=true 时 Nak，立即（requeueAfter > 0 时由服务端延迟）重新投递；requeue=false 时 Term，不再投递
+func (nq *NatsQueue) Nack(job *models.TranscriptionJob, requeue bool, requeueAfter time.Duration) error {
+	msg, ok := job.NatsMsg.(jetstream.Msg)
+	if !ok {
+		return nil // 不是 NATS 消息，忽略
+	}
+	if requeue && requeueAfter > 0 {
+		return msg.NakWithDelay(requeueAfter)
+	}
+	if requeue {
+		return msg.Nak()
+	}
+	return msg.Term()
+}
+
+// Ping 检查 NATS 连接和消费者是否可用
+func (nq *NatsQueue) Ping() error {
+	select {
+	case <-nq.closed:
+		return fmt.Errorf("队列已关闭")
+	default:
+	}
+
+	if !nq.conn.IsConnected() {
+		return fmt.Errorf("连接已断开 (状态: %s)", nq.conn.Status())
+	}
+	if _, err := nq.consumerInfo(); err != nil {
+		return fmt.Errorf("查询消费者信息失败: %w", err)
+	}
+	return nil
+}
+
+// Stats 查询消费者的待投递消息数和正在等待消息的拉取请求数（即空闲的 Worker 数），并附带本实例正在处理的任务数
+func (nq *NatsQueue) Stats() (QueueStats, error) {
+	info, err := nq.consumerInfo()
+	if err != nil {
+		return QueueStats{}, fmt.Errorf("查询消费者信息失败: %w", err)
+	}
+	return QueueStats{Depth: int(info.NumPending), InFlight: nq.inFlight(), Consumers: info.NumWaiting}, nil
+}
+
+// consumerInfo 从服务端查询消费者状态
+func (nq *NatsQueue) consumerInfo() (*jetstream.ConsumerInfo, error) {
+	ctx, cancel := context.WithTimeout(nq.ctx, 5*time.Second)
+	defer cancel()
+	return nq.consumer.Info(ctx)
+}
+
+// Close 关闭队列：中断进行中的拉取请求，并在发送完缓冲的确认后断开连接
+func (nq *NatsQueue) Close() error {
+	select {
+	case <-nq.closed:
+		return nil // 已经关闭
+	default:
+		close(nq.closed)
+		nq.cancel()
+
+		if err := nq.conn.Drain(); err != nil {
+			nq.conn.Close()
+		}
+
+		slog.Info("✓ NATS 队列已关闭")
+		return nil
+	}
+}
@@ -1,6 +1,67 @@
 package queue
 
-import "github.com/z-wentao/voiceflow/pkg/models"
+import (
+    "context"
+    "sync/atomic"
+    "time"
+
+    "github.com/z-wentao/voiceflow/pkg/models"
+)
+
+// QueueStats 队列状态（管理接口展示）
+type QueueStats struct {
+    Depth     int `json:"depth"`              // 等待处理的任务数（不含已投递给 Worker 的）
+    InFlight  int `json:"in_flight"`          // 本实例 Worker 正在处理的任务数
+    Consumers int `json:"consumers"`          // 消费者数量（内存队列为 0）
+    Prefetch  int `json:"prefetch,omitempty"` // 本实例消费者的预取数量（仅 RabbitMQ）
+}
+
+// InFlightTracker 统计本实例正在处理的任务数的队列，Worker 在处理任务前后调用
+// Broker 只知道已投递、尚未确认的消息数（包含预取后还没有被 Worker 取走的），这里只统计真正在处理的任务
+type InFlightTracker interface {
+    Started()
+    Finished()
+}
+
+// inFlightGauge 进行中的任务计数，嵌入各队列实现 InFlightTracker
+type inFlightGauge struct {
+    n atomic.Int64
+}
+
+// Started 开始处理一个任务
+func (g *inFlightGauge) Started() {
+    g.n.Add(1)
+}
+
+// Finished 一个任务处理结束
+func (g *inFlightGauge) Finished() {
+    g.n.Add(-1)
+}
+
+// inFlight 当前正在处理的任务数
+func (g *inFlightGauge) inFlight() int {
+    return int(g.n.Load())
+}
+
+// ContextEnqueuer 入队时可能等待的队列（内存队列的 block 策略），ctx 取消时停止等待
+type ContextEnqueuer interface {
+    EnqueueContext(ctx context.Context, job *models.TranscriptionJob) error
+}
+
+// EnqueueContext 将任务加入队列：队列实现了 ContextEnqueuer 时传入 ctx（如请求的 context，客户端断开后不再等待），
+// 否则调用 Enqueue
+func EnqueueContext(ctx context.Context, q Queue, job *models.TranscriptionJob) error {
+    if ce, ok := q.(ContextEnqueuer); ok {
+	return ce.EnqueueContext(ctx, job)
+    }
+    return q.Enqueue(job)
+}
+
+// PrefetchSetter 支持运行时调整消费者预取数量的队列（RabbitMQ）
+type PrefetchSetter interface {
+    // SetPrefetch 立即对当前消费通道生效，重连后沿用新的值
+    SetPrefetch(prefetchCount int) error
+}
 
 // Queue 任务队列接口
 // 面试亮点：使用接口抽象，方便后续切换到 RabbitMQ
@@ -16,7 +77,14 @@ type Queue interface {
 
     // Nack 拒绝消息（任务处理失败）
     // requeue: 是否重新入队
-    Nack(job *models.TranscriptionJob, requeue bool) error
+    // requeueAfter: 重新入队前的等待时间（requeue 为 true 时有效，0 表示立即重新入队），用于临时错误的延迟重试
+    Nack(job *models.TranscriptionJob, requeue bool, requeueAfter time.Duration) error
+
+    // Ping 检查队列连接是否可用（用于健康检查）
+    Ping() error
+
+    // Stats 查询队列深度、本实例正在处理的任务数和消费者数量
+    Stats() (QueueStats, error)
 
     // Close 关闭队列
     Close() error
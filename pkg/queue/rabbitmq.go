@@ -2,9 +2,9 @@ package queue
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
 	"sync"
 	"time"
 
@@ -12,15 +12,29 @@ import (
 	"github.com/z-wentao/voiceflow/pkg/models"
 )
 
+// 断线重连的退避时间：从 reconnectMinDelay 开始每次翻倍，最长 reconnectMaxDelay（变量便于测试缩短）
+var (
+	reconnectMinDelay = 1 * time.Second
+	reconnectMaxDelay = 30 * time.Second
+)
+
+// ErrPublishNacked Broker 拒绝了消息（发布确认返回 nack，或等待确认期间连接断开）
+var ErrPublishNacked = errors.New("Broker 未确认消息")
+
 // RabbitMQQueue RabbitMQ 队列实现（简化版）
 // 核心改进：
 // 1. 单一 Consumer（所有 Worker 共享）
 // 2. 通过 QoS prefetchCount 控制并发
 // 3. 手动 Ack/Nack 保证消息可靠性
+// 4. 连接意外断开（如 RabbitMQ 重启）时自动重连：发布和消费连接各自监听 NotifyClose，
+//    按指数退避重新建立连接，期间 Enqueue / Dequeue 等待重连完成
+// 5. 可选的发布确认（publisher confirms）：Enqueue 等到 Broker 确认消息已写入队列才返回
 type RabbitMQQueue struct {
-	url       string
-	queueName string
-	closed    chan struct{}
+	url           string
+	queueName     string
+	prefetchCount int
+	confirms      bool // 发布通道是否开启确认模式
+	closed        chan struct{}
 	ctx       context.Context
 	cancel    context.CancelFunc
 
@@ -36,18 +50,35 @@ type RabbitMQQueue struct {
 
 	// 用于保护 Ack/Nack 操作（RabbitMQ Channel 不是并发安全的）
 	ackMutex              sync.Mutex
+
+	// 保护上面的连接、通道、deliveriesGoChannel（重连时替换）和 prefetchCount（SetPrefetch 修改）
+	// publishReady / consumeReady 在连接可用时为已关闭的 channel，断开后换成新的未关闭 channel，重连成功时关闭
+	connMutex             sync.Mutex
+	publishReady          chan struct{}
+	consumeReady          chan struct{}
+
+	inFlightGauge
 }
 
 // NewRabbitMQQueue 创建 RabbitMQ 队列
-func NewRabbitMQQueue(url, queueName string) (*RabbitMQQueue, error) {
+// prefetchCount 为消费者的预取数量（<= 0 时为 1），应等于或略大于 Worker 数量，否则多出的 Worker 拿不到消息
+// confirms 为 true 时发布通道开启确认模式，Enqueue 等待 Broker 的 ack，收到 nack 或超时返回错误（每次入队多一次往返）
+func NewRabbitMQQueue(url, queueName string, prefetchCount int, confirms bool) (*RabbitMQQueue, error) {
 	ctx, cancel := context.WithCancel(context.Background())
 
+	if prefetchCount <= 0 {
+		prefetchCount = 1
+	}
 	rq := &RabbitMQQueue{
-		url:       url,
-		queueName: queueName,
-		closed:    make(chan struct{}),
-		ctx:       ctx,
-		cancel:    cancel,
+		url:           url,
+		queueName:     queueName,
+		prefetchCount: prefetchCount,
+		confirms:      confirms,
+		closed:        make(chan struct{}),
+		ctx:           ctx,
+		cancel:        cancel,
+		publishReady:  make(chan struct{}),
+		consumeReady:  make(chan struct{}),
 	}
 
 	// 1. 建立发布连接
@@ -58,12 +89,11 @@ func NewRabbitMQQueue(url, queueName string) (*RabbitMQQueue, error) {
 
 	// 2. 建立消费连接
 	if err := rq.setupConsumer(); err != nil {
-		cancel()
-		rq.closePublisher()
+		rq.Close()
 		return nil, fmt.Errorf("初始化消费者失败: %w", err)
 	}
 
-	log.Printf("✓ RabbitMQ 队列初始化成功 (队列: %s)", queueName)
+	slog.Info("✓ RabbitMQ 队列初始化成功", "queue", queueName)
 
 	return rq, nil
 }
@@ -81,25 +111,48 @@ func (rq *RabbitMQQueue) setupPublisher() error {
 		return fmt.Errorf("创建 RabbitMQ Channel 失败: %w", err)
 	}
 
-	// 声明持久化队列（幂等操作）
+	// 声明持久化优先级队列（幂等操作）
+	// 已存在的队列参数不同（如旧版本声明的非优先级队列）时 RabbitMQ 返回 PRECONDITION_FAILED，
+	// 需要删除旧队列或换一个队列名
 	_, err = ch.QueueDeclare(
 		rq.queueName, // name
 		true,         // durable: 持久化队列
 		false,        // autoDelete: 不自动删除
 		false,        // exclusive: 非独占
 		false,        // noWait
-		nil,          // args
+		amqp.Table{"x-max-priority": int32(models.MaxPriority)}, // args: 启用消息优先级
 	)
 	if err != nil {
 		ch.Close()
 		conn.Close()
-		return fmt.Errorf("声明队列失败: %w", err)
+		return fmt.Errorf("声明队列失败（已存在的同名队列不是优先级队列时需要先删除）: %w", err)
+	}
+
+	// 确认模式：Broker 把消息写入队列（持久化消息写入磁盘）后回复 ack，无法处理时回复 nack
+	if rq.confirms {
+		if err := ch.Confirm(false); err != nil {
+			ch.Close()
+			conn.Close()
+			return fmt.Errorf("开启发布确认失败: %w", err)
+		}
 	}
 
+	rq.connMutex.Lock()
+	if rq.isClosed() {
+		// 重连期间队列被关闭
+		rq.connMutex.Unlock()
+		ch.Close()
+		conn.Close()
+		return ErrQueueClosed
+	}
 	rq.publishConn = conn
 	rq.publishRabbitChannel = ch
+	markReady(rq.publishReady)
+	rq.connMutex.Unlock()
 
-	log.Println("✓ RabbitMQ 发布者连接已建立")
+	go rq.watch("publisher", conn, ch, func() { rq.publisherDown(ch) }, rq.setupPublisher)
+
+	slog.Info("✓ RabbitMQ 发布者连接已建立")
 	return nil
 }
 
@@ -116,19 +169,16 @@ func (rq *RabbitMQQueue) setupConsumer() error {
 		return fmt.Errorf("创建 RabbitMQ Channel 失败: %w", err)
 	}
 
-	// 设置 QoS：预取数量 = Worker 数量
-	// 这样 RabbitMQ 会一次性推送 3 条消息到 deliveriesGoChannel
-	// 3 个 Worker 各拿一条，实现并发处理
-	workerCount := 3 // 可以作为参数传入，这里硬编码为 3
-	err = ch.Qos(
-		workerCount, // prefetchCount: 预取消息数量
-		0,           // prefetchSize: 0 表示不限制
-		false,       // global: false 表示只应用于当前 channel
-	)
-	if err != nil {
+	// 设置 QoS：预取数量 >= Worker 数量
+	// RabbitMQ 最多推送 prefetchCount 条未确认的消息到 deliveriesGoChannel，
+	// 每个 Worker 各拿一条，实现并发处理；预取数量小于 Worker 数量时多出的 Worker 一直空闲
+	rq.connMutex.Lock()
+	prefetchCount := rq.prefetchCount
+	rq.connMutex.Unlock()
+	if err := applyPrefetch(ch, prefetchCount); err != nil {
 		ch.Close()
 		conn.Close()
-		return fmt.Errorf("设置 QoS 失败: %w", err)
+		return err
 	}
 
 	// 启动消费（订阅队列）
@@ -148,33 +198,231 @@ func (rq *RabbitMQQueue) setupConsumer() error {
 		return fmt.Errorf("启动消费失败: %w", err)
 	}
 
+	rq.connMutex.Lock()
+	if rq.isClosed() {
+		rq.connMutex.Unlock()
+		ch.Close()
+		conn.Close()
+		return ErrQueueClosed
+	}
 	rq.consumeConn = conn
 	rq.consumeRabbitChannel = ch
 	rq.deliveriesGoChannel = deliveries
+	markReady(rq.consumeReady)
+	rq.connMutex.Unlock()
 
-	log.Printf("✓ RabbitMQ 消费者已启动 (prefetchCount=%d)", workerCount)
+	go rq.watch("consumer", conn, ch, func() { rq.consumerDown(deliveries) }, rq.setupConsumer)
+
+	slog.Info("✓ RabbitMQ 消费者已启动", "prefetch_count", prefetchCount)
+	return nil
+}
+
+// SetPrefetch 运行时调整消费者的预取数量（如 Worker 数量变化后）
+// 对当前消费通道重新调用 Qos，立即生效：调小时已推送的未确认消息不受影响，确认后不再补足到旧的数量；
+// 消费连接断开时只保存新的值，重连时使用
+func (rq *RabbitMQQueue) SetPrefetch(prefetchCount int) error {
+	if prefetchCount <= 0 {
+		return fmt.Errorf("预取数量必须大于 0: %d", prefetchCount)
+	}
+
+	// 与 ackInternal 相同的加锁顺序：先 ackMutex（通道操作）再 connMutex
+	rq.ackMutex.Lock()
+	defer rq.ackMutex.Unlock()
+	rq.connMutex.Lock()
+	defer rq.connMutex.Unlock()
+	if rq.isClosed() {
+		return ErrQueueClosed
+	}
+
+	select {
+	case <-rq.consumeReady:
+		if err := applyPrefetch(rq.consumeRabbitChannel, prefetchCount); err != nil {
+			return err
+		}
+	default:
+		// 正在重连，setupConsumer 会使用新的值
+	}
+
+	old := rq.prefetchCount
+	rq.prefetchCount = prefetchCount
+	slog.Info("✓ RabbitMQ 预取数量已调整", "old", old, "new", prefetchCount)
 	return nil
 }
 
+// qosChannel 可以设置 QoS 的通道（*amqp.Channel 实现）
+type qosChannel interface {
+	Qos(prefetchCount, prefetchSize int, global bool) error
+}
+
+// applyPrefetch 设置通道的预取数量：不限制消息大小，只应用于当前通道
+func applyPrefetch(ch qosChannel, prefetchCount int) error {
+	err := ch.Qos(
+		prefetchCount, // prefetchCount: 预取消息数量
+		0,             // prefetchSize: 0 表示不限制
+		false,         // global: false 表示只应用于当前 channel
+	)
+	if err != nil {
+		return fmt.Errorf("设置 QoS 失败: %w", err)
+	}
+	return nil
+}
+
+// watch 等待连接或通道关闭：主动关闭队列时直接返回，意外断开时标记不可用并重连
+// 重连成功后 setup 会为新的连接启动新的 watch
+func (rq *RabbitMQQueue) watch(name string, conn *amqp.Connection, ch *amqp.Channel, markDown func(), setup func() error) {
+	// 连接在注册之前已经关闭时，NotifyClose 直接关闭传入的 channel
+	connClosed := conn.NotifyClose(make(chan *amqp.Error, 1))
+	chClosed := ch.NotifyClose(make(chan *amqp.Error, 1))
+
+	var reason *amqp.Error
+	select {
+	case <-rq.closed:
+		return
+	case reason = <-connClosed:
+	case reason = <-chClosed:
+	}
+	if rq.isClosed() {
+		return // Close 主动关闭的连接
+	}
+
+	markDown()
+	conn.Close() // 只有通道被关闭（如 Broker 返回通道级错误）时连接仍然打开
+	slog.Warn("⚠️ RabbitMQ 连接已断开，开始重连", "connection", name, "reason", reason)
+
+	rq.reconnect(name, setup)
+}
+
+// reconnect 按指数退避重新建立连接，直到成功或队列被关闭
+func (rq *RabbitMQQueue) reconnect(name string, setup func() error) {
+	delay := reconnectMinDelay
+	for attempt := 1; ; attempt++ {
+		select {
+		case <-rq.closed:
+			return
+		case <-time.After(delay):
+		}
+
+		err := setup()
+		if err == nil {
+			slog.Info("✓ RabbitMQ 重连成功", "connection", name, "attempts", attempt)
+			return
+		}
+		if rq.isClosed() {
+			return
+		}
+
+		delay = min(delay*2, reconnectMaxDelay)
+		slog.Warn("⚠️ RabbitMQ 重连失败", "connection", name, "attempt", attempt, "retry_in", delay, "error", err)
+	}
+}
+
+// publisherDown 发布连接断开：ch 仍是当前的发布通道时标记为不可用（Enqueue 等待重连）
+func (rq *RabbitMQQueue) publisherDown(ch *amqp.Channel) {
+	rq.connMutex.Lock()
+	defer rq.connMutex.Unlock()
+
+	if rq.publishRabbitChannel == ch {
+		rq.publishReady = markDown(rq.publishReady)
+	}
+}
+
+// consumerDown 消费连接断开：deliveries 仍是当前的消费通道时标记为不可用（Dequeue 等待重连）
+// 由 watch 和 Dequeue（读到 deliveries 关闭）调用，哪个先发现都可以
+func (rq *RabbitMQQueue) consumerDown(deliveries <-chan amqp.Delivery) {
+	rq.connMutex.Lock()
+	defer rq.connMutex.Unlock()
+
+	if rq.deliveriesGoChannel == deliveries {
+		rq.consumeReady = markDown(rq.consumeReady)
+	}
+}
+
+// markReady 关闭 ready（已关闭时不重复关闭）
+func markReady(ready chan struct{}) {
+	select {
+	case <-ready:
+	default:
+		close(ready)
+	}
+}
+
+// markDown ready 已关闭（连接可用）时换成新的未关闭 channel，已经是未关闭的 channel 时原样返回
+func markDown(ready chan struct{}) chan struct{} {
+	select {
+	case <-ready:
+		return make(chan struct{})
+	default:
+		return ready
+	}
+}
+
+// isClosed 队列是否已经关闭
+func (rq *RabbitMQQueue) isClosed() bool {
+	select {
+	case <-rq.closed:
+		return true
+	default:
+		return false
+	}
+}
+
+// publisher 返回当前可用的发布通道，重连期间等待重连完成（最多等到 ctx 结束）
+func (rq *RabbitMQQueue) publisher(ctx context.Context) (*amqp.Channel, error) {
+	for {
+		rq.connMutex.Lock()
+		ch, ready := rq.publishRabbitChannel, rq.publishReady
+		rq.connMutex.Unlock()
+
+		select {
+		case <-ready:
+			return ch, nil
+		default:
+		}
+
+		select {
+		case <-rq.closed:
+			return nil, ErrQueueClosed
+		case <-ctx.Done():
+			return nil, fmt.Errorf("RabbitMQ 正在重连: %w", ctx.Err())
+		case <-ready:
+		}
+	}
+}
+
 // Enqueue 将任务加入队列
 func (rq *RabbitMQQueue) Enqueue(job *models.TranscriptionJob) error {
+	return rq.publish(job, rq.queueName, nil)
+}
+
+// publish 通过默认 exchange 把任务发布到 routingKey 指定的队列；declare 不为 nil 时先在发布通道上声明目标队列
+func (rq *RabbitMQQueue) publish(job *models.TranscriptionJob, routingKey string, declare func(ch *amqp.Channel) error) error {
 	rq.publishMutex.Lock()
 	defer rq.publishMutex.Unlock()
 
-	body, err := json.Marshal(job)
+	body, err := models.MarshalJob(job)
 	if err != nil {
 		return fmt.Errorf("序列化任务失败: %w", err)
 	}
 
-	// 创建上下文（5 秒超时）
+	// 创建上下文（5 秒超时，发布连接正在重连时包括等待重连和等待确认的时间）
 	ctx, cancel := context.WithTimeout(rq.ctx, 5*time.Second)
 	defer cancel()
 
-	// 发布消息到队列
-	err = rq.publishRabbitChannel.PublishWithContext(
+	ch, err := rq.publisher(ctx)
+	if err != nil {
+		return fmt.Errorf("发布消息失败: %w", err)
+	}
+	if declare != nil {
+		if err := declare(ch); err != nil {
+			return fmt.Errorf("声明队列失败: %w", err)
+		}
+	}
+
+	// 发布消息到队列（未开启确认模式时 confirmation 为 nil）
+	confirmation, err := ch.PublishWithDeferredConfirmWithContext(
 		ctx,
-		"",           // exchange: 空字符串表示默认 exchange
-		rq.queueName, // routing key
+		"",         // exchange: 空字符串表示默认 exchange
+		routingKey, // routing key
 		false,        // mandatory
 		false,        // immediate
 		amqp.Publishing{
@@ -182,45 +430,94 @@ func (rq *RabbitMQQueue) Enqueue(job *models.TranscriptionJob) error {
 			ContentType:  "application/json",
 			Body:         body,
 			Timestamp:    time.Now(),
+			Priority:     messagePriority(job.Priority),
 		},
 	)
 
 	if err != nil {
 		return fmt.Errorf("发布消息失败: %w", err)
 	}
+	if confirmation == nil {
+		return nil
+	}
+	return awaitConfirm(ctx, confirmation)
+}
+
+// confirmer 发布确认的等待接口（*amqp.DeferredConfirmation 实现）
+type confirmer interface {
+	WaitContext(ctx context.Context) (bool, error)
+}
 
+// awaitConfirm 等待 Broker 确认：超时或 nack 时消息可能没有进入队列，返回错误由调用方提示用户重试
+// （超时的消息也可能已经入队：调用方把任务标记为失败后，Worker 取到这条消息时会跳过）
+func awaitConfirm(ctx context.Context, confirmation confirmer) error {
+	acked, err := confirmation.WaitContext(ctx)
+	if err != nil {
+		return fmt.Errorf("等待 Broker 确认超时: %w", err)
+	}
+	if !acked {
+		return ErrPublishNacked
+	}
 	return nil
 }
 
+// messagePriority 将任务优先级限制在 0 ~ MaxPriority 之间
+func messagePriority(priority int) uint8 {
+	if priority < 0 {
+		return 0
+	}
+	if priority > models.MaxPriority {
+		return models.MaxPriority
+	}
+	return uint8(priority)
+}
+
 // Dequeue 从队列取出任务（阻塞）
 // 所有 Worker goroutine 共享同一个 deliveriesGoChannel
-// Go Channel 保证每条消息只会被一个 Worker 读取
+// Go Channel 保证每条消息只会被一个 Worker 读取；消费连接断开期间等待重连，之后从新的 deliveriesGoChannel 读取
 func (rq *RabbitMQQueue) Dequeue() (*models.TranscriptionJob, error) {
-	// 从 Go Channel 读取消息
-	select {
-	case <-rq.closed:
-		return nil, fmt.Errorf("队列已关闭")
-	case <-rq.ctx.Done():
-		return nil, fmt.Errorf("队列已关闭")
-	case delivery, ok := <-rq.deliveriesGoChannel:
-		if !ok {
-			// Go Channel 已关闭
-			return nil, fmt.Errorf("消费通道已关闭")
+	for {
+		rq.connMutex.Lock()
+		deliveries, ready := rq.deliveriesGoChannel, rq.consumeReady
+		rq.connMutex.Unlock()
+
+		// 重连期间等待，重连成功后重新读取 deliveriesGoChannel
+		select {
+		case <-ready:
+		default:
+			select {
+			case <-rq.closed:
+				return nil, ErrQueueClosed
+			case <-ready:
+			}
+			continue
 		}
 
-		// 反序列化任务
-		var job models.TranscriptionJob
-		if err := json.Unmarshal(delivery.Body, &job); err != nil {
-			// 反序列化失败，拒绝消息（不重新入队）
-			rq.nackInternal(delivery.DeliveryTag, false)
-			return nil, fmt.Errorf("反序列化任务失败: %w", err)
+		// 从 Go Channel 读取消息
+		select {
+		case <-rq.closed:
+			return nil, ErrQueueClosed
+		case delivery, ok := <-deliveries:
+			if !ok {
+				// 消费连接已断开（watch 可能还没发现）：标记后等待重连
+				rq.consumerDown(deliveries)
+				continue
+			}
+
+			// 反序列化任务（旧版本生产者发布的消息自动升级）
+			job, err := models.UnmarshalJob(delivery.Body)
+			if err != nil {
+				// 反序列化失败，拒绝消息（不重新入队）
+				rq.nackInternal(&delivery, false)
+				return nil, err
+			}
+
+			// 保存 delivery 信息用于后续确认
+			job.DeliveryTag = delivery.DeliveryTag
+			job.RabbitMQDelivery = &delivery
+
+			return job, nil
 		}
-
-		// 保存 delivery 信息用于后续确认
-		job.DeliveryTag = delivery.DeliveryTag
-		job.RabbitMQDelivery = &delivery
-
-		return &job, nil
 	}
 }
 
@@ -231,34 +528,129 @@ func (rq *RabbitMQQueue) Ack(job *models.TranscriptionJob) error {
 	}
 
 	delivery := job.RabbitMQDelivery.(*amqp.Delivery)
-	return rq.ackInternal(delivery.DeliveryTag)
+	return rq.ackInternal(delivery)
 }
 
 // Nack 拒绝消息（任务处理失败）
-func (rq *RabbitMQQueue) Nack(job *models.TranscriptionJob, requeue bool) error {
+// requeueAfter > 0 时通过延迟重试队列重新入队，见 nackAfter
+func (rq *RabbitMQQueue) Nack(job *models.TranscriptionJob, requeue bool, requeueAfter time.Duration) error {
 	if job.RabbitMQDelivery == nil {
 		return nil // 不是 RabbitMQ 消息，忽略
 	}
 
 	delivery := job.RabbitMQDelivery.(*amqp.Delivery)
-	return rq.nackInternal(delivery.DeliveryTag, requeue)
+	if requeue && requeueAfter > 0 {
+		return rq.nackAfter(job, delivery, requeueAfter)
+	}
+	return rq.nackInternal(delivery, requeue)
+}
+
+// nackAfter 延迟重新入队：把任务发布到该等待时间对应的重试队列，再确认原消息
+// 重试队列没有消费者，消息等待 x-message-ttl 过期后由 Broker 死信路由回主队列，等待期间不占用预取名额，服务重启也不影响；
+// 发布失败时退回为立即重新入队，任务不会丢失。发布成功、确认原消息之前进程退出时任务会被处理两次
+func (rq *RabbitMQQueue) nackAfter(job *models.TranscriptionJob, delivery *amqp.Delivery, delay time.Duration) error {
+	delay = retryBucket(delay)
+	name := rq.retryQueueName(delay)
+	err := rq.publish(job, name, func(ch *amqp.Channel) error {
+		return declareRetryQueue(ch, name, rq.queueName, delay)
+	})
+	if err != nil {
+		if nackErr := rq.nackInternal(delivery, true); nackErr != nil {
+			return fmt.Errorf("发布到延迟重试队列失败: %v，退回队列也失败: %w", err, nackErr)
+		}
+		return fmt.Errorf("发布到延迟重试队列失败，已立即退回队列: %w", err)
+	}
+	return rq.ackInternal(delivery)
+}
+
+// retryBuckets 延迟重试队列的等待时间档位：等待时间向上取整到最近的档位，超过最大档位时按最大档位等待，
+// 重试队列最多 len(retryBuckets) 个，不会因为每次等待时间略有不同而声明大量一次性队列
+var retryBuckets = []time.Duration{
+	time.Second,
+	5 * time.Second,
+	10 * time.Second,
+	30 * time.Second,
+	time.Minute,
+	2 * time.Minute,
+	5 * time.Minute,
+	10 * time.Minute,
+	30 * time.Minute,
+	time.Hour,
+}
+
+// retryBucket 等待时间所在的档位
+func retryBucket(delay time.Duration) time.Duration {
+	for _, bucket := range retryBuckets {
+		if delay <= bucket {
+			return bucket
+		}
+	}
+	return retryBuckets[len(retryBuckets)-1]
+}
+
+// retryQueueName 延迟重试队列名（每种等待时间一个队列，如 voiceflow_jobs.retry.30000ms）
+func (rq *RabbitMQQueue) retryQueueName(delay time.Duration) string {
+	return fmt.Sprintf("%s.retry.%dms", rq.queueName, delay.Milliseconds())
+}
+
+// declareRetryQueue 声明延迟重试队列（幂等，每次发布前声明，Broker 重置后也能重建）
+// 队列中的消息在 x-message-ttl 后过期，经默认 exchange（x-dead-letter-exchange 为空）按 x-dead-letter-routing-key 回到主队列；
+// x-expires 让不再使用的重试队列被自动删除，每次声明都会重新计时，且总是长于消息的等待时间
+func declareRetryQueue(ch *amqp.Channel, name, target string, delay time.Duration) error {
+	ttl := delay.Milliseconds()
+	_, err := ch.QueueDeclare(
+		name,  // name
+		true,  // durable: 持久化队列
+		false, // autoDelete: 没有消费者，不能依赖自动删除
+		false, // exclusive: 非独占
+		false, // noWait
+		amqp.Table{
+			"x-message-ttl":             ttl,
+			"x-dead-letter-exchange":    "",
+			"x-dead-letter-routing-key": target,
+			"x-expires":                 2*ttl + time.Minute.Milliseconds(),
+		},
+	)
+	return err
 }
 
 // ackInternal 内部 Ack 实现（带锁保护）
 // 因为 RabbitMQ Channel 不是并发安全的，多个 Worker 可能同时调用
-func (rq *RabbitMQQueue) ackInternal(deliveryTag uint64) error {
+func (rq *RabbitMQQueue) ackInternal(delivery *amqp.Delivery) error {
 	rq.ackMutex.Lock()
 	defer rq.ackMutex.Unlock()
 
-	return rq.consumeRabbitChannel.Ack(deliveryTag, false)
+	ch, ok := rq.deliveryChannel(delivery)
+	if !ok {
+		return nil
+	}
+	return ch.Ack(delivery.DeliveryTag, false)
 }
 
 // nackInternal 内部 Nack 实现（带锁保护）
-func (rq *RabbitMQQueue) nackInternal(deliveryTag uint64, requeue bool) error {
+func (rq *RabbitMQQueue) nackInternal(delivery *amqp.Delivery, requeue bool) error {
 	rq.ackMutex.Lock()
 	defer rq.ackMutex.Unlock()
 
-	return rq.consumeRabbitChannel.Nack(deliveryTag, false, requeue)
+	ch, ok := rq.deliveryChannel(delivery)
+	if !ok {
+		return nil
+	}
+	return ch.Nack(delivery.DeliveryTag, false, requeue)
+}
+
+// deliveryChannel 返回投递消息的消费通道；消息来自重连之前的通道时返回 false
+// delivery tag 只在投递它的通道内有效，旧通道上未确认的消息已由 Broker 重新投递，确认请求直接忽略
+func (rq *RabbitMQQueue) deliveryChannel(delivery *amqp.Delivery) (*amqp.Channel, bool) {
+	rq.connMutex.Lock()
+	ch := rq.consumeRabbitChannel
+	rq.connMutex.Unlock()
+
+	if ch == nil || delivery.Acknowledger != amqp.Acknowledger(ch) {
+		slog.Warn("⚠️ 消息来自已断开的连接，忽略确认（Broker 会重新投递）", "delivery_tag", delivery.DeliveryTag)
+		return nil, false
+	}
+	return ch, true
 }
 
 // Close 关闭队列
@@ -267,6 +659,10 @@ func (rq *RabbitMQQueue) Close() error {
 	case <-rq.closed:
 		return nil // 已经关闭
 	default:
+		rq.connMutex.Lock()
+		defer rq.connMutex.Unlock()
+
+		// 先标记关闭：watch 不再重连，进行中的重连建立的连接直接关闭
 		close(rq.closed)
 		rq.cancel()
 
@@ -281,7 +677,7 @@ func (rq *RabbitMQQueue) Close() error {
 		// 关闭发布连接
 		rq.closePublisher()
 
-		log.Println("✓ RabbitMQ 队列已关闭")
+		slog.Info("✓ RabbitMQ 队列已关闭")
 		return nil
 	}
 }
@@ -296,9 +692,56 @@ func (rq *RabbitMQQueue) closePublisher() {
 	}
 }
 
+// Ping 检查 RabbitMQ 连接是否可用
+// 消费连接断开后 Worker 将无法收到消息，因此需要同时检查两条连接
+func (rq *RabbitMQQueue) Ping() error {
+	select {
+	case <-rq.closed:
+		return fmt.Errorf("队列已关闭")
+	default:
+	}
+
+	rq.connMutex.Lock()
+	publishDown := rq.publishConn == nil || rq.publishConn.IsClosed() || rq.publishRabbitChannel.IsClosed()
+	consumeDown := rq.consumeConn == nil || rq.consumeConn.IsClosed() || rq.consumeRabbitChannel.IsClosed()
+	rq.connMutex.Unlock()
+
+	// 断开期间后台正在重连，健康检查如实报告
+	if publishDown {
+		return fmt.Errorf("发布连接已断开（正在重连）")
+	}
+	if consumeDown {
+		return fmt.Errorf("消费连接已断开（正在重连）")
+	}
+
+	if _, _, err := rq.GetQueueInfo(); err != nil {
+		return fmt.Errorf("查询队列信息失败: %w", err)
+	}
+	return nil
+}
+
+// Stats 通过 QueueInspect 查询队列中的消息数和消费者数量，并附带本实例正在处理的任务数和当前的预取数量
+func (rq *RabbitMQQueue) Stats() (QueueStats, error) {
+	messages, consumers, err := rq.GetQueueInfo()
+	if err != nil {
+		return QueueStats{}, fmt.Errorf("查询队列信息失败: %w", err)
+	}
+
+	rq.connMutex.Lock()
+	prefetchCount := rq.prefetchCount
+	rq.connMutex.Unlock()
+	return QueueStats{Depth: messages, InFlight: rq.inFlight(), Consumers: consumers, Prefetch: prefetchCount}, nil
+}
+
 // GetQueueInfo 获取队列信息（调试用）
+// messages 只统计尚未推送给消费者的消息，已预取（最多 prefetchCount 条）但未确认的消息不计入；
+// consumers 是消费者数量（每个实例一个，与 Worker 数量无关），每个实例的并发由预取数量和 Worker 数量中较小的一个决定
 func (rq *RabbitMQQueue) GetQueueInfo() (messages, consumers int, err error) {
-	q, err := rq.publishRabbitChannel.QueueInspect(rq.queueName)
+	rq.connMutex.Lock()
+	ch := rq.publishRabbitChannel
+	rq.connMutex.Unlock()
+
+	q, err := ch.QueueInspect(rq.queueName)
 	if err != nil {
 		return 0, 0, err
 	}
@@ -0,0 +1,413 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+	"github.com/z-wentao/voiceflow/pkg/models"
+)
+
+func TestRetryBucket(t *testing.T) {
+	tests := []struct {
+		delay time.Duration
+		want  time.Duration
+	}{
+		{0, time.Second},
+		{300 * time.Millisecond, time.Second},
+		{time.Second, time.Second},
+		{4999 * time.Millisecond, 5 * time.Second},
+		{29*time.Second + 999*time.Millisecond, 30 * time.Second},
+		{30*time.Second + time.Millisecond, time.Minute},
+		{time.Hour, time.Hour},
+		{3 * time.Hour, time.Hour},
+	}
+	for _, tt := range tests {
+		if got := retryBucket(tt.delay); got != tt.want {
+			t.Errorf("retryBucket(%v) = %v，期望 %v", tt.delay, got, tt.want)
+		}
+	}
+}
+
+func TestRetryQueueNameStable(t *testing.T) {
+	rq := &RabbitMQQueue{queueName: "voiceflow_jobs"}
+
+	// 同一档位内略有不同的等待时间使用同一个重试队列
+	names := make(map[string]bool)
+	for _, delay := range []time.Duration{29 * time.Second, 29*time.Second + 998*time.Millisecond, 30 * time.Second} {
+		names[rq.retryQueueName(retryBucket(delay))] = true
+	}
+	if len(names) != 1 || !names["voiceflow_jobs.retry.30000ms"] {
+		t.Fatalf("重试队列名 = %v，期望只有 voiceflow_jobs.retry.30000ms", names)
+	}
+}
+
+// newTestRabbitMQQueue 不连接 Broker 的队列：连接字段由测试按需设置
+func newTestRabbitMQQueue() *RabbitMQQueue {
+	return &RabbitMQQueue{
+		queueName:     "voiceflow_jobs",
+		prefetchCount: 1,
+		closed:        make(chan struct{}),
+		publishReady:  make(chan struct{}),
+		consumeReady:  make(chan struct{}),
+	}
+}
+
+func isReady(ready chan struct{}) bool {
+	select {
+	case <-ready:
+		return true
+	default:
+		return false
+	}
+}
+
+func TestMarkReadyMarkDown(t *testing.T) {
+	tests := []struct {
+		name      string
+		ready     bool // 初始状态
+		markDown  bool // true 调用 markDown，false 调用 markReady
+		wantReady bool
+		wantSame  bool // markDown 是否原样返回
+	}{
+		{"ready 后断开", true, true, false, false},
+		{"断开时重复断开", false, true, false, true},
+		{"断开后重连", false, false, true, true},
+		{"重复 markReady 不 panic", true, false, true, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ready := make(chan struct{})
+			if tt.ready {
+				close(ready)
+			}
+			got := ready
+			if tt.markDown {
+				got = markDown(ready)
+			} else {
+				markReady(ready)
+			}
+			if isReady(got) != tt.wantReady {
+				t.Errorf("ready = %v，期望 %v", isReady(got), tt.wantReady)
+			}
+			if (got == ready) != tt.wantSame {
+				t.Errorf("返回原 channel = %v，期望 %v", got == ready, tt.wantSame)
+			}
+		})
+	}
+}
+
+func TestRabbitMQDownIgnoresStale(t *testing.T) {
+	current, stale := new(amqp.Channel), new(amqp.Channel)
+	currentDeliveries, staleDeliveries := make(chan amqp.Delivery), make(chan amqp.Delivery)
+
+	tests := []struct {
+		name string
+		down func(rq *RabbitMQQueue)
+		get  func(rq *RabbitMQQueue) chan struct{}
+		want bool // 调用后是否仍然可用
+	}{
+		{"当前发布通道断开", func(rq *RabbitMQQueue) { rq.publisherDown(current) }, func(rq *RabbitMQQueue) chan struct{} { return rq.publishReady }, false},
+		{"旧发布通道断开", func(rq *RabbitMQQueue) { rq.publisherDown(stale) }, func(rq *RabbitMQQueue) chan struct{} { return rq.publishReady }, true},
+		{"当前消费通道断开", func(rq *RabbitMQQueue) { rq.consumerDown(currentDeliveries) }, func(rq *RabbitMQQueue) chan struct{} { return rq.consumeReady }, false},
+		{"旧消费通道断开", func(rq *RabbitMQQueue) { rq.consumerDown(staleDeliveries) }, func(rq *RabbitMQQueue) chan struct{} { return rq.consumeReady }, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rq := newTestRabbitMQQueue()
+			rq.publishRabbitChannel = current
+			rq.deliveriesGoChannel = currentDeliveries
+			markReady(rq.publishReady)
+			markReady(rq.consumeReady)
+
+			tt.down(rq)
+			if got := isReady(tt.get(rq)); got != tt.want {
+				t.Errorf("可用 = %v，期望 %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRabbitMQDeliveryChannel(t *testing.T) {
+	current, stale := new(amqp.Channel), new(amqp.Channel)
+
+	tests := []struct {
+		name     string
+		consumer *amqp.Channel
+		acker    amqp.Acknowledger
+		wantOK   bool
+	}{
+		{"当前通道投递", current, current, true},
+		{"重连之前的通道投递", current, stale, false},
+		{"消费通道尚未建立", nil, stale, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rq := newTestRabbitMQQueue()
+			rq.consumeRabbitChannel = tt.consumer
+			delivery := &amqp.Delivery{Acknowledger: tt.acker, DeliveryTag: 7}
+
+			ch, ok := rq.deliveryChannel(delivery)
+			if ok != tt.wantOK {
+				t.Fatalf("ok = %v，期望 %v", ok, tt.wantOK)
+			}
+			if ok && ch != tt.consumer {
+				t.Errorf("返回的通道不是当前消费通道")
+			}
+			if !ok {
+				// 旧通道的 delivery tag 已失效：Ack / Nack 直接忽略，不在新通道上确认
+				job := &models.TranscriptionJob{JobID: "job-1", RabbitMQDelivery: delivery}
+				if err := rq.Ack(job); err != nil {
+					t.Errorf("Ack 旧消息: %v", err)
+				}
+				if err := rq.Nack(job, true, 0); err != nil {
+					t.Errorf("Nack 旧消息: %v", err)
+				}
+			}
+		})
+	}
+}
+
+func TestRabbitMQDequeueAcrossReconnect(t *testing.T) {
+	tests := []struct {
+		name    string
+		close   bool // 重连期间关闭队列
+		wantErr error
+	}{
+		{"重连后从新通道读取", false, nil},
+		{"重连期间关闭队列", true, ErrQueueClosed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rq := newTestRabbitMQQueue()
+			oldDeliveries := make(chan amqp.Delivery)
+			rq.deliveriesGoChannel = oldDeliveries
+			markReady(rq.consumeReady)
+
+			type result struct {
+				job *models.TranscriptionJob
+				err error
+			}
+			done := make(chan result, 1)
+			go func() {
+				job, err := rq.Dequeue()
+				done <- result{job, err}
+			}()
+
+			// 消费连接断开：旧的 deliveries 被关闭，Dequeue 标记不可用后等待重连
+			close(oldDeliveries)
+			deadline := time.Now().Add(time.Second)
+			for {
+				rq.connMutex.Lock()
+				ready := rq.consumeReady
+				rq.connMutex.Unlock()
+				if !isReady(ready) {
+					break
+				}
+				if time.Now().After(deadline) {
+					t.Fatal("Dequeue 没有把消费连接标记为断开")
+				}
+				time.Sleep(time.Millisecond)
+			}
+			select {
+			case r := <-done:
+				t.Fatalf("重连完成之前 Dequeue 返回: %+v", r)
+			case <-time.After(20 * time.Millisecond):
+			}
+
+			newDeliveries := make(chan amqp.Delivery, 1)
+			if tt.close {
+				close(rq.closed)
+			} else {
+				body, err := models.MarshalJob(newTestJob("job-1", 0))
+				if err != nil {
+					t.Fatal(err)
+				}
+				newDeliveries <- amqp.Delivery{Body: body, DeliveryTag: 1}
+				rq.connMutex.Lock()
+				rq.deliveriesGoChannel = newDeliveries
+				markReady(rq.consumeReady)
+				rq.connMutex.Unlock()
+			}
+
+			select {
+			case r := <-done:
+				if !errors.Is(r.err, tt.wantErr) {
+					t.Fatalf("err = %v，期望 %v", r.err, tt.wantErr)
+				}
+				if tt.wantErr == nil && (r.job == nil || r.job.JobID != "job-1" || r.job.DeliveryTag != 1) {
+					t.Errorf("job = %+v，期望新通道投递的 job-1", r.job)
+				}
+			case <-time.After(time.Second):
+				t.Fatal("Dequeue 没有在重连或关闭后返回")
+			}
+		})
+	}
+}
+
+func TestRabbitMQReconnect(t *testing.T) {
+	minDelay, maxDelay := reconnectMinDelay, reconnectMaxDelay
+	reconnectMinDelay, reconnectMaxDelay = time.Millisecond, 4*time.Millisecond
+	defer func() { reconnectMinDelay, reconnectMaxDelay = minDelay, maxDelay }()
+
+	tests := []struct {
+		name         string
+		failures     int  // setup 前几次失败
+		closeAfter   int  // 第几次尝试后关闭队列（0 不关闭）
+		closedBefore bool // 开始重连之前队列已关闭
+		wantAttempts int32
+	}{
+		{"第一次就成功", 0, 0, false, 1},
+		{"失败后退避重试直到成功", 5, 0, false, 6},
+		{"重连期间关闭队列后停止", 100, 3, false, 3},
+		{"队列已关闭不再重连", 0, 0, true, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rq := newTestRabbitMQQueue()
+			if tt.closedBefore {
+				close(rq.closed)
+			}
+
+			var attempts atomic.Int32
+			setup := func() error {
+				n := attempts.Add(1)
+				if tt.closeAfter > 0 && int(n) == tt.closeAfter {
+					close(rq.closed)
+					return ErrQueueClosed
+				}
+				if int(n) <= tt.failures {
+					return errors.New("connection refused")
+				}
+				return nil
+			}
+
+			done := make(chan struct{})
+			go func() {
+				rq.reconnect("consumer", setup)
+				close(done)
+			}()
+			select {
+			case <-done:
+			case <-time.After(2 * time.Second):
+				t.Fatal("reconnect 没有返回")
+			}
+			if got := attempts.Load(); got != tt.wantAttempts {
+				t.Errorf("尝试次数 = %d，期望 %d", got, tt.wantAttempts)
+			}
+		})
+	}
+}
+
+// fakeConfirmation 模拟 Broker 的发布确认：block 为 true 时一直等到 ctx 结束
+type fakeConfirmation struct {
+	acked bool
+	block bool
+}
+
+func (f fakeConfirmation) WaitContext(ctx context.Context) (bool, error) {
+	if f.block {
+		<-ctx.Done()
+		return false, ctx.Err()
+	}
+	return f.acked, nil
+}
+
+func TestAwaitConfirm(t *testing.T) {
+	tests := []struct {
+		name         string
+		confirmation fakeConfirmation
+		wantErr      error
+	}{
+		{"Broker ack", fakeConfirmation{acked: true}, nil},
+		{"Broker nack", fakeConfirmation{acked: false}, ErrPublishNacked},
+		{"等待确认超时", fakeConfirmation{block: true}, context.DeadlineExceeded},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+			defer cancel()
+
+			err := awaitConfirm(ctx, tt.confirmation)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v，期望 %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// fakeQosChannel 记录 Qos 调用参数
+type fakeQosChannel struct {
+	calls [][3]any
+	err   error
+}
+
+func (f *fakeQosChannel) Qos(prefetchCount, prefetchSize int, global bool) error {
+	f.calls = append(f.calls, [3]any{prefetchCount, prefetchSize, global})
+	return f.err
+}
+
+func TestApplyPrefetch(t *testing.T) {
+	tests := []struct {
+		name     string
+		prefetch int
+		err      error
+	}{
+		{"配置的预取数量", 8, nil},
+		{"预取数量为 1", 1, nil},
+		{"Broker 拒绝", 4, errors.New("channel closed")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ch := &fakeQosChannel{err: tt.err}
+			err := applyPrefetch(ch, tt.prefetch)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("err = %v，期望 %v", err, tt.err)
+			}
+			// 只限制条数、不限制消息大小，且只应用于当前通道
+			want := [3]any{tt.prefetch, 0, false}
+			if len(ch.calls) != 1 || ch.calls[0] != want {
+				t.Fatalf("Qos 调用 = %v，期望 [%v]", ch.calls, want)
+			}
+		})
+	}
+}
+
+// TestRabbitMQSetPrefetch 消费连接断开期间调整预取数量：保存新的值，重连时 setupConsumer 使用
+func TestRabbitMQSetPrefetch(t *testing.T) {
+	tests := []struct {
+		name     string
+		prefetch int
+		closed   bool
+		wantErr  error
+		want     int
+	}{
+		{"重连期间保存新的值", 8, false, nil, 8},
+		{"预取数量为 0", 0, false, nil, 1},
+		{"预取数量为负数", -2, false, nil, 1},
+		{"队列已关闭", 8, true, ErrQueueClosed, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rq := newTestRabbitMQQueue()
+			if tt.closed {
+				close(rq.closed)
+			}
+
+			err := rq.SetPrefetch(tt.prefetch)
+			if tt.prefetch <= 0 {
+				if err == nil {
+					t.Fatal("无效的预取数量没有返回错误")
+				}
+			} else if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v，期望 %v", err, tt.wantErr)
+			}
+			if rq.prefetchCount != tt.want {
+				t.Fatalf("prefetchCount = %d，期望 %d", rq.prefetchCount, tt.want)
+			}
+		})
+	}
+}
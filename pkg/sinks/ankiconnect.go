@@ -0,0 +1,162 @@
+package sinks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"html"
+	"net/http"
+	"time"
+)
+
+// ankiConnectVersion AnkiConnect API 版本
+const ankiConnectVersion = 6
+
+// AnkiConnectOptions AnkiConnect 同步参数
+type AnkiConnectOptions struct {
+	URL        string // AnkiConnect 地址，默认 http://localhost:8765
+	Deck       string // 牌组名称，不存在时自动创建，默认 VoiceFlow
+	Model      string // 笔记类型，默认 Basic
+	FrontField string // 正面字段名，默认 Front
+	BackField  string // 背面字段名，默认 Back
+	Timeout    time.Duration
+}
+
+// AnkiConnectSink 通过 AnkiConnect 插件将单词添加为 Anki 笔记
+// 正面为单词，背面为释义和例句；重复的单词由 Anki 跳过
+type AnkiConnectSink struct {
+	name   string
+	label  string
+	opts   AnkiConnectOptions
+	client *http.Client
+}
+
+// NewAnkiConnectSink 创建 AnkiConnect 同步目标
+func NewAnkiConnectSink(name, label string, opts AnkiConnectOptions) *AnkiConnectSink {
+	if label == "" {
+		label = "Anki"
+	}
+	if opts.URL == "" {
+		opts.URL = "http://localhost:8765"
+	}
+	if opts.Deck == "" {
+		opts.Deck = "VoiceFlow"
+	}
+	if opts.Model == "" {
+		opts.Model = "Basic"
+	}
+	if opts.FrontField == "" {
+		opts.FrontField = "Front"
+	}
+	if opts.BackField == "" {
+		opts.BackField = "Back"
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = 10 * time.Second
+	}
+	return &AnkiConnectSink{
+		name:   name,
+		label:  label,
+		opts:   opts,
+		client: &http.Client{Timeout: opts.Timeout},
+	}
+}
+
+func (s *AnkiConnectSink) Name() string  { return s.name }
+func (s *AnkiConnectSink) Label() string { return s.label }
+func (s *AnkiConnectSink) Type() string  { return TypeAnkiConnect }
+
+// ankiNote AnkiConnect 笔记
+type ankiNote struct {
+	DeckName  string            `json:"deckName"`
+	ModelName string            `json:"modelName"`
+	Fields    map[string]string `json:"fields"`
+	Tags      []string          `json:"tags"`
+	Options   map[string]any    `json:"options"`
+}
+
+// Sync 创建牌组并批量添加笔记
+func (s *AnkiConnectSink) Sync(ctx context.Context, req Request) (int, error) {
+	if err := s.invoke(ctx, "createDeck", map[string]any{"deck": s.opts.Deck}, nil); err != nil {
+		return 0, err
+	}
+
+	notes := make([]ankiNote, 0, len(req.Words))
+	for _, w := range req.Words {
+		back := html.EscapeString(w.Definition)
+		if w.Example != "" {
+			back += "<br><i>" + html.EscapeString(w.Example) + "</i>"
+		}
+		notes = append(notes, ankiNote{
+			DeckName:  s.opts.Deck,
+			ModelName: s.opts.Model,
+			Fields: map[string]string{
+				s.opts.FrontField: html.EscapeString(w.Word),
+				s.opts.BackField:  back,
+			},
+			Tags:    []string{"voiceflow"},
+			Options: map[string]any{"allowDuplicate": false},
+		})
+	}
+
+	// addNotes 对每条笔记返回笔记 ID，添加失败（如重复）的位置为 null
+	var ids []*int64
+	if err := s.invoke(ctx, "addNotes", map[string]any{"notes": notes}, &ids); err != nil {
+		return 0, err
+	}
+
+	added := 0
+	for _, id := range ids {
+		if id != nil {
+			added++
+		}
+	}
+	return added, nil
+}
+
+// invoke 调用 AnkiConnect 接口
+func (s *AnkiConnectSink) invoke(ctx context.Context, action string, params any, result any) error {
+	body, err := json.Marshal(map[string]any{
+		"action":  action,
+		"version": ankiConnectVersion,
+		"params":  params,
+	})
+	if err != nil {
+		return fmt.Errorf("序列化请求失败: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.opts.URL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("创建请求失败: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("连接 AnkiConnect 失败: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("AnkiConnect 返回状态码 %d", resp.StatusCode)
+	}
+
+	var envelope struct {
+		Result json.RawMessage `json:"result"`
+		Error  *string         `json:"error"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&envelope); err != nil {
+		return fmt.Errorf("解析 AnkiConnect 响应失败: %w", err)
+	}
+	// addNotes 部分失败时 error 也可能非空，以逐条结果为准
+	if envelope.Error != nil && (action != "addNotes" || len(envelope.Result) == 0 || string(envelope.Result) == "null") {
+		return fmt.Errorf("AnkiConnect 错误 (%s): %s", action, *envelope.Error)
+	}
+	if result != nil {
+		if err := json.Unmarshal(envelope.Result, result); err != nil {
+			return fmt.Errorf("解析 AnkiConnect 结果失败: %w", err)
+		}
+	}
+	return nil
+}
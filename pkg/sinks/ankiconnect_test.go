@@ -0,0 +1,112 @@
+package sinks
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/z-wentao/voiceflow/pkg/models"
+)
+
+// ankiRequest AnkiConnect 请求
+type ankiRequest struct {
+	Action  string `json:"action"`
+	Version int    `json:"version"`
+	Params  struct {
+		Deck  string     `json:"deck"`
+		Notes []ankiNote `json:"notes"`
+	} `json:"params"`
+}
+
+func TestAnkiConnectSink(t *testing.T) {
+	words := []models.WordDetail{
+		{Word: "apple", Definition: "苹果 <fruit>", Example: "An apple & a pear."},
+		{Word: "banana", Definition: "香蕉"},
+		{Word: "cherry", Definition: "樱桃"},
+	}
+
+	tests := []struct {
+		name       string
+		createDeck string // createDeck 的响应
+		addNotes   string // addNotes 的响应
+		status     int
+		wantCount  int
+		wantErr    string
+	}{
+		{"全部添加", `{"result":null,"error":null}`, `{"result":[1,2,3],"error":null}`, http.StatusOK, 3, ""},
+		{"重复的单词跳过", `{"result":null,"error":null}`, `{"result":[1,null,3],"error":"cannot create note because it is a duplicate"}`, http.StatusOK, 2, ""},
+		{"创建牌组失败", `{"result":null,"error":"collection is not available"}`, "", http.StatusOK, 0, "collection is not available"},
+		{"addNotes 整体失败", `{"result":null,"error":null}`, `{"result":null,"error":"model was not found: Basic"}`, http.StatusOK, 0, "model was not found"},
+		{"HTTP 错误", "", "", http.StatusForbidden, 0, "状态码 403"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var requests []ankiRequest
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				var req ankiRequest
+				if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+					t.Errorf("解析请求体: %v", err)
+				}
+				requests = append(requests, req)
+				if tt.status != http.StatusOK {
+					w.WriteHeader(tt.status)
+					return
+				}
+				switch req.Action {
+				case "createDeck":
+					w.Write([]byte(tt.createDeck))
+				case "addNotes":
+					w.Write([]byte(tt.addNotes))
+				default:
+					t.Errorf("未知的 action: %s", req.Action)
+				}
+			}))
+			defer srv.Close()
+
+			sink := NewAnkiConnectSink("anki", "", AnkiConnectOptions{URL: srv.URL, Deck: "English", BackField: "Meaning"})
+			count, err := sink.Sync(context.Background(), Request{JobID: "job-1", Words: words})
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v，期望包含 %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Sync: %v", err)
+			}
+			if count != tt.wantCount {
+				t.Fatalf("同步数量 = %d，期望 %d", count, tt.wantCount)
+			}
+
+			if len(requests) != 2 || requests[0].Action != "createDeck" || requests[0].Params.Deck != "English" || requests[1].Action != "addNotes" {
+				t.Fatalf("请求 = %+v，期望先 createDeck 再 addNotes", requests)
+			}
+			for _, req := range requests {
+				if req.Version != ankiConnectVersion {
+					t.Errorf("%s 的 version = %d", req.Action, req.Version)
+				}
+			}
+			notes := requests[1].Params.Notes
+			if len(notes) != len(words) {
+				t.Fatalf("提交了 %d 条笔记，期望 %d 条", len(notes), len(words))
+			}
+			// 正面为单词，背面为转义后的释义和例句；字段名、笔记类型使用配置（未设置时为默认值）
+			first := notes[0]
+			if first.DeckName != "English" || first.ModelName != "Basic" {
+				t.Errorf("笔记牌组 / 类型 = %s / %s", first.DeckName, first.ModelName)
+			}
+			if first.Fields["Front"] != "apple" {
+				t.Errorf("Front = %q", first.Fields["Front"])
+			}
+			if want := "苹果 &lt;fruit&gt;<br><i>An apple &amp; a pear.</i>"; first.Fields["Meaning"] != want {
+				t.Errorf("Meaning = %q，期望 %q", first.Fields["Meaning"], want)
+			}
+			if notes[1].Fields["Meaning"] != "香蕉" {
+				t.Errorf("没有例句时 Meaning = %q", notes[1].Fields["Meaning"])
+			}
+		})
+	}
+}
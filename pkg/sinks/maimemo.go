@@ -0,0 +1,43 @@
+package sinks
+
+import (
+	"context"
+
+	"github.com/z-wentao/voiceflow/pkg/maimemo_service"
+)
+
+// MaimemoSink 同步到墨墨云词本（通过 Maimemo 微服务）
+// 需要用户在表单中填写 notepad_id，token 来自表单或服务端保存的凭证
+type MaimemoSink struct {
+	name   string
+	label  string
+	client *maimemo_service.Client
+}
+
+// NewMaimemoSink 创建墨墨同步目标
+func NewMaimemoSink(name, label string, client *maimemo_service.Client) *MaimemoSink {
+	if label == "" {
+		label = "墨墨背单词"
+	}
+	return &MaimemoSink{name: name, label: label, client: client}
+}
+
+func (s *MaimemoSink) Name() string  { return s.name }
+func (s *MaimemoSink) Label() string { return s.label }
+func (s *MaimemoSink) Type() string  { return TypeMaimemo }
+
+// Sync 添加单词到云词本，云词本中已有的单词跳过，返回实际添加的单词数
+// 云词本已满时返回已添加的数量和 ErrNotepadFull
+func (s *MaimemoSink) Sync(ctx context.Context, req Request) (int, error) {
+	token := req.Params["token"]
+	notepadID := req.Params["notepad_id"]
+	if token == "" || notepadID == "" {
+		return 0, ErrMissingParams
+	}
+
+	added, _, err := s.client.AddWordsToNotepad(ctx, token, notepadID, wordList(req.Words))
+	if err != nil {
+		return added, err
+	}
+	return added, nil
+}
@@ -0,0 +1,75 @@
+package sinks
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/z-wentao/voiceflow/pkg/maimemo_service"
+	"github.com/z-wentao/voiceflow/pkg/models"
+)
+
+func TestMaimemoSink(t *testing.T) {
+	words := []models.WordDetail{{Word: "apple"}, {Word: "banana"}, {Word: "cherry"}}
+
+	tests := []struct {
+		name      string
+		params    map[string]string
+		content   string // 云词本现有内容
+		limits    maimemo_service.Limits
+		wantCount int
+		wantPosts int
+		wantErr   error
+	}{
+		{"添加新单词", map[string]string{"token": "token", "notepad_id": "np-1"}, "#2025-01-01\n", maimemo_service.Limits{}, 3, 1, nil},
+		{"已有的单词跳过", map[string]string{"token": "token", "notepad_id": "np-1"}, "#2025-01-01\nApple\ncherry", maimemo_service.Limits{}, 1, 1, nil},
+		{"全部已存在不提交", map[string]string{"token": "token", "notepad_id": "np-1"}, "#2025-01-01\napple\nbanana\ncherry", maimemo_service.Limits{}, 0, 0, nil},
+		{"云词本已满", map[string]string{"token": "token", "notepad_id": "np-1"}, "#2025-01-01\n", maimemo_service.Limits{MaxNotepadBytes: 32}, 1, 1, maimemo_service.ErrNotepadFull},
+		{"缺少 token", map[string]string{"notepad_id": "np-1"}, "", maimemo_service.Limits{}, 0, 0, ErrMissingParams},
+		{"缺少云词本", map[string]string{"token": "token"}, "", maimemo_service.Limits{}, 0, 0, ErrMissingParams},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var posts [][]string
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch {
+				case r.Method == http.MethodGet && r.URL.Path == "/api/v1/notepads/np-1" && r.Header.Get("X-Maimemo-Token") == "token":
+					json.NewEncoder(w).Encode(maimemo_service.GetNotepadResponse{Notepad: maimemo_service.Notepad{ID: "np-1", Content: tt.content}})
+				case r.Method == http.MethodPost && r.URL.Path == "/api/v1/notepads/np-1/words":
+					var req maimemo_service.AddWordsRequest
+					if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Token != "token" {
+						http.Error(w, `{"error":"bad request"}`, http.StatusBadRequest)
+						return
+					}
+					posts = append(posts, req.Words)
+					json.NewEncoder(w).Encode(maimemo_service.AddWordsResponse{Message: "ok", Count: len(req.Words)})
+				default:
+					http.Error(w, `{"error":"not found"}`, http.StatusNotFound)
+				}
+			}))
+			defer srv.Close()
+
+			client := maimemo_service.NewClient(srv.URL)
+			tt.limits.RequestInterval = -1
+			client.SetLimits(tt.limits)
+			sink := NewMaimemoSink("maimemo", "", client)
+
+			count, err := sink.Sync(context.Background(), Request{JobID: "job-1", Words: words, Params: tt.params})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v，期望 %v", err, tt.wantErr)
+			}
+			if count != tt.wantCount {
+				t.Fatalf("同步数量 = %d，期望 %d", count, tt.wantCount)
+			}
+			if len(posts) != tt.wantPosts {
+				t.Fatalf("提交了 %d 次，期望 %d 次: %v", len(posts), tt.wantPosts, posts)
+			}
+			if sink.Label() != "墨墨背单词" {
+				t.Errorf("未设置 label 时 Label() = %q", sink.Label())
+			}
+		})
+	}
+}
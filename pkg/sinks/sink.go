@@ -0,0 +1,78 @@
+package sinks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/z-wentao/voiceflow/pkg/models"
+)
+
+// 同步目标类型
+const (
+	TypeMaimemo     = "maimemo"
+	TypeAnkiConnect = "ankiconnect"
+	TypeWebhook     = "webhook"
+)
+
+// ErrMissingParams 缺少用户需要填写的参数（如墨墨的 Token）
+var ErrMissingParams = errors.New("缺少同步参数")
+
+// VocabularySink 单词同步目标（墨墨、Anki 等外部背单词 / 间隔重复系统）
+type VocabularySink interface {
+	// Name 配置中的唯一名称（用于路由和同步记录）
+	Name() string
+	// Label 界面上显示的名称
+	Label() string
+	// Type 同步目标类型（决定界面上是否需要填写参数）
+	Type() string
+	// Sync 同步单词，返回成功同步的单词数
+	Sync(ctx context.Context, req Request) (int, error)
+}
+
+// Request 同步请求
+type Request struct {
+	JobID    string
+	Filename string
+	Words    []models.WordDetail
+	Params   map[string]string // 用户在表单中填写的参数（如墨墨的 token / notepad_id）
+}
+
+// Registry 已配置的同步目标（按配置顺序）
+type Registry struct {
+	sinks  []VocabularySink
+	byName map[string]VocabularySink
+}
+
+// NewRegistry 创建同步目标注册表（名称不能重复）
+func NewRegistry(sinks ...VocabularySink) (*Registry, error) {
+	r := &Registry{byName: make(map[string]VocabularySink, len(sinks))}
+	for _, sink := range sinks {
+		if _, exists := r.byName[sink.Name()]; exists {
+			return nil, fmt.Errorf("同步目标名称重复: %s", sink.Name())
+		}
+		r.byName[sink.Name()] = sink
+		r.sinks = append(r.sinks, sink)
+	}
+	return r, nil
+}
+
+// Get 按名称查找同步目标
+func (r *Registry) Get(name string) (VocabularySink, bool) {
+	sink, ok := r.byName[name]
+	return sink, ok
+}
+
+// List 返回所有同步目标
+func (r *Registry) List() []VocabularySink {
+	return r.sinks
+}
+
+// wordList 提取单词文本
+func wordList(words []models.WordDetail) []string {
+	list := make([]string, len(words))
+	for i, w := range words {
+		list[i] = w.Word
+	}
+	return list
+}
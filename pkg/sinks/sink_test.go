@@ -0,0 +1,57 @@
+package sinks
+
+import (
+	"context"
+	"testing"
+)
+
+// stubSink 只有名称的同步目标
+type stubSink struct{ name string }
+
+func (s stubSink) Name() string  { return s.name }
+func (s stubSink) Label() string { return s.name }
+func (s stubSink) Type() string  { return TypeWebhook }
+func (s stubSink) Sync(ctx context.Context, req Request) (int, error) {
+	return len(req.Words), nil
+}
+
+func TestNewRegistry(t *testing.T) {
+	tests := []struct {
+		name    string
+		sinks   []string
+		wantErr bool
+	}{
+		{"没有同步目标", nil, false},
+		{"按配置顺序注册", []string{"maimemo", "anki", "srs"}, false},
+		{"名称重复", []string{"anki", "srs", "anki"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var sinks []VocabularySink
+			for _, name := range tt.sinks {
+				sinks = append(sinks, stubSink{name})
+			}
+			r, err := NewRegistry(sinks...)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v，期望出错 = %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if len(r.List()) != len(tt.sinks) {
+				t.Fatalf("List 返回 %d 个，期望 %d 个", len(r.List()), len(tt.sinks))
+			}
+			for i, name := range tt.sinks {
+				if r.List()[i].Name() != name {
+					t.Errorf("List()[%d] = %s，期望 %s", i, r.List()[i].Name(), name)
+				}
+				if sink, ok := r.Get(name); !ok || sink.Name() != name {
+					t.Errorf("Get(%s) = %v, %v", name, sink, ok)
+				}
+			}
+			if _, ok := r.Get("unknown"); ok {
+				t.Error("Get 返回了未配置的同步目标")
+			}
+		})
+	}
+}
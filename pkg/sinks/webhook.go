@@ -0,0 +1,81 @@
+package sinks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/z-wentao/voiceflow/pkg/models"
+)
+
+// WebhookPayload 单词同步 webhook 请求体
+type WebhookPayload struct {
+	Event    string              `json:"event"` // 固定为 vocabulary.sync
+	SentAt   time.Time           `json:"sent_at"`
+	JobID    string              `json:"job_id"`
+	Filename string              `json:"filename"`
+	Words    []models.WordDetail `json:"words"`
+}
+
+// WebhookSink 以 JSON 形式将单词推送到任意 HTTP 接口（自建 SRS 等）
+type WebhookSink struct {
+	name   string
+	label  string
+	url    string
+	client *http.Client
+}
+
+// NewWebhookSink 创建 webhook 同步目标
+func NewWebhookSink(name, label, url string, timeout time.Duration) *WebhookSink {
+	if label == "" {
+		label = name
+	}
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+	return &WebhookSink{
+		name:   name,
+		label:  label,
+		url:    url,
+		client: &http.Client{Timeout: timeout},
+	}
+}
+
+func (s *WebhookSink) Name() string  { return s.name }
+func (s *WebhookSink) Label() string { return s.label }
+func (s *WebhookSink) Type() string  { return TypeWebhook }
+
+// Sync 推送单词，接口返回 2xx 视为全部同步成功
+func (s *WebhookSink) Sync(ctx context.Context, req Request) (int, error) {
+	body, err := json.Marshal(WebhookPayload{
+		Event:    "vocabulary.sync",
+		SentAt:   time.Now(),
+		JobID:    req.JobID,
+		Filename: req.Filename,
+		Words:    req.Words,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("序列化请求失败: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return 0, fmt.Errorf("创建请求失败: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("User-Agent", "VoiceFlow-Webhook/1.0")
+
+	resp, err := s.client.Do(httpReq)
+	if err != nil {
+		return 0, fmt.Errorf("请求失败: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return 0, fmt.Errorf("接口返回状态码 %d", resp.StatusCode)
+	}
+	return len(req.Words), nil
+}
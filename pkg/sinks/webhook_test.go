@@ -0,0 +1,71 @@
+package sinks
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/z-wentao/voiceflow/pkg/models"
+)
+
+func TestWebhookSink(t *testing.T) {
+	words := []models.WordDetail{
+		{Word: "apple", Definition: "苹果", Example: "An apple a day."},
+		{Word: "banana", Definition: "香蕉"},
+	}
+
+	tests := []struct {
+		name      string
+		status    int
+		delay     time.Duration
+		wantCount int
+		wantErr   bool
+	}{
+		{"2xx 视为全部同步成功", http.StatusOK, 0, 2, false},
+		{"202 Accepted", http.StatusAccepted, 0, 2, false},
+		{"非 2xx 返回错误", http.StatusInternalServerError, 0, 0, true},
+		{"重定向不算成功", http.StatusNotModified, 0, 0, true},
+		{"超时返回错误", http.StatusOK, 200 * time.Millisecond, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// 超时的请求在 Sync 返回后 handler 仍在运行，请求内容通过 channel 传回
+			contentTypes := make(chan string, 1)
+			payloads := make(chan WebhookPayload, 1)
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				var payload WebhookPayload
+				if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+					t.Errorf("解析请求体: %v", err)
+				}
+				contentTypes <- r.Header.Get("Content-Type")
+				payloads <- payload
+				time.Sleep(tt.delay)
+				w.WriteHeader(tt.status)
+			}))
+			defer srv.Close()
+
+			sink := NewWebhookSink("srs", "", srv.URL, 50*time.Millisecond)
+			count, err := sink.Sync(context.Background(), Request{JobID: "job-1", Filename: "talk.mp3", Words: words})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v，期望出错 = %v", err, tt.wantErr)
+			}
+			if count != tt.wantCount {
+				t.Fatalf("同步数量 = %d，期望 %d", count, tt.wantCount)
+			}
+
+			ct, got := <-contentTypes, <-payloads
+			if ct != "application/json" {
+				t.Errorf("Content-Type = %q", ct)
+			}
+			if got.Event != "vocabulary.sync" || got.JobID != "job-1" || got.Filename != "talk.mp3" || len(got.Words) != 2 || got.Words[0] != words[0] {
+				t.Errorf("请求体 = %+v", got)
+			}
+			if sink.Label() != "srs" {
+				t.Errorf("未设置 label 时 Label() = %q，期望使用名称", sink.Label())
+			}
+		})
+	}
+}
@@ -0,0 +1,154 @@
+package sources
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// maxFeedSize 订阅源 XML 最大字节数
+const maxFeedSize = 10 << 20
+
+// Item 订阅源中的一期节目（带媒体附件）
+type Item struct {
+	GUID      string    // 唯一标识（RSS guid / Atom id），可能为空
+	Title     string    // 标题
+	MediaURL  string    // 媒体附件地址（enclosure）
+	Published time.Time // 发布时间，解析失败时为零值
+}
+
+// Key 去重标识：优先使用 GUID，没有时使用媒体地址
+func (it Item) Key() string {
+	if it.GUID != "" {
+		return it.GUID
+	}
+	return it.MediaURL
+}
+
+// rssFeed RSS 2.0 结构（只解析需要的字段）
+type rssFeed struct {
+	Items []struct {
+		Title     string `xml:"title"`
+		GUID      string `xml:"guid"`
+		PubDate   string `xml:"pubDate"`
+		Enclosure struct {
+			URL string `xml:"url,attr"`
+		} `xml:"enclosure"`
+	} `xml:"channel>item"`
+}
+
+// atomFeed Atom 结构（只解析需要的字段）
+type atomFeed struct {
+	Entries []struct {
+		Title     string `xml:"title"`
+		ID        string `xml:"id"`
+		Updated   string `xml:"updated"`
+		Published string `xml:"published"`
+		Links     []struct {
+			Rel  string `xml:"rel,attr"`
+			Href string `xml:"href,attr"`
+		} `xml:"link"`
+	} `xml:"entry"`
+}
+
+// fetchFeed 下载并解析订阅源
+func fetchFeed(ctx context.Context, client *http.Client, url string) ([]Item, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("创建请求失败: %w", err)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("获取订阅源失败: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("获取订阅源失败: 状态码 %d", resp.StatusCode)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxFeedSize))
+	if err != nil {
+		return nil, fmt.Errorf("读取订阅源失败: %w", err)
+	}
+
+	return ParseFeed(data)
+}
+
+// ParseFeed 解析 RSS 2.0 或 Atom 订阅源，返回带媒体附件的条目（按发布时间倒序）
+func ParseFeed(data []byte) ([]Item, error) {
+	var root struct {
+		XMLName xml.Name
+	}
+	if err := xml.Unmarshal(data, &root); err != nil {
+		return nil, fmt.Errorf("解析订阅源失败: %w", err)
+	}
+
+	var items []Item
+	switch root.XMLName.Local {
+	case "rss":
+		var feed rssFeed
+		if err := xml.Unmarshal(data, &feed); err != nil {
+			return nil, fmt.Errorf("解析 RSS 失败: %w", err)
+		}
+		for _, it := range feed.Items {
+			if it.Enclosure.URL == "" {
+				continue
+			}
+			items = append(items, Item{
+				GUID:      strings.TrimSpace(it.GUID),
+				Title:     strings.TrimSpace(it.Title),
+				MediaURL:  strings.TrimSpace(it.Enclosure.URL),
+				Published: parseTime(it.PubDate),
+			})
+		}
+	case "feed":
+		var feed atomFeed
+		if err := xml.Unmarshal(data, &feed); err != nil {
+			return nil, fmt.Errorf("解析 Atom 失败: %w", err)
+		}
+		for _, entry := range feed.Entries {
+			for _, link := range entry.Links {
+				if link.Rel != "enclosure" || link.Href == "" {
+					continue
+				}
+				published := parseTime(entry.Published)
+				if published.IsZero() {
+					published = parseTime(entry.Updated)
+				}
+				items = append(items, Item{
+					GUID:      strings.TrimSpace(entry.ID),
+					Title:     strings.TrimSpace(entry.Title),
+					MediaURL:  strings.TrimSpace(link.Href),
+					Published: published,
+				})
+				break
+			}
+		}
+	default:
+		return nil, fmt.Errorf("不支持的订阅源格式: <%s>", root.XMLName.Local)
+	}
+
+	// 稳定排序：发布时间都缺失时保持订阅源原有顺序（通常已按时间倒序）
+	sort.SliceStable(items, func(i, j int) bool {
+		return items[i].Published.After(items[j].Published)
+	})
+	return items, nil
+}
+
+// parseTime 解析订阅源中常见的时间格式，失败返回零值
+func parseTime(value string) time.Time {
+	value = strings.TrimSpace(value)
+	for _, layout := range []string{time.RFC1123Z, time.RFC1123, time.RFC3339, "Mon, 2 Jan 2006 15:04:05 -0700", "Mon, 2 Jan 2006 15:04:05 MST"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
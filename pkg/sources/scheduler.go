@@ -0,0 +1,266 @@
+package sources
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/z-wentao/voiceflow/pkg/storage"
+)
+
+// maxBackoff 连续失败时的最长等待时间
+const maxBackoff = 24 * time.Hour
+
+// Config 订阅源配置
+type Config struct {
+	Name     string        // 名称（会写入任务的 Source 字段）
+	URL      string        // RSS / Atom 地址
+	Interval time.Duration // 轮询间隔
+	MaxItems int           // 每次轮询最多检查最新的几期（避免首次订阅时转录全部历史节目）
+	Enabled  bool
+}
+
+// IngestFunc 下载一期节目并创建转录任务（jobID 由调度器预先分配）
+type IngestFunc func(ctx context.Context, jobID, source string, item Item) error
+
+// Status 订阅源状态
+type Status struct {
+	Name                string    `json:"name"`
+	URL                 string    `json:"url"`
+	Enabled             bool      `json:"enabled"`
+	IntervalMinutes     int       `json:"interval_minutes"`
+	LastPollAt          time.Time `json:"last_poll_at,omitempty"`
+	LastSuccessAt       time.Time `json:"last_success_at,omitempty"`
+	LastError           string    `json:"last_error,omitempty"`
+	LastNewItems        int       `json:"last_new_items"`       // 上次轮询新建的任务数
+	TotalIngested       int       `json:"total_ingested"`       // 启动以来新建的任务数
+	ConsecutiveFailures int       `json:"consecutive_failures"` // 连续失败次数（决定退避时间）
+	NextPollAt          time.Time `json:"next_poll_at"`
+}
+
+// source 运行时的订阅源
+type source struct {
+	cfg    Config
+	status Status
+}
+
+// Scheduler 订阅源调度器
+// 定期拉取订阅源，发现新节目后下载并创建转录任务；
+// 已处理的节目通过存储层的哈希登记去重（重启或多实例部署也不会重复转录）
+type Scheduler struct {
+	mu      sync.Mutex
+	sources map[string]*source
+	order   []string // 配置顺序，用于状态列表
+
+	store  storage.Store
+	ingest IngestFunc
+	client *http.Client
+
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// NewScheduler 创建订阅源调度器
+func NewScheduler(store storage.Store, ingest IngestFunc, configs []Config) *Scheduler {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	s := &Scheduler{
+		sources: make(map[string]*source),
+		store:   store,
+		ingest:  ingest,
+		client:  &http.Client{Timeout: 30 * time.Second},
+		ctx:     ctx,
+		cancel:  cancel,
+	}
+
+	now := time.Now()
+	for _, cfg := range configs {
+		s.sources[cfg.Name] = &source{
+			cfg: cfg,
+			status: Status{
+				Name:            cfg.Name,
+				URL:             cfg.URL,
+				Enabled:         cfg.Enabled,
+				IntervalMinutes: int(cfg.Interval / time.Minute),
+				NextPollAt:      now,
+			},
+		}
+		s.order = append(s.order, cfg.Name)
+	}
+
+	return s
+}
+
+// Start 启动调度循环
+func (s *Scheduler) Start() {
+	go s.run()
+}
+
+// Stop 停止调度循环（进行中的下载会被取消）
+func (s *Scheduler) Stop() {
+	s.cancel()
+}
+
+// run 调度主循环：每 30 秒检查一次到期的订阅源
+func (s *Scheduler) run() {
+	ticker := time.NewTicker(30 * time.Second)
+	defer ticker.Stop()
+
+	for {
+		for _, name := range s.dueSources() {
+			s.poll(name)
+		}
+
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// dueSources 返回已到轮询时间的订阅源
+func (s *Scheduler) dueSources() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	due := make([]string, 0)
+	for _, name := range s.order {
+		src := s.sources[name]
+		if src.status.Enabled && !now.Before(src.status.NextPollAt) {
+			due = append(due, name)
+		}
+	}
+	return due
+}
+
+// poll 拉取一个订阅源并创建新任务
+func (s *Scheduler) poll(name string) {
+	s.mu.Lock()
+	cfg := s.sources[name].cfg
+	s.mu.Unlock()
+
+	created, err := s.ingestNew(cfg)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	status := &s.sources[name].status
+	now := time.Now()
+	status.LastPollAt = now
+	status.LastNewItems = created
+	status.TotalIngested += created
+
+	if err != nil {
+		status.ConsecutiveFailures++
+		status.LastError = err.Error()
+		status.NextPollAt = now.Add(backoff(cfg.Interval, status.ConsecutiveFailures))
+		log.Printf("⚠️ 订阅源 %s 拉取失败 (连续 %d 次)，%s 后重试: %v",
+			name, status.ConsecutiveFailures, status.NextPollAt.Sub(now).Round(time.Second), err)
+		return
+	}
+
+	status.ConsecutiveFailures = 0
+	status.LastError = ""
+	status.LastSuccessAt = now
+	status.NextPollAt = now.Add(cfg.Interval)
+	if created > 0 {
+		log.Printf("📻 订阅源 %s 新增 %d 个任务", name, created)
+	}
+}
+
+// ingestNew 检查最新的节目，为未处理过的节目创建任务，返回新建任务数
+func (s *Scheduler) ingestNew(cfg Config) (int, error) {
+	items, err := fetchFeed(s.ctx, s.client, cfg.URL)
+	if err != nil {
+		return 0, err
+	}
+	if cfg.MaxItems > 0 && len(items) > cfg.MaxItems {
+		items = items[:cfg.MaxItems]
+	}
+
+	// 从旧到新处理，保证任务创建顺序与发布顺序一致
+	created := 0
+	for i := len(items) - 1; i >= 0; i-- {
+		item := items[i]
+		hash := itemHash(item)
+		jobID := uuid.New().String()
+
+		// 登记不能过期：节目会一直留在订阅源中，登记过期后会被重新导入
+		_, isNew, err := storage.SetMarkerIfAbsent(s.ctx, s.store, hash, jobID)
+		if err != nil {
+			return created, fmt.Errorf("登记节目失败: %w", err)
+		}
+		if !isNew {
+			continue
+		}
+
+		log.Printf("📻 订阅源 %s 发现新节目: %s", cfg.Name, item.Title)
+		if err := s.ingest(s.ctx, jobID, cfg.Name, item); err != nil {
+			// 释放登记，下次轮询重试
+			storage.DeleteMarker(s.ctx, s.store, hash, jobID)
+			return created, fmt.Errorf("导入节目 %q 失败: %w", item.Title, err)
+		}
+		created++
+	}
+
+	return created, nil
+}
+
+// itemHash 节目的去重哈希（登记见 storage.SetMarkerIfAbsent，可能与上传文件的内容哈希共用存储，加前缀区分）
+func itemHash(item Item) string {
+	sum := sha256.Sum256([]byte(item.Key()))
+	return "feed:" + hex.EncodeToString(sum[:])
+}
+
+// backoff 连续失败时的等待时间：轮询间隔按 2 的幂次增长，最长 24 小时（不短于轮询间隔）
+func backoff(interval time.Duration, failures int) time.Duration {
+	d := interval
+	for i := 0; i < failures && d < maxBackoff; i++ {
+		d *= 2
+	}
+	return min(d, max(maxBackoff, interval))
+}
+
+// List 返回所有订阅源的状态
+func (s *Scheduler) List() []Status {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	result := make([]Status, 0, len(s.order))
+	for _, name := range s.order {
+		result = append(result, s.sources[name].status)
+	}
+	return result
+}
+
+// SetEnabled 启用或停用订阅源；重新启用时立即轮询一次并清空失败计数
+func (s *Scheduler) SetEnabled(name string, enabled bool) (Status, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	src, ok := s.sources[name]
+	if !ok {
+		return Status{}, fmt.Errorf("订阅源不存在: %s", name)
+	}
+
+	if enabled && !src.status.Enabled {
+		src.status.ConsecutiveFailures = 0
+		src.status.NextPollAt = time.Now()
+	}
+	src.status.Enabled = enabled
+
+	if enabled {
+		log.Printf("📻 订阅源已启用: %s", name)
+	} else {
+		log.Printf("📻 订阅源已停用: %s", name)
+	}
+	return src.status, nil
+}
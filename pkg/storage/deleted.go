@@ -0,0 +1,57 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// PostgreSQL 与 SQLite 共用的软删除读写（transcription_jobs.deleted_at），placeholder 生成第 n 个参数的占位符
+
+// notDeleted 排除软删除任务的查询条件
+const notDeleted = `deleted_at IS NULL`
+
+// nullTime 零值时间写入为 NULL（deleted_at 为 NULL 表示未删除）
+func nullTime(t time.Time) any {
+	if t.IsZero() {
+		return nil
+	}
+	return t
+}
+
+// softDelete 记录任务的删除时间（已删除的任务视为不存在），版本号加 1
+func softDelete(ctx context.Context, db *sql.DB, placeholder func(n int) string, jobID string) error {
+	query := `UPDATE transcription_jobs SET deleted_at = ` + placeholder(1) + `, version = version + 1
+    WHERE job_id = ` + placeholder(2) + ` AND ` + notDeleted
+
+	result, err := db.ExecContext(ctx, query, time.Now(), jobID)
+	if err != nil {
+		return fmt.Errorf("删除任务失败: %w", err)
+	}
+	return requireAffected(result, fmt.Errorf("任务不存在: %s", jobID))
+}
+
+// restoreDeleted 清除任务的删除时间，版本号加 1
+func restoreDeleted(ctx context.Context, db *sql.DB, placeholder func(n int) string, jobID string) error {
+	query := `UPDATE transcription_jobs SET deleted_at = NULL, version = version + 1
+    WHERE job_id = ` + placeholder(1) + ` AND deleted_at IS NOT NULL`
+
+	result, err := db.ExecContext(ctx, query, jobID)
+	if err != nil {
+		return fmt.Errorf("恢复任务失败: %w", err)
+	}
+	return requireAffected(result, fmt.Errorf("任务不存在或未被删除: %s", jobID))
+}
+
+// requireAffected 语句没有修改任何行时返回 notFound
+func requireAffected(result sql.Result, notFound error) error {
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("获取执行结果失败: %w", err)
+	}
+	if rowsAffected == 0 {
+		return notFound
+	}
+	return nil
+}
@@ -0,0 +1,479 @@
+package storage
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/z-wentao/voiceflow/pkg/models"
+)
+
+// encryptedPrefix 加密字段的前缀，完整格式为 enc:<密钥 ID>:<base64(nonce + 密文)>
+// 没有前缀的值是启用加密之前写入的明文，读取时原样返回
+const encryptedPrefix = "enc:"
+
+// ErrUnknownEncryptionKey 密文使用的密钥没有配置（密钥已更换且没有保留在 old_encryption_keys 中）
+var ErrUnknownEncryptionKey = errors.New("未配置对应的加密密钥")
+
+// FieldCipher 字段加密：AES-256-GCM，密钥由配置的字符串经 SHA-256 派生
+// 密文带有密钥 ID（密钥的摘要），轮换密钥时新数据使用当前密钥加密，旧密钥只用于解密之前写入的数据
+type FieldCipher struct {
+	keyID string                 // 当前密钥的 ID
+	keys  map[string]cipher.AEAD // 密钥 ID -> 加密算法（当前密钥和旧密钥）
+}
+
+// NewFieldCipher 创建字段加密，key 为当前密钥，oldKeys 为轮换前使用的密钥
+func NewFieldCipher(key string, oldKeys ...string) (*FieldCipher, error) {
+	if key == "" {
+		return nil, fmt.Errorf("加密密钥不能为空")
+	}
+
+	c := &FieldCipher{keys: make(map[string]cipher.AEAD)}
+	for i, k := range append([]string{key}, oldKeys...) {
+		if k == "" {
+			continue
+		}
+		id, aead, err := newFieldAEAD(k)
+		if err != nil {
+			return nil, err
+		}
+		if i == 0 {
+			c.keyID = id
+		}
+		c.keys[id] = aead
+	}
+	return c, nil
+}
+
+// newFieldAEAD 由密钥字符串派生 AES-256-GCM，密钥 ID 为派生密钥再做一次 SHA-256 的前 4 字节（十六进制）
+func newFieldAEAD(key string) (string, cipher.AEAD, error) {
+	sum := sha256.Sum256([]byte(key))
+	block, err := aes.NewCipher(sum[:])
+	if err != nil {
+		return "", nil, fmt.Errorf("初始化加密算法失败: %w", err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return "", nil, fmt.Errorf("初始化加密算法失败: %w", err)
+	}
+	idSum := sha256.Sum256(sum[:])
+	return hex.EncodeToString(idSum[:4]), aead, nil
+}
+
+// KeyID 当前密钥的 ID（写入密文前缀，用于确认轮换后使用的密钥）
+func (c *FieldCipher) KeyID() string {
+	return c.keyID
+}
+
+// Encrypt 使用当前密钥加密，aad 为附加认证数据（任务 ID 和字段名，密文不能被挪用到其他任务或字段）
+// 空字符串不加密
+func (c *FieldCipher) Encrypt(plain, aad string) (string, error) {
+	if plain == "" {
+		return "", nil
+	}
+
+	aead := c.keys[c.keyID]
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("生成随机数失败: %w", err)
+	}
+	sealed := aead.Seal(nonce, nonce, []byte(plain), []byte(aad))
+	return encryptedPrefix + c.keyID + ":" + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt 按密文中的密钥 ID 解密；没有加密前缀的值原样返回
+func (c *FieldCipher) Decrypt(value, aad string) (string, error) {
+	rest, ok := strings.CutPrefix(value, encryptedPrefix)
+	if !ok {
+		return value, nil
+	}
+
+	keyID, encoded, ok := strings.Cut(rest, ":")
+	if !ok {
+		return "", fmt.Errorf("加密字段格式错误")
+	}
+	aead, ok := c.keys[keyID]
+	if !ok {
+		return "", fmt.Errorf("%w（密钥 ID: %s）", ErrUnknownEncryptionKey, keyID)
+	}
+	sealed, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil || len(sealed) < aead.NonceSize() {
+		return "", fmt.Errorf("加密字段格式错误")
+	}
+	plain, err := aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], []byte(aad))
+	if err != nil {
+		return "", fmt.Errorf("解密失败: %w", err)
+	}
+	return string(plain), nil
+}
+
+// fieldAAD 任务字段的附加认证数据
+func fieldAAD(jobID, field string) string {
+	return jobID + "\x00" + field
+}
+
+// jobSecrets 任务中加密保存的字段
+type jobSecrets struct {
+	result      string
+	vocabulary  []string
+	vocabDetail []models.WordDetail
+}
+
+// secretsOf 读取任务中需要加密的字段
+func secretsOf(job *models.TranscriptionJob) jobSecrets {
+	return jobSecrets{result: job.Result, vocabulary: job.Vocabulary, vocabDetail: job.VocabDetail}
+}
+
+// equal 两组字段的内容是否相同
+func (s jobSecrets) equal(other jobSecrets) bool {
+	return s.result == other.result &&
+		slices.Equal(s.vocabulary, other.vocabulary) &&
+		slices.Equal(s.vocabDetail, other.vocabDetail)
+}
+
+// keepUnchanged 内容与 before 相同的字段沿用 stored 中的原密文
+func (s *jobSecrets) keepUnchanged(before, after, stored jobSecrets) {
+	if after.result == before.result {
+		s.result = stored.result
+	}
+	if slices.Equal(after.vocabulary, before.vocabulary) {
+		s.vocabulary = stored.vocabulary
+	}
+	if slices.Equal(after.vocabDetail, before.vocabDetail) {
+		s.vocabDetail = stored.vocabDetail
+	}
+}
+
+// apply 写回任务
+func (s jobSecrets) apply(job *models.TranscriptionJob) {
+	job.Result = s.result
+	job.Vocabulary = s.vocabulary
+	job.VocabDetail = s.vocabDetail
+}
+
+// sealJob 加密任务的转录文本和单词
+// 单词列表和单词详情各自序列化为 JSON 后整体加密，保存为只有一个元素的列表（单词详情的密文放在 Word 字段），
+// 字段类型不变，各存储不需要修改表结构
+func (c *FieldCipher) sealJob(job *models.TranscriptionJob) (jobSecrets, error) {
+	plain := secretsOf(job)
+	sealed := jobSecrets{vocabulary: plain.vocabulary, vocabDetail: plain.vocabDetail}
+
+	var err error
+	if sealed.result, err = c.Encrypt(plain.result, fieldAAD(job.JobID, "result")); err != nil {
+		return jobSecrets{}, err
+	}
+	if len(plain.vocabulary) > 0 {
+		value, err := c.sealJSON(plain.vocabulary, fieldAAD(job.JobID, "vocabulary"))
+		if err != nil {
+			return jobSecrets{}, err
+		}
+		sealed.vocabulary = []string{value}
+	}
+	if len(plain.vocabDetail) > 0 {
+		value, err := c.sealJSON(plain.vocabDetail, fieldAAD(job.JobID, "vocab_detail"))
+		if err != nil {
+			return jobSecrets{}, err
+		}
+		sealed.vocabDetail = []models.WordDetail{{Word: value}}
+	}
+	return sealed, nil
+}
+
+// sealJSON 序列化为 JSON 后加密
+func (c *FieldCipher) sealJSON(v any, aad string) (string, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return "", fmt.Errorf("序列化加密字段失败: %w", err)
+	}
+	return c.Encrypt(string(data), aad)
+}
+
+// openJob 返回解密了转录文本和单词的任务副本（启用加密之前写入的明文原样保留）
+// 不修改传入的任务：Update 的更新函数收到的是存储内部的任务，原地解密会把明文写回存储
+func (c *FieldCipher) openJob(job *models.TranscriptionJob) (*models.TranscriptionJob, error) {
+	opened := secretsOf(job)
+
+	var err error
+	if opened.result, err = c.Decrypt(job.Result, fieldAAD(job.JobID, "result")); err != nil {
+		return nil, fmt.Errorf("解密转录文本失败 (任务 %s): %w", job.JobID, err)
+	}
+	if len(job.Vocabulary) == 1 && strings.HasPrefix(job.Vocabulary[0], encryptedPrefix) {
+		opened.vocabulary = nil
+		if err := c.openJSON(job.Vocabulary[0], fieldAAD(job.JobID, "vocabulary"), &opened.vocabulary); err != nil {
+			return nil, fmt.Errorf("解密单词失败 (任务 %s): %w", job.JobID, err)
+		}
+	}
+	if len(job.VocabDetail) == 1 && strings.HasPrefix(job.VocabDetail[0].Word, encryptedPrefix) {
+		opened.vocabDetail = nil
+		if err := c.openJSON(job.VocabDetail[0].Word, fieldAAD(job.JobID, "vocab_detail"), &opened.vocabDetail); err != nil {
+			return nil, fmt.Errorf("解密单词详情失败 (任务 %s): %w", job.JobID, err)
+		}
+	}
+
+	plain := *job
+	opened.apply(&plain)
+	return &plain, nil
+}
+
+// openJSON 解密后反序列化
+func (c *FieldCipher) openJSON(value, aad string, v any) error {
+	plain, err := c.Decrypt(value, aad)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal([]byte(plain), v); err != nil {
+		return fmt.Errorf("反序列化加密字段失败: %w", err)
+	}
+	return nil
+}
+
+// openJobs 解密任务列表
+func (c *FieldCipher) openJobs(jobs []*models.TranscriptionJob, err error) ([]*models.TranscriptionJob, error) {
+	if err != nil {
+		return nil, err
+	}
+	opened := make([]*models.TranscriptionJob, 0, len(jobs))
+	for _, job := range jobs {
+		plain, err := c.openJob(job)
+		if err != nil {
+			return nil, err
+		}
+		opened = append(opened, plain)
+	}
+	return opened, nil
+}
+
+// EncryptedStore 字段加密的存储包装：转录文本、单词和片段结果加密后写入底层存储，读取时解密
+// 适用于所有 Store 实现；文件名、状态等其他字段和单词索引（job_words）不加密
+type EncryptedStore struct {
+	Store
+	cipher *FieldCipher
+}
+
+// NewEncryptedStore 创建字段加密的存储包装
+// 底层存储支持归档（Archiver）时返回的存储同样支持
+func NewEncryptedStore(store Store, cipher *FieldCipher) Store {
+	encrypted := &EncryptedStore{Store: store, cipher: cipher}
+	if _, ok := store.(Archiver); ok {
+		return &encryptedArchiver{encrypted}
+	}
+	return encrypted
+}
+
+// encryptedArchiver 支持归档的底层存储的加密包装（归档只移动记录，不涉及明文）
+type encryptedArchiver struct {
+	*EncryptedStore
+}
+
+// MoveToArchive 归档完成较久的任务
+func (s *encryptedArchiver) MoveToArchive(ctx context.Context, olderThan time.Time) (int, error) {
+	return s.Store.(Archiver).MoveToArchive(ctx, olderThan)
+}
+
+// Save 加密后保存任务（不修改传入的任务，保存后的版本号写回 job.Version）
+func (s *EncryptedStore) Save(ctx context.Context, job *models.TranscriptionJob) error {
+	sealed, err := s.cipher.sealJob(job)
+	if err != nil {
+		return err
+	}
+	stored := *job
+	sealed.apply(&stored)
+
+	if err := s.Store.Save(ctx, &stored); err != nil {
+		return err
+	}
+	job.Version = stored.Version
+	return nil
+}
+
+// Get 获取并解密任务
+func (s *EncryptedStore) Get(ctx context.Context, jobID string) (*models.TranscriptionJob, error) {
+	job, err := s.Store.Get(ctx, jobID)
+	if err != nil {
+		return nil, err
+	}
+	return s.cipher.openJob(job)
+}
+
+// Update 解密后执行更新函数，再加密写回
+// 更新函数没有修改的字段（逐个字段比较）沿用原来的密文（每次加密的随机数不同，重新加密会让 Redis 等按字段写回的存储重写大字段）；
+// 解密失败时不执行更新函数，任务原样写回
+func (s *EncryptedStore) Update(ctx context.Context, jobID string, updateFn func(*models.TranscriptionJob)) error {
+	var updateErr error
+	err := s.Store.Update(ctx, jobID, func(job *models.TranscriptionJob) {
+		updateErr = nil
+		stored := secretsOf(job)
+		opened, err := s.cipher.openJob(job)
+		if err != nil {
+			updateErr = err
+			return
+		}
+		*job = *opened
+		before := secretsOf(job)
+		before.vocabulary = slices.Clone(before.vocabulary)
+		before.vocabDetail = slices.Clone(before.vocabDetail)
+
+		updateFn(job)
+
+		after := secretsOf(job)
+		if after.equal(before) {
+			stored.apply(job)
+			return
+		}
+		sealed, err := s.cipher.sealJob(job)
+		if err != nil {
+			updateErr = err
+			stored.apply(job)
+			return
+		}
+		sealed.keepUnchanged(before, after, stored)
+		sealed.apply(job)
+	})
+	if err != nil {
+		return err
+	}
+	return updateErr
+}
+
+// List 列出并解密最近的任务
+func (s *EncryptedStore) List(ctx context.Context) ([]*models.TranscriptionJob, error) {
+	return s.cipher.openJobs(s.Store.List(ctx))
+}
+
+// ListAll 列出并解密所有任务
+func (s *EncryptedStore) ListAll(ctx context.Context) ([]*models.TranscriptionJob, error) {
+	return s.cipher.openJobs(s.Store.ListAll(ctx))
+}
+
+// ForEach 遍历并解密所有任务（底层存储支持分页遍历时分页读取）
+func (s *EncryptedStore) ForEach(ctx context.Context, fn func(*models.TranscriptionJob) error) error {
+	return ForEachJob(ctx, s.Store, func(job *models.TranscriptionJob) error {
+		plain, err := s.cipher.openJob(job)
+		if err != nil {
+			return err
+		}
+		return fn(plain)
+	})
+}
+
+// ListForUser 列出并解密指定用户的任务
+func (s *EncryptedStore) ListForUser(ctx context.Context, userID string, limit int) ([]*models.TranscriptionJob, error) {
+	return s.cipher.openJobs(s.Store.ListForUser(ctx, userID, limit))
+}
+
+// ListByStatus 列出并解密指定状态的任务
+func (s *EncryptedStore) ListByStatus(ctx context.Context, statuses ...models.JobStatus) ([]*models.TranscriptionJob, error) {
+	return s.cipher.openJobs(s.Store.ListByStatus(ctx, statuses...))
+}
+
+// ListDeleted 列出并解密软删除的任务
+func (s *EncryptedStore) ListDeleted(ctx context.Context, before time.Time) ([]*models.TranscriptionJob, error) {
+	return s.cipher.openJobs(s.Store.ListDeleted(ctx, before))
+}
+
+// Search 搜索任务
+// 转录文本已加密，底层存储的索引无法匹配，这里逐个解密后匹配文件名和转录文本（按创建时间倒序，最多 searchLimit 个）
+func (s *EncryptedStore) Search(ctx context.Context, query string) ([]*models.TranscriptionJob, error) {
+	errEnough := errors.New("搜索结果已满")
+	matched := make([]*models.TranscriptionJob, 0)
+	err := s.ForEach(ctx, func(job *models.TranscriptionJob) error {
+		if !matchJob(job, query) {
+			return nil
+		}
+		matched = append(matched, job)
+		if len(matched) >= searchLimit {
+			return errEnough
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, errEnough) {
+		return nil, fmt.Errorf("搜索任务失败: %w", err)
+	}
+	return matched, nil
+}
+
+// FindByHash 查找并解密相同文件内容已完成的任务
+func (s *EncryptedStore) FindByHash(ctx context.Context, hash string) (*models.TranscriptionJob, error) {
+	job, err := s.Store.FindByHash(ctx, hash)
+	if err != nil || job == nil {
+		return job, err
+	}
+	return s.cipher.openJob(job)
+}
+
+// JobsByWord 查找并解密包含指定单词的任务
+func (s *EncryptedStore) JobsByWord(ctx context.Context, word string) ([]*models.TranscriptionJob, error) {
+	return s.cipher.openJobs(s.Store.JobsByWord(ctx, word))
+}
+
+// segmentStore 底层存储的片段结果存储
+func (s *EncryptedStore) segmentStore() (SegmentStore, error) {
+	store, ok := s.Store.(SegmentStore)
+	if !ok {
+		return nil, fmt.Errorf("存储不支持保存片段结果")
+	}
+	return store, nil
+}
+
+// segmentAAD 片段结果的附加认证数据
+func segmentAAD(jobID string, index int) string {
+	return fieldAAD(jobID, "segment:"+strconv.Itoa(index))
+}
+
+// SaveSegment 加密后保存片段结果
+func (s *EncryptedStore) SaveSegment(ctx context.Context, jobID string, index int, data []byte) error {
+	store, err := s.segmentStore()
+	if err != nil {
+		return err
+	}
+	sealed, err := s.cipher.Encrypt(string(data), segmentAAD(jobID, index))
+	if err != nil {
+		return err
+	}
+	return store.SaveSegment(ctx, jobID, index, []byte(sealed))
+}
+
+// LoadSegments 读取并解密片段结果
+func (s *EncryptedStore) LoadSegments(ctx context.Context, jobID string) (map[int][]byte, error) {
+	store, err := s.segmentStore()
+	if err != nil {
+		return nil, err
+	}
+	segments, err := store.LoadSegments(ctx, jobID)
+	if err != nil {
+		return nil, err
+	}
+	for index, data := range segments {
+		plain, err := s.cipher.Decrypt(string(data), segmentAAD(jobID, index))
+		if err != nil {
+			return nil, fmt.Errorf("解密片段结果失败 (任务 %s, 片段 %d): %w", jobID, index, err)
+		}
+		segments[index] = []byte(plain)
+	}
+	return segments, nil
+}
+
+// DeleteSegments 删除片段结果
+func (s *EncryptedStore) DeleteSegments(ctx context.Context, jobID string) error {
+	store, err := s.segmentStore()
+	if err != nil {
+		return err
+	}
+	return store.DeleteSegments(ctx, jobID)
+}
+
+// IndexMaintenance 返回底层存储的索引维护状态
+func (s *EncryptedStore) IndexMaintenance() *IndexMaintenanceStats {
+	return IndexMaintenanceOf(s.Store)
+}
@@ -0,0 +1,216 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/z-wentao/voiceflow/pkg/models"
+)
+
+func newTestCipher(t *testing.T, key string, oldKeys ...string) *FieldCipher {
+	t.Helper()
+	c, err := NewFieldCipher(key, oldKeys...)
+	if err != nil {
+		t.Fatalf("NewFieldCipher: %v", err)
+	}
+	return c
+}
+
+func TestFieldCipherRoundTrip(t *testing.T) {
+	c := newTestCipher(t, "current-key")
+	tests := []struct {
+		name  string
+		plain string
+	}{
+		{"英文", "Hello everyone. Welcome to the show."},
+		{"中文", "欢迎收听本期节目"},
+		{"带前缀的明文", "enc:not-a-key:abc"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sealed, err := c.Encrypt(tt.plain, fieldAAD("job-1", "result"))
+			if err != nil {
+				t.Fatalf("Encrypt: %v", err)
+			}
+			if !strings.HasPrefix(sealed, encryptedPrefix+c.KeyID()+":") || strings.Contains(sealed, tt.plain) {
+				t.Fatalf("密文格式错误: %s", sealed)
+			}
+			plain, err := c.Decrypt(sealed, fieldAAD("job-1", "result"))
+			if err != nil || plain != tt.plain {
+				t.Fatalf("Decrypt = %q, %v，期望 %q", plain, err, tt.plain)
+			}
+			// 密文不能挪用到其他任务
+			if _, err := c.Decrypt(sealed, fieldAAD("job-2", "result")); err == nil {
+				t.Fatal("附加认证数据不同时应解密失败")
+			}
+		})
+	}
+
+	if sealed, err := c.Encrypt("", "aad"); err != nil || sealed != "" {
+		t.Fatalf("空字符串 = %q, %v，期望不加密", sealed, err)
+	}
+}
+
+func TestFieldCipherKeyRotation(t *testing.T) {
+	old := newTestCipher(t, "old-key")
+	sealed, err := old.Encrypt("secret transcript", "aad")
+	if err != nil {
+		t.Fatalf("Encrypt: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		cipher  *FieldCipher
+		wantErr error
+	}{
+		{"保留旧密钥", newTestCipher(t, "new-key", "old-key"), nil},
+		{"未保留旧密钥", newTestCipher(t, "new-key"), ErrUnknownEncryptionKey},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plain, err := tt.cipher.Decrypt(sealed, "aad")
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v，期望 %v", err, tt.wantErr)
+			}
+			if tt.wantErr == nil && plain != "secret transcript" {
+				t.Fatalf("Decrypt = %q", plain)
+			}
+		})
+	}
+
+	// 轮换后新数据使用当前密钥加密
+	rotated := newTestCipher(t, "new-key", "old-key")
+	resealed, err := rotated.Encrypt("secret transcript", "aad")
+	if err != nil {
+		t.Fatalf("Encrypt: %v", err)
+	}
+	if rotated.KeyID() == old.KeyID() || !strings.HasPrefix(resealed, encryptedPrefix+rotated.KeyID()+":") {
+		t.Fatalf("轮换后的密文 %s 未使用当前密钥 %s", resealed, rotated.KeyID())
+	}
+}
+
+func TestEncryptedStoreLegacyPlaintext(t *testing.T) {
+	ctx := context.Background()
+	inner := NewJobStore(100)
+	legacy := &models.TranscriptionJob{
+		JobID:       "job-1",
+		Status:      models.StatusCompleted,
+		Result:      "plain transcript",
+		Vocabulary:  []string{"show"},
+		VocabDetail: []models.WordDetail{{Word: "show", Definition: "节目"}},
+	}
+	if err := inner.Save(ctx, legacy); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	store := NewEncryptedStore(inner, newTestCipher(t, "current-key"))
+	job, err := store.Get(ctx, "job-1")
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if job.Result != legacy.Result || !slices.Equal(job.Vocabulary, legacy.Vocabulary) || !slices.Equal(job.VocabDetail, legacy.VocabDetail) {
+		t.Fatalf("启用加密之前的明文应原样返回: %+v", job)
+	}
+}
+
+func TestEncryptedStoreRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	inner := NewJobStore(100)
+	store := NewEncryptedStore(inner, newTestCipher(t, "current-key"))
+
+	job := &models.TranscriptionJob{
+		JobID:       "job-1",
+		Status:      models.StatusCompleted,
+		Result:      "secret transcript",
+		Vocabulary:  []string{"serendipity"},
+		VocabDetail: []models.WordDetail{{Word: "serendipity", Definition: "意外发现"}},
+	}
+	if err := store.Save(ctx, job); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	if job.Result != "secret transcript" {
+		t.Fatal("Save 不应修改传入的任务")
+	}
+
+	raw, _ := inner.Get(ctx, "job-1")
+	for _, value := range []string{raw.Result, raw.Vocabulary[0], raw.VocabDetail[0].Word} {
+		if !strings.HasPrefix(value, encryptedPrefix) {
+			t.Fatalf("底层存储中应为密文: %q", value)
+		}
+	}
+
+	got, err := store.Get(ctx, "job-1")
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if got.Result != job.Result || !slices.Equal(got.Vocabulary, job.Vocabulary) || !slices.Equal(got.VocabDetail, job.VocabDetail) {
+		t.Fatalf("Get = %+v", got)
+	}
+}
+
+func TestEncryptedStoreUpdate(t *testing.T) {
+	tests := []struct {
+		name         string
+		update       func(job *models.TranscriptionJob)
+		wantResult   string
+		wantResealed bool // 转录文本的密文是否变化
+		wantVocab    bool // 单词的密文是否变化
+	}{
+		{"只修改状态", func(job *models.TranscriptionJob) { job.Progress = 50 }, "secret transcript", false, false},
+		{"读取但不修改", func(job *models.TranscriptionJob) { _ = job.Result + job.Vocabulary[0] }, "secret transcript", false, false},
+		{"修改转录文本", func(job *models.TranscriptionJob) { job.Result = "edited transcript" }, "edited transcript", true, false},
+		{"修改单词", func(job *models.TranscriptionJob) { job.Vocabulary = append(job.Vocabulary, "episode") }, "secret transcript", false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			inner := NewJobStore(100)
+			store := NewEncryptedStore(inner, newTestCipher(t, "current-key"))
+			if err := store.Save(ctx, &models.TranscriptionJob{JobID: "job-1", Result: "secret transcript", Vocabulary: []string{"show"}}); err != nil {
+				t.Fatalf("Save: %v", err)
+			}
+			before, _ := inner.Get(ctx, "job-1")
+
+			if err := store.Update(ctx, "job-1", tt.update); err != nil {
+				t.Fatalf("Update: %v", err)
+			}
+
+			after, _ := inner.Get(ctx, "job-1")
+			if resealed := after.Result != before.Result; resealed != tt.wantResealed {
+				t.Fatalf("密文变化 = %v，期望 %v", resealed, tt.wantResealed)
+			}
+			if vocab := !slices.Equal(after.Vocabulary, before.Vocabulary); vocab != tt.wantVocab {
+				t.Fatalf("单词密文变化 = %v，期望 %v", vocab, tt.wantVocab)
+			}
+			if !strings.HasPrefix(after.Result, encryptedPrefix) || !strings.HasPrefix(after.Vocabulary[0], encryptedPrefix) {
+				t.Fatalf("底层存储中应保持密文: %+v", after)
+			}
+			got, _ := store.Get(ctx, "job-1")
+			if got.Result != tt.wantResult {
+				t.Fatalf("Result = %q，期望 %q", got.Result, tt.wantResult)
+			}
+		})
+	}
+}
+
+func TestEncryptedStoreUnknownKey(t *testing.T) {
+	ctx := context.Background()
+	inner := NewJobStore(100)
+	if err := NewEncryptedStore(inner, newTestCipher(t, "old-key")).Save(ctx, &models.TranscriptionJob{JobID: "job-1", Result: "secret"}); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	// 更换密钥且没有保留旧密钥：读取失败，更新函数不执行，密文原样保留
+	store := NewEncryptedStore(inner, newTestCipher(t, "new-key"))
+	if _, err := store.Get(ctx, "job-1"); !errors.Is(err, ErrUnknownEncryptionKey) {
+		t.Fatalf("Get err = %v，期望 ErrUnknownEncryptionKey", err)
+	}
+	called := false
+	err := store.Update(ctx, "job-1", func(job *models.TranscriptionJob) { called = true })
+	if !errors.Is(err, ErrUnknownEncryptionKey) || called {
+		t.Fatalf("Update err = %v, called = %v", err, called)
+	}
+}
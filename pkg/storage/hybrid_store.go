@@ -1,12 +1,29 @@
 package storage
 
 import (
-    "log"
+    "context"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "log/slog"
+    "os"
+    "path/filepath"
+    "sort"
     "time"
 
     "github.com/z-wentao/voiceflow/pkg/models"
 )
 
+// hybridSyncTimeout 后台同步每一批任务（以及缓存回写）的超时时间，与请求的 context 无关
+const hybridSyncTimeout = 30 * time.Second
+
+const (
+    hybridSyncBatchSize    = 50              // 每批最多写入的任务数
+    hybridSyncInterval     = 5 * time.Second // 定时写入的间隔，也是首次重试的等待时间
+    hybridSyncMaxBackoff   = 5 * time.Minute // 重试间隔上限
+    defaultSyncMaxAttempts = 5               // 默认的最大写入次数
+)
+
 // HybridJobStore 混合存储：Redis（热数据） + PostgreSQL（冷数据）
 // 面试亮点：双层架构，平衡性能和可靠性
 type HybridJobStore struct {
@@ -14,37 +31,67 @@ type HybridJobStore struct {
     db        Store                                 // PostgreSQL 存储（持久化）
     syncQueue chan *models.TranscriptionJob        // 异步同步队列
     stopCh    chan struct{}                         // 停止信号
+    doneCh    chan struct{}                         // 同步 Worker 已退出
+    listLimit int                                   // List 返回的最大任务数
+
+    syncInterval    time.Duration // 定时写入的间隔，也是首次重试的等待时间
+    syncMaxAttempts int           // 同步失败的任务最多写入的次数
+    deadLetterPath  string        // 多次同步失败的任务写入的死信文件（JSON Lines），为空时只记录日志
+}
+
+// HybridOptions 混合存储可选参数（零值即默认行为）
+type HybridOptions struct {
+    SyncMaxAttempts int    // 同步到数据库失败时最多写入的次数（包括第一次），默认 5
+    DeadLetterPath  string // 多次同步失败的任务写入的死信文件（JSON Lines），为空时只记录日志
 }
 
 // NewHybridJobStore 创建混合存储
-func NewHybridJobStore(redis, db Store) *HybridJobStore {
+func NewHybridJobStore(redis, db Store, opts HybridOptions) *HybridJobStore {
+    return newHybridJobStore(redis, db, opts, hybridSyncInterval)
+}
+
+// newHybridJobStore 创建混合存储，syncInterval 为定时写入的间隔（测试中缩短）
+func newHybridJobStore(redis, db Store, opts HybridOptions, syncInterval time.Duration) *HybridJobStore {
+    if opts.SyncMaxAttempts <= 0 {
+	opts.SyncMaxAttempts = defaultSyncMaxAttempts
+    }
+
     store := &HybridJobStore{
 	redis:     redis,
 	db:        db,
 	syncQueue: make(chan *models.TranscriptionJob, 100),
 	stopCh:    make(chan struct{}),
+	doneCh:    make(chan struct{}),
+	listLimit: defaultRedisListLimit,
+
+	syncInterval:    syncInterval,
+	syncMaxAttempts: opts.SyncMaxAttempts,
+	deadLetterPath:  opts.DeadLetterPath,
     }
 
     // 启动后台同步 Worker
     go store.syncWorker()
 
-    log.Println("✓ 混合存储初始化成功（Redis + PostgreSQL）")
+    slog.Info("✓ 混合存储初始化成功（Redis + PostgreSQL）")
 
     return store
 }
 
 // Save 保存任务
-// 策略：立即写 Redis，异步写数据库
-func (s *HybridJobStore) Save(job *models.TranscriptionJob) error {
+// 策略：立即写 Redis，异步写数据库；版本号以 Redis 为准，版本冲突时直接返回，不同步数据库
+func (s *HybridJobStore) Save(ctx context.Context, job *models.TranscriptionJob) error {
     // 1. 快速写入 Redis（用户立即可查询）
-    if err := s.redis.Save(job); err != nil {
-	log.Printf("⚠️ Redis 写入失败: %v", err)
+    if err := s.redis.Save(ctx, job); err != nil {
+	if errors.Is(err, ErrVersionConflict) {
+	    return err
+	}
+	slog.Warn("⚠️ Redis 写入失败", "job_id", job.JobID, "error", err)
 	// Redis 失败不影响业务，继续写数据库
     }
 
     // 2. 异步写入数据库（仅完成或失败的任务）
-    if job.Status == models.StatusCompleted || job.Status == models.StatusFailed {
-	s.asyncSyncToDB(job)
+    if isTerminal(job.Status) {
+	s.asyncSyncToDB(ctx, job)
     }
 
     return nil
@@ -52,193 +99,683 @@ func (s *HybridJobStore) Save(job *models.TranscriptionJob) error {
 
 // Get 获取任务
 // 策略：优先 Redis，未命中查数据库并回写 Redis
-func (s *HybridJobStore) Get(jobID string) (*models.TranscriptionJob, error) {
+func (s *HybridJobStore) Get(ctx context.Context, jobID string) (*models.TranscriptionJob, error) {
     // 1. 先查 Redis（缓存命中，快速返回）
-    job, err := s.redis.Get(jobID)
+    job, err := s.redis.Get(ctx, jobID)
     if err == nil {
 	return job, nil
     }
 
     // 2. Redis 未命中，查数据库
-    log.Printf("📚 Redis 缓存未命中，查询数据库: %s", jobID)
-    job, err = s.db.Get(jobID)
+    slog.Debug("📚 Redis 缓存未命中，查询数据库", "job_id", jobID)
+    job, err = s.db.Get(ctx, jobID)
     if err != nil {
 	return nil, err
     }
 
     // 3. 回写 Redis（缓存预热，下次查询更快）
-    go func() {
-	if err := s.redis.Save(job); err != nil {
-	    log.Printf("⚠️ 回写 Redis 失败: %v", err)
-	}
-    }()
+    // 回写后 Redis 中的版本号加 1，同步回写以便返回的任务带上新的版本号，调用方随后的 Save 才能通过版本检查
+    if err := s.redis.Save(ctx, job); err != nil {
+	slog.Warn("⚠️ 回写 Redis 失败", "job_id", jobID, "error", err)
+    }
 
     return job, nil
 }
 
 // Update 更新任务
-// 策略：只更新 Redis（快速），完成时同步数据库
-func (s *HybridJobStore) Update(jobID string, updateFn func(*models.TranscriptionJob)) error {
+// 策略：只更新 Redis（快速），进入完成/失败状态时同步数据库
+// 进度更新非常频繁，这里通过包装回调拿到更新后的任务，避免再读一次 Redis；
+// 只有状态从未完成变为完成/失败时才同步数据库，已完成任务的后续修改请使用 Save
+func (s *HybridJobStore) Update(ctx context.Context, jobID string, updateFn func(*models.TranscriptionJob)) error {
+    var before models.JobStatus
+    var updated *models.TranscriptionJob
+    trackingFn := func(job *models.TranscriptionJob) {
+	before = job.Status
+	updateFn(job)
+	updated = job
+    }
+
     // 1. 更新 Redis（快速响应）
-    err := s.redis.Update(jobID, updateFn)
+    err := s.redis.Update(ctx, jobID, trackingFn)
     if err != nil {
-	log.Printf("⚠️ Redis 更新失败: %v, 尝试更新数据库", err)
+	slog.Warn("⚠️ Redis 更新失败，尝试更新数据库", "job_id", jobID, "error", err)
 	// Redis 失败，尝试更新数据库
-	return s.db.Update(jobID, updateFn)
+	return s.db.Update(ctx, jobID, updateFn)
     }
 
-    // 2. 如果任务完成或失败，同步到数据库
-    job, _ := s.redis.Get(jobID)
-    if job != nil && (job.Status == models.StatusCompleted || job.Status == models.StatusFailed) {
-	s.asyncSyncToDB(job)
+    // 2. 如果任务刚进入完成或失败状态，同步到数据库
+    if updated != nil && !isTerminal(before) && isTerminal(updated.Status) {
+	s.asyncSyncToDB(ctx, updated)
     }
 
     return nil
 }
 
-// List 列出任务
-// 策略：优先 Redis，失败降级到数据库
-func (s *HybridJobStore) List() ([]*models.TranscriptionJob, error) {
-    // 优先从 Redis 获取（最近的热数据）
-    jobs, err := s.redis.List()
+// isTerminal 是否为终态（完成或失败）
+func isTerminal(status models.JobStatus) bool {
+    return status == models.StatusCompleted || status == models.StatusFailed
+}
+
+// SetListLimit 设置 List 返回的最大任务数（<= 0 时使用默认值 100）
+func (s *HybridJobStore) SetListLimit(limit int) {
+    if limit <= 0 {
+	limit = defaultRedisListLimit
+    }
+    s.listLimit = limit
+}
+
+// List 列出最近的任务（按创建时间倒序，最多 listLimit 个）
+// 策略：合并 Redis（最新状态）与数据库（Redis 中已过期的历史任务），同一任务以 Redis 为准；
+// 任意一方失败时只使用另一方的结果
+func (s *HybridJobStore) List(ctx context.Context) ([]*models.TranscriptionJob, error) {
+    hot, err := s.redis.List(ctx)
     if err != nil {
 	// Redis 失败，降级到数据库
-	log.Printf("⚠️ Redis 列表查询失败: %v, 降级到数据库", err)
-	return s.db.List()
+	slog.Warn("⚠️ Redis 列表查询失败，降级到数据库", "error", err)
+	return s.db.List(ctx)
     }
 
-    return jobs, nil
+    cold, err := s.db.List(ctx)
+    if err != nil {
+	slog.Warn("⚠️ 数据库列表查询失败，只返回 Redis 中的任务", "error", err)
+	return hot, nil
+    }
+
+    return mergeJobs(hot, cold, s.listLimit), nil
 }
 
-func (s *HybridJobStore) ListAll() ([]*models.TranscriptionJob, error) {
-    jobs, err := s.db.List()
+// mergeJobs 合并 Redis 与数据库的任务列表：按 JobID 去重（保留 Redis 中的版本），
+// 按创建时间倒序排列后取前 limit 个（limit <= 0 表示不限制）
+func mergeJobs(hot, cold []*models.TranscriptionJob, limit int) []*models.TranscriptionJob {
+    seen := make(map[string]bool, len(hot))
+    merged := make([]*models.TranscriptionJob, 0, len(hot)+len(cold))
+    for _, job := range hot {
+	seen[job.JobID] = true
+	merged = append(merged, job)
+    }
+    for _, job := range cold {
+	if !seen[job.JobID] {
+	    merged = append(merged, job)
+	}
+    }
+
+    sort.SliceStable(merged, func(i, j int) bool {
+	return merged[i].CreatedAt.After(merged[j].CreatedAt)
+    })
+
+    if limit > 0 && len(merged) > limit {
+	merged = merged[:limit]
+    }
+    return merged
+}
+
+// ListAll 列出所有任务（历史记录）：直接查询数据库，已结束的任务都已同步到数据库
+func (s *HybridJobStore) ListAll(ctx context.Context) ([]*models.TranscriptionJob, error) {
+    jobs, err := s.db.ListAll(ctx)
     if err != nil {
-	log.Printf("DB 查询失败", err)
+	slog.Error("❌ 数据库查询失败", "error", err)
 	return nil, err
     }
 
     return jobs, nil
 }
 
-// Delete 删除任务
+// ForEach 遍历数据库中的所有任务（与 ListAll 一致；数据库支持时分页读取）
+func (s *HybridJobStore) ForEach(ctx context.Context, fn func(*models.TranscriptionJob) error) error {
+    return ForEachJob(ctx, s.db, fn)
+}
+
+// ListForUser 列出指定用户的任务
+// 策略与 List / ListAll 一致：限制条数（最近的任务）时合并 Redis 与数据库的前 limit 个，Redis 失败时降级到数据库；
+// 不限制（历史记录）时查数据库
+func (s *HybridJobStore) ListForUser(ctx context.Context, userID string, limit int) ([]*models.TranscriptionJob, error) {
+    if limit <= 0 {
+	return s.db.ListForUser(ctx, userID, limit)
+    }
+
+    hot, err := s.redis.ListForUser(ctx, userID, limit)
+    if err != nil {
+	slog.Warn("⚠️ Redis 列表查询失败，降级到数据库", "error", err)
+	return s.db.ListForUser(ctx, userID, limit)
+    }
+
+    cold, err := s.db.ListForUser(ctx, userID, limit)
+    if err != nil {
+	slog.Warn("⚠️ 数据库列表查询失败，只返回 Redis 中的任务", "error", err)
+	return hot, nil
+    }
+
+    return mergeJobs(hot, cold, limit), nil
+}
+
+// ListSummaries 列出任务投影
+// 未结束的任务只保存在 Redis 中（结束时才同步到数据库），只查询未结束状态时读 Redis，否则读数据库
+func (s *HybridJobStore) ListSummaries(ctx context.Context, statuses ...models.JobStatus) ([]models.JobSummary, error) {
+    onlyActive := len(statuses) > 0
+    for _, status := range statuses {
+	if isTerminal(status) {
+	    onlyActive = false
+	}
+    }
+    if onlyActive {
+	return s.redis.ListSummaries(ctx, statuses...)
+    }
+    return s.db.ListSummaries(ctx, statuses...)
+}
+
+// ListByStatus 列出指定状态的任务（与 ListSummaries 相同：只查询未结束状态时读 Redis）
+func (s *HybridJobStore) ListByStatus(ctx context.Context, statuses ...models.JobStatus) ([]*models.TranscriptionJob, error) {
+    onlyActive := len(statuses) > 0
+    for _, status := range statuses {
+	if isTerminal(status) {
+	    onlyActive = false
+	}
+    }
+    if onlyActive {
+	return s.redis.ListByStatus(ctx, statuses...)
+    }
+    return s.db.ListByStatus(ctx, statuses...)
+}
+
+// CountByStatus 按状态统计任务数量
+// 未结束的任务只保存在 Redis 中，排队中/处理中的数量取自 Redis，完成/失败的数量以数据库为准；
+// 数据库不可用时全部使用 Redis 的数量（只包含 TTL 内的任务），Redis 不可用时全部使用数据库的数量
+func (s *HybridJobStore) CountByStatus(ctx context.Context, userID string) (map[models.JobStatus]int, error) {
+    counts, err := s.db.CountByStatus(ctx, userID)
+    if err != nil {
+	slog.Warn("⚠️ 数据库统计失败，使用 Redis 的数量", "error", err)
+	return s.redis.CountByStatus(ctx, userID)
+    }
+
+    active, err := s.redis.CountByStatus(ctx, userID)
+    if err != nil {
+	slog.Warn("⚠️ Redis 统计失败，使用数据库的数量", "error", err)
+	return counts, nil
+    }
+    for _, status := range models.ActiveStatuses {
+	if active[status] > 0 {
+	    counts[status] = active[status]
+	} else {
+	    delete(counts, status)
+	}
+    }
+    return counts, nil
+}
+
+// Stats 汇总任务统计
+// 完成/失败的数量和平均时长以数据库为准，任务总数加上只保存在 Redis 中的未结束任务；
+// 数据库不可用时使用 Redis 的统计（只包含 TTL 内的任务），Redis 不可用时只统计数据库中的任务
+func (s *HybridJobStore) Stats(ctx context.Context, userID string, since time.Time) (models.JobStats, error) {
+    stats, err := s.db.Stats(ctx, userID, since)
+    if err != nil {
+	slog.Warn("⚠️ 数据库统计失败，使用 Redis 的统计", "error", err)
+	return s.redis.Stats(ctx, userID, since)
+    }
+
+    hot, err := s.redis.Stats(ctx, userID, since)
+    if err != nil {
+	slog.Warn("⚠️ Redis 统计失败，只统计数据库中的任务", "error", err)
+	return stats, nil
+    }
+    stats.Total += hot.Total - hot.Completed - hot.Failed
+    return stats, nil
+}
+
+// DeleteOlderThan 删除创建时间早于 t 的已结束任务
+// 已结束的任务都已同步到数据库，以数据库删除的数量为准；Redis 中的缓存同时清理
+func (s *HybridJobStore) DeleteOlderThan(ctx context.Context, t time.Time) (int, error) {
+    if _, err := s.redis.DeleteOlderThan(ctx, t); err != nil {
+	slog.Warn("⚠️ Redis 清理失败", "error", err)
+    }
+    return s.db.DeleteOlderThan(ctx, t)
+}
+
+// FindByHash 查找相同文件内容已完成的任务（已完成的任务都已同步到数据库）
+func (s *HybridJobStore) FindByHash(ctx context.Context, hash string) (*models.TranscriptionJob, error) {
+    return s.db.FindByHash(ctx, hash)
+}
+
+// Search 搜索任务
+// 策略：搜索面向历史记录，直接使用数据库（PostgreSQL 全文索引）
+func (s *HybridJobStore) Search(ctx context.Context, query string) ([]*models.TranscriptionJob, error) {
+    return s.db.Search(ctx, query)
+}
+
+// Delete 软删除任务
+// 策略：同时标记 Redis 和数据库中的数据（以数据库为准，清理器从数据库中查找要永久删除的任务）
+func (s *HybridJobStore) Delete(ctx context.Context, jobID string) error {
+    // 1. 标记 Redis 中的数据
+    redisErr := s.redis.Delete(ctx, jobID)
+    if redisErr != nil {
+	slog.Warn("⚠️ Redis 删除失败", "job_id", jobID, "error", redisErr)
+	// Redis 删除失败不影响整体流程（如任务已不在缓存中）
+    }
+
+    // 2. 标记数据库中的数据；任务还没有同步到数据库时（如转录中的任务）写入 Redis 中已标记的任务
+    err := s.db.Delete(ctx, jobID)
+    if err != nil && redisErr == nil {
+	err = s.syncCachedToDB(ctx, jobID)
+    }
+    if err != nil {
+	slog.Error("❌ 数据库删除失败", "job_id", jobID, "error", err)
+	return err
+    }
+
+    return nil
+}
+
+// Restore 恢复软删除的任务
+// 策略：同 Delete
+func (s *HybridJobStore) Restore(ctx context.Context, jobID string) error {
+    redisErr := s.redis.Restore(ctx, jobID)
+    if redisErr != nil {
+	slog.Warn("⚠️ Redis 恢复失败", "job_id", jobID, "error", redisErr)
+    }
+
+    err := s.db.Restore(ctx, jobID)
+    if err != nil && redisErr == nil {
+	err = s.syncCachedToDB(ctx, jobID)
+    }
+    if err != nil {
+	slog.Error("❌ 数据库恢复失败", "job_id", jobID, "error", err)
+	return err
+    }
+
+    return nil
+}
+
+// syncCachedToDB 把 Redis 中的任务同步写入数据库（不检查版本号，与异步同步相同）
+func (s *HybridJobStore) syncCachedToDB(ctx context.Context, jobID string) error {
+    job, err := s.redis.Get(ctx, jobID)
+    if err != nil {
+	return err
+    }
+    job.Version = 0
+    return s.db.Save(ctx, job)
+}
+
+// ListDeleted 列出软删除的任务
+// 策略：以数据库为准，Redis 中的缓存过期后删除索引不再完整
+func (s *HybridJobStore) ListDeleted(ctx context.Context, before time.Time) ([]*models.TranscriptionJob, error) {
+    return s.db.ListDeleted(ctx, before)
+}
+
+// Purge 永久删除任务
 // 策略：同时删除 Redis 和数据库中的数据
-func (s *HybridJobStore) Delete(jobID string) error {
+func (s *HybridJobStore) Purge(ctx context.Context, jobID string) error {
     // 1. 删除 Redis 中的数据
-    if err := s.redis.Delete(jobID); err != nil {
-	log.Printf("⚠️ Redis 删除失败: %v", err)
-	// Redis 删除失败不影响整体流程
+    if err := s.redis.Purge(ctx, jobID); err != nil {
+	slog.Warn("⚠️ Redis 删除失败", "job_id", jobID, "error", err)
+	// Redis 删除失败不影响整体流程（缓存过期后自动清理）
     }
 
     // 2. 删除数据库中的数据（确保持久化数据被清理）
-    if err := s.db.Delete(jobID); err != nil {
-	log.Printf("⚠️ 数据库删除失败: %v", err)
+    if err := s.db.Purge(ctx, jobID); err != nil {
+	slog.Error("❌ 数据库删除失败", "job_id", jobID, "error", err)
 	return err
     }
 
     return nil
 }
 
-// Close 关闭存储
-func (s *HybridJobStore) Close() error {
-    // 1. 停止同步 Worker
-    close(s.stopCh)
+// IncrUsage 累加月度费用
+// 策略：费用是需要长期保存的计数器，直接写数据库
+func (s *HybridJobStore) IncrUsage(ctx context.Context, month string, costUSD float64) (float64, error) {
+    return s.db.IncrUsage(ctx, month, costUSD)
+}
 
-    // 2. 等待队列清空（最多等待 5 秒）
-    timeout := time.After(5 * time.Second)
-    ticker := time.NewTicker(100 * time.Millisecond)
-    defer ticker.Stop()
+// GetUsage 获取月度费用
+func (s *HybridJobStore) GetUsage(ctx context.Context, month string) (float64, error) {
+    return s.db.GetUsage(ctx, month)
+}
 
-    for {
-	select {
-	case <-timeout:
-	    log.Printf("⚠️ 同步队列清空超时，剩余 %d 个任务", len(s.syncQueue))
-	    goto cleanup
-	case <-ticker.C:
-	    if len(s.syncQueue) == 0 {
-		goto cleanup
-	    }
-	}
+// SetCredential 保存用户凭证
+// 策略：凭证需要长期保存，直接写数据库
+func (s *HybridJobStore) SetCredential(ctx context.Context, userID, name, value string) error {
+    return s.db.SetCredential(ctx, userID, name, value)
+}
+
+// GetCredential 获取用户凭证
+func (s *HybridJobStore) GetCredential(ctx context.Context, userID, name string) (string, error) {
+    return s.db.GetCredential(ctx, userID, name)
+}
+
+// DeleteCredential 删除用户凭证
+func (s *HybridJobStore) DeleteCredential(ctx context.Context, userID, name string) error {
+    return s.db.DeleteCredential(ctx, userID, name)
+}
+
+// SetHashIfAbsent 登记文件内容哈希
+// 策略：哈希登记需要和任务一样长期有效，直接使用数据库的唯一约束
+func (s *HybridJobStore) SetHashIfAbsent(ctx context.Context, hash, jobID string) (string, bool, error) {
+    return s.db.SetHashIfAbsent(ctx, hash, jobID)
+}
+
+// DeleteHash 删除文件内容哈希登记
+func (s *HybridJobStore) DeleteHash(ctx context.Context, hash, jobID string) error {
+    return s.db.DeleteHash(ctx, hash, jobID)
+}
+
+// IndexWords 单词索引保存在数据库中（提取单词的任务均已完成并同步到数据库）
+func (s *HybridJobStore) IndexWords(ctx context.Context, jobID string, words []string) error {
+    return s.db.IndexWords(ctx, jobID, words)
+}
+
+// JobsByWord 查询数据库中的单词索引
+func (s *HybridJobStore) JobsByWord(ctx context.Context, word string) ([]*models.TranscriptionJob, error) {
+    return s.db.JobsByWord(ctx, word)
+}
+
+// ListVocabulary 查询数据库中的单词索引
+func (s *HybridJobStore) ListVocabulary(ctx context.Context) (map[string][]string, error) {
+    return s.db.ListVocabulary(ctx)
+}
+
+// Ping 检查 Redis 和数据库是否都可用
+func (s *HybridJobStore) Ping(ctx context.Context) error {
+    if err := s.redis.Ping(ctx); err != nil {
+	return fmt.Errorf("Redis: %w", err)
     }
+    if err := s.db.Ping(ctx); err != nil {
+	return fmt.Errorf("数据库: %w", err)
+    }
+    return nil
+}
 
-    cleanup:
-    // 3. 关闭存储
+// SaveSegment 保存片段结果（保存在 Redis 中，与未结束的任务一起过期）
+func (s *HybridJobStore) SaveSegment(ctx context.Context, jobID string, index int, data []byte) error {
+    store, err := s.segmentStore()
+    if err != nil {
+	return err
+    }
+    return store.SaveSegment(ctx, jobID, index, data)
+}
+
+// LoadSegments 读取任务已保存的片段结果
+func (s *HybridJobStore) LoadSegments(ctx context.Context, jobID string) (map[int][]byte, error) {
+    store, err := s.segmentStore()
+    if err != nil {
+	return nil, err
+    }
+    return store.LoadSegments(ctx, jobID)
+}
+
+// DeleteSegments 删除任务的片段结果
+func (s *HybridJobStore) DeleteSegments(ctx context.Context, jobID string) error {
+    store, err := s.segmentStore()
+    if err != nil {
+	return err
+    }
+    return store.DeleteSegments(ctx, jobID)
+}
+
+// segmentStore 片段结果的存储：优先 Redis，Redis 不支持时使用数据库
+func (s *HybridJobStore) segmentStore() (SegmentStore, error) {
+    if store, ok := s.redis.(SegmentStore); ok {
+	return store, nil
+    }
+    if store, ok := s.db.(SegmentStore); ok {
+	return store, nil
+    }
+    return nil, fmt.Errorf("存储不支持保存片段结果")
+}
+
+// MoveToArchive 把完成较久的任务移到数据库的归档表
+// Redis 中的缓存不处理：已完成的任务早已同步到数据库，缓存过期后从数据库（包括归档表）读取
+func (s *HybridJobStore) MoveToArchive(ctx context.Context, olderThan time.Time) (int, error) {
+    archiver, ok := s.db.(Archiver)
+    if !ok {
+	return 0, fmt.Errorf("数据库不支持归档")
+    }
+    return archiver.MoveToArchive(ctx, olderThan)
+}
+
+// IndexMaintenance 返回 Redis 索引的后台维护状态
+func (s *HybridJobStore) IndexMaintenance() *IndexMaintenanceStats {
+    return IndexMaintenanceOf(s.redis)
+}
+
+// Close 关闭存储
+// 停止同步 Worker：队列中剩余的任务和等待重试的任务立即写入数据库，仍然失败的写入死信文件
+func (s *HybridJobStore) Close() error {
+    // 1. 停止同步 Worker 并等待剩余任务写入
+    close(s.stopCh)
+    <-s.doneCh
+
+    // 2. 关闭存储
     s.redis.Close()
     s.db.Close()
 
-    log.Println("✓ 混合存储已关闭")
+    slog.Info("✓ 混合存储已关闭")
     return nil
 }
 
-// asyncSyncToDB 异步同步到数据库
-func (s *HybridJobStore) asyncSyncToDB(job *models.TranscriptionJob) {
+// asyncSyncToDB 异步同步到数据库（队列满时使用调用方的 ctx 同步写入）
+// 数据库只是 Redis 的副本，同步使用版本号清零的副本直接覆盖，不做版本检查，也不修改调用方的任务
+func (s *HybridJobStore) asyncSyncToDB(ctx context.Context, job *models.TranscriptionJob) {
+    synced := *job
+    synced.Version = 0
+    job = &synced
+
     select {
     case s.syncQueue <- job:
     // 成功加入队列
     default:
 	// 队列满，同步写入（阻塞）
-	log.Printf("⚠️ 同步队列已满，同步写入数据库")
-	if err := s.db.Save(job); err != nil {
-	    log.Printf("❌ 同步写入数据库失败: %v", err)
+	slog.Warn("⚠️ 同步队列已满，同步写入数据库", "job_id", job.JobID)
+	if err := s.db.Save(ctx, job); err != nil {
+	    slog.Error("❌ 同步写入数据库失败", "job_id", job.JobID, "error", err)
 	}
     }
 }
 
+// syncEntry 待同步到数据库的任务
+type syncEntry struct {
+    job      *models.TranscriptionJob
+    attempts int       // 已失败的次数
+    nextAt   time.Time // 下次重试的时间（等待重试时有效）
+}
+
+// syncBatch 一批待同步的任务，同一任务只保留最后一次写入的版本
+type syncBatch struct {
+    entries []*syncEntry
+    index   map[string]int // JobID → entries 中的位置
+}
+
+func newSyncBatch() *syncBatch {
+    return &syncBatch{index: make(map[string]int)}
+}
+
+// add 加入任务；批次中已有同一任务时替换为新版本（保留位置）
+func (b *syncBatch) add(entry *syncEntry) {
+    if i, ok := b.index[entry.job.JobID]; ok {
+	b.entries[i] = entry
+	return
+    }
+    b.index[entry.job.JobID] = len(b.entries)
+    b.entries = append(b.entries, entry)
+}
+
+// take 取出批次中的所有任务并清空批次
+func (b *syncBatch) take() []*syncEntry {
+    entries := b.entries
+    b.entries = nil
+    clear(b.index)
+    return entries
+}
+
 // syncWorker 后台同步 Worker
-// 策略：批量写入（50条或5秒）
+// 策略：批量写入（50条或5秒），同一任务在一批中只写入最后的版本；
+// 写入失败的任务按指数退避重试，达到 syncMaxAttempts 次后写入死信文件
 func (s *HybridJobStore) syncWorker() {
-    ticker := time.NewTicker(5 * time.Second)
+    defer close(s.doneCh)
+
+    ticker := time.NewTicker(s.syncInterval)
     defer ticker.Stop()
 
-    batch := make([]*models.TranscriptionJob, 0, 50)
+    batch := newSyncBatch()
+    retries := make(map[string]*syncEntry) // 等待重试的任务（按 JobID）
 
     for {
 	select {
-	case job, ok := <-s.syncQueue:
-	    if !ok {
-		// 队列关闭，写入剩余数据
-		s.batchSave(batch)
-		return
-	    }
-
-	    batch = append(batch, job)
+	case job := <-s.syncQueue:
+	    // 新版本取代等待重试的旧版本（重新计数）
+	    delete(retries, job.JobID)
+	    batch.add(&syncEntry{job: job})
 
 	    // 批量写入（达到 50 条）
-	    if len(batch) >= 50 {
-		s.batchSave(batch)
-		batch = batch[:0]
+	    if len(batch.entries) >= hybridSyncBatchSize {
+		s.flush(batch.take(), retries, false)
 	    }
 
-	case <-ticker.C:
-	    // 定时写入（5秒）
-	    if len(batch) > 0 {
-		s.batchSave(batch)
-		batch = batch[:0]
+	case now := <-ticker.C:
+	    // 定时写入（5秒），到期的重试一并写入（重试时间按定时器的粒度取整，差半个周期以内的视为到期）
+	    for jobID, entry := range retries {
+		if entry.nextAt.Sub(now) < s.syncInterval/2 {
+		    delete(retries, jobID)
+		    batch.add(entry)
+		}
+	    }
+	    if len(batch.entries) > 0 {
+		s.flush(batch.take(), retries, false)
 	    }
 
 	case <-s.stopCh:
-	    // 收到停止信号
-	    s.batchSave(batch)
+	    // 收到停止信号：写入队列中剩余的任务和所有等待重试的任务
+	    for drained := false; !drained; {
+		select {
+		case job := <-s.syncQueue:
+		    delete(retries, job.JobID)
+		    batch.add(&syncEntry{job: job})
+		default:
+		    drained = true
+		}
+	    }
+	    for _, entry := range retries {
+		batch.add(entry)
+	    }
+	    s.flush(batch.take(), nil, true)
 	    return
 	}
     }
 }
 
-// batchSave 批量保存到数据库
-func (s *HybridJobStore) batchSave(jobs []*models.TranscriptionJob) {
-    if len(jobs) == 0 {
+// flush 写入一批任务；失败的任务加入 retries 等待重试，final 为 true（关闭时）或重试次数用尽时写入死信文件
+func (s *HybridJobStore) flush(entries []*syncEntry, retries map[string]*syncEntry, final bool) {
+    jobs := make([]*models.TranscriptionJob, len(entries))
+    for i, entry := range entries {
+	jobs[i] = entry.job
+    }
+
+    failed := s.batchSave(jobs)
+    for _, entry := range entries {
+	err, ok := failed[entry.job.JobID]
+	if !ok {
+	    continue
+	}
+
+	entry.attempts++
+	if final || entry.attempts >= s.syncMaxAttempts {
+	    s.deadLetter(entry, err)
+	    continue
+	}
+
+	backoff := s.syncBackoff(entry.attempts)
+	entry.nextAt = time.Now().Add(backoff)
+	retries[entry.job.JobID] = entry
+	slog.Warn("⚠️ 同步任务失败，稍后重试", "job_id", entry.job.JobID, "attempts", entry.attempts, "backoff", backoff, "error", err)
+    }
+}
+
+// syncBackoff 第 attempts 次失败后的重试间隔：从定时写入的间隔（默认 5 秒）起按 2 倍递增，最长 5 分钟
+func (s *HybridJobStore) syncBackoff(attempts int) time.Duration {
+    backoff := s.syncInterval
+    for i := 1; i < attempts && backoff < hybridSyncMaxBackoff; i++ {
+	backoff *= 2
+    }
+    return min(backoff, hybridSyncMaxBackoff)
+}
+
+// deadLetterRecord 死信文件中的一行（JSON Lines），包含完整任务，可用于手动补写数据库
+type deadLetterRecord struct {
+    JobID    string                   `json:"job_id"`
+    Attempts int                      `json:"attempts"`
+    Error    string                   `json:"error"`
+    FailedAt time.Time                `json:"failed_at"`
+    Job      *models.TranscriptionJob `json:"job"`
+}
+
+// deadLetter 记录最终同步失败的任务（未配置死信文件或写入失败时只记录日志）
+func (s *HybridJobStore) deadLetter(entry *syncEntry, syncErr error) {
+    slog.Error("❌ 同步任务多次失败，放弃重试", "job_id", entry.job.JobID, "attempts", entry.attempts, "error", syncErr, "dead_letter", s.deadLetterPath)
+    if s.deadLetterPath == "" {
 	return
     }
 
-    log.Printf("🔄 批量同步 %d 个任务到数据库", len(jobs))
+    line, err := json.Marshal(deadLetterRecord{
+	JobID:    entry.job.JobID,
+	Attempts: entry.attempts,
+	Error:    syncErr.Error(),
+	FailedAt: time.Now(),
+	Job:      entry.job,
+    })
+    if err == nil {
+	err = appendLine(s.deadLetterPath, line)
+    }
+    if err != nil {
+	slog.Error("❌ 写入死信文件失败", "job_id", entry.job.JobID, "path", s.deadLetterPath, "error", err)
+    }
+}
 
-    successCount := 0
-    for _, job := range jobs {
-	if err := s.db.Save(job); err != nil {
-	    log.Printf("❌ 同步任务失败: %s, 错误: %v", job.JobID, err)
-	} else {
-	    successCount++
+// appendLine 向文件追加一行（目录不存在时自动创建）
+func appendLine(path string, line []byte) error {
+    if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+	return err
+    }
+    f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+    if err != nil {
+	return err
+    }
+    if _, err := f.Write(append(line, '\n')); err != nil {
+	f.Close()
+	return err
+    }
+    return f.Close()
+}
+
+// batchSave 批量保存到数据库，返回保存失败的任务及原因
+// 每批使用独立的超时 context，数据库卡住时不会永久阻塞同步 Worker
+func (s *HybridJobStore) batchSave(jobs []*models.TranscriptionJob) map[string]error {
+    failed := make(map[string]error)
+    if len(jobs) == 0 {
+	return failed
+    }
+
+    ctx, cancel := context.WithTimeout(context.Background(), hybridSyncTimeout)
+    defer cancel()
+
+    slog.Info("🔄 批量同步任务到数据库", "jobs", len(jobs))
+
+    // 数据库支持批量写入时一次往返写入整批，否则逐个保存
+    if saver, ok := s.db.(BatchSaver); ok {
+	if err := saver.BatchSave(ctx, jobs); err != nil {
+	    var batchErr *BatchSaveError
+	    if errors.As(err, &batchErr) {
+		failed = batchErr.Failed
+	    } else {
+		for _, job := range jobs {
+		    failed[job.JobID] = err
+		}
+	    }
+	}
+    } else {
+	for _, job := range jobs {
+	    if err := s.db.Save(ctx, job); err != nil {
+		failed[job.JobID] = err
+	    }
 	}
     }
 
-    log.Printf("✓ 成功同步 %d/%d 个任务到数据库", successCount, len(jobs))
+    slog.Info("✓ 同步任务到数据库完成", "succeeded", len(jobs)-len(failed), "jobs", len(jobs))
+    return failed
 }
@@ -0,0 +1,519 @@
+package storage
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/z-wentao/voiceflow/pkg/models"
+)
+
+var errFlakyDB = errors.New("数据库暂时不可用")
+
+// flakyStore 模拟不稳定的数据库：每个任务的前 failures 次写入失败，记录每个任务的写入次数
+type flakyStore struct {
+	*JobStore
+	failures int
+
+	mu       sync.Mutex
+	attempts map[string]int
+	saves    []string // 成功写入的任务（按写入顺序，用于检查合并）
+}
+
+func newFlakyStore(failures int) *flakyStore {
+	return &flakyStore{JobStore: NewJobStore(0), failures: failures, attempts: make(map[string]int)}
+}
+
+func (s *flakyStore) Save(ctx context.Context, job *models.TranscriptionJob) error {
+	// 持有锁直到写入完成，snapshot 看到的写入次数与存储中的数据一致
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.attempts[job.JobID]++
+	if s.attempts[job.JobID] <= s.failures {
+		return errFlakyDB
+	}
+	if err := s.JobStore.Save(ctx, job); err != nil {
+		return err
+	}
+	s.saves = append(s.saves, job.JobID)
+	return nil
+}
+
+func (s *flakyStore) snapshot() (attempts map[string]int, saves []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	attempts = make(map[string]int, len(s.attempts))
+	for id, n := range s.attempts {
+		attempts[id] = n
+	}
+	return attempts, append([]string(nil), s.saves...)
+}
+
+// flakyBatchStore 同时支持批量写入的不稳定数据库（部分失败时返回 *BatchSaveError）
+type flakyBatchStore struct {
+	*flakyStore
+}
+
+func (s flakyBatchStore) BatchSave(ctx context.Context, jobs []*models.TranscriptionJob) error {
+	failed := make(map[string]error)
+	for _, job := range jobs {
+		if err := s.Save(ctx, job); err != nil {
+			failed[job.JobID] = err
+		}
+	}
+	if len(failed) > 0 {
+		return &BatchSaveError{Failed: failed}
+	}
+	return nil
+}
+
+// waitFor 轮询直到 cond 成立，超时则测试失败
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(3 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("等待%s超时", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func readDeadLetters(t *testing.T, path string) []deadLetterRecord {
+	t.Helper()
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		t.Fatalf("打开死信文件: %v", err)
+	}
+	defer f.Close()
+
+	var records []deadLetterRecord
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var record deadLetterRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			t.Fatalf("解析死信记录: %v", err)
+		}
+		records = append(records, record)
+	}
+	return records
+}
+
+func TestSyncBatchCoalesce(t *testing.T) {
+	batch := newSyncBatch()
+	for i, id := range []string{"job-1", "job-2", "job-1", "job-3", "job-1"} {
+		batch.add(&syncEntry{job: &models.TranscriptionJob{JobID: id, Progress: i}})
+	}
+
+	entries := batch.take()
+	var got []string
+	for _, entry := range entries {
+		got = append(got, fmt.Sprintf("%s:%d", entry.job.JobID, entry.job.Progress))
+	}
+	if fmt.Sprint(got) != "[job-1:4 job-2:1 job-3:3]" {
+		t.Fatalf("批次 = %v，期望同一任务只保留最后的版本并保持首次加入的位置", got)
+	}
+	if len(batch.take()) != 0 {
+		t.Fatal("take 之后批次应为空")
+	}
+}
+
+func TestSyncBackoff(t *testing.T) {
+	s := &HybridJobStore{syncInterval: hybridSyncInterval}
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{1, 5 * time.Second},
+		{2, 10 * time.Second},
+		{3, 20 * time.Second},
+		{6, 160 * time.Second},
+		{7, hybridSyncMaxBackoff},
+		{50, hybridSyncMaxBackoff},
+	}
+	for _, tt := range tests {
+		if got := s.syncBackoff(tt.attempts); got != tt.want {
+			t.Errorf("第 %d 次失败后等待 %s，期望 %s", tt.attempts, got, tt.want)
+		}
+	}
+}
+
+// TestHybridSyncRetries 写入数据库失败的任务按退避重试直到写入成功，重试次数用尽时写入死信文件
+func TestHybridSyncRetries(t *testing.T) {
+	tests := []struct {
+		name          string
+		failures      int
+		maxAttempts   int
+		batch         bool
+		wantAttempts  int
+		wantPersisted bool
+	}{
+		{"第一次写入成功", 0, 3, false, 1, true},
+		{"重试后写入成功", 2, 3, false, 3, true},
+		{"批量写入重试后成功", 2, 3, true, 3, true},
+		{"重试次数用尽", 5, 3, false, 3, false},
+		{"批量写入重试次数用尽", 5, 3, true, 3, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			flaky := newFlakyStore(tt.failures)
+			var db Store = flaky
+			if tt.batch {
+				db = flakyBatchStore{flaky}
+			}
+			deadLetters := filepath.Join(t.TempDir(), "dead.jsonl")
+			store := newHybridJobStore(NewJobStore(0), db, HybridOptions{SyncMaxAttempts: tt.maxAttempts, DeadLetterPath: deadLetters}, 10*time.Millisecond)
+			defer store.Close()
+
+			if err := store.Save(ctx, &models.TranscriptionJob{JobID: "job-1", Status: models.StatusCompleted, Result: "hello"}); err != nil {
+				t.Fatalf("Save: %v", err)
+			}
+			waitFor(t, "同步结束", func() bool {
+				attempts, _ := flaky.snapshot()
+				return attempts["job-1"] == tt.wantAttempts && (tt.wantPersisted || len(readDeadLetters(t, deadLetters)) == 1)
+			})
+
+			_, err := flaky.JobStore.Get(ctx, "job-1")
+			if persisted := err == nil; persisted != tt.wantPersisted {
+				t.Fatalf("数据库中有任务 = %v，期望 %v", persisted, tt.wantPersisted)
+			}
+			records := readDeadLetters(t, deadLetters)
+			if tt.wantPersisted {
+				if len(records) != 0 {
+					t.Fatalf("写入成功的任务不应进入死信文件: %+v", records)
+				}
+				return
+			}
+			if records[0].JobID != "job-1" || records[0].Attempts != tt.maxAttempts || records[0].Job.Result != "hello" {
+				t.Fatalf("死信记录 = %+v", records[0])
+			}
+		})
+	}
+}
+
+// TestHybridSyncCoalesces 同一批次内多次保存同一任务只写入最后的版本
+func TestHybridSyncCoalesces(t *testing.T) {
+	ctx := context.Background()
+	flaky := newFlakyStore(0)
+	store := newHybridJobStore(NewJobStore(0), flaky, HybridOptions{}, time.Hour)
+
+	for i := 1; i <= 3; i++ {
+		job := &models.TranscriptionJob{JobID: "job-1", Status: models.StatusCompleted, Result: fmt.Sprintf("v%d", i)}
+		if err := store.Save(ctx, job); err != nil {
+			t.Fatalf("Save: %v", err)
+		}
+	}
+	store.Close()
+
+	_, saves := flaky.snapshot()
+	if len(saves) != 1 {
+		t.Fatalf("写入数据库 %d 次，期望合并为 1 次", len(saves))
+	}
+	job, err := flaky.JobStore.Get(ctx, "job-1")
+	if err != nil || job.Result != "v3" {
+		t.Fatalf("数据库中的任务 = %+v, %v，期望最后的版本 v3", job, err)
+	}
+}
+
+// TestHybridCloseFlushesRetries 关闭时等待重试的任务也会写入数据库
+func TestHybridCloseFlushesRetries(t *testing.T) {
+	ctx := context.Background()
+	flaky := newFlakyStore(1)
+	// 定时器不会触发：第一批在达到批量大小时写入，失败的任务等待重试
+	store := newHybridJobStore(NewJobStore(0), flaky, HybridOptions{}, time.Hour)
+
+	for i := 0; i < hybridSyncBatchSize; i++ {
+		job := &models.TranscriptionJob{JobID: fmt.Sprintf("job-%d", i), Status: models.StatusCompleted}
+		if err := store.Save(ctx, job); err != nil {
+			t.Fatalf("Save: %v", err)
+		}
+	}
+	waitFor(t, "第一批写入", func() bool {
+		attempts, _ := flaky.snapshot()
+		return len(attempts) == hybridSyncBatchSize
+	})
+	store.Close()
+
+	attempts, saves := flaky.snapshot()
+	if len(saves) != hybridSyncBatchSize {
+		t.Fatalf("关闭后写入了 %d 个任务，期望 %d", len(saves), hybridSyncBatchSize)
+	}
+	for id, n := range attempts {
+		if n != 2 {
+			t.Fatalf("任务 %s 写入 %d 次，期望 2 次（失败一次，关闭时重试）", id, n)
+		}
+	}
+}
+
+// TestHybridUpdateSyncsOnTransition Update 只在任务进入完成/失败状态时同步数据库，进度更新和已完成任务的修改不同步
+func TestHybridUpdateSyncsOnTransition(t *testing.T) {
+	tests := []struct {
+		name     string
+		from     models.JobStatus
+		update   func(*models.TranscriptionJob)
+		wantSync bool
+	}{
+		{"进度更新", models.StatusProcessing, func(j *models.TranscriptionJob) { j.Progress = 50 }, false},
+		{"进入完成状态", models.StatusProcessing, func(j *models.TranscriptionJob) { j.Status = models.StatusCompleted }, true},
+		{"进入失败状态", models.StatusPending, func(j *models.TranscriptionJob) { j.Status = models.StatusFailed }, true},
+		{"修改已完成的任务", models.StatusCompleted, func(j *models.TranscriptionJob) { j.Vocabulary = []string{"apple"} }, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			redis, _ := newTestRedisStore(t)
+			db := newFlakyStore(0)
+			store := newHybridJobStore(redis, db, HybridOptions{}, time.Hour)
+
+			if err := redis.Save(ctx, &models.TranscriptionJob{JobID: "job-1", Status: tt.from}); err != nil {
+				t.Fatalf("Save: %v", err)
+			}
+			if err := store.Update(ctx, "job-1", tt.update); err != nil {
+				t.Fatalf("Update: %v", err)
+			}
+			store.Close()
+
+			attempts, _ := db.snapshot()
+			if synced := attempts["job-1"] > 0; synced != tt.wantSync {
+				t.Fatalf("同步数据库 = %v，期望 %v", synced, tt.wantSync)
+			}
+		})
+	}
+}
+
+// BenchmarkHybridUpdate 进度更新的吞吐量：Update 通过回调拿到更新后的任务，
+// 与之前每次更新后再 Get 一次判断是否需要同步相比，省掉一次 Redis 读取（cmds/op 为每次更新的 Redis 命令数）
+func BenchmarkHybridUpdate(b *testing.B) {
+	benchmarks := []struct {
+		name     string
+		readBack bool
+	}{
+		{"update", false},
+		{"update+get", true},
+	}
+	for _, bm := range benchmarks {
+		b.Run(bm.name, func(b *testing.B) {
+			ctx := context.Background()
+			redis, mr := newTestRedisStore(b)
+			store := newHybridJobStore(redis, NewJobStore(0), HybridOptions{}, time.Hour)
+			defer store.Close()
+			job := &models.TranscriptionJob{JobID: "job-1", Status: models.StatusProcessing, Result: strings.Repeat("hello world ", 10000)}
+			if err := redis.Save(ctx, job); err != nil {
+				b.Fatalf("Save: %v", err)
+			}
+
+			commands := mr.CommandCount()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if err := store.Update(ctx, "job-1", func(j *models.TranscriptionJob) { j.Progress = i % 100 }); err != nil {
+					b.Fatalf("Update: %v", err)
+				}
+				if bm.readBack {
+					if _, err := store.Get(ctx, "job-1"); err != nil {
+						b.Fatalf("Get: %v", err)
+					}
+				}
+			}
+			b.StopTimer()
+			b.ReportMetric(float64(mr.CommandCount()-commands)/float64(b.N), "cmds/op")
+		})
+	}
+}
+
+// rejectingStore 拒绝写入 ID 以 bad 开头的任务
+type rejectingStore struct {
+	*JobStore
+}
+
+func (s rejectingStore) Save(ctx context.Context, job *models.TranscriptionJob) error {
+	if strings.HasPrefix(job.JobID, "bad") {
+		return errFlakyDB
+	}
+	return s.JobStore.Save(ctx, job)
+}
+
+// rejectingBatchStore 支持批量写入的 rejectingStore，wholeBatch 为 true 时整批失败（不返回 *BatchSaveError）
+type rejectingBatchStore struct {
+	rejectingStore
+	wholeBatch bool
+}
+
+func (s rejectingBatchStore) BatchSave(ctx context.Context, jobs []*models.TranscriptionJob) error {
+	if s.wholeBatch {
+		return errFlakyDB
+	}
+	failed := make(map[string]error)
+	for _, job := range jobs {
+		if err := s.Save(ctx, job); err != nil {
+			failed[job.JobID] = err
+		}
+	}
+	if len(failed) > 0 {
+		return &BatchSaveError{Failed: failed}
+	}
+	return nil
+}
+
+// TestHybridBatchSave 批量写入的部分失败：只有失败的任务进入重试，其他任务照常写入；
+// 数据库不支持批量写入时逐个保存
+func TestHybridBatchSave(t *testing.T) {
+	tests := []struct {
+		name       string
+		db         func() Store
+		wantFailed string
+		wantSaved  string
+	}{
+		{"逐个保存", func() Store { return rejectingStore{NewJobStore(0)} }, "[bad-2]", "[job-1 job-3]"},
+		{"批量写入部分失败", func() Store { return rejectingBatchStore{rejectingStore{NewJobStore(0)}, false} }, "[bad-2]", "[job-1 job-3]"},
+		{"批量写入整批失败", func() Store { return rejectingBatchStore{rejectingStore{NewJobStore(0)}, true} }, "[bad-2 job-1 job-3]", "[]"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			db := tt.db()
+			store := &HybridJobStore{db: db}
+			var jobs []*models.TranscriptionJob
+			for _, id := range []string{"job-1", "bad-2", "job-3"} {
+				jobs = append(jobs, &models.TranscriptionJob{JobID: id, Status: models.StatusCompleted})
+			}
+
+			var failed []string
+			for id := range store.batchSave(jobs) {
+				failed = append(failed, id)
+			}
+			sort.Strings(failed)
+			if fmt.Sprint(failed) != tt.wantFailed {
+				t.Fatalf("失败的任务 %v，期望 %s", failed, tt.wantFailed)
+			}
+
+			var saved []string
+			for _, job := range jobs {
+				if _, err := db.Get(ctx, job.JobID); err == nil {
+					saved = append(saved, job.JobID)
+				}
+			}
+			if fmt.Sprint(saved) != tt.wantSaved {
+				t.Fatalf("写入的任务 %v，期望 %s", saved, tt.wantSaved)
+			}
+		})
+	}
+}
+
+// failingListStore List 总是失败的数据库
+type failingListStore struct {
+	Store
+}
+
+func (s failingListStore) List(ctx context.Context) ([]*models.TranscriptionJob, error) {
+	return nil, errFlakyDB
+}
+
+// TestHybridList List 合并 Redis 与数据库：同一任务以 Redis 为准，按创建时间倒序截取 listLimit 个，任意一方失败时使用另一方
+func TestHybridList(t *testing.T) {
+	base := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	job := func(id string, minute int, status models.JobStatus) *models.TranscriptionJob {
+		return &models.TranscriptionJob{JobID: id, Status: status, CreatedAt: base.Add(time.Duration(minute) * time.Minute)}
+	}
+
+	tests := []struct {
+		name      string
+		hot       []*models.TranscriptionJob // 只写入 Redis
+		cold      []*models.TranscriptionJob // 只写入数据库
+		limit     int
+		redisDown bool
+		dbDown    bool
+		want      string // JobID:状态，按返回顺序
+	}{
+		{
+			name: "只在 Redis",
+			hot:  []*models.TranscriptionJob{job("a", 1, models.StatusPending), job("b", 2, models.StatusProcessing)},
+			want: "[b:processing a:pending]",
+		},
+		{
+			name: "只在数据库（Redis 中已过期）",
+			cold: []*models.TranscriptionJob{job("a", 1, models.StatusCompleted), job("b", 2, models.StatusFailed)},
+			want: "[b:failed a:completed]",
+		},
+		{
+			name: "两边都有时以 Redis 为准",
+			hot:  []*models.TranscriptionJob{job("a", 1, models.StatusCompleted), job("c", 3, models.StatusProcessing)},
+			cold: []*models.TranscriptionJob{job("a", 1, models.StatusProcessing), job("b", 2, models.StatusCompleted)},
+			want: "[c:processing b:completed a:completed]",
+		},
+		{
+			name:  "合并后按创建时间截取前 limit 个",
+			hot:   []*models.TranscriptionJob{job("a", 1, models.StatusPending), job("d", 4, models.StatusPending)},
+			cold:  []*models.TranscriptionJob{job("b", 2, models.StatusCompleted), job("c", 3, models.StatusCompleted), job("e", 5, models.StatusCompleted)},
+			limit: 3,
+			want:  "[e:completed d:pending c:completed]",
+		},
+		{
+			name:      "Redis 不可用时降级到数据库",
+			hot:       []*models.TranscriptionJob{job("a", 1, models.StatusProcessing)},
+			cold:      []*models.TranscriptionJob{job("b", 2, models.StatusCompleted)},
+			redisDown: true,
+			want:      "[b:completed]",
+		},
+		{
+			name:   "数据库不可用时只返回 Redis",
+			hot:    []*models.TranscriptionJob{job("a", 1, models.StatusProcessing)},
+			cold:   []*models.TranscriptionJob{job("b", 2, models.StatusCompleted)},
+			dbDown: true,
+			want:   "[a:processing]",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			redis, mr := newTestRedisStore(t)
+			var db Store = NewJobStore(0)
+			for _, j := range tt.hot {
+				if err := redis.Save(ctx, j); err != nil {
+					t.Fatalf("Redis Save: %v", err)
+				}
+			}
+			for _, j := range tt.cold {
+				if err := db.Save(ctx, j); err != nil {
+					t.Fatalf("数据库 Save: %v", err)
+				}
+			}
+			if tt.redisDown {
+				mr.Close()
+			}
+			if tt.dbDown {
+				db = failingListStore{db}
+			}
+
+			store := newHybridJobStore(redis, db, HybridOptions{}, time.Hour)
+			defer store.Close()
+			if tt.limit > 0 {
+				store.SetListLimit(tt.limit)
+			}
+
+			jobs, err := store.List(ctx)
+			if err != nil {
+				t.Fatalf("List: %v", err)
+			}
+			var got []string
+			for _, j := range jobs {
+				got = append(got, j.JobID+":"+string(j.Status))
+			}
+			if fmt.Sprint(got) != tt.want {
+				t.Fatalf("List = %v，期望 %s", got, tt.want)
+			}
+		})
+	}
+}
@@ -1,37 +1,109 @@
 package storage
 
 import (
+    "container/list"
+    "context"
     "fmt"
+    "slices"
+    "sort"
     "sync"
+    "time"
 
     "github.com/z-wentao/voiceflow/pkg/models"
 )
 
 // JobStore 任务存储（内存实现）
 // 面试亮点：使用 RWMutex 保证并发安全
+// 保存和读取的都是任务的副本（与 Redis / 数据库一致），调用方修改返回的任务不会影响存储，Save 的版本号检查才有意义
+// 设置了任务数上限时按最近访问顺序（LRU）淘汰已结束的任务，等待处理和处理中的任务不会被淘汰
 type JobStore struct {
-    jobs map[string]*models.TranscriptionJob
-    mu   sync.RWMutex // 读写锁
+    jobs     map[string]*models.TranscriptionJob
+    usage    map[string]float64         // 按月累计的 OpenAI 费用
+    secrets  map[string]string          // 用户凭证（用户 ID + 凭证名 → 密文）
+    hashes   map[string]string          // 文件内容哈希 → 任务 ID
+    words    map[string]map[string]bool // 单词 → 任务 ID 集合（倒排索引）
+    jobWords map[string][]string        // 任务 ID → 已索引的单词（用于替换和删除）
+    segments map[string]map[int][]byte  // 任务 ID → 已完成片段的结果
+    mu       sync.RWMutex               // 读写锁
+
+    // LRU：recent 按最近访问排列任务 ID（队首最新），Get 只持有读锁，因此由 lruMu 单独保护（加锁顺序 mu → lruMu）
+    maxJobs  int                      // 任务数上限，0 表示不限制
+    recent   *list.List
+    elements map[string]*list.Element // 任务 ID → recent 中的节点
+    lruMu    sync.Mutex
+    onEvict  func(job *models.TranscriptionJob)
 }
 
 // NewJobStore 创建任务存储
-func NewJobStore() *JobStore {
+// maxJobs > 0 时最多保留 maxJobs 个任务，超过时淘汰最久未访问的已完成 / 失败任务；0 表示不限制
+func NewJobStore(maxJobs int) *JobStore {
     return &JobStore{
-	jobs: make(map[string]*models.TranscriptionJob),
+	jobs:     make(map[string]*models.TranscriptionJob),
+	usage:    make(map[string]float64),
+	secrets:  make(map[string]string),
+	hashes:   make(map[string]string),
+	words:    make(map[string]map[string]bool),
+	jobWords: make(map[string][]string),
+	segments: make(map[string]map[int][]byte),
+	maxJobs:  max(maxJobs, 0),
+	recent:   list.New(),
+	elements: make(map[string]*list.Element),
     }
 }
 
-// Save 保存任务
-func (js *JobStore) Save(job *models.TranscriptionJob) error {
+// SetEvictHandler 设置任务被淘汰后的回调（如删除任务的媒体和字幕文件），在 Save 返回前、不持有锁时调用
+func (js *JobStore) SetEvictHandler(fn func(job *models.TranscriptionJob)) {
+    js.onEvict = fn
+}
+
+// cloneJob 任务的副本（切片字段一并复制）
+func cloneJob(job *models.TranscriptionJob) *models.TranscriptionJob {
+    clone := *job
+    clone.Vocabulary = slices.Clone(job.Vocabulary)
+    clone.VocabDetail = slices.Clone(job.VocabDetail)
+    clone.SyncHistory = slices.Clone(job.SyncHistory)
+    return &clone
+}
+
+// cloneJobs 任务列表的副本
+func cloneJobs(jobs []*models.TranscriptionJob) []*models.TranscriptionJob {
+    clones := make([]*models.TranscriptionJob, 0, len(jobs))
+    for _, job := range jobs {
+	clones = append(clones, cloneJob(job))
+    }
+    return clones
+}
+
+// Save 保存任务的副本（job.Version > 0 时检查版本号），保存后的版本号写回 job.Version
+// 新任务使任务数超过上限时淘汰最久未访问的已结束任务
+func (js *JobStore) Save(ctx context.Context, job *models.TranscriptionJob) error {
     js.mu.Lock()
-    defer js.mu.Unlock()
 
-    js.jobs[job.JobID] = job
+    version := job.Version
+    if existing, exists := js.jobs[job.JobID]; exists {
+	if job.Version > 0 && existing.Version != job.Version {
+	    js.mu.Unlock()
+	    return versionConflict(job.JobID)
+	}
+	version = max(version, existing.Version)
+    }
+
+    job.Version = version + 1
+    js.jobs[job.JobID] = cloneJob(job)
+    js.touch(job.JobID)
+    evicted := js.evict(job.JobID)
+    js.mu.Unlock()
+
+    if js.onEvict != nil {
+	for _, job := range evicted {
+	    js.onEvict(job)
+	}
+    }
     return nil
 }
 
-// Get 获取任务
-func (js *JobStore) Get(jobID string) (*models.TranscriptionJob, error) {
+// Get 获取任务的副本
+func (js *JobStore) Get(ctx context.Context, jobID string) (*models.TranscriptionJob, error) {
     js.mu.RLock()
     defer js.mu.RUnlock()
 
@@ -40,11 +112,12 @@ func (js *JobStore) Get(jobID string) (*models.TranscriptionJob, error) {
 	return nil, fmt.Errorf("任务不存在: %s", jobID)
     }
 
-    return job, nil
+    js.touch(jobID)
+    return cloneJob(job), nil
 }
 
 // Update 更新任务状态
-func (js *JobStore) Update(jobID string, updateFn func(*models.TranscriptionJob)) error {
+func (js *JobStore) Update(ctx context.Context, jobID string, updateFn func(*models.TranscriptionJob)) error {
     js.mu.Lock()
     defer js.mu.Unlock()
 
@@ -54,37 +127,249 @@ func (js *JobStore) Update(jobID string, updateFn func(*models.TranscriptionJob)
     }
 
     updateFn(job)
+    job.Version++
+    js.touch(jobID)
     return nil
 }
 
+// touch 把任务移到最近访问的位置（不限制任务数时不记录）
+// 调用方需持有 mu（读锁即可）
+func (js *JobStore) touch(jobID string) {
+    if js.maxJobs == 0 {
+	return
+    }
+
+    js.lruMu.Lock()
+    defer js.lruMu.Unlock()
+    if elem, ok := js.elements[jobID]; ok {
+	js.recent.MoveToFront(elem)
+	return
+    }
+    js.elements[jobID] = js.recent.PushFront(jobID)
+}
+
+// evict 任务数超过上限时从最久未访问的一端淘汰已完成 / 失败的任务（keep 为刚保存的任务，不淘汰），返回被淘汰的任务
+// 等待处理和处理中的任务跳过（全部是进行中的任务时允许暂时超过上限）。调用方需持有写锁
+func (js *JobStore) evict(keep string) []*models.TranscriptionJob {
+    if js.maxJobs == 0 || len(js.jobs) <= js.maxJobs {
+	return nil
+    }
+
+    js.lruMu.Lock()
+    var candidates []string
+    for elem := js.recent.Back(); elem != nil && len(js.jobs)-len(candidates) > js.maxJobs; elem = elem.Prev() {
+	jobID := elem.Value.(string)
+	if job, ok := js.jobs[jobID]; ok && jobID != keep && (job.Status == models.StatusCompleted || job.Status == models.StatusFailed) {
+	    candidates = append(candidates, jobID)
+	}
+    }
+    js.lruMu.Unlock()
+
+    evicted := make([]*models.TranscriptionJob, 0, len(candidates))
+    for _, jobID := range candidates {
+	job := js.jobs[jobID]
+	if js.hashes[job.ContentHash] == jobID {
+	    delete(js.hashes, job.ContentHash)
+	}
+	js.remove(jobID)
+	evicted = append(evicted, job)
+    }
+    return evicted
+}
+
+// remove 删除任务及其片段结果、单词索引和访问记录（调用方需持有写锁）
+func (js *JobStore) remove(jobID string) {
+    delete(js.jobs, jobID)
+    delete(js.segments, jobID)
+    js.unindexWords(jobID)
+
+    js.lruMu.Lock()
+    if elem, ok := js.elements[jobID]; ok {
+	js.recent.Remove(elem)
+	delete(js.elements, jobID)
+    }
+    js.lruMu.Unlock()
+}
+
 // List 列出所有任务
-func (js *JobStore) List() ([]*models.TranscriptionJob, error) {
+func (js *JobStore) List(ctx context.Context) ([]*models.TranscriptionJob, error) {
+    js.mu.RLock()
+    defer js.mu.RUnlock()
+
+    return cloneJobs(js.visibleJobs()), nil
+}
+
+func (js *JobStore) ListAll(ctx context.Context) ([]*models.TranscriptionJob, error) {
     js.mu.RLock()
     defer js.mu.RUnlock()
 
+    return cloneJobs(js.visibleJobs()), nil
+}
+
+// visibleJobs 未被软删除的任务（内部保存的指针，调用方需持有读锁，返回给调用方前需复制）
+func (js *JobStore) visibleJobs() []*models.TranscriptionJob {
     jobs := make([]*models.TranscriptionJob, 0, len(js.jobs))
     for _, job := range js.jobs {
-	jobs = append(jobs, job)
+	if job.DeletedAt.IsZero() {
+	    jobs = append(jobs, job)
+	}
+    }
+    return jobs
+}
+
+// ListForUser 列出指定用户的任务（按创建时间倒序）
+func (js *JobStore) ListForUser(ctx context.Context, userID string, limit int) ([]*models.TranscriptionJob, error) {
+    js.mu.RLock()
+    defer js.mu.RUnlock()
+
+    jobs := make([]*models.TranscriptionJob, 0)
+    for _, job := range js.visibleJobs() {
+	if job.UserID == userID {
+	    jobs = append(jobs, job)
+	}
     }
 
-    return jobs, nil
+    sort.Slice(jobs, func(i, j int) bool {
+	return jobs[i].CreatedAt.After(jobs[j].CreatedAt)
+    })
+    return cloneJobs(limitJobs(jobs, limit)), nil
 }
 
-func (js *JobStore) ListAll() ([]*models.TranscriptionJob, error) {
+// ListSummaries 列出指定状态任务的轻量投影
+func (js *JobStore) ListSummaries(ctx context.Context, statuses ...models.JobStatus) ([]models.JobSummary, error) {
     js.mu.RLock()
     defer js.mu.RUnlock()
 
-    jobs := make([]*models.TranscriptionJob, 0, len(js.jobs))
-    for _, job := range js.jobs {
-	jobs = append(jobs, job)
+    filter := statusFilter(statuses)
+    summaries := make([]models.JobSummary, 0)
+    for _, job := range js.visibleJobs() {
+	if filter == nil || filter[job.Status] {
+	    summaries = append(summaries, toSummary(job))
+	}
     }
 
-    return jobs, nil
+    return summaries, nil
 }
 
+// CountByStatus 按状态统计任务数量
+func (js *JobStore) CountByStatus(ctx context.Context, userID string) (map[models.JobStatus]int, error) {
+    js.mu.RLock()
+    defer js.mu.RUnlock()
+
+    counts := make(map[models.JobStatus]int)
+    for _, job := range js.visibleJobs() {
+	if userID == "" || job.UserID == userID {
+	    counts[job.Status]++
+	}
+    }
+    return counts, nil
+}
+
+// Stats 汇总任务统计（遍历所有任务）
+func (js *JobStore) Stats(ctx context.Context, userID string, since time.Time) (models.JobStats, error) {
+    js.mu.RLock()
+    defer js.mu.RUnlock()
+
+    return statsOf(js.visibleJobs(), userID, since), nil
+}
+
+// DeleteOlderThan 删除创建时间早于 t 的已结束任务
+func (js *JobStore) DeleteOlderThan(ctx context.Context, t time.Time) (int, error) {
+    js.mu.Lock()
+    defer js.mu.Unlock()
+
+    deleted := 0
+    for jobID, job := range js.jobs {
+	if (job.Status == models.StatusCompleted || job.Status == models.StatusFailed) && job.CreatedAt.Before(t) {
+	    js.remove(jobID)
+	    deleted++
+	}
+    }
+    return deleted, nil
+}
+
+// ListByStatus 列出指定状态的任务（按创建时间正序）
+func (js *JobStore) ListByStatus(ctx context.Context, statuses ...models.JobStatus) ([]*models.TranscriptionJob, error) {
+    js.mu.RLock()
+    defer js.mu.RUnlock()
+
+    filter := statusFilter(statuses)
+    jobs := make([]*models.TranscriptionJob, 0)
+    for _, job := range js.visibleJobs() {
+	if filter == nil || filter[job.Status] {
+	    jobs = append(jobs, job)
+	}
+    }
+
+    sort.Slice(jobs, func(i, j int) bool {
+	return jobs[i].CreatedAt.Before(jobs[j].CreatedAt)
+    })
+    return cloneJobs(jobs), nil
+}
+
+// Search 搜索任务（不区分大小写的子串匹配）
+func (js *JobStore) Search(ctx context.Context, query string) ([]*models.TranscriptionJob, error) {
+    jobs, err := js.ListAll(ctx)
+    if err != nil {
+	return nil, err
+    }
+
+    sort.Slice(jobs, func(i, j int) bool {
+	return jobs[i].CreatedAt.After(jobs[j].CreatedAt)
+    })
+    return filterJobs(jobs, query), nil
+}
+
+// Delete 软删除任务（记录删除时间）
+func (js *JobStore) Delete(ctx context.Context, jobID string) error {
+    js.mu.Lock()
+    defer js.mu.Unlock()
+
+    job, exists := js.jobs[jobID]
+    if !exists || !job.DeletedAt.IsZero() {
+	return fmt.Errorf("任务不存在: %s", jobID)
+    }
+
+    job.DeletedAt = time.Now()
+    job.Version++
+    return nil
+}
+
+// Restore 恢复软删除的任务
+func (js *JobStore) Restore(ctx context.Context, jobID string) error {
+    js.mu.Lock()
+    defer js.mu.Unlock()
+
+    job, exists := js.jobs[jobID]
+    if !exists || job.DeletedAt.IsZero() {
+	return fmt.Errorf("任务不存在或未被删除: %s", jobID)
+    }
+
+    job.DeletedAt = time.Time{}
+    job.Version++
+    return nil
+}
+
+// ListDeleted 列出删除时间早于 before 的软删除任务（按删除时间正序）
+func (js *JobStore) ListDeleted(ctx context.Context, before time.Time) ([]*models.TranscriptionJob, error) {
+    js.mu.RLock()
+    defer js.mu.RUnlock()
+
+    jobs := make([]*models.TranscriptionJob, 0)
+    for _, job := range js.jobs {
+	if !job.DeletedAt.IsZero() && job.DeletedAt.Before(before) {
+	    jobs = append(jobs, job)
+	}
+    }
+
+    sort.Slice(jobs, func(i, j int) bool {
+	return jobs[i].DeletedAt.Before(jobs[j].DeletedAt)
+    })
+    return cloneJobs(jobs), nil
+}
 
-// Delete 删除任务
-func (js *JobStore) Delete(jobID string) error {
+// Purge 永久删除任务
+func (js *JobStore) Purge(ctx context.Context, jobID string) error {
     js.mu.Lock()
     defer js.mu.Unlock()
 
@@ -92,7 +377,196 @@ func (js *JobStore) Delete(jobID string) error {
 	return fmt.Errorf("任务不存在: %s", jobID)
     }
 
-    delete(js.jobs, jobID)
+    js.remove(jobID)
+    return nil
+}
+
+// SaveSegment 保存片段结果
+func (js *JobStore) SaveSegment(ctx context.Context, jobID string, index int, data []byte) error {
+    js.mu.Lock()
+    defer js.mu.Unlock()
+
+    if js.segments[jobID] == nil {
+	js.segments[jobID] = make(map[int][]byte)
+    }
+    js.segments[jobID][index] = data
+    return nil
+}
+
+// LoadSegments 读取任务已保存的片段结果
+func (js *JobStore) LoadSegments(ctx context.Context, jobID string) (map[int][]byte, error) {
+    js.mu.RLock()
+    defer js.mu.RUnlock()
+
+    segments := make(map[int][]byte, len(js.segments[jobID]))
+    for index, data := range js.segments[jobID] {
+	segments[index] = data
+    }
+    return segments, nil
+}
+
+// DeleteSegments 删除任务的片段结果
+func (js *JobStore) DeleteSegments(ctx context.Context, jobID string) error {
+    js.mu.Lock()
+    defer js.mu.Unlock()
+
+    delete(js.segments, jobID)
+    return nil
+}
+
+// IncrUsage 累加月度费用
+func (js *JobStore) IncrUsage(ctx context.Context, month string, costUSD float64) (float64, error) {
+    js.mu.Lock()
+    defer js.mu.Unlock()
+
+    js.usage[month] += costUSD
+    return js.usage[month], nil
+}
+
+// GetUsage 获取月度费用
+func (js *JobStore) GetUsage(ctx context.Context, month string) (float64, error) {
+    js.mu.RLock()
+    defer js.mu.RUnlock()
+
+    return js.usage[month], nil
+}
+
+// credentialKey 用户凭证的 map key
+func credentialKey(userID, name string) string {
+    return userID + "\x00" + name
+}
+
+// SetCredential 保存用户凭证
+func (js *JobStore) SetCredential(ctx context.Context, userID, name, value string) error {
+    js.mu.Lock()
+    defer js.mu.Unlock()
+
+    js.secrets[credentialKey(userID, name)] = value
+    return nil
+}
+
+// GetCredential 获取用户凭证
+func (js *JobStore) GetCredential(ctx context.Context, userID, name string) (string, error) {
+    js.mu.RLock()
+    defer js.mu.RUnlock()
+
+    return js.secrets[credentialKey(userID, name)], nil
+}
+
+// DeleteCredential 删除用户凭证
+func (js *JobStore) DeleteCredential(ctx context.Context, userID, name string) error {
+    js.mu.Lock()
+    defer js.mu.Unlock()
+
+    delete(js.secrets, credentialKey(userID, name))
+    return nil
+}
+
+// SetHashIfAbsent 登记文件内容哈希（持有写锁，天然原子）
+func (js *JobStore) SetHashIfAbsent(ctx context.Context, hash, jobID string) (string, bool, error) {
+    js.mu.Lock()
+    defer js.mu.Unlock()
+
+    if existing, ok := js.hashes[hash]; ok {
+	return existing, false, nil
+    }
+    js.hashes[hash] = jobID
+    return jobID, true, nil
+}
+
+// FindByHash 查找相同文件内容最近一次已完成的任务
+func (js *JobStore) FindByHash(ctx context.Context, hash string) (*models.TranscriptionJob, error) {
+    js.mu.RLock()
+    defer js.mu.RUnlock()
+
+    var found *models.TranscriptionJob
+    for _, job := range js.visibleJobs() {
+	if job.ContentHash != hash || job.Status != models.StatusCompleted {
+	    continue
+	}
+	if found == nil || job.CreatedAt.After(found.CreatedAt) {
+	    found = job
+	}
+    }
+    if found == nil {
+	return nil, nil
+    }
+    return cloneJob(found), nil
+}
+
+// DeleteHash 删除文件内容哈希登记
+func (js *JobStore) DeleteHash(ctx context.Context, hash, jobID string) error {
+    js.mu.Lock()
+    defer js.mu.Unlock()
+
+    if js.hashes[hash] == jobID {
+	delete(js.hashes, hash)
+    }
+    return nil
+}
+
+// IndexWords 替换任务的单词索引
+func (js *JobStore) IndexWords(ctx context.Context, jobID string, words []string) error {
+    js.mu.Lock()
+    defer js.mu.Unlock()
+
+    js.unindexWords(jobID)
+    for _, word := range words {
+	if js.words[word] == nil {
+	    js.words[word] = make(map[string]bool)
+	}
+	js.words[word][jobID] = true
+    }
+    js.jobWords[jobID] = append([]string(nil), words...)
+    return nil
+}
+
+// unindexWords 从倒排索引中移除任务（调用方需持有写锁）
+func (js *JobStore) unindexWords(jobID string) {
+    for _, word := range js.jobWords[jobID] {
+	delete(js.words[word], jobID)
+	if len(js.words[word]) == 0 {
+	    delete(js.words, word)
+	}
+    }
+    delete(js.jobWords, jobID)
+}
+
+// JobsByWord 查找包含指定单词的任务
+func (js *JobStore) JobsByWord(ctx context.Context, word string) ([]*models.TranscriptionJob, error) {
+    js.mu.RLock()
+    defer js.mu.RUnlock()
+
+    jobs := make([]*models.TranscriptionJob, 0, len(js.words[word]))
+    for jobID := range js.words[word] {
+	if job, ok := js.jobs[jobID]; ok && job.DeletedAt.IsZero() {
+	    jobs = append(jobs, job)
+	}
+    }
+
+    sort.Slice(jobs, func(i, j int) bool {
+	return jobs[i].CreatedAt.After(jobs[j].CreatedAt)
+    })
+    return cloneJobs(jobs), nil
+}
+
+// ListVocabulary 列出所有任务已提取的单词
+func (js *JobStore) ListVocabulary(ctx context.Context) (map[string][]string, error) {
+    js.mu.RLock()
+    defer js.mu.RUnlock()
+
+    result := make(map[string][]string, len(js.jobWords))
+    for jobID, words := range js.jobWords {
+	if job, ok := js.jobs[jobID]; ok && !job.DeletedAt.IsZero() {
+	    continue
+	}
+	result[jobID] = append([]string(nil), words...)
+    }
+    return result, nil
+}
+
+// Ping 检查存储是否可用（内存存储始终可用）
+func (js *JobStore) Ping(ctx context.Context) error {
     return nil
 }
 
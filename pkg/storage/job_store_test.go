@@ -0,0 +1,120 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/z-wentao/voiceflow/pkg/models"
+)
+
+func TestJobStoreGetReturnsCopy(t *testing.T) {
+	ctx := context.Background()
+	store := NewJobStore(0)
+	if err := store.Save(ctx, &models.TranscriptionJob{JobID: "job-1", Status: models.StatusPending, Vocabulary: []string{"apple"}}); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	job, err := store.Get(ctx, "job-1")
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	job.Status = models.StatusCompleted
+	job.Vocabulary[0] = "banana"
+
+	stored, err := store.Get(ctx, "job-1")
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if stored.Status != models.StatusPending || stored.Vocabulary[0] != "apple" {
+		t.Fatalf("修改 Get 返回的任务影响了存储: status=%s vocabulary=%v", stored.Status, stored.Vocabulary)
+	}
+}
+
+func TestJobStoreSaveVersionConflict(t *testing.T) {
+	ctx := context.Background()
+	store := NewJobStore(0)
+	if err := store.Save(ctx, &models.TranscriptionJob{JobID: "job-1"}); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	first, _ := store.Get(ctx, "job-1")
+	second, _ := store.Get(ctx, "job-1")
+	first.Progress = 10
+	if err := store.Save(ctx, first); err != nil {
+		t.Fatalf("第一次保存: %v", err)
+	}
+	second.Progress = 20
+	if err := store.Save(ctx, second); !errors.Is(err, ErrVersionConflict) {
+		t.Fatalf("用旧版本保存应返回 ErrVersionConflict，实际为 %v", err)
+	}
+}
+
+func TestModifyConcurrent(t *testing.T) {
+	ctx := context.Background()
+	store := NewJobStore(0)
+	if err := store.Save(ctx, &models.TranscriptionJob{JobID: "job-1"}); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	// 并发修改同一个任务：版本冲突时 Modify 重新读取，成功的修改都不会被覆盖
+	const writers = 8
+	var wg sync.WaitGroup
+	var succeeded atomic.Int64
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := Modify(ctx, store, "job-1", func(job *models.TranscriptionJob) error {
+				job.Attempts++
+				return nil
+			})
+			if err == nil {
+				succeeded.Add(1)
+			} else if !errors.Is(err, ErrVersionConflict) {
+				t.Errorf("Modify: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	job, err := store.Get(ctx, "job-1")
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if int64(job.Attempts) != succeeded.Load() || succeeded.Load() == 0 {
+		t.Fatalf("Attempts = %d，成功的修改 %d 次", job.Attempts, succeeded.Load())
+	}
+}
+
+func TestModifyRetriesOnConflict(t *testing.T) {
+	ctx := context.Background()
+	store := NewJobStore(0)
+	if err := store.Save(ctx, &models.TranscriptionJob{JobID: "job-1"}); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	// 第一次调用 fn 时其他操作修改了任务，Modify 的保存应当冲突并重新读取
+	calls := 0
+	job, err := Modify(ctx, store, "job-1", func(job *models.TranscriptionJob) error {
+		calls++
+		if calls == 1 {
+			if err := store.Update(ctx, "job-1", func(j *models.TranscriptionJob) { j.Progress = 50 }); err != nil {
+				t.Fatalf("Update: %v", err)
+			}
+		}
+		job.Status = models.StatusCompleted
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Modify: %v", err)
+	}
+	if calls != 2 {
+		t.Fatalf("fn 调用了 %d 次，期望冲突后重试一次", calls)
+	}
+	if job.Progress != 50 || job.Status != models.StatusCompleted {
+		t.Fatalf("重试后的任务丢失了并发修改: progress=%d status=%s", job.Progress, job.Status)
+	}
+}
@@ -0,0 +1,238 @@
+package storage
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// migrationLockID 执行迁移时持有的 PostgreSQL advisory lock，多个实例同时启动时只有一个执行迁移
+const migrationLockID = 732_001_288
+
+// migration 一个版本化的迁移（Goose 格式 SQL 文件中 "-- +goose Up" 的部分）
+type migration struct {
+	version int64
+	name    string
+	up      string
+}
+
+// loadMigrations 读取迁移文件，按版本号排序
+// 文件名格式与 Goose 一致：<版本号>_<描述>.sql
+func loadMigrations(fsys fs.FS) ([]migration, error) {
+	files, err := fs.Glob(fsys, "*.sql")
+	if err != nil {
+		return nil, fmt.Errorf("读取迁移文件失败: %w", err)
+	}
+
+	migrations := make([]migration, 0, len(files))
+	seen := make(map[int64]string)
+	for _, file := range files {
+		prefix, _, _ := strings.Cut(file, "_")
+		version, err := strconv.ParseInt(prefix, 10, 64)
+		if err != nil || version <= 0 {
+			return nil, fmt.Errorf("迁移文件名缺少版本号: %s", file)
+		}
+		if other, ok := seen[version]; ok {
+			return nil, fmt.Errorf("迁移版本号重复: %s 和 %s", other, file)
+		}
+		seen[version] = file
+
+		content, err := fs.ReadFile(fsys, file)
+		if err != nil {
+			return nil, fmt.Errorf("读取迁移文件失败: %w", err)
+		}
+		up, err := upSection(string(content))
+		if err != nil {
+			return nil, fmt.Errorf("解析迁移文件 %s 失败: %w", file, err)
+		}
+		migrations = append(migrations, migration{
+			version: version,
+			name:    strings.TrimSuffix(path.Base(file), ".sql"),
+			up:      up,
+		})
+	}
+
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].version < migrations[j].version
+	})
+	return migrations, nil
+}
+
+// upSection 提取 "-- +goose Up" 与 "-- +goose Down" 之间的 SQL（去掉其他 goose 注解行）
+func upSection(content string) (string, error) {
+	var up strings.Builder
+	inUp, found := false, false
+
+	scanner := bufio.NewScanner(strings.NewReader(content))
+	for scanner.Scan() {
+		line := scanner.Text()
+		annotation, ok := strings.CutPrefix(strings.TrimSpace(line), "-- +goose ")
+		if ok {
+			switch strings.TrimSpace(annotation) {
+			case "Up":
+				inUp, found = true, true
+			case "Down":
+				inUp = false
+			}
+			continue
+		}
+		if inUp {
+			up.WriteString(line)
+			up.WriteByte('\n')
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return "", err
+	}
+	if !found {
+		return "", fmt.Errorf("缺少 -- +goose Up")
+	}
+	return up.String(), nil
+}
+
+// migratePostgres 执行尚未应用的迁移，已应用的版本记录在 schema_migrations 表中
+// 每个迁移在独立的事务中执行并登记版本，失败时回滚该迁移并停止，之前的迁移保持已应用
+func migratePostgres(ctx context.Context, db *sql.DB, fsys fs.FS) (int, error) {
+	migrations, err := loadMigrations(fsys)
+	if err != nil {
+		return 0, err
+	}
+
+	// advisory lock 属于会话，加锁、迁移和解锁需要使用同一个连接
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("获取数据库连接失败: %w", err)
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, migrationLockID); err != nil {
+		return 0, fmt.Errorf("获取迁移锁失败: %w", err)
+	}
+	defer conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock($1)`, migrationLockID)
+
+	if _, err := conn.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+    version BIGINT PRIMARY KEY,
+    name TEXT NOT NULL,
+    applied_at TIMESTAMP NOT NULL DEFAULT NOW()
+    )`); err != nil {
+		return 0, fmt.Errorf("创建 schema_migrations 表失败: %w", err)
+	}
+
+	applied, err := appliedVersions(ctx, conn)
+	if err != nil {
+		return 0, err
+	}
+	if len(applied) == 0 {
+		if applied, err = adoptGooseVersions(ctx, conn, migrations); err != nil {
+			return 0, err
+		}
+	}
+
+	count := 0
+	for _, m := range migrations {
+		if applied[m.version] {
+			continue
+		}
+		if err := applyMigration(ctx, conn, m); err != nil {
+			return count, err
+		}
+		slog.Info("✓ 已执行数据库迁移", "migration", m.name)
+		count++
+	}
+	return count, nil
+}
+
+// appliedVersions 读取已应用的迁移版本
+func appliedVersions(ctx context.Context, conn *sql.Conn) (map[int64]bool, error) {
+	rows, err := conn.QueryContext(ctx, `SELECT version FROM schema_migrations`)
+	if err != nil {
+		return nil, fmt.Errorf("读取迁移版本失败: %w", err)
+	}
+	defer rows.Close()
+
+	applied := make(map[int64]bool)
+	for rows.Next() {
+		var version int64
+		if err := rows.Scan(&version); err != nil {
+			return nil, fmt.Errorf("读取迁移版本失败: %w", err)
+		}
+		applied[version] = true
+	}
+	return applied, rows.Err()
+}
+
+// adoptGooseVersions 之前使用 goose 命令行迁移的数据库：把 goose_db_version 中已应用的版本登记到 schema_migrations，
+// 避免重复执行（goose 每次 up/down 追加一行，以每个版本最新的一行为准）
+func adoptGooseVersions(ctx context.Context, conn *sql.Conn, migrations []migration) (map[int64]bool, error) {
+	applied := make(map[int64]bool)
+
+	var table sql.NullString
+	if err := conn.QueryRowContext(ctx, `SELECT to_regclass('goose_db_version')::text`).Scan(&table); err != nil {
+		return nil, fmt.Errorf("检查 goose 迁移记录失败: %w", err)
+	}
+	if !table.Valid {
+		return applied, nil
+	}
+
+	rows, err := conn.QueryContext(ctx, `SELECT DISTINCT ON (version_id) version_id, is_applied
+    FROM goose_db_version WHERE version_id > 0
+    ORDER BY version_id, id DESC`)
+	if err != nil {
+		return nil, fmt.Errorf("读取 goose 迁移记录失败: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var version int64
+		var isApplied bool
+		if err := rows.Scan(&version, &isApplied); err != nil {
+			return nil, fmt.Errorf("读取 goose 迁移记录失败: %w", err)
+		}
+		if isApplied {
+			applied[version] = true
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("读取 goose 迁移记录失败: %w", err)
+	}
+
+	for _, m := range migrations {
+		if !applied[m.version] {
+			continue
+		}
+		if _, err := conn.ExecContext(ctx, `INSERT INTO schema_migrations (version, name) VALUES ($1, $2) ON CONFLICT DO NOTHING`, m.version, m.name); err != nil {
+			return nil, fmt.Errorf("登记迁移版本失败: %w", err)
+		}
+	}
+	if len(applied) > 0 {
+		slog.Info("✓ 已导入 goose 迁移记录", "versions", len(applied))
+	}
+	return applied, nil
+}
+
+// applyMigration 在事务中执行一个迁移并登记版本
+func applyMigration(ctx context.Context, conn *sql.Conn, m migration) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("开启事务失败: %w", err)
+	}
+	defer tx.Rollback()
+
+	// 不带参数时 lib/pq 使用简单查询协议，一次可以执行多条语句
+	if _, err := tx.ExecContext(ctx, m.up); err != nil {
+		return fmt.Errorf("执行迁移 %s 失败: %w", m.name, err)
+	}
+	if _, err := tx.ExecContext(ctx, `INSERT INTO schema_migrations (version, name) VALUES ($1, $2)`, m.version, m.name); err != nil {
+		return fmt.Errorf("登记迁移版本失败: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("提交迁移 %s 失败: %w", m.name, err)
+	}
+	return nil
+}
@@ -0,0 +1,231 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io/fs"
+	"os"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/z-wentao/voiceflow/migrations"
+)
+
+func TestLoadMigrations(t *testing.T) {
+	tests := []struct {
+		name      string
+		files     fstest.MapFS
+		wantNames []string
+		wantErr   string
+	}{
+		{
+			name: "按版本号排序",
+			files: fstest.MapFS{
+				"00010_add_b.sql":  {Data: []byte("-- +goose Up\nALTER TABLE t ADD b INT;\n")},
+				"00002_add_a.sql":  {Data: []byte("-- +goose Up\nALTER TABLE t ADD a INT;\n")},
+				"00001_create.sql": {Data: []byte("-- +goose Up\nCREATE TABLE t (id INT);\n")},
+				"README.md":        {Data: []byte("不是迁移文件")},
+			},
+			wantNames: []string{"00001_create", "00002_add_a", "00010_add_b"},
+		},
+		{name: "没有迁移文件", files: fstest.MapFS{}},
+		{
+			name:    "缺少版本号",
+			files:   fstest.MapFS{"create.sql": {Data: []byte("-- +goose Up\nSELECT 1;\n")}},
+			wantErr: "缺少版本号",
+		},
+		{
+			name: "版本号重复",
+			files: fstest.MapFS{
+				"00001_a.sql": {Data: []byte("-- +goose Up\nSELECT 1;\n")},
+				"1_b.sql":     {Data: []byte("-- +goose Up\nSELECT 2;\n")},
+			},
+			wantErr: "版本号重复",
+		},
+		{
+			name:    "缺少 Up 部分",
+			files:   fstest.MapFS{"00001_a.sql": {Data: []byte("-- +goose Down\nDROP TABLE t;\n")}},
+			wantErr: "缺少 -- +goose Up",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := loadMigrations(tt.files)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("loadMigrations = %v，期望包含 %q 的错误", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("loadMigrations: %v", err)
+			}
+			var names []string
+			for _, m := range got {
+				names = append(names, m.name)
+			}
+			if fmt.Sprint(names) != fmt.Sprint(tt.wantNames) {
+				t.Fatalf("迁移 = %v，期望 %v", names, tt.wantNames)
+			}
+		})
+	}
+}
+
+func TestUpSection(t *testing.T) {
+	content := `-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE t (id INT);
+-- +goose StatementEnd
+-- +goose Down
+DROP TABLE t;
+`
+	up, err := upSection(content)
+	if err != nil {
+		t.Fatalf("upSection: %v", err)
+	}
+	if up != "CREATE TABLE t (id INT);\n" {
+		t.Fatalf("upSection = %q", up)
+	}
+}
+
+// TestEmbeddedMigrations 内置的迁移文件都能解析，版本号从 1 开始连续
+func TestEmbeddedMigrations(t *testing.T) {
+	got, err := loadMigrations(migrations.FS)
+	if err != nil {
+		t.Fatalf("loadMigrations: %v", err)
+	}
+	if len(got) == 0 {
+		t.Fatal("没有内置的迁移文件")
+	}
+	for i, m := range got {
+		if m.version != int64(i+1) {
+			t.Fatalf("第 %d 个迁移 %s 的版本号为 %d，期望 %d", i, m.name, m.version, i+1)
+		}
+		if strings.TrimSpace(m.up) == "" {
+			t.Fatalf("迁移 %s 的 Up 部分为空", m.name)
+		}
+	}
+}
+
+// TestMigratePostgres 从空数据库、从中间版本（之前的迁移由本程序或 goose 命令行执行）迁移到最新版本
+// 需要设置 VOICEFLOW_TEST_POSTGRES_DSN；每个用例使用独立的 schema，结束后删除
+func TestMigratePostgres(t *testing.T) {
+	all, err := loadMigrations(migrations.FS)
+	if err != nil {
+		t.Fatalf("loadMigrations: %v", err)
+	}
+	const intermediate = 10
+
+	tests := []struct {
+		name    string
+		prepare func(t *testing.T, db *sql.DB)
+		want    int
+	}{
+		{"从零开始", func(t *testing.T, db *sql.DB) {}, len(all)},
+		{"从中间版本继续", func(t *testing.T, db *sql.DB) {
+			applied, err := migratePostgres(context.Background(), db, firstMigrations(t, intermediate))
+			if err != nil || applied != intermediate {
+				t.Fatalf("迁移前 %d 个版本: applied=%d err=%v", intermediate, applied, err)
+			}
+		}, len(all) - intermediate},
+		{"导入 goose 迁移记录", func(t *testing.T, db *sql.DB) {
+			for _, m := range all[:intermediate] {
+				if _, err := db.Exec(m.up); err != nil {
+					t.Fatalf("执行 %s: %v", m.name, err)
+				}
+			}
+			if _, err := db.Exec(`CREATE TABLE goose_db_version (id SERIAL PRIMARY KEY, version_id BIGINT NOT NULL, is_applied BOOLEAN NOT NULL)`); err != nil {
+				t.Fatalf("创建 goose_db_version: %v", err)
+			}
+			for _, m := range all[:intermediate] {
+				if _, err := db.Exec(`INSERT INTO goose_db_version (version_id, is_applied) VALUES ($1, TRUE)`, m.version); err != nil {
+					t.Fatalf("登记 goose 版本: %v", err)
+				}
+			}
+		}, len(all) - intermediate},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			db := openTestSchema(t)
+			tt.prepare(t, db)
+
+			applied, err := migratePostgres(ctx, db, migrations.FS)
+			if err != nil {
+				t.Fatalf("migratePostgres: %v", err)
+			}
+			if applied != tt.want {
+				t.Fatalf("执行了 %d 个迁移，期望 %d", applied, tt.want)
+			}
+			if applied, err := migratePostgres(ctx, db, migrations.FS); err != nil || applied != 0 {
+				t.Fatalf("再次迁移: applied=%d err=%v，期望 0", applied, err)
+			}
+
+			var versions int
+			if err := db.QueryRow(`SELECT COUNT(*) FROM schema_migrations`).Scan(&versions); err != nil {
+				t.Fatalf("读取 schema_migrations: %v", err)
+			}
+			if versions != len(all) {
+				t.Fatalf("已登记 %d 个版本，期望 %d", versions, len(all))
+			}
+		})
+	}
+}
+
+// firstMigrations 只包含前 n 个内置迁移文件的文件系统
+func firstMigrations(t *testing.T, n int) fs.FS {
+	t.Helper()
+	files, err := fs.Glob(migrations.FS, "*.sql")
+	if err != nil {
+		t.Fatalf("Glob: %v", err)
+	}
+	subset := fstest.MapFS{}
+	for _, file := range files[:n] {
+		data, err := fs.ReadFile(migrations.FS, file)
+		if err != nil {
+			t.Fatalf("ReadFile: %v", err)
+		}
+		subset[file] = &fstest.MapFile{Data: data}
+	}
+	return subset
+}
+
+// openTestSchema 在 VOICEFLOW_TEST_POSTGRES_DSN 指定的数据库中创建一个空 schema，返回只使用该 schema 的连接
+func openTestSchema(t *testing.T) *sql.DB {
+	t.Helper()
+	dsn := os.Getenv("VOICEFLOW_TEST_POSTGRES_DSN")
+	if dsn == "" {
+		t.Skip("未设置 VOICEFLOW_TEST_POSTGRES_DSN，跳过 PostgreSQL 测试")
+	}
+	admin, err := sql.Open("postgres", dsn)
+	if err != nil {
+		t.Fatalf("打开数据库: %v", err)
+	}
+	t.Cleanup(func() { admin.Close() })
+
+	schema := fmt.Sprintf("voiceflow_test_%d", time.Now().UnixNano())
+	if _, err := admin.Exec(`CREATE SCHEMA ` + schema); err != nil {
+		t.Fatalf("创建 schema: %v", err)
+	}
+	t.Cleanup(func() { admin.Exec(`DROP SCHEMA ` + schema + ` CASCADE`) })
+
+	// lib/pq 把未知的连接参数作为会话参数发送
+	if strings.Contains(dsn, "://") {
+		sep := "?"
+		if strings.Contains(dsn, "?") {
+			sep = "&"
+		}
+		dsn += sep + "search_path=" + schema
+	} else {
+		dsn += " search_path=" + schema
+	}
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		t.Fatalf("打开数据库: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
@@ -0,0 +1,9 @@
+package storage
+
+// MongoOptions MongoDB 存储参数
+// MongoDB 客户端只在使用 mongo 构建标签时编译（go build -tags mongo），见 mongo_store.go
+type MongoOptions struct {
+    URI       string // 连接串，如 "mongodb://localhost:27017"
+    Database  string // 数据库名
+    ListLimit int    // List 返回的最大任务数，<= 0 时使用默认值 100
+}
@@ -0,0 +1,836 @@
+//go:build mongo
+
+package storage
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "log/slog"
+    "regexp"
+    "strconv"
+    "time"
+
+    "github.com/z-wentao/voiceflow/pkg/models"
+    "go.mongodb.org/mongo-driver/v2/bson"
+    "go.mongodb.org/mongo-driver/v2/mongo"
+    "go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// MongoJobStore MongoDB 任务存储
+// 1. 每个任务是 transcription_jobs 集合中的一个文档（_id 为任务 ID），字段名与任务的 JSON 字段一致
+// 2. 任务的 version 字段做乐观锁：Save / Update 按 (_id, version) 写回，Update 在 version 变化时重试
+// 3. 不依赖多文档事务，单机部署的 MongoDB（非副本集）也可以使用
+type MongoJobStore struct {
+    client    *mongo.Client
+    listLimit int // List 返回的最大任务数
+
+    jobs        *mongo.Collection // 任务
+    usage       *mongo.Collection // 月度费用
+    credentials *mongo.Collection // 用户凭证
+    hashes      *mongo.Collection // 文件内容哈希登记
+    words       *mongo.Collection // 单词索引（每个任务一个文档）
+    segments    *mongo.Collection // 已完成片段的结果（每个任务一个文档）
+}
+
+// mongoJobDocument 任务文档：任务字段（按 JSON 字段名，包括乐观锁版本号 version）展开到文档顶层，加上 _id
+type mongoJobDocument struct {
+    ID                      string `bson:"_id"`
+    models.TranscriptionJob `bson:",inline"`
+}
+
+// defaultMongoListLimit List 默认返回的任务数
+const defaultMongoListLimit = 100
+
+// mongoPageSize ListAll / ForEach 每页读取的任务数
+const mongoPageSize = 500
+
+// mongoUpdateRetries Update 遇到并发修改时的最大重试次数
+const mongoUpdateRetries = 10
+
+// mongoConnectTimeout 启动时连接和创建索引的超时时间
+const mongoConnectTimeout = 10 * time.Second
+
+// NewMongoJobStore 创建 MongoDB 任务存储（连接数据库并创建索引）
+func NewMongoJobStore(opts MongoOptions) (*MongoJobStore, error) {
+    // 任务结构只有 JSON 标签，按 JSON 字段名读写文档
+    clientOpts := options.Client().
+	ApplyURI(opts.URI).
+	SetBSONOptions(&options.BSONOptions{UseJSONStructTags: true})
+
+    client, err := mongo.Connect(clientOpts)
+    if err != nil {
+	return nil, fmt.Errorf("创建 MongoDB 客户端失败: %w", err)
+    }
+
+    ctx, cancel := context.WithTimeout(context.Background(), mongoConnectTimeout)
+    defer cancel()
+
+    if err := client.Ping(ctx, nil); err != nil {
+	client.Disconnect(context.Background())
+	return nil, fmt.Errorf("连接 MongoDB 失败: %w", err)
+    }
+
+    db := client.Database(opts.Database)
+    s := &MongoJobStore{
+	client:      client,
+	listLimit:   defaultMongoListLimit,
+	jobs:        db.Collection("transcription_jobs"),
+	usage:       db.Collection("openai_usage"),
+	credentials: db.Collection("user_credentials"),
+	hashes:      db.Collection("job_content_hashes"),
+	words:       db.Collection("job_words"),
+	segments:    db.Collection("job_segments"),
+    }
+    s.SetListLimit(opts.ListLimit)
+
+    if err := s.createIndexes(ctx); err != nil {
+	client.Disconnect(context.Background())
+	return nil, err
+    }
+
+    slog.Info("✓ MongoDB 存储初始化成功", "database", opts.Database)
+    return s, nil
+}
+
+// createIndexes 创建查询用到的索引（已存在时不做任何操作）
+func (s *MongoJobStore) createIndexes(ctx context.Context) error {
+    jobIndexes := []mongo.IndexModel{
+	{Keys: bson.D{{Key: "created_at", Value: -1}, {Key: "_id", Value: -1}}}, // 列表和分页遍历
+	{Keys: bson.D{{Key: "status", Value: 1}, {Key: "created_at", Value: 1}}}, // 按状态查询和统计
+	{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "created_at", Value: -1}}},
+	{Keys: bson.D{{Key: "content_hash", Value: 1}}},
+	{Keys: bson.D{{Key: "deleted_at", Value: 1}}}, // 软删除清理
+    }
+    if _, err := s.jobs.Indexes().CreateMany(ctx, jobIndexes); err != nil {
+	return fmt.Errorf("创建任务索引失败: %w", err)
+    }
+
+    credentialIndex := mongo.IndexModel{
+	Keys:    bson.D{{Key: "user_id", Value: 1}, {Key: "name", Value: 1}},
+	Options: options.Index().SetUnique(true),
+    }
+    if _, err := s.credentials.Indexes().CreateOne(ctx, credentialIndex); err != nil {
+	return fmt.Errorf("创建凭证索引失败: %w", err)
+    }
+
+    if _, err := s.words.Indexes().CreateOne(ctx, mongo.IndexModel{Keys: bson.D{{Key: "words", Value: 1}}}); err != nil {
+	return fmt.Errorf("创建单词索引失败: %w", err)
+    }
+    return nil
+}
+
+// SetListLimit 设置 List 返回的最大任务数（<= 0 时使用默认值 100）
+func (s *MongoJobStore) SetListLimit(limit int) {
+    if limit <= 0 {
+	limit = defaultMongoListLimit
+    }
+    s.listLimit = limit
+}
+
+// byID 按 _id 查询的过滤条件
+func byID(id string) bson.D {
+    return bson.D{{Key: "_id", Value: id}}
+}
+
+// mongoUpsertPipeline 整体替换任务文档并递增 version 的更新管道
+// 文档不存在时插入，version 为 expected + 1（expected 为 0 时从 1 开始）；
+// 任务字段用 $literal 包裹，以 $ 开头的文件名等字符串不会被当作字段路径
+func mongoUpsertPipeline(job *models.TranscriptionJob, expected int64) mongo.Pipeline {
+    doc := mongoJobDocument{ID: job.JobID, TranscriptionJob: *job}
+    current := bson.D{{Key: "$ifNull", Value: bson.A{"$version", 0}}}
+    version := bson.D{{Key: "$add", Value: bson.A{bson.D{{Key: "$max", Value: bson.A{current, expected}}}, 1}}}
+
+    return mongo.Pipeline{
+	{{Key: "$replaceWith", Value: bson.D{{Key: "$mergeObjects", Value: bson.A{
+	    bson.D{{Key: "$literal", Value: doc}},
+	    bson.D{{Key: "version", Value: version}},
+	}}}}},
+    }
+}
+
+// Save 保存任务（整体替换，version 加 1，进行中的 Update 会因版本变化而重试）
+// job.Version > 0 时以 (_id, version) 为条件：文档已被修改时条件不匹配，upsert 插入同一 _id 报重复键，返回 ErrVersionConflict
+func (s *MongoJobStore) Save(ctx context.Context, job *models.TranscriptionJob) error {
+    filter := byID(job.JobID)
+    if job.Version > 0 {
+	filter = append(filter, bson.E{Key: "version", Value: job.Version})
+    }
+
+    opts := options.FindOneAndUpdate().
+	SetUpsert(true).
+	SetReturnDocument(options.After).
+	SetProjection(bson.D{{Key: "version", Value: 1}})
+
+    var saved struct {
+	Version int64 `bson:"version"`
+    }
+    err := s.jobs.FindOneAndUpdate(ctx, filter, mongoUpsertPipeline(job, job.Version), opts).Decode(&saved)
+    if mongo.IsDuplicateKeyError(err) {
+	return versionConflict(job.JobID)
+    }
+    if err != nil {
+	return fmt.Errorf("保存到 MongoDB 失败: %w", err)
+    }
+
+    job.Version = saved.Version
+    return nil
+}
+
+// BatchSave 批量保存任务：一次无序 BulkWrite 写入所有任务，同一任务出现多次时只写入最后一次
+// 部分任务写入失败时通过 *BatchSaveError 返回，其他任务照常写入；不检查版本号，也不修改传入任务的 Version
+func (s *MongoJobStore) BatchSave(ctx context.Context, jobs []*models.TranscriptionJob) error {
+    jobs = latestJobs(jobs)
+    if len(jobs) == 0 {
+	return nil
+    }
+
+    writes := make([]mongo.WriteModel, 0, len(jobs))
+    for _, job := range jobs {
+	writes = append(writes, mongo.NewUpdateOneModel().
+	    SetFilter(byID(job.JobID)).
+	    SetUpdate(mongoUpsertPipeline(job, 0)).
+	    SetUpsert(true))
+    }
+
+    _, err := s.jobs.BulkWrite(ctx, writes, options.BulkWrite().SetOrdered(false))
+    if err == nil {
+	return nil
+    }
+
+    failed := make(map[string]error)
+    var bulkErr mongo.BulkWriteException
+    if errors.As(err, &bulkErr) && len(bulkErr.WriteErrors) > 0 {
+	for _, writeErr := range bulkErr.WriteErrors {
+	    if writeErr.Index >= 0 && writeErr.Index < len(jobs) {
+		failed[jobs[writeErr.Index].JobID] = fmt.Errorf("保存到 MongoDB 失败: %w", writeErr)
+	    }
+	}
+    } else {
+	// 连接失败等整批错误：无法确定哪些任务已写入，全部记为失败（重试是幂等的）
+	for _, job := range jobs {
+	    failed[job.JobID] = fmt.Errorf("保存到 MongoDB 失败: %w", err)
+	}
+    }
+    return &BatchSaveError{Failed: failed}
+}
+
+// getDocument 读取任务文档
+func (s *MongoJobStore) getDocument(ctx context.Context, jobID string) (*mongoJobDocument, error) {
+    var doc mongoJobDocument
+    err := s.jobs.FindOne(ctx, byID(jobID)).Decode(&doc)
+    if errors.Is(err, mongo.ErrNoDocuments) {
+	return nil, fmt.Errorf("任务不存在: %s", jobID)
+    }
+    if err != nil {
+	return nil, fmt.Errorf("查询 MongoDB 失败: %w", err)
+    }
+    return &doc, nil
+}
+
+// Get 获取任务
+func (s *MongoJobStore) Get(ctx context.Context, jobID string) (*models.TranscriptionJob, error) {
+    doc, err := s.getDocument(ctx, jobID)
+    if err != nil {
+	return nil, err
+    }
+    return &doc.TranscriptionJob, nil
+}
+
+// Update 更新任务（乐观锁）
+// 读取任务和版本号、执行更新函数，再以 (_id, version) 为条件替换文档并递增版本号；
+// 期间任务被其他 Update / Save 修改过时条件不匹配，重新读取后重试，updateFn 可能被调用多次
+func (s *MongoJobStore) Update(ctx context.Context, jobID string, updateFn func(*models.TranscriptionJob)) error {
+    for i := 0; i < mongoUpdateRetries; i++ {
+	// 1. 获取现有任务和版本号
+	job, err := s.Get(ctx, jobID)
+	if err != nil {
+	    return err
+	}
+	version := job.Version
+
+	// 2. 执行更新函数
+	updateFn(job)
+
+	// 3. 版本号未变化时写回
+	job.Version = version + 1
+	filter := bson.D{{Key: "_id", Value: jobID}, {Key: "version", Value: version}}
+	next := mongoJobDocument{ID: jobID, TranscriptionJob: *job}
+	result, err := s.jobs.ReplaceOne(ctx, filter, next)
+	if err != nil {
+	    return fmt.Errorf("保存到 MongoDB 失败: %w", err)
+	}
+	if result.MatchedCount == 1 {
+	    return nil
+	}
+    }
+    return fmt.Errorf("更新任务失败: 并发修改冲突，已重试 %d 次", mongoUpdateRetries)
+}
+
+// findJobs 查询任务并解码
+func (s *MongoJobStore) findJobs(ctx context.Context, filter any, opts *options.FindOptionsBuilder) ([]*models.TranscriptionJob, error) {
+    cursor, err := s.jobs.Find(ctx, filter, opts)
+    if err != nil {
+	return nil, fmt.Errorf("查询 MongoDB 失败: %w", err)
+    }
+
+    var docs []mongoJobDocument
+    if err := cursor.All(ctx, &docs); err != nil {
+	return nil, fmt.Errorf("读取任务失败: %w", err)
+    }
+
+    jobs := make([]*models.TranscriptionJob, 0, len(docs))
+    for i := range docs {
+	jobs = append(jobs, &docs[i].TranscriptionJob)
+    }
+    return jobs, nil
+}
+
+// newestFirst 按创建时间倒序
+var newestFirst = bson.D{{Key: "created_at", Value: -1}, {Key: "_id", Value: -1}}
+
+// deletedAfter 删除时间晚于零值时间（即已软删除；未删除的任务 deleted_at 不存在或为零值时间）
+var deletedAfter = bson.D{{Key: "$gt", Value: time.Time{}}}
+
+// mongoNotDeleted 排除软删除任务的条件
+var mongoNotDeleted = bson.E{Key: "deleted_at", Value: bson.D{{Key: "$not", Value: deletedAfter}}}
+
+// List 列出最近的任务（按创建时间倒序，最多 listLimit 个）
+func (s *MongoJobStore) List(ctx context.Context) ([]*models.TranscriptionJob, error) {
+    return s.findJobs(ctx, bson.D{mongoNotDeleted}, options.Find().SetSort(newestFirst).SetLimit(int64(s.listLimit)))
+}
+
+// ListAll 列出所有任务（按创建时间倒序，不限制数量）
+func (s *MongoJobStore) ListAll(ctx context.Context) ([]*models.TranscriptionJob, error) {
+    jobs := make([]*models.TranscriptionJob, 0)
+    err := s.ForEach(ctx, func(job *models.TranscriptionJob) error {
+	jobs = append(jobs, job)
+	return nil
+    })
+    if err != nil {
+	return nil, err
+    }
+    return jobs, nil
+}
+
+// ForEach 按创建时间倒序遍历所有任务
+// 使用 (created_at, _id) 的 keyset 分页，每次查询一页（mongoPageSize 个），不会一次加载全部任务。fn 返回错误时停止遍历
+func (s *MongoJobStore) ForEach(ctx context.Context, fn func(*models.TranscriptionJob) error) error {
+    filter := bson.D{mongoNotDeleted}
+    for {
+	page, err := s.findJobs(ctx, filter, options.Find().SetSort(newestFirst).SetLimit(mongoPageSize))
+	if err != nil {
+	    return err
+	}
+	for _, job := range page {
+	    if err := fn(job); err != nil {
+		return err
+	    }
+	}
+	if len(page) < mongoPageSize {
+	    return nil
+	}
+
+	last := page[len(page)-1]
+	filter = bson.D{mongoNotDeleted, {Key: "$or", Value: bson.A{
+	    bson.D{{Key: "created_at", Value: bson.D{{Key: "$lt", Value: last.CreatedAt}}}},
+	    bson.D{{Key: "created_at", Value: last.CreatedAt}, {Key: "_id", Value: bson.D{{Key: "$lt", Value: last.JobID}}}},
+	}}}
+    }
+}
+
+// ListForUser 列出指定用户的任务（user_id 索引，按创建时间倒序）
+func (s *MongoJobStore) ListForUser(ctx context.Context, userID string, limit int) ([]*models.TranscriptionJob, error) {
+    opts := options.Find().SetSort(newestFirst)
+    if limit > 0 {
+	opts.SetLimit(int64(limit))
+    }
+    return s.findJobs(ctx, bson.D{{Key: "user_id", Value: userID}, mongoNotDeleted}, opts)
+}
+
+// statusMatch 状态过滤条件（未指定状态时不过滤状态），排除软删除的任务
+func statusMatch(statuses []models.JobStatus) bson.D {
+    if len(statuses) == 0 {
+	return bson.D{mongoNotDeleted}
+    }
+    return bson.D{{Key: "status", Value: bson.D{{Key: "$in", Value: statuses}}}, mongoNotDeleted}
+}
+
+// ListSummaries 列出指定状态任务的轻量投影（只读取投影需要的字段）
+func (s *MongoJobStore) ListSummaries(ctx context.Context, statuses ...models.JobStatus) ([]models.JobSummary, error) {
+    projection := bson.D{
+	{Key: "job_id", Value: 1},
+	{Key: "filename", Value: 1},
+	{Key: "status", Value: 1},
+	{Key: "progress", Value: 1},
+	{Key: "created_at", Value: 1},
+	{Key: "user_id", Value: 1},
+    }
+    cursor, err := s.jobs.Find(ctx, statusMatch(statuses), options.Find().SetSort(newestFirst).SetProjection(projection))
+    if err != nil {
+	return nil, fmt.Errorf("查询 MongoDB 失败: %w", err)
+    }
+
+    summaries := make([]models.JobSummary, 0)
+    if err := cursor.All(ctx, &summaries); err != nil {
+	return nil, fmt.Errorf("读取任务失败: %w", err)
+    }
+    return summaries, nil
+}
+
+// CountByStatus 按状态统计任务数量（$group 聚合）
+func (s *MongoJobStore) CountByStatus(ctx context.Context, userID string) (map[models.JobStatus]int, error) {
+    match := bson.D{mongoNotDeleted}
+    if userID != "" {
+	match = append(match, bson.E{Key: "user_id", Value: userID})
+    }
+    pipeline := mongo.Pipeline{
+	{{Key: "$match", Value: match}},
+	{{Key: "$group", Value: bson.D{{Key: "_id", Value: "$status"}, {Key: "count", Value: bson.D{{Key: "$sum", Value: 1}}}}}},
+    }
+
+    cursor, err := s.jobs.Aggregate(ctx, pipeline)
+    if err != nil {
+	return nil, fmt.Errorf("统计任务失败: %w", err)
+    }
+    var groups []struct {
+	Status models.JobStatus `bson:"_id"`
+	Count  int              `bson:"count"`
+    }
+    if err := cursor.All(ctx, &groups); err != nil {
+	return nil, fmt.Errorf("统计任务失败: %w", err)
+    }
+
+    counts := make(map[models.JobStatus]int, len(groups))
+    for _, group := range groups {
+	counts[group.Status] = group.Count
+    }
+    return counts, nil
+}
+
+// Stats 汇总任务统计（$group 聚合）
+func (s *MongoJobStore) Stats(ctx context.Context, userID string, since time.Time) (models.JobStats, error) {
+    match := bson.D{mongoNotDeleted}
+    if userID != "" {
+	match = append(match, bson.E{Key: "user_id", Value: userID})
+    }
+    if !since.IsZero() {
+	match = append(match, bson.E{Key: "created_at", Value: bson.D{{Key: "$gte", Value: since}}})
+    }
+    isStatus := func(status models.JobStatus) bson.D {
+	return bson.D{{Key: "$eq", Value: bson.A{"$status", status}}}
+    }
+    countIf := func(status models.JobStatus) bson.D {
+	return bson.D{{Key: "$sum", Value: bson.D{{Key: "$cond", Value: bson.A{isStatus(status), 1, 0}}}}}
+    }
+    pipeline := mongo.Pipeline{
+	{{Key: "$match", Value: match}},
+	{{Key: "$group", Value: bson.D{
+	    {Key: "_id", Value: nil},
+	    {Key: "total", Value: bson.D{{Key: "$sum", Value: 1}}},
+	    {Key: "completed", Value: countIf(models.StatusCompleted)},
+	    {Key: "failed", Value: countIf(models.StatusFailed)},
+	    // $avg 忽略 null，只计算已完成的任务
+	    {Key: "avg_duration", Value: bson.D{{Key: "$avg", Value: bson.D{{Key: "$cond", Value: bson.A{isStatus(models.StatusCompleted), "$duration", nil}}}}}},
+	}}},
+    }
+
+    cursor, err := s.jobs.Aggregate(ctx, pipeline)
+    if err != nil {
+	return models.JobStats{}, fmt.Errorf("统计任务失败: %w", err)
+    }
+    var results []models.JobStats
+    if err := cursor.All(ctx, &results); err != nil {
+	return models.JobStats{}, fmt.Errorf("统计任务失败: %w", err)
+    }
+    if len(results) == 0 {
+	return models.JobStats{}, nil // 没有任务
+    }
+    return results[0], nil
+}
+
+// ListByStatus 列出指定状态的任务（按创建时间正序）
+func (s *MongoJobStore) ListByStatus(ctx context.Context, statuses ...models.JobStatus) ([]*models.TranscriptionJob, error) {
+    oldestFirst := bson.D{{Key: "created_at", Value: 1}, {Key: "_id", Value: 1}}
+    return s.findJobs(ctx, statusMatch(statuses), options.Find().SetSort(oldestFirst))
+}
+
+// Search 搜索任务（文件名和转录文本的子串匹配，不区分大小写，按创建时间倒序）
+// 没有使用文本索引：文本索引按单词匹配，无法匹配文件名片段
+func (s *MongoJobStore) Search(ctx context.Context, query string) ([]*models.TranscriptionJob, error) {
+    pattern := bson.Regex{Pattern: regexp.QuoteMeta(query), Options: "i"}
+    filter := bson.D{mongoNotDeleted, {Key: "$or", Value: bson.A{
+	bson.D{{Key: "filename", Value: pattern}},
+	bson.D{{Key: "result", Value: pattern}},
+    }}}
+
+    jobs, err := s.findJobs(ctx, filter, options.Find().SetSort(newestFirst).SetLimit(searchLimit))
+    if err != nil {
+	return nil, fmt.Errorf("搜索任务失败: %w", err)
+    }
+    return jobs, nil
+}
+
+// Delete 软删除任务（记录删除时间并递增版本号）
+func (s *MongoJobStore) Delete(ctx context.Context, jobID string) error {
+    filter := bson.D{{Key: "_id", Value: jobID}, mongoNotDeleted}
+    update := bson.D{
+	{Key: "$set", Value: bson.D{{Key: "deleted_at", Value: time.Now()}}},
+	{Key: "$inc", Value: bson.D{{Key: "version", Value: 1}}},
+    }
+    result, err := s.jobs.UpdateOne(ctx, filter, update)
+    if err != nil {
+	return fmt.Errorf("删除任务失败: %w", err)
+    }
+    if result.MatchedCount == 0 {
+	return fmt.Errorf("任务不存在: %s", jobID)
+    }
+    return nil
+}
+
+// Restore 恢复软删除的任务（删除时间重置为零值时间并递增版本号）
+func (s *MongoJobStore) Restore(ctx context.Context, jobID string) error {
+    filter := bson.D{{Key: "_id", Value: jobID}, {Key: "deleted_at", Value: deletedAfter}}
+    update := bson.D{
+	{Key: "$set", Value: bson.D{{Key: "deleted_at", Value: time.Time{}}}},
+	{Key: "$inc", Value: bson.D{{Key: "version", Value: 1}}},
+    }
+    result, err := s.jobs.UpdateOne(ctx, filter, update)
+    if err != nil {
+	return fmt.Errorf("恢复任务失败: %w", err)
+    }
+    if result.MatchedCount == 0 {
+	return fmt.Errorf("任务不存在或未被删除: %s", jobID)
+    }
+    return nil
+}
+
+// ListDeleted 列出删除时间早于 before 的软删除任务（deleted_at 索引，按删除时间正序）
+func (s *MongoJobStore) ListDeleted(ctx context.Context, before time.Time) ([]*models.TranscriptionJob, error) {
+    filter := bson.D{{Key: "deleted_at", Value: bson.D{{Key: "$gt", Value: time.Time{}}, {Key: "$lt", Value: before}}}}
+    return s.findJobs(ctx, filter, options.Find().SetSort(bson.D{{Key: "deleted_at", Value: 1}}))
+}
+
+// Purge 永久删除任务及其单词索引和片段结果
+func (s *MongoJobStore) Purge(ctx context.Context, jobID string) error {
+    result, err := s.jobs.DeleteOne(ctx, byID(jobID))
+    if err != nil {
+	return fmt.Errorf("删除任务失败: %w", err)
+    }
+    if result.DeletedCount == 0 {
+	return fmt.Errorf("任务不存在: %s", jobID)
+    }
+
+    if _, err := s.words.DeleteOne(ctx, byID(jobID)); err != nil {
+	return fmt.Errorf("删除单词索引失败: %w", err)
+    }
+    return s.DeleteSegments(ctx, jobID)
+}
+
+// DeleteOlderThan 删除创建时间早于 t 的已结束任务及其单词索引和片段结果
+// 先删除任务文档，再删除对应的单词索引和片段结果（不使用事务，中途失败时残留的单词索引和片段结果不影响查询）
+func (s *MongoJobStore) DeleteOlderThan(ctx context.Context, t time.Time) (int, error) {
+    filter := bson.D{
+	{Key: "created_at", Value: bson.D{{Key: "$lt", Value: t}}},
+	{Key: "status", Value: bson.D{{Key: "$in", Value: terminalStatuses}}},
+    }
+
+    cursor, err := s.jobs.Find(ctx, filter, options.Find().SetProjection(bson.D{{Key: "_id", Value: 1}}))
+    if err != nil {
+	return 0, fmt.Errorf("查询 MongoDB 失败: %w", err)
+    }
+    var docs []struct {
+	ID string `bson:"_id"`
+    }
+    if err := cursor.All(ctx, &docs); err != nil {
+	return 0, fmt.Errorf("读取任务失败: %w", err)
+    }
+    if len(docs) == 0 {
+	return 0, nil
+    }
+
+    ids := make([]string, 0, len(docs))
+    for _, doc := range docs {
+	ids = append(ids, doc.ID)
+    }
+    inIDs := bson.D{{Key: "_id", Value: bson.D{{Key: "$in", Value: ids}}}}
+
+    // 查询之后状态发生变化的任务（如被重新处理）不删除
+    result, err := s.jobs.DeleteMany(ctx, append(inIDs, filter...))
+    if err != nil {
+	return 0, fmt.Errorf("删除任务失败: %w", err)
+    }
+    if _, err := s.words.DeleteMany(ctx, inIDs); err != nil {
+	return 0, fmt.Errorf("删除单词索引失败: %w", err)
+    }
+    if _, err := s.segments.DeleteMany(ctx, inIDs); err != nil {
+	return 0, fmt.Errorf("删除片段结果失败: %w", err)
+    }
+    return int(result.DeletedCount), nil
+}
+
+// SaveSegment 保存片段结果（job_segments 集合中每个任务一个文档，片段序号为字段名）
+func (s *MongoJobStore) SaveSegment(ctx context.Context, jobID string, index int, data []byte) error {
+    update := bson.D{{Key: "$set", Value: bson.D{
+	{Key: "segments." + strconv.Itoa(index), Value: string(data)},
+	{Key: "updated_at", Value: time.Now()},
+    }}}
+    if _, err := s.segments.UpdateOne(ctx, byID(jobID), update, options.UpdateOne().SetUpsert(true)); err != nil {
+	return fmt.Errorf("保存片段结果失败: %w", err)
+    }
+    return nil
+}
+
+// LoadSegments 读取任务已保存的片段结果
+func (s *MongoJobStore) LoadSegments(ctx context.Context, jobID string) (map[int][]byte, error) {
+    var doc struct {
+	Segments map[string]string `bson:"segments"`
+    }
+    err := s.segments.FindOne(ctx, byID(jobID)).Decode(&doc)
+    if errors.Is(err, mongo.ErrNoDocuments) {
+	return map[int][]byte{}, nil
+    }
+    if err != nil {
+	return nil, fmt.Errorf("读取片段结果失败: %w", err)
+    }
+
+    segments := make(map[int][]byte, len(doc.Segments))
+    for field, data := range doc.Segments {
+	index, err := strconv.Atoi(field)
+	if err != nil {
+	    continue
+	}
+	segments[index] = []byte(data)
+    }
+    return segments, nil
+}
+
+// DeleteSegments 删除任务的片段结果
+func (s *MongoJobStore) DeleteSegments(ctx context.Context, jobID string) error {
+    if _, err := s.segments.DeleteOne(ctx, byID(jobID)); err != nil {
+	return fmt.Errorf("删除片段结果失败: %w", err)
+    }
+    return nil
+}
+
+// IncrUsage 累加月度费用（$inc 原子累加）
+func (s *MongoJobStore) IncrUsage(ctx context.Context, month string, costUSD float64) (float64, error) {
+    update := bson.D{
+	{Key: "$inc", Value: bson.D{{Key: "cost_usd", Value: costUSD}}},
+	{Key: "$set", Value: bson.D{{Key: "updated_at", Value: time.Now()}}},
+    }
+    opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+
+    var doc struct {
+	CostUSD float64 `bson:"cost_usd"`
+    }
+    if err := s.usage.FindOneAndUpdate(ctx, byID(month), update, opts).Decode(&doc); err != nil {
+	return 0, fmt.Errorf("累加费用失败: %w", err)
+    }
+    return doc.CostUSD, nil
+}
+
+// GetUsage 获取月度费用
+func (s *MongoJobStore) GetUsage(ctx context.Context, month string) (float64, error) {
+    var doc struct {
+	CostUSD float64 `bson:"cost_usd"`
+    }
+    err := s.usage.FindOne(ctx, byID(month)).Decode(&doc)
+    if errors.Is(err, mongo.ErrNoDocuments) {
+	return 0, nil
+    }
+    if err != nil {
+	return 0, fmt.Errorf("获取费用失败: %w", err)
+    }
+    return doc.CostUSD, nil
+}
+
+// credentialFilter 凭证的查询条件（user_id + name 唯一索引）
+func credentialFilter(userID, name string) bson.D {
+    return bson.D{{Key: "user_id", Value: userID}, {Key: "name", Value: name}}
+}
+
+// SetCredential 保存用户凭证（已存在时覆盖）
+func (s *MongoJobStore) SetCredential(ctx context.Context, userID, name, value string) error {
+    update := bson.D{{Key: "$set", Value: bson.D{
+	{Key: "value", Value: value},
+	{Key: "updated_at", Value: time.Now()},
+    }}}
+    if _, err := s.credentials.UpdateOne(ctx, credentialFilter(userID, name), update, options.UpdateOne().SetUpsert(true)); err != nil {
+	return fmt.Errorf("保存凭证失败: %w", err)
+    }
+    return nil
+}
+
+// GetCredential 获取用户凭证
+func (s *MongoJobStore) GetCredential(ctx context.Context, userID, name string) (string, error) {
+    var doc struct {
+	Value string `bson:"value"`
+    }
+    err := s.credentials.FindOne(ctx, credentialFilter(userID, name)).Decode(&doc)
+    if errors.Is(err, mongo.ErrNoDocuments) {
+	return "", nil
+    }
+    if err != nil {
+	return "", fmt.Errorf("获取凭证失败: %w", err)
+    }
+    return doc.Value, nil
+}
+
+// DeleteCredential 删除用户凭证
+func (s *MongoJobStore) DeleteCredential(ctx context.Context, userID, name string) error {
+    if _, err := s.credentials.DeleteOne(ctx, credentialFilter(userID, name)); err != nil {
+	return fmt.Errorf("删除凭证失败: %w", err)
+    }
+    return nil
+}
+
+// SetHashIfAbsent 登记文件内容哈希（依赖 _id 唯一约束，多实例并发只有一个成功）
+func (s *MongoJobStore) SetHashIfAbsent(ctx context.Context, hash, jobID string) (string, bool, error) {
+    doc := bson.D{{Key: "_id", Value: hash}, {Key: "job_id", Value: jobID}, {Key: "created_at", Value: time.Now()}}
+
+    // 插入冲突后读取已有值；若恰好被删除则重新插入
+    for attempt := 0; attempt < 3; attempt++ {
+	_, err := s.hashes.InsertOne(ctx, doc)
+	if err == nil {
+	    return jobID, true, nil
+	}
+	if !mongo.IsDuplicateKeyError(err) {
+	    return "", false, fmt.Errorf("登记文件哈希失败: %w", err)
+	}
+
+	var existing struct {
+	    JobID string `bson:"job_id"`
+	}
+	err = s.hashes.FindOne(ctx, byID(hash)).Decode(&existing)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+	    continue
+	}
+	if err != nil {
+	    return "", false, fmt.Errorf("读取文件哈希失败: %w", err)
+	}
+	return existing.JobID, false, nil
+    }
+
+    return "", false, fmt.Errorf("登记文件哈希失败: 并发冲突")
+}
+
+// FindByHash 查找相同文件内容最近一次已完成的任务（content_hash 索引）
+func (s *MongoJobStore) FindByHash(ctx context.Context, hash string) (*models.TranscriptionJob, error) {
+    filter := bson.D{{Key: "content_hash", Value: hash}, {Key: "status", Value: models.StatusCompleted}, mongoNotDeleted}
+
+    var doc mongoJobDocument
+    err := s.jobs.FindOne(ctx, filter, options.FindOne().SetSort(newestFirst)).Decode(&doc)
+    if errors.Is(err, mongo.ErrNoDocuments) {
+	return nil, nil
+    }
+    if err != nil {
+	return nil, fmt.Errorf("按文件哈希查询任务失败: %w", err)
+    }
+    return &doc.TranscriptionJob, nil
+}
+
+// DeleteHash 删除文件内容哈希登记
+func (s *MongoJobStore) DeleteHash(ctx context.Context, hash, jobID string) error {
+    filter := bson.D{{Key: "_id", Value: hash}, {Key: "job_id", Value: jobID}}
+
+    if _, err := s.hashes.DeleteOne(ctx, filter); err != nil {
+	return fmt.Errorf("删除文件哈希失败: %w", err)
+    }
+    return nil
+}
+
+// IndexWords 替换任务的单词索引（整体替换任务的单词文档，words 字段建有多键索引）
+func (s *MongoJobStore) IndexWords(ctx context.Context, jobID string, words []string) error {
+    if words == nil {
+	words = []string{}
+    }
+    doc := bson.D{{Key: "_id", Value: jobID}, {Key: "words", Value: words}}
+
+    if _, err := s.words.ReplaceOne(ctx, byID(jobID), doc, options.Replace().SetUpsert(true)); err != nil {
+	return fmt.Errorf("写入单词索引失败: %w", err)
+    }
+    return nil
+}
+
+// wordsDocument 任务的单词索引文档
+type wordsDocument struct {
+    JobID string   `bson:"_id"`
+    Words []string `bson:"words"`
+}
+
+// JobsByWord 查找包含指定单词的任务
+func (s *MongoJobStore) JobsByWord(ctx context.Context, word string) ([]*models.TranscriptionJob, error) {
+    cursor, err := s.words.Find(ctx, bson.D{{Key: "words", Value: word}}, options.Find().SetProjection(bson.D{{Key: "_id", Value: 1}}))
+    if err != nil {
+	return nil, fmt.Errorf("查询单词索引失败: %w", err)
+    }
+    var docs []wordsDocument
+    if err := cursor.All(ctx, &docs); err != nil {
+	return nil, fmt.Errorf("查询单词索引失败: %w", err)
+    }
+    if len(docs) == 0 {
+	return []*models.TranscriptionJob{}, nil
+    }
+
+    ids := make([]string, 0, len(docs))
+    for _, doc := range docs {
+	ids = append(ids, doc.JobID)
+    }
+    filter := bson.D{{Key: "_id", Value: bson.D{{Key: "$in", Value: ids}}}, mongoNotDeleted}
+    return s.findJobs(ctx, filter, options.Find().SetSort(newestFirst).SetLimit(searchLimit))
+}
+
+// ListVocabulary 列出所有任务已提取的单词（跳过软删除的任务）
+func (s *MongoJobStore) ListVocabulary(ctx context.Context) (map[string][]string, error) {
+    deletedCursor, err := s.jobs.Find(ctx, bson.D{{Key: "deleted_at", Value: deletedAfter}}, options.Find().SetProjection(bson.D{{Key: "_id", Value: 1}}))
+    if err != nil {
+	return nil, fmt.Errorf("查询 MongoDB 失败: %w", err)
+    }
+    var deletedDocs []struct {
+	ID string `bson:"_id"`
+    }
+    if err := deletedCursor.All(ctx, &deletedDocs); err != nil {
+	return nil, fmt.Errorf("读取任务失败: %w", err)
+    }
+    deleted := make([]string, 0, len(deletedDocs))
+    for _, doc := range deletedDocs {
+	deleted = append(deleted, doc.ID)
+    }
+
+    cursor, err := s.words.Find(ctx, bson.D{{Key: "_id", Value: bson.D{{Key: "$nin", Value: deleted}}}})
+    if err != nil {
+	return nil, fmt.Errorf("查询单词索引失败: %w", err)
+    }
+    var docs []wordsDocument
+    if err := cursor.All(ctx, &docs); err != nil {
+	return nil, fmt.Errorf("读取单词索引失败: %w", err)
+    }
+
+    result := make(map[string][]string, len(docs))
+    for _, doc := range docs {
+	if len(doc.Words) > 0 {
+	    result[doc.JobID] = doc.Words
+	}
+    }
+    return result, nil
+}
+
+// Ping 检查 MongoDB 连接是否可用
+func (s *MongoJobStore) Ping(ctx context.Context) error {
+    ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+    defer cancel()
+
+    if err := s.client.Ping(ctx, nil); err != nil {
+	return fmt.Errorf("MongoDB 连接不可用: %w", err)
+    }
+    return nil
+}
+
+// Close 断开 MongoDB 连接
+func (s *MongoJobStore) Close() error {
+    ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+    defer cancel()
+
+    return s.client.Disconnect(ctx)
+}
@@ -0,0 +1,15 @@
+//go:build !mongo
+
+package storage
+
+import "fmt"
+
+// MongoJobStore 未使用 mongo 构建标签编译时的占位类型
+type MongoJobStore struct {
+    Store
+}
+
+// NewMongoJobStore 未使用 mongo 构建标签编译，不包含 MongoDB 客户端
+func NewMongoJobStore(opts MongoOptions) (*MongoJobStore, error) {
+    return nil, fmt.Errorf("当前程序未包含 MongoDB 支持，请使用 go build -tags mongo 重新编译")
+}
@@ -1,20 +1,34 @@
 package storage
 
 import (
+    "context"
     "database/sql"
     "encoding/json"
+    "errors"
     "fmt"
+    "log/slog"
+    "strings"
+    "time"
 
-    _ "github.com/lib/pq"
+    "github.com/lib/pq"
+    "github.com/z-wentao/voiceflow/migrations"
     "github.com/z-wentao/voiceflow/pkg/models"
 )
 
 type PostgresJobStore struct {
-    db *sql.DB
+    db        *sql.DB
+    listLimit int // List 返回的最大任务数
 }
 
+// defaultPostgresListLimit List 默认返回的任务数
+const defaultPostgresListLimit = 100
+
+// postgresPageSize ListAll / ForEach 每页读取的任务数
+const postgresPageSize = 500
+
 // NewPostgresJobStore 创建 PostgreSQL 任务存储
-func NewPostgresJobStore(connStr string) (*PostgresJobStore, error) {
+// migrate 为 true 时执行尚未应用的迁移（新数据库从零建表）
+func NewPostgresJobStore(connStr string, migrate bool) (*PostgresJobStore, error) {
     db, err := sql.Open("postgres", connStr)
     if err != nil {
 	return nil, fmt.Errorf("打开数据库连接失败: %w", err)
@@ -29,30 +43,46 @@ func NewPostgresJobStore(connStr string) (*PostgresJobStore, error) {
     db.SetMaxOpenConns(25)
     db.SetMaxIdleConns(5)
 
-    return &PostgresJobStore{db: db}, nil
-}
-
-func (s *PostgresJobStore) Save(job *models.TranscriptionJob) error {
-    vocabularyJSON, err := json.Marshal(job.Vocabulary)
-    if err != nil {
-	return fmt.Errorf("序列化 vocabulary 失败: %w", err)
+    if migrate {
+	applied, err := migratePostgres(context.Background(), db, migrations.FS)
+	if err != nil {
+	    db.Close()
+	    return nil, fmt.Errorf("数据库迁移失败: %w", err)
+	}
+	if applied > 0 {
+	    slog.Info("✓ 数据库迁移完成", "applied", applied)
+	}
     }
 
-    vocabDetailJSON, err := json.Marshal(job.VocabDetail)
-    if err != nil {
-	return fmt.Errorf("序列化 vocab_detail 失败: %w", err)
+    return &PostgresJobStore{db: db, listLimit: defaultPostgresListLimit}, nil
+}
+
+// SetListLimit 设置 List 返回的最大任务数（<= 0 时使用默认值 100）
+func (s *PostgresJobStore) SetListLimit(limit int) {
+    if limit <= 0 {
+	limit = defaultPostgresListLimit
     }
+    s.listLimit = limit
+}
 
-    // UPSERT method
-    query := `
-    INSERT INTO transcription_jobs (
-    job_id, filename, file_path, status, progress,
+// postgresJobColumns 查询/写入任务时使用的列（顺序与 scanPostgresJob 一致）
+const postgresJobColumns = `job_id, filename, file_path, status, progress,
     result, subtitle_path, vtt_path, bilingual_srt_path, bilingual_vtt_path,
     language, duration, error,
-    vocabulary, vocab_detail, created_at, completed_at
-    ) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17)
+    vocabulary, vocab_detail, created_at, completed_at, media_purged, source, sync_history, attempts, content_hash, priority, user_id,
+    last_updated, file_size, prompt, temperature, model, retry_count, next_attempt_at,
+    estimated_cost, prompt_tokens, completion_tokens, deleted_at, version`
+
+// postgresJobColumnCount 每个任务写入的参数个数（与 postgresJobColumns 一致）
+var postgresJobColumnCount = len(strings.Split(postgresJobColumns, ","))
+
+// postgresUpsertConflict UPSERT 语句的冲突处理部分（Save 和 BatchSave 共用）
+// 写入的 version 参数为期望的版本号 + 1（见 postgresJobArgs）：为 1 时直接覆盖，
+// 否则只在数据库中的版本号等于期望值时覆盖，不满足条件时不更新也不返回行
+const postgresUpsertConflict = `
     ON CONFLICT (job_id)
     DO UPDATE SET
+    file_path = EXCLUDED.file_path,
     status = EXCLUDED.status,
     progress = EXCLUDED.progress,
     result = EXCLUDED.result,
@@ -65,10 +95,180 @@ func (s *PostgresJobStore) Save(job *models.TranscriptionJob) error {
     error = EXCLUDED.error,
     vocabulary = EXCLUDED.vocabulary,
     vocab_detail = EXCLUDED.vocab_detail,
-    completed_at = EXCLUDED.completed_at
+    completed_at = EXCLUDED.completed_at,
+    media_purged = EXCLUDED.media_purged,
+    sync_history = EXCLUDED.sync_history,
+    attempts = EXCLUDED.attempts,
+    content_hash = EXCLUDED.content_hash,
+    priority = EXCLUDED.priority,
+    user_id = EXCLUDED.user_id,
+    last_updated = EXCLUDED.last_updated,
+    file_size = EXCLUDED.file_size,
+    prompt = EXCLUDED.prompt,
+    temperature = EXCLUDED.temperature,
+    model = EXCLUDED.model,
+    retry_count = EXCLUDED.retry_count,
+    next_attempt_at = EXCLUDED.next_attempt_at,
+    estimated_cost = EXCLUDED.estimated_cost,
+    prompt_tokens = EXCLUDED.prompt_tokens,
+    completion_tokens = EXCLUDED.completion_tokens,
+    deleted_at = EXCLUDED.deleted_at,
+    version = transcription_jobs.version + 1,
+    search_vector = EXCLUDED.search_vector
+    WHERE EXCLUDED.version = 1 OR transcription_jobs.version = EXCLUDED.version - 1
     `
 
-    _, err = s.db.Exec(query,
+// postgresBatchSize 一条多行 UPSERT 最多包含的任务数（PostgreSQL 单条语句最多 65535 个参数）
+const postgresBatchSize = 500
+
+// Save 保存任务（UPSERT，job.Version > 0 时检查版本号）
+func (s *PostgresJobStore) Save(ctx context.Context, job *models.TranscriptionJob) error {
+    version, err := s.upsert(ctx, job, job.Version)
+    if err != nil {
+	return err
+    }
+    job.Version = version
+    return nil
+}
+
+// upsert 写入一个任务，返回写入后的版本号
+// expected > 0 时只在数据库中的版本号等于 expected 时覆盖已有任务，否则返回 ErrVersionConflict
+func (s *PostgresJobStore) upsert(ctx context.Context, job *models.TranscriptionJob, expected int64) (int64, error) {
+    args, err := postgresJobArgs(job, expected)
+    if err != nil {
+	return 0, err
+    }
+
+    query := `
+    INSERT INTO transcription_jobs (` + postgresJobColumns + `, search_vector
+    ) VALUES ` + postgresJobPlaceholders(0) + postgresUpsertConflict + `
+    RETURNING version`
+
+    var version int64
+    err = s.db.QueryRowContext(ctx, query, args...).Scan(&version)
+    if err == sql.ErrNoRows {
+	return 0, versionConflict(job.JobID)
+    }
+    if err != nil {
+	return 0, fmt.Errorf("保存到数据库失败: %w", err)
+    }
+
+    return version, nil
+}
+
+// BatchSave 批量保存任务：每 postgresBatchSize 个任务一条多行 UPSERT，一次往返写入一批
+// 1. 同一任务出现多次时只写入最后一次（多行 UPSERT 不能在一条语句中两次更新同一行）
+// 2. 整批写入失败时（如某个任务的数据不合法）逐个重试，失败的任务通过 *BatchSaveError 返回，其他任务照常写入
+// 3. 不检查版本号（直接覆盖），也不修改传入任务的 Version
+func (s *PostgresJobStore) BatchSave(ctx context.Context, jobs []*models.TranscriptionJob) error {
+    jobs = latestJobs(jobs)
+    failed := make(map[string]error)
+
+    for start := 0; start < len(jobs); start += postgresBatchSize {
+	end := min(start+postgresBatchSize, len(jobs))
+	s.upsertBatch(ctx, jobs[start:end], failed)
+    }
+
+    if len(failed) > 0 {
+	return &BatchSaveError{Failed: failed}
+    }
+    return nil
+}
+
+// upsertBatch 用一条多行 UPSERT 写入一批任务，失败的任务记录到 failed
+func (s *PostgresJobStore) upsertBatch(ctx context.Context, jobs []*models.TranscriptionJob, failed map[string]error) {
+    valid := make([]*models.TranscriptionJob, 0, len(jobs))
+    args := make([]any, 0, len(jobs)*postgresJobColumnCount)
+    placeholders := make([]string, 0, len(jobs))
+    for _, job := range jobs {
+	jobArgs, err := postgresJobArgs(job, 0)
+	if err != nil {
+	    failed[job.JobID] = err
+	    continue
+	}
+	placeholders = append(placeholders, postgresJobPlaceholders(len(args)))
+	args = append(args, jobArgs...)
+	valid = append(valid, job)
+    }
+    if len(valid) == 0 {
+	return
+    }
+
+    query := `
+    INSERT INTO transcription_jobs (` + postgresJobColumns + `, search_vector
+    ) VALUES ` + strings.Join(placeholders, ",\n    ") + postgresUpsertConflict
+
+    _, err := s.db.ExecContext(ctx, query, args...)
+    if err == nil {
+	return
+    }
+
+    // 超时或取消时逐个重试也会失败，整批记为失败
+    if ctx.Err() != nil {
+	for _, job := range valid {
+	    failed[job.JobID] = fmt.Errorf("保存到数据库失败: %w", err)
+	}
+	return
+    }
+
+    // 多行 UPSERT 是原子的：一个任务出错整条语句回滚，逐个重试找出出错的任务
+    slog.Warn("⚠️ 批量写入任务失败，逐个重试", "jobs", len(valid), "error", err)
+    for _, job := range valid {
+	if _, err := s.upsert(ctx, job, 0); err != nil {
+	    failed[job.JobID] = err
+	}
+    }
+}
+
+// latestJobs 去除重复的任务（按任务 ID），保留最后一次出现的版本，顺序不变
+func latestJobs(jobs []*models.TranscriptionJob) []*models.TranscriptionJob {
+    last := make(map[string]int, len(jobs))
+    for i, job := range jobs {
+	last[job.JobID] = i
+    }
+    if len(last) == len(jobs) {
+	return jobs
+    }
+
+    unique := make([]*models.TranscriptionJob, 0, len(last))
+    for i, job := range jobs {
+	if last[job.JobID] == i {
+	    unique = append(unique, job)
+	}
+    }
+    return unique
+}
+
+// postgresJobPlaceholders 一个任务的 VALUES 占位符，参数编号从 offset+1 开始
+// 最后一列 search_vector 由文件名（第 2 个参数）和转录文本（第 6 个参数）生成
+func postgresJobPlaceholders(offset int) string {
+    var b strings.Builder
+    b.WriteString("(")
+    for i := 1; i <= postgresJobColumnCount; i++ {
+	fmt.Fprintf(&b, "$%d, ", offset+i)
+    }
+    fmt.Fprintf(&b, "to_tsvector('english', $%d || ' ' || $%d))", offset+2, offset+6)
+    return b.String()
+}
+
+// postgresJobArgs 任务的写入参数（顺序与 postgresJobColumns 一致），expected 为期望的版本号（0 表示不检查）
+func postgresJobArgs(job *models.TranscriptionJob, expected int64) ([]any, error) {
+    vocabularyJSON, err := json.Marshal(job.Vocabulary)
+    if err != nil {
+	return nil, fmt.Errorf("序列化 vocabulary 失败: %w", err)
+    }
+
+    vocabDetailJSON, err := json.Marshal(job.VocabDetail)
+    if err != nil {
+	return nil, fmt.Errorf("序列化 vocab_detail 失败: %w", err)
+    }
+
+    syncHistoryJSON, err := json.Marshal(job.SyncHistory)
+    if err != nil {
+	return nil, fmt.Errorf("序列化 sync_history 失败: %w", err)
+    }
+
+    return []any{
 	job.JobID,
 	job.Filename,
 	job.FilePath,
@@ -86,34 +286,39 @@ func (s *PostgresJobStore) Save(job *models.TranscriptionJob) error {
 	vocabDetailJSON,
 	job.CreatedAt,
 	job.CompletedAt,
-	)
-
-    if err != nil {
-	return fmt.Errorf("保存到数据库失败: %w", err)
-    }
-
-    return nil
+	job.MediaPurged,
+	job.Source,
+	syncHistoryJSON,
+	job.Attempts,
+	job.ContentHash,
+	job.Priority,
+	job.UserID,
+	job.LastUpdated,
+	job.FileSize,
+	job.Prompt,
+	job.Temperature,
+	job.Model,
+	job.RetryCount,
+	nullTime(job.NextAttemptAt),
+	job.EstimatedCost,
+	job.PromptTokens,
+	job.CompletionTokens,
+	nullTime(job.DeletedAt),
+	expected + 1,
+    }, nil
 }
 
-// Get 获取任务
-func (s *PostgresJobStore) Get(jobID string) (*models.TranscriptionJob, error) {
-    query := `
-    SELECT job_id, filename, file_path, status, progress,
-    result, subtitle_path, vtt_path, bilingual_srt_path, bilingual_vtt_path,
-    language, duration, error,
-    vocabulary, vocab_detail, created_at, completed_at
-    FROM transcription_jobs
-    WHERE job_id = $1
-    `
-
+// scanPostgresJob 扫描一行任务数据并处理 NULL 值
+func scanPostgresJob(row scanner) (*models.TranscriptionJob, error) {
     var job models.TranscriptionJob
-    var vocabularyJSON, vocabDetailJSON []byte
+    var vocabularyJSON, vocabDetailJSON, syncHistoryJSON []byte
     var result, subtitlePath, vttPath, bilingualSRTPath, bilingualVTTPath, language, errorMsg sql.NullString
-    var filePath sql.NullString
-    var duration sql.NullFloat64
-    var completedAt sql.NullTime
+    var filePath, source, contentHash, userID, prompt, model sql.NullString
+    var duration, temperature sql.NullFloat64
+    var fileSize sql.NullInt64
+    var completedAt, lastUpdated, nextAttemptAt, deletedAt sql.NullTime
 
-    err := s.db.QueryRow(query, jobID).Scan(
+    err := row.Scan(
 	&job.JobID,
 	&job.Filename,
 	&filePath,
@@ -131,19 +336,43 @@ func (s *PostgresJobStore) Get(jobID string) (*models.TranscriptionJob, error) {
 	&vocabDetailJSON,
 	&job.CreatedAt,
 	&completedAt,
+	&job.MediaPurged,
+	&source,
+	&syncHistoryJSON,
+	&job.Attempts,
+	&contentHash,
+	&job.Priority,
+	&userID,
+	&lastUpdated,
+	&fileSize,
+	&prompt,
+	&temperature,
+	&model,
+	&job.RetryCount,
+	&nextAttemptAt,
+	&job.EstimatedCost,
+	&job.PromptTokens,
+	&job.CompletionTokens,
+	&deletedAt,
+	&job.Version,
 	)
-
-    if err == sql.ErrNoRows {
-	return nil, fmt.Errorf("任务不存在: %s", jobID)
-    }
     if err != nil {
-	return nil, fmt.Errorf("查询数据库失败: %w", err)
+	return nil, err
     }
 
     // 处理 NULL 值
     if filePath.Valid {
 	job.FilePath = filePath.String
     }
+    if source.Valid {
+	job.Source = source.String
+    }
+    if contentHash.Valid {
+	job.ContentHash = contentHash.String
+    }
+    if userID.Valid {
+	job.UserID = userID.String
+    }
     if result.Valid {
 	job.Result = result.String
     }
@@ -165,12 +394,33 @@ func (s *PostgresJobStore) Get(jobID string) (*models.TranscriptionJob, error) {
     if duration.Valid {
 	job.Duration = duration.Float64
     }
+    if fileSize.Valid {
+	job.FileSize = fileSize.Int64
+    }
+    if prompt.Valid {
+	job.Prompt = prompt.String
+    }
+    if temperature.Valid {
+	job.Temperature = temperature.Float64
+    }
+    if model.Valid {
+	job.Model = model.String
+    }
     if errorMsg.Valid {
 	job.Error = errorMsg.String
     }
     if completedAt.Valid {
 	job.CompletedAt = completedAt.Time
     }
+    if lastUpdated.Valid {
+	job.LastUpdated = lastUpdated.Time
+    }
+    if nextAttemptAt.Valid {
+	job.NextAttemptAt = nextAttemptAt.Time
+    }
+    if deletedAt.Valid {
+	job.DeletedAt = deletedAt.Time
+    }
 
     // 反序列化 JSON 字段
     if len(vocabularyJSON) > 0 {
@@ -179,38 +429,70 @@ func (s *PostgresJobStore) Get(jobID string) (*models.TranscriptionJob, error) {
     if len(vocabDetailJSON) > 0 {
 	json.Unmarshal(vocabDetailJSON, &job.VocabDetail)
     }
+    if len(syncHistoryJSON) > 0 {
+	json.Unmarshal(syncHistoryJSON, &job.SyncHistory)
+    }
 
     return &job, nil
 }
 
-// Update 更新任务
-func (s *PostgresJobStore) Update(jobID string, updateFn func(*models.TranscriptionJob)) error {
-    // 1. 获取现有任务
-    job, err := s.Get(jobID)
+// Get 获取任务
+func (s *PostgresJobStore) Get(ctx context.Context, jobID string) (*models.TranscriptionJob, error) {
+    query := `SELECT ` + postgresJobColumns + ` FROM transcription_jobs WHERE job_id = $1`
+
+    job, err := scanPostgresJob(s.db.QueryRowContext(ctx, query, jobID))
+    if err == sql.ErrNoRows {
+	// 任务表中没有时查询归档表（已归档的旧任务仍然可以查看和下载）
+	query = `SELECT ` + postgresJobColumns + ` FROM transcription_jobs_archive WHERE job_id = $1`
+	job, err = scanPostgresJob(s.db.QueryRowContext(ctx, query, jobID))
+    }
+    if err == sql.ErrNoRows {
+	return nil, fmt.Errorf("任务不存在: %s", jobID)
+    }
     if err != nil {
-	return err
+	return nil, fmt.Errorf("查询数据库失败: %w", err)
     }
 
-    // 2. 执行更新函数
-    updateFn(job)
+    return job, nil
+}
+
+// postgresUpdateRetries Update 遇到版本冲突时的最大重试次数
+const postgresUpdateRetries = 10
+
+// Update 更新任务
+// 使用版本号乐观锁（不持有行锁）：读取任务、执行更新函数，写回时要求版本号未变；
+// 并发的 Update（如 Worker 写进度的同时另一个 Worker 标记失败）在冲突时重新读取并重试，不会互相覆盖
+func (s *PostgresJobStore) Update(ctx context.Context, jobID string, updateFn func(*models.TranscriptionJob)) error {
+    for i := 0; i < postgresUpdateRetries; i++ {
+	// 1. 获取现有任务
+	job, err := s.Get(ctx, jobID)
+	if err != nil {
+	    return err
+	}
+
+	// 2. 执行更新函数
+	updateFn(job)
 
-    // 3. 保存回数据库
-    return s.Save(job)
+	// 3. 版本号未变时写回
+	_, err = s.upsert(ctx, job, job.Version)
+	if !errors.Is(err, ErrVersionConflict) {
+	    return err
+	}
+    }
+    return fmt.Errorf("更新任务失败: 并发修改冲突，已重试 %d 次", postgresUpdateRetries)
 }
 
-// List 列出所有任务（按创建时间倒序）
-func (s *PostgresJobStore) List() ([]*models.TranscriptionJob, error) {
+// List 列出最近的任务（按创建时间倒序，最多 listLimit 个）
+func (s *PostgresJobStore) List(ctx context.Context) ([]*models.TranscriptionJob, error) {
     query := `
-    SELECT job_id, filename, file_path, status, progress,
-    result, subtitle_path, vtt_path, bilingual_srt_path, bilingual_vtt_path,
-    language, duration, error,
-    vocabulary, vocab_detail, created_at, completed_at
+    SELECT ` + postgresJobColumns + `
     FROM transcription_jobs
+    WHERE ` + notDeleted + `
     ORDER BY created_at DESC
-    LIMIT 100
+    LIMIT $1
     `
 
-    rows, err := s.db.Query(query)
+    rows, err := s.db.QueryContext(ctx, query, s.listLimit)
     if err != nil {
 	return nil, fmt.Errorf("查询数据库失败: %w", err)
     }
@@ -219,92 +501,373 @@ func (s *PostgresJobStore) List() ([]*models.TranscriptionJob, error) {
     jobs := make([]*models.TranscriptionJob, 0)
 
     for rows.Next() {
-	var job models.TranscriptionJob
-	var vocabularyJSON, vocabDetailJSON []byte
-	var result, subtitlePath, vttPath, bilingualSRTPath, bilingualVTTPath, language, errorMsg sql.NullString
-	var filePath sql.NullString
-	var duration sql.NullFloat64
-	var completedAt sql.NullTime
-
-	err := rows.Scan(
-	    &job.JobID,
-	    &job.Filename,
-	    &filePath,
-	    &job.Status,
-	    &job.Progress,
-	    &result,
-	    &subtitlePath,
-	    &vttPath,
-	    &bilingualSRTPath,
-	    &bilingualVTTPath,
-	    &language,
-	    &duration,
-	    &errorMsg,
-	    &vocabularyJSON,
-	    &vocabDetailJSON,
-	    &job.CreatedAt,
-	    &completedAt,
-	    )
-
+	job, err := scanPostgresJob(rows)
 	if err != nil {
 	    continue
 	}
+	jobs = append(jobs, job)
+    }
 
-	// 处理 NULL 值
-	if filePath.Valid {
-	    job.FilePath = filePath.String
-	}
-	if result.Valid {
-	    job.Result = result.String
+    return jobs, nil
+}
+
+// ListAll 列出所有任务（按创建时间倒序，不限制数量）
+func (s *PostgresJobStore) ListAll(ctx context.Context) ([]*models.TranscriptionJob, error) {
+    jobs := make([]*models.TranscriptionJob, 0)
+    err := s.ForEach(ctx, func(job *models.TranscriptionJob) error {
+	jobs = append(jobs, job)
+	return nil
+    })
+    if err != nil {
+	return nil, err
+    }
+    return jobs, nil
+}
+
+// ForEach 按创建时间倒序遍历所有任务
+// 使用 (created_at, job_id) 的 keyset 分页，每次查询一页（postgresPageSize 个），不会一次加载全部任务；
+// 翻页不依赖 OFFSET，遍历期间新增或删除任务不会导致重复或跳过已有的任务。fn 返回错误时停止遍历
+func (s *PostgresJobStore) ForEach(ctx context.Context, fn func(*models.TranscriptionJob) error) error {
+    var last *models.TranscriptionJob
+    for {
+	page, err := s.listPage(ctx, last)
+	if err != nil {
+	    return err
 	}
-	if subtitlePath.Valid {
-	    job.SubtitlePath = subtitlePath.String
+	for _, job := range page {
+	    if err := fn(job); err != nil {
+		return err
+	    }
 	}
-	if vttPath.Valid {
-	    job.VTTPath = vttPath.String
+	if len(page) < postgresPageSize {
+	    return nil
 	}
-	if bilingualSRTPath.Valid {
-	    job.BilingualSRTPath = bilingualSRTPath.String
+	last = page[len(page)-1]
+    }
+}
+
+// listPage 查询 after 之后的一页任务（after 为 nil 时从最新的任务开始）
+// 先读完整页再返回，调用方处理任务期间不占用数据库连接
+func (s *PostgresJobStore) listPage(ctx context.Context, after *models.TranscriptionJob) ([]*models.TranscriptionJob, error) {
+    var rows *sql.Rows
+    var err error
+    if after == nil {
+	query := `SELECT ` + postgresJobColumns + ` FROM transcription_jobs
+	WHERE ` + notDeleted + `
+	ORDER BY created_at DESC, job_id DESC
+	LIMIT $1`
+	rows, err = s.db.QueryContext(ctx, query, postgresPageSize)
+    } else {
+	query := `SELECT ` + postgresJobColumns + ` FROM transcription_jobs
+	WHERE (created_at, job_id) < ($1, $2) AND ` + notDeleted + `
+	ORDER BY created_at DESC, job_id DESC
+	LIMIT $3`
+	rows, err = s.db.QueryContext(ctx, query, after.CreatedAt, after.JobID, postgresPageSize)
+    }
+    if err != nil {
+	return nil, fmt.Errorf("查询数据库失败: %w", err)
+    }
+    defer rows.Close()
+
+    page := make([]*models.TranscriptionJob, 0, postgresPageSize)
+    for rows.Next() {
+	// 无法解析的行不能跳过：下一页的游标取自本页最后一行
+	job, err := scanPostgresJob(rows)
+	if err != nil {
+	    return nil, fmt.Errorf("读取任务失败: %w", err)
 	}
-	if bilingualVTTPath.Valid {
-	    job.BilingualVTTPath = bilingualVTTPath.String
+	page = append(page, job)
+    }
+    return page, rows.Err()
+}
+
+// ListForUser 列出指定用户的任务（user_id 索引，按创建时间倒序）
+func (s *PostgresJobStore) ListForUser(ctx context.Context, userID string, limit int) ([]*models.TranscriptionJob, error) {
+    // LIMIT NULL 表示不限制
+    query := `
+    SELECT ` + postgresJobColumns + `
+    FROM transcription_jobs
+    WHERE user_id = $1 AND ` + notDeleted + `
+    ORDER BY created_at DESC
+    LIMIT NULLIF($2, 0)
+    `
+
+    if limit < 0 {
+	limit = 0
+    }
+    rows, err := s.db.QueryContext(ctx, query, userID, limit)
+    if err != nil {
+	return nil, fmt.Errorf("查询数据库失败: %w", err)
+    }
+    defer rows.Close()
+
+    jobs := make([]*models.TranscriptionJob, 0)
+    for rows.Next() {
+	job, err := scanPostgresJob(rows)
+	if err != nil {
+	    continue
 	}
-	if language.Valid {
-	    job.Language = language.String
+	jobs = append(jobs, job)
+    }
+
+    return jobs, rows.Err()
+}
+
+// ListSummaries 列出指定状态任务的轻量投影（只查询投影需要的列）
+func (s *PostgresJobStore) ListSummaries(ctx context.Context, statuses ...models.JobStatus) ([]models.JobSummary, error) {
+    condition, args := statusCondition(statuses, func(n int) string { return fmt.Sprintf("$%d", n) })
+    query := `SELECT ` + summaryColumns + ` FROM transcription_jobs WHERE ` + condition + ` AND ` + notDeleted + ` ORDER BY created_at DESC`
+
+    rows, err := s.db.QueryContext(ctx, query, args...)
+    if err != nil {
+	return nil, fmt.Errorf("查询数据库失败: %w", err)
+    }
+    return scanSummaries(rows)
+}
+
+// CountByStatus 按状态统计任务数量（GROUP BY status）
+func (s *PostgresJobStore) CountByStatus(ctx context.Context, userID string) (map[models.JobStatus]int, error) {
+    return countStatuses(ctx, s.db, userID, "$1")
+}
+
+// Stats 汇总任务统计（一条聚合查询）
+func (s *PostgresJobStore) Stats(ctx context.Context, userID string, since time.Time) (models.JobStats, error) {
+    return queryStats(ctx, s.db, userID, since, postgresPlaceholder)
+}
+
+// DeleteOlderThan 删除创建时间早于 t 的已结束任务及其单词索引（包括已归档的任务）
+func (s *PostgresJobStore) DeleteOlderThan(ctx context.Context, t time.Time) (int, error) {
+    deleted, err := deleteOlderThan(ctx, s.db, t, postgresPlaceholder)
+    if err != nil {
+	return 0, err
+    }
+
+    archived, err := s.deleteArchivedOlderThan(ctx, t)
+    if err != nil {
+	return deleted, err
+    }
+    return deleted + archived, nil
+}
+
+// deleteArchivedOlderThan 删除创建时间早于 t 的归档任务及其单词索引
+func (s *PostgresJobStore) deleteArchivedOlderThan(ctx context.Context, t time.Time) (int, error) {
+    tx, err := s.db.BeginTx(ctx, nil)
+    if err != nil {
+	return 0, fmt.Errorf("开启事务失败: %w", err)
+    }
+    defer tx.Rollback()
+
+    if _, err := tx.ExecContext(ctx, `DELETE FROM job_words WHERE job_id IN (SELECT job_id FROM transcription_jobs_archive WHERE created_at < $1)`, t); err != nil {
+	return 0, fmt.Errorf("删除单词索引失败: %w", err)
+    }
+    result, err := tx.ExecContext(ctx, `DELETE FROM transcription_jobs_archive WHERE created_at < $1`, t)
+    if err != nil {
+	return 0, fmt.Errorf("删除归档任务失败: %w", err)
+    }
+    deleted, err := result.RowsAffected()
+    if err != nil {
+	return 0, fmt.Errorf("获取删除结果失败: %w", err)
+    }
+
+    if err := tx.Commit(); err != nil {
+	return 0, fmt.Errorf("提交事务失败: %w", err)
+    }
+    return int(deleted), nil
+}
+
+// postgresArchiveBatchSize 每个归档事务移动的任务数（避免一个事务锁住大量行）
+const postgresArchiveBatchSize = 500
+
+// MoveToArchive 把完成时间早于 olderThan 的已完成任务移到归档表，返回归档的任务数
+// 每批任务在一个事务中复制到归档表并从任务表删除（片段结果一并删除，单词索引保留，单词本中仍然可以看到）；
+// 软删除的任务不归档，由清理器按删除保留期永久删除
+func (s *PostgresJobStore) MoveToArchive(ctx context.Context, olderThan time.Time) (int, error) {
+    total := 0
+    for {
+	moved, err := s.archiveBatch(ctx, olderThan)
+	total += moved
+	if err != nil {
+	    return total, err
 	}
-	if duration.Valid {
-	    job.Duration = duration.Float64
+	if moved < postgresArchiveBatchSize {
+	    return total, nil
 	}
-	if errorMsg.Valid {
-	    job.Error = errorMsg.String
+    }
+}
+
+// archiveBatch 在一个事务中归档一批任务
+// 归档表中已有的同一任务（归档后又被修改、重新写入任务表的任务）先删除，再写入最新的版本
+func (s *PostgresJobStore) archiveBatch(ctx context.Context, olderThan time.Time) (int, error) {
+    tx, err := s.db.BeginTx(ctx, nil)
+    if err != nil {
+	return 0, fmt.Errorf("开启事务失败: %w", err)
+    }
+    defer tx.Rollback()
+
+    // 1. 锁定这一批任务（其他实例同时归档时跳过已锁定的行）
+    rows, err := tx.QueryContext(ctx, `SELECT job_id FROM transcription_jobs
+	WHERE status = $1 AND completed_at < $2 AND `+notDeleted+`
+	ORDER BY completed_at
+	LIMIT $3
+	FOR UPDATE SKIP LOCKED`, models.StatusCompleted, olderThan, postgresArchiveBatchSize)
+    if err != nil {
+	return 0, fmt.Errorf("查询待归档任务失败: %w", err)
+    }
+    var ids []string
+    for rows.Next() {
+	var id string
+	if err := rows.Scan(&id); err != nil {
+	    rows.Close()
+	    return 0, fmt.Errorf("读取待归档任务失败: %w", err)
 	}
-	if completedAt.Valid {
-	    job.CompletedAt = completedAt.Time
+	ids = append(ids, id)
+    }
+    rows.Close()
+    if err := rows.Err(); err != nil {
+	return 0, fmt.Errorf("读取待归档任务失败: %w", err)
+    }
+    if len(ids) == 0 {
+	return 0, nil
+    }
+
+    // 2. 复制到归档表，再从任务表删除
+    statements := []struct {
+	query string
+	desc  string
+    }{
+	{`DELETE FROM transcription_jobs_archive WHERE job_id = ANY($1)`, "删除旧的归档记录"},
+	{`INSERT INTO transcription_jobs_archive (` + postgresJobColumns + `, search_vector)
+	    SELECT ` + postgresJobColumns + `, search_vector FROM transcription_jobs WHERE job_id = ANY($1)`, "写入归档表"},
+	{`DELETE FROM job_segments WHERE job_id = ANY($1)`, "删除片段结果"},
+	{`DELETE FROM transcription_jobs WHERE job_id = ANY($1)`, "删除已归档的任务"},
+    }
+    for _, stmt := range statements {
+	if _, err := tx.ExecContext(ctx, stmt.query, pq.Array(ids)); err != nil {
+	    return 0, fmt.Errorf("%s失败: %w", stmt.desc, err)
 	}
+    }
+
+    if err := tx.Commit(); err != nil {
+	return 0, fmt.Errorf("提交事务失败: %w", err)
+    }
+    return len(ids), nil
+}
+
+// unarchive 把归档的任务移回任务表（任务未归档时不做任何操作）
+// 任务表中已有同一任务（归档后又被修改过）时保留任务表中的版本
+func (s *PostgresJobStore) unarchive(ctx context.Context, jobID string) error {
+    query := `WITH moved AS (
+	DELETE FROM transcription_jobs_archive WHERE job_id = $1
+	RETURNING ` + postgresJobColumns + `, search_vector
+    )
+    INSERT INTO transcription_jobs (` + postgresJobColumns + `, search_vector)
+    SELECT ` + postgresJobColumns + `, search_vector FROM moved
+    ON CONFLICT (job_id) DO NOTHING`
+
+    if _, err := s.db.ExecContext(ctx, query, jobID); err != nil {
+	return fmt.Errorf("恢复归档任务失败: %w", err)
+    }
+    return nil
+}
+
+// ListByStatus 列出指定状态的任务（按创建时间正序）
+func (s *PostgresJobStore) ListByStatus(ctx context.Context, statuses ...models.JobStatus) ([]*models.TranscriptionJob, error) {
+    condition, args := statusCondition(statuses, func(n int) string { return fmt.Sprintf("$%d", n) })
+    query := `SELECT ` + postgresJobColumns + ` FROM transcription_jobs WHERE ` + condition + ` AND ` + notDeleted + ` ORDER BY created_at ASC`
+
+    rows, err := s.db.QueryContext(ctx, query, args...)
+    if err != nil {
+	return nil, fmt.Errorf("查询数据库失败: %w", err)
+    }
+    defer rows.Close()
 
-	// 反序列化 JSON 字段
-	if len(vocabularyJSON) > 0 {
-	    json.Unmarshal(vocabularyJSON, &job.Vocabulary)
+    jobs := make([]*models.TranscriptionJob, 0)
+    for rows.Next() {
+	job, err := scanPostgresJob(rows)
+	if err != nil {
+	    continue
 	}
-	if len(vocabDetailJSON) > 0 {
-	    json.Unmarshal(vocabDetailJSON, &job.VocabDetail)
+	jobs = append(jobs, job)
+    }
+
+    return jobs, rows.Err()
+}
+
+// Search 全文搜索任务（search_vector GIN 索引，按相关度排序）
+// 文件名额外做子串匹配，方便按文件名片段查找
+func (s *PostgresJobStore) Search(ctx context.Context, query string) ([]*models.TranscriptionJob, error) {
+    sqlQuery := `
+    SELECT ` + postgresJobColumns + `
+    FROM transcription_jobs
+    WHERE (search_vector @@ plainto_tsquery('english', $1) OR filename ILIKE $2)
+    AND ` + notDeleted + `
+    ORDER BY ts_rank(search_vector, plainto_tsquery('english', $1)) DESC, created_at DESC
+    LIMIT $3
+    `
+
+    rows, err := s.db.QueryContext(ctx, sqlQuery, query, likePattern(query), searchLimit)
+    if err != nil {
+	return nil, fmt.Errorf("搜索任务失败: %w", err)
+    }
+    defer rows.Close()
+
+    jobs := make([]*models.TranscriptionJob, 0)
+    for rows.Next() {
+	job, err := scanPostgresJob(rows)
+	if err != nil {
+	    continue
 	}
+	jobs = append(jobs, job)
+    }
+
+    return jobs, rows.Err()
+}
 
-	jobs = append(jobs, &job)
+// Delete 软删除任务（记录 deleted_at；已归档的任务先移回任务表）
+func (s *PostgresJobStore) Delete(ctx context.Context, jobID string) error {
+    if err := s.unarchive(ctx, jobID); err != nil {
+	return err
     }
+    return softDelete(ctx, s.db, postgresPlaceholder, jobID)
+}
 
-    return jobs, nil
+// Restore 恢复软删除的任务
+func (s *PostgresJobStore) Restore(ctx context.Context, jobID string) error {
+    return restoreDeleted(ctx, s.db, postgresPlaceholder, jobID)
 }
 
-func (s *PostgresJobStore) ListAll() ([]*models.TranscriptionJob, error) {
-    return s.List()
+// ListDeleted 列出删除时间早于 before 的软删除任务（按删除时间正序）
+func (s *PostgresJobStore) ListDeleted(ctx context.Context, before time.Time) ([]*models.TranscriptionJob, error) {
+    query := `SELECT ` + postgresJobColumns + ` FROM transcription_jobs
+    WHERE deleted_at IS NOT NULL AND deleted_at < $1
+    ORDER BY deleted_at ASC`
+
+    rows, err := s.db.QueryContext(ctx, query, before)
+    if err != nil {
+	return nil, fmt.Errorf("查询数据库失败: %w", err)
+    }
+    defer rows.Close()
+
+    jobs := make([]*models.TranscriptionJob, 0)
+    for rows.Next() {
+	job, err := scanPostgresJob(rows)
+	if err != nil {
+	    continue
+	}
+	jobs = append(jobs, job)
+    }
+
+    return jobs, rows.Err()
 }
 
-// Delete 删除任务
-func (s *PostgresJobStore) Delete(jobID string) error {
+// Purge 永久删除任务（包括归档表中的记录）及其单词索引和片段结果
+func (s *PostgresJobStore) Purge(ctx context.Context, jobID string) error {
+    if err := s.unarchive(ctx, jobID); err != nil {
+	return err
+    }
     query := `DELETE FROM transcription_jobs WHERE job_id = $1`
 
-    result, err := s.db.Exec(query, jobID)
+    result, err := s.db.ExecContext(ctx, query, jobID)
     if err != nil {
 	return fmt.Errorf("删除任务失败: %w", err)
     }
@@ -318,6 +881,235 @@ func (s *PostgresJobStore) Delete(jobID string) error {
 	return fmt.Errorf("任务不存在: %s", jobID)
     }
 
+    if _, err := s.db.ExecContext(ctx, `DELETE FROM job_words WHERE job_id = $1`, jobID); err != nil {
+	return fmt.Errorf("删除单词索引失败: %w", err)
+    }
+    if err := s.DeleteSegments(ctx, jobID); err != nil {
+	return err
+    }
+
+    return nil
+}
+
+// SaveSegment 保存片段结果（job_segments 表）
+func (s *PostgresJobStore) SaveSegment(ctx context.Context, jobID string, index int, data []byte) error {
+    return saveSegment(ctx, s.db, postgresPlaceholder, jobID, index, data)
+}
+
+// LoadSegments 读取任务已保存的片段结果
+func (s *PostgresJobStore) LoadSegments(ctx context.Context, jobID string) (map[int][]byte, error) {
+    return loadSegments(ctx, s.db, postgresPlaceholder, jobID)
+}
+
+// DeleteSegments 删除任务的片段结果
+func (s *PostgresJobStore) DeleteSegments(ctx context.Context, jobID string) error {
+    return deleteSegments(ctx, s.db, postgresPlaceholder, jobID)
+}
+
+// postgresPlaceholder PostgreSQL 的第 n 个参数占位符
+func postgresPlaceholder(n int) string {
+    return fmt.Sprintf("$%d", n)
+}
+
+// IncrUsage 累加月度费用（UPSERT 原子累加）
+func (s *PostgresJobStore) IncrUsage(ctx context.Context, month string, costUSD float64) (float64, error) {
+    query := `
+    INSERT INTO openai_usage (month, cost_usd, updated_at)
+    VALUES ($1, $2, NOW())
+    ON CONFLICT (month)
+    DO UPDATE SET
+    cost_usd = openai_usage.cost_usd + EXCLUDED.cost_usd,
+    updated_at = NOW()
+    RETURNING cost_usd
+    `
+
+    var total float64
+    if err := s.db.QueryRowContext(ctx, query, month, costUSD).Scan(&total); err != nil {
+	return 0, fmt.Errorf("累加费用失败: %w", err)
+    }
+    return total, nil
+}
+
+// GetUsage 获取月度费用
+func (s *PostgresJobStore) GetUsage(ctx context.Context, month string) (float64, error) {
+    query := `SELECT cost_usd FROM openai_usage WHERE month = $1`
+
+    var total float64
+    err := s.db.QueryRowContext(ctx, query, month).Scan(&total)
+    if err == sql.ErrNoRows {
+	return 0, nil
+    }
+    if err != nil {
+	return 0, fmt.Errorf("获取费用失败: %w", err)
+    }
+    return total, nil
+}
+
+// SetCredential 保存用户凭证（已存在时覆盖）
+func (s *PostgresJobStore) SetCredential(ctx context.Context, userID, name, value string) error {
+    query := `
+    INSERT INTO user_credentials (user_id, name, value, updated_at)
+    VALUES ($1, $2, $3, NOW())
+    ON CONFLICT (user_id, name)
+    DO UPDATE SET
+    value = EXCLUDED.value,
+    updated_at = NOW()
+    `
+
+    if _, err := s.db.ExecContext(ctx, query, userID, name, value); err != nil {
+	return fmt.Errorf("保存凭证失败: %w", err)
+    }
+    return nil
+}
+
+// GetCredential 获取用户凭证
+func (s *PostgresJobStore) GetCredential(ctx context.Context, userID, name string) (string, error) {
+    query := `SELECT value FROM user_credentials WHERE user_id = $1 AND name = $2`
+
+    var value string
+    err := s.db.QueryRowContext(ctx, query, userID, name).Scan(&value)
+    if err == sql.ErrNoRows {
+	return "", nil
+    }
+    if err != nil {
+	return "", fmt.Errorf("获取凭证失败: %w", err)
+    }
+    return value, nil
+}
+
+// DeleteCredential 删除用户凭证
+func (s *PostgresJobStore) DeleteCredential(ctx context.Context, userID, name string) error {
+    if _, err := s.db.ExecContext(ctx, `DELETE FROM user_credentials WHERE user_id = $1 AND name = $2`, userID, name); err != nil {
+	return fmt.Errorf("删除凭证失败: %w", err)
+    }
+    return nil
+}
+
+// SetHashIfAbsent 登记文件内容哈希（依赖 content_hash 主键约束，多实例并发只有一个成功）
+func (s *PostgresJobStore) SetHashIfAbsent(ctx context.Context, hash, jobID string) (string, bool, error) {
+    insert := `
+    INSERT INTO job_content_hashes (content_hash, job_id, created_at)
+    VALUES ($1, $2, NOW())
+    ON CONFLICT (content_hash) DO NOTHING
+    `
+    query := `SELECT job_id FROM job_content_hashes WHERE content_hash = $1`
+
+    // 插入冲突后读取已有值；若恰好被删除则重新插入
+    for attempt := 0; attempt < 3; attempt++ {
+	result, err := s.db.ExecContext(ctx, insert, hash, jobID)
+	if err != nil {
+	    return "", false, fmt.Errorf("登记文件哈希失败: %w", err)
+	}
+	if rows, _ := result.RowsAffected(); rows == 1 {
+	    return jobID, true, nil
+	}
+
+	var existing string
+	err = s.db.QueryRowContext(ctx, query, hash).Scan(&existing)
+	if err == sql.ErrNoRows {
+	    continue
+	}
+	if err != nil {
+	    return "", false, fmt.Errorf("读取文件哈希失败: %w", err)
+	}
+	return existing, false, nil
+    }
+
+    return "", false, fmt.Errorf("登记文件哈希失败: 并发冲突")
+}
+
+// FindByHash 查找相同文件内容最近一次已完成的任务（content_hash 索引）
+func (s *PostgresJobStore) FindByHash(ctx context.Context, hash string) (*models.TranscriptionJob, error) {
+    query := `SELECT ` + postgresJobColumns + ` FROM transcription_jobs
+    WHERE content_hash = $1 AND status = $2 AND ` + notDeleted + `
+    ORDER BY created_at DESC LIMIT 1`
+
+    job, err := scanPostgresJob(s.db.QueryRowContext(ctx, query, hash, models.StatusCompleted))
+    if err == sql.ErrNoRows {
+	return nil, nil
+    }
+    if err != nil {
+	return nil, fmt.Errorf("按文件哈希查询任务失败: %w", err)
+    }
+    return job, nil
+}
+
+// DeleteHash 删除文件内容哈希登记
+func (s *PostgresJobStore) DeleteHash(ctx context.Context, hash, jobID string) error {
+    query := `DELETE FROM job_content_hashes WHERE content_hash = $1 AND job_id = $2`
+
+    if _, err := s.db.ExecContext(ctx, query, hash, jobID); err != nil {
+	return fmt.Errorf("删除文件哈希失败: %w", err)
+    }
+    return nil
+}
+
+// IndexWords 替换任务的单词索引（事务中先删除再插入）
+func (s *PostgresJobStore) IndexWords(ctx context.Context, jobID string, words []string) error {
+    tx, err := s.db.BeginTx(ctx, nil)
+    if err != nil {
+	return fmt.Errorf("开启事务失败: %w", err)
+    }
+    defer tx.Rollback()
+
+    if _, err := tx.ExecContext(ctx, `DELETE FROM job_words WHERE job_id = $1`, jobID); err != nil {
+	return fmt.Errorf("删除单词索引失败: %w", err)
+    }
+
+    insert := `INSERT INTO job_words (word, job_id) VALUES ($1, $2) ON CONFLICT DO NOTHING`
+    for _, word := range words {
+	if _, err := tx.ExecContext(ctx, insert, word, jobID); err != nil {
+	    return fmt.Errorf("写入单词索引失败: %w", err)
+	}
+    }
+
+    if err := tx.Commit(); err != nil {
+	return fmt.Errorf("提交单词索引失败: %w", err)
+    }
+    return nil
+}
+
+// JobsByWord 查找包含指定单词的任务
+func (s *PostgresJobStore) JobsByWord(ctx context.Context, word string) ([]*models.TranscriptionJob, error) {
+    query := `
+    SELECT ` + postgresJobColumns + `
+    FROM transcription_jobs
+    WHERE job_id IN (SELECT job_id FROM job_words WHERE word = $1) AND ` + notDeleted + `
+    ORDER BY created_at DESC
+    LIMIT $2
+    `
+
+    rows, err := s.db.QueryContext(ctx, query, word, searchLimit)
+    if err != nil {
+	return nil, fmt.Errorf("查询单词索引失败: %w", err)
+    }
+    defer rows.Close()
+
+    jobs := make([]*models.TranscriptionJob, 0)
+    for rows.Next() {
+	job, err := scanPostgresJob(rows)
+	if err != nil {
+	    continue
+	}
+	jobs = append(jobs, job)
+    }
+
+    return jobs, rows.Err()
+}
+
+// ListVocabulary 列出所有任务已提取的单词
+func (s *PostgresJobStore) ListVocabulary(ctx context.Context) (map[string][]string, error) {
+    return listVocabulary(ctx, s.db)
+}
+
+// Ping 检查数据库连接是否可用
+func (s *PostgresJobStore) Ping(ctx context.Context) error {
+    ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+    defer cancel()
+
+    if err := s.db.PingContext(ctx); err != nil {
+	return fmt.Errorf("数据库连接不可用: %w", err)
+    }
     return nil
 }
 
@@ -0,0 +1,146 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/z-wentao/voiceflow/pkg/models"
+)
+
+func TestLatestJobs(t *testing.T) {
+	tests := []struct {
+		name string
+		jobs []string // 任务 ID:版本
+		want []string
+	}{
+		{"没有重复", []string{"a:1", "b:1", "c:1"}, []string{"a:1", "b:1", "c:1"}},
+		{"保留最后一次出现的版本", []string{"a:1", "b:1", "a:2", "c:1", "a:3"}, []string{"b:1", "c:1", "a:3"}},
+		{"空列表", nil, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var jobs []*models.TranscriptionJob
+			for _, spec := range tt.jobs {
+				id, result, _ := strings.Cut(spec, ":")
+				jobs = append(jobs, &models.TranscriptionJob{JobID: id, Result: result})
+			}
+			var got []string
+			for _, job := range latestJobs(jobs) {
+				got = append(got, job.JobID+":"+job.Result)
+			}
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Fatalf("latestJobs = %v，期望 %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPostgresJobPlaceholders(t *testing.T) {
+	args, err := postgresJobArgs(&models.TranscriptionJob{JobID: "job-1"}, 0)
+	if err != nil {
+		t.Fatalf("postgresJobArgs: %v", err)
+	}
+	if len(args) != postgresJobColumnCount {
+		t.Fatalf("参数 %d 个，列 %d 个", len(args), postgresJobColumnCount)
+	}
+
+	// 第二个任务的占位符从第一个任务的参数之后开始编号
+	second := postgresJobPlaceholders(postgresJobColumnCount)
+	if !strings.HasPrefix(second, fmt.Sprintf("($%d, ", postgresJobColumnCount+1)) ||
+		!strings.Contains(second, fmt.Sprintf("$%d, to_tsvector", 2*postgresJobColumnCount)) ||
+		!strings.Contains(second, fmt.Sprintf("$%d || ' ' || $%d", postgresJobColumnCount+2, postgresJobColumnCount+6)) {
+		t.Fatalf("占位符编号错误: %s", second)
+	}
+}
+
+// TestPostgresBatchSave 一个任务写入失败不影响同一批的其他任务（需要 VOICEFLOW_TEST_POSTGRES_DSN）
+func TestPostgresBatchSave(t *testing.T) {
+	tests := []struct {
+		name       string
+		statuses   []models.JobStatus
+		wantFailed []int // 失败的任务序号
+	}{
+		{"全部成功", []models.JobStatus{models.StatusCompleted, models.StatusFailed, models.StatusCompleted}, nil},
+		{"中间一个任务不合法", []models.JobStatus{models.StatusCompleted, "bogus", models.StatusCompleted}, []int{1}},
+		{"全部不合法", []models.JobStatus{"bogus", "bogus"}, []int{0, 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			store := newTestPostgresStore(t)
+			prefix := testID("batch")
+			jobs := make([]*models.TranscriptionJob, len(tt.statuses))
+			for i, status := range tt.statuses {
+				jobs[i] = &models.TranscriptionJob{JobID: fmt.Sprintf("%s-%d", prefix, i), Status: status, Result: "hello"}
+			}
+
+			err := store.BatchSave(ctx, jobs)
+			failed := map[string]bool{}
+			if err != nil {
+				var batchErr *BatchSaveError
+				if !errors.As(err, &batchErr) {
+					t.Fatalf("BatchSave 返回 %v，期望 *BatchSaveError", err)
+				}
+				for id := range batchErr.Failed {
+					failed[id] = true
+				}
+			}
+			if len(failed) != len(tt.wantFailed) {
+				t.Fatalf("失败的任务 %v，期望 %v", failed, tt.wantFailed)
+			}
+			for _, i := range tt.wantFailed {
+				if !failed[jobs[i].JobID] {
+					t.Fatalf("任务 %d 应写入失败", i)
+				}
+			}
+			for _, job := range jobs {
+				_, err := store.Get(ctx, job.JobID)
+				if saved := err == nil; saved == failed[job.JobID] {
+					t.Fatalf("任务 %s 写入 = %v，失败 = %v", job.JobID, saved, failed[job.JobID])
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkPostgresBatchSave 一批 50 个任务：多行 UPSERT（一次往返）与逐个 Save 比较（需要 VOICEFLOW_TEST_POSTGRES_DSN）
+func BenchmarkPostgresBatchSave(b *testing.B) {
+	const batch = hybridSyncBatchSize
+	benchmarks := []struct {
+		name string
+		save func(ctx context.Context, store *PostgresJobStore, jobs []*models.TranscriptionJob) error
+	}{
+		{"batch", func(ctx context.Context, store *PostgresJobStore, jobs []*models.TranscriptionJob) error {
+			return store.BatchSave(ctx, jobs)
+		}},
+		{"loop", func(ctx context.Context, store *PostgresJobStore, jobs []*models.TranscriptionJob) error {
+			for _, job := range jobs {
+				if _, err := store.upsert(ctx, job, 0); err != nil {
+					return err
+				}
+			}
+			return nil
+		}},
+	}
+	for _, bm := range benchmarks {
+		b.Run(bm.name, func(b *testing.B) {
+			ctx := context.Background()
+			store := newTestPostgresStore(b)
+			prefix := testID("bench")
+			jobs := make([]*models.TranscriptionJob, batch)
+			for i := range jobs {
+				jobs[i] = &models.TranscriptionJob{JobID: fmt.Sprintf("%s-%d", prefix, i), Status: models.StatusCompleted, Result: "hello world"}
+			}
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if err := bm.save(ctx, store, jobs); err != nil {
+					b.Fatalf("保存失败: %v", err)
+				}
+			}
+		})
+	}
+}
@@ -3,7 +3,13 @@ package storage
 import (
     "context"
     "encoding/json"
+    "errors"
     "fmt"
+    "log/slog"
+    "sort"
+    "strconv"
+    "strings"
+    "sync"
     "time"
 
     "github.com/redis/go-redis/v9"
@@ -11,13 +17,37 @@ import (
 )
 
 type RedisJobStore struct {
-    client *redis.Client
-    ttl    time.Duration  
-    ctx    context.Context
+    client    *redis.Client
+    ttl       time.Duration
+    listLimit int // List 返回的最大任务数
+
+    // 读取任务时剩余过期时间不足 ttl 的一半则重新设为 ttl（经常查看的任务不会过期），见 SetRefreshOnAccess
+    refreshOnAccess bool
+
+    // 状态索引（每个状态一个 Sorted Set）已建立时按状态计数不需要遍历任务
+    statusIndexed bool
+
+    // 后台索引维护（定期清理索引中已过期的任务 ID）
+    cleanInterval time.Duration
+    ctx           context.Context // Close 时取消，中断进行中的清理
+    cancel        context.CancelFunc
+    wg            sync.WaitGroup
+    maintMu       sync.Mutex
+    maint         IndexMaintenanceStats
 }
 
+// defaultRedisListLimit List 默认返回的任务数
+const defaultRedisListLimit = 100
+
+// redisPipelineChunk 每次 pipeline 读取的任务数（避免单次请求过大阻塞 Redis）
+const redisPipelineChunk = 100
+
+// redisMaintenanceTimeout 单次索引清理的超时时间
+const redisMaintenanceTimeout = time.Minute
+
 // NewRedisJobStore 创建 Redis 任务存储
-func NewRedisJobStore(addr, password string, db int, ttl time.Duration) (*RedisJobStore, error) {
+// cleanInterval > 0 时启动后台 goroutine，每隔 cleanInterval 清理一次索引中已过期的任务 ID（Close 时停止）
+func NewRedisJobStore(addr, password string, db int, ttl, cleanInterval time.Duration) (*RedisJobStore, error) {
     client := redis.NewClient(&redis.Options{
 	Addr:     addr,     // Redis 地址，如 "localhost:6379"
 	Password: password, // 密码，无密码留空
@@ -25,57 +55,192 @@ func NewRedisJobStore(addr, password string, db int, ttl time.Duration) (*RedisJ
     })
 
     // 测试连接
-    ctx := context.Background()
-    if err := client.Ping(ctx).Err(); err != nil {
+    if err := client.Ping(context.Background()).Err(); err != nil {
 	return nil, fmt.Errorf("连接 Redis 失败: %w", err)
     }
 
-    return &RedisJobStore{
-	client: client,
-	ttl:    ttl,
-	ctx:    ctx,
-    }, nil
+    ctx, cancel := context.WithCancel(context.Background())
+    rs := &RedisJobStore{
+	client:        client,
+	ttl:           ttl,
+	listLimit:     defaultRedisListLimit,
+	cleanInterval: cleanInterval,
+	ctx:           ctx,
+	cancel:        cancel,
+    }
+    rs.ensureStatusIndex(context.Background())
+
+    if cleanInterval > 0 {
+	rs.wg.Add(1)
+	go rs.maintainIndex()
+    }
+
+    return rs, nil
+}
+
+// SetListLimit 设置 List 返回的最大任务数（<= 0 时使用默认值 100）
+func (rs *RedisJobStore) SetListLimit(limit int) {
+    if limit <= 0 {
+	limit = defaultRedisListLimit
+    }
+    rs.listLimit = limit
+}
+
+// SetRefreshOnAccess 设置读取任务时是否刷新过期时间
+// 开启后 Get 发现任务剩余的过期时间不足 ttl 的一半时异步重新设为 ttl（单词索引和片段结果一起刷新）；
+// 关闭时（默认）任务在保存后 ttl 到期即过期
+func (rs *RedisJobStore) SetRefreshOnAccess(enabled bool) {
+    rs.refreshOnAccess = enabled
 }
 
 // getKey 生成 Redis key: voiceflow:job:{jobID}
+// 任务保存为哈希，每个 JSON 顶层字段一个哈希字段（见 encodeJobFields）；旧版本保存的是整个 JSON 字符串，读取时自动迁移
 func (rs *RedisJobStore) getKey(jobID string) string {
     return fmt.Sprintf("voiceflow:job:%s", jobID)
 }
 
-// Save 保存任务到 Redis
-func (rs *RedisJobStore) Save(job *models.TranscriptionJob) error {
-    // 1. 序列化为 JSON
-    data, err := json.Marshal(job)
+// encodeJobFields 将任务编码为哈希字段：字段名为 JSON 字段名，值为该字段的 JSON 编码
+// 进度、状态等小字段与转录结果、单词列表等大字段相互独立，Update 只需写回发生变化的字段
+func encodeJobFields(job *models.TranscriptionJob) (map[string]string, error) {
+    data, err := models.MarshalJob(job)
     if err != nil {
-	return fmt.Errorf("序列化任务失败: %w", err)
+	return nil, fmt.Errorf("序列化任务失败: %w", err)
     }
 
-    // 2. 保存到 Redis，设置过期时间
-    key := rs.getKey(job.JobID)
-    if err := rs.client.Set(rs.ctx, key, data, rs.ttl).Err(); err != nil {
-	return fmt.Errorf("保存到 Redis 失败: %w", err)
+    var raw map[string]json.RawMessage
+    if err := json.Unmarshal(data, &raw); err != nil {
+	return nil, fmt.Errorf("序列化任务失败: %w", err)
     }
+    fields := make(map[string]string, len(raw))
+    for name, value := range raw {
+	fields[name] = string(value)
+    }
+    return fields, nil
+}
 
-    // 3. 将 JobID 加入索引集合（用于 List 操作）
-    // 使用 Sorted Set，score 为创建时间戳
-    indexKey := "voiceflow:jobs:index"
-    score := float64(job.CreatedAt.Unix())
-    if err := rs.client.ZAdd(rs.ctx, indexKey, redis.Z{
-	Score:  score,
-	Member: job.JobID,
-    }).Err(); err != nil {
-	return fmt.Errorf("添加到索引失败: %w", err)
+// decodeJobFields 由哈希字段还原任务（旧版本数据自动升级）
+func decodeJobFields(fields map[string]string) (*models.TranscriptionJob, error) {
+    raw := make(map[string]json.RawMessage, len(fields))
+    for name, value := range fields {
+	raw[name] = json.RawMessage(value)
     }
+    data, err := json.Marshal(raw)
+    if err != nil {
+	return nil, fmt.Errorf("解析任务数据失败: %w", err)
+    }
+    return models.UnmarshalJob(data)
+}
 
-    return nil
+// hashArgs 将哈希字段转换为 HSET 参数
+func hashArgs(fields map[string]string) []any {
+    args := make([]any, 0, len(fields)*2)
+    for name, value := range fields {
+	args = append(args, name, value)
+    }
+    return args
+}
+
+// isWrongType 判断是否为类型不匹配错误（对旧版本的字符串格式任务执行哈希命令）
+func isWrongType(err error) bool {
+    return err != nil && strings.HasPrefix(err.Error(), "WRONGTYPE")
+}
+
+// readJobFields 读取任务的哈希字段，任务不存在时返回 redis.Nil
+// 旧版本的字符串格式任务转换为哈希字段返回，legacy 为 true（由调用方决定是否迁移）
+func (rs *RedisJobStore) readJobFields(ctx context.Context, c redis.Cmdable, key string) (fields map[string]string, legacy bool, err error) {
+    fields, err = c.HGetAll(ctx, key).Result()
+    if isWrongType(err) {
+	data, err := c.Get(ctx, key).Bytes()
+	if err != nil {
+	    return nil, false, err
+	}
+	job, err := models.UnmarshalJob(data)
+	if err != nil {
+	    return nil, false, err
+	}
+	fields, err = encodeJobFields(job)
+	return fields, true, err
+    }
+    if err != nil {
+	return nil, false, err
+    }
+    if len(fields) == 0 {
+	return nil, false, redis.Nil
+    }
+    return fields, false, nil
+}
+
+// expire 设置任务数据的过期时间（ttl <= 0 时不过期）
+func (rs *RedisJobStore) expire(ctx context.Context, pipe redis.Pipeliner, key string) {
+    if rs.ttl > 0 {
+	pipe.Expire(ctx, key, rs.ttl)
+    }
+}
+
+// readVersion 读取任务的版本号，任务不存在、没有版本号或是旧版本的字符串格式时返回 0
+func readVersion(ctx context.Context, c redis.Cmdable, key string) (int64, error) {
+    version, err := c.HGet(ctx, key, "version").Int64()
+    if err == redis.Nil || isWrongType(err) {
+	return 0, nil
+    }
+    return version, err
+}
+
+// Save 保存任务到 Redis（整体替换任务的哈希，设置过期时间，并加入索引）
+// 使用 WATCH / MULTI 读取并检查版本号：job.Version > 0 且与 Redis 中的版本号不同时返回 ErrVersionConflict
+func (rs *RedisJobStore) Save(ctx context.Context, job *models.TranscriptionJob) error {
+    key := rs.getKey(job.JobID)
+    expected := job.Version
+
+    txFn := func(tx *redis.Tx) error {
+	// 1. 读取当前版本号（WATCH 之后读取）
+	current, err := readVersion(ctx, tx, key)
+	if err != nil {
+	    return fmt.Errorf("从 Redis 获取失败: %w", err)
+	}
+	if expected > 0 && current > 0 && current != expected {
+	    return versionConflict(job.JobID)
+	}
+
+	// 2. 编码为哈希字段（版本号加 1）
+	next := *job
+	next.Version = max(current, expected) + 1
+	fields, err := encodeJobFields(&next)
+	if err != nil {
+	    return err
+	}
+
+	// 3. 在事务中替换任务数据，并将 JobID 加入索引集合（用于 List 操作，见 indexJob）
+	_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+	    pipe.Del(ctx, key) // 清除旧字段（包括旧版本的字符串格式）
+	    pipe.HSet(ctx, key, hashArgs(fields)...)
+	    rs.expire(ctx, pipe, key)
+	    rs.indexJob(ctx, pipe, job)
+	    return nil
+	})
+	if err != nil {
+	    return fmt.Errorf("保存到 Redis 失败: %w", err)
+	}
+
+	job.Version = next.Version
+	return nil
+    }
+
+    // 读取版本号到写入之间 key 被修改时事务不执行，重新读取后重试（此时带版本号的保存会返回冲突）
+    for i := 0; i < redisUpdateRetries; i++ {
+	if err := rs.client.Watch(ctx, txFn, key); !errors.Is(err, redis.TxFailedErr) {
+	    return err
+	}
+    }
+    return fmt.Errorf("保存任务失败: 并发修改冲突，已重试 %d 次", redisUpdateRetries)
 }
 
-// Get 从 Redis 获取任务
-func (rs *RedisJobStore) Get(jobID string) (*models.TranscriptionJob, error) {
+// Get 从 Redis 获取任务（旧版本的字符串格式任务读取后迁移为哈希）
+func (rs *RedisJobStore) Get(ctx context.Context, jobID string) (*models.TranscriptionJob, error) {
     key := rs.getKey(jobID)
 
     // 从 Redis 获取数据
-    data, err := rs.client.Get(rs.ctx, key).Bytes()
+    fields, legacy, err := rs.readJobFields(ctx, rs.client, key)
     if err == redis.Nil {
 	return nil, fmt.Errorf("任务不存在: %s", jobID)
     }
@@ -83,66 +248,705 @@ func (rs *RedisJobStore) Get(jobID string) (*models.TranscriptionJob, error) {
 	return nil, fmt.Errorf("从 Redis 获取失败: %w", err)
     }
 
-    // 反序列化
-    var job models.TranscriptionJob
-    if err := json.Unmarshal(data, &job); err != nil {
-	return nil, fmt.Errorf("反序列化任务失败: %w", err)
+    // 反序列化（旧版本数据自动升级）
+    job, err := decodeJobFields(fields)
+    if err != nil {
+	return nil, err
     }
 
-    return &job, nil
+    if legacy {
+	rs.migrateLegacyJob(ctx, key)
+    }
+    if rs.refreshOnAccess && rs.ttl > 0 {
+	go rs.refreshTTL(jobID)
+    }
+    return job, nil
 }
 
-// Update 更新任务
-func (rs *RedisJobStore) Update(jobID string, updateFn func(*models.TranscriptionJob)) error {
-    // 1. 获取现有任务
-    job, err := rs.Get(jobID)
+// redisRefreshTimeout 异步刷新过期时间的超时时间
+const redisRefreshTimeout = 5 * time.Second
+
+// refreshTTLScript 任务剩余过期时间不足阈值时重新设置所有 KEYS 的过期时间
+// KEYS[1] 为任务 key，其余为随任务过期的 key；ARGV[1] 为新的过期时间，ARGV[2] 为阈值（毫秒）
+// 任务已不存在或没有过期时间时不做任何事，不会让已过期的任务重新出现；不存在的附属 key 执行 PEXPIRE 也不会被创建
+var refreshTTLScript = redis.NewScript(`
+local ttl = redis.call("PTTL", KEYS[1])
+if ttl < 0 or ttl >= tonumber(ARGV[2]) then
+    return 0
+end
+for i = 1, #KEYS do
+    redis.call("PEXPIRE", KEYS[i], ARGV[1])
+end
+return 1
+`)
+
+// refreshTTL 刷新任务及其单词索引、片段结果的过期时间（在 Get 之后异步执行，失败只记录日志）
+// 任务索引（有序集合）本身不过期，任务最终过期后由 CleanExpiredJobs 清理
+func (rs *RedisJobStore) refreshTTL(jobID string) {
+    ctx, cancel := context.WithTimeout(rs.ctx, redisRefreshTimeout)
+    defer cancel()
+
+    keys := []string{rs.getKey(jobID), rs.jobWordsKey(jobID), rs.segmentsKey(jobID)}
+    refreshed, err := refreshTTLScript.Run(ctx, rs.client, keys, rs.ttl.Milliseconds(), (rs.ttl / 2).Milliseconds()).Int()
     if err != nil {
+	if rs.ctx.Err() == nil {
+	    slog.Warn("⚠️ 刷新 Redis 任务过期时间失败", "job_id", jobID, "error", err)
+	}
+	return
+    }
+    if refreshed == 1 {
+	slog.Debug("刷新 Redis 任务过期时间", "job_id", jobID, "ttl", rs.ttl)
+    }
+}
+
+// migrateLegacyJob 将旧版本的字符串格式任务迁移为哈希（保留剩余的过期时间）
+// 使用 WATCH 避免覆盖并发的写入；迁移失败不影响读取，下次读取时重试
+func (rs *RedisJobStore) migrateLegacyJob(ctx context.Context, key string) {
+    err := rs.client.Watch(ctx, func(tx *redis.Tx) error {
+	data, err := tx.Get(ctx, key).Bytes()
+	if isWrongType(err) || err == redis.Nil {
+	    return nil // 已被其他请求迁移或删除
+	}
+	if err != nil {
+	    return err
+	}
+	ttl, err := tx.PTTL(ctx, key).Result()
+	if err != nil {
+	    return err
+	}
+
+	job, err := models.UnmarshalJob(data)
+	if err != nil {
+	    return err
+	}
+	fields, err := encodeJobFields(job)
+	if err != nil {
+	    return err
+	}
+
+	_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+	    pipe.Del(ctx, key)
+	    pipe.HSet(ctx, key, hashArgs(fields)...)
+	    if ttl > 0 {
+		pipe.PExpire(ctx, key, ttl)
+	    }
+	    return nil
+	})
 	return err
+    }, key)
+    if err != nil && !errors.Is(err, redis.TxFailedErr) {
+	slog.Warn("⚠️ 迁移 Redis 任务格式失败", "key", key, "error", err)
+    }
+}
+
+// redisUpdateRetries Save / Update 遇到并发修改时的最大重试次数
+const redisUpdateRetries = 10
+
+// Update 更新任务
+// 使用 WATCH / MULTI 乐观锁：读取任务到写回之间 key 被其他客户端修改时事务不执行，
+// 重新读取最新数据并再次执行 updateFn，避免并发的 Update 互相覆盖（因此 updateFn 可能被调用多次）。
+// 只写回发生变化的哈希字段，进度更新不会重写转录结果、单词列表等大字段
+func (rs *RedisJobStore) Update(ctx context.Context, jobID string, updateFn func(*models.TranscriptionJob)) error {
+    key := rs.getKey(jobID)
+
+    txFn := func(tx *redis.Tx) error {
+	// 1. 获取现有任务（WATCH 之后读取）
+	oldFields, legacy, err := rs.readJobFields(ctx, tx, key)
+	if err == redis.Nil {
+	    return fmt.Errorf("任务不存在: %s", jobID)
+	}
+	if err != nil {
+	    return fmt.Errorf("从 Redis 获取失败: %w", err)
+	}
+	job, err := decodeJobFields(oldFields)
+	if err != nil {
+	    return err
+	}
+	version := job.Version
+
+	// 2. 执行更新函数（版本号加 1）
+	updateFn(job)
+	job.Version = version + 1
+
+	// 3. 找出变化的字段和被移除的字段（omitempty 的字段变为空值时不再出现）
+	fields, err := encodeJobFields(job)
+	if err != nil {
+	    return err
+	}
+	changed := make(map[string]string)
+	for name, value := range fields {
+	    if old, ok := oldFields[name]; !ok || old != value {
+		changed[name] = value
+	    }
+	}
+	removed := make([]string, 0)
+	for name := range oldFields {
+	    if _, ok := fields[name]; !ok {
+		removed = append(removed, name)
+	    }
+	}
+
+	// 4. 在 MULTI 事务中写回（key 被修改过时返回 redis.TxFailedErr）
+	// 旧版本的字符串格式任务整体替换为哈希
+	_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+	    if legacy {
+		pipe.Del(ctx, key)
+		changed, removed = fields, nil
+	    }
+	    if len(changed) > 0 {
+		pipe.HSet(ctx, key, hashArgs(changed)...)
+	    }
+	    if len(removed) > 0 {
+		pipe.HDel(ctx, key, removed...)
+	    }
+	    rs.expire(ctx, pipe, key)
+	    rs.indexJob(ctx, pipe, job)
+	    return nil
+	})
+	if err != nil {
+	    return fmt.Errorf("保存到 Redis 失败: %w", err)
+	}
+	return nil
+    }
+
+    for i := 0; i < redisUpdateRetries; i++ {
+	if err := rs.client.Watch(ctx, txFn, key); !errors.Is(err, redis.TxFailedErr) {
+	    return err
+	}
     }
+    return fmt.Errorf("更新任务失败: 并发修改冲突，已重试 %d 次", redisUpdateRetries)
+}
 
-    // 2. 执行更新函数
-    updateFn(job)
+// List 列出最近的任务（按创建时间倒序，最多 listLimit 个）
+func (rs *RedisJobStore) List(ctx context.Context) ([]*models.TranscriptionJob, error) {
+    return rs.listRange(ctx, int64(rs.listLimit)-1)
+}
 
-    // 3. 保存回 Redis
-    return rs.Save(job)
+// ListAll 列出全部任务（按创建时间倒序）
+func (rs *RedisJobStore) ListAll(ctx context.Context) ([]*models.TranscriptionJob, error) {
+    return rs.listRange(ctx, -1)
 }
 
-// List 列出所有任务
-func (rs *RedisJobStore) List() ([]*models.TranscriptionJob, error) {
+// listRange 按时间倒序读取索引中前 stop+1 个任务（stop 为 -1 时读取全部）
+// 任务详情每 redisPipelineChunk 个用一次 pipeline 读取，已过期的任务从索引中删除
+func (rs *RedisJobStore) listRange(ctx context.Context, stop int64) ([]*models.TranscriptionJob, error) {
     indexKey := "voiceflow:jobs:index"
 
-    // 1. 从索引获取所有 JobID（按时间倒序）
-    jobIDs, err := rs.client.ZRevRange(rs.ctx, indexKey, 0, -1).Result()
+    // 1. 从索引获取 JobID（按时间倒序）
+    jobIDs, err := rs.client.ZRevRange(ctx, indexKey, 0, stop).Result()
     if err != nil {
 	return nil, fmt.Errorf("获取任务索引失败: %w", err)
     }
 
     // 2. 批量获取任务详情
     jobs := make([]*models.TranscriptionJob, 0, len(jobIDs))
+    expired := make([]any, 0)
+    for start := 0; start < len(jobIDs); start += redisPipelineChunk {
+	chunk := jobIDs[start:min(start+redisPipelineChunk, len(jobIDs))]
+	cmds := make([]*redis.MapStringStringCmd, len(chunk))
+	// 各命令的错误逐个检查（旧版本字符串格式的任务返回 WRONGTYPE）
+	rs.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+	    for i, jobID := range chunk {
+		cmds[i] = pipe.HGetAll(ctx, rs.getKey(jobID))
+	    }
+	    return nil
+	})
+
+	for i, cmd := range cmds {
+	    fields, err := cmd.Result()
+	    if isWrongType(err) {
+		// 旧版本格式：单独读取并迁移
+		if job, err := rs.Get(ctx, chunk[i]); err == nil {
+		    jobs = append(jobs, job)
+		}
+		continue
+	    }
+	    if err != nil {
+		return nil, fmt.Errorf("从 Redis 获取失败: %w", err)
+	    }
+	    if len(fields) == 0 {
+		// 任务已过期，稍后从索引中删除
+		expired = append(expired, chunk[i])
+		continue
+	    }
+	    job, err := decodeJobFields(fields)
+	    if err != nil {
+		continue
+	    }
+	    jobs = append(jobs, job)
+	}
+    }
+
+    if len(expired) > 0 {
+	rs.client.ZRem(ctx, indexKey, expired...)
+    }
+
+    return jobs, nil
+}
+
+// ListForUser 列出指定用户的任务（按索引时间倒序遍历，取满 limit 个即停止）
+func (rs *RedisJobStore) ListForUser(ctx context.Context, userID string, limit int) ([]*models.TranscriptionJob, error) {
+    indexKey := "voiceflow:jobs:index"
+
+    jobIDs, err := rs.client.ZRevRange(ctx, indexKey, 0, -1).Result()
+    if err != nil {
+	return nil, fmt.Errorf("获取任务索引失败: %w", err)
+    }
+
+    jobs := make([]*models.TranscriptionJob, 0)
     for _, jobID := range jobIDs {
-	job, err := rs.Get(jobID)
+	job, err := rs.Get(ctx, jobID)
 	if err != nil {
 	    // 任务可能已过期，跳过
-	    // 同时从索引中删除
-	    rs.client.ZRem(rs.ctx, indexKey, jobID)
+	    continue
+	}
+	if job.UserID != userID {
 	    continue
 	}
 	jobs = append(jobs, job)
+	if limit > 0 && len(jobs) >= limit {
+	    break
+	}
     }
 
     return jobs, nil
 }
 
-func (rs *RedisJobStore) ListAll() ([]*models.TranscriptionJob, error) {
-    return rs.List()
+// ListSummaries 列出指定状态任务的轻量投影
+// 只解码投影需要的字段，不为转录文本、单词列表分配内存
+func (rs *RedisJobStore) ListSummaries(ctx context.Context, statuses ...models.JobStatus) ([]models.JobSummary, error) {
+    indexKey := "voiceflow:jobs:index"
+
+    jobIDs, err := rs.client.ZRevRange(ctx, indexKey, 0, -1).Result()
+    if err != nil {
+	return nil, fmt.Errorf("获取任务索引失败: %w", err)
+    }
+
+    filter := statusFilter(statuses)
+    summaries := make([]models.JobSummary, 0)
+    for _, jobID := range jobIDs {
+	summary, err := rs.getSummary(ctx, jobID)
+	if err != nil {
+	    // 任务可能已过期，跳过
+	    continue
+	}
+	if filter == nil || filter[summary.Status] {
+	    summaries = append(summaries, summary)
+	}
+    }
+
+    return summaries, nil
 }
 
-func (rs *RedisJobStore) Delete(jobID string) error {
+// redisSummaryFields 任务投影（models.JobSummary）对应的哈希字段
+var redisSummaryFields = []string{"job_id", "filename", "status", "progress", "created_at", "user_id"}
+
+// errInvalidSummary 任务数据无法解析为投影
+var errInvalidSummary = errors.New("任务数据格式错误")
+
+// getSummary 只读取投影需要的哈希字段（HMGET），任务不存在时返回 redis.Nil
+func (rs *RedisJobStore) getSummary(ctx context.Context, jobID string) (models.JobSummary, error) {
+    var summary models.JobSummary
+    key := rs.getKey(jobID)
+
+    values, err := rs.client.HMGet(ctx, key, redisSummaryFields...).Result()
+    if isWrongType(err) {
+	// 旧版本格式：读取整个 JSON 字符串
+	data, err := rs.client.Get(ctx, key).Bytes()
+	if err != nil {
+	    return summary, err
+	}
+	if err := json.Unmarshal(data, &summary); err != nil {
+	    return summary, fmt.Errorf("%w: %v", errInvalidSummary, err)
+	}
+	return summary, nil
+    }
+    if err != nil {
+	return summary, err
+    }
+
+    raw := make(map[string]json.RawMessage, len(values))
+    for i, value := range values {
+	if s, ok := value.(string); ok {
+	    raw[redisSummaryFields[i]] = json.RawMessage(s)
+	}
+    }
+    if len(raw) == 0 {
+	return summary, redis.Nil
+    }
+    data, err := json.Marshal(raw)
+    if err == nil {
+	err = json.Unmarshal(data, &summary)
+    }
+    if err != nil {
+	return summary, fmt.Errorf("%w: %v", errInvalidSummary, err)
+    }
+    return summary, nil
+}
+
+// CountByStatus 按状态统计任务数量
+// 统计所有用户时读取状态索引的大小（ZCARD）；按用户统计或状态索引尚未建立时遍历任务索引，只解码投影字段
+func (rs *RedisJobStore) CountByStatus(ctx context.Context, userID string) (map[models.JobStatus]int, error) {
+    if userID == "" && rs.statusIndexed {
+	return rs.countStatusIndex(ctx, time.Time{})
+    }
+
+    summaries, err := rs.ListSummaries(ctx)
+    if err != nil {
+	return nil, err
+    }
+    return countSummaries(summaries, userID), nil
+}
+
+// Stats 汇总任务统计
+// 统计所有用户时任务数来自状态索引（ZCOUNT 按创建时间过滤），平均时长只读取已完成任务的 duration 字段；
+// 按用户统计或状态索引尚未建立时遍历所有任务
+func (rs *RedisJobStore) Stats(ctx context.Context, userID string, since time.Time) (models.JobStats, error) {
+    if userID != "" || !rs.statusIndexed {
+	jobs, err := rs.ListAll(ctx)
+	if err != nil {
+	    return models.JobStats{}, err
+	}
+	return statsOf(jobs, userID, since), nil
+    }
+
+    counts, err := rs.countStatusIndex(ctx, since)
+    if err != nil {
+	return models.JobStats{}, err
+    }
+    stats := models.JobStats{
+	Completed: counts[models.StatusCompleted],
+	Failed:    counts[models.StatusFailed],
+    }
+    for _, count := range counts {
+	stats.Total += count
+    }
+
+    completed, err := rs.client.ZRangeByScore(ctx, rs.statusKey(models.StatusCompleted), &redis.ZRangeBy{
+	Min: scoreMin(since),
+	Max: "+inf",
+    }).Result()
+    if err != nil {
+	return models.JobStats{}, fmt.Errorf("获取状态索引失败: %w", err)
+    }
+    stats.AvgDuration, err = rs.averageDuration(ctx, completed)
+    if err != nil {
+	return models.JobStats{}, err
+    }
+    return stats, nil
+}
+
+// averageDuration 计算任务的平均音频时长（每 redisPipelineChunk 个任务用一次 pipeline 读取 duration 字段，已过期的任务不计入）
+func (rs *RedisJobStore) averageDuration(ctx context.Context, jobIDs []string) (float64, error) {
+    total, count := 0.0, 0
+    for start := 0; start < len(jobIDs); start += redisPipelineChunk {
+	chunk := jobIDs[start:min(start+redisPipelineChunk, len(jobIDs))]
+	cmds := make([]*redis.StringCmd, len(chunk))
+	if _, err := rs.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+	    for i, jobID := range chunk {
+		cmds[i] = pipe.HGet(ctx, rs.getKey(jobID), "duration")
+	    }
+	    return nil
+	}); err != nil && err != redis.Nil && !isWrongType(err) {
+	    return 0, fmt.Errorf("读取任务时长失败: %w", err)
+	}
+
+	for _, cmd := range cmds {
+	    duration, err := strconv.ParseFloat(cmd.Val(), 64)
+	    if cmd.Err() != nil || err != nil {
+		continue // 已过期或旧版本格式
+	    }
+	    total += duration
+	    count++
+	}
+    }
+    if count == 0 {
+	return 0, nil
+    }
+    return total / float64(count), nil
+}
+
+// statusKey 生成状态索引 key: voiceflow:jobs:status:{status}
+// Sorted Set，成员为该状态的任务 ID，score 为创建时间戳（与 voiceflow:jobs:index 相同）
+func (rs *RedisJobStore) statusKey(status models.JobStatus) string {
+    return "voiceflow:jobs:status:" + string(status)
+}
+
+// redisStatusIndexReadyKey 状态索引已建立的标记（旧版本写入的任务已回填）
+const redisStatusIndexReadyKey = "voiceflow:jobs:status:ready"
+
+// redisDeletedIndexKey 软删除任务的索引（Sorted Set，score 为删除时间戳）
+const redisDeletedIndexKey = "voiceflow:jobs:deleted"
+
+// indexJob 更新任务所在的索引（在保存任务的事务中执行）
+// 未删除的任务加入任务索引（Sorted Set，score 为创建时间戳）和状态索引；
+// 软删除的任务从这些索引中移除、加入删除索引，列表、搜索和统计不再包含它
+func (rs *RedisJobStore) indexJob(ctx context.Context, pipe redis.Pipeliner, job *models.TranscriptionJob) {
+    if job.DeletedAt.IsZero() {
+	pipe.ZRem(ctx, redisDeletedIndexKey, job.JobID)
+	pipe.ZAdd(ctx, "voiceflow:jobs:index", redis.Z{
+	    Score:  float64(job.CreatedAt.Unix()),
+	    Member: job.JobID,
+	})
+	rs.indexStatus(ctx, pipe, job)
+	return
+    }
+
+    pipe.ZRem(ctx, "voiceflow:jobs:index", job.JobID)
+    for _, status := range models.AllStatuses {
+	pipe.ZRem(ctx, rs.statusKey(status), job.JobID)
+    }
+    pipe.ZAdd(ctx, redisDeletedIndexKey, redis.Z{
+	Score:  float64(job.DeletedAt.Unix()),
+	Member: job.JobID,
+    })
+}
+
+// indexStatus 将任务加入当前状态的索引，并从其他状态的索引中移除（在保存任务的事务中执行）
+func (rs *RedisJobStore) indexStatus(ctx context.Context, pipe redis.Pipeliner, job *models.TranscriptionJob) {
+    for _, status := range models.AllStatuses {
+	if status != job.Status {
+	    pipe.ZRem(ctx, rs.statusKey(status), job.JobID)
+	}
+    }
+    pipe.ZAdd(ctx, rs.statusKey(job.Status), redis.Z{
+	Score:  float64(job.CreatedAt.Unix()),
+	Member: job.JobID,
+    })
+}
+
+// unindexStatus 从所有状态索引中移除任务
+func (rs *RedisJobStore) unindexStatus(ctx context.Context, jobIDs ...any) error {
+    _, err := rs.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+	for _, status := range models.AllStatuses {
+	    pipe.ZRem(ctx, rs.statusKey(status), jobIDs...)
+	}
+	return nil
+    })
+    if err != nil {
+	return fmt.Errorf("清理状态索引失败: %w", err)
+    }
+    return nil
+}
+
+// countStatusIndex 按状态索引统计创建时间不早于 since 的任务数（since 为零时统计全部，数量为 0 的状态不返回）
+// 已过期的任务在后台索引维护清理前仍计入
+func (rs *RedisJobStore) countStatusIndex(ctx context.Context, since time.Time) (map[models.JobStatus]int, error) {
+    cmds := make(map[models.JobStatus]*redis.IntCmd, len(models.AllStatuses))
+    if _, err := rs.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+	for _, status := range models.AllStatuses {
+	    cmds[status] = pipe.ZCount(ctx, rs.statusKey(status), scoreMin(since), "+inf")
+	}
+	return nil
+    }); err != nil {
+	return nil, fmt.Errorf("读取状态索引失败: %w", err)
+    }
+
+    counts := make(map[models.JobStatus]int)
+    for status, cmd := range cmds {
+	if n := cmd.Val(); n > 0 {
+	    counts[status] = int(n)
+	}
+    }
+    return counts, nil
+}
+
+// scoreMin 按创建时间过滤的 score 下限（since 为零时不过滤）
+func scoreMin(since time.Time) string {
+    if since.IsZero() {
+	return "-inf"
+    }
+    return strconv.FormatInt(since.Unix(), 10)
+}
+
+// ensureStatusIndex 确认状态索引已建立：没有标记时（首次升级到带状态索引的版本）遍历任务索引回填
+// 回填失败时按状态统计退回到遍历任务，下次启动时重试
+func (rs *RedisJobStore) ensureStatusIndex(ctx context.Context) {
+    ready, err := rs.client.Exists(ctx, redisStatusIndexReadyKey).Result()
+    if err != nil {
+	slog.Warn("⚠️ 检查状态索引失败，按状态统计时遍历任务", "error", err)
+	return
+    }
+    if ready > 0 {
+	rs.statusIndexed = true
+	return
+    }
+
+    summaries, err := rs.ListSummaries(ctx)
+    if err == nil {
+	_, err = rs.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+	    for _, summary := range summaries {
+		pipe.ZAdd(ctx, rs.statusKey(summary.Status), redis.Z{
+		    Score:  float64(summary.CreatedAt.Unix()),
+		    Member: summary.JobID,
+		})
+	    }
+	    pipe.Set(ctx, redisStatusIndexReadyKey, "1", 0)
+	    return nil
+	})
+    }
+    if err != nil {
+	slog.Warn("⚠️ 建立状态索引失败，按状态统计时遍历任务", "error", err)
+	return
+    }
+
+    rs.statusIndexed = true
+    slog.Info("✓ 已建立 Redis 状态索引", "jobs", len(summaries))
+}
+
+// DeleteOlderThan 删除创建时间早于 t 的已结束任务
+// 任务数据通常已因 TTL 过期，这里同时清理索引中残留的任务 ID 和单词索引
+func (rs *RedisJobStore) DeleteOlderThan(ctx context.Context, t time.Time) (int, error) {
+    indexKey := "voiceflow:jobs:index"
+
+    jobIDs, err := rs.client.ZRangeByScore(ctx, indexKey, &redis.ZRangeBy{
+	Min: "-inf",
+	Max: fmt.Sprintf("(%d", t.Unix()),
+    }).Result()
+    if err != nil {
+	return 0, fmt.Errorf("获取任务索引失败: %w", err)
+    }
+
+    deleted, stale := 0, 0
+    for _, jobID := range jobIDs {
+	summary, err := rs.getSummary(ctx, jobID)
+	if err == redis.Nil {
+	    // 已过期：只清理索引
+	    rs.client.ZRem(ctx, indexKey, jobID)
+	    if err := rs.unindexStatus(ctx, jobID); err != nil {
+		return deleted, err
+	    }
+	    if err := rs.IndexWords(ctx, jobID, nil); err != nil {
+		return deleted, err
+	    }
+	    stale++
+	    continue
+	}
+	if errors.Is(err, errInvalidSummary) {
+	    continue
+	}
+	if err != nil {
+	    return deleted, fmt.Errorf("从 Redis 获取失败: %w", err)
+	}
+	if summary.Status != models.StatusCompleted && summary.Status != models.StatusFailed {
+	    continue
+	}
+	if err := rs.Purge(ctx, jobID); err != nil {
+	    return deleted, err
+	}
+	deleted++
+    }
+
+    if stale > 0 {
+	slog.Info("🧹 已清理 Redis 索引中过期的任务", "count", stale)
+    }
+    return deleted, nil
+}
+
+// ListByStatus 列出指定状态的任务（按创建时间正序）
+func (rs *RedisJobStore) ListByStatus(ctx context.Context, statuses ...models.JobStatus) ([]*models.TranscriptionJob, error) {
+    indexKey := "voiceflow:jobs:index"
+
+    jobIDs, err := rs.client.ZRange(ctx, indexKey, 0, -1).Result()
+    if err != nil {
+	return nil, fmt.Errorf("获取任务索引失败: %w", err)
+    }
+
+    filter := statusFilter(statuses)
+    jobs := make([]*models.TranscriptionJob, 0)
+    for _, jobID := range jobIDs {
+	job, err := rs.Get(ctx, jobID)
+	if err != nil {
+	    // 任务可能已过期，跳过
+	    continue
+	}
+	if filter == nil || filter[job.Status] {
+	    jobs = append(jobs, job)
+	}
+    }
+
+    return jobs, nil
+}
+
+// Search 搜索任务（遍历索引中的任务做子串匹配，按创建时间倒序，最多返回 searchLimit 个）
+func (rs *RedisJobStore) Search(ctx context.Context, query string) ([]*models.TranscriptionJob, error) {
+    jobs, err := rs.ListAll(ctx)
+    if err != nil {
+	return nil, err
+    }
+
+    // 先排序再截断，保证返回的是最近的匹配任务
+    sort.Slice(jobs, func(i, j int) bool {
+	return jobs[i].CreatedAt.After(jobs[j].CreatedAt)
+    })
+    return filterJobs(jobs, query), nil
+}
+
+// Delete 软删除任务（记录删除时间，任务从任务索引和状态索引移到删除索引）
+func (rs *RedisJobStore) Delete(ctx context.Context, jobID string) error {
+    alreadyDeleted := false
+    err := rs.Update(ctx, jobID, func(job *models.TranscriptionJob) {
+	alreadyDeleted = !job.DeletedAt.IsZero()
+	if !alreadyDeleted {
+	    job.DeletedAt = time.Now()
+	}
+    })
+    if err != nil {
+	return err
+    }
+    if alreadyDeleted {
+	return fmt.Errorf("任务不存在: %s", jobID)
+    }
+    return nil
+}
+
+// Restore 恢复软删除的任务（重新加入任务索引和状态索引）
+func (rs *RedisJobStore) Restore(ctx context.Context, jobID string) error {
+    wasDeleted := false
+    err := rs.Update(ctx, jobID, func(job *models.TranscriptionJob) {
+	wasDeleted = !job.DeletedAt.IsZero()
+	job.DeletedAt = time.Time{}
+    })
+    if err != nil {
+	return err
+    }
+    if !wasDeleted {
+	return fmt.Errorf("任务不存在或未被删除: %s", jobID)
+    }
+    return nil
+}
+
+// ListDeleted 列出删除时间早于 before 的软删除任务（按删除时间正序，已过期的任务从删除索引中移除）
+func (rs *RedisJobStore) ListDeleted(ctx context.Context, before time.Time) ([]*models.TranscriptionJob, error) {
+    jobIDs, err := rs.client.ZRangeByScore(ctx, redisDeletedIndexKey, &redis.ZRangeBy{
+	Min: "-inf",
+	Max: fmt.Sprintf("(%d", before.Unix()),
+    }).Result()
+    if err != nil {
+	return nil, fmt.Errorf("获取删除索引失败: %w", err)
+    }
+
+    jobs := make([]*models.TranscriptionJob, 0, len(jobIDs))
+    for _, jobID := range jobIDs {
+	job, err := rs.Get(ctx, jobID)
+	if err != nil {
+	    rs.client.ZRem(ctx, redisDeletedIndexKey, jobID)
+	    continue
+	}
+	if !job.DeletedAt.IsZero() {
+	    jobs = append(jobs, job)
+	}
+    }
+    return jobs, nil
+}
+
+// Purge 永久删除任务及其索引、片段结果和单词索引
+func (rs *RedisJobStore) Purge(ctx context.Context, jobID string) error {
     key := rs.getKey(jobID)
     indexKey := "voiceflow:jobs:index"
 
     // 删除任务数据
-    deleted, err := rs.client.Del(rs.ctx, key).Result()
+    deleted, err := rs.client.Del(ctx, key).Result()
     if err != nil {
 	return fmt.Errorf("删除任务失败: %w", err)
     }
@@ -152,39 +956,456 @@ func (rs *RedisJobStore) Delete(jobID string) error {
     }
 
     // 从索引中删除
-    rs.client.ZRem(rs.ctx, indexKey, jobID)
+    rs.client.ZRem(ctx, indexKey, jobID)
+    rs.client.ZRem(ctx, redisDeletedIndexKey, jobID)
+    if err := rs.unindexStatus(ctx, jobID); err != nil {
+	return err
+    }
+    rs.client.Del(ctx, rs.segmentsKey(jobID))
+    if err := rs.IndexWords(ctx, jobID, nil); err != nil {
+	return err
+    }
+
+    return nil
+}
+
+// segmentsKey 生成片段结果 key: voiceflow:job:{jobID}:segments（哈希，字段为片段序号）
+func (rs *RedisJobStore) segmentsKey(jobID string) string {
+    return fmt.Sprintf("voiceflow:job:%s:segments", jobID)
+}
+
+// SaveSegment 保存片段结果（与任务使用相同的 TTL）
+func (rs *RedisJobStore) SaveSegment(ctx context.Context, jobID string, index int, data []byte) error {
+    key := rs.segmentsKey(jobID)
+    _, err := rs.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+	pipe.HSet(ctx, key, strconv.Itoa(index), data)
+	rs.expire(ctx, pipe, key)
+	return nil
+    })
+    if err != nil {
+	return fmt.Errorf("保存片段结果失败: %w", err)
+    }
+    return nil
+}
+
+// LoadSegments 读取任务已保存的片段结果
+func (rs *RedisJobStore) LoadSegments(ctx context.Context, jobID string) (map[int][]byte, error) {
+    fields, err := rs.client.HGetAll(ctx, rs.segmentsKey(jobID)).Result()
+    if err != nil {
+	return nil, fmt.Errorf("读取片段结果失败: %w", err)
+    }
+
+    segments := make(map[int][]byte, len(fields))
+    for field, value := range fields {
+	index, err := strconv.Atoi(field)
+	if err != nil {
+	    continue
+	}
+	segments[index] = []byte(value)
+    }
+    return segments, nil
+}
+
+// DeleteSegments 删除任务的片段结果
+func (rs *RedisJobStore) DeleteSegments(ctx context.Context, jobID string) error {
+    if err := rs.client.Del(ctx, rs.segmentsKey(jobID)).Err(); err != nil {
+	return fmt.Errorf("删除片段结果失败: %w", err)
+    }
+    return nil
+}
+
+// usageKey 生成月度费用 key: voiceflow:usage:{month}
+func (rs *RedisJobStore) usageKey(month string) string {
+    return fmt.Sprintf("voiceflow:usage:%s", month)
+}
+
+// IncrUsage 累加月度费用（INCRBYFLOAT 原子操作，多实例并发安全）
+func (rs *RedisJobStore) IncrUsage(ctx context.Context, month string, costUSD float64) (float64, error) {
+    total, err := rs.client.IncrByFloat(ctx, rs.usageKey(month), costUSD).Result()
+    if err != nil {
+	return 0, fmt.Errorf("累加费用失败: %w", err)
+    }
+    return total, nil
+}
+
+// GetUsage 获取月度费用
+func (rs *RedisJobStore) GetUsage(ctx context.Context, month string) (float64, error) {
+    total, err := rs.client.Get(ctx, rs.usageKey(month)).Float64()
+    if err == redis.Nil {
+	return 0, nil
+    }
+    if err != nil {
+	return 0, fmt.Errorf("获取费用失败: %w", err)
+    }
+    return total, nil
+}
+
+// credentialKey 生成用户凭证 key: voiceflow:credential:{userID}:{name}
+func (rs *RedisJobStore) credentialKey(userID, name string) string {
+    return fmt.Sprintf("voiceflow:credential:%s:%s", userID, name)
+}
+
+// SetCredential 保存用户凭证（不设置过期时间）
+func (rs *RedisJobStore) SetCredential(ctx context.Context, userID, name, value string) error {
+    if err := rs.client.Set(ctx, rs.credentialKey(userID, name), value, 0).Err(); err != nil {
+	return fmt.Errorf("保存凭证失败: %w", err)
+    }
+    return nil
+}
+
+// GetCredential 获取用户凭证
+func (rs *RedisJobStore) GetCredential(ctx context.Context, userID, name string) (string, error) {
+    value, err := rs.client.Get(ctx, rs.credentialKey(userID, name)).Result()
+    if err == redis.Nil {
+	return "", nil
+    }
+    if err != nil {
+	return "", fmt.Errorf("获取凭证失败: %w", err)
+    }
+    return value, nil
+}
+
+// DeleteCredential 删除用户凭证
+func (rs *RedisJobStore) DeleteCredential(ctx context.Context, userID, name string) error {
+    if err := rs.client.Del(ctx, rs.credentialKey(userID, name)).Err(); err != nil {
+	return fmt.Errorf("删除凭证失败: %w", err)
+    }
+    return nil
+}
+
+// hashKey 生成文件内容哈希 key: voiceflow:hash:{hash}
+func (rs *RedisJobStore) hashKey(hash string) string {
+    return fmt.Sprintf("voiceflow:hash:%s", hash)
+}
+
+// deleteHashScript 仅当 key 仍指向指定任务时才删除（GET + DEL 需要原子执行）
+var deleteHashScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+    return redis.call("DEL", KEYS[1])
+end
+return 0
+`)
+
+// SetHashIfAbsent 登记文件内容哈希（SET NX 原子操作，多实例并发只有一个成功）
+// 哈希 key 与任务数据使用相同的过期时间
+func (rs *RedisJobStore) SetHashIfAbsent(ctx context.Context, hash, jobID string) (string, bool, error) {
+    key := rs.hashKey(hash)
+
+    // 抢占失败后读取已有值；若恰好被删除则重新抢占
+    for attempt := 0; attempt < 3; attempt++ {
+	created, err := rs.client.SetNX(ctx, key, jobID, rs.ttl).Result()
+	if err != nil {
+	    return "", false, fmt.Errorf("登记文件哈希失败: %w", err)
+	}
+	if created {
+	    return jobID, true, nil
+	}
+
+	existing, err := rs.client.Get(ctx, key).Result()
+	if err == redis.Nil {
+	    continue
+	}
+	if err != nil {
+	    return "", false, fmt.Errorf("读取文件哈希失败: %w", err)
+	}
+	return existing, false, nil
+    }
+
+    return "", false, fmt.Errorf("登记文件哈希失败: 并发冲突")
+}
+
+// FindByHash 查找相同文件内容已完成的任务（通过哈希登记查找）
+func (rs *RedisJobStore) FindByHash(ctx context.Context, hash string) (*models.TranscriptionJob, error) {
+    jobID, err := rs.client.Get(ctx, rs.hashKey(hash)).Result()
+    if err == redis.Nil {
+	return nil, nil
+    }
+    if err != nil {
+	return nil, fmt.Errorf("读取文件哈希失败: %w", err)
+    }
+
+    job, err := rs.Get(ctx, jobID)
+    if err != nil || job.Status != models.StatusCompleted || !job.DeletedAt.IsZero() {
+	// 登记的任务已过期、尚未完成或已删除
+	return nil, nil
+    }
+    return job, nil
+}
+
+// DeleteHash 删除文件内容哈希登记
+func (rs *RedisJobStore) DeleteHash(ctx context.Context, hash, jobID string) error {
+    if err := deleteHashScript.Run(ctx, rs.client, []string{rs.hashKey(hash)}, jobID).Err(); err != nil {
+	return fmt.Errorf("删除文件哈希失败: %w", err)
+    }
+    return nil
+}
+
+// markerKey 生成永不过期的登记 key: voiceflow:marker:{key}
+func (rs *RedisJobStore) markerKey(key string) string {
+    return fmt.Sprintf("voiceflow:marker:%s", key)
+}
+
+// setMarkerScript 登记 KEYS[1]（不设置过期时间）：已登记时返回已有的值；
+// 旧版本保存在哈希登记 KEYS[2] 中（会过期）的值迁移到 KEYS[1] 并删除旧 key，升级后不会把已处理的登记当作新的，
+// 登记删除后也不会再从旧 key 恢复
+var setMarkerScript = redis.NewScript(`
+local existing = redis.call("GET", KEYS[1])
+if existing then
+    return {0, existing}
+end
+existing = redis.call("GET", KEYS[2])
+if existing then
+    redis.call("SET", KEYS[1], existing)
+    redis.call("DEL", KEYS[2])
+    return {0, existing}
+end
+redis.call("SET", KEYS[1], ARGV[1])
+return {1, ARGV[1]}
+`)
+
+// SetMarkerIfAbsent 登记永不过期的标记（订阅源的节目等不能随任务 TTL 过期，否则过期后会重复导入）
+func (rs *RedisJobStore) SetMarkerIfAbsent(ctx context.Context, key, value string) (string, bool, error) {
+    result, err := setMarkerScript.Run(ctx, rs.client, []string{rs.markerKey(key), rs.hashKey(key)}, value).Slice()
+    if err != nil {
+	return "", false, fmt.Errorf("登记标记失败: %w", err)
+    }
+    if len(result) != 2 {
+	return "", false, fmt.Errorf("登记标记失败: 意外的返回值 %v", result)
+    }
+    created, _ := result[0].(int64)
+    existing, _ := result[1].(string)
+    return existing, created == 1, nil
+}
+
+// DeleteMarker 删除登记（仅当仍为 value 时删除）
+func (rs *RedisJobStore) DeleteMarker(ctx context.Context, key, value string) error {
+    if err := deleteHashScript.Run(ctx, rs.client, []string{rs.markerKey(key)}, value).Err(); err != nil {
+	return fmt.Errorf("删除标记失败: %w", err)
+    }
+    return nil
+}
+
+// wordKey 生成单词倒排索引 key: voiceflow:word:{word}（Set，成员为任务 ID）
+func (rs *RedisJobStore) wordKey(word string) string {
+    return fmt.Sprintf("voiceflow:word:%s", word)
+}
+
+// jobWordsKey 生成任务已索引单词的 key: voiceflow:jobwords:{jobID}（Set，用于替换和删除）
+func (rs *RedisJobStore) jobWordsKey(jobID string) string {
+    return fmt.Sprintf("voiceflow:jobwords:%s", jobID)
+}
+
+// IndexWords 替换任务的单词索引（事务中先移除旧单词再写入新单词）
+func (rs *RedisJobStore) IndexWords(ctx context.Context, jobID string, words []string) error {
+    oldWords, err := rs.client.SMembers(ctx, rs.jobWordsKey(jobID)).Result()
+    if err != nil {
+	return fmt.Errorf("读取单词索引失败: %w", err)
+    }
+
+    _, err = rs.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+	for _, word := range oldWords {
+	    pipe.SRem(ctx, rs.wordKey(word), jobID)
+	}
+	pipe.Del(ctx, rs.jobWordsKey(jobID))
+
+	if len(words) == 0 {
+	    return nil
+	}
+	members := make([]any, len(words))
+	for i, word := range words {
+	    pipe.SAdd(ctx, rs.wordKey(word), jobID)
+	    members[i] = word
+	}
+	pipe.SAdd(ctx, rs.jobWordsKey(jobID), members...)
+	pipe.Expire(ctx, rs.jobWordsKey(jobID), rs.ttl)
+	return nil
+    })
+    if err != nil {
+	return fmt.Errorf("更新单词索引失败: %w", err)
+    }
+    return nil
+}
+
+// JobsByWord 查找包含指定单词的任务（已过期的任务顺便从索引中移除）
+func (rs *RedisJobStore) JobsByWord(ctx context.Context, word string) ([]*models.TranscriptionJob, error) {
+    jobIDs, err := rs.client.SMembers(ctx, rs.wordKey(word)).Result()
+    if err != nil {
+	return nil, fmt.Errorf("读取单词索引失败: %w", err)
+    }
+
+    jobs := make([]*models.TranscriptionJob, 0, len(jobIDs))
+    for _, jobID := range jobIDs {
+	job, err := rs.Get(ctx, jobID)
+	if err != nil {
+	    rs.client.SRem(ctx, rs.wordKey(word), jobID)
+	    continue
+	}
+	if job.DeletedAt.IsZero() {
+	    jobs = append(jobs, job)
+	}
+    }
+
+    sort.Slice(jobs, func(i, j int) bool {
+	return jobs[i].CreatedAt.After(jobs[j].CreatedAt)
+    })
+    return jobs, nil
+}
+
+// ListVocabulary 列出所有任务已提取的单词（扫描每个任务的单词集合，跳过软删除的任务）
+func (rs *RedisJobStore) ListVocabulary(ctx context.Context) (map[string][]string, error) {
+    deletedIDs, err := rs.client.ZRange(ctx, redisDeletedIndexKey, 0, -1).Result()
+    if err != nil {
+	return nil, fmt.Errorf("获取删除索引失败: %w", err)
+    }
+    deleted := make(map[string]bool, len(deletedIDs))
+    for _, jobID := range deletedIDs {
+	deleted[jobID] = true
+    }
+
+    prefix := rs.jobWordsKey("")
+    result := make(map[string][]string)
 
+    iter := rs.client.Scan(ctx, 0, prefix+"*", 100).Iterator()
+    for iter.Next(ctx) {
+	key := iter.Val()
+	if deleted[strings.TrimPrefix(key, prefix)] {
+	    continue
+	}
+	words, err := rs.client.SMembers(ctx, key).Result()
+	if err != nil {
+	    return nil, fmt.Errorf("读取单词索引失败: %w", err)
+	}
+	result[strings.TrimPrefix(key, prefix)] = words
+    }
+    if err := iter.Err(); err != nil {
+	return nil, fmt.Errorf("扫描单词索引失败: %w", err)
+    }
+    return result, nil
+}
+
+// Ping 检查 Redis 连接是否可用
+func (rs *RedisJobStore) Ping(ctx context.Context) error {
+    ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+    defer cancel()
+
+    if err := rs.client.Ping(ctx).Err(); err != nil {
+	return fmt.Errorf("Redis 连接不可用: %w", err)
+    }
     return nil
 }
 
+// Close 停止后台索引维护并关闭连接
 func (rs *RedisJobStore) Close() error {
+    rs.cancel()
+    rs.wg.Wait()
     return rs.client.Close()
 }
 
-// CleanExpiredJobs 清理过期的任务索引（可选的维护方法）
-// 这个方法可以定期调用，清理索引中已过期的任务
-func (rs *RedisJobStore) CleanExpiredJobs() error {
+// CleanExpiredJobs 清理索引中已过期的任务 ID，返回删除的数量
+// 每 redisPipelineChunk 个任务用一次 pipeline 执行 EXISTS，不存在的任务 ID 用一次 ZREM 删除（同时从状态索引中删除）
+func (rs *RedisJobStore) CleanExpiredJobs(ctx context.Context) (int, error) {
     indexKey := "voiceflow:jobs:index"
 
     // 获取所有 JobID
-    jobIDs, err := rs.client.ZRange(rs.ctx, indexKey, 0, -1).Result()
+    jobIDs, err := rs.client.ZRange(ctx, indexKey, 0, -1).Result()
     if err != nil {
-	return err
+	return 0, fmt.Errorf("获取任务索引失败: %w", err)
     }
 
-    // 检查每个任务是否存在
-    for _, jobID := range jobIDs {
-	key := rs.getKey(jobID)
-	exists, err := rs.client.Exists(rs.ctx, key).Result()
-	if err != nil {
+    removed := 0
+    for start := 0; start < len(jobIDs); start += redisPipelineChunk {
+	chunk := jobIDs[start:min(start+redisPipelineChunk, len(jobIDs))]
+	cmds := make([]*redis.IntCmd, len(chunk))
+	if _, err := rs.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+	    for i, jobID := range chunk {
+		cmds[i] = pipe.Exists(ctx, rs.getKey(jobID))
+	    }
+	    return nil
+	}); err != nil {
+	    return removed, fmt.Errorf("检查任务是否存在失败: %w", err)
+	}
+
+	// 任务不存在的，从索引中删除
+	expired := make([]any, 0)
+	for i, cmd := range cmds {
+	    if cmd.Val() == 0 {
+		expired = append(expired, chunk[i])
+	    }
+	}
+	if len(expired) == 0 {
 	    continue
 	}
+	n, err := rs.client.ZRem(ctx, indexKey, expired...).Result()
+	if err != nil {
+	    return removed, fmt.Errorf("清理任务索引失败: %w", err)
+	}
+	if err := rs.unindexStatus(ctx, expired...); err != nil {
+	    return removed, err
+	}
+	removed += int(n)
+    }
+
+    return removed, nil
+}
+
+// maintainIndex 后台索引维护：启动时清理一次，之后每隔 cleanInterval 清理一次
+func (rs *RedisJobStore) maintainIndex() {
+    defer rs.wg.Done()
+
+    ticker := time.NewTicker(rs.cleanInterval)
+    defer ticker.Stop()
+
+    for {
+	rs.cleanIndex()
+
+	select {
+	case <-rs.ctx.Done():
+	    return
+	case <-ticker.C:
+	}
+    }
+}
+
+// cleanIndex 执行一次索引清理并记录结果
+func (rs *RedisJobStore) cleanIndex() {
+    ctx, cancel := context.WithTimeout(rs.ctx, redisMaintenanceTimeout)
+    defer cancel()
+
+    removed, err := rs.CleanExpiredJobs(ctx)
+
+    rs.maintMu.Lock()
+    now := time.Now()
+    rs.maint.LastCleanAt = &now
+    rs.maint.LastRemoved = removed
+    rs.maint.TotalRemoved += removed
+    rs.maint.LastError = ""
+    if err != nil {
+	rs.maint.LastError = err.Error()
+    }
+    rs.maintMu.Unlock()
 
-	// 如果任务不存在，从索引中删除
-	if exists == 0 {
-	    rs.client.ZRem(rs.ctx, indexKey, jobID)
+    if err != nil {
+	if rs.ctx.Err() != nil {
+	    return // 正在关闭
 	}
+	slog.Warn("⚠️ 清理 Redis 任务索引失败", "removed", removed, "error", err)
+	return
+    }
+    if removed > 0 {
+	slog.Info("🧹 已清理 Redis 索引中过期的任务", "count", removed)
     }
+}
 
-    return nil
+// IndexMaintenance 返回后台索引维护的状态（未启用后台维护时返回 nil）
+func (rs *RedisJobStore) IndexMaintenance() *IndexMaintenanceStats {
+    if rs.cleanInterval <= 0 {
+	return nil
+    }
+
+    rs.maintMu.Lock()
+    defer rs.maintMu.Unlock()
+
+    stats := rs.maint
+    stats.Interval = rs.cleanInterval.String()
+    return &stats
 }
@@ -0,0 +1,522 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/z-wentao/voiceflow/pkg/models"
+)
+
+// saveRedisJobs 保存 n 个任务，job-0 最早创建
+func saveRedisJobs(t testing.TB, store *RedisJobStore, n int) {
+	t.Helper()
+	ctx := context.Background()
+	base := time.Now().Add(-time.Duration(n) * time.Minute)
+	for i := 0; i < n; i++ {
+		job := &models.TranscriptionJob{
+			JobID:     fmt.Sprintf("job-%d", i),
+			Status:    models.StatusCompleted,
+			Result:    "hello world",
+			CreatedAt: base.Add(time.Duration(i) * time.Minute),
+		}
+		if err := store.Save(ctx, job); err != nil {
+			t.Fatalf("Save: %v", err)
+		}
+	}
+}
+
+func TestRedisList(t *testing.T) {
+	tests := []struct {
+		name      string
+		jobs      int
+		limit     int
+		all       bool
+		expire    []string // 从 Redis 中删除的任务（模拟过期）
+		wantFirst string
+		wantCount int
+	}{
+		{"按创建时间倒序", 5, 0, false, nil, "job-4", 5},
+		{"只读取 limit 个", 250, 20, false, nil, "job-249", 20},
+		{"跨越多个 pipeline 分块", 250, 0, true, nil, "job-249", 250},
+		{"跳过已过期的任务", 10, 0, false, []string{"job-9", "job-3"}, "job-8", 8},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			store, mr := newTestRedisStore(t)
+			store.SetListLimit(tt.limit)
+			saveRedisJobs(t, store, tt.jobs)
+			for _, id := range tt.expire {
+				mr.Del(store.getKey(id))
+			}
+
+			list := store.List
+			if tt.all {
+				list = store.ListAll
+			}
+			jobs, err := list(ctx)
+			if err != nil {
+				t.Fatalf("List: %v", err)
+			}
+			if len(jobs) != tt.wantCount || jobs[0].JobID != tt.wantFirst {
+				t.Fatalf("返回 %d 个任务，第一个 %s，期望 %d 个，第一个 %s", len(jobs), jobs[0].JobID, tt.wantCount, tt.wantFirst)
+			}
+			for i := 1; i < len(jobs); i++ {
+				if jobs[i].CreatedAt.After(jobs[i-1].CreatedAt) {
+					t.Fatalf("第 %d 个任务 %s 比前一个新", i, jobs[i].JobID)
+				}
+			}
+
+			// 已过期的任务从索引中删除
+			indexed, err := mr.ZMembers("voiceflow:jobs:index")
+			if err != nil {
+				t.Fatalf("ZMembers: %v", err)
+			}
+			if len(indexed) != tt.jobs-len(tt.expire) {
+				t.Fatalf("索引中有 %d 个任务，期望 %d 个", len(indexed), tt.jobs-len(tt.expire))
+			}
+		})
+	}
+}
+
+// BenchmarkRedisList 列出 500 个任务：pipeline 分块读取与逐个 Get 比较
+func BenchmarkRedisList(b *testing.B) {
+	const jobs = 500
+	benchmarks := []struct {
+		name string
+		list func(ctx context.Context, store *RedisJobStore) (int, error)
+	}{
+		{"pipeline", func(ctx context.Context, store *RedisJobStore) (int, error) {
+			list, err := store.ListAll(ctx)
+			return len(list), err
+		}},
+		{"sequential", func(ctx context.Context, store *RedisJobStore) (int, error) {
+			ids, err := store.client.ZRevRange(ctx, "voiceflow:jobs:index", 0, -1).Result()
+			if err != nil {
+				return 0, err
+			}
+			for _, id := range ids {
+				if _, err := store.Get(ctx, id); err != nil {
+					return 0, err
+				}
+			}
+			return len(ids), nil
+		}},
+	}
+	for _, bm := range benchmarks {
+		b.Run(bm.name, func(b *testing.B) {
+			ctx := context.Background()
+			store, _ := newTestRedisStore(b)
+			saveRedisJobs(b, store, jobs)
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				n, err := bm.list(ctx, store)
+				if err != nil || n != jobs {
+					b.Fatalf("读取 %d 个任务: %v", n, err)
+				}
+			}
+		})
+	}
+}
+
+// hsetRecorder 记录客户端发出的 HSET 命令写入的字段名，以及 HSET / SET 写入的字节数（包括 pipeline 和事务中的命令）
+type hsetRecorder struct {
+	mu      sync.Mutex
+	fields  []string
+	written int
+}
+
+func (r *hsetRecorder) DialHook(next redis.DialHook) redis.DialHook { return next }
+
+func (r *hsetRecorder) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		r.record(cmd)
+		return next(ctx, cmd)
+	}
+}
+
+func (r *hsetRecorder) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		for _, cmd := range cmds {
+			r.record(cmd)
+		}
+		return next(ctx, cmds)
+	}
+}
+
+func (r *hsetRecorder) record(cmd redis.Cmder) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	args := cmd.Args()
+	switch cmd.Name() {
+	case "hset":
+		for i := 2; i+1 < len(args); i += 2 {
+			r.fields = append(r.fields, fmt.Sprint(args[i]))
+			r.written += argLen(args[i+1])
+		}
+	case "set":
+		r.written += argLen(args[2])
+	}
+}
+
+// argLen 命令参数的字节数
+func argLen(arg any) int {
+	if data, ok := arg.([]byte); ok {
+		return len(data)
+	}
+	return len(fmt.Sprint(arg))
+}
+
+func (r *hsetRecorder) take() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	fields := r.fields
+	r.fields = nil
+	sort.Strings(fields)
+	return fields
+}
+
+// TestRedisUpdateWritesChangedFields 任务保存为哈希，Update 只写回发生变化的字段，不重写转录结果等大字段
+func TestRedisUpdateWritesChangedFields(t *testing.T) {
+	tests := []struct {
+		name   string
+		update func(*models.TranscriptionJob)
+		want   string
+	}{
+		{"进度更新", func(j *models.TranscriptionJob) { j.Progress = 50 }, "[progress version]"},
+		{"状态更新", func(j *models.TranscriptionJob) { j.Status = models.StatusCompleted; j.Progress = 100 }, "[progress status version]"},
+		{"写入单词", func(j *models.TranscriptionJob) { j.Vocabulary = []string{"apple"} }, "[version vocabulary]"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			store, _ := newTestRedisStore(t)
+			job := &models.TranscriptionJob{JobID: "job-1", Status: models.StatusProcessing, Result: strings.Repeat("hello ", 50000)}
+			if err := store.Save(ctx, job); err != nil {
+				t.Fatalf("Save: %v", err)
+			}
+
+			recorder := &hsetRecorder{}
+			store.client.AddHook(recorder)
+			if err := store.Update(ctx, "job-1", tt.update); err != nil {
+				t.Fatalf("Update: %v", err)
+			}
+			if got := fmt.Sprint(recorder.take()); got != tt.want {
+				t.Fatalf("写入的字段 %s，期望 %s", got, tt.want)
+			}
+
+			got, err := store.Get(ctx, "job-1")
+			if err != nil {
+				t.Fatalf("Get: %v", err)
+			}
+			want := *job
+			tt.update(&want)
+			if got.Result != want.Result || got.Progress != want.Progress || got.Status != want.Status || len(got.Vocabulary) != len(want.Vocabulary) {
+				t.Fatalf("更新后的任务 = %+v", got)
+			}
+		})
+	}
+}
+
+// TestRedisLegacyJobMigration 旧版本保存的 JSON 字符串在读取或更新时迁移为哈希，过期时间不变
+func TestRedisLegacyJobMigration(t *testing.T) {
+	tests := []struct {
+		name   string
+		access func(ctx context.Context, store *RedisJobStore) error
+	}{
+		{"Get", func(ctx context.Context, store *RedisJobStore) error {
+			job, err := store.Get(ctx, "job-1")
+			if err == nil && job.Result != "hello" {
+				err = fmt.Errorf("Result = %q", job.Result)
+			}
+			return err
+		}},
+		{"Update", func(ctx context.Context, store *RedisJobStore) error {
+			return store.Update(ctx, "job-1", func(j *models.TranscriptionJob) { j.Progress = 100 })
+		}},
+		{"List", func(ctx context.Context, store *RedisJobStore) error {
+			jobs, err := store.List(ctx)
+			if err == nil && (len(jobs) != 1 || jobs[0].Result != "hello") {
+				err = fmt.Errorf("List = %+v", jobs)
+			}
+			return err
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			store, mr := newTestRedisStore(t)
+			key := store.getKey("job-1")
+			mr.Set(key, `{"job_id":"job-1","status":"completed","result":"hello","RabbitMQDelivery":{}}`)
+			mr.SetTTL(key, time.Hour)
+			mr.ZAdd("voiceflow:jobs:index", 1, "job-1")
+
+			if err := tt.access(ctx, store); err != nil {
+				t.Fatalf("读取旧格式任务: %v", err)
+			}
+			if typ := mr.Type(key); typ != "hash" {
+				t.Fatalf("key 类型 = %s，期望迁移为 hash", typ)
+			}
+			if ttl := mr.TTL(key); ttl <= 0 || ttl > time.Hour {
+				t.Fatalf("迁移后的过期时间 = %s", ttl)
+			}
+			job, err := store.Get(ctx, "job-1")
+			if err != nil || job.Result != "hello" || job.SchemaVersion != models.CurrentSchemaVersion {
+				t.Fatalf("迁移后的任务 = %+v, %v", job, err)
+			}
+		})
+	}
+}
+
+// BenchmarkRedisProgressUpdate 带 300KB 转录结果的任务更新进度：
+// 哈希格式只写回变化的字段，旧的整文档格式每次读取并重写整个 JSON（written-B/op 为每次更新写入 Redis 的字节数）
+func BenchmarkRedisProgressUpdate(b *testing.B) {
+	job := &models.TranscriptionJob{JobID: "job-1", Status: models.StatusProcessing, Result: strings.Repeat("hello ", 50000)}
+	benchmarks := []struct {
+		name   string
+		update func(ctx context.Context, store *RedisJobStore, progress int) error
+	}{
+		{"hash", func(ctx context.Context, store *RedisJobStore, progress int) error {
+			return store.Update(ctx, job.JobID, func(j *models.TranscriptionJob) { j.Progress = progress })
+		}},
+		{"document", func(ctx context.Context, store *RedisJobStore, progress int) error {
+			key := "voiceflow:bench:document"
+			data, err := store.client.Get(ctx, key).Bytes()
+			if err != nil {
+				return err
+			}
+			j, err := models.UnmarshalJob(data)
+			if err != nil {
+				return err
+			}
+			j.Progress = progress
+			if data, err = models.MarshalJob(j); err != nil {
+				return err
+			}
+			return store.client.Set(ctx, key, data, time.Hour).Err()
+		}},
+	}
+	for _, bm := range benchmarks {
+		b.Run(bm.name, func(b *testing.B) {
+			ctx := context.Background()
+			store, _ := newTestRedisStore(b)
+			if err := store.Save(ctx, job); err != nil {
+				b.Fatalf("Save: %v", err)
+			}
+			data, _ := models.MarshalJob(job)
+			store.client.Set(ctx, "voiceflow:bench:document", data, time.Hour)
+			recorder := &hsetRecorder{}
+			store.client.AddHook(recorder)
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if err := bm.update(ctx, store, i%100); err != nil {
+					b.Fatalf("更新进度: %v", err)
+				}
+			}
+			b.StopTimer()
+			b.ReportMetric(float64(recorder.written)/float64(b.N), "written-B/op")
+		})
+	}
+}
+
+// TestRedisRefreshTTL 剩余过期时间不足 ttl 的一半时刷新任务、单词索引和片段结果；
+// 任务已过期时不会重新出现，索引中残留的任务 ID 在 List 时清理
+func TestRedisRefreshTTL(t *testing.T) {
+	tests := []struct {
+		name    string
+		elapsed time.Duration // 保存后经过的时间（ttl 为 1 小时）
+		wantTTL time.Duration // 刷新后任务的剩余过期时间，0 表示任务已不存在
+	}{
+		{"剩余时间充足不刷新", 20 * time.Minute, 40 * time.Minute},
+		{"剩余时间不足一半时刷新", 40 * time.Minute, time.Hour},
+		{"任务已过期不会重新出现", 61 * time.Minute, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			store, mr := newTestRedisStore(t)
+			if err := store.Save(ctx, &models.TranscriptionJob{JobID: "job-1", Status: models.StatusCompleted, CreatedAt: time.Now()}); err != nil {
+				t.Fatalf("Save: %v", err)
+			}
+			if err := store.IndexWords(ctx, "job-1", []string{"apple"}); err != nil {
+				t.Fatalf("IndexWords: %v", err)
+			}
+			if err := store.SaveSegment(ctx, "job-1", 0, []byte("{}")); err != nil {
+				t.Fatalf("SaveSegment: %v", err)
+			}
+			mr.FastForward(tt.elapsed)
+
+			store.refreshTTL("job-1")
+
+			keys := []string{store.getKey("job-1"), store.jobWordsKey("job-1"), store.segmentsKey("job-1")}
+			for _, key := range keys {
+				if tt.wantTTL == 0 {
+					if mr.Exists(key) {
+						t.Errorf("%s 在过期后重新出现", key)
+					}
+					continue
+				}
+				if got := mr.TTL(key); got != tt.wantTTL {
+					t.Errorf("%s 的剩余过期时间 = %v，期望 %v", key, got, tt.wantTTL)
+				}
+			}
+
+			// 过期任务的索引项不会残留：List 跳过并从索引中删除
+			jobs, err := store.List(ctx)
+			if err != nil {
+				t.Fatalf("List: %v", err)
+			}
+			indexed, _ := mr.ZMembers("voiceflow:jobs:index")
+			if wantExists := tt.wantTTL > 0; (len(jobs) == 1) != wantExists || (len(indexed) == 1) != wantExists {
+				t.Errorf("List 返回 %d 个任务，索引中 %v，期望任务存在 = %v", len(jobs), indexed, wantExists)
+			}
+		})
+	}
+}
+
+// TestRedisGetRefreshesOnAccess 开启 SetRefreshOnAccess 后 Get 异步刷新过期时间，默认关闭
+func TestRedisGetRefreshesOnAccess(t *testing.T) {
+	tests := []struct {
+		name    string
+		enabled bool
+		wantTTL time.Duration
+	}{
+		{"默认不刷新", false, 20 * time.Minute},
+		{"开启后 Get 刷新", true, time.Hour},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			store, mr := newTestRedisStore(t)
+			store.SetRefreshOnAccess(tt.enabled)
+			if err := store.Save(ctx, &models.TranscriptionJob{JobID: "job-1", Status: models.StatusCompleted}); err != nil {
+				t.Fatalf("Save: %v", err)
+			}
+			mr.FastForward(40 * time.Minute)
+
+			if _, err := store.Get(ctx, "job-1"); err != nil {
+				t.Fatalf("Get: %v", err)
+			}
+			key := store.getKey("job-1")
+			if tt.enabled {
+				waitFor(t, "刷新过期时间", func() bool { return mr.TTL(key) == tt.wantTTL })
+				return
+			}
+			// 关闭时不启动异步刷新：稍等片刻后过期时间仍未改变
+			time.Sleep(20 * time.Millisecond)
+			if got := mr.TTL(key); got != tt.wantTTL {
+				t.Fatalf("剩余过期时间 = %v，期望 %v", got, tt.wantTTL)
+			}
+		})
+	}
+}
+
+// TestRedisMarkers 标记不随任务 TTL 过期；旧版本保存在哈希登记中的值在第一次登记时迁移
+func TestRedisMarkers(t *testing.T) {
+	tests := []struct {
+		name        string
+		setup       func(t *testing.T, store *RedisJobStore)
+		value       string
+		wantValue   string
+		wantCreated bool
+	}{
+		{
+			name:        "新登记",
+			setup:       func(t *testing.T, store *RedisJobStore) {},
+			value:       "job-1",
+			wantValue:   "job-1",
+			wantCreated: true,
+		},
+		{
+			name: "已登记",
+			setup: func(t *testing.T, store *RedisJobStore) {
+				if _, _, err := store.SetMarkerIfAbsent(context.Background(), "feed:abc", "job-1"); err != nil {
+					t.Fatal(err)
+				}
+			},
+			value:     "job-2",
+			wantValue: "job-1",
+		},
+		{
+			name: "迁移旧版本的哈希登记",
+			setup: func(t *testing.T, store *RedisJobStore) {
+				if _, _, err := store.SetHashIfAbsent(context.Background(), "feed:abc", "job-old"); err != nil {
+					t.Fatal(err)
+				}
+			},
+			value:     "job-2",
+			wantValue: "job-old",
+		},
+		{
+			name: "删除后重新登记",
+			setup: func(t *testing.T, store *RedisJobStore) {
+				ctx := context.Background()
+				if _, _, err := store.SetMarkerIfAbsent(ctx, "feed:abc", "job-1"); err != nil {
+					t.Fatal(err)
+				}
+				// 只删除仍为指定值的登记
+				if err := store.DeleteMarker(ctx, "feed:abc", "job-other"); err != nil {
+					t.Fatal(err)
+				}
+				if err := store.DeleteMarker(ctx, "feed:abc", "job-1"); err != nil {
+					t.Fatal(err)
+				}
+			},
+			value:       "job-2",
+			wantValue:   "job-2",
+			wantCreated: true,
+		},
+		{
+			name: "迁移后删除再重新登记",
+			setup: func(t *testing.T, store *RedisJobStore) {
+				ctx := context.Background()
+				if _, _, err := store.SetHashIfAbsent(ctx, "feed:abc", "job-old"); err != nil {
+					t.Fatal(err)
+				}
+				if _, _, err := store.SetMarkerIfAbsent(ctx, "feed:abc", "job-1"); err != nil {
+					t.Fatal(err)
+				}
+				if err := store.DeleteMarker(ctx, "feed:abc", "job-old"); err != nil {
+					t.Fatal(err)
+				}
+			},
+			value:       "job-2",
+			wantValue:   "job-2",
+			wantCreated: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			store, mr := newTestRedisStore(t)
+			tt.setup(t, store)
+
+			value, created, err := SetMarkerIfAbsent(ctx, store, "feed:abc", tt.value)
+			if err != nil {
+				t.Fatalf("SetMarkerIfAbsent: %v", err)
+			}
+			if value != tt.wantValue || created != tt.wantCreated {
+				t.Fatalf("SetMarkerIfAbsent = (%s, %v)，期望 (%s, %v)", value, created, tt.wantValue, tt.wantCreated)
+			}
+
+			// 超过任务的 TTL 后登记仍然存在
+			mr.FastForward(2 * time.Hour)
+			value, created, err = SetMarkerIfAbsent(ctx, store, "feed:abc", "job-3")
+			if err != nil {
+				t.Fatalf("SetMarkerIfAbsent: %v", err)
+			}
+			if created || value != tt.wantValue {
+				t.Fatalf("TTL 之后 SetMarkerIfAbsent = (%s, %v)，期望仍为 %s", value, created, tt.wantValue)
+			}
+		})
+	}
+}
@@ -0,0 +1,37 @@
+package storage
+
+import (
+	"strings"
+
+	"github.com/z-wentao/voiceflow/pkg/models"
+)
+
+// searchLimit 搜索结果数量上限
+const searchLimit = 100
+
+// matchJob 判断任务的文件名或转录文本是否包含关键词（不区分大小写）
+func matchJob(job *models.TranscriptionJob, query string) bool {
+	q := strings.ToLower(query)
+	return strings.Contains(strings.ToLower(job.Filename), q) ||
+		strings.Contains(strings.ToLower(job.Result), q)
+}
+
+// filterJobs 逐个扫描任务，返回匹配关键词的任务（用于没有索引的存储）
+func filterJobs(jobs []*models.TranscriptionJob, query string) []*models.TranscriptionJob {
+	matched := make([]*models.TranscriptionJob, 0)
+	for _, job := range jobs {
+		if matchJob(job, query) {
+			matched = append(matched, job)
+			if len(matched) >= searchLimit {
+				break
+			}
+		}
+	}
+	return matched
+}
+
+// likePattern 将关键词转换为 LIKE 模式（转义 % 和 _）
+func likePattern(query string) string {
+	escaped := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(query)
+	return "%" + escaped + "%"
+}
@@ -0,0 +1,51 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// PostgreSQL 与 SQLite 共用的片段结果读写（job_segments 表），placeholder 生成第 n 个参数的占位符
+
+// saveSegment 保存片段结果（同一片段重复保存时覆盖）
+func saveSegment(ctx context.Context, db *sql.DB, placeholder func(n int) string, jobID string, index int, data []byte) error {
+	query := `INSERT INTO job_segments (job_id, segment_index, data, updated_at)
+    VALUES (` + placeholder(1) + `, ` + placeholder(2) + `, ` + placeholder(3) + `, ` + placeholder(4) + `)
+    ON CONFLICT (job_id, segment_index)
+    DO UPDATE SET data = excluded.data, updated_at = excluded.updated_at`
+
+	if _, err := db.ExecContext(ctx, query, jobID, index, string(data), time.Now()); err != nil {
+		return fmt.Errorf("保存片段结果失败: %w", err)
+	}
+	return nil
+}
+
+// loadSegments 读取任务已保存的片段结果
+func loadSegments(ctx context.Context, db *sql.DB, placeholder func(n int) string, jobID string) (map[int][]byte, error) {
+	rows, err := db.QueryContext(ctx, `SELECT segment_index, data FROM job_segments WHERE job_id = `+placeholder(1), jobID)
+	if err != nil {
+		return nil, fmt.Errorf("读取片段结果失败: %w", err)
+	}
+	defer rows.Close()
+
+	segments := make(map[int][]byte)
+	for rows.Next() {
+		var index int
+		var data string
+		if err := rows.Scan(&index, &data); err != nil {
+			return nil, fmt.Errorf("读取片段结果失败: %w", err)
+		}
+		segments[index] = []byte(data)
+	}
+	return segments, rows.Err()
+}
+
+// deleteSegments 删除任务的片段结果
+func deleteSegments(ctx context.Context, db *sql.DB, placeholder func(n int) string, jobID string) error {
+	if _, err := db.ExecContext(ctx, `DELETE FROM job_segments WHERE job_id = `+placeholder(1), jobID); err != nil {
+		return fmt.Errorf("删除片段结果失败: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,739 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/z-wentao/voiceflow/pkg/models"
+	_ "modernc.org/sqlite" // 纯 Go 实现，无需 cgo
+)
+
+// SQLiteJobStore SQLite 任务存储（单文件部署，适合个人自托管）
+// 表结构与 PostgreSQL 一致，vocabulary / vocab_detail 以 JSON 文本保存
+type SQLiteJobStore struct {
+	db *sql.DB
+	mu sync.Mutex // 保证 Update 的读-改-写不被其他写入打断
+}
+
+// sqliteSchema 建表语句（幂等）
+const sqliteSchema = `
+CREATE TABLE IF NOT EXISTS transcription_jobs (
+    job_id TEXT PRIMARY KEY,
+    filename TEXT NOT NULL,
+    file_path TEXT,
+    status TEXT NOT NULL,
+    progress INTEGER DEFAULT 0,
+    result TEXT,
+    subtitle_path TEXT,
+    vtt_path TEXT,
+    bilingual_srt_path TEXT,
+    bilingual_vtt_path TEXT,
+    language TEXT,
+    duration REAL,
+    error TEXT,
+    vocabulary TEXT,
+    vocab_detail TEXT,
+    created_at TIMESTAMP NOT NULL,
+    completed_at TIMESTAMP,
+    media_purged INTEGER NOT NULL DEFAULT 0,
+    source TEXT,
+    sync_history TEXT,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    content_hash TEXT,
+    priority INTEGER NOT NULL DEFAULT 0,
+    user_id TEXT,
+    last_updated TIMESTAMP,
+    file_size INTEGER,
+    prompt TEXT,
+    temperature REAL,
+    model TEXT,
+    version INTEGER NOT NULL DEFAULT 1,
+    deleted_at TIMESTAMP,
+    retry_count INTEGER NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMP,
+    estimated_cost REAL NOT NULL DEFAULT 0,
+    prompt_tokens INTEGER NOT NULL DEFAULT 0,
+    completion_tokens INTEGER NOT NULL DEFAULT 0
+);
+CREATE INDEX IF NOT EXISTS idx_jobs_status ON transcription_jobs(status);
+CREATE INDEX IF NOT EXISTS idx_jobs_created_at ON transcription_jobs(created_at DESC);
+
+CREATE TABLE IF NOT EXISTS openai_usage (
+    month TEXT PRIMARY KEY,
+    cost_usd REAL NOT NULL DEFAULT 0,
+    updated_at TIMESTAMP NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS job_content_hashes (
+    content_hash TEXT PRIMARY KEY,
+    job_id TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS job_words (
+    word TEXT NOT NULL,
+    job_id TEXT NOT NULL,
+    PRIMARY KEY (word, job_id)
+);
+CREATE INDEX IF NOT EXISTS idx_job_words_job_id ON job_words(job_id);
+
+CREATE TABLE IF NOT EXISTS user_credentials (
+    user_id TEXT NOT NULL,
+    name TEXT NOT NULL,
+    value TEXT NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (user_id, name)
+);
+
+CREATE TABLE IF NOT EXISTS job_segments (
+    job_id TEXT NOT NULL,
+    segment_index INTEGER NOT NULL,
+    data TEXT NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (job_id, segment_index)
+);
+`
+
+// sqliteColumnMigrations 旧数据库文件需要补充的列（以及依赖新列的索引）
+// SQLite 不支持 ADD COLUMN IF NOT EXISTS，列已存在时忽略错误
+var sqliteColumnMigrations = []string{
+	`ALTER TABLE transcription_jobs ADD COLUMN media_purged INTEGER NOT NULL DEFAULT 0`,
+	`ALTER TABLE transcription_jobs ADD COLUMN source TEXT`,
+	`ALTER TABLE transcription_jobs ADD COLUMN sync_history TEXT`,
+	`ALTER TABLE transcription_jobs ADD COLUMN attempts INTEGER NOT NULL DEFAULT 0`,
+	`ALTER TABLE transcription_jobs ADD COLUMN content_hash TEXT`,
+	`CREATE INDEX IF NOT EXISTS idx_jobs_content_hash ON transcription_jobs(content_hash)`,
+	`ALTER TABLE transcription_jobs ADD COLUMN priority INTEGER NOT NULL DEFAULT 0`,
+	`ALTER TABLE transcription_jobs ADD COLUMN user_id TEXT`,
+	`CREATE INDEX IF NOT EXISTS idx_jobs_user_id ON transcription_jobs(user_id, created_at DESC)`,
+	`ALTER TABLE transcription_jobs ADD COLUMN last_updated TIMESTAMP`,
+	`ALTER TABLE transcription_jobs ADD COLUMN file_size INTEGER`,
+	`ALTER TABLE transcription_jobs ADD COLUMN prompt TEXT`,
+	`ALTER TABLE transcription_jobs ADD COLUMN temperature REAL`,
+	`ALTER TABLE transcription_jobs ADD COLUMN model TEXT`,
+	`ALTER TABLE transcription_jobs ADD COLUMN version INTEGER NOT NULL DEFAULT 1`,
+	`ALTER TABLE transcription_jobs ADD COLUMN deleted_at TIMESTAMP`,
+	`ALTER TABLE transcription_jobs ADD COLUMN retry_count INTEGER NOT NULL DEFAULT 0`,
+	`ALTER TABLE transcription_jobs ADD COLUMN next_attempt_at TIMESTAMP`,
+	`ALTER TABLE transcription_jobs ADD COLUMN estimated_cost REAL NOT NULL DEFAULT 0`,
+	`ALTER TABLE transcription_jobs ADD COLUMN prompt_tokens INTEGER NOT NULL DEFAULT 0`,
+	`ALTER TABLE transcription_jobs ADD COLUMN completion_tokens INTEGER NOT NULL DEFAULT 0`,
+}
+
+// sqliteJobColumns 查询任务时使用的列（顺序与 scanSQLiteJob 一致）
+const sqliteJobColumns = `job_id, filename, file_path, status, progress,
+    result, subtitle_path, vtt_path, bilingual_srt_path, bilingual_vtt_path,
+    language, duration, error,
+    vocabulary, vocab_detail, created_at, completed_at, media_purged, source, sync_history, attempts, content_hash, priority, user_id,
+    last_updated, file_size, prompt, temperature, model, version, deleted_at, retry_count, next_attempt_at,
+    estimated_cost, prompt_tokens, completion_tokens`
+
+// NewSQLiteJobStore 创建 SQLite 任务存储
+func NewSQLiteJobStore(path string) (*SQLiteJobStore, error) {
+	if dir := filepath.Dir(path); dir != "" {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return nil, fmt.Errorf("创建数据库目录失败: %w", err)
+		}
+	}
+
+	// WAL 模式提升并发读性能，busy_timeout 避免并发写时立即报 SQLITE_BUSY
+	dsn := fmt.Sprintf("file:%s?_pragma=journal_mode(WAL)&_pragma=busy_timeout(5000)", path)
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("打开数据库失败: %w", err)
+	}
+
+	// SQLite 同一时间只允许一个写连接
+	db.SetMaxOpenConns(1)
+
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("连接数据库失败: %w", err)
+	}
+
+	if _, err := db.Exec(sqliteSchema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("初始化表结构失败: %w", err)
+	}
+	for _, stmt := range sqliteColumnMigrations {
+		if _, err := db.Exec(stmt); err != nil && !strings.Contains(err.Error(), "duplicate column") {
+			db.Close()
+			return nil, fmt.Errorf("升级表结构失败: %w", err)
+		}
+	}
+
+	return &SQLiteJobStore{db: db}, nil
+}
+
+// Save 保存任务（UPSERT，job.Version > 0 时检查版本号）
+func (s *SQLiteJobStore) Save(ctx context.Context, job *models.TranscriptionJob) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.save(ctx, job)
+}
+
+// save 写入任务（调用方需持有锁），成功后更新 job.Version
+// 写入的 version 为期望的版本号 + 1：为 1 时直接覆盖，否则只在表中的版本号等于期望值时覆盖
+func (s *SQLiteJobStore) save(ctx context.Context, job *models.TranscriptionJob) error {
+	vocabularyJSON, err := json.Marshal(job.Vocabulary)
+	if err != nil {
+		return fmt.Errorf("序列化 vocabulary 失败: %w", err)
+	}
+
+	vocabDetailJSON, err := json.Marshal(job.VocabDetail)
+	if err != nil {
+		return fmt.Errorf("序列化 vocab_detail 失败: %w", err)
+	}
+
+	syncHistoryJSON, err := json.Marshal(job.SyncHistory)
+	if err != nil {
+		return fmt.Errorf("序列化 sync_history 失败: %w", err)
+	}
+
+	query := `
+    INSERT INTO transcription_jobs (` + sqliteJobColumns + `)
+    VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+    ON CONFLICT (job_id)
+    DO UPDATE SET
+    file_path = excluded.file_path,
+    status = excluded.status,
+    progress = excluded.progress,
+    result = excluded.result,
+    subtitle_path = excluded.subtitle_path,
+    vtt_path = excluded.vtt_path,
+    bilingual_srt_path = excluded.bilingual_srt_path,
+    bilingual_vtt_path = excluded.bilingual_vtt_path,
+    language = excluded.language,
+    duration = excluded.duration,
+    error = excluded.error,
+    vocabulary = excluded.vocabulary,
+    vocab_detail = excluded.vocab_detail,
+    completed_at = excluded.completed_at,
+    media_purged = excluded.media_purged,
+    sync_history = excluded.sync_history,
+    attempts = excluded.attempts,
+    content_hash = excluded.content_hash,
+    priority = excluded.priority,
+    user_id = excluded.user_id,
+    last_updated = excluded.last_updated,
+    file_size = excluded.file_size,
+    prompt = excluded.prompt,
+    temperature = excluded.temperature,
+    model = excluded.model,
+    deleted_at = excluded.deleted_at,
+    retry_count = excluded.retry_count,
+    next_attempt_at = excluded.next_attempt_at,
+    estimated_cost = excluded.estimated_cost,
+    prompt_tokens = excluded.prompt_tokens,
+    completion_tokens = excluded.completion_tokens,
+    version = transcription_jobs.version + 1
+    WHERE excluded.version = 1 OR transcription_jobs.version = excluded.version - 1
+    RETURNING version
+    `
+
+	var version int64
+	err = s.db.QueryRowContext(ctx, query,
+		job.JobID,
+		job.Filename,
+		job.FilePath,
+		job.Status,
+		job.Progress,
+		job.Result,
+		job.SubtitlePath,
+		job.VTTPath,
+		job.BilingualSRTPath,
+		job.BilingualVTTPath,
+		job.Language,
+		job.Duration,
+		job.Error,
+		string(vocabularyJSON),
+		string(vocabDetailJSON),
+		job.CreatedAt,
+		job.CompletedAt,
+		job.MediaPurged,
+		job.Source,
+		string(syncHistoryJSON),
+		job.Attempts,
+		job.ContentHash,
+		job.Priority,
+		job.UserID,
+		job.LastUpdated,
+		job.FileSize,
+		job.Prompt,
+		job.Temperature,
+		job.Model,
+		job.Version+1,
+		nullTime(job.DeletedAt),
+		job.RetryCount,
+		nullTime(job.NextAttemptAt),
+		job.EstimatedCost,
+		job.PromptTokens,
+		job.CompletionTokens,
+	).Scan(&version)
+	if err == sql.ErrNoRows {
+		return versionConflict(job.JobID)
+	}
+	if err != nil {
+		return fmt.Errorf("保存到数据库失败: %w", err)
+	}
+
+	job.Version = version
+	return nil
+}
+
+// scanner 兼容 *sql.Row 和 *sql.Rows
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+// scanSQLiteJob 扫描一行任务数据并处理 NULL 值
+func scanSQLiteJob(row scanner) (*models.TranscriptionJob, error) {
+	var job models.TranscriptionJob
+	var result, filePath, subtitlePath, vttPath, bilingualSRTPath, bilingualVTTPath, language, errorMsg sql.NullString
+	var vocabularyJSON, vocabDetailJSON, source, syncHistoryJSON, contentHash, userID, prompt, model sql.NullString
+	var duration, temperature sql.NullFloat64
+	var fileSize sql.NullInt64
+	var completedAt, lastUpdated, deletedAt, nextAttemptAt sql.NullTime
+
+	err := row.Scan(
+		&job.JobID,
+		&job.Filename,
+		&filePath,
+		&job.Status,
+		&job.Progress,
+		&result,
+		&subtitlePath,
+		&vttPath,
+		&bilingualSRTPath,
+		&bilingualVTTPath,
+		&language,
+		&duration,
+		&errorMsg,
+		&vocabularyJSON,
+		&vocabDetailJSON,
+		&job.CreatedAt,
+		&completedAt,
+		&job.MediaPurged,
+		&source,
+		&syncHistoryJSON,
+		&job.Attempts,
+		&contentHash,
+		&job.Priority,
+		&userID,
+		&lastUpdated,
+		&fileSize,
+		&prompt,
+		&temperature,
+		&model,
+		&job.Version,
+		&deletedAt,
+		&job.RetryCount,
+		&nextAttemptAt,
+		&job.EstimatedCost,
+		&job.PromptTokens,
+		&job.CompletionTokens,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	job.FilePath = filePath.String
+	job.Source = source.String
+	job.ContentHash = contentHash.String
+	job.UserID = userID.String
+	job.Result = result.String
+	job.SubtitlePath = subtitlePath.String
+	job.VTTPath = vttPath.String
+	job.BilingualSRTPath = bilingualSRTPath.String
+	job.BilingualVTTPath = bilingualVTTPath.String
+	job.Language = language.String
+	job.Duration = duration.Float64
+	job.FileSize = fileSize.Int64
+	job.Prompt = prompt.String
+	job.Temperature = temperature.Float64
+	job.Model = model.String
+	job.Error = errorMsg.String
+	if completedAt.Valid {
+		job.CompletedAt = completedAt.Time
+	}
+	if lastUpdated.Valid {
+		job.LastUpdated = lastUpdated.Time
+	}
+	if deletedAt.Valid {
+		job.DeletedAt = deletedAt.Time
+	}
+	if nextAttemptAt.Valid {
+		job.NextAttemptAt = nextAttemptAt.Time
+	}
+
+	// 反序列化 JSON 字段
+	if vocabularyJSON.String != "" {
+		json.Unmarshal([]byte(vocabularyJSON.String), &job.Vocabulary)
+	}
+	if vocabDetailJSON.String != "" {
+		json.Unmarshal([]byte(vocabDetailJSON.String), &job.VocabDetail)
+	}
+	if syncHistoryJSON.String != "" {
+		json.Unmarshal([]byte(syncHistoryJSON.String), &job.SyncHistory)
+	}
+
+	return &job, nil
+}
+
+// Get 获取任务
+func (s *SQLiteJobStore) Get(ctx context.Context, jobID string) (*models.TranscriptionJob, error) {
+	query := `SELECT ` + sqliteJobColumns + ` FROM transcription_jobs WHERE job_id = ?`
+
+	job, err := scanSQLiteJob(s.db.QueryRowContext(ctx, query, jobID))
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("任务不存在: %s", jobID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("查询数据库失败: %w", err)
+	}
+
+	return job, nil
+}
+
+// Update 更新任务
+// 单进程部署，用互斥锁保证读-改-写期间没有其他写入
+func (s *SQLiteJobStore) Update(ctx context.Context, jobID string, updateFn func(*models.TranscriptionJob)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	job, err := s.Get(ctx, jobID)
+	if err != nil {
+		return err
+	}
+
+	updateFn(job)
+
+	return s.save(ctx, job)
+}
+
+// List 列出最近的任务（按创建时间倒序）
+func (s *SQLiteJobStore) List(ctx context.Context) ([]*models.TranscriptionJob, error) {
+	return s.list(ctx, `SELECT `+sqliteJobColumns+` FROM transcription_jobs WHERE `+notDeleted+` ORDER BY created_at DESC LIMIT 100`)
+}
+
+// ListAll 列出所有任务
+func (s *SQLiteJobStore) ListAll(ctx context.Context) ([]*models.TranscriptionJob, error) {
+	return s.list(ctx, `SELECT `+sqliteJobColumns+` FROM transcription_jobs WHERE `+notDeleted+` ORDER BY created_at DESC`)
+}
+
+// ListForUser 列出指定用户的任务（按创建时间倒序，LIMIT -1 表示不限制）
+func (s *SQLiteJobStore) ListForUser(ctx context.Context, userID string, limit int) ([]*models.TranscriptionJob, error) {
+	if limit <= 0 {
+		limit = -1
+	}
+	return s.list(ctx, `SELECT `+sqliteJobColumns+` FROM transcription_jobs WHERE user_id = ? AND `+notDeleted+` ORDER BY created_at DESC LIMIT ?`, userID, limit)
+}
+
+// ListSummaries 列出指定状态任务的轻量投影（只查询投影需要的列）
+func (s *SQLiteJobStore) ListSummaries(ctx context.Context, statuses ...models.JobStatus) ([]models.JobSummary, error) {
+	condition, args := statusCondition(statuses, func(int) string { return "?" })
+	query := `SELECT ` + summaryColumns + ` FROM transcription_jobs WHERE ` + condition + ` AND ` + notDeleted + ` ORDER BY created_at DESC`
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("查询数据库失败: %w", err)
+	}
+	return scanSummaries(rows)
+}
+
+// CountByStatus 按状态统计任务数量（GROUP BY status）
+func (s *SQLiteJobStore) CountByStatus(ctx context.Context, userID string) (map[models.JobStatus]int, error) {
+	return countStatuses(ctx, s.db, userID, "?")
+}
+
+// Stats 汇总任务统计（一条聚合查询）
+func (s *SQLiteJobStore) Stats(ctx context.Context, userID string, since time.Time) (models.JobStats, error) {
+	return queryStats(ctx, s.db, userID, since, sqlitePlaceholder)
+}
+
+// DeleteOlderThan 删除创建时间早于 t 的已结束任务及其单词索引
+func (s *SQLiteJobStore) DeleteOlderThan(ctx context.Context, t time.Time) (int, error) {
+	return deleteOlderThan(ctx, s.db, t, func(int) string { return "?" })
+}
+
+// ListByStatus 列出指定状态的任务（按创建时间正序）
+func (s *SQLiteJobStore) ListByStatus(ctx context.Context, statuses ...models.JobStatus) ([]*models.TranscriptionJob, error) {
+	condition, args := statusCondition(statuses, func(int) string { return "?" })
+	return s.list(ctx, `SELECT `+sqliteJobColumns+` FROM transcription_jobs WHERE `+condition+` AND `+notDeleted+` ORDER BY created_at ASC`, args...)
+}
+
+// Search 搜索任务（LIKE 子串匹配，ASCII 字母不区分大小写）
+func (s *SQLiteJobStore) Search(ctx context.Context, query string) ([]*models.TranscriptionJob, error) {
+	pattern := likePattern(query)
+	return s.list(ctx, `SELECT `+sqliteJobColumns+` FROM transcription_jobs
+    WHERE (filename LIKE ? ESCAPE '\' OR result LIKE ? ESCAPE '\') AND `+notDeleted+`
+    ORDER BY created_at DESC LIMIT ?`, pattern, pattern, searchLimit)
+}
+
+// list 执行查询并扫描任务列表
+func (s *SQLiteJobStore) list(ctx context.Context, query string, args ...any) ([]*models.TranscriptionJob, error) {
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("查询数据库失败: %w", err)
+	}
+	defer rows.Close()
+
+	jobs := make([]*models.TranscriptionJob, 0)
+	for rows.Next() {
+		job, err := scanSQLiteJob(rows)
+		if err != nil {
+			continue
+		}
+		jobs = append(jobs, job)
+	}
+
+	return jobs, rows.Err()
+}
+
+// Delete 软删除任务（记录 deleted_at，持有锁以免打断进行中的 Update）
+func (s *SQLiteJobStore) Delete(ctx context.Context, jobID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return softDelete(ctx, s.db, sqlitePlaceholder, jobID)
+}
+
+// Restore 恢复软删除的任务
+func (s *SQLiteJobStore) Restore(ctx context.Context, jobID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return restoreDeleted(ctx, s.db, sqlitePlaceholder, jobID)
+}
+
+// ListDeleted 列出删除时间早于 before 的软删除任务（按删除时间正序）
+func (s *SQLiteJobStore) ListDeleted(ctx context.Context, before time.Time) ([]*models.TranscriptionJob, error) {
+	return s.list(ctx, `SELECT `+sqliteJobColumns+` FROM transcription_jobs
+    WHERE deleted_at IS NOT NULL AND deleted_at < ?
+    ORDER BY deleted_at ASC`, before)
+}
+
+// Purge 永久删除任务及其单词索引和片段结果
+func (s *SQLiteJobStore) Purge(ctx context.Context, jobID string) error {
+	result, err := s.db.ExecContext(ctx, `DELETE FROM transcription_jobs WHERE job_id = ?`, jobID)
+	if err != nil {
+		return fmt.Errorf("删除任务失败: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("获取删除结果失败: %w", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("任务不存在: %s", jobID)
+	}
+
+	if _, err := s.db.ExecContext(ctx, `DELETE FROM job_words WHERE job_id = ?`, jobID); err != nil {
+		return fmt.Errorf("删除单词索引失败: %w", err)
+	}
+	if err := s.DeleteSegments(ctx, jobID); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// SaveSegment 保存片段结果（job_segments 表）
+func (s *SQLiteJobStore) SaveSegment(ctx context.Context, jobID string, index int, data []byte) error {
+	return saveSegment(ctx, s.db, sqlitePlaceholder, jobID, index, data)
+}
+
+// LoadSegments 读取任务已保存的片段结果
+func (s *SQLiteJobStore) LoadSegments(ctx context.Context, jobID string) (map[int][]byte, error) {
+	return loadSegments(ctx, s.db, sqlitePlaceholder, jobID)
+}
+
+// DeleteSegments 删除任务的片段结果
+func (s *SQLiteJobStore) DeleteSegments(ctx context.Context, jobID string) error {
+	return deleteSegments(ctx, s.db, sqlitePlaceholder, jobID)
+}
+
+// sqlitePlaceholder SQLite 的参数占位符
+func sqlitePlaceholder(int) string {
+	return "?"
+}
+
+// IncrUsage 累加月度费用
+func (s *SQLiteJobStore) IncrUsage(ctx context.Context, month string, costUSD float64) (float64, error) {
+	query := `
+    INSERT INTO openai_usage (month, cost_usd, updated_at)
+    VALUES (?, ?, ?)
+    ON CONFLICT (month)
+    DO UPDATE SET
+    cost_usd = openai_usage.cost_usd + excluded.cost_usd,
+    updated_at = excluded.updated_at
+    RETURNING cost_usd
+    `
+
+	var total float64
+	if err := s.db.QueryRowContext(ctx, query, month, costUSD, time.Now()).Scan(&total); err != nil {
+		return 0, fmt.Errorf("累加费用失败: %w", err)
+	}
+	return total, nil
+}
+
+// GetUsage 获取月度费用
+func (s *SQLiteJobStore) GetUsage(ctx context.Context, month string) (float64, error) {
+	var total float64
+	err := s.db.QueryRowContext(ctx, `SELECT cost_usd FROM openai_usage WHERE month = ?`, month).Scan(&total)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("获取费用失败: %w", err)
+	}
+	return total, nil
+}
+
+// SetCredential 保存用户凭证（已存在时覆盖）
+func (s *SQLiteJobStore) SetCredential(ctx context.Context, userID, name, value string) error {
+	query := `
+    INSERT INTO user_credentials (user_id, name, value, updated_at)
+    VALUES (?, ?, ?, ?)
+    ON CONFLICT (user_id, name)
+    DO UPDATE SET
+    value = excluded.value,
+    updated_at = excluded.updated_at
+    `
+
+	if _, err := s.db.ExecContext(ctx, query, userID, name, value, time.Now()); err != nil {
+		return fmt.Errorf("保存凭证失败: %w", err)
+	}
+	return nil
+}
+
+// GetCredential 获取用户凭证
+func (s *SQLiteJobStore) GetCredential(ctx context.Context, userID, name string) (string, error) {
+	var value string
+	err := s.db.QueryRowContext(ctx, `SELECT value FROM user_credentials WHERE user_id = ? AND name = ?`, userID, name).Scan(&value)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("获取凭证失败: %w", err)
+	}
+	return value, nil
+}
+
+// DeleteCredential 删除用户凭证
+func (s *SQLiteJobStore) DeleteCredential(ctx context.Context, userID, name string) error {
+	if _, err := s.db.ExecContext(ctx, `DELETE FROM user_credentials WHERE user_id = ? AND name = ?`, userID, name); err != nil {
+		return fmt.Errorf("删除凭证失败: %w", err)
+	}
+	return nil
+}
+
+// SetHashIfAbsent 登记文件内容哈希（content_hash 主键约束保证只登记一次）
+func (s *SQLiteJobStore) SetHashIfAbsent(ctx context.Context, hash, jobID string) (string, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	result, err := s.db.ExecContext(ctx, `INSERT INTO job_content_hashes (content_hash, job_id, created_at)
+    VALUES (?, ?, ?) ON CONFLICT (content_hash) DO NOTHING`, hash, jobID, time.Now())
+	if err != nil {
+		return "", false, fmt.Errorf("登记文件哈希失败: %w", err)
+	}
+	if rows, _ := result.RowsAffected(); rows == 1 {
+		return jobID, true, nil
+	}
+
+	var existing string
+	if err := s.db.QueryRowContext(ctx, `SELECT job_id FROM job_content_hashes WHERE content_hash = ?`, hash).Scan(&existing); err != nil {
+		return "", false, fmt.Errorf("读取文件哈希失败: %w", err)
+	}
+	return existing, false, nil
+}
+
+// FindByHash 查找相同文件内容最近一次已完成的任务（content_hash 索引）
+func (s *SQLiteJobStore) FindByHash(ctx context.Context, hash string) (*models.TranscriptionJob, error) {
+	query := `SELECT ` + sqliteJobColumns + ` FROM transcription_jobs
+    WHERE content_hash = ? AND status = ? AND ` + notDeleted + `
+    ORDER BY created_at DESC LIMIT 1`
+
+	job, err := scanSQLiteJob(s.db.QueryRowContext(ctx, query, hash, models.StatusCompleted))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("按文件哈希查询任务失败: %w", err)
+	}
+	return job, nil
+}
+
+// DeleteHash 删除文件内容哈希登记
+func (s *SQLiteJobStore) DeleteHash(ctx context.Context, hash, jobID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, err := s.db.ExecContext(ctx, `DELETE FROM job_content_hashes WHERE content_hash = ? AND job_id = ?`, hash, jobID); err != nil {
+		return fmt.Errorf("删除文件哈希失败: %w", err)
+	}
+	return nil
+}
+
+// IndexWords 替换任务的单词索引（事务中先删除再插入）
+func (s *SQLiteJobStore) IndexWords(ctx context.Context, jobID string, words []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("开启事务失败: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM job_words WHERE job_id = ?`, jobID); err != nil {
+		return fmt.Errorf("删除单词索引失败: %w", err)
+	}
+	for _, word := range words {
+		if _, err := tx.ExecContext(ctx, `INSERT INTO job_words (word, job_id) VALUES (?, ?) ON CONFLICT DO NOTHING`, word, jobID); err != nil {
+			return fmt.Errorf("写入单词索引失败: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("提交单词索引失败: %w", err)
+	}
+	return nil
+}
+
+// JobsByWord 查找包含指定单词的任务
+func (s *SQLiteJobStore) JobsByWord(ctx context.Context, word string) ([]*models.TranscriptionJob, error) {
+	return s.list(ctx, `SELECT `+sqliteJobColumns+` FROM transcription_jobs
+    WHERE job_id IN (SELECT job_id FROM job_words WHERE word = ?) AND `+notDeleted+`
+    ORDER BY created_at DESC LIMIT ?`, word, searchLimit)
+}
+
+// ListVocabulary 列出所有任务已提取的单词
+func (s *SQLiteJobStore) ListVocabulary(ctx context.Context) (map[string][]string, error) {
+	return listVocabulary(ctx, s.db)
+}
+
+// Ping 检查数据库是否可用
+func (s *SQLiteJobStore) Ping(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+
+	if err := s.db.PingContext(ctx); err != nil {
+		return fmt.Errorf("数据库不可用: %w", err)
+	}
+	return nil
+}
+
+// Close 关闭数据库连接
+func (s *SQLiteJobStore) Close() error {
+	return s.db.Close()
+}
@@ -1,27 +1,265 @@
 package storage
 
-import "github.com/z-wentao/voiceflow/pkg/models"
+import (
+    "context"
+    "errors"
+    "fmt"
+    "time"
+
+    "github.com/z-wentao/voiceflow/pkg/models"
+)
 
 // Store 任务存储接口
+// 除 Close 外的方法都接收 context：HTTP 请求取消或超时后，数据库/Redis 操作随之中断
 type Store interface {
-    // Save 保存任务
-    Save(job *models.TranscriptionJob) error
+    // Save 保存任务，成功后 job.Version 更新为写入后的版本号
+    // job.Version > 0 时（从存储读取的任务）只在存储中的版本号与之相同时写入，
+    // 期间任务被其他操作修改过则返回 ErrVersionConflict；job.Version 为 0 时直接覆盖
+    Save(ctx context.Context, job *models.TranscriptionJob) error
 
     // Get 获取任务
-    Get(jobID string) (*models.TranscriptionJob, error)
+    Get(ctx context.Context, jobID string) (*models.TranscriptionJob, error)
 
     // Update 更新任务（使用回调函数模式）
-    Update(jobID string, updateFn func(*models.TranscriptionJob)) error
+    // 读取、执行 updateFn 和写回是原子的，并发的 Update 不会丢失彼此的修改，写回时版本号加 1；
+    // 实现使用乐观锁，在版本冲突时重新读取并重试，updateFn 可能被调用多次，只能修改传入的任务
+    Update(ctx context.Context, jobID string, updateFn func(*models.TranscriptionJob)) error
 
     // List 列出所有任务
-    List() ([]*models.TranscriptionJob, error)
+    List(ctx context.Context) ([]*models.TranscriptionJob, error)
 
     // List all jobs history
-    ListAll() ([]*models.TranscriptionJob, error)
+    ListAll(ctx context.Context) ([]*models.TranscriptionJob, error)
+
+    // ListForUser 列出指定用户的任务，按创建时间倒序（limit <= 0 表示不限制）
+    ListForUser(ctx context.Context, userID string, limit int) ([]*models.TranscriptionJob, error)
+
+    // ListSummaries 列出指定状态任务的轻量投影（不读取转录文本等大字段）
+    ListSummaries(ctx context.Context, statuses ...models.JobStatus) ([]models.JobSummary, error)
+
+    // ListByStatus 列出指定状态的任务（完整数据），按创建时间正序（启动恢复时先创建的先入队）
+    ListByStatus(ctx context.Context, statuses ...models.JobStatus) ([]*models.TranscriptionJob, error)
+
+    // CountByStatus 按状态统计任务数量（userID 为空时统计所有用户的任务）
+    CountByStatus(ctx context.Context, userID string) (map[models.JobStatus]int, error)
+
+    // Stats 汇总任务数、完成数、失败数和已完成任务的平均音频时长
+    // userID 为空时统计所有用户的任务；since 不为零时只统计创建时间不早于 since 的任务（如今天失败的任务数）
+    Stats(ctx context.Context, userID string, since time.Time) (models.JobStats, error)
+
+    // Search 按关键词搜索任务（匹配文件名和转录文本），按相关度或创建时间倒序
+    Search(ctx context.Context, query string) ([]*models.TranscriptionJob, error)
+
+    // Delete 软删除任务：记录删除时间（job.DeletedAt），任务不再出现在列表、搜索和统计中，可以用 Restore 恢复
+    // Get 仍然返回已删除的任务（由调用方判断 DeletedAt）；任务不存在或已被删除时返回错误
+    Delete(ctx context.Context, jobID string) error
+
+    // Restore 恢复软删除的任务（清除删除时间）；任务不存在或未被删除时返回错误
+    Restore(ctx context.Context, jobID string) error
+
+    // ListDeleted 列出删除时间早于 before 的软删除任务（按删除时间正序），用于永久清理
+    ListDeleted(ctx context.Context, before time.Time) ([]*models.TranscriptionJob, error)
+
+    // Purge 永久删除任务及其片段结果和单词索引（无论是否已软删除），媒体和字幕文件由调用方清理
+    Purge(ctx context.Context, jobID string) error
+
+    // DeleteOlderThan 删除创建时间早于 t 的已结束任务（完成或失败）及其单词索引，返回删除的任务数
+    // 未结束的任务不会被删除；媒体和字幕文件由调用方清理
+    DeleteOlderThan(ctx context.Context, t time.Time) (int, error)
+
+    // IncrUsage 累加指定月份（格式 2006-01）的 OpenAI 预估费用，返回累加后的总额
+    IncrUsage(ctx context.Context, month string, costUSD float64) (float64, error)
+
+    // GetUsage 获取指定月份的 OpenAI 预估费用
+    GetUsage(ctx context.Context, month string) (float64, error)
+
+    // SetCredential 保存用户的第三方凭证（如墨墨 Token），value 应为调用方加密后的密文
+    SetCredential(ctx context.Context, userID, name, value string) error
+
+    // GetCredential 获取用户的第三方凭证密文，没有保存时返回空字符串
+    GetCredential(ctx context.Context, userID, name string) (string, error)
 
-    // Delete 删除任务
-    Delete(jobID string) error
+    // DeleteCredential 删除用户的第三方凭证（没有保存时不报错）
+    DeleteCredential(ctx context.Context, userID, name string) error
+
+    // SetHashIfAbsent 原子地登记文件内容哈希对应的任务
+    // 哈希尚未登记时写入 jobID 并返回 created=true；
+    // 已被登记（包括其他 API 实例并发登记）时返回已有的任务 ID
+    SetHashIfAbsent(ctx context.Context, hash, jobID string) (existingJobID string, created bool, err error)
+
+    // FindByHash 查找相同文件内容（SHA-256）最近一次已完成的任务，没有时返回 nil, nil
+    FindByHash(ctx context.Context, hash string) (*models.TranscriptionJob, error)
+
+    // DeleteHash 删除文件内容哈希登记（仅当仍指向 jobID 时删除，避免误删其他实例的新登记）
+    DeleteHash(ctx context.Context, hash, jobID string) error
+
+    // IndexWords 替换任务的单词索引（words 应为归一化后的单词，传空列表表示清空）
+    IndexWords(ctx context.Context, jobID string, words []string) error
+
+    // JobsByWord 查找包含指定单词（归一化形式）的任务，按创建时间倒序
+    JobsByWord(ctx context.Context, word string) ([]*models.TranscriptionJob, error)
+
+    // ListVocabulary 列出所有任务已提取的单词（来自单词索引，归一化形式），key 为任务 ID
+    ListVocabulary(ctx context.Context) (map[string][]string, error)
+
+    // Ping 检查存储连接是否可用（用于健康检查）
+    Ping(ctx context.Context) error
 
     // Close 关闭存储连接
     Close() error
 }
+
+// JobIterator 可选接口：支持分页遍历所有任务的存储（如 PostgreSQL），不会一次把所有任务加载到内存
+type JobIterator interface {
+    // ForEach 按创建时间倒序遍历所有任务，fn 返回错误时停止遍历并返回该错误
+    ForEach(ctx context.Context, fn func(*models.TranscriptionJob) error) error
+}
+
+// ForEachJob 遍历存储中的所有任务：存储实现 JobIterator 时分页读取，否则使用 ListAll
+func ForEachJob(ctx context.Context, store Store, fn func(*models.TranscriptionJob) error) error {
+    if iterator, ok := store.(JobIterator); ok {
+	return iterator.ForEach(ctx, fn)
+    }
+
+    jobs, err := store.ListAll(ctx)
+    if err != nil {
+	return err
+    }
+    for _, job := range jobs {
+	if err := fn(job); err != nil {
+	    return err
+	}
+    }
+    return nil
+}
+
+// MarkerStore 可选接口：永不过期的登记（如订阅源已导入的节目），语义与 SetHashIfAbsent / DeleteHash 相同
+// 哈希登记会随任务过期的存储（Redis）需要实现；其余存储的哈希登记本身不会过期，直接使用哈希登记
+type MarkerStore interface {
+    // SetMarkerIfAbsent 原子地登记 key：尚未登记时写入 value 并返回 created=true，已登记时返回已有的值
+    SetMarkerIfAbsent(ctx context.Context, key, value string) (existing string, created bool, err error)
+
+    // DeleteMarker 删除登记（仅当仍为 value 时删除）
+    DeleteMarker(ctx context.Context, key, value string) error
+}
+
+// SetMarkerIfAbsent 登记永不过期的标记：存储实现 MarkerStore 时使用专门的登记，否则使用哈希登记
+func SetMarkerIfAbsent(ctx context.Context, store Store, key, value string) (string, bool, error) {
+    if markers, ok := store.(MarkerStore); ok {
+	return markers.SetMarkerIfAbsent(ctx, key, value)
+    }
+    return store.SetHashIfAbsent(ctx, key, value)
+}
+
+// DeleteMarker 删除 SetMarkerIfAbsent 的登记（仅当仍为 value 时删除）
+func DeleteMarker(ctx context.Context, store Store, key, value string) error {
+    if markers, ok := store.(MarkerStore); ok {
+	return markers.DeleteMarker(ctx, key, value)
+    }
+    return store.DeleteHash(ctx, key, value)
+}
+
+// SegmentStore 可选接口：保存转录中已完成片段的结果（序列化后的 Whisper 响应）
+// 任务中断后重新处理时，转换引擎跳过已保存结果的片段；所有片段完成后删除
+type SegmentStore interface {
+    // SaveSegment 保存任务中一个片段的结果（同一片段重复保存时覆盖）
+    SaveSegment(ctx context.Context, jobID string, index int, data []byte) error
+
+    // LoadSegments 读取任务已保存的所有片段结果，key 为片段序号
+    LoadSegments(ctx context.Context, jobID string) (map[int][]byte, error)
+
+    // DeleteSegments 删除任务的所有片段结果（没有保存时不报错）
+    DeleteSegments(ctx context.Context, jobID string) error
+}
+
+// Archiver 可选接口：支持把完成较久的任务移到归档表的存储（如 PostgreSQL），让任务表保持较小
+// 归档的任务不再出现在列表、搜索和统计中，Get 仍然可以读取
+type Archiver interface {
+    // MoveToArchive 把完成时间早于 olderThan 的已完成任务移到归档表，返回归档的任务数
+    MoveToArchive(ctx context.Context, olderThan time.Time) (int, error)
+}
+
+// IndexMaintainer 可选接口：在后台维护任务索引的存储（如 Redis），供管理接口查询维护状态
+type IndexMaintainer interface {
+    // IndexMaintenance 返回最近一次维护的结果，未启用后台维护时返回 nil
+    IndexMaintenance() *IndexMaintenanceStats
+}
+
+// IndexMaintenanceStats 任务索引的后台维护状态
+type IndexMaintenanceStats struct {
+    Interval     string     `json:"interval"`                // 维护间隔
+    LastCleanAt  *time.Time `json:"last_clean_at,omitempty"` // 最近一次清理的时间（尚未执行时为空）
+    LastRemoved  int        `json:"last_removed"`            // 最近一次清理删除的过期任务 ID 数
+    TotalRemoved int        `json:"total_removed"`           // 启动以来删除的过期任务 ID 总数
+    LastError    string     `json:"last_error,omitempty"`    // 最近一次清理失败的原因
+}
+
+// IndexMaintenanceOf 返回存储的索引维护状态，存储不做后台维护时返回 nil
+func IndexMaintenanceOf(store Store) *IndexMaintenanceStats {
+    if maintainer, ok := store.(IndexMaintainer); ok {
+	return maintainer.IndexMaintenance()
+    }
+    return nil
+}
+
+// BatchSaver 可选接口：支持一次往返写入多个任务的存储（如 PostgreSQL）
+// 混合存储的同步 Worker 优先使用，存储未实现时逐个调用 Save
+type BatchSaver interface {
+    // BatchSave 批量保存任务；部分任务失败时返回 *BatchSaveError，其余任务照常写入
+    BatchSave(ctx context.Context, jobs []*models.TranscriptionJob) error
+}
+
+// BatchSaveError 批量保存时部分任务失败（未列出的任务已写入）
+type BatchSaveError struct {
+    Failed map[string]error // 保存失败的任务 ID 及原因
+}
+
+func (e *BatchSaveError) Error() string {
+    return fmt.Sprintf("%d 个任务保存失败", len(e.Failed))
+}
+
+// Unwrap 返回每个任务的失败原因（支持 errors.Is / errors.As）
+func (e *BatchSaveError) Unwrap() []error {
+    errs := make([]error, 0, len(e.Failed))
+    for _, err := range e.Failed {
+	errs = append(errs, err)
+    }
+    return errs
+}
+
+// ErrVersionConflict 保存任务时存储中的版本号与任务不一致：任务读取后被其他操作修改过
+// 调用方应重新读取任务再修改（见 Modify），而不是用旧数据覆盖
+var ErrVersionConflict = errors.New("任务已被其他操作修改")
+
+// versionConflict 返回带任务 ID 的版本冲突错误
+func versionConflict(jobID string) error {
+    return fmt.Errorf("%w: %s", ErrVersionConflict, jobID)
+}
+
+// modifyRetries Modify 遇到版本冲突时的最大尝试次数
+const modifyRetries = 10
+
+// Modify 读取任务、执行 fn 修改并保存，版本冲突时重新读取并重试
+// 与 Update 不同，fn 可以返回错误中止修改（不保存），也可以执行耗时操作（不持有锁）；
+// fn 可能被调用多次，只能修改传入的任务。返回保存后的任务
+func Modify(ctx context.Context, store Store, jobID string, fn func(*models.TranscriptionJob) error) (*models.TranscriptionJob, error) {
+    var err error
+    for attempt := 0; attempt < modifyRetries; attempt++ {
+	var job *models.TranscriptionJob
+	job, err = store.Get(ctx, jobID)
+	if err != nil {
+	    return nil, err
+	}
+	if err = fn(job); err != nil {
+	    return nil, err
+	}
+	err = store.Save(ctx, job)
+	if err == nil {
+	    return job, nil
+	}
+	if !errors.Is(err, ErrVersionConflict) {
+	    return nil, err
+	}
+    }
+    return nil, err
+}
@@ -0,0 +1,224 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+
+	"github.com/z-wentao/voiceflow/pkg/models"
+)
+
+// testBackend 可以在本地运行测试的存储后端（PostgreSQL 需要设置 VOICEFLOW_TEST_POSTGRES_DSN）
+type testBackend struct {
+	name string
+	open func(t *testing.T) Store
+}
+
+var testBackends = []testBackend{
+	{"memory", func(t *testing.T) Store { return NewJobStore(0) }},
+	{"sqlite", func(t *testing.T) Store { return newTestSQLiteStore(t) }},
+	{"redis", func(t *testing.T) Store { store, _ := newTestRedisStore(t); return store }},
+	{"postgres", func(t *testing.T) Store { return newTestPostgresStore(t) }},
+}
+
+func newTestSQLiteStore(t *testing.T) *SQLiteJobStore {
+	t.Helper()
+	store, err := NewSQLiteJobStore(filepath.Join(t.TempDir(), "voiceflow.db"))
+	if err != nil {
+		t.Fatalf("NewSQLiteJobStore: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+	return store
+}
+
+func newTestRedisStore(t testing.TB) (*RedisJobStore, *miniredis.Miniredis) {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	store, err := NewRedisJobStore(mr.Addr(), "", 0, time.Hour, 0)
+	if err != nil {
+		t.Fatalf("NewRedisJobStore: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+	return store, mr
+}
+
+// newTestPostgresStore 连接 VOICEFLOW_TEST_POSTGRES_DSN 指定的数据库，未设置时跳过测试
+func newTestPostgresStore(t testing.TB) *PostgresJobStore {
+	t.Helper()
+	dsn := os.Getenv("VOICEFLOW_TEST_POSTGRES_DSN")
+	if dsn == "" {
+		t.Skip("未设置 VOICEFLOW_TEST_POSTGRES_DSN，跳过 PostgreSQL 测试")
+	}
+	store, err := NewPostgresJobStore(dsn, true)
+	if err != nil {
+		t.Fatalf("NewPostgresJobStore: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+	return store
+}
+
+// testID 生成测试用的唯一 ID（共享的 PostgreSQL 数据库中多次运行不冲突）
+func testID(prefix string) string {
+	return fmt.Sprintf("%s-%d", prefix, time.Now().UnixNano())
+}
+
+// TestSetHashIfAbsentConcurrent 多个副本同时上传同一个文件，只有一个能登记哈希，其余都拿到同一个已有任务
+func TestSetHashIfAbsentConcurrent(t *testing.T) {
+	for _, backend := range testBackends {
+		t.Run(backend.name, func(t *testing.T) {
+			ctx := context.Background()
+			store := backend.open(t)
+			hash := testID("hash")
+
+			const uploads = 16
+			var wg sync.WaitGroup
+			existing := make([]string, uploads)
+			created := make([]bool, uploads)
+			errs := make([]error, uploads)
+			for i := 0; i < uploads; i++ {
+				wg.Add(1)
+				go func(i int) {
+					defer wg.Done()
+					existing[i], created[i], errs[i] = store.SetHashIfAbsent(ctx, hash, fmt.Sprintf("job-%d", i))
+				}(i)
+			}
+			wg.Wait()
+
+			var winner string
+			for i := 0; i < uploads; i++ {
+				if errs[i] != nil {
+					t.Fatalf("SetHashIfAbsent: %v", errs[i])
+				}
+				if created[i] {
+					if winner != "" {
+						t.Fatalf("%s 和 job-%d 都登记成功", winner, i)
+					}
+					winner = fmt.Sprintf("job-%d", i)
+				}
+			}
+			if winner == "" {
+				t.Fatal("没有上传登记成功")
+			}
+			for i := 0; i < uploads; i++ {
+				if existing[i] != winner {
+					t.Fatalf("job-%d 拿到的任务 = %s，期望 %s", i, existing[i], winner)
+				}
+			}
+		})
+	}
+}
+
+// TestSetMarkerIfAbsent 所有存储的永不过期登记（Redis 使用专门的 key，其余存储使用哈希登记）
+func TestSetMarkerIfAbsent(t *testing.T) {
+	for _, backend := range testBackends {
+		t.Run(backend.name, func(t *testing.T) {
+			ctx := context.Background()
+			store := backend.open(t)
+			key := testID("feed:")
+
+			steps := []struct {
+				name        string
+				run         func() (string, bool, error)
+				wantValue   string
+				wantCreated bool
+			}{
+				{"登记", func() (string, bool, error) { return SetMarkerIfAbsent(ctx, store, key, "job-1") }, "job-1", true},
+				{"重复登记返回已有的值", func() (string, bool, error) { return SetMarkerIfAbsent(ctx, store, key, "job-2") }, "job-1", false},
+				{"删除其他值不影响登记", func() (string, bool, error) {
+					if err := DeleteMarker(ctx, store, key, "job-2"); err != nil {
+						return "", false, err
+					}
+					return SetMarkerIfAbsent(ctx, store, key, "job-3")
+				}, "job-1", false},
+				{"删除后重新登记", func() (string, bool, error) {
+					if err := DeleteMarker(ctx, store, key, "job-1"); err != nil {
+						return "", false, err
+					}
+					return SetMarkerIfAbsent(ctx, store, key, "job-4")
+				}, "job-4", true},
+			}
+			for _, step := range steps {
+				value, created, err := step.run()
+				if err != nil {
+					t.Fatalf("%s: %v", step.name, err)
+				}
+				if value != step.wantValue || created != step.wantCreated {
+					t.Fatalf("%s = (%s, %v)，期望 (%s, %v)", step.name, value, created, step.wantValue, step.wantCreated)
+				}
+			}
+		})
+	}
+}
+
+// TestUpdateConcurrent 多个 goroutine 同时更新同一任务的不同字段（Worker 写进度、提取单词写词表），成功的更新都不会被覆盖
+// 并发冲突重试次数用尽时 Update 返回错误，这种更新不计入结果
+func TestUpdateConcurrent(t *testing.T) {
+	for _, backend := range testBackends {
+		t.Run(backend.name, func(t *testing.T) {
+			ctx := context.Background()
+			store := backend.open(t)
+			jobID := testID("job")
+			if err := store.Save(ctx, &models.TranscriptionJob{JobID: jobID, Status: models.StatusProcessing}); err != nil {
+				t.Fatalf("Save: %v", err)
+			}
+
+			const writers, updates = 4, 25
+			var wg sync.WaitGroup
+			var attempts, words atomic.Int64
+			for i := 0; i < writers; i++ {
+				wg.Add(2)
+				go func() {
+					defer wg.Done()
+					for n := 0; n < updates; n++ {
+						err := store.Update(ctx, jobID, func(job *models.TranscriptionJob) {
+							job.Attempts++
+							job.Progress = n
+						})
+						countUpdate(t, err, &attempts)
+					}
+				}()
+				go func(i int) {
+					defer wg.Done()
+					for n := 0; n < updates; n++ {
+						err := store.Update(ctx, jobID, func(job *models.TranscriptionJob) {
+							job.Vocabulary = append(job.Vocabulary, fmt.Sprintf("word-%d-%d", i, n))
+						})
+						countUpdate(t, err, &words)
+					}
+				}(i)
+			}
+			wg.Wait()
+
+			job, err := store.Get(ctx, jobID)
+			if err != nil {
+				t.Fatalf("Get: %v", err)
+			}
+			if attempts.Load() == 0 || words.Load() == 0 {
+				t.Fatalf("没有成功的更新: attempts=%d words=%d", attempts.Load(), words.Load())
+			}
+			if int64(job.Attempts) != attempts.Load() || int64(len(job.Vocabulary)) != words.Load() {
+				t.Fatalf("Attempts = %d（成功 %d 次），单词 %d 个（成功 %d 次），有更新丢失",
+					job.Attempts, attempts.Load(), len(job.Vocabulary), words.Load())
+			}
+		})
+	}
+}
+
+// countUpdate 统计成功的更新；并发冲突以外的错误使测试失败
+func countUpdate(t *testing.T, err error, succeeded *atomic.Int64) {
+	t.Helper()
+	switch {
+	case err == nil:
+		succeeded.Add(1)
+	case !strings.Contains(err.Error(), "冲突"):
+		t.Errorf("Update: %v", err)
+	}
+}
@@ -0,0 +1,236 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/z-wentao/voiceflow/pkg/models"
+)
+
+// summaryColumns 任务投影查询使用的列（顺序与 scanSummaries 一致）
+const summaryColumns = `job_id, filename, status, progress, created_at, user_id`
+
+// toSummary 将任务转换为轻量投影
+func toSummary(job *models.TranscriptionJob) models.JobSummary {
+	return models.JobSummary{
+		JobID:     job.JobID,
+		Filename:  job.Filename,
+		Status:    job.Status,
+		Progress:  job.Progress,
+		CreatedAt: job.CreatedAt,
+		UserID:    job.UserID,
+	}
+}
+
+// statusFilter 状态过滤集合（未指定状态时返回 nil，表示不过滤）
+func statusFilter(statuses []models.JobStatus) map[models.JobStatus]bool {
+	if len(statuses) == 0 {
+		return nil
+	}
+	filter := make(map[models.JobStatus]bool, len(statuses))
+	for _, status := range statuses {
+		filter[status] = true
+	}
+	return filter
+}
+
+// statusCondition 生成 SQL 状态过滤条件和参数，placeholder 根据参数序号返回占位符
+// 未指定状态时返回恒真条件
+func statusCondition(statuses []models.JobStatus, placeholder func(n int) string) (string, []any) {
+	if len(statuses) == 0 {
+		return "1 = 1", nil
+	}
+	marks := make([]string, len(statuses))
+	args := make([]any, len(statuses))
+	for i, status := range statuses {
+		marks[i] = placeholder(i + 1)
+		args[i] = string(status)
+	}
+	return "status IN (" + strings.Join(marks, ", ") + ")", args
+}
+
+// countStatuses 执行 GROUP BY status 计数查询，placeholder 为第一个参数的占位符（userID 为空时统计所有用户）
+func countStatuses(ctx context.Context, db *sql.DB, userID, placeholder string) (map[models.JobStatus]int, error) {
+	query := `SELECT status, COUNT(*) FROM transcription_jobs WHERE ` + notDeleted
+	var args []any
+	if userID != "" {
+		query += ` AND user_id = ` + placeholder
+		args = append(args, userID)
+	}
+	query += ` GROUP BY status`
+
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("查询数据库失败: %w", err)
+	}
+	defer rows.Close()
+
+	counts := make(map[models.JobStatus]int)
+	for rows.Next() {
+		var status string
+		var count int
+		if err := rows.Scan(&status, &count); err != nil {
+			return nil, fmt.Errorf("读取任务数量失败: %w", err)
+		}
+		counts[models.JobStatus(status)] = count
+	}
+	return counts, rows.Err()
+}
+
+// queryStats 用一条聚合查询汇总任务统计（PostgreSQL 和 SQLite 共用），placeholder 根据参数序号返回占位符
+func queryStats(ctx context.Context, db *sql.DB, userID string, since time.Time, placeholder func(n int) string) (models.JobStats, error) {
+	completed := `'` + string(models.StatusCompleted) + `'`
+	failed := `'` + string(models.StatusFailed) + `'`
+	query := `SELECT COUNT(*),
+	COALESCE(SUM(CASE WHEN status = ` + completed + ` THEN 1 ELSE 0 END), 0),
+	COALESCE(SUM(CASE WHEN status = ` + failed + ` THEN 1 ELSE 0 END), 0),
+	COALESCE(AVG(CASE WHEN status = ` + completed + ` THEN duration END), 0)
+	FROM transcription_jobs`
+
+	conditions := []string{notDeleted}
+	var args []any
+	if userID != "" {
+		args = append(args, userID)
+		conditions = append(conditions, `user_id = `+placeholder(len(args)))
+	}
+	if !since.IsZero() {
+		args = append(args, since)
+		conditions = append(conditions, `created_at >= `+placeholder(len(args)))
+	}
+	query += ` WHERE ` + strings.Join(conditions, ` AND `)
+
+	var stats models.JobStats
+	if err := db.QueryRowContext(ctx, query, args...).Scan(&stats.Total, &stats.Completed, &stats.Failed, &stats.AvgDuration); err != nil {
+		return models.JobStats{}, fmt.Errorf("统计任务失败: %w", err)
+	}
+	return stats, nil
+}
+
+// statsOf 逐个统计任务（用于没有聚合查询的存储），userID 和 since 的含义同 Store.Stats
+func statsOf(jobs []*models.TranscriptionJob, userID string, since time.Time) models.JobStats {
+	var stats models.JobStats
+	totalDuration := 0.0
+	for _, job := range jobs {
+		if userID != "" && job.UserID != userID {
+			continue
+		}
+		if !since.IsZero() && job.CreatedAt.Before(since) {
+			continue
+		}
+		stats.Total++
+		switch job.Status {
+		case models.StatusCompleted:
+			stats.Completed++
+			totalDuration += job.Duration
+		case models.StatusFailed:
+			stats.Failed++
+		}
+	}
+	if stats.Completed > 0 {
+		stats.AvgDuration = totalDuration / float64(stats.Completed)
+	}
+	return stats
+}
+
+// CostStats 汇总创建时间在 [from, to) 内、有费用记录的任务的费用估算（userID 为空时统计所有用户的任务）
+// 通过 ForEachJob 遍历任务，支持分页遍历的存储不会一次加载所有任务
+func CostStats(ctx context.Context, store Store, userID string, from, to time.Time) (models.CostStats, error) {
+	var stats models.CostStats
+	err := ForEachJob(ctx, store, func(job *models.TranscriptionJob) error {
+		if job.EstimatedCost <= 0 || (userID != "" && job.UserID != userID) {
+			return nil
+		}
+		if job.CreatedAt.Before(from) || !job.CreatedAt.Before(to) {
+			return nil
+		}
+		stats.Jobs++
+		stats.EstimatedCost += job.EstimatedCost
+		stats.AudioMinutes += job.Duration / 60
+		stats.PromptTokens += job.PromptTokens
+		stats.CompletionTokens += job.CompletionTokens
+		return nil
+	})
+	if err != nil {
+		return models.CostStats{}, fmt.Errorf("统计任务费用失败: %w", err)
+	}
+	return stats, nil
+}
+
+// terminalStatuses 已结束的任务状态（保留期清理只删除这些任务）
+var terminalStatuses = []models.JobStatus{models.StatusCompleted, models.StatusFailed}
+
+// deleteOlderThan 在一个事务中删除创建时间早于 t 的已结束任务及其单词索引，
+// placeholder 根据参数序号返回占位符
+func deleteOlderThan(ctx context.Context, db *sql.DB, t time.Time, placeholder func(n int) string) (int, error) {
+	condition, args := statusCondition(terminalStatuses, func(n int) string { return placeholder(n + 1) })
+	where := `created_at < ` + placeholder(1) + ` AND ` + condition
+	args = append([]any{t}, args...)
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("开启事务失败: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM job_words WHERE job_id IN (SELECT job_id FROM transcription_jobs WHERE `+where+`)`, args...); err != nil {
+		return 0, fmt.Errorf("删除单词索引失败: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM job_segments WHERE job_id IN (SELECT job_id FROM transcription_jobs WHERE `+where+`)`, args...); err != nil {
+		return 0, fmt.Errorf("删除片段结果失败: %w", err)
+	}
+	result, err := tx.ExecContext(ctx, `DELETE FROM transcription_jobs WHERE `+where, args...)
+	if err != nil {
+		return 0, fmt.Errorf("删除任务失败: %w", err)
+	}
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("获取删除结果失败: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("提交事务失败: %w", err)
+	}
+	return int(deleted), nil
+}
+
+// countSummaries 按状态统计任务投影（userID 为空时统计所有用户）
+func countSummaries(summaries []models.JobSummary, userID string) map[models.JobStatus]int {
+	counts := make(map[models.JobStatus]int)
+	for _, s := range summaries {
+		if userID == "" || s.UserID == userID {
+			counts[s.Status]++
+		}
+	}
+	return counts
+}
+
+// limitJobs 截取前 limit 个任务（limit <= 0 表示不限制）
+func limitJobs(jobs []*models.TranscriptionJob, limit int) []*models.TranscriptionJob {
+	if limit > 0 && len(jobs) > limit {
+		return jobs[:limit]
+	}
+	return jobs
+}
+
+// scanSummaries 扫描投影查询结果
+func scanSummaries(rows *sql.Rows) ([]models.JobSummary, error) {
+	defer rows.Close()
+
+	summaries := make([]models.JobSummary, 0)
+	for rows.Next() {
+		var s models.JobSummary
+		var status string
+		var userID sql.NullString
+		if err := rows.Scan(&s.JobID, &s.Filename, &status, &s.Progress, &s.CreatedAt, &userID); err != nil {
+			return nil, fmt.Errorf("读取任务失败: %w", err)
+		}
+		s.Status = models.JobStatus(status)
+		s.UserID = userID.String
+		summaries = append(summaries, s)
+	}
+
+	return summaries, rows.Err()
+}
@@ -0,0 +1,28 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// listVocabulary 从 job_words 表读取所有任务的单词（PostgreSQL 和 SQLite 共用，不含软删除的任务）
+// 混合存储中未结束的任务只在 Redis 中，因此排除已删除的任务而不是只保留表中存在的任务
+func listVocabulary(ctx context.Context, db *sql.DB) (map[string][]string, error) {
+	rows, err := db.QueryContext(ctx, `SELECT job_id, word FROM job_words
+    WHERE job_id NOT IN (SELECT job_id FROM transcription_jobs WHERE deleted_at IS NOT NULL)`)
+	if err != nil {
+		return nil, fmt.Errorf("查询单词索引失败: %w", err)
+	}
+	defer rows.Close()
+
+	result := make(map[string][]string)
+	for rows.Next() {
+		var jobID, word string
+		if err := rows.Scan(&jobID, &word); err != nil {
+			return nil, fmt.Errorf("读取单词索引失败: %w", err)
+		}
+		result[jobID] = append(result[jobID], word)
+	}
+	return result, rows.Err()
+}
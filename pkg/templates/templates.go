@@ -3,10 +3,15 @@ package templates
 import (
     "fmt"
     "html/template"
+    "net/url"
+    "path/filepath"
     "strings"
     "time"
+    "unicode"
 
+    "github.com/z-wentao/voiceflow/pkg/artifacts"
     "github.com/z-wentao/voiceflow/pkg/models"
+    "github.com/z-wentao/voiceflow/pkg/vocabulary"
 )
 
 // FormatTime 格式化时间
@@ -26,10 +31,56 @@ func FormatTime(t time.Time) string {
     return t.Format("2006-01-02 15:04")
 }
 
+// FormatDuration 格式化音频时长（秒），如 "42 分钟"、"1 小时 5 分钟"
+func FormatDuration(seconds float64) string {
+    total := int(seconds + 0.5)
+    if total < 60 {
+	return fmt.Sprintf("%d 秒", total)
+    }
+    minutes := (total + 30) / 60
+    if minutes < 60 {
+	return fmt.Sprintf("%d 分钟", minutes)
+    }
+    if minutes%60 == 0 {
+	return fmt.Sprintf("%d 小时", minutes/60)
+    }
+    return fmt.Sprintf("%d 小时 %d 分钟", minutes/60, minutes%60)
+}
+
+// FormatFileSize 格式化文件大小（字节），如 "850 KB"、"85.3 MB"
+func FormatFileSize(size int64) string {
+    switch {
+    case size < 1024:
+	return fmt.Sprintf("%d B", size)
+    case size < 1024*1024:
+	return fmt.Sprintf("%.0f KB", float64(size)/1024)
+    case size < 1024*1024*1024:
+	return fmt.Sprintf("%.1f MB", float64(size)/1024/1024)
+    default:
+	return fmt.Sprintf("%.2f GB", float64(size)/1024/1024/1024)
+    }
+}
+
+// mediaInfo 任务卡片中的时长和文件大小（旧任务没有记录时不显示）
+func mediaInfo(job *models.TranscriptionJob) string {
+    var parts []string
+    if job.Duration > 0 {
+	parts = append(parts, "时长: "+FormatDuration(job.Duration))
+    }
+    if job.FileSize > 0 {
+	parts = append(parts, "大小: "+FormatFileSize(job.FileSize))
+    }
+    if len(parts) == 0 {
+	return ""
+    }
+    return " | " + strings.Join(parts, " | ")
+}
+
 // IsVideoFile 判断是否是视频文件
+// .ogg / .opus 几乎都是语音消息等纯音频（Ogg 视频很少见），按音频处理
 func IsVideoFile(filename string) bool {
-    ext := strings.ToLower(filename[strings.LastIndex(filename, "."):])
-    videoExts := []string{".mp4", ".webm", ".ogg", ".mov", ".avi", ".mkv", ".wmv", ".flv", ".m4v"}
+    ext := strings.ToLower(filepath.Ext(filename))
+    videoExts := []string{".mp4", ".webm", ".mov", ".avi", ".mkv", ".wmv", ".flv", ".m4v"}
     for _, ve := range videoExts {
 	if ext == ve {
 	    return true
@@ -46,27 +97,40 @@ func GetMediaIcon(filename string) string {
     return "🎵"
 }
 
+// statusText 任务状态的显示文本
+func statusText(status models.JobStatus) string {
+    switch status {
+    case models.StatusPending:
+	return "等待处理"
+    case models.StatusProcessing:
+	return "处理中"
+    case models.StatusCompleted:
+	return "已完成"
+    case models.StatusFailed:
+	return "失败"
+    default:
+	return "未知"
+    }
+}
+
 // RenderTaskCard 渲染任务卡片
 func RenderTaskCard(job *models.TranscriptionJob) template.HTML {
-    statusText := map[string]string{
-	"pending":    "等待处理",
-	"processing": "处理中",
-	"completed":  "已完成",
-	"failed":     "失败",
-    }
-    status := statusText[string(job.Status)]
-    if status == "" {
-	status = "未知"
-    }
+    status := statusText(job.Status)
 
     spinner := ""
     if job.Status == "processing" {
 	spinner = "<span>⏳</span>"
     }
 
+    source := ""
+    if job.Source != "" {
+	source = fmt.Sprintf("<span>📻 %s</span>", template.HTMLEscapeString(job.Source))
+    }
+
+    // 进行中的任务通过 SSE 实时更新进度（见 index.html 的 watchActiveJobs）
     progress := ""
-    if job.Progress > 0 {
-	progress = fmt.Sprintf("<span>进度: %d%%</span>", job.Progress)
+    if job.Progress > 0 || job.Status == models.StatusPending || job.Status == models.StatusProcessing {
+	progress = fmt.Sprintf(`<span id="progress-%s">进度: %d%%</span>`, job.JobID, job.Progress)
     }
 
     actions := fmt.Sprintf(`
@@ -76,20 +140,63 @@ func RenderTaskCard(job *models.TranscriptionJob) template.HTML {
     if job.Status == "completed" {
 	actions += fmt.Sprintf(`
 	    <a href="/api/jobs/%s/download" style="display: inline-block; padding: 8px 12px; background: #f0f0f0; border: 1px solid #ccc; border-radius: 4px; text-decoration: none; color: #333; cursor: pointer;">📥 下载文本</a>
-	    `, job.JobID)
+	    <a href="/api/jobs/%s/artifacts.zip" style="display: inline-block; padding: 8px 12px; background: #f0f0f0; border: 1px solid #ccc; border-radius: 4px; text-decoration: none; color: #333; cursor: pointer;">📦 打包下载</a>
+	    <a href="/api/jobs/%s/share" target="_blank" style="display: inline-block; padding: 8px 12px; background: #f0f0f0; border: 1px solid #ccc; border-radius: 4px; text-decoration: none; color: #333; cursor: pointer;">🔗 分享页面</a>
+	    `, job.JobID, job.JobID, job.JobID)
 
 	// 如果有字幕文件，显示下载字幕按钮
 	if job.SubtitlePath != "" {
 	    actions += fmt.Sprintf(`
 		<a href="/api/jobs/%s/download-subtitle" style="display: inline-block; padding: 8px 12px; background: #f0f0f0; border: 1px solid #ccc; border-radius: 4px; text-decoration: none; color: #333; cursor: pointer;">🎬 下载字幕</a>
+		<a href="/api/jobs/%s/transcript-timestamped.txt" style="display: inline-block; padding: 8px 12px; background: #f0f0f0; border: 1px solid #ccc; border-radius: 4px; text-decoration: none; color: #333; cursor: pointer;">🕒 带时间戳文本</a>
+		`, job.JobID, job.JobID)
+	}
+
+	// 双语字幕：已生成时显示下载按钮，否则显示生成按钮
+	if job.BilingualSRTPath != "" {
+	    actions += fmt.Sprintf(`
+		<a href="/api/jobs/%s/download-bilingual-subtitle" style="display: inline-block; padding: 8px 12px; background: #f0f0f0; border: 1px solid #ccc; border-radius: 4px; text-decoration: none; color: #333; cursor: pointer;">🌐 下载双语字幕</a>
 		`, job.JobID)
+	} else if job.SubtitlePath != "" {
+	    actions += fmt.Sprintf(`
+		<button hx-post="/api/jobs/%s/generate-bilingual"
+		hx-target="#details-%s"
+		hx-swap="innerHTML">🌐 生成双语字幕</button>
+		`, job.JobID, job.JobID)
 	}
 
 	actions += fmt.Sprintf(`
 	    <button hx-post="/api/jobs/%s/extract-vocabulary"
+	    hx-include="#min-level-%s, #max-level-%s, #exclude-known-%s"
 	    hx-target="#details-%s"
 	    hx-swap="innerHTML">📚 提取单词</button>
-	    `, job.JobID, job.JobID)
+	    %s - %s
+	    <label><input type="checkbox" id="exclude-known-%s" name="exclude_known" value="1"> 排除已学过的单词</label>
+	    `, job.JobID, job.JobID, job.JobID, job.JobID, job.JobID,
+	    levelSelect("min-level-"+job.JobID, "min_level", "最低等级"),
+	    levelSelect("max-level-"+job.JobID, "max_level", "最高等级"),
+	    job.JobID)
+    }
+
+    // 已结束且原始媒体仍在的任务可以重新转录（可修改语言和模型）
+    if (job.Status == models.StatusCompleted || job.Status == models.StatusFailed) && !job.MediaPurged {
+	keepVocabulary := ""
+	if len(job.Vocabulary) > 0 {
+	    keepVocabulary = fmt.Sprintf(`<label><input type="checkbox" id="keep-vocabulary-%s" name="keep_vocabulary" value="1"> 保留单词</label>`, job.JobID)
+	}
+	actions += fmt.Sprintf(`
+	    <button hx-post="/api/jobs/%s/re-transcribe"
+	    hx-include="#retranscribe-language-%s, #retranscribe-model-%s, #keep-vocabulary-%s"
+	    hx-confirm="确定重新转录？当前的转录结果和字幕将被清除"
+	    hx-target="#task-%s"
+	    hx-swap="outerHTML">🔁 重新转录</button>
+	    <input type="text" id="retranscribe-language-%s" name="language" value="%s" placeholder="语言（如 en，留空自动检测）" size="8">
+	    <input type="text" id="retranscribe-model-%s" name="model" value="%s" placeholder="模型（默认 whisper-1）" size="12">
+	    %s
+	    `, job.JobID, job.JobID, job.JobID, job.JobID, job.JobID,
+	    job.JobID, template.HTMLEscapeString(job.Language),
+	    job.JobID, template.HTMLEscapeString(job.Model),
+	    keepVocabulary)
     }
 
     actions += fmt.Sprintf(`
@@ -105,8 +212,8 @@ func RenderTaskCard(job *models.TranscriptionJob) template.HTML {
     html := fmt.Sprintf(`
 	<div class="task-card" data-job-id="%s" data-status="%s" id="task-%s">
 	<hr>
-	<p><strong>%s</strong> %s</p>
-	<p>状态: <strong>%s</strong> | %s | 时间: %s</p>
+	<p><strong>%s</strong> %s %s</p>
+	<p>状态: <strong>%s</strong> | %s | 时间: %s%s</p>
 	<p>%s</p>
 	<div id="details-%s"></div>
 	</div>
@@ -115,10 +222,12 @@ func RenderTaskCard(job *models.TranscriptionJob) template.HTML {
 	job.Status,
 	job.JobID,
 	template.HTMLEscapeString(job.Filename),
+	source,
 	spinner,
 	status,
 	progress,
 	FormatTime(job.CreatedAt),
+	mediaInfo(job),
 	actions,
 	job.JobID,
 	)
@@ -126,8 +235,49 @@ func RenderTaskCard(job *models.TranscriptionJob) template.HTML {
     return template.HTML(html)
 }
 
+// RenderDeletedJob 渲染删除后替换任务卡片的提示（带撤销按钮，恢复后替换为任务卡片）
+func RenderDeletedJob(job *models.TranscriptionJob) template.HTML {
+    return template.HTML(fmt.Sprintf(`
+	<div class="bg-gray-50 text-gray-600 p-3 rounded-lg text-sm" id="task-%s">
+	🗑️ 已删除 %s
+	<button hx-post="/api/jobs/%s/restore"
+	hx-target="#task-%s"
+	hx-swap="outerHTML">↩️ 撤销</button>
+	</div>
+	`, job.JobID, template.HTMLEscapeString(job.Filename), job.JobID, job.JobID))
+}
+
+// RenderJobProgress 渲染任务进度片段（进度条 + 百分比 + 状态）
+// poll 为 true 时片段每 2 秒请求 /api/jobs/:job_id/progress 替换自身，任务结束后服务端返回 286 停止轮询
+func RenderJobProgress(job *models.TranscriptionJob, poll bool) template.HTML {
+    polling := ""
+    if poll {
+	polling = fmt.Sprintf(` hx-get="/api/jobs/%s/progress" hx-trigger="every 2s" hx-swap="outerHTML"`, job.JobID)
+    }
+    return template.HTML(fmt.Sprintf(`<span id="progress-%s" data-status="%s"%s><progress max="100" value="%d"></progress> %d%% %s</span>`,
+	job.JobID, job.Status, polling, job.Progress, job.Progress, statusText(job.Status)))
+}
+
+// SyncTarget 单词面板上的同步按钮（对应配置中的一个同步目标）
+type SyncTarget struct {
+    Name  string
+    Label string
+    Type  string // maimemo 类型需要先填写 Token 和云词本
+}
+
+// levelSelect 渲染 CEFR 等级选择框（默认不限）
+func levelSelect(id, name, title string) string {
+    var html strings.Builder
+    html.WriteString(fmt.Sprintf(`<select id="%s" name="%s" title="%s"><option value="">不限</option>`, id, name, title))
+    for _, level := range vocabulary.Levels {
+	html.WriteString(fmt.Sprintf(`<option value="%s">%s</option>`, level, level))
+    }
+    html.WriteString(`</select>`)
+    return html.String()
+}
+
 // RenderTaskDetails 渲染任务详情
-func RenderTaskDetails(job *models.TranscriptionJob) template.HTML {
+func RenderTaskDetails(job *models.TranscriptionJob, targets []SyncTarget) template.HTML {
     var html strings.Builder
 
     html.WriteString("<hr>")
@@ -169,16 +319,61 @@ func RenderTaskDetails(job *models.TranscriptionJob) template.HTML {
 	    `, template.HTMLEscapeString(job.Error)))
     }
 
+    // 自动重试（临时错误）
+    if job.RetryCount > 0 {
+	html.WriteString(renderRetryInfo(job))
+    }
+
+    // 费用估算
+    if job.EstimatedCost > 0 {
+	html.WriteString(renderCost(job))
+    }
+
     // 单词列表
     if job.Status == "completed" && len(job.VocabDetail) > 0 {
-	html.WriteString(renderVocabulary(job))
+	html.WriteString(renderVocabulary(job, targets))
     }
 
     return template.HTML(html.String())
 }
 
+// renderCost 渲染任务的 OpenAI 费用估算（转录按音频时长，单词提取和字幕翻译按 token 用量）
+func renderCost(job *models.TranscriptionJob) string {
+    tokens := ""
+    if job.PromptTokens > 0 || job.CompletionTokens > 0 {
+	tokens = fmt.Sprintf("，LLM 输入 %d / 输出 %d tokens", job.PromptTokens, job.CompletionTokens)
+    }
+    return fmt.Sprintf(`
+	<div>
+	<p>💰 费用估算: $%.4f（音频 %.1f 分钟%s）</p>
+	</div>
+	`, job.EstimatedCost, job.Duration/60, tokens)
+}
+
+// renderRetryInfo 渲染自动重试信息：已重试次数，等待重试时显示下一次重试时间和上次的错误
+func renderRetryInfo(job *models.TranscriptionJob) string {
+    if job.Status != "pending" || job.NextAttemptAt.IsZero() {
+	return fmt.Sprintf(`
+	    <div>
+	    <p>🔁 因临时错误自动重试过 %d 次</p>
+	    </div>
+	    `, job.RetryCount)
+    }
+    return fmt.Sprintf(`
+	<div>
+	<p>🔁 第 %d 次自动重试将于 %s 开始</p>
+	<p><strong>上次错误:</strong> %s</p>
+	</div>
+	`, job.RetryCount, job.NextAttemptAt.Format("15:04:05"), template.HTMLEscapeString(job.Error))
+}
+
 // renderMediaPlayer 渲染媒体播放器（支持字幕）
 func renderMediaPlayer(job *models.TranscriptionJob) string {
+    // 原始媒体已按保留策略清理，转录结果和字幕仍可下载
+    if job.MediaPurged {
+	return `<div class="bg-gray-50 text-gray-500 p-3 rounded-lg text-sm">🧹 媒体已清理</div>`
+    }
+
     if IsVideoFile(job.Filename) {
 	// 视频播放器容器（使用自定义字幕渲染）
 	player := fmt.Sprintf(`
@@ -200,8 +395,8 @@ func renderMediaPlayer(job *models.TranscriptionJob) string {
 	    }
 	    </style>
 	    <div id="video-container-%s" style="position: relative; display: inline-block; max-width: 100%%;">
-	    <video id="video-%s" controls crossorigin="anonymous" src="/%s" style="max-width: 100%%; display: block;"></video>`,
-	    job.JobID, job.JobID, job.JobID, job.JobID, job.JobID, job.JobID, job.FilePath)
+	    <video id="video-%s" controls crossorigin="anonymous" src="/api/jobs/%s/media" style="max-width: 100%%; display: block;"></video>`,
+	    job.JobID, job.JobID, job.JobID, job.JobID, job.JobID, job.JobID, job.JobID)
 
 	if job.VTTPath != "" && job.Status == models.StatusCompleted {
 	    // 添加字幕容器（DOM 元素，插件可以访问）
@@ -257,7 +452,7 @@ func renderMediaPlayer(job *models.TranscriptionJob) string {
 		i++;
 		let text = '';
 		while (i < lines.length && lines[i].trim() !== '') {
-		text += lines[i].trim() + ' ';
+		text += lines[i].trim().replace(/<[^>]*>/g, '') + ' '; // 去掉逐词时间标签
 		i++;
 		}
 
@@ -355,69 +550,180 @@ func renderMediaPlayer(job *models.TranscriptionJob) string {
     }
 
     // 音频播放器（暂不支持字幕显示，但可以下载）
-    return fmt.Sprintf(`<audio controls src="/%s"></audio>`, job.FilePath)
+    return fmt.Sprintf(`<audio controls src="/api/jobs/%s/media"></audio>`, job.JobID)
 }
 
 // renderVocabulary 渲染单词列表
-func renderVocabulary(job *models.TranscriptionJob) string {
+func renderVocabulary(job *models.TranscriptionJob, targets []SyncTarget) string {
     var html strings.Builder
 
     html.WriteString(fmt.Sprintf(`
 	<div>
 	<hr>
 	<h4>📚 提取的单词 (%d)</h4>
-	<button onclick="showMaimemoForm('%s')">🔄 同步到墨墨</button>
-	<ul>
-	`, len(job.VocabDetail), job.JobID))
+	`, len(job.VocabDetail)))
+
+    // 每个同步目标一个按钮；墨墨需要先填写 Token 和云词本
+    var maimemo *SyncTarget
+    for i, target := range targets {
+	if target.Type == "maimemo" {
+	    maimemo = &targets[i]
+	    html.WriteString(fmt.Sprintf(`<button onclick="showMaimemoForm('%s')">🔄 同步到%s</button>
+		`, job.JobID, template.HTMLEscapeString(target.Label)))
+	    continue
+	}
+	html.WriteString(fmt.Sprintf(`<button hx-post="/api/jobs/%s/sync/%s"
+	    hx-target="#sync-result-%s"
+	    hx-swap="innerHTML"
+	    hx-confirm="确定同步到%s？">🔄 同步到%s</button>
+	    `, job.JobID, url.PathEscape(target.Name), job.JobID,
+	    template.HTMLEscapeString(target.Label), template.HTMLEscapeString(target.Label)))
+    }
+
+    html.WriteString(fmt.Sprintf(`
+	<a href="/api/jobs/%s/vocabulary.tsv">📥 导出 Anki (TSV)</a>
+	<a href="/api/jobs/%s/vocabulary.csv">📥 导出 CSV</a>
+	<div id="sync-result-%s" style="margin-top: 10px;"></div>
+	`, job.JobID, job.JobID, job.JobID))
+
+    html.WriteString(renderSyncHistory(job.SyncHistory, targets))
+    html.WriteString("<ul>")
 
     for _, word := range job.VocabDetail {
 	example := ""
 	if word.Example != "" {
 	    example = fmt.Sprintf("<br><em>%s</em>", template.HTMLEscapeString(word.Example))
 	}
+	// 旧数据没有音标和等级，不显示
+	phonetic := ""
+	if word.Phonetic != "" {
+	    phonetic = fmt.Sprintf(` <span style="font-family: monospace; color: #666;">%s</span>`, template.HTMLEscapeString(word.Phonetic))
+	}
+	level := ""
+	if word.Level != "" {
+	    level = fmt.Sprintf(` <small title="CEFR 等级">[%s]</small>`, template.HTMLEscapeString(word.Level))
+	}
 	html.WriteString(fmt.Sprintf(`
 	    <li>
-	    <strong>%s</strong><br>
+	    <strong>%s</strong>%s%s<br>
 	    %s%s
 	    </li>
-	    `, template.HTMLEscapeString(word.Word), template.HTMLEscapeString(word.Definition), example))
+	    `, template.HTMLEscapeString(word.Word), phonetic, level, template.HTMLEscapeString(word.Definition), example))
     }
 
     html.WriteString("</ul>")
-    html.WriteString(renderMaimemoForm(job.JobID))
+    if maimemo != nil {
+	html.WriteString(renderMaimemoForm(job.JobID, maimemo.Name))
+    }
     html.WriteString("</div>")
 
     return html.String()
 }
 
+// renderSyncHistory 渲染单词同步记录（最近的在前）
+func renderSyncHistory(history []models.SyncRecord, targets []SyncTarget) string {
+    if len(history) == 0 {
+	return ""
+    }
+
+    labels := make(map[string]string, len(targets))
+    for _, target := range targets {
+	labels[target.Name] = target.Label
+    }
+
+    var html strings.Builder
+    html.WriteString(`<details><summary>同步记录</summary><ul>`)
+    for i := len(history) - 1; i >= 0; i-- {
+	record := history[i]
+	label, ok := labels[record.Sink]
+	if !ok {
+	    label = record.Sink
+	}
+	if record.Error != "" {
+	    html.WriteString(fmt.Sprintf(`<li>❌ %s %s: %s</li>`,
+		FormatTime(record.SyncedAt), template.HTMLEscapeString(label), template.HTMLEscapeString(record.Error)))
+	} else {
+	    html.WriteString(fmt.Sprintf(`<li>✅ %s %s: %d 个单词</li>`,
+		FormatTime(record.SyncedAt), template.HTMLEscapeString(label), record.Count))
+	}
+    }
+    html.WriteString(`</ul></details>`)
+
+    return html.String()
+}
+
 // renderMaimemoForm 渲染墨墨同步表单
-func renderMaimemoForm(jobID string) string {
+// Token 区域在表单显示时才加载：已在服务端保存 Token 时只需选择云词本
+func renderMaimemoForm(jobID, sinkName string) string {
     return fmt.Sprintf(`
 	<div id="maimemo-form-%s" hidden>
 	<hr>
 	<h4>同步到墨墨背单词</h4>
-	<input type="hidden" id="job-id-%s" name="job_id" value="%s">
-	<label>墨墨 API Token:</label>
-	<input type="text" id="token-%s" name="token" placeholder="输入 Token" onchange="saveToken(this.value)">
-	<br>
+	<div class="maimemo-token" hx-get="/api/maimemo/token" hx-trigger="intersect once" hx-swap="outerHTML"></div>
 	<label>云词本 ID:</label>
 	<input type="text" id="notepad-%s" name="notepad_id" placeholder="输入云词本 ID" onchange="saveNotepadId(this.value)">
 	<button hx-post="/api/maimemo/list-notepads"
-	hx-include="#token-%s, #job-id-%s"
+	hx-vals='{"job_id": "%s"}'
+	hx-include="#maimemo-form-%s [name=token]"
 	hx-target="#notepad-list-%s"
 	hx-swap="innerHTML"
 	onclick="document.getElementById('notepad-list-%s').hidden = false">🔍 查询云词本</button>
 	<div id="notepad-list-%s" hidden style="margin-top: 10px; padding: 10px; border: 1px solid #ddd; border-radius: 4px; max-height: 200px; overflow-y: auto;"></div>
+	<details id="maimemo-create-%s" style="margin-top: 10px;">
+	<summary>➕ 新建云词本</summary>
+	<input type="text" name="title" placeholder="云词本标题" maxlength="100">
+	<input type="text" name="brief" placeholder="简介（可选）" maxlength="500">
+	<button hx-post="/api/maimemo/create-notepad"
+	hx-vals='{"job_id": "%s"}'
+	hx-include="#maimemo-form-%s [name=token], #maimemo-create-%s [name=title], #maimemo-create-%s [name=brief]"
+	hx-target="#maimemo-create-result-%s"
+	hx-swap="innerHTML">创建</button>
+	<div id="maimemo-create-result-%s"></div>
+	</details>
 	<br>
-	<button hx-post="/api/jobs/%s/sync-to-maimemo"
-	hx-include="#token-%s, #notepad-%s"
+	<button hx-post="/api/jobs/%s/sync/%s"
+	hx-include="#maimemo-form-%s [name=token], #notepad-%s"
 	hx-target="#sync-result-%s"
 	hx-swap="innerHTML"
 	hx-confirm="确定同步？">确认同步</button>
 	<button onclick="hideMaimemoForm('%s')">取消</button>
-	<div id="sync-result-%s" style="margin-top: 10px;"></div>
 	</div>
-	`, jobID, jobID, jobID, jobID, jobID, jobID, jobID, jobID, jobID, jobID, jobID, jobID, jobID, jobID, jobID, jobID)
+	`, jobID, jobID, jobID, jobID, jobID, jobID, jobID,
+	jobID, jobID, jobID, jobID, jobID, jobID, jobID,
+	jobID, url.PathEscape(sinkName), jobID, jobID, jobID, jobID)
+}
+
+// RenderMaimemoToken 渲染墨墨 Token 区域
+// 已保存时只显示状态和删除按钮；未保存时显示输入框，canSave 为 true（配置了加密密钥）时可保存到服务端，
+// 否则 Token 只随本次查询 / 同步请求提交
+func RenderMaimemoToken(saved, canSave bool) template.HTML {
+    switch {
+    case saved:
+	return template.HTML(`
+	    <div class="maimemo-token">
+	    ✅ 已保存墨墨 API Token
+	    <button hx-delete="/api/maimemo/token"
+	    hx-target="closest .maimemo-token"
+	    hx-swap="outerHTML"
+	    hx-confirm="确定删除保存的 Token？">删除</button>
+	    </div>
+	    `)
+    case canSave:
+	return template.HTML(`
+	    <form class="maimemo-token" hx-post="/api/maimemo/token" hx-swap="outerHTML">
+	    <label>墨墨 API Token:</label>
+	    <input type="password" name="token" placeholder="输入 Token" autocomplete="off" required>
+	    <button type="submit">💾 保存</button>
+	    </form>
+	    `)
+    default:
+	return template.HTML(`
+	    <div class="maimemo-token">
+	    <label>墨墨 API Token:</label>
+	    <input type="password" name="token" placeholder="输入 Token" autocomplete="off">
+	    </div>
+	    `)
+    }
 }
 
 // RenderNotepads 渲染云词本列表
@@ -430,20 +736,101 @@ func RenderNotepads(notepads []map[string]interface{}, jobID string) template.HT
     html.WriteString("<p style='margin: 0 0 8px 0; font-size: 12px; color: #666;'>点击选择云词本：</p>")
     html.WriteString("<ul style='list-style: none; margin: 0; padding: 0;'>")
     for _, notepad := range notepads {
-	id := notepad["id"].(string)
-	title := notepad["title"].(string)
+	id, _ := notepad["id"].(string)
+	title, _ := notepad["title"].(string)
 	html.WriteString(fmt.Sprintf(`
 	    <li onclick="selectNotepad('%s', '%s')" style="padding: 8px 12px; margin: 4px 0; background: #f5f5f5; border-radius: 4px; cursor: pointer; transition: background 0.2s;" onmouseover="this.style.background='#e8e8e8'" onmouseout="this.style.background='#f5f5f5'">
 		<strong>%s</strong><br>
 		<small style="color: #666;">ID: %s</small>
 	    </li>
-	    `, jobID, id, template.HTMLEscapeString(title), id))
+	    `, template.HTMLEscapeString(template.JSEscapeString(jobID)), template.HTMLEscapeString(template.JSEscapeString(id)),
+	    template.HTMLEscapeString(title), template.HTMLEscapeString(id)))
     }
     html.WriteString("</ul>")
 
     return template.HTML(html.String())
 }
 
+// RenderNotepadCreated 渲染创建云词本的结果，并把新云词本填入同步表单
+func RenderNotepadCreated(jobID, notepadID, title string) template.HTML {
+    return template.HTML(fmt.Sprintf(`
+	<p style="margin: 6px 0; color: #15803d;">✅ 已创建云词本 <strong>%s</strong>（ID: %s）</p>
+	<script>selectNotepad('%s', '%s')</script>
+	`, template.HTMLEscapeString(title), template.HTMLEscapeString(notepadID),
+	template.JSEscapeString(jobID), template.JSEscapeString(notepadID)))
+}
+
+// RenderSubTaskStatus 渲染大模型子任务状态（单词提取等）
+// 进行中时每 2 秒轮询一次状态（收到 WebSocket 推送的子任务结束事件时立即刷新），并提供取消按钮
+func RenderSubTaskStatus(jobID, taskID, label, state, errMsg string) template.HTML {
+    switch state {
+    case "running":
+	return template.HTML(fmt.Sprintf(`
+	    <div id="subtask-%s" class="text-center p-8"
+	    hx-get="/api/jobs/%s/tasks/%s"
+	    hx-trigger="every 2s, subtask-%s from:body"
+	    hx-swap="outerHTML">
+	    <span class="spinner"></span>
+	    <p class="text-gray-600 mt-2">正在%s，请稍候...</p>
+	    <button hx-post="/api/jobs/%s/tasks/%s/cancel"
+	    hx-target="#subtask-%s"
+	    hx-swap="outerHTML">⏹ 取消</button>
+	    </div>
+	    `, taskID, jobID, taskID, taskID, label, jobID, taskID, taskID))
+    case "cancelled":
+	return template.HTML(fmt.Sprintf(`
+	    <div id="subtask-%s" class="bg-gray-50 text-gray-600 p-3 rounded-lg text-sm" data-state="cancelled">
+	    🚫 已取消%s，结果未保存
+	    </div>
+	    `, taskID, label))
+    case "failed":
+	return template.HTML(fmt.Sprintf(`
+	    <div id="subtask-%s" class="bg-red-50 text-red-800 p-3 rounded-lg text-sm" data-state="failed">
+	    ❌ %s失败: %s
+	    </div>
+	    `, taskID, label, template.HTMLEscapeString(errMsg)))
+    default:
+	return template.HTML(fmt.Sprintf(`
+	    <div id="subtask-%s" class="bg-green-50 text-green-800 p-3 rounded-lg text-sm" data-state="%s">
+	    ✅ %s已完成
+	    </div>
+	    `, taskID, template.HTMLEscapeString(state), label))
+    }
+}
+
+// RenderSubTaskDetail 渲染子任务的结果说明（显示在任务详情上方）
+func RenderSubTaskDetail(detail string) template.HTML {
+    return template.HTML(fmt.Sprintf(`
+	<div class="bg-green-50 text-green-800 p-3 rounded-lg text-sm">
+	ℹ️ %s
+	</div>
+	`, template.HTMLEscapeString(detail)))
+}
+
+// RenderActiveSummary 渲染页头的进行中任务汇总
+func RenderActiveSummary(summary models.ActiveSummary) template.HTML {
+    if summary.Total == 0 {
+	return template.HTML(`<span class="text-gray-500">✅ 没有进行中的任务</span>`)
+    }
+
+    html := fmt.Sprintf(`<span>⏳ 排队中 %d · 🔄 处理中 %d · 平均进度 %d%%</span>`,
+	summary.Counts[models.StatusPending], summary.Counts[models.StatusProcessing], summary.AverageProgress)
+    if summary.Closest != nil {
+	html += fmt.Sprintf(` <span class="text-gray-500">（即将完成: %s %d%%）</span>`,
+	    template.HTMLEscapeString(summary.Closest.Filename), summary.Closest.Progress)
+    }
+    return template.HTML(html)
+}
+
+// RenderStatusCounts 渲染各状态的任务数量
+func RenderStatusCounts(counts map[models.JobStatus]int) template.HTML {
+    return template.HTML(fmt.Sprintf(`<span>⏳ %s %d · 🔄 %s %d · ✅ %s %d · ❌ %s %d</span>`,
+	statusText(models.StatusPending), counts[models.StatusPending],
+	statusText(models.StatusProcessing), counts[models.StatusProcessing],
+	statusText(models.StatusCompleted), counts[models.StatusCompleted],
+	statusText(models.StatusFailed), counts[models.StatusFailed]))
+}
+
 // RenderTasksList 渲染任务列表
 func RenderTasksList(jobs []*models.TranscriptionJob) template.HTML {
     if len(jobs) == 0 {
@@ -457,3 +844,140 @@ func RenderTasksList(jobs []*models.TranscriptionJob) template.HTML {
 
     return template.HTML(html.String())
 }
+
+// snippetRadius 搜索摘要中关键词前后保留的字符数
+const snippetRadius = 60
+
+// RenderSearchResults 渲染搜索结果（任务卡片 + 高亮的匹配片段）
+func RenderSearchResults(jobs []*models.TranscriptionJob, query string) template.HTML {
+    if len(jobs) == 0 {
+	return template.HTML(fmt.Sprintf("<p>没有找到包含「%s」的任务</p>", template.HTMLEscapeString(query)))
+    }
+
+    var html strings.Builder
+    html.WriteString(fmt.Sprintf("<p>找到 %d 个相关任务</p>", len(jobs)))
+    for _, job := range jobs {
+	html.WriteString(string(RenderTaskCard(job)))
+	if snippet := searchSnippet(job.Result, query); snippet != "" {
+	    html.WriteString(fmt.Sprintf(`<p style="color: #555; font-size: 14px;">…%s…</p>`, snippet))
+	}
+    }
+
+    return template.HTML(html.String())
+}
+
+// searchSnippet 截取关键词附近的文本并用 <mark> 高亮（已做 HTML 转义）
+// 找不到完整关键词时（如全文检索的词形变化）依次尝试各个单词，仍找不到则取开头
+func searchSnippet(text, query string) string {
+    runes := []rune(text)
+    if len(runes) == 0 {
+	return ""
+    }
+
+    candidates := append([]string{query}, strings.Fields(query)...)
+    for _, candidate := range candidates {
+	needle := []rune(candidate)
+	pos := indexFold(runes, needle)
+	if pos < 0 {
+	    continue
+	}
+
+	start := max(pos-snippetRadius, 0)
+	end := min(pos+len(needle)+snippetRadius, len(runes))
+	return template.HTMLEscapeString(string(runes[start:pos])) +
+	    "<mark>" + template.HTMLEscapeString(string(runes[pos:pos+len(needle)])) + "</mark>" +
+	    template.HTMLEscapeString(string(runes[pos+len(needle):end]))
+    }
+
+    return template.HTMLEscapeString(string(runes[:min(2*snippetRadius, len(runes))]))
+}
+
+// indexFold 不区分大小写查找子串，返回字符（rune）下标
+func indexFold(text, needle []rune) int {
+    if len(needle) == 0 {
+	return -1
+    }
+    for i := 0; i+len(needle) <= len(text); i++ {
+	matched := true
+	for j, r := range needle {
+	    if unicode.ToLower(text[i+j]) != unicode.ToLower(r) {
+		matched = false
+		break
+	    }
+	}
+	if matched {
+	    return i
+	}
+    }
+    return -1
+}
+
+// artifactLabels 分享页面中各类产物的名称（未列出的类型直接显示 kind）
+var artifactLabels = map[string]string{
+    "transcript_txt":         "📥 转录文本",
+    "transcript_json":        "🧾 JSON 转录稿",
+    "transcript_md":          "📝 Markdown 转录稿",
+    "transcript_timestamped": "🕒 带时间戳文本",
+    "srt":                    "🎬 SRT 字幕",
+    "vtt":                    "🎬 WebVTT 字幕",
+    "bilingual_srt":          "🌐 双语 SRT 字幕",
+    "bilingual_vtt":          "🌐 双语 WebVTT 字幕",
+    "vocabulary_csv":         "📚 单词（CSV）",
+    "vocabulary_tsv":         "📚 单词（Anki TSV）",
+    "zip":                    "📦 全部打包下载",
+    "media":                  "🎵 原始媒体",
+}
+
+// RenderSharePage 渲染任务的分享页面（完整 HTML 文档）：任务信息、所有可下载产物和转录文本
+// 产物列表来自 artifacts.List，新增的产物类型会自动出现
+func RenderSharePage(job *models.TranscriptionJob, list []artifacts.Artifact) template.HTML {
+    var html strings.Builder
+    name := template.HTMLEscapeString(job.Filename)
+
+    html.WriteString(fmt.Sprintf(`<!DOCTYPE html>
+<html lang="zh-CN">
+<head>
+<meta charset="UTF-8">
+<meta name="viewport" content="width=device-width, initial-scale=1.0">
+<title>%s - VoiceFlow</title>
+</head>
+<body>
+<h1>%s %s</h1>
+<p>状态: %s`, name, GetMediaIcon(job.Filename), name, statusText(job.Status)))
+    if job.Duration > 0 {
+	html.WriteString(" | 时长: " + FormatDuration(job.Duration))
+    }
+    if job.Language != "" {
+	html.WriteString(" | 语言: " + template.HTMLEscapeString(job.Language))
+    }
+    html.WriteString("</p>\n")
+
+    if len(list) == 0 {
+	html.WriteString("<p>暂无可下载的文件</p>\n")
+    } else {
+	html.WriteString("<h2>下载</h2>\n<ul>\n")
+	for _, artifact := range list {
+	    label, ok := artifactLabels[artifact.Kind]
+	    if !ok {
+		label = artifact.Kind
+	    }
+	    // 打包下载的大小未知（为 0），只显示文件名
+	    info := template.HTMLEscapeString(artifact.Filename)
+	    if artifact.Size > 0 {
+		info += " · " + FormatFileSize(artifact.Size)
+	    }
+	    html.WriteString(fmt.Sprintf(`<li><a href="%s" download="%s">%s</a> <small>%s</small></li>
+`, template.HTMLEscapeString(artifact.URL), template.HTMLEscapeString(artifact.Filename), label, info))
+	}
+	html.WriteString("</ul>\n")
+    }
+
+    if job.Status == models.StatusCompleted && job.Result != "" {
+	html.WriteString(fmt.Sprintf(`<h2>转录结果</h2>
+<textarea rows="20" cols="100" readonly>%s</textarea>
+`, template.HTMLEscapeString(job.Result)))
+    }
+
+    html.WriteString("</body>\n</html>\n")
+    return template.HTML(html.String())
+}
@@ -0,0 +1,27 @@
+package templates
+
+import "testing"
+
+func TestIsVideoFile(t *testing.T) {
+	tests := []struct {
+		filename string
+		want     bool
+	}{
+		{"talk.mp4", true},
+		{"TALK.MOV", true},
+		{"clip.mkv", true},
+		{"recording.webm", true},
+		{"voice.ogg", false},
+		{"voice.opus", false},
+		{"talk.mp3", false},
+		{"talk.m4a", false},
+		{"notes", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.filename, func(t *testing.T) {
+			if got := IsVideoFile(tt.filename); got != tt.want {
+				t.Fatalf("IsVideoFile(%q) = %v，期望 %v", tt.filename, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,41 @@
+package transcriber
+
+import "strings"
+
+// BilingualCues 将原文字幕与译文合并为双语字幕（原文在上，译文在下）
+// translations 与 cues 一一对应，译文为空时只保留原文
+func BilingualCues(cues []Cue, translations []string) []Cue {
+	result := make([]Cue, 0, len(cues))
+	for i, cue := range cues {
+		text := cue.Text
+		if i < len(translations) {
+			if translated := strings.TrimSpace(translations[i]); translated != "" {
+				text += "\n" + translated
+			}
+		}
+		// 双语字幕不使用逐词高亮（译文没有单词时间戳）
+		result = append(result, Cue{
+			Start: cue.Start,
+			End:   cue.End,
+			Text:  text,
+		})
+	}
+	return result
+}
+
+// GenerateBilingualSRT 生成双语 SRT 字幕文件
+func GenerateBilingualSRT(cues []Cue, translations []string, outputPath string) error {
+	return WriteSRT(BilingualCues(cues, translations), outputPath)
+}
+
+// GenerateBilingualVTT 生成双语 WebVTT 字幕文件
+func GenerateBilingualVTT(cues []Cue, translations []string, outputPath string) error {
+	return WriteVTT(BilingualCues(cues, translations), outputPath)
+}
+
+// BilingualPath 双语字幕文件路径（与原字幕同目录），如 uploads/abc.srt -> uploads/abc.bilingual.srt
+func BilingualPath(subtitlePath, ext string) string {
+	base := strings.TrimSuffix(subtitlePath, ".srt")
+	base = strings.TrimSuffix(base, ".vtt")
+	return base + ".bilingual" + ext
+}
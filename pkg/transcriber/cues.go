@@ -0,0 +1,220 @@
+package transcriber
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"unicode/utf8"
+)
+
+// defaultLineChars 每行字幕的默认最大长度（字符数，Netflix 字幕规范）
+const defaultLineChars = 42
+
+// Cue 一条字幕（时间为整个音频中的绝对时间）
+// 字幕生成流程：BuildCues 解析 → 各种变换（如 WrapCues、CapCues）→ WriteSRT / WriteVTT 输出
+type Cue struct {
+	Start float64       // 开始时间（秒）
+	End   float64       // 结束时间（秒）
+	Text  string        // 字幕文本（可包含换行）
+	Words []WhisperWord // 单词级时间戳（绝对时间，可为空）
+}
+
+// BuildCues 将各音频片段的 Whisper 结果转换为字幕列表
+// 每个 Whisper 片段的时间都会加上所在音频片段的起始偏移。
+// 音频片段与上一片段重叠时（Segment.Overlap > 0），完全落在重叠区域内的字幕已由上一片段生成，直接丢弃；
+// 跨越边界的字幕从上一条字幕结束时开始，保证时间戳单调递增
+func BuildCues(segmentResults []SegmentResult) []Cue {
+	cues := make([]Cue, 0)
+
+	for _, sr := range segmentResults {
+		if sr.Response == nil || len(sr.Response.Segments) == 0 {
+			continue
+		}
+
+		// 上一片段的结束时间（绝对时间），不重叠时等于片段开始时间
+		boundary := sr.Segment.Start + sr.Segment.Overlap
+
+		// 单词级时间戳按顺序分配给各个片段
+		wordIndex := 0
+
+		for _, whisperSeg := range sr.Response.Segments {
+			var words []WhisperWord
+			if len(sr.Response.Words) > 0 {
+				words, wordIndex = wordsInSegment(sr.Response.Words, wordIndex, whisperSeg)
+			}
+
+			// 清理文本（去除首尾空格）
+			text := strings.TrimSpace(whisperSeg.Text)
+			if text == "" {
+				continue
+			}
+
+			cue := Cue{
+				Start: sr.Segment.Start + whisperSeg.Start,
+				End:   sr.Segment.Start + whisperSeg.End,
+				Text:  text,
+			}
+			for _, w := range words {
+				cue.Words = append(cue.Words, WhisperWord{
+					Word:  w.Word,
+					Start: sr.Segment.Start + w.Start,
+					End:   sr.Segment.Start + w.End,
+				})
+			}
+
+			if sr.Segment.Overlap > 0 {
+				if cue.End <= boundary {
+					continue
+				}
+				if len(cues) > 0 {
+					var ok bool
+					if cue, ok = trimCueStart(cue, cues[len(cues)-1].End); !ok {
+						continue
+					}
+				}
+			}
+			cues = append(cues, cue)
+		}
+	}
+
+	return cues
+}
+
+// trimCueStart 将字幕的开始时间推迟到 start（上一条字幕的结束时间）
+// 有单词级时间戳时同时去掉 start 之前已经说完的单词，并用剩余单词重建文本；
+// 推迟后没有剩余时长或单词时返回 false
+func trimCueStart(cue Cue, start float64) (Cue, bool) {
+	if cue.Start >= start {
+		return cue, true
+	}
+	if cue.End <= start {
+		return cue, false
+	}
+	cue.Start = start
+
+	if len(cue.Words) == 0 {
+		return cue, true
+	}
+	kept := make([]WhisperWord, 0, len(cue.Words))
+	texts := make([]string, 0, len(cue.Words))
+	for _, w := range cue.Words {
+		if w.End <= start {
+			continue
+		}
+		kept = append(kept, w)
+		texts = append(texts, strings.TrimSpace(w.Word))
+	}
+	if len(kept) == 0 {
+		return cue, false
+	}
+	cue.Words = kept
+	cue.Text = strings.Join(texts, " ")
+	return cue, true
+}
+
+// wordsInSegment 从 start 位置开始取出属于该片段的单词，返回单词和下一个起始位置
+func wordsInSegment(words []WhisperWord, start int, seg WhisperSegment) ([]WhisperWord, int) {
+	i := start
+	// 跳过落在片段之前的单词
+	for i < len(words) && words[i].Start < seg.Start {
+		i++
+	}
+	begin := i
+	for i < len(words) && words[i].Start < seg.End {
+		i++
+	}
+	return words[begin:i], i
+}
+
+// ValidateCues 检查手动编辑的字幕：时间不能为负、结束晚于开始、文本不能为空，
+// 并且按时间顺序排列、互不重叠（每条字幕不能早于上一条结束）
+func ValidateCues(cues []Cue) error {
+	prevEnd := 0.0
+	for i, cue := range cues {
+		switch {
+		case cue.Start < 0:
+			return fmt.Errorf("第 %d 条字幕的开始时间不能为负数", i+1)
+		case cue.End <= cue.Start:
+			return fmt.Errorf("第 %d 条字幕的结束时间必须晚于开始时间", i+1)
+		case strings.TrimSpace(cue.Text) == "":
+			return fmt.Errorf("第 %d 条字幕的文本为空", i+1)
+		case cue.Start < prevEnd:
+			return fmt.Errorf("第 %d 条字幕与上一条重叠（开始于 %.3f 秒，上一条结束于 %.3f 秒）", i+1, cue.Start, prevEnd)
+		}
+		prevEnd = cue.End
+	}
+	return nil
+}
+
+// CuesText 将字幕文本按顺序拼接为纯文本结果（字幕内的换行替换为空格）
+func CuesText(cues []Cue) string {
+	texts := make([]string, 0, len(cues))
+	for _, cue := range cues {
+		texts = append(texts, strings.Join(strings.Fields(cue.Text), " "))
+	}
+	return strings.Join(texts, " ")
+}
+
+// CapCues 将字幕数量限制在 maxCues 以内（maxCues <= 0 表示不限制）
+// 相邻字幕均匀分组合并（而不是全部集中在开头），合并后的时间范围覆盖原有字幕，
+// 文本按行长度折行，避免出现一整行超长字幕。应在所有其他变换之后调用。
+func CapCues(cues []Cue, maxCues int) []Cue {
+	if maxCues <= 0 || len(cues) <= maxCues {
+		return cues
+	}
+
+	total := len(cues)
+	merged := make([]Cue, 0, maxCues)
+	for i := 0; i < maxCues; i++ {
+		// 第 i 组包含 [i*total/maxCues, (i+1)*total/maxCues)，各组大小最多相差 1
+		begin := i * total / maxCues
+		end := (i + 1) * total / maxCues
+		if begin == end {
+			continue
+		}
+		merged = append(merged, mergeCues(cues[begin:end]))
+	}
+
+	log.Printf("✂️  字幕数量 %d 超过上限 %d，已合并为 %d 条", total, maxCues, len(merged))
+	return merged
+}
+
+// mergeCues 合并一组相邻字幕
+func mergeCues(group []Cue) Cue {
+	result := Cue{
+		Start: group[0].Start,
+		End:   group[len(group)-1].End,
+	}
+
+	texts := make([]string, 0, len(group))
+	for _, c := range group {
+		texts = append(texts, c.Text)
+		result.Words = append(result.Words, c.Words...)
+	}
+	result.Text = joinLines(texts, defaultLineChars)
+
+	return result
+}
+
+// joinLines 拼接多段文本，超过行长度时换行
+func joinLines(texts []string, lineChars int) string {
+	var builder strings.Builder
+	lineLen := 0
+	for _, text := range texts {
+		for i, line := range strings.Split(text, "\n") {
+			n := utf8.RuneCountInString(line)
+			switch {
+			case builder.Len() == 0:
+			case i > 0 || lineLen+1+n > lineChars:
+				builder.WriteString("\n")
+				lineLen = 0
+			default:
+				builder.WriteString(" ")
+				lineLen++
+			}
+			builder.WriteString(line)
+			lineLen += n
+		}
+	}
+	return builder.String()
+}
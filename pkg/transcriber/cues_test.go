@@ -0,0 +1,88 @@
+package transcriber
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// tinyCues 生成 n 条相邻的短字幕，每条 0.5 秒、带一个单词级时间戳
+func tinyCues(n int) []Cue {
+	cues := make([]Cue, n)
+	for i := range cues {
+		start := float64(i) * 0.5
+		word := fmt.Sprintf("w%d", i)
+		cues[i] = Cue{
+			Start: start,
+			End:   start + 0.5,
+			Text:  word,
+			Words: []WhisperWord{{Word: word, Start: start, End: start + 0.5}},
+		}
+	}
+	return cues
+}
+
+func TestCapCues(t *testing.T) {
+	tests := []struct {
+		name    string
+		cues    int
+		maxCues int
+		want    int
+	}{
+		{"不限制", 100, 0, 100},
+		{"未超过上限", 100, 100, 100},
+		{"大量短字幕", 5000, 100, 100},
+		{"不能整除", 1000, 7, 7},
+		{"只比上限多一条", 101, 100, 100},
+		{"上限为一条", 50, 1, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cues := tinyCues(tt.cues)
+			got := CapCues(cues, tt.maxCues)
+			if len(got) != tt.want {
+				t.Fatalf("字幕 %d 条，期望 %d 条", len(got), tt.want)
+			}
+
+			// 合并后的字幕首尾相接、完整覆盖原有时间轴
+			if got[0].Start != cues[0].Start || got[len(got)-1].End != cues[len(cues)-1].End {
+				t.Fatalf("时间范围 %.1f–%.1f，期望 %.1f–%.1f", got[0].Start, got[len(got)-1].End, cues[0].Start, cues[len(cues)-1].End)
+			}
+			for i := 1; i < len(got); i++ {
+				if got[i].Start != got[i-1].End {
+					t.Fatalf("第 %d 条开始于 %.1f，上一条结束于 %.1f，时间轴不连续", i+1, got[i].Start, got[i-1].End)
+				}
+			}
+			if err := ValidateCues(got); err != nil {
+				t.Fatalf("ValidateCues: %v", err)
+			}
+
+			// 文本和单词时间戳都保留，且均匀分组（各组大小最多相差 1）
+			if CuesText(got) != CuesText(cues) {
+				t.Fatal("合并后的文本与原文不一致")
+			}
+			minWords, maxWords := len(cues), 0
+			for _, cue := range got {
+				minWords = min(minWords, len(cue.Words))
+				maxWords = max(maxWords, len(cue.Words))
+			}
+			if maxWords-minWords > 1 {
+				t.Fatalf("每条字幕包含 %d 到 %d 条原字幕，合并不均匀", minWords, maxWords)
+			}
+		})
+	}
+}
+
+func TestCapCuesWrapsMergedText(t *testing.T) {
+	cues := []Cue{
+		{Start: 0, End: 1, Text: "the quick brown fox jumps"},
+		{Start: 1, End: 2, Text: "over the lazy dog again"},
+		{Start: 2, End: 3, Text: "and again"},
+	}
+	got := CapCues(cues, 1)
+	for _, line := range strings.Split(got[0].Text, "\n") {
+		if len(line) > defaultLineChars {
+			t.Fatalf("合并后的行 %q 超过 %d 个字符", line, defaultLineChars)
+		}
+	}
+}
@@ -2,13 +2,15 @@ package transcriber
 
 import (
     "context"
+    "errors"
     "fmt"
-    "log"
     "path/filepath"
     "sort"
     "strings"
     "sync"
+    "time"
 
+    "github.com/z-wentao/voiceflow/pkg/logging"
     "github.com/z-wentao/voiceflow/pkg/models"
 )
 
@@ -18,18 +20,65 @@ type TranscriptionEngine struct {
     whisperClient       *WhisperClient
     splitter            *AudioSplitter
     segmentConcurrency  int // 音频分片并发处理数
+    maxCues             int // 字幕条数上限（0 表示不限制）
+    maxLineChars        int // 字幕每行最大宽度（0 表示不折行）
+    prompt              string  // 默认的 Whisper 提示词（任务未指定时使用）
+    temperature         float64 // 默认的 Whisper 采样温度（任务未指定时使用，0 表示不发送）
+    translator          *Translator // 字幕翻译器（nil 表示不生成双语字幕）
+    timeoutPerMinute    time.Duration // 每分钟音频的单次请求时间预算（0 表示不按时长设置截止时间）
+    segmentStore        SegmentStore  // 保存已完成片段的结果（nil 表示不支持中断后续传）
 }
 
-func NewTranscriptionEngine(apiKey string, segmentConcurrency int, segmentDuration int) *TranscriptionEngine {
+// minSegmentTimeout 按时长计算的单次请求截止时间下限（短片段也需要上传和排队的时间）
+const minSegmentTimeout = 30 * time.Second
+
+// EngineOptions 转换引擎可选参数（零值即默认行为）
+type EngineOptions struct {
+    BaseURL        string      // OpenAI 兼容的 API 地址（如自建 whisper.cpp），为空时使用 OpenAI 官方地址
+    WordTimestamps bool        // 请求单词级时间戳，生成逐词高亮的 VTT
+    MaxCues        int         // 字幕条数上限，超过时均匀合并相邻字幕（0 表示不限制）
+    MaxLineChars   int         // 字幕每行最大宽度（中日韩文字计 2），超长时折成两行、超过两行时拆分字幕（0 表示不折行）
+    OverlapSeconds int         // 相邻音频片段的重叠时长（秒），重叠部分的重复字幕会被去除（0 表示不重叠）
+    Prompt         string      // 默认的 Whisper 提示词（专有名词、术语），任务指定时以任务为准
+    Temperature    float64     // 默认的 Whisper 采样温度（0 到 1），任务指定时以任务为准
+    Translator     *Translator // 设置后在生成字幕时同时生成双语字幕
+
+    WhisperTimeout   time.Duration // Whisper HTTP 请求的超时上限，0 时使用 DefaultWhisperTimeout
+    TimeoutPerMinute time.Duration // 每分钟音频的请求时间预算，片段请求的截止时间按片段时长等比例计算（0 表示不设置）
+    SegmentStore     SegmentStore  // 保存已完成片段的结果，任务中断后重新处理时跳过这些片段（nil 表示每次从头转录）
+    SegmentEncoding  SegmentEncoding // 音频片段的 MP3 编码参数（零值为 128k，保持源文件的采样率和声道数）
+}
+
+func NewTranscriptionEngine(apiKey string, segmentConcurrency int, segmentDuration int, opts EngineOptions) *TranscriptionEngine {
     if segmentConcurrency <= 0 {
 	segmentConcurrency = 3 // 默认 3 个并发分片处理
     }
 
+    whisperClient := NewWhisperClient(apiKey, opts.BaseURL, opts.WhisperTimeout)
+    whisperClient.SetWordTimestamps(opts.WordTimestamps)
+
     return &TranscriptionEngine{
-	whisperClient:      NewWhisperClient(apiKey),
-	splitter:           NewAudioSplitter(segmentDuration),
+	whisperClient:      whisperClient,
+	splitter:           NewAudioSplitter(segmentDuration, opts.OverlapSeconds, opts.SegmentEncoding),
 	segmentConcurrency: segmentConcurrency,
+	maxCues:            opts.MaxCues,
+	maxLineChars:       opts.MaxLineChars,
+	prompt:             opts.Prompt,
+	temperature:        opts.Temperature,
+	translator:         opts.Translator,
+	timeoutPerMinute:   opts.TimeoutPerMinute,
+	segmentStore:       opts.SegmentStore,
+    }
+}
+
+// segmentTimeout 按片段时长计算单次 Whisper 请求的截止时间（不低于 minSegmentTimeout）
+// 返回 0 表示不设置截止时间，只受 HTTP 客户端超时限制
+func (te *TranscriptionEngine) segmentTimeout(segment models.Segment) time.Duration {
+    if te.timeoutPerMinute <= 0 {
+	return 0
     }
+    minutes := (segment.End - segment.Start) / 60
+    return max(time.Duration(minutes*float64(te.timeoutPerMinute)), minSegmentTimeout)
 }
 
 // ProcessResult 处理结果（内部用于 Channel 传递）
@@ -37,13 +86,20 @@ type ProcessResult struct {
     SegmentIndex int
     Response     *WhisperResponse // 完整的 Whisper 响应（包含时间戳）
     Error        error
+    Duration     time.Duration // 片段转录耗时（包括重试）
 }
 
 // TranscriptionResult 转录结果
 type TranscriptionResult struct {
-    Text         string // 纯文本结果
-    SubtitlePath string // SRT 字幕文件路径
-    VTTPath      string // WebVTT 字幕文件路径（用于网页播放）
+    Text             string  // 纯文本结果
+    SubtitlePath     string  // SRT 字幕文件路径
+    VTTPath          string  // WebVTT 字幕文件路径（用于网页播放）
+    BilingualSRTPath string  // 双语 SRT 字幕文件路径（未开启或翻译失败时为空）
+    BilingualVTTPath string  // 双语 WebVTT 字幕文件路径
+    Duration         float64 // 音频总时长（秒），保存到任务记录并用于估算 Whisper 费用
+
+    TranslationPromptTokens     int // 翻译字幕消耗的输入 token 数（用于估算费用）
+    TranslationCompletionTokens int // 翻译字幕消耗的输出 token 数
 }
 
 // Transcribe 转换整个音频文件（返回文本和字幕）
@@ -52,38 +108,74 @@ type TranscriptionResult struct {
 // 3. Channel 收集结果
 // 4. WaitGroup 等待所有 Goroutine 完成
 // 5. 错误处理和进度回调
+// opts 中未设置的提示词和采样温度使用引擎的默认值
+// 配置了 SegmentStore 且 jobID 不为空时，每个片段完成后保存结果，同一任务重新处理时跳过已完成的片段；
+// 所有片段完成后删除保存的结果
 func (te *TranscriptionEngine) Transcribe(
     ctx context.Context,
+    jobID string,
     audioPath string,
-    language string,
+    opts WhisperOptions,
     progressCallback func(progress int),
 ) (*TranscriptionResult, error) {
+    if opts.Prompt == "" {
+	opts.Prompt = te.prompt
+    }
+    if opts.Temperature == 0 {
+	opts.Temperature = te.temperature
+    }
+
+    // Whisper 不支持的格式（ogg / opus）先转码为 MP3，转录结束后删除转码文件
+    // 字幕文件仍以原始文件命名
+    input, cleanup, err := prepareAudio(ctx, audioPath)
+    if err != nil {
+	return nil, err
+    }
+    defer cleanup()
+
     // split the video or audio
-    log.Printf("开始分片音频: %s", audioPath)
-    segments, err := te.splitter.Split(audioPath)
+    logger := logging.FromContext(ctx)
+    logger.Info("开始分片音频", "path", input)
+    segments, err := te.splitter.Split(input)
     if err != nil {
 	return nil, fmt.Errorf("分片失败: %v", err)
     }
     defer te.splitter.Cleanup(segments)
 
     totalSegments := len(segments)
-    log.Printf("✓ 音频已分片，共 %d 个片段", totalSegments)
+    totalDuration := segments[totalSegments-1].End
+    logger.Info("✓ 音频已分片", "segments", totalSegments, "audio_duration", totalDuration)
+
+    // 1. 读取上次中断前已完成的片段
+    fingerprint := te.optionsFingerprint(opts)
+    results := te.loadSegments(ctx, jobID, segments, fingerprint)
+    if len(results) > 0 {
+	logger.Info("♻️ 跳过已完成的片段", "resumed", len(results), "total", totalSegments)
+    }
+    pending := make(map[int]models.Segment, totalSegments-len(results))
+    for _, segment := range segments {
+	if _, ok := results[segment.Index]; !ok {
+	    pending[segment.Index] = segment
+	}
+    }
 
     // 2. 创建任务队列和结果收集 Channel
-    taskChan := make(chan models.Segment, totalSegments)
-    resultChan := make(chan ProcessResult, totalSegments)
+    taskChan := make(chan models.Segment, len(pending))
+    resultChan := make(chan ProcessResult, len(pending))
 
     // 3. 启动 Goroutine Pool（面试亮点：并发控制）
-    log.Printf("🚀 启动 %d 个并发分片处理器进行处理...", te.segmentConcurrency)
+    logger.Info("🚀 启动并发分片处理器", "concurrency", te.segmentConcurrency)
     var wg sync.WaitGroup
     for i := 0; i < te.segmentConcurrency; i++ {
 	wg.Add(1)
-	go te.segmentProcessor(ctx, i, taskChan, resultChan, language, &wg)
+	go te.segmentProcessor(ctx, i, taskChan, resultChan, opts, &wg)
     }
 
-    // 4. 发送任务到队列
+    // 4. 发送任务到队列（按片段顺序）
     for _, segment := range segments {
-	taskChan <- segment
+	if _, ok := pending[segment.Index]; ok {
+	    taskChan <- segment
+	}
     }
     close(taskChan) // 关闭任务 Channel，告诉 worker 没有更多任务了
 
@@ -93,22 +185,28 @@ func (te *TranscriptionEngine) Transcribe(
 	close(resultChan)   // 关闭结果 Channel
     }()
 
-    // 6. 收集结果
-    results := make(map[int]*WhisperResponse)
-    var errors []error
-    completedCount := 0
+    // 6. 收集结果（已完成的片段计入进度）
+    var failures []error
+    completedCount := len(results)
+    if completedCount > 0 && progressCallback != nil {
+	progressCallback((completedCount * 100) / totalSegments)
+    }
 
     for result := range resultChan {
 	completedCount++
 
 	if result.Error != nil {
-	    errors = append(errors, fmt.Errorf("片段 %d 失败: %v", result.SegmentIndex, result.Error))
-	    log.Printf("❌ 片段 #%d 转换失败: %v", result.SegmentIndex, result.Error)
+	    failures = append(failures, fmt.Errorf("片段 %d 失败: %w", result.SegmentIndex, result.Error))
+	    logger.Error("❌ 片段转换失败", "segment", result.SegmentIndex, "duration", result.Duration, "error", result.Error)
 	} else {
 	    results[result.SegmentIndex] = result.Response
-	    log.Printf("✅ 片段 #%d 转换完成 | 进度: %d/%d (%.1f%%) | 文本长度: %d 字符",
-		result.SegmentIndex, completedCount, totalSegments,
-		float64(completedCount*100)/float64(totalSegments), len(result.Response.Text))
+	    te.saveSegment(ctx, jobID, pending[result.SegmentIndex], fingerprint, result.Response)
+	    logger.Info("✅ 片段转换完成",
+		"segment", result.SegmentIndex,
+		"completed", completedCount,
+		"total", totalSegments,
+		"duration", result.Duration,
+		"text_length", len(result.Response.Text))
 	}
 
 	// 进度回调
@@ -119,34 +217,43 @@ func (te *TranscriptionEngine) Transcribe(
     }
 
     // 7. 检查是否有错误
-    if len(errors) > 0 {
-	return nil, fmt.Errorf("转换过程中出现 %d 个错误: %v", len(errors), errors[0])
+    // 错误链中保留 ErrWhisperTimeout，调用方可以区分超时与其他失败
+    if len(failures) > 0 {
+	return nil, fmt.Errorf("转换过程中出现 %d 个错误: %w", len(failures), failures[0])
     }
+    te.clearSegments(ctx, jobID)
 
     // 8. 按顺序合并文本结果
-    finalText := te.mergeTextResults(results, totalSegments)
-    log.Printf("✓ 所有片段转换完成，总长度: %d 字符", len(finalText))
+    finalText := te.mergeTextResults(segments, results)
+    logger.Info("✓ 所有片段转换完成", "text_length", len(finalText))
 
     // 9. 生成字幕文件（SRT 和 VTT）
-    srtPath, vttPath, err := te.generateSubtitleFiles(segments, results, audioPath)
+    srtPath, vttPath, cues, err := te.generateSubtitleFiles(segments, results, audioPath)
     if err != nil {
-	log.Printf("⚠️ 生成字幕文件失败: %v", err)
+	logger.Warn("⚠️ 生成字幕文件失败", "error", err)
 	// 不影响主流程，继续返回文本结果
 	return &TranscriptionResult{
 	    Text:         finalText,
 	    SubtitlePath: "",
 	    VTTPath:      "",
+	    Duration:     totalDuration,
 	}, nil
     }
 
-    log.Printf("✓ 字幕文件已生成:")
-    log.Printf("  - SRT: %s", srtPath)
-    log.Printf("  - VTT: %s", vttPath)
-    return &TranscriptionResult{
+    logger.Info("✓ 字幕文件已生成", "srt", srtPath, "vtt", vttPath)
+    result := &TranscriptionResult{
 	Text:         finalText,
 	SubtitlePath: srtPath,
 	VTTPath:      vttPath,
-    }, nil
+	Duration:     totalDuration,
+    }
+
+    // 10. 生成双语字幕（可选，失败时只保留单语字幕）
+    if te.translator != nil {
+	te.generateBilingualFiles(ctx, cues, srtPath, result)
+    }
+
+    return result, nil
 }
 
 // segmentProcessor 分片处理器 - Goroutine Pool 中的工作单元
@@ -156,12 +263,13 @@ func (te *TranscriptionEngine) segmentProcessor(
     processorID int,
     taskChan <-chan models.Segment,
     resultChan chan<- ProcessResult,
-    language string,
+    opts WhisperOptions,
     wg *sync.WaitGroup,
 ) {
     defer wg.Done()
 
-    log.Printf("分片处理器 #%d 启动", processorID)
+    logger := logging.FromContext(ctx).With("processor", processorID)
+    logger.Debug("分片处理器启动")
 
     for segment := range taskChan {
 	// 检查 Context 是否已取消
@@ -176,23 +284,29 @@ func (te *TranscriptionEngine) segmentProcessor(
 	}
 
 	// 转换音频片段（带重试）
-	log.Printf("🔄 [分片处理器-%d] 正在处理片段 #%d (%.1fs - %.1fs)",
-	    processorID, segment.Index, segment.Start, segment.End)
-	response, err := te.whisperClient.TranscribeWithRetry(ctx, segment.FilePath, language, 3)
+	timeout := te.segmentTimeout(segment)
+	logger.Info("🔄 正在处理片段", "segment", segment.Index, "start", segment.Start, "end", segment.End, "timeout", timeout)
+	startTime := time.Now()
+	response, err := te.whisperClient.TranscribeWithRetry(ctx, segment.FilePath, opts, 3, timeout)
+	if errors.Is(err, ErrWhisperTimeout) {
+	    logger.Warn("⏱️ 片段转录超时", "segment", segment.Index, "timeout", timeout, "error", err)
+	}
 
 	// 发送结果
 	resultChan <- ProcessResult{
 	    SegmentIndex: segment.Index,
 	    Response:     response,
 	    Error:        err,
+	    Duration:     time.Since(startTime).Round(time.Millisecond),
 	}
     }
 
-    log.Printf("分片处理器 #%d 结束", processorID)
+    logger.Debug("分片处理器结束")
 }
 
 // mergeTextResults 按顺序合并所有片段的文本结果
-func (te *TranscriptionEngine) mergeTextResults(results map[int]*WhisperResponse, totalSegments int) string {
+// 与上一片段重叠的开头部分已由上一片段转录，跳过完全落在重叠区域内的 Whisper 片段
+func (te *TranscriptionEngine) mergeTextResults(segments []models.Segment, results map[int]*WhisperResponse) string {
     // 按索引排序
     indices := make([]int, 0, len(results))
     for idx := range results {
@@ -200,6 +314,11 @@ func (te *TranscriptionEngine) mergeTextResults(results map[int]*WhisperResponse
     }
     sort.Ints(indices)
 
+    overlaps := make(map[int]float64, len(segments))
+    for _, seg := range segments {
+	overlaps[seg.Index] = seg.Overlap
+    }
+
     // 合并文本
     var builder strings.Builder
     for _, idx := range indices {
@@ -207,19 +326,38 @@ func (te *TranscriptionEngine) mergeTextResults(results map[int]*WhisperResponse
 	    builder.WriteString(" ") // 片段之间添加空格
 	}
 	if resp := results[idx]; resp != nil {
-	    builder.WriteString(resp.Text)
+	    builder.WriteString(segmentText(resp, overlaps[idx]))
 	}
     }
 
     return builder.String()
 }
 
+// segmentText 片段的转录文本，去掉结束时间不晚于 overlap 的 Whisper 片段
+// 没有时间戳（或不重叠）时返回完整文本
+func segmentText(resp *WhisperResponse, overlap float64) string {
+    if overlap <= 0 || len(resp.Segments) == 0 {
+	return resp.Text
+    }
+
+    texts := make([]string, 0, len(resp.Segments))
+    for _, seg := range resp.Segments {
+	if seg.End <= overlap {
+	    continue
+	}
+	if text := strings.TrimSpace(seg.Text); text != "" {
+	    texts = append(texts, text)
+	}
+    }
+    return strings.Join(texts, " ")
+}
+
 // generateSubtitleFiles 生成字幕文件（SRT 和 VTT）
 func (te *TranscriptionEngine) generateSubtitleFiles(
     segments []models.Segment,
     results map[int]*WhisperResponse,
     audioPath string,
-) (string, string, error) {
+) (string, string, []Cue, error) {
     // 准备 SegmentResult 数据
     segmentResults := make([]SegmentResult, 0, len(segments))
     for _, seg := range segments {
@@ -236,15 +374,54 @@ func (te *TranscriptionEngine) generateSubtitleFiles(
     srtPath := basePath + ".srt"
     vttPath := basePath + ".vtt"
 
+    // 解析字幕并应用变换（条数上限最后执行）
+    cues := BuildCues(segmentResults)
+    cues = WrapCues(cues, te.maxLineChars)
+    cues = CapCues(cues, te.maxCues)
+
     // 生成 SRT 文件
-    if err := GenerateSRT(segmentResults, srtPath); err != nil {
-	return "", "", fmt.Errorf("生成 SRT 失败: %w", err)
+    if err := WriteSRT(cues, srtPath); err != nil {
+	return "", "", nil, fmt.Errorf("生成 SRT 失败: %w", err)
     }
 
     // 生成 VTT 文件
-    if err := GenerateVTT(segmentResults, vttPath); err != nil {
-	return "", "", fmt.Errorf("生成 VTT 失败: %w", err)
+    if err := WriteVTT(cues, vttPath); err != nil {
+	return "", "", nil, fmt.Errorf("生成 VTT 失败: %w", err)
+    }
+
+    return srtPath, vttPath, cues, nil
+}
+
+// generateBilingualFiles 翻译字幕并生成双语 SRT / VTT，结果写入 result
+// 翻译或写文件失败只记录日志，不影响单语字幕和转录结果
+func (te *TranscriptionEngine) generateBilingualFiles(ctx context.Context, cues []Cue, srtPath string, result *TranscriptionResult) {
+    if len(cues) == 0 {
+	return
+    }
+
+    logger := logging.FromContext(ctx)
+    logger.Info("🌐 开始翻译字幕", "cues", len(cues), "target_language", te.translator.TargetLanguage())
+    translated, err := te.translator.TranslateCues(ctx, cues)
+    // 已经产生的调用费用即使翻译失败也要记录
+    result.TranslationPromptTokens = translated.PromptTokens
+    result.TranslationCompletionTokens = translated.CompletionTokens
+    if err != nil {
+	logger.Warn("⚠️ 翻译字幕失败，跳过双语字幕", "error", err)
+	return
+    }
+
+    bilingualSRT := BilingualPath(srtPath, ".srt")
+    bilingualVTT := BilingualPath(srtPath, ".vtt")
+    if err := GenerateBilingualSRT(cues, translated.Lines, bilingualSRT); err != nil {
+	logger.Warn("⚠️ 生成双语 SRT 失败", "error", err)
+	return
+    }
+    if err := GenerateBilingualVTT(cues, translated.Lines, bilingualVTT); err != nil {
+	logger.Warn("⚠️ 生成双语 VTT 失败", "error", err)
+	return
     }
 
-    return srtPath, vttPath, nil
+    result.BilingualSRTPath = bilingualSRT
+    result.BilingualVTTPath = bilingualVTT
+    logger.Info("✓ 双语字幕已生成", "srt", bilingualSRT, "vtt", bilingualVTT)
 }
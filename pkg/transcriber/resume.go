@@ -0,0 +1,112 @@
+package transcriber
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"math"
+
+	"github.com/z-wentao/voiceflow/pkg/logging"
+	"github.com/z-wentao/voiceflow/pkg/models"
+)
+
+// SegmentStore 保存已完成片段的转录结果（由任务存储实现，如 Redis、PostgreSQL）
+// 任务中断（服务崩溃、重启）后重新处理时，已保存结果的片段不再调用 Whisper
+type SegmentStore interface {
+	// SaveSegment 保存任务中一个片段的结果（同一片段重复保存时覆盖）
+	SaveSegment(ctx context.Context, jobID string, index int, data []byte) error
+
+	// LoadSegments 读取任务已保存的所有片段结果，key 为片段序号
+	LoadSegments(ctx context.Context, jobID string) (map[int][]byte, error)
+
+	// DeleteSegments 删除任务的所有片段结果（没有保存时不报错）
+	DeleteSegments(ctx context.Context, jobID string) error
+}
+
+// segmentTolerance 判断片段边界是否一致的误差（秒）
+const segmentTolerance = 0.01
+
+// segmentRecord 保存到 SegmentStore 的片段结果
+// 片段边界和请求参数指纹与本次转录一致时才复用，分片配置或 Whisper 参数变化后重新转录
+type segmentRecord struct {
+	Start       float64          `json:"start"`
+	End         float64          `json:"end"`
+	Fingerprint string           `json:"fingerprint"`
+	Response    *WhisperResponse `json:"response"`
+}
+
+// optionsFingerprint 影响转录结果的请求参数指纹
+func (te *TranscriptionEngine) optionsFingerprint(opts WhisperOptions) string {
+	model := opts.Model
+	if model == "" {
+		model = DefaultModel
+	}
+	data, _ := json.Marshal([]any{model, opts.Language, opts.Prompt, opts.Temperature, te.whisperClient.wordTimestamps})
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:8])
+}
+
+// loadSegments 读取任务已保存的片段结果（未配置 SegmentStore 或 jobID 为空时返回空）
+// 读取失败或记录无法复用时忽略，对应片段重新转录
+func (te *TranscriptionEngine) loadSegments(ctx context.Context, jobID string, segments []models.Segment, fingerprint string) map[int]*WhisperResponse {
+	done := make(map[int]*WhisperResponse)
+	if te.segmentStore == nil || jobID == "" {
+		return done
+	}
+
+	logger := logging.FromContext(ctx)
+	saved, err := te.segmentStore.LoadSegments(ctx, jobID)
+	if err != nil {
+		logger.Warn("⚠️ 读取已完成的片段失败，全部重新转录", "error", err)
+		return done
+	}
+
+	for _, segment := range segments {
+		data, ok := saved[segment.Index]
+		if !ok {
+			continue
+		}
+		var record segmentRecord
+		if err := json.Unmarshal(data, &record); err != nil || record.Response == nil {
+			continue
+		}
+		if record.Fingerprint != fingerprint ||
+			math.Abs(record.Start-segment.Start) > segmentTolerance ||
+			math.Abs(record.End-segment.End) > segmentTolerance {
+			continue
+		}
+		done[segment.Index] = record.Response
+	}
+	return done
+}
+
+// saveSegment 保存片段结果（失败时只记录日志，不影响本次转录）
+func (te *TranscriptionEngine) saveSegment(ctx context.Context, jobID string, segment models.Segment, fingerprint string, resp *WhisperResponse) {
+	if te.segmentStore == nil || jobID == "" {
+		return
+	}
+
+	data, err := json.Marshal(segmentRecord{
+		Start:       segment.Start,
+		End:         segment.End,
+		Fingerprint: fingerprint,
+		Response:    resp,
+	})
+	if err == nil {
+		err = te.segmentStore.SaveSegment(ctx, jobID, segment.Index, data)
+	}
+	if err != nil {
+		logging.FromContext(ctx).Warn("⚠️ 保存片段结果失败", "segment", segment.Index, "error", err)
+	}
+}
+
+// clearSegments 所有片段转录完成后删除已保存的片段结果
+func (te *TranscriptionEngine) clearSegments(ctx context.Context, jobID string) {
+	if te.segmentStore == nil || jobID == "" {
+		return
+	}
+	if err := te.segmentStore.DeleteSegments(ctx, jobID); err != nil {
+		logging.FromContext(ctx).Warn("⚠️ 删除已保存的片段结果失败", "error", err)
+	}
+}
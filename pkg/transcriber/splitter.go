@@ -15,16 +15,55 @@ import (
 
 // AudioSplitter 音频分片器
 type AudioSplitter struct {
-    segmentDuration int // 每个片段的时长（秒），默认 600 秒（10 分钟）
+    segmentDuration int             // 每个片段的时长（秒），默认 600 秒（10 分钟）
+    overlap         int             // 相邻片段的重叠时长（秒）：第二个片段起每段提前开始，避免切点处的单词丢失
+    encoding        SegmentEncoding // 片段的 MP3 编码参数
 }
 
-// NewAudioSplitter 创建分片器
-func NewAudioSplitter(segmentDuration int) *AudioSplitter {
+// defaultSegmentBitrate 片段默认的 MP3 比特率
+const defaultSegmentBitrate = "128k"
+
+// SegmentEncoding 片段的 MP3 编码参数（零值为默认行为：128k，保持源文件的采样率和声道数）
+// 语音使用 64k / 16kHz / 单声道即可，上传给 Whisper 的数据量约为默认的一半，识别准确率不受影响
+type SegmentEncoding struct {
+    Bitrate    string // 比特率，如 "64k"，为空时为 128k
+    SampleRate int    // 采样率（Hz），如 16000，0 表示保持源文件
+    Channels   int    // 声道数，1 为单声道，0 表示保持源文件
+}
+
+// custom 是否设置了编码参数（未设置时 MP3 源文件直接复制音频流）
+func (e SegmentEncoding) custom() bool {
+    return e != SegmentEncoding{}
+}
+
+// ffmpegArgs 转码为 MP3 的 FFmpeg 编码参数
+func (e SegmentEncoding) ffmpegArgs() []string {
+    bitrate := e.Bitrate
+    if bitrate == "" {
+	bitrate = defaultSegmentBitrate
+    }
+    args := []string{"-acodec", "libmp3lame", "-ab", bitrate}
+    if e.SampleRate > 0 {
+	args = append(args, "-ar", strconv.Itoa(e.SampleRate))
+    }
+    if e.Channels > 0 {
+	args = append(args, "-ac", strconv.Itoa(e.Channels))
+    }
+    return args
+}
+
+// NewAudioSplitter 创建分片器（overlapSeconds 为 0 表示不重叠，不能超过片段时长）
+func NewAudioSplitter(segmentDuration, overlapSeconds int, encoding SegmentEncoding) *AudioSplitter {
     if segmentDuration <= 0 {
 	segmentDuration = 600 // 默认 10 分钟
     }
+    if overlapSeconds < 0 || overlapSeconds >= segmentDuration {
+	overlapSeconds = 0
+    }
     return &AudioSplitter{
 	segmentDuration: segmentDuration,
+	overlap:         overlapSeconds,
+	encoding:        encoding,
     }
 }
 
@@ -32,7 +71,7 @@ func NewAudioSplitter(segmentDuration int) *AudioSplitter {
 // 面试亮点：处理大文件，优化并发转换
 func (as *AudioSplitter) Split(audioPath string) ([]models.Segment, error) {
     // 1. 获取音频时长
-    duration, err := as.getAudioDuration(audioPath)
+    duration, err := as.Duration(audioPath)
     if err != nil {
 	return nil, fmt.Errorf("获取音频时长失败: %v", err)
     }
@@ -54,7 +93,7 @@ func (as *AudioSplitter) Split(audioPath string) ([]models.Segment, error) {
 	}, nil
     }
 
-    log.Printf("✂️  音频将被切分为 %d 个片段 (每片 %d 秒)", segmentCount, as.segmentDuration)
+    log.Printf("✂️  音频将被切分为 %d 个片段 (每片 %d 秒, 重叠 %d 秒)", segmentCount, as.segmentDuration, as.overlap)
 
     // 3. 创建临时目录存放片段
     // BUG FIX: 为每个音频文件创建独立的 segments 子目录，避免并发任务时文件名冲突
@@ -65,7 +104,8 @@ func (as *AudioSplitter) Split(audioPath string) ([]models.Segment, error) {
 	return nil, fmt.Errorf("创建片段目录失败: %v", err)
     }
 
-    // 4. 切分音频
+    // 4. 切分音频（能否直接复制音频流对所有片段都一样，只判断一次）
+    copyCodec := as.canCopy(audioPath)
     segments := make([]models.Segment, 0, segmentCount)
     for i := 0; i < segmentCount; i++ {
 	start := float64(i * as.segmentDuration)
@@ -74,13 +114,20 @@ func (as *AudioSplitter) Split(audioPath string) ([]models.Segment, error) {
 	    end = duration
 	}
 
+	// 第二个片段起从上一片段结束前 overlap 秒开始
+	overlap := 0.0
+	if i > 0 {
+	    overlap = float64(as.overlap)
+	}
+	start -= overlap
+
 	// 片段文件名
 	segmentPath := filepath.Join(segmentsDir, fmt.Sprintf("segment_%03d.mp3", i))
 
 	// 使用 FFmpeg 切分
 	log.Printf("  ✂️  正在切分片段 %d/%d: %.2f秒 -> %.2f秒 (时长: %.2f秒)",
 	    i+1, segmentCount, start, end, end-start)
-	if err := as.extractSegment(audioPath, segmentPath, start, float64(as.segmentDuration)); err != nil {
+	if err := as.extractSegment(audioPath, segmentPath, start, float64(as.segmentDuration)+overlap, copyCodec); err != nil {
 	    return nil, fmt.Errorf("切分片段 %d 失败: %v", i, err)
 	}
 
@@ -89,14 +136,21 @@ func (as *AudioSplitter) Split(audioPath string) ([]models.Segment, error) {
 	    FilePath: segmentPath,
 	    Start:    start,
 	    End:      end,
+	    Overlap:  overlap,
 	})
     }
 
     return segments, nil
 }
 
-// getAudioDuration 获取音频/视频文件时长（秒）
-func (as *AudioSplitter) getAudioDuration(audioPath string) (float64, error) {
+// Duration 获取音频/视频文件时长（秒）
+func (as *AudioSplitter) Duration(audioPath string) (float64, error) {
+    return ProbeDuration(audioPath)
+}
+
+// ProbeDuration 使用 ffprobe 获取音频/视频文件时长（秒）
+// ffprobe 无法解析的文件（损坏或不是媒体文件）返回错误，上传时据此提前拒绝
+func ProbeDuration(audioPath string) (float64, error) {
     // 使用 FFprobe 获取时长
     // ffprobe -v error -show_entries format=duration -of default=noprint_wrappers=1:nokey=1 input.mp3
     cmd := exec.Command("ffprobe",
@@ -128,29 +182,24 @@ func (as *AudioSplitter) getAudioDuration(audioPath string) (float64, error) {
     return duration, nil
 }
 
-// extractSegment 从音频/视频中提取片段
-func (as *AudioSplitter) extractSegment(inputPath, outputPath string, startTime, duration float64) error {
-    // 判断输入文件类型
-    ext := strings.ToLower(filepath.Ext(inputPath))
-    isVideo := (ext == ".mp4" || ext == ".webm" || ext == ".avi" || ext == ".mov")
-
+// extractSegment 从音频/视频中提取片段（copyCodec 为 true 时直接复制音频流，否则按编码参数转码为 MP3）
+func (as *AudioSplitter) extractSegment(inputPath, outputPath string, startTime, duration float64, copyCodec bool) error {
     var cmd *exec.Cmd
 
-    if isVideo {
-	// 视频文件：提取音频并转码为 MP3
-	// ffmpeg -i video.mp4 -ss 0 -t 300 -vn -acodec libmp3lame -ab 128k -y output.mp3
-	cmd = exec.Command("ffmpeg",
+    if !copyCodec {
+	// 视频文件、非 MP3 编码的音频（如浏览器录音的 webm/opus），或 MP3 与设置的编码参数不同：提取音频并转码为 MP3
+	// ffmpeg -i video.mp4 -ss 0 -t 300 -vn -acodec libmp3lame -ab 128k [-ar 16000 -ac 1] -y output.mp3
+	args := []string{
 	    "-i", inputPath,
 	    "-ss", fmt.Sprintf("%.2f", startTime),
 	    "-t", fmt.Sprintf("%.2f", duration),
-	    "-vn",              // 禁用视频流
-	    "-acodec", "libmp3lame", // 转码为 MP3
-	    "-ab", "128k",      // 音频比特率 128kbps
-	    "-y",
-	    outputPath,
-	    )
+	    "-vn", // 禁用视频流
+	}
+	args = append(args, as.encoding.ffmpegArgs()...)
+	args = append(args, "-y", outputPath)
+	cmd = exec.Command("ffmpeg", args...)
     } else {
-	// 纯音频文件：直接复制（快速，不重新编码）
+	// MP3 音频：直接复制（快速，不重新编码）
 	// ffmpeg -i input.mp3 -ss 0 -t 300 -acodec copy -y output.mp3
 	cmd = exec.Command("ffmpeg",
 	    "-i", inputPath,
@@ -173,6 +222,117 @@ func (as *AudioSplitter) extractSegment(inputPath, outputPath string, startTime,
     return nil
 }
 
+// copyCodecFormats 音频流可以直接复制进 MP3 片段的格式，其余格式（视频容器、webm/opus、aac、flac、wav 等）
+// 使用 -acodec copy 会失败或生成无法播放的片段，必须转码
+var copyCodecFormats = map[string]bool{
+    ".mp3":  true,
+    ".mpga": true,
+}
+
+// canCopyCodec 根据扩展名判断切片时能否直接复制音频流（不区分大小写）
+func canCopyCodec(inputPath string) bool {
+    return copyCodecFormats[strings.ToLower(filepath.Ext(inputPath))]
+}
+
+// canCopy 判断切片时能否直接复制音频流：MP3 文件在未设置编码参数，
+// 或源文件的比特率、采样率、声道数与设置一致时直接复制，否则转码（无法读取源文件参数时也转码）
+func (as *AudioSplitter) canCopy(inputPath string) bool {
+    if !canCopyCodec(inputPath) {
+	return false
+    }
+    if !as.encoding.custom() {
+	return true
+    }
+
+    stream, err := probeAudioStream(inputPath)
+    if err != nil {
+	log.Printf("⚠️ 读取音频参数失败，按编码参数转码: %v", err)
+	return false
+    }
+    return stream.matches(as.encoding)
+}
+
+// audioStream ffprobe 读取的音频流参数
+type audioStream struct {
+    codec      string
+    bitrate    int // bit/s，VBR 文件为平均值
+    sampleRate int
+    channels   int
+}
+
+// matches 音频流是否已经是 enc 指定的 MP3 编码（未设置的参数不比较）
+func (s audioStream) matches(enc SegmentEncoding) bool {
+    if s.codec != "mp3" {
+	return false
+    }
+    if enc.SampleRate > 0 && s.sampleRate != enc.SampleRate {
+	return false
+    }
+    if enc.Channels > 0 && s.channels != enc.Channels {
+	return false
+    }
+    bitrate := enc.Bitrate
+    if bitrate == "" {
+	bitrate = defaultSegmentBitrate
+    }
+    return s.bitrate == parseBitrate(bitrate)
+}
+
+// parseBitrate 解析 FFmpeg 比特率（如 "64k" → 64000），无法解析时返回 0
+func parseBitrate(value string) int {
+    multiplier := 1
+    if trimmed, ok := strings.CutSuffix(strings.ToLower(value), "k"); ok {
+	value, multiplier = trimmed, 1000
+    }
+    n, err := strconv.Atoi(value)
+    if err != nil {
+	return 0
+    }
+    return n * multiplier
+}
+
+// probeAudioStream 使用 ffprobe 读取第一条音频流的编码、比特率、采样率和声道数
+func probeAudioStream(audioPath string) (audioStream, error) {
+    // ffprobe -v error -select_streams a:0 -show_entries stream=codec_name,bit_rate,sample_rate,channels -of default=noprint_wrappers=1 input.mp3
+    cmd := exec.Command("ffprobe",
+	"-v", "error",
+	"-select_streams", "a:0",
+	"-show_entries", "stream=codec_name,bit_rate,sample_rate,channels",
+	"-of", "default=noprint_wrappers=1",
+	audioPath,
+	)
+
+    var stdout, stderr bytes.Buffer
+    cmd.Stdout = &stdout
+    cmd.Stderr = &stderr
+
+    if err := cmd.Run(); err != nil {
+	return audioStream{}, fmt.Errorf("ffprobe 执行失败: %v (stderr: %s)", err, stderr.String())
+    }
+
+    var stream audioStream
+    for _, line := range strings.Split(stdout.String(), "\n") {
+	key, value, ok := strings.Cut(strings.TrimSpace(line), "=")
+	if !ok {
+	    continue
+	}
+	switch key {
+	case "codec_name":
+	    stream.codec = value
+	case "bit_rate":
+	    stream.bitrate, _ = strconv.Atoi(value)
+	case "sample_rate":
+	    stream.sampleRate, _ = strconv.Atoi(value)
+	case "channels":
+	    stream.channels, _ = strconv.Atoi(value)
+	}
+    }
+    if stream.codec == "" {
+	return audioStream{}, fmt.Errorf("没有音频流: %s", audioPath)
+    }
+    return stream, nil
+}
+
 // Cleanup 清理临时片段文件
 func (as *AudioSplitter) Cleanup(segments []models.Segment) error {
     if len(segments) > 0 {
@@ -0,0 +1,103 @@
+package transcriber
+
+import (
+	"context"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+func TestCanCopyCodec(t *testing.T) {
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{"talk.mp3", true},
+		{"TALK.MP3", true},
+		{"talk.mpga", true},
+		{"voice.ogg", false},
+		{"voice.opus", false},
+		{"recording.webm", false},
+		{"RECORDING.WEBM", false},
+		{"talk.m4a", false},
+		{"talk.aac", false},
+		{"talk.flac", false},
+		{"talk.wav", false},
+		{"talk.mp4", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			if got := canCopyCodec(tt.path); got != tt.want {
+				t.Fatalf("canCopyCodec(%q) = %v，期望 %v", tt.path, got, tt.want)
+			}
+		})
+	}
+}
+
+// makeAudio 用 ffmpeg 生成 seconds 秒的测试音频（编码由 args 指定），未安装 ffmpeg 或编码器不可用时跳过测试
+func makeAudio(t *testing.T, name string, seconds string, args ...string) string {
+	t.Helper()
+	if _, err := exec.LookPath("ffmpeg"); err != nil {
+		t.Skip("未安装 ffmpeg，跳过测试")
+	}
+	path := filepath.Join(t.TempDir(), name)
+	cmdArgs := append([]string{"-f", "lavfi", "-i", "sine=frequency=440:duration=" + seconds}, args...)
+	cmdArgs = append(cmdArgs, "-y", path)
+	if out, err := exec.Command("ffmpeg", cmdArgs...).CombinedOutput(); err != nil {
+		t.Skipf("生成测试音频失败（编码器不可用？）: %v\n%s", err, out)
+	}
+	return path
+}
+
+// TestSplitTranscodes Whisper 不支持或不能直接复制音频流的格式，经过转码后切分出的片段都是 MP3
+func TestSplitTranscodes(t *testing.T) {
+	tests := []struct {
+		name string
+		file string
+		args []string
+	}{
+		{"ogg/vorbis", "voice.ogg", []string{"-c:a", "libvorbis"}},
+		{"ogg/opus", "voice.ogg", []string{"-c:a", "libopus"}},
+		{"opus", "voice.opus", []string{"-c:a", "libopus"}},
+		{"webm/opus（浏览器录音）", "recording.webm", []string{"-c:a", "libopus"}},
+		{"webm/vorbis", "recording.webm", []string{"-c:a", "libvorbis"}},
+		{"m4a/aac", "talk.m4a", []string{"-c:a", "aac"}},
+		{"wav", "talk.wav", []string{"-c:a", "pcm_s16le"}},
+		{"flac", "talk.flac", []string{"-c:a", "flac"}},
+		{"mp3", "talk.mp3", []string{"-c:a", "libmp3lame"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// 2.5 秒按 1 秒切分为 3 个片段（避免末尾出现空片段）
+			path := makeAudio(t, tt.file, "2.5", tt.args...)
+
+			input, cleanup, err := prepareAudio(context.Background(), path)
+			if err != nil {
+				t.Fatalf("prepareAudio: %v", err)
+			}
+			defer cleanup()
+			if needsTranscode(path) == (input == path) {
+				t.Fatalf("prepareAudio(%s) = %s，转码判断错误", tt.file, input)
+			}
+
+			splitter := NewAudioSplitter(1, 0, SegmentEncoding{})
+			segments, err := splitter.Split(input)
+			if err != nil {
+				t.Fatalf("Split: %v", err)
+			}
+			defer splitter.Cleanup(segments)
+			if len(segments) != 3 {
+				t.Fatalf("切分出 %d 个片段，期望 3 个", len(segments))
+			}
+			for _, segment := range segments {
+				stream, err := probeAudioStream(segment.FilePath)
+				if err != nil {
+					t.Fatalf("读取片段 %d: %v", segment.Index, err)
+				}
+				if stream.codec != "mp3" {
+					t.Fatalf("片段 %d 的编码为 %s，期望 mp3", segment.Index, stream.codec)
+				}
+			}
+		})
+	}
+}
@@ -19,7 +19,13 @@ type SegmentResult struct {
 // segments: 音频片段信息（包含时间偏移）
 // responses: 对应的 Whisper 响应（包含时间戳）
 // outputPath: 输出文件路径
-func GenerateSRT(segmentResults []SegmentResult, outputPath string) error {
+// maxLineChars: 每行最大宽度，超长字幕自动折行或拆分（<= 0 表示不折行）
+func GenerateSRT(segmentResults []SegmentResult, outputPath string, maxLineChars int) error {
+	return WriteSRT(WrapCues(BuildCues(segmentResults), maxLineChars), outputPath)
+}
+
+// WriteSRT 将字幕列表写入 SRT 文件
+func WriteSRT(cues []Cue, outputPath string) error {
 	// 创建输出目录
 	dir := filepath.Dir(outputPath)
 	if err := os.MkdirAll(dir, 0755); err != nil {
@@ -35,40 +41,20 @@ func GenerateSRT(segmentResults []SegmentResult, outputPath string) error {
 
 	// 生成 SRT 内容
 	var builder strings.Builder
-	subtitleIndex := 1
-
-	for _, sr := range segmentResults {
-		if sr.Response == nil || len(sr.Response.Segments) == 0 {
-			continue
-		}
-
-		// 遍历每个 Whisper 片段
-		for _, whisperSeg := range sr.Response.Segments {
-			// 计算实际时间（加上音频片段的起始偏移）
-			actualStart := sr.Segment.Start + whisperSeg.Start
-			actualEnd := sr.Segment.Start + whisperSeg.End
-
-			// 格式化 SRT 时间戳
-			startTime := formatSRTTime(actualStart)
-			endTime := formatSRTTime(actualEnd)
-
-			// 清理文本（去除首尾空格）
-			text := strings.TrimSpace(whisperSeg.Text)
-			if text == "" {
-				continue
-			}
-
-			// 写入 SRT 格式
-			// 1
-			// 00:00:00,000 --> 00:00:05,200
-			// 字幕文本
-			//
-			builder.WriteString(fmt.Sprintf("%d\n", subtitleIndex))
-			builder.WriteString(fmt.Sprintf("%s --> %s\n", startTime, endTime))
-			builder.WriteString(fmt.Sprintf("%s\n\n", text))
 
-			subtitleIndex++
-		}
+	for i, cue := range cues {
+		// 格式化 SRT 时间戳
+		startTime := formatSRTTime(cue.Start)
+		endTime := formatSRTTime(cue.End)
+
+		// 写入 SRT 格式
+		// 1
+		// 00:00:00,000 --> 00:00:05,200
+		// 字幕文本
+		//
+		builder.WriteString(fmt.Sprintf("%d\n", i+1))
+		builder.WriteString(fmt.Sprintf("%s --> %s\n", startTime, endTime))
+		builder.WriteString(fmt.Sprintf("%s\n\n", cue.Text))
 	}
 
 	// 写入文件
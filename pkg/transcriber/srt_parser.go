@@ -0,0 +1,86 @@
+package transcriber
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// ParseSRT 读取 SRT 字幕文件，返回字幕列表（用于对已完成任务做二次处理，如翻译）
+func ParseSRT(path string) ([]Cue, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("读取 SRT 文件失败: %w", err)
+	}
+	return ParseSRTContent(data)
+}
+
+// ParseSRTContent 解析 SRT 字幕内容（字幕保存在对象存储时使用）
+func ParseSRTContent(data []byte) ([]Cue, error) {
+	content := strings.ReplaceAll(string(data), "\r\n", "\n")
+	content = strings.TrimPrefix(content, "\ufeff") // 去掉 UTF-8 BOM
+
+	cues := make([]Cue, 0)
+	for _, block := range strings.Split(content, "\n\n") {
+		lines := strings.Split(strings.TrimSpace(block), "\n")
+		if len(lines) < 2 {
+			continue
+		}
+
+		// 序号行可省略，找到时间行
+		timeLine := 0
+		if !strings.Contains(lines[0], "-->") {
+			timeLine = 1
+		}
+		if timeLine >= len(lines) {
+			continue
+		}
+
+		parts := strings.Split(lines[timeLine], "-->")
+		if len(parts) != 2 {
+			continue
+		}
+		start, err := parseSRTTime(parts[0])
+		if err != nil {
+			return nil, fmt.Errorf("解析时间失败 %q: %w", lines[timeLine], err)
+		}
+		end, err := parseSRTTime(parts[1])
+		if err != nil {
+			return nil, fmt.Errorf("解析时间失败 %q: %w", lines[timeLine], err)
+		}
+
+		cues = append(cues, Cue{
+			Start: start,
+			End:   end,
+			Text:  strings.Join(lines[timeLine+1:], "\n"),
+		})
+	}
+
+	return cues, nil
+}
+
+// parseSRTTime 解析 SRT 时间格式，例如 00:01:05,500 -> 65.5
+func parseSRTTime(value string) (float64, error) {
+	value = strings.TrimSpace(value)
+	value = strings.Replace(value, ",", ".", 1)
+
+	parts := strings.Split(value, ":")
+	if len(parts) != 3 {
+		return 0, fmt.Errorf("格式错误")
+	}
+	hours, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return 0, err
+	}
+	minutes, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return 0, err
+	}
+	seconds, err := strconv.ParseFloat(parts[2], 64)
+	if err != nil {
+		return 0, err
+	}
+
+	return float64(hours*3600+minutes*60) + seconds, nil
+}
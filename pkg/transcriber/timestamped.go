@@ -0,0 +1,21 @@
+package transcriber
+
+import (
+	"fmt"
+	"strings"
+)
+
+// FormatTimestampedTranscript 生成带时间戳的纯文本稿，每条字幕一行（字幕内的换行合并为空格）
+// 例如: [00:01:23] Never drink liquid nitrogen.
+func FormatTimestampedTranscript(cues []Cue) string {
+	var builder strings.Builder
+	for _, cue := range cues {
+		text := strings.Join(strings.Fields(cue.Text), " ")
+		if text == "" {
+			continue
+		}
+		seconds := int(cue.Start)
+		builder.WriteString(fmt.Sprintf("[%02d:%02d:%02d] %s\n", seconds/3600, seconds%3600/60, seconds%60, text))
+	}
+	return builder.String()
+}
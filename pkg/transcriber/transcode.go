@@ -0,0 +1,72 @@
+package transcriber
+
+import (
+    "bytes"
+    "context"
+    "fmt"
+    "os"
+    "os/exec"
+    "path/filepath"
+    "strings"
+
+    "github.com/z-wentao/voiceflow/pkg/logging"
+)
+
+// transcodeFormats Whisper 不接受、但 ffmpeg 可以解码的格式（如 Telegram / WhatsApp 的语音消息），
+// 分片前先转码为 MP3
+var transcodeFormats = map[string]bool{
+    ".ogg":  true,
+    ".opus": true,
+}
+
+// needsTranscode 根据扩展名判断文件是否需要先转码（不区分大小写）
+func needsTranscode(audioPath string) bool {
+    return transcodeFormats[strings.ToLower(filepath.Ext(audioPath))]
+}
+
+// prepareAudio 分片前的预处理：需要转码的文件在原文件旁边生成 MP3，返回实际用于分片的路径；
+// cleanup 删除转码生成的文件（不需要转码时为空操作，原始文件不受影响）。ctx 取消时终止 ffmpeg
+func prepareAudio(ctx context.Context, audioPath string) (path string, cleanup func(), err error) {
+    if !needsTranscode(audioPath) {
+	return audioPath, func() {}, nil
+    }
+
+    logger := logging.FromContext(ctx)
+    converted := strings.TrimSuffix(audioPath, filepath.Ext(audioPath)) + ".transcoded.mp3"
+    logger.Info("🔄 格式 Whisper 不支持，转码为 MP3", "format", filepath.Ext(audioPath), "output", filepath.Base(converted))
+    if err := transcodeToMP3(ctx, audioPath, converted); err != nil {
+	os.Remove(converted)
+	return "", nil, err
+    }
+
+    cleanup = func() {
+	if err := os.Remove(converted); err != nil && !os.IsNotExist(err) {
+	    logger.Warn("⚠️ 删除转码文件失败", "error", err)
+	}
+    }
+    return converted, cleanup, nil
+}
+
+// transcodeToMP3 使用 FFmpeg 将音频转码为 MP3（忽略视频流），ctx 取消时终止 ffmpeg
+func transcodeToMP3(ctx context.Context, inputPath, outputPath string) error {
+    // ffmpeg -i voice.ogg -vn -acodec libmp3lame -ab 128k -y voice.transcoded.mp3
+    cmd := exec.CommandContext(ctx, "ffmpeg",
+	"-i", inputPath,
+	"-vn",
+	"-acodec", "libmp3lame",
+	"-ab", "128k",
+	"-y",
+	outputPath,
+	)
+
+    var stderr bytes.Buffer
+    cmd.Stderr = &stderr
+
+    if err := cmd.Run(); err != nil {
+	if ctx.Err() != nil {
+	    return fmt.Errorf("转码已取消: %w", ctx.Err())
+	}
+	return fmt.Errorf("转码失败: ffmpeg 执行失败: %v (stderr: %s)", err, stderr.String())
+    }
+    return nil
+}
@@ -0,0 +1,71 @@
+package transcriber
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestNeedsTranscode(t *testing.T) {
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{"uploads/voice.ogg", true},
+		{"uploads/voice.opus", true},
+		{"uploads/VOICE.OGG", true},
+		{"uploads/voice.Opus", true},
+		{"uploads/talk.mp3", false},
+		{"uploads/talk.m4a", false},
+		{"uploads/talk.wav", false},
+		{"uploads/talk.webm", false},
+		{"uploads/talk.mp4", false},
+		{"uploads/voice.ogg.mp3", false},
+		{"uploads/voice", false},
+		{"uploads.ogg/voice.mp3", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			if got := needsTranscode(tt.path); got != tt.want {
+				t.Fatalf("needsTranscode(%q) = %v，期望 %v", tt.path, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPrepareAudioPassThrough(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "talk.mp3")
+	os.WriteFile(path, []byte("ID3"), 0644)
+
+	input, cleanup, err := prepareAudio(context.Background(), path)
+	if err != nil {
+		t.Fatalf("prepareAudio: %v", err)
+	}
+	cleanup()
+	if input != path {
+		t.Fatalf("不需要转码时应直接使用原文件，得到 %s", input)
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Fatalf("cleanup 不应删除原文件: %v", err)
+	}
+}
+
+func TestPrepareAudioCanceled(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "voice.ogg")
+	os.WriteFile(path, []byte("OggS"), 0644)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, _, err := prepareAudio(ctx, path); err == nil || !strings.Contains(err.Error(), "转码已取消") {
+		t.Fatalf("err = %v，期望转码已取消", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "voice.transcoded.mp3")); !os.IsNotExist(err) {
+		t.Fatal("转码失败时应删除输出文件")
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Fatalf("原文件应保留: %v", err)
+	}
+}
@@ -0,0 +1,119 @@
+package transcriber
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// translateBatchSize 每次请求翻译的字幕条数（兼顾上下文连贯和单次响应长度）
+const translateBatchSize = 50
+
+// Translator 字幕翻译器（调用 OpenAI Chat 接口）
+type Translator struct {
+	client     *openai.Client
+	targetLang string
+}
+
+// TranslateResult 翻译结果
+type TranslateResult struct {
+	Lines []string // 与输入字幕一一对应的译文
+
+	PromptTokens     int // 输入 token 数（用于估算费用）
+	CompletionTokens int // 输出 token 数
+}
+
+// NewTranslator 创建字幕翻译器
+// client 与单词提取器共用，targetLang 为目标语言（如 "简体中文"）
+func NewTranslator(client *openai.Client, targetLang string) *Translator {
+	return &Translator{
+		client:     client,
+		targetLang: targetLang,
+	}
+}
+
+// TargetLanguage 目标语言
+func (t *Translator) TargetLanguage() string {
+	return t.targetLang
+}
+
+// TranslateCues 逐条翻译字幕，分批请求
+func (t *Translator) TranslateCues(ctx context.Context, cues []Cue) (*TranslateResult, error) {
+	result := &TranslateResult{
+		Lines: make([]string, 0, len(cues)),
+	}
+
+	for start := 0; start < len(cues); start += translateBatchSize {
+		end := min(start+translateBatchSize, len(cues))
+
+		texts := make([]string, 0, end-start)
+		for _, cue := range cues[start:end] {
+			texts = append(texts, cue.Text)
+		}
+
+		lines, resp, err := t.translateBatch(ctx, texts)
+		if resp != nil {
+			// 已经产生的调用费用即使后续失败也要统计
+			result.PromptTokens += resp.Usage.PromptTokens
+			result.CompletionTokens += resp.Usage.CompletionTokens
+		}
+		if err != nil {
+			return result, fmt.Errorf("翻译第 %d-%d 条字幕失败: %w", start+1, end, err)
+		}
+		result.Lines = append(result.Lines, lines...)
+
+		log.Printf("🌐 已翻译字幕 %d/%d", end, len(cues))
+	}
+
+	return result, nil
+}
+
+// translateBatch 翻译一批字幕文本
+func (t *Translator) translateBatch(ctx context.Context, texts []string) ([]string, *openai.ChatCompletionResponse, error) {
+	input, err := json.Marshal(texts)
+	if err != nil {
+		return nil, nil, fmt.Errorf("序列化字幕失败: %w", err)
+	}
+
+	resp, err := t.client.CreateChatCompletion(ctx, openai.ChatCompletionRequest{
+		Model: openai.GPT4oMini,
+		Messages: []openai.ChatCompletionMessage{
+			{
+				Role: openai.ChatMessageRoleSystem,
+				Content: fmt.Sprintf("你是一个专业的字幕翻译。把用户给出的 JSON 数组中的每一条字幕翻译成%s，"+
+					"译文简洁自然，结合上下文理解但不要合并或拆分条目。"+
+					`只返回 JSON 对象 {"translations": [...]}，数组长度必须与输入相同且顺序一致。`, t.targetLang),
+			},
+			{
+				Role:    openai.ChatMessageRoleUser,
+				Content: string(input),
+			},
+		},
+		Temperature: 0.3,
+		ResponseFormat: &openai.ChatCompletionResponseFormat{
+			Type: openai.ChatCompletionResponseFormatTypeJSONObject,
+		},
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("调用 OpenAI API 失败: %w", err)
+	}
+	if len(resp.Choices) == 0 {
+		return nil, &resp, fmt.Errorf("OpenAI API 未返回结果")
+	}
+
+	var parsed struct {
+		Translations []string `json:"translations"`
+	}
+	content := resp.Choices[0].Message.Content
+	if err := json.Unmarshal([]byte(content), &parsed); err != nil {
+		return nil, &resp, fmt.Errorf("解析 AI 响应失败: %w, 原始响应: %s", err, content)
+	}
+	if len(parsed.Translations) != len(texts) {
+		return nil, &resp, fmt.Errorf("译文条数不匹配: 期望 %d，实际 %d", len(texts), len(parsed.Translations))
+	}
+
+	return parsed.Translations, &resp, nil
+}
@@ -8,7 +8,14 @@ import (
 )
 
 // GenerateVTT 生成 WebVTT 字幕文件（用于 HTML5 video 播放）
-func GenerateVTT(segmentResults []SegmentResult, outputPath string) error {
+// 超过 maxLineChars 的字幕自动折行或拆分（<= 0 表示不折行）
+func GenerateVTT(segmentResults []SegmentResult, outputPath string, maxLineChars int) error {
+	return WriteVTT(WrapCues(BuildCues(segmentResults), maxLineChars), outputPath)
+}
+
+// WriteVTT 将字幕列表写入 WebVTT 文件
+// 字幕带有单词级时间戳时，生成逐词高亮的 cue 文本
+func WriteVTT(cues []Cue, outputPath string) error {
 	// 创建输出目录
 	dir := filepath.Dir(outputPath)
 	if err := os.MkdirAll(dir, 0755); err != nil {
@@ -28,36 +35,20 @@ func GenerateVTT(segmentResults []SegmentResult, outputPath string) error {
 	// VTT 文件必须以 "WEBVTT" 开头
 	builder.WriteString("WEBVTT\n\n")
 
-	subtitleIndex := 1
+	for i, cue := range cues {
+		// 格式化 VTT 时间戳
+		startTime := formatVTTTime(cue.Start)
+		endTime := formatVTTTime(cue.End)
 
-	for _, sr := range segmentResults {
-		if sr.Response == nil || len(sr.Response.Segments) == 0 {
-			continue
+		text := cue.Text
+		if len(cue.Words) > 0 {
+			text = karaokeText(cue.Words, cue.Start)
 		}
 
-		// 遍历每个 Whisper 片段
-		for _, whisperSeg := range sr.Response.Segments {
-			// 计算实际时间（加上音频片段的起始偏移）
-			actualStart := sr.Segment.Start + whisperSeg.Start
-			actualEnd := sr.Segment.Start + whisperSeg.End
-
-			// 格式化 VTT 时间戳
-			startTime := formatVTTTime(actualStart)
-			endTime := formatVTTTime(actualEnd)
-
-			// 清理文本（去除首尾空格）
-			text := strings.TrimSpace(whisperSeg.Text)
-			if text == "" {
-				continue
-			}
-
-			// 写入 VTT 格式
-			builder.WriteString(fmt.Sprintf("%d\n", subtitleIndex))
-			builder.WriteString(fmt.Sprintf("%s --> %s\n", startTime, endTime))
-			builder.WriteString(fmt.Sprintf("%s\n\n", text))
-
-			subtitleIndex++
-		}
+		// 写入 VTT 格式
+		builder.WriteString(fmt.Sprintf("%d\n", i+1))
+		builder.WriteString(fmt.Sprintf("%s --> %s\n", startTime, endTime))
+		builder.WriteString(fmt.Sprintf("%s\n\n", text))
 	}
 
 	// 写入文件
@@ -68,6 +59,27 @@ func GenerateVTT(segmentResults []SegmentResult, outputPath string) error {
 	return nil
 }
 
+// karaokeText 生成带 cue 内部时间标签的文本（WebVTT 卡拉OK 格式）
+// 例如: <c>Never</c> <00:00:01.000><c>drink</c> <00:00:01.500><c>liquid</c>
+func karaokeText(words []WhisperWord, cueStart float64) string {
+	var builder strings.Builder
+	for _, w := range words {
+		word := strings.TrimSpace(w.Word)
+		if word == "" {
+			continue
+		}
+		if builder.Len() > 0 {
+			builder.WriteString(" ")
+			// 时间标签必须严格晚于 cue 开始时间，第一个单词无需标签
+			if w.Start > cueStart {
+				builder.WriteString(fmt.Sprintf("<%s>", formatVTTTime(w.Start)))
+			}
+		}
+		builder.WriteString(fmt.Sprintf("<c>%s</c>", word))
+	}
+	return builder.String()
+}
+
 // formatVTTTime 将秒数格式化为 VTT 时间格式
 // 例如: 65.5 -> 00:01:05.500
 // VTT 使用点号(.)而不是逗号(,)
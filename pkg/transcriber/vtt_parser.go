@@ -0,0 +1,102 @@
+package transcriber
+
+import (
+	"fmt"
+	"html"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// vttTagPattern cue 文本中的标签，如 <c>、</c>、<v Speaker>、卡拉OK 时间标签 <00:00:01.500>
+var vttTagPattern = regexp.MustCompile(`<[^>]*>`)
+
+// ParseVTTContent 解析 WebVTT 字幕内容，返回字幕列表（与前端 index.html 中的 parseVTT 对应）
+// 跳过文件头和 NOTE / STYLE / REGION 块，去掉 cue 文本中的标签（逐词高亮生成的时间标签等），只保留纯文本
+func ParseVTTContent(data []byte) ([]Cue, error) {
+	content := strings.ReplaceAll(string(data), "\r\n", "\n")
+	content = strings.TrimPrefix(content, "\ufeff") // 去掉 UTF-8 BOM
+
+	if !strings.HasPrefix(content, "WEBVTT") {
+		return nil, fmt.Errorf("不是有效的 WebVTT 文件: 缺少 WEBVTT 文件头")
+	}
+
+	cues := make([]Cue, 0)
+	for i, block := range strings.Split(content, "\n\n") {
+		lines := strings.Split(strings.Trim(block, "\n"), "\n")
+		if i == 0 || len(lines) == 0 {
+			continue // 文件头
+		}
+		if isVTTMetadataBlock(lines[0]) {
+			continue
+		}
+
+		// cue 标识行可省略，找到时间行
+		timeLine := 0
+		if !strings.Contains(lines[0], "-->") {
+			timeLine = 1
+		}
+		if timeLine >= len(lines) || !strings.Contains(lines[timeLine], "-->") {
+			continue
+		}
+
+		// 时间行: 00:01:05.500 --> 00:01:08.000 align:start（结束时间后可带 cue 设置）
+		parts := strings.SplitN(lines[timeLine], "-->", 2)
+		endFields := strings.Fields(parts[1])
+		if len(endFields) == 0 {
+			return nil, fmt.Errorf("解析时间失败 %q: 缺少结束时间", lines[timeLine])
+		}
+		start, err := parseVTTTime(parts[0])
+		if err != nil {
+			return nil, fmt.Errorf("解析时间失败 %q: %w", lines[timeLine], err)
+		}
+		end, err := parseVTTTime(endFields[0])
+		if err != nil {
+			return nil, fmt.Errorf("解析时间失败 %q: %w", lines[timeLine], err)
+		}
+
+		text := vttTagPattern.ReplaceAllString(strings.Join(lines[timeLine+1:], "\n"), "")
+		cues = append(cues, Cue{
+			Start: start,
+			End:   end,
+			Text:  strings.TrimSpace(html.UnescapeString(text)),
+		})
+	}
+
+	return cues, nil
+}
+
+// isVTTMetadataBlock 判断是否为注释、样式或区域定义块（不是字幕）
+func isVTTMetadataBlock(firstLine string) bool {
+	for _, keyword := range []string{"NOTE", "STYLE", "REGION"} {
+		if firstLine == keyword || strings.HasPrefix(firstLine, keyword+" ") || strings.HasPrefix(firstLine, keyword+"\t") {
+			return true
+		}
+	}
+	return false
+}
+
+// parseVTTTime 解析 WebVTT 时间格式，小时可省略，例如 00:01:05.500 或 01:05.500 -> 65.5
+func parseVTTTime(value string) (float64, error) {
+	parts := strings.Split(strings.TrimSpace(value), ":")
+	if len(parts) == 2 {
+		parts = append([]string{"0"}, parts...)
+	}
+	if len(parts) != 3 {
+		return 0, fmt.Errorf("格式错误")
+	}
+	hours, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return 0, err
+	}
+	minutes, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return 0, err
+	}
+	seconds, err := strconv.ParseFloat(parts[2], 64)
+	if err != nil {
+		return 0, err
+	}
+
+	return float64(hours*3600+minutes*60) + seconds, nil
+}
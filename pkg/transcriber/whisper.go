@@ -4,40 +4,105 @@ import (
     "bytes"
     "context"
     "encoding/json"
+    "errors"
     "fmt"
     "io"
     "mime/multipart"
+    "net"
     "net/http"
     "os"
     "path/filepath"
+    "strconv"
+    "strings"
     "time"
 )
 
 const (
-    whisperAPIURL = "https://api.openai.com/v1/audio/transcriptions"
+    // DefaultBaseURL OpenAI API 地址（未配置 base_url 时使用）
+    DefaultBaseURL = "https://api.openai.com/v1"
+
+    // DefaultModel 默认的 Whisper 模型（请求未指定模型时使用）
+    DefaultModel = "whisper-1"
+
+    // DefaultWhisperTimeout HTTP 客户端的默认超时时间（未配置时使用）
+    DefaultWhisperTimeout = 5 * time.Minute
 )
 
+// ErrWhisperTimeout Whisper 请求超时（HTTP 客户端超时或单次请求的截止时间已到）
+// 与任务被取消、API 返回错误等其他失败区分，可用 errors.Is 判断
+var ErrWhisperTimeout = errors.New("Whisper 请求超时")
+
+// APIError Whisper API 返回的非 200 响应
+type APIError struct {
+    StatusCode int
+    Body       string
+}
+
+func (e *APIError) Error() string {
+    return fmt.Sprintf("API 返回错误 (状态码 %d): %s", e.StatusCode, e.Body)
+}
+
+// IsTransient 判断错误是否为临时错误，稍后重试可能成功：
+// API 限流（429）和服务端错误（5xx）、请求超时、网络错误（连接被拒绝、连接中断等）。
+// 文件不存在、格式不支持（4xx）等永久错误返回 false；任务被取消也返回 false
+func IsTransient(err error) bool {
+    if err == nil {
+	return false
+    }
+    var apiErr *APIError
+    if errors.As(err, &apiErr) {
+	return apiErr.StatusCode == http.StatusTooManyRequests || apiErr.StatusCode >= 500
+    }
+    if errors.Is(err, ErrWhisperTimeout) {
+	return true
+    }
+    var netErr net.Error
+    return errors.As(err, &netErr)
+}
+
 // WhisperClient OpenAI Whisper API 客户端
+// 也可以对接 OpenAI 兼容的自建服务（如 whisper.cpp server）
 type WhisperClient struct {
-    apiKey     string
-    httpClient *http.Client
+    apiKey         string
+    apiURL         string // 转录接口地址：<base_url>/audio/transcriptions
+    httpClient     *http.Client
+    wordTimestamps bool // 是否请求单词级时间戳
 }
 
 // NewWhisperClient 创建 Whisper 客户端
-func NewWhisperClient(apiKey string) *WhisperClient {
+// baseURL 为 OpenAI 兼容的 API 地址（如 http://localhost:8000/v1），为空时使用 OpenAI 官方地址
+// timeout 为每个 HTTP 请求的超时上限（包括上传和等待转录结果），<= 0 时使用 DefaultWhisperTimeout
+func NewWhisperClient(apiKey, baseURL string, timeout time.Duration) *WhisperClient {
+    if baseURL == "" {
+	baseURL = DefaultBaseURL
+    }
+    if timeout <= 0 {
+	timeout = DefaultWhisperTimeout
+    }
     return &WhisperClient{
 	apiKey: apiKey,
+	apiURL: strings.TrimRight(baseURL, "/") + "/audio/transcriptions",
 	httpClient: &http.Client{
-	    Timeout: 5 * time.Minute, // 5 分钟超时
+	    Timeout: timeout,
 	},
     }
 }
 
+// isTimeout 判断请求错误是否为超时（截止时间已到或 HTTP 客户端超时）
+func isTimeout(err error) bool {
+    if errors.Is(err, context.DeadlineExceeded) {
+	return true
+    }
+    var netErr net.Error
+    return errors.As(err, &netErr) && netErr.Timeout()
+}
+
 // WhisperResponse API 响应（verbose_json 格式）
 type WhisperResponse struct {
     Text     string           `json:"text"`
     Language string           `json:"language"`
     Segments []WhisperSegment `json:"segments"` // 时间戳片段信息
+    Words    []WhisperWord    `json:"words"`    // 单词级时间戳（仅在请求 word 粒度时返回）
 }
 
 // WhisperSegment Whisper 返回的时间戳片段
@@ -48,9 +113,29 @@ type WhisperSegment struct {
     Text  string  `json:"text"`  // 片段文本
 }
 
+// WhisperWord Whisper 返回的单词级时间戳
+type WhisperWord struct {
+    Word  string  `json:"word"`
+    Start float64 `json:"start"` // 开始时间（秒）
+    End   float64 `json:"end"`   // 结束时间（秒）
+}
+
+// WhisperOptions 单次转录请求的可选参数（零值的字段不发送，使用 API 默认行为）
+type WhisperOptions struct {
+    Language    string  // 音频语言（如 en），为空时自动检测
+    Prompt      string  // 提示词：列出专有名词、术语（如药品名），引导 Whisper 的拼写
+    Temperature float64 // 采样温度（0 到 1），为 0 时不发送
+    Model       string  // 模型名称，为空时使用 DefaultModel
+}
+
+// SetWordTimestamps 设置是否请求单词级时间戳
+func (wc *WhisperClient) SetWordTimestamps(enabled bool) {
+    wc.wordTimestamps = enabled
+}
+
 // Transcribe 转换音频为文字（返回完整响应，包含时间戳）
 // 支持 Context 超时控制（面试亮点）
-func (wc *WhisperClient) Transcribe(ctx context.Context, audioPath string, language string) (*WhisperResponse, error) {
+func (wc *WhisperClient) Transcribe(ctx context.Context, audioPath string, opts WhisperOptions) (*WhisperResponse, error) {
     // 1. 打开音频文件
     file, err := os.Open(audioPath)
     if err != nil {
@@ -72,22 +157,40 @@ func (wc *WhisperClient) Transcribe(ctx context.Context, audioPath string, langu
     }
 
     // 添加模型参数
-    writer.WriteField("model", "whisper-1")
+    model := opts.Model
+    if model == "" {
+	model = DefaultModel
+    }
+    writer.WriteField("model", model)
 
     // 添加语言参数（可选，不指定则自动检测）
-    if language != "" {
-	writer.WriteField("language", language)
+    if opts.Language != "" {
+	writer.WriteField("language", opts.Language)
+    }
+
+    // 提示词和采样温度（可选）
+    if opts.Prompt != "" {
+	writer.WriteField("prompt", opts.Prompt)
+    }
+    if opts.Temperature != 0 {
+	writer.WriteField("temperature", strconv.FormatFloat(opts.Temperature, 'f', -1, 64))
     }
 
     // 添加响应格式（使用 verbose_json 获取时间戳信息）
     writer.WriteField("response_format", "verbose_json")
 
+    // 单词级时间戳（需同时请求 segment 粒度，否则不返回 segments）
+    if wc.wordTimestamps {
+	writer.WriteField("timestamp_granularities[]", "segment")
+	writer.WriteField("timestamp_granularities[]", "word")
+    }
+
     if err := writer.Close(); err != nil {
 	return nil, fmt.Errorf("关闭表单失败: %v", err)
     }
 
     // 3. 创建 HTTP 请求
-    req, err := http.NewRequestWithContext(ctx, "POST", whisperAPIURL, body)
+    req, err := http.NewRequestWithContext(ctx, "POST", wc.apiURL, body)
     if err != nil {
 	return nil, fmt.Errorf("创建请求失败: %v", err)
     }
@@ -98,19 +201,25 @@ func (wc *WhisperClient) Transcribe(ctx context.Context, audioPath string, langu
     // 4. 发送请求
     resp, err := wc.httpClient.Do(req)
     if err != nil {
-	return nil, fmt.Errorf("请求失败: %v", err)
+	if isTimeout(err) {
+	    return nil, fmt.Errorf("%w: %v", ErrWhisperTimeout, err)
+	}
+	return nil, fmt.Errorf("请求失败: %w", err)
     }
     defer resp.Body.Close()
 
     // 5. 检查响应状态
     if resp.StatusCode != http.StatusOK {
 	bodyBytes, _ := io.ReadAll(resp.Body)
-	return nil, fmt.Errorf("API 返回错误 (状态码 %d): %s", resp.StatusCode, string(bodyBytes))
+	return nil, &APIError{StatusCode: resp.StatusCode, Body: string(bodyBytes)}
     }
 
     // 6. 解析响应
     var whisperResp WhisperResponse
     if err := json.NewDecoder(resp.Body).Decode(&whisperResp); err != nil {
+	if isTimeout(err) {
+	    return nil, fmt.Errorf("%w: 读取响应: %v", ErrWhisperTimeout, err)
+	}
 	return nil, fmt.Errorf("解析响应失败: %v", err)
     }
 
@@ -118,20 +227,21 @@ func (wc *WhisperClient) Transcribe(ctx context.Context, audioPath string, langu
 }
 
 // TranscribeWithRetry 带重试的转换（面试亮点：错误处理）
-func (wc *WhisperClient) TranscribeWithRetry(ctx context.Context, audioPath string, language string, maxRetries int) (*WhisperResponse, error) {
+// timeout > 0 时每次请求单独设置截止时间，超时的请求返回 ErrWhisperTimeout 并按普通失败重试
+func (wc *WhisperClient) TranscribeWithRetry(ctx context.Context, audioPath string, opts WhisperOptions, maxRetries int, timeout time.Duration) (*WhisperResponse, error) {
     var lastErr error
 
     for i := 0; i < maxRetries; i++ {
-	resp, err := wc.Transcribe(ctx, audioPath, language)
+	resp, err := wc.transcribeWithTimeout(ctx, audioPath, opts, timeout)
 	if err == nil {
 	    return resp, nil
 	}
 
 	lastErr = err
 
-	// 检查是否因为 Context 取消
+	// 检查是否因为 Context 取消（整个任务的截止时间已到时仍报告为超时）
 	if ctx.Err() != nil {
-	    return nil, fmt.Errorf("任务被取消: %v", ctx.Err())
+	    return nil, canceledError(ctx)
 	}
 
 	// 指数退避
@@ -141,10 +251,35 @@ func (wc *WhisperClient) TranscribeWithRetry(ctx context.Context, audioPath stri
 	    case <-time.After(waitTime):
 		continue
 	    case <-ctx.Done():
-		return nil, fmt.Errorf("任务被取消: %v", ctx.Err())
+		return nil, canceledError(ctx)
 	    }
 	}
     }
 
-    return nil, fmt.Errorf("重试 %d 次后仍然失败: %v", maxRetries, lastErr)
+    return nil, fmt.Errorf("重试 %d 次后仍然失败: %w", maxRetries, lastErr)
+}
+
+// transcribeWithTimeout 在单独的截止时间内执行一次转录（timeout <= 0 时只受 HTTP 客户端超时限制）
+func (wc *WhisperClient) transcribeWithTimeout(ctx context.Context, audioPath string, opts WhisperOptions, timeout time.Duration) (*WhisperResponse, error) {
+    if timeout <= 0 {
+	return wc.Transcribe(ctx, audioPath, opts)
+    }
+
+    reqCtx, cancel := context.WithTimeout(ctx, timeout)
+    defer cancel()
+
+    resp, err := wc.Transcribe(reqCtx, audioPath, opts)
+    if err != nil && ctx.Err() == nil && reqCtx.Err() != nil && !errors.Is(err, ErrWhisperTimeout) {
+	// 截止时间在读取请求体等阶段到达，错误中不一定带有超时信息
+	err = fmt.Errorf("%w (%s): %v", ErrWhisperTimeout, timeout, err)
+    }
+    return resp, err
+}
+
+// canceledError 上层 Context 结束时的错误：截止时间已到视为超时，否则为任务被取消
+func canceledError(ctx context.Context) error {
+    if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+	return fmt.Errorf("%w: %v", ErrWhisperTimeout, ctx.Err())
+    }
+    return fmt.Errorf("任务被取消: %v", ctx.Err())
 }
@@ -0,0 +1,190 @@
+package transcriber
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// maxCueLines 每条字幕最多显示的行数
+const maxCueLines = 2
+
+// WrapCues 按行宽折行：超过一行的字幕在单词边界处分成两行，
+// 超过两行时拆分成多条字幕，时间按各条文本的宽度比例分配（maxLineChars <= 0 表示不折行）。
+// 宽度按显示宽度计算：中日韩文字和全角标点占 2 个字符，因此一行最多约 21 个汉字；
+// 中日韩文字之间没有空格，可以在任意两个字之间换行，但不会把句末标点放到行首
+func WrapCues(cues []Cue, maxLineChars int) []Cue {
+	if maxLineChars <= 0 {
+		return cues
+	}
+
+	wrapped := make([]Cue, 0, len(cues))
+	for _, cue := range cues {
+		lines := wrapText(cue.Text, maxLineChars)
+		if len(lines) <= maxCueLines {
+			cue.Text = strings.Join(lines, "\n")
+			wrapped = append(wrapped, cue)
+			continue
+		}
+		wrapped = append(wrapped, splitCue(cue, lines)...)
+	}
+	return wrapped
+}
+
+// splitCue 将超过两行的字幕每两行拆成一条，时间按文本宽度比例分配
+// 单词级时间戳按开始时间分到对应的字幕中
+func splitCue(cue Cue, lines []string) []Cue {
+	total := 0
+	for _, line := range lines {
+		total += textWidth(line)
+	}
+
+	parts := make([]Cue, 0, (len(lines)+maxCueLines-1)/maxCueLines)
+	start := cue.Start
+	consumed := 0
+	for i := 0; i < len(lines); i += maxCueLines {
+		end := i + maxCueLines
+		if end > len(lines) {
+			end = len(lines)
+		}
+		for _, line := range lines[i:end] {
+			consumed += textWidth(line)
+		}
+
+		part := Cue{Start: start, End: cue.End, Text: strings.Join(lines[i:end], "\n")}
+		if end < len(lines) && total > 0 {
+			part.End = cue.Start + (cue.End-cue.Start)*float64(consumed)/float64(total)
+		}
+		for _, w := range cue.Words {
+			if w.Start >= part.Start && (w.Start < part.End || end == len(lines)) {
+				part.Words = append(part.Words, w)
+			}
+		}
+		parts = append(parts, part)
+		start = part.End
+	}
+	return parts
+}
+
+// token 折行的最小单位：一个单词，或一个中日韩文字 / 全角标点
+type token struct {
+	text  string
+	width int
+	space bool // 原文中前面有空白
+}
+
+// wrapText 将文本按 maxWidth 贪心折行（原有换行视为空格）
+// 单个单词超过行宽时单独占一行，不在单词中间断开；
+// 行首不能是标点，因此标点放不下时连同前一个字一起移到下一行
+func wrapText(text string, maxWidth int) []string {
+	tokens := tokenize(text)
+	if len(tokens) == 0 {
+		return []string{strings.TrimSpace(text)}
+	}
+
+	var lines [][]token
+	var line []token
+	for _, t := range tokens {
+		if len(line) > 0 && lineWidth(append(line, t)) > maxWidth {
+			next := []token{t}
+			if isClosingPunct(t.text) && len(line) > 1 {
+				next = append([]token{line[len(line)-1]}, next...)
+				line = line[:len(line)-1]
+			}
+			lines = append(lines, line)
+			line = next
+			continue
+		}
+		line = append(line, t)
+	}
+	lines = append(lines, line)
+
+	texts := make([]string, len(lines))
+	for i, l := range lines {
+		texts[i] = joinTokens(l)
+	}
+	return texts
+}
+
+// joinTokens 拼接一行中的 token（行首不保留空格）
+func joinTokens(line []token) string {
+	var builder strings.Builder
+	for i, t := range line {
+		if i > 0 && t.space {
+			builder.WriteString(" ")
+		}
+		builder.WriteString(t.text)
+	}
+	return builder.String()
+}
+
+// lineWidth 一行 token 拼接后的显示宽度
+func lineWidth(line []token) int {
+	width := 0
+	for i, t := range line {
+		if i > 0 && t.space {
+			width++
+		}
+		width += t.width
+	}
+	return width
+}
+
+// tokenize 将文本拆分为单词和中日韩文字
+func tokenize(text string) []token {
+	var tokens []token
+	var word strings.Builder
+	wordWidth := 0
+	space := false
+
+	flush := func() {
+		if word.Len() > 0 {
+			tokens = append(tokens, token{text: word.String(), width: wordWidth, space: space})
+			word.Reset()
+			wordWidth = 0
+			space = false
+		}
+	}
+
+	for _, r := range text {
+		switch {
+		case unicode.IsSpace(r):
+			flush()
+			space = len(tokens) > 0
+		case isWide(r):
+			flush()
+			tokens = append(tokens, token{text: string(r), width: 2, space: space})
+			space = false
+		default:
+			word.WriteRune(r)
+			wordWidth++
+		}
+	}
+	flush()
+	return tokens
+}
+
+// textWidth 文本的显示宽度（中日韩文字和全角字符计 2）
+func textWidth(text string) int {
+	width := 0
+	for _, r := range text {
+		if isWide(r) {
+			width += 2
+		} else {
+			width++
+		}
+	}
+	return width
+}
+
+// isWide 是否为占两个字符宽度的中日韩文字或全角符号
+func isWide(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul) ||
+		(r >= 0x3000 && r <= 0x303F) || // 中日韩标点（、。「」等）
+		(r >= 0xFF01 && r <= 0xFF60) // 全角字符（，！？等）
+}
+
+// isClosingPunct 是否为不能出现在行首的单个标点（避头尾）
+func isClosingPunct(text string) bool {
+	return utf8.RuneCountInString(text) == 1 && strings.ContainsAny(text, "，。、！？；：）」』》〉】,.!?;:)")
+}
@@ -0,0 +1,108 @@
+package transcriber
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/z-wentao/voiceflow/pkg/models"
+)
+
+func TestWrapText(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		maxWidth int
+		want     []string
+	}{
+		{"不超过行宽", "hello world", 42, []string{"hello world"}},
+		{"在单词边界折行", "the quick brown fox jumps over the lazy dog", 20, []string{"the quick brown fox", "jumps over the lazy", "dog"}},
+		{"原有换行视为空格", "hello\nworld", 42, []string{"hello world"}},
+		{"超长单词单独占一行", "a supercalifragilistic word", 10, []string{"a", "supercalifragilistic", "word"}},
+		{"汉字按两个字符宽度计算", "我们今天学习英语单词", 10, []string{"我们今天学", "习英语单词"}},
+		{"一行最多二十一个汉字", strings.Repeat("字", 30), 42, []string{strings.Repeat("字", 21), strings.Repeat("字", 9)}},
+		{"句末标点不放在行首", "我们今天学。明天", 10, []string{"我们今天", "学。明天"}},
+		{"中英混排", "我喜欢 Go 语言和 Rust", 12, []string{"我喜欢 Go 语", "言和 Rust"}},
+		{"日文假名", "ありがとうございます", 8, []string{"ありがと", "うござい", "ます"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := wrapText(tt.text, tt.maxWidth)
+			if strings.Join(got, "|") != strings.Join(tt.want, "|") {
+				t.Fatalf("wrapText = %q，期望 %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWrapCues(t *testing.T) {
+	tests := []struct {
+		name     string
+		cue      Cue
+		maxChars int
+		wantEnds []float64 // 拆分后每条字幕的结束时间
+	}{
+		{"不折行", Cue{Start: 0, End: 10, Text: strings.Repeat("字", 100)}, 0, []float64{10}},
+		{"折成两行", Cue{Start: 0, End: 10, Text: strings.Repeat("字", 30)}, 42, []float64{10}},
+		// 100 个汉字折成 21+21+21+21+16 五行，每两行一条，时间按宽度比例分配
+		{"汉字超过两行时拆分", Cue{Start: 0, End: 10, Text: strings.Repeat("字", 100)}, 42, []float64{4.2, 8.4, 10}},
+		{"英文超过两行时拆分", Cue{Start: 5, End: 9, Text: "aaaa bbbb cccc dddd"}, 4, []float64{7, 9}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := WrapCues([]Cue{tt.cue}, tt.maxChars)
+			if len(got) != len(tt.wantEnds) {
+				t.Fatalf("拆分为 %d 条字幕，期望 %d 条: %+v", len(got), len(tt.wantEnds), got)
+			}
+			start := tt.cue.Start
+			var texts []string
+			for i, cue := range got {
+				if cue.Start != start || math.Abs(cue.End-tt.wantEnds[i]) > 1e-9 {
+					t.Fatalf("第 %d 条 %.2f–%.2f，期望 %.2f–%.2f", i+1, cue.Start, cue.End, start, tt.wantEnds[i])
+				}
+				start = cue.End
+
+				lines := strings.Split(cue.Text, "\n")
+				if tt.maxChars > 0 && len(lines) > maxCueLines {
+					t.Fatalf("第 %d 条有 %d 行", i+1, len(lines))
+				}
+				for _, line := range lines {
+					if tt.maxChars > 0 && textWidth(line) > tt.maxChars {
+						t.Fatalf("行 %q 宽度 %d 超过 %d", line, textWidth(line), tt.maxChars)
+					}
+				}
+				texts = append(texts, strings.ReplaceAll(cue.Text, "\n", ""))
+			}
+			if strings.ReplaceAll(strings.Join(texts, ""), " ", "") != strings.ReplaceAll(tt.cue.Text, " ", "") {
+				t.Fatal("拆分后的文本与原文不一致")
+			}
+		})
+	}
+}
+
+// TestGenerateSRTWrapsWithOffset 折行和拆分不影响音频片段的时间偏移
+func TestGenerateSRTWrapsWithOffset(t *testing.T) {
+	results := []SegmentResult{{
+		Segment: models.Segment{Index: 1, Start: 60},
+		Response: &WhisperResponse{Segments: []WhisperSegment{
+			{Start: 1, End: 5, Text: strings.Repeat("字", 100)},
+		}},
+	}}
+	path := filepath.Join(t.TempDir(), "out.srt")
+	if err := GenerateSRT(results, path, 42); err != nil {
+		t.Fatalf("GenerateSRT: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+	cues, err := ParseSRTContent(data)
+	if err != nil {
+		t.Fatalf("ParseSRTContent: %v", err)
+	}
+	if len(cues) != 3 || cues[0].Start != 61 || cues[len(cues)-1].End != 65 {
+		t.Fatalf("字幕 = %+v，期望拆分为 3 条，覆盖 61–65 秒", cues)
+	}
+}
@@ -4,7 +4,6 @@ import (
     "context"
     "encoding/json"
     "fmt"
-    "strings"
 
     "github.com/sashabaranov/go-openai"
 )
@@ -15,23 +14,38 @@ type Extractor struct {
 }
 
 // NewExtractor 创建单词提取器
-func NewExtractor(apiKey string) *Extractor {
+// baseURL 为 OpenAI 兼容的 API 地址，为空时使用 OpenAI 官方地址
+func NewExtractor(apiKey, baseURL string) *Extractor {
+    config := openai.DefaultConfig(apiKey)
+    if baseURL != "" {
+	config.BaseURL = baseURL
+    }
     return &Extractor{
-	client: openai.NewClient(apiKey),
+	client: openai.NewClientWithConfig(config),
     }
 }
 
+// Client 返回底层 OpenAI 客户端（供字幕翻译等其他大模型功能复用）
+func (e *Extractor) Client() *openai.Client {
+    return e.client
+}
+
 // Word 单词信息
 type Word struct {
     Word       string `json:"word"`        // 单词
     Definition string `json:"definition"`  // 释义
     Example    string `json:"example"`     // 例句
+    Phonetic   string `json:"phonetic"`    // 国际音标（IPA），如 /ˌsɒf.ɪ.stɪˈkeɪ.tɪd/
+    Level      string `json:"level"`       // CEFR 等级（A1–C2），无法识别时为空
 }
 
 // ExtractResult 提取结果
 type ExtractResult struct {
     Words []string `json:"words"` // 单词列表（仅单词，用于墨墨）
     Details []Word `json:"details"` // 详细信息（用于前端展示）
+
+    PromptTokens     int `json:"prompt_tokens"`     // 输入 token 数（用于估算费用）
+    CompletionTokens int `json:"completion_tokens"` // 输出 token 数
 }
 
 // Extract 从文本中提取关键英文单词
@@ -76,15 +90,18 @@ func (e *Extractor) Extract(ctx context.Context, text string) (*ExtractResult, e
 	return nil, fmt.Errorf("解析 AI 响应失败: %w, 原始响应: %s", err, content)
     }
 
-    // 提取单词列表
+    // 提取单词列表（模型返回的等级可能大小写不一或不在范围内）
     words := make([]string, len(result.Words))
-    for i, w := range result.Words {
-	words[i] = w.Word
+    for i := range result.Words {
+	result.Words[i].Level = NormalizeLevel(result.Words[i].Level)
+	words[i] = result.Words[i].Word
     }
 
     return &ExtractResult{
-	Words:   words,
-	Details: result.Words,
+	Words:            words,
+	Details:          result.Words,
+	PromptTokens:     resp.Usage.PromptTokens,
+	CompletionTokens: resp.Usage.CompletionTokens,
     }, nil
 }
 
@@ -104,14 +121,17 @@ func buildPrompt(text string) string {
 	- 忽略 a, the, is, are 等基础词汇
 	- 每个单词只出现一次
 	- 最多提取 50 个单词
+	- 按欧洲语言共同参考框架（CEFR）标注每个单词的难度等级：A1、A2（入门）、B1、B2（中级）、C1、C2（高级）
 
 	2. 输出格式（严格遵循 JSON 格式）：
 	{
 	"words": [
 	{
 	"word": "单词或短语（小写）",
+	"phonetic": "国际音标（IPA，英式或美式均可，用 / / 包裹）",
 	"definition": "中文释义（简洁，不超过20字）",
-	"example": "英文例句（来自原文或自己创建，不超过50字）"
+	"example": "英文例句（来自原文或自己创建，不超过50字）",
+	"level": "CEFR 等级，A1/A2/B1/B2/C1/C2 之一"
 	}
 	]
 	}
@@ -121,13 +141,17 @@ func buildPrompt(text string) string {
 	"words": [
 	{
 	"word": "artificial intelligence",
+	"phonetic": "/ˌɑːtɪˈfɪʃl ɪnˈtelɪdʒəns/",
 	"definition": "人工智能",
-	"example": "Artificial intelligence is transforming many industries."
+	"example": "Artificial intelligence is transforming many industries.",
+	"level": "B2"
 	},
 	{
 	"word": "sophisticated",
+	"phonetic": "/səˈfɪstɪkeɪtɪd/",
 	"definition": "复杂的，精密的",
-	"example": "This is a sophisticated algorithm."
+	"example": "This is a sophisticated algorithm.",
+	"level": "C1"
 	}
 	]
 	}
@@ -138,20 +162,37 @@ func buildPrompt(text string) string {
 	请严格按照 JSON 格式输出，不要包含任何其他说明文字。`, text)
 }
 
-// FilterDuplicates 去重单词列表
+// FilterKnown 过滤已经学过的单词（known 为归一化后的单词集合，如其他任务已提取的单词）
+// 返回保留的单词和被过滤的数量；与 FilterDuplicates 使用相同的归一化规则
+func FilterKnown(words []Word, known map[string]bool) ([]Word, int) {
+    if len(known) == 0 {
+	return words, 0
+    }
+
+    result := make([]Word, 0, len(words))
+    for _, w := range words {
+	if known[Normalize(w.Word)] {
+	    continue
+	}
+	result = append(result, w)
+    }
+    return result, len(words) - len(result)
+}
+
+// FilterDuplicates 去重单词列表（返回归一化后的单词）
 func FilterDuplicates(words []string) []string {
     seen := make(map[string]bool)
     result := make([]string, 0, len(words))
 
     for _, word := range words {
-	// 转为小写进行比较
-	lower := strings.ToLower(strings.TrimSpace(word))
-	if lower == "" {
+	// 归一化后比较，与单词索引的标识保持一致
+	normalized := Normalize(word)
+	if normalized == "" {
 	    continue
 	}
-	if !seen[lower] {
-	    seen[lower] = true
-	    result = append(result, lower)
+	if !seen[normalized] {
+	    seen[normalized] = true
+	    result = append(result, normalized)
 	}
     }
 
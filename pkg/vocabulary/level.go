@@ -0,0 +1,66 @@
+package vocabulary
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Levels CEFR 等级（由易到难）
+var Levels = []string{"A1", "A2", "B1", "B2", "C1", "C2"}
+
+// levelRank 等级序号（A1 = 1），无效等级返回 0
+func levelRank(level string) int {
+	for i, l := range Levels {
+		if l == level {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+// NormalizeLevel 规范化等级（去空白、转大写），无法识别时返回空字符串（未标注）
+func NormalizeLevel(level string) string {
+	level = strings.ToUpper(strings.TrimSpace(level))
+	if levelRank(level) == 0 {
+		return ""
+	}
+	return level
+}
+
+// ParseLevelRange 解析等级范围参数（为空表示不限制）
+func ParseLevelRange(minLevel, maxLevel string) (string, string, error) {
+	min, max := NormalizeLevel(minLevel), NormalizeLevel(maxLevel)
+	if min == "" && strings.TrimSpace(minLevel) != "" {
+		return "", "", fmt.Errorf("无效的等级: %s（可选 %s）", minLevel, strings.Join(Levels, "/"))
+	}
+	if max == "" && strings.TrimSpace(maxLevel) != "" {
+		return "", "", fmt.Errorf("无效的等级: %s（可选 %s）", maxLevel, strings.Join(Levels, "/"))
+	}
+	if min != "" && max != "" && levelRank(min) > levelRank(max) {
+		return "", "", fmt.Errorf("最低等级 %s 高于最高等级 %s", min, max)
+	}
+	return min, max, nil
+}
+
+// FilterByLevel 保留等级在 [minLevel, maxLevel] 范围内的单词（参数为空表示该侧不限制）
+// 设置了范围时，未标注等级的单词无法判断，一并过滤
+func FilterByLevel(words []Word, minLevel, maxLevel string) []Word {
+	if minLevel == "" && maxLevel == "" {
+		return words
+	}
+
+	min, max := levelRank(minLevel), levelRank(maxLevel)
+	if max == 0 {
+		max = len(Levels)
+	}
+
+	result := make([]Word, 0, len(words))
+	for _, w := range words {
+		rank := levelRank(w.Level)
+		if rank == 0 || rank < min || rank > max {
+			continue
+		}
+		result = append(result, w)
+	}
+	return result
+}
@@ -0,0 +1,112 @@
+package vocabulary
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Normalize 单词归一化：去除首尾空白和标点、转小写、合并多余空格，并做基础的词形还原
+// 单词去重、单词索引等功能都以归一化结果作为单词的唯一标识，修改规则时需重建索引
+func Normalize(word string) string {
+	fields := strings.Fields(strings.ToLower(word))
+	tokens := make([]string, 0, len(fields))
+	for _, f := range fields {
+		f = strings.TrimFunc(f, func(r rune) bool {
+			return unicode.IsPunct(r) && r != '-' && r != '\''
+		})
+		f = strings.Trim(f, "-'")
+		if f != "" {
+			tokens = append(tokens, f)
+		}
+	}
+	if len(tokens) == 0 {
+		return ""
+	}
+
+	// 短语只还原最后一个词（artificial intelligences → artificial intelligence）
+	tokens[len(tokens)-1] = lemmatize(tokens[len(tokens)-1])
+	return strings.Join(tokens, " ")
+}
+
+// lemmatize 基础的复数还原（不依赖词典，只处理规则变化）
+func lemmatize(word string) string {
+	if len(word) <= 3 || !isASCIIWord(word) {
+		return word
+	}
+
+	switch {
+	case strings.HasSuffix(word, "ies") && len(word) > 4:
+		// studies → study
+		return word[:len(word)-3] + "y"
+	case strings.HasSuffix(word, "sses"),
+		strings.HasSuffix(word, "xes"),
+		strings.HasSuffix(word, "zes"),
+		strings.HasSuffix(word, "ches"),
+		strings.HasSuffix(word, "shes"):
+		// classes → class, boxes → box, matches → match
+		return word[:len(word)-2]
+	case strings.HasSuffix(word, "ss"),
+		strings.HasSuffix(word, "us"),
+		strings.HasSuffix(word, "is"):
+		// class, status, analysis 不是复数
+		return word
+	case strings.HasSuffix(word, "s"):
+		return word[:len(word)-1]
+	}
+	return word
+}
+
+// isASCIIWord 是否为纯英文字母单词（带连字符、数字等的词不做还原）
+func isASCIIWord(word string) bool {
+	for _, r := range word {
+		if r < 'a' || r > 'z' {
+			return false
+		}
+	}
+	return true
+}
+
+// SentenceContext 在文本中查找包含指定单词（归一化形式）的第一个句子，找不到时返回空字符串
+func SentenceContext(text, word string) string {
+	n := len(strings.Fields(word))
+	if n == 0 {
+		return ""
+	}
+
+	for _, sentence := range splitSentences(text) {
+		tokens := strings.Fields(sentence)
+		for i := 0; i+n <= len(tokens); i++ {
+			if Normalize(strings.Join(tokens[i:i+n], " ")) == word {
+				return sentence
+			}
+		}
+	}
+	return ""
+}
+
+// splitSentences 按句末标点和换行切分句子
+func splitSentences(text string) []string {
+	sentences := make([]string, 0)
+	var builder strings.Builder
+	flush := func() {
+		if s := strings.TrimSpace(builder.String()); s != "" {
+			sentences = append(sentences, s)
+		}
+		builder.Reset()
+	}
+
+	for _, r := range text {
+		if r == '\n' {
+			flush()
+			continue
+		}
+		builder.WriteRune(r)
+		switch r {
+		case '.', '!', '?', '。', '！', '？':
+			flush()
+		}
+	}
+	flush()
+
+	return sentences
+}
@@ -0,0 +1,101 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/z-wentao/voiceflow/pkg/artifacts"
+	"github.com/z-wentao/voiceflow/pkg/filestore"
+	"github.com/z-wentao/voiceflow/pkg/models"
+)
+
+// 事件类型
+const (
+	EventJobCompleted = "job.completed"
+	EventJobFailed    = "job.failed"
+)
+
+// Payload webhook 请求体
+type Payload struct {
+	Event  string           `json:"event"`
+	SentAt time.Time        `json:"sent_at"`
+	Job    artifacts.Result `json:"job"` // 与 result.json 内容一致
+}
+
+// Notifier 任务结束时向外部系统推送通知
+type Notifier struct {
+	url     string
+	baseURL string
+	files   filestore.FileStore
+	client  *http.Client
+}
+
+// NewNotifier 创建通知器（url 为空时不推送）
+// baseURL 用于生成 payload 中的绝对地址，files 用于列出任务产物
+func NewNotifier(url, baseURL string, timeout time.Duration, files filestore.FileStore) *Notifier {
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+	return &Notifier{
+		url:     url,
+		baseURL: baseURL,
+		files:   files,
+		client:  &http.Client{Timeout: timeout},
+	}
+}
+
+// Enabled 是否配置了 webhook 地址
+func (n *Notifier) Enabled() bool {
+	return n != nil && n.url != ""
+}
+
+// JobFinished 异步推送任务结束通知（失败只记录日志，不影响任务）
+// 生成结果描述需要读取文件存储，也在后台执行，不阻塞调用方（Worker）
+func (n *Notifier) JobFinished(job *models.TranscriptionJob) {
+	if !n.Enabled() {
+		return
+	}
+
+	event := EventJobCompleted
+	if job.Status == models.StatusFailed {
+		event = EventJobFailed
+	}
+	sentAt := time.Now()
+
+	go func() {
+		payload := Payload{
+			Event:  event,
+			SentAt: sentAt,
+			Job:    artifacts.BuildResult(context.Background(), n.files, job, n.baseURL),
+		}
+		if err := n.send(payload); err != nil {
+			log.Printf("⚠️ webhook 推送失败 (任务 %s): %v", job.JobID, err)
+			return
+		}
+		log.Printf("✓ webhook 已推送: %s (任务 %s)", event, job.JobID)
+	}()
+}
+
+// send 发送 webhook 请求
+func (n *Notifier) send(payload Payload) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("序列化 payload 失败: %w", err)
+	}
+
+	resp, err := n.client.Post(n.url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("请求失败: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("响应状态码 %d", resp.StatusCode)
+	}
+	return nil
+}
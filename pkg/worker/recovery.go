@@ -0,0 +1,88 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/z-wentao/voiceflow/pkg/models"
+	"github.com/z-wentao/voiceflow/pkg/queue"
+	"github.com/z-wentao/voiceflow/pkg/storage"
+)
+
+// RecoveryResult 启动恢复的结果
+type RecoveryResult struct {
+	Requeued int // 重新入队的任务数
+	Failed   int // 中断次数达到上限、标记为失败的任务数
+	Skipped  int // 入队失败（如内存队列已满）的任务数，保持 pending 等待下次启动
+	Delayed  int // 等待自动重试的任务数，到 NextAttemptAt 时才入队
+}
+
+// Recover 启动时恢复未完成的任务
+// 内存队列在进程退出时丢失所有消息，而任务记录仍保存在 Redis / 数据库中：
+// pending 任务直接重新入队；processing 任务（处理中被中断）重置为 pending 并累加中断次数后入队，
+// 中断次数达到 maxAttempts 时标记为失败，避免反复导致崩溃的任务无限重试（maxAttempts <= 0 表示不限制）。
+// 等待自动重试（NextAttemptAt 在未来）的 pending 任务到 NextAttemptAt 时才入队，不提前重试。
+// 如果队列本身持久化了消息（RabbitMQ），同一任务可能被投递两次，Worker 认领任务时跳过已结束或正在处理的重复任务
+func Recover(ctx context.Context, store storage.Store, q queue.Queue, maxAttempts int) (RecoveryResult, error) {
+	var result RecoveryResult
+
+	jobs, err := store.ListByStatus(ctx, models.StatusPending, models.StatusProcessing)
+	if err != nil {
+		return result, fmt.Errorf("查询未完成任务失败: %w", err)
+	}
+
+	for _, job := range jobs {
+		if job.Status == models.StatusProcessing {
+			attempts := job.Attempts + 1
+			if maxAttempts > 0 && attempts >= maxAttempts {
+				errMsg := fmt.Sprintf("处理中被中断 %d 次，不再重试", attempts)
+				if err := store.Update(ctx, job.JobID, func(j *models.TranscriptionJob) {
+					j.Attempts = attempts
+					j.Status = models.StatusFailed
+					j.Error = errMsg
+					j.CompletedAt = time.Now()
+				}); err != nil {
+					slog.Warn("⚠️ 标记任务失败时出错", "job_id", job.JobID, "error", err)
+					continue
+				}
+				slog.Error("❌ 任务处理中被中断次数过多，标记为失败", "job_id", job.JobID, "attempts", attempts)
+				result.Failed++
+				continue
+			}
+
+			if err := store.Update(ctx, job.JobID, func(j *models.TranscriptionJob) {
+				j.Attempts = attempts
+				j.Status = models.StatusPending
+				j.Progress = 0
+			}); err != nil {
+				slog.Warn("⚠️ 重置任务状态失败", "job_id", job.JobID, "error", err)
+				continue
+			}
+			job.Attempts = attempts
+			job.Status = models.StatusPending
+			job.Progress = 0
+		}
+
+		if wait := time.Until(job.NextAttemptAt); job.Status == models.StatusPending && wait > 0 {
+			job := job
+			time.AfterFunc(wait, func() {
+				if err := q.Enqueue(job); err != nil {
+					slog.Warn("⚠️ 等待重试的任务入队失败", "job_id", job.JobID, "error", err)
+				}
+			})
+			result.Delayed++
+			continue
+		}
+
+		if err := q.Enqueue(job); err != nil {
+			slog.Warn("⚠️ 任务重新入队失败", "job_id", job.JobID, "error", err)
+			result.Skipped++
+			continue
+		}
+		result.Requeued++
+	}
+
+	return result, nil
+}
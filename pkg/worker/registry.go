@@ -0,0 +1,57 @@
+package worker
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Worker 状态
+const (
+	StateIdle       = "idle"       // 等待任务
+	StateProcessing = "processing" // 正在处理任务
+	StateStopped    = "stopped"    // 已停止
+)
+
+// State 单个 Worker 的当前状态
+type State struct {
+	ID    int       `json:"id"`
+	State string    `json:"state"`            // idle / processing / stopped
+	JobID string    `json:"job_id,omitempty"` // 正在处理的任务（processing 时）
+	Since time.Time `json:"since"`            // 进入当前状态的时间
+}
+
+// Registry 记录所有 Worker 的当前状态，供管理接口查询
+// 只保存在进程内存中：多实例部署时每个实例只能看到自己的 Worker
+type Registry struct {
+	mu     sync.Mutex
+	states map[int]State
+}
+
+// NewRegistry 创建 Worker 状态登记表
+func NewRegistry() *Registry {
+	return &Registry{states: make(map[int]State)}
+}
+
+// set 更新 Worker 状态（nil 登记表时忽略，方便不需要状态的调用方）
+func (r *Registry) set(id int, state, jobID string) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.states[id] = State{ID: id, State: state, JobID: jobID, Since: time.Now()}
+}
+
+// Snapshot 返回所有 Worker 的状态（按 ID 排序）
+func (r *Registry) Snapshot() []State {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	states := make([]State, 0, len(r.states))
+	for _, state := range r.states {
+		states = append(states, state)
+	}
+	sort.Slice(states, func(i, j int) bool { return states[i].ID < states[j].ID })
+	return states
+}
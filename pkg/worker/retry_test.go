@@ -0,0 +1,27 @@
+package worker
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRetryPolicyDelay(t *testing.T) {
+	policy := RetryPolicy{MaxRetries: 6, BaseDelay: time.Second, MaxDelay: 10 * time.Second}
+	tests := []struct {
+		retry int
+		want  time.Duration
+	}{
+		{1, time.Second},
+		{2, 2 * time.Second},
+		{3, 4 * time.Second},
+		{4, 8 * time.Second},
+		{5, 10 * time.Second},
+		{6, 10 * time.Second},
+		{100, 10 * time.Second},
+	}
+	for _, tt := range tests {
+		if got := policy.delay(tt.retry); got != tt.want {
+			t.Errorf("第 %d 次重试等待 %s，期望 %s", tt.retry, got, tt.want)
+		}
+	}
+}
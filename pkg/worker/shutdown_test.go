@@ -0,0 +1,103 @@
+package worker
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/z-wentao/voiceflow/pkg/filestore"
+	"github.com/z-wentao/voiceflow/pkg/models"
+	"github.com/z-wentao/voiceflow/pkg/queue"
+	"github.com/z-wentao/voiceflow/pkg/storage"
+	"github.com/z-wentao/voiceflow/pkg/transcriber"
+)
+
+// slowEngine 模拟耗时的转换：started 通知开始处理，delay 之后返回结果（context 被取消时提前返回错误）
+type slowEngine struct {
+	delay   time.Duration
+	started chan struct{}
+}
+
+func (e *slowEngine) Transcribe(ctx context.Context, jobID, audioPath string, opts transcriber.WhisperOptions, progressCallback func(progress int)) (*transcriber.TranscriptionResult, error) {
+	close(e.started)
+	select {
+	case <-time.After(e.delay):
+		progressCallback(100)
+		return &transcriber.TranscriptionResult{Text: "hello world", Duration: 1}, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// TestWorkerStopDrainsRunningJob Stop 等待进行中的任务完成，而不是中断它；排空超时后 Abort 把任务退回队列
+func TestWorkerStopDrainsRunningJob(t *testing.T) {
+	tests := []struct {
+		name       string
+		abort      bool
+		wantStatus models.JobStatus
+		wantResult string
+		wantQueued int
+	}{
+		{"Stop 等待任务完成", false, models.StatusCompleted, "hello world", 0},
+		{"排空超时后 Abort", true, models.StatusPending, "", 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			dir := t.TempDir()
+			if err := os.WriteFile(filepath.Join(dir, "audio.mp3"), []byte("audio"), 0o644); err != nil {
+				t.Fatalf("WriteFile: %v", err)
+			}
+			store := storage.NewJobStore(0)
+			q := queue.NewMemoryQueue(4)
+			defer q.Close()
+			job := &models.TranscriptionJob{JobID: "job-1", FilePath: "audio.mp3", Status: models.StatusPending}
+			if err := store.Save(ctx, job); err != nil {
+				t.Fatalf("Save: %v", err)
+			}
+
+			engine := &slowEngine{delay: 200 * time.Millisecond, started: make(chan struct{})}
+			w := NewWorker(1, q, store, filestore.NewLocalStore(dir), dir, engine, nil, nil, nil, nil)
+			w.Start()
+			if err := q.Enqueue(&models.TranscriptionJob{JobID: job.JobID, FilePath: job.FilePath}); err != nil {
+				t.Fatalf("Enqueue: %v", err)
+			}
+			select {
+			case <-engine.started:
+			case <-time.After(time.Second):
+				t.Fatal("任务没有开始处理")
+			}
+
+			stopped := make(chan struct{})
+			go func() {
+				w.Stop()
+				close(stopped)
+			}()
+			if tt.abort {
+				// 与 main 一致：Stop 开始等待之后才会因排空超时调用 Abort
+				for !w.isStopped() {
+					time.Sleep(time.Millisecond)
+				}
+				w.Abort()
+			}
+			select {
+			case <-stopped:
+			case <-time.After(2 * time.Second):
+				t.Fatal("Stop 没有返回")
+			}
+
+			got, err := store.Get(ctx, job.JobID)
+			if err != nil {
+				t.Fatalf("Get: %v", err)
+			}
+			if got.Status != tt.wantStatus || got.Result != tt.wantResult {
+				t.Fatalf("任务状态 = %s，结果 = %q，期望 %s / %q", got.Status, got.Result, tt.wantStatus, tt.wantResult)
+			}
+			if stats, _ := q.Stats(); stats.Depth != tt.wantQueued {
+				t.Fatalf("队列深度 = %d，期望 %d", stats.Depth, tt.wantQueued)
+			}
+		})
+	}
+}
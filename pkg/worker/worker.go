@@ -2,14 +2,21 @@ package worker
 
 import (
     "context"
-    "log"
-    "strings"
+    "errors"
+    "fmt"
+    "log/slog"
+    "sync"
     "time"
 
+    "github.com/z-wentao/voiceflow/pkg/budget"
+    "github.com/z-wentao/voiceflow/pkg/events"
+    "github.com/z-wentao/voiceflow/pkg/filestore"
+    "github.com/z-wentao/voiceflow/pkg/logging"
     "github.com/z-wentao/voiceflow/pkg/models"
     "github.com/z-wentao/voiceflow/pkg/queue"
     "github.com/z-wentao/voiceflow/pkg/storage"
     "github.com/z-wentao/voiceflow/pkg/transcriber"
+    "github.com/z-wentao/voiceflow/pkg/webhook"
 )
 
 // Worker 任务处理器
@@ -17,135 +24,466 @@ type Worker struct {
     id     int
     queue  queue.Queue
     store  storage.Store
-    engine *transcriber.TranscriptionEngine
-    ctx    context.Context
-    cancel context.CancelFunc
+    files  filestore.FileStore
+    tmpDir string // 从对象存储下载媒体的临时目录
+    engine Engine
+    budget *budget.Tracker
+    notify *webhook.Notifier
+    events *events.Hub
+    states *Registry // 当前状态登记（管理接口查询），可以为 nil
+    logger *slog.Logger // 带 worker_id 字段的日志记录器
+    retry  RetryPolicy  // 临时错误的自动重试策略（默认不重试）
+
+    mu      sync.Mutex
+    stopped bool               // Stop 之后不再处理新任务
+    aborted bool               // Abort 之后被取消的任务重新入队，而不是标记为失败
+    running sync.WaitGroup     // 进行中的任务（Stop 等待其完成）
+    abort   context.CancelFunc // 取消进行中任务的 context（排空超时后由 Abort 调用）
+}
+
+// Engine 转换引擎（由 *transcriber.TranscriptionEngine 实现）
+type Engine interface {
+    Transcribe(ctx context.Context, jobID, audioPath string, opts transcriber.WhisperOptions, progressCallback func(progress int)) (*transcriber.TranscriptionResult, error)
+}
+
+// jobTimeout 单个任务的最长处理时间
+const jobTimeout = 30 * time.Minute
+
+// RetryPolicy 临时错误（Whisper 429/5xx、网络超时等，见 transcriber.IsTransient）的自动重试策略
+type RetryPolicy struct {
+    MaxRetries int           // 每个任务最多自动重试的次数，<= 0 表示不重试
+    BaseDelay  time.Duration // 第一次重试前的等待时间，之后每次翻倍
+    MaxDelay   time.Duration // 等待时间上限
+}
+
+// delay 第 retry 次重试前的等待时间（retry 从 1 开始）
+func (p RetryPolicy) delay(retry int) time.Duration {
+    delay := p.BaseDelay
+    for i := 1; i < retry && delay < p.MaxDelay; i++ {
+	delay *= 2
+    }
+    return min(delay, p.MaxDelay)
 }
 
 func NewWorker(
     id int,
     q queue.Queue,
     store storage.Store,
-    engine *transcriber.TranscriptionEngine,
+    files filestore.FileStore,
+    tmpDir string,
+    engine Engine,
+    budget *budget.Tracker,
+    notify *webhook.Notifier,
+    hub *events.Hub,
+    states *Registry,
 ) *Worker {
-    ctx, cancel := context.WithCancel(context.Background())
-
     return &Worker{
 	id:     id,
 	queue:  q,
 	store:  store,
+	files:  files,
+	tmpDir: tmpDir,
 	engine: engine,
-	ctx:    ctx,
-	cancel: cancel,
+	budget: budget,
+	notify: notify,
+	events: hub,
+	states: states,
+	logger: slog.With("worker_id", id),
     }
 }
 
+// SetRetryPolicy 设置临时错误的自动重试策略（在 Start 之前调用）
+func (w *Worker) SetRetryPolicy(policy RetryPolicy) {
+    w.retry = policy
+}
+
 // Start 启动 Worker（在独立的 Goroutine 中运行）
 // 面试亮点：优雅的启动和关闭
 func (w *Worker) Start() {
     go w.run()
 }
 
-// Stop 停止 Worker
+// Stop 停止从队列获取新任务，并阻塞直到进行中的任务处理完成（或被 Abort 取消后重新入队）
 func (w *Worker) Stop() {
-    log.Printf("[Worker-%d] 正在停止...", w.id)
-    w.cancel()
+    w.logger.Info("正在停止 Worker（进行中的任务会继续完成）...")
+    w.mu.Lock()
+    w.stopped = true
+    w.mu.Unlock()
+    w.running.Wait()
+}
+
+// Abort 取消进行中的任务，用于排空超时
+// 被取消的任务重置为 pending 并退回队列（Nack requeue），由其他实例或重启后重新处理
+func (w *Worker) Abort() {
+    w.mu.Lock()
+    defer w.mu.Unlock()
+    w.aborted = true
+    if w.abort != nil {
+	w.abort()
+    }
+}
+
+// isAborted 是否已调用 Abort
+func (w *Worker) isAborted() bool {
+    w.mu.Lock()
+    defer w.mu.Unlock()
+    return w.aborted
+}
+
+// isStopped 是否已调用 Stop
+func (w *Worker) isStopped() bool {
+    w.mu.Lock()
+    defer w.mu.Unlock()
+    return w.stopped
+}
+
+// begin 登记一个进行中的任务，已停止时返回 false
+// 与 Stop 在同一把锁下判断，保证 Stop 等待时不会漏掉任务
+func (w *Worker) begin(cancel context.CancelFunc) bool {
+    w.mu.Lock()
+    defer w.mu.Unlock()
+    if w.stopped {
+	return false
+    }
+    w.running.Add(1)
+    w.abort = cancel
+    return true
+}
+
+// finish 任务处理结束
+func (w *Worker) finish() {
+    w.mu.Lock()
+    w.abort = nil
+    w.mu.Unlock()
+    w.running.Done()
 }
 
 // run Worker 主循环
 func (w *Worker) run() {
-    log.Printf("[Worker-%d] 已启动，等待任务...", w.id)
-
-    for {
-	// 检查是否需要停止
-	select {
-	case <-w.ctx.Done():
-	    log.Printf("[Worker-%d] 已停止", w.id)
-	    return
-	default:
-	}
+    w.logger.Info("Worker 已启动，等待任务...")
+    w.states.set(w.id, StateIdle, "")
 
-	// 从队列获取任务（阻塞）
+    for !w.isStopped() {
+	// 从队列获取任务（阻塞，关闭队列后返回错误）
 	job, err := w.queue.Dequeue()
 	if err != nil {
-	    log.Printf("[Worker-%d] 从队列获取任务失败: %v", w.id, err)
+	    if w.isStopped() {
+		break
+	    }
+	    w.logger.Error("❌ 从队列获取任务失败", "error", err)
 	    time.Sleep(1 * time.Second)
 	    continue
 	}
 
-	// 处理任务
-	w.processJob(job)
+	// 任务的 context 独立于 Worker 的生命周期：Stop 不会中断进行中的任务
+	ctx, cancel := context.WithTimeout(context.Background(), jobTimeout)
+	if !w.begin(cancel) {
+	    // Stop 之后才取到的任务：退回队列，由其他实例（或重启后）处理
+	    cancel()
+	    if err := w.queue.Nack(job, true, 0); err != nil {
+		w.logger.Warn("⚠️ 退回任务失败", "job_id", job.JobID, "error", err)
+	    }
+	    break
+	}
+
+	// 处理任务期间的日志都带有 job_id 字段（转换引擎通过 context 取得同一个记录器）
+	ctx = logging.WithLogger(ctx, w.logger.With("job_id", job.JobID))
+
+	w.states.set(w.id, StateProcessing, job.JobID)
+	if !w.claim(ctx, job) {
+	    // 已删除、已结束或正在由其他 Worker 处理的任务（如启动恢复与 RabbitMQ 重复投递），直接确认消息
+	    if err := w.queue.Ack(job); err != nil {
+		logging.FromContext(ctx).Warn("⚠️ 确认消息失败", "error", err)
+	    }
+	} else {
+	    done := w.trackInFlight()
+	    w.processJob(ctx, job)
+	    done()
+	}
+	cancel()
+	w.finish()
+	w.states.set(w.id, StateIdle, "")
     }
+
+    w.states.set(w.id, StateStopped, "")
+    w.logger.Info("Worker 已停止")
 }
 
-// processJob 处理单个任务
-func (w *Worker) processJob(job *models.TranscriptionJob) {
-    log.Printf("\n" + strings.Repeat("=", 80))
-    log.Printf("[Worker-%d] 📝 开始处理任务: %s", w.id, job.JobID)
-    log.Printf("[Worker-%d] 📂 文件名: %s", w.id, job.Filename)
+// trackInFlight 队列支持时登记一个正在处理的任务，返回处理结束时调用的函数
+func (w *Worker) trackInFlight() func() {
+    tracker, ok := w.queue.(queue.InFlightTracker)
+    if !ok {
+	return func() {}
+    }
+    tracker.Started()
+    return tracker.Finished
+}
+
+// claim 跳过任务的原因
+var (
+    errJobFinished = errors.New("任务已结束")
+    errJobClaimed  = errors.New("任务正在由其他 Worker 处理")
+)
 
-    // 更新状态为处理中
-    w.store.Update(job.JobID, func(j *models.TranscriptionJob) {
+// claim 认领任务：通过 storage.Modify 把任务原子地改为 processing（版本冲突时重新读取再判断），
+// 同一任务的多条消息（RabbitMQ 重复投递、启动恢复重复入队）只有一条能认领成功。
+// 返回 false 表示无需处理：任务不存在（被删除）、已经结束，或正在由其他 Worker 处理。
+// processing 状态超过 jobTimeout 没有更新的任务视为处理它的实例已经退出，可以重新认领
+func (w *Worker) claim(ctx context.Context, job *models.TranscriptionJob) bool {
+    logger := logging.FromContext(ctx)
+    _, err := storage.Modify(ctx, w.store, job.JobID, func(j *models.TranscriptionJob) error {
+	switch {
+	case j.Status == models.StatusCompleted || j.Status == models.StatusFailed:
+	    return errJobFinished
+	case j.Status == models.StatusProcessing && time.Since(j.LastUpdated) < jobTimeout:
+	    return errJobClaimed
+	}
+	// 清除上一次临时失败留下的错误和重试时间
 	j.Status = models.StatusProcessing
 	j.Progress = 0
+	j.Error = ""
+	j.NextAttemptAt = time.Time{}
+	j.LastUpdated = time.Now()
+	return nil
     })
+    switch {
+    case err == nil:
+	return true
+    case errors.Is(err, errJobFinished), errors.Is(err, errJobClaimed):
+	logger.Info("⏭️ 跳过重复消息", "reason", err)
+    default:
+	logger.Info("⏭️ 认领任务失败，跳过", "error", err)
+    }
+    return false
+}
 
-    // 进度回调
+// processJob 处理单个任务
+func (w *Worker) processJob(ctx context.Context, job *models.TranscriptionJob) {
+    logger := logging.FromContext(ctx)
+    logger.Info("📝 开始处理任务", "filename", job.Filename)
+
+    // 任务已由 claim 改为处理中
+    w.publish(job, models.StatusProcessing, 0, "")
+
+    // 进度回调（同时刷新 LastUpdated，进度轮询接口据此判断是否有变化）
     progressCallback := func(progress int) {
-	w.store.Update(job.JobID, func(j *models.TranscriptionJob) {
+	w.store.Update(ctx, job.JobID, func(j *models.TranscriptionJob) {
 	    j.Progress = progress
+	    j.LastUpdated = time.Now()
 	})
-	log.Printf("[Worker-%d] 任务 %s 进度: %d%%", w.id, job.JobID, progress)
+	w.publish(job, models.StatusProcessing, progress, "")
+	logger.Info("任务进度", "progress", progress)
     }
 
-    ctx, cancel := context.WithTimeout(w.ctx, 30*time.Minute)
-    defer cancel()
-
-    // 调用转换引擎
+    // 获取本地媒体文件（对象存储时下载到临时目录，切分和转码需要本地文件）
     startTime := time.Now()
-    result, err := w.engine.Transcribe(ctx, job.FilePath, "", progressCallback)
-
+    audioPath, cleanup, err := filestore.Fetch(ctx, w.files, job.FilePath, w.tmpDir)
     if err != nil {
-	// 处理失败
-	log.Printf("[Worker-%d] ❌ 任务 %s 失败: %v", w.id, job.JobID, err)
-	w.store.Update(job.JobID, func(j *models.TranscriptionJob) {
-	    j.Status = models.StatusFailed
-	    j.Error = err.Error()
-	    j.CompletedAt = time.Now()
-	})
+	w.fail(ctx, job, fmt.Errorf("获取媒体文件失败: %w", err))
+	return
+    }
+    defer cleanup()
 
-	// 拒绝消息（不重新入队，避免无限重试）
-	// 注意：RabbitMQ 会执行真实的 Nack，MemoryQueue 则是空操作
-	if nackErr := w.queue.Nack(job, false); nackErr != nil {
-	    log.Printf("[Worker-%d] ⚠️  Nack 消息失败: %v", w.id, nackErr)
-	}
+    // 调用转换引擎
+    result, err := w.engine.Transcribe(ctx, job.JobID, audioPath, transcriber.WhisperOptions{
+	Language:    job.Language,
+	Prompt:      job.Prompt,
+	Temperature: job.Temperature,
+	Model:       job.Model,
+    }, progressCallback)
+    if err != nil {
+	w.fail(ctx, job, err)
 	return
     }
 
+    // 字幕上传到文件存储，任务中保存对象 key
+    w.storeSubtitles(ctx, job.FilePath, result)
+
+    // 记录 Whisper 和字幕翻译费用（进行中的任务不受预算限制，照常完成），同时累加到任务的费用估算
+    cost := w.budget.RecordWhisper(ctx, result.Duration)
+    if result.TranslationPromptTokens > 0 || result.TranslationCompletionTokens > 0 {
+	cost += w.budget.RecordChat(ctx, result.TranslationPromptTokens, result.TranslationCompletionTokens)
+    }
+
     // 处理成功
     duration := time.Since(startTime)
-    log.Printf("[Worker-%d] 🎉 任务 %s 完成！", w.id, job.JobID)
-    log.Printf("[Worker-%d] ⏱️  总耗时: %.2f 秒 (%.2f 分钟)", w.id, duration.Seconds(), duration.Minutes())
-    log.Printf("[Worker-%d] 📝 转换结果长度: %d 字符", w.id, len(result.Text))
+    attrs := []any{"duration", duration.Round(time.Millisecond), "text_length", len(result.Text)}
     if result.SubtitlePath != "" {
-	log.Printf("[Worker-%d] 🎬 字幕文件:", w.id)
-	log.Printf("[Worker-%d]    - SRT: %s", w.id, result.SubtitlePath)
-	log.Printf("[Worker-%d]    - VTT: %s", w.id, result.VTTPath)
+	attrs = append(attrs, "srt", result.SubtitlePath, "vtt", result.VTTPath)
+    }
+    if result.BilingualSRTPath != "" {
+	attrs = append(attrs, "bilingual_srt", result.BilingualSRTPath, "bilingual_vtt", result.BilingualVTTPath)
     }
-    log.Printf(strings.Repeat("=", 80) + "\n")
+    logger.Info("🎉 任务完成", attrs...)
 
-    w.store.Update(job.JobID, func(j *models.TranscriptionJob) {
+    w.store.Update(ctx, job.JobID, func(j *models.TranscriptionJob) {
 	j.Status = models.StatusCompleted
 	j.Result = result.Text
 	j.SubtitlePath = result.SubtitlePath
 	j.VTTPath = result.VTTPath
+	j.BilingualSRTPath = result.BilingualSRTPath
+	j.BilingualVTTPath = result.BilingualVTTPath
+	j.Duration = result.Duration // 上传时已由 ffprobe 读取，旧任务在这里补上
+	j.EstimatedCost += cost
+	j.PromptTokens += result.TranslationPromptTokens
+	j.CompletionTokens += result.TranslationCompletionTokens
 	j.Progress = 100
 	j.CompletedAt = time.Now()
+	j.LastUpdated = j.CompletedAt
     })
+    w.publish(job, models.StatusCompleted, 100, "")
+    w.notifyFinished(ctx, job.JobID)
 
     // 确认消息（任务成功完成）
-    // 注意：RabbitMQ 会执行真实的 Ack，MemoryQueue 则是空操作
+    // 注意：RabbitMQ 会执行真实的 Ack，MemoryQueue 取出时已移除，Ack 为空操作
     if err := w.queue.Ack(job); err != nil {
-	log.Printf("[Worker-%d] ⚠️  确认消息失败: %v", w.id, err)
+	logger.Warn("⚠️ 确认消息失败", "error", err)
+    }
+}
+
+// fail 标记任务失败并拒绝消息（因关闭服务被取消的任务改为重新入队，临时错误在重试次数内延迟后重新入队）
+// 任务 context 可能已超时或被取消，写入最终状态时去掉取消信号
+func (w *Worker) fail(ctx context.Context, job *models.TranscriptionJob, err error) {
+    ctx = context.WithoutCancel(ctx)
+    if w.isAborted() {
+	w.requeue(ctx, job, err)
+	return
+    }
+    if transcriber.IsTransient(err) && w.retryLater(ctx, job, err) {
+	return
+    }
+
+    logger := logging.FromContext(ctx)
+    logger.Error("❌ 任务失败", "error", err)
+    w.store.Update(ctx, job.JobID, func(j *models.TranscriptionJob) {
+	j.Status = models.StatusFailed
+	j.Error = err.Error()
+	j.CompletedAt = time.Now()
+	j.LastUpdated = j.CompletedAt
+    })
+    w.publish(job, models.StatusFailed, 0, err.Error())
+    w.notifyFinished(ctx, job.JobID)
+
+    // 拒绝消息（不重新入队，避免无限重试）
+    // 注意：RabbitMQ 会执行真实的 Nack，MemoryQueue 直接丢弃
+    if nackErr := w.queue.Nack(job, false, 0); nackErr != nil {
+	logger.Warn("⚠️ Nack 消息失败", "error", nackErr)
+    }
+}
+
+// requeue 关闭服务时被中断的任务：重置为 pending 并退回队列
+// RabbitMQ 会把消息重新投递给其他实例；内存队列已关闭时消息随进程丢失，由下次启动的恢复流程重新入队
+func (w *Worker) requeue(ctx context.Context, job *models.TranscriptionJob, cause error) {
+    logger := logging.FromContext(ctx)
+    logger.Warn("↩️ 任务因服务关闭被中断，重新入队", "cause", cause)
+    w.store.Update(ctx, job.JobID, func(j *models.TranscriptionJob) {
+	j.Status = models.StatusPending
+	j.Progress = 0
+	j.LastUpdated = time.Now()
+    })
+    w.publish(job, models.StatusPending, 0, "")
+
+    if err := w.queue.Nack(job, true, 0); err != nil {
+	logger.Warn("⚠️ Nack 消息失败", "error", err)
+    }
+}
+
+// retryLater 临时错误：在重试次数内将任务重置为 pending，通过 Nack 的 requeueAfter 延迟退回队列，返回是否已安排重试
+// 等待由队列实现：RabbitMQ 使用 TTL + 死信路由的重试队列（等待期间不占用预取名额，服务重启不影响），
+// NATS 使用 NakWithDelay，内存队列使用 time.AfterFunc（等待期间关闭时任务保持 pending，由下次启动的恢复流程重新入队）
+func (w *Worker) retryLater(ctx context.Context, job *models.TranscriptionJob, cause error) bool {
+    if w.retry.MaxRetries <= 0 {
+	return false
+    }
+
+    var scheduled bool
+    var retry int
+    var delay time.Duration
+    err := w.store.Update(ctx, job.JobID, func(j *models.TranscriptionJob) {
+	scheduled = false
+	if j.RetryCount >= w.retry.MaxRetries {
+	    return
+	}
+	retry = j.RetryCount + 1
+	delay = w.retry.delay(retry)
+
+	j.RetryCount = retry
+	j.NextAttemptAt = time.Now().Add(delay)
+	j.Status = models.StatusPending
+	j.Progress = 0
+	j.Error = cause.Error()
+	j.LastUpdated = time.Now()
+	scheduled = true
+    })
+    logger := logging.FromContext(ctx)
+    if err != nil {
+	logger.Warn("⚠️ 安排重试失败，标记任务失败", "error", err)
+	return false
+    }
+    if !scheduled {
+	logger.Warn("⚠️ 任务自动重试次数已用完", "retries", w.retry.MaxRetries)
+	return false
+    }
+
+    // 按重试策略计算的等待时间（而不是到 NextAttemptAt 的剩余时间）：同一次重试的等待时间固定，
+    // RabbitMQ 按等待时间复用重试队列
+    logger.Warn("🔁 任务遇到临时错误，稍后重试", "retry", retry, "max_retries", w.retry.MaxRetries, "delay", delay.Round(time.Second), "error", cause)
+    w.publish(job, models.StatusPending, 0, "")
+
+    if err := w.queue.Nack(job, true, delay); err != nil {
+	logger.Warn("⚠️ 重试任务退回队列失败", "error", err)
+    }
+    return true
+}
+
+// storeSubtitles 将生成的字幕上传到文件存储，并把结果中的本地路径替换为对象 key
+// key 与媒体文件位于同一前缀下（如 uploads/abc.mp3 → uploads/abc.srt）；
+// 上传失败的字幕视为未生成，不影响任务完成（与字幕生成失败的处理一致）
+func (w *Worker) storeSubtitles(ctx context.Context, mediaKey string, result *transcriber.TranscriptionResult) {
+    subtitles := []struct {
+	path        *string
+	contentType string
+    }{
+	{&result.SubtitlePath, "text/plain; charset=utf-8"},
+	{&result.VTTPath, "text/vtt; charset=utf-8"},
+	{&result.BilingualSRTPath, "text/plain; charset=utf-8"},
+	{&result.BilingualVTTPath, "text/vtt; charset=utf-8"},
+    }
+
+    for _, sub := range subtitles {
+	if *sub.path == "" {
+	    continue
+	}
+	key := filestore.SiblingKey(mediaKey, *sub.path)
+	if err := filestore.PutFile(ctx, w.files, key, *sub.path, sub.contentType); err != nil {
+	    logging.FromContext(ctx).Warn("⚠️ 上传字幕失败", "key", key, "error", err)
+	    *sub.path = ""
+	    continue
+	}
+	*sub.path = key
+    }
+}
+
+// publish 发布任务进度事件（供 SSE 和 WebSocket 实时推送）
+func (w *Worker) publish(job *models.TranscriptionJob, status models.JobStatus, progress int, errMsg string) {
+    w.events.Publish(events.Event{
+	JobID:    job.JobID,
+	Status:   status,
+	Progress: progress,
+	Error:    errMsg,
+	UserID:   job.UserID,
+    })
+}
+
+// notifyFinished 推送任务结束通知（读取最新的任务数据）
+func (w *Worker) notifyFinished(ctx context.Context, jobID string) {
+    if !w.notify.Enabled() {
+	return
+    }
+    job, err := w.store.Get(ctx, jobID)
+    if err != nil {
+	logging.FromContext(ctx).Warn("⚠️ 读取任务失败，跳过 webhook", "error", err)
+	return
     }
+    w.notify.JobFinished(job)
 }
@@ -0,0 +1,137 @@
+package worker
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/z-wentao/voiceflow/pkg/models"
+	"github.com/z-wentao/voiceflow/pkg/queue"
+	"github.com/z-wentao/voiceflow/pkg/storage"
+)
+
+func newTestWorker(id int, q queue.Queue, store storage.Store) *Worker {
+	return NewWorker(id, q, store, nil, "", nil, nil, nil, nil, nil)
+}
+
+// TestClaimDuplicateDelivery 同一个 pending 任务的两条消息（RabbitMQ 中的消息 + 启动恢复重新入队）
+// 同时被两个 Worker 取到时，只有一个能认领
+func TestClaimDuplicateDelivery(t *testing.T) {
+	ctx := context.Background()
+	store := storage.NewJobStore(0)
+	q := queue.NewMemoryQueue(10)
+	job := &models.TranscriptionJob{JobID: "job-1", Status: models.StatusPending}
+	if err := store.Save(ctx, job); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	for round := 0; round < 20; round++ {
+		if err := store.Update(ctx, job.JobID, func(j *models.TranscriptionJob) { j.Status = models.StatusPending }); err != nil {
+			t.Fatalf("Update: %v", err)
+		}
+
+		var claimed atomic.Int64
+		var wg sync.WaitGroup
+		for id := 1; id <= 2; id++ {
+			wg.Add(1)
+			go func(w *Worker) {
+				defer wg.Done()
+				if w.claim(ctx, &models.TranscriptionJob{JobID: job.JobID}) {
+					claimed.Add(1)
+				}
+			}(newTestWorker(id, q, store))
+		}
+		wg.Wait()
+
+		if claimed.Load() != 1 {
+			t.Fatalf("第 %d 轮: %d 个 Worker 认领了同一个任务，期望 1 个", round, claimed.Load())
+		}
+	}
+}
+
+func TestClaim(t *testing.T) {
+	tests := []struct {
+		name string
+		job  models.TranscriptionJob
+		want bool
+	}{
+		{"等待处理", models.TranscriptionJob{Status: models.StatusPending}, true},
+		{"等待重试", models.TranscriptionJob{Status: models.StatusPending, Error: "429", NextAttemptAt: time.Now()}, true},
+		{"已完成", models.TranscriptionJob{Status: models.StatusCompleted}, false},
+		{"已失败", models.TranscriptionJob{Status: models.StatusFailed}, false},
+		{"正在处理", models.TranscriptionJob{Status: models.StatusProcessing, LastUpdated: time.Now()}, false},
+		{"处理实例已退出", models.TranscriptionJob{Status: models.StatusProcessing, LastUpdated: time.Now().Add(-2 * jobTimeout)}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			store := storage.NewJobStore(0)
+			tt.job.JobID = "job-1"
+			if err := store.Save(ctx, &tt.job); err != nil {
+				t.Fatalf("Save: %v", err)
+			}
+
+			w := newTestWorker(1, queue.NewMemoryQueue(1), store)
+			if got := w.claim(ctx, &models.TranscriptionJob{JobID: "job-1"}); got != tt.want {
+				t.Fatalf("claim = %v，期望 %v", got, tt.want)
+			}
+
+			stored, _ := store.Get(ctx, "job-1")
+			if tt.want && (stored.Status != models.StatusProcessing || stored.Error != "" || !stored.NextAttemptAt.IsZero()) {
+				t.Fatalf("认领后的任务: status=%s error=%q next_attempt_at=%v", stored.Status, stored.Error, stored.NextAttemptAt)
+			}
+			if !tt.want && stored.Status != tt.job.Status {
+				t.Fatalf("跳过的任务状态被修改为 %s", stored.Status)
+			}
+		})
+	}
+
+	t.Run("任务不存在", func(t *testing.T) {
+		w := newTestWorker(1, queue.NewMemoryQueue(1), storage.NewJobStore(0))
+		if w.claim(context.Background(), &models.TranscriptionJob{JobID: "missing"}) {
+			t.Fatal("不存在的任务不应认领成功")
+		}
+	})
+}
+
+func TestRecoverRespectsNextAttemptAt(t *testing.T) {
+	ctx := context.Background()
+	store := storage.NewJobStore(0)
+	q := queue.NewMemoryQueue(10)
+	defer q.Close()
+
+	jobs := []*models.TranscriptionJob{
+		{JobID: "pending", Status: models.StatusPending},
+		{JobID: "waiting", Status: models.StatusPending, RetryCount: 1, NextAttemptAt: time.Now().Add(100 * time.Millisecond)},
+		{JobID: "interrupted", Status: models.StatusProcessing},
+	}
+	for _, job := range jobs {
+		if err := store.Save(ctx, job); err != nil {
+			t.Fatalf("Save: %v", err)
+		}
+	}
+
+	result, err := Recover(ctx, store, q, 3)
+	if err != nil {
+		t.Fatalf("Recover: %v", err)
+	}
+	if result.Requeued != 2 || result.Delayed != 1 {
+		t.Fatalf("Recover = %+v，期望重新入队 2 个、等待重试 1 个", result)
+	}
+	if stats, _ := q.Stats(); stats.Depth != 2 {
+		t.Fatalf("恢复后队列深度 = %d，等待重试的任务不应立即入队", stats.Depth)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		if stats, _ := q.Stats(); stats.Depth == 3 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("等待重试的任务到 NextAttemptAt 后没有入队")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}